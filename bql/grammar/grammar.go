@@ -21,6 +21,9 @@
 package grammar
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/semantic"
 )
@@ -30,10 +33,12 @@ func startClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemQuery),
+				NewSymbol("DISTINCT_ROWS"),
 				NewSymbol("VARS"),
 				NewTokenType(lexer.ItemFrom),
 				NewSymbol("INPUT_GRAPHS"),
-				NewSymbol("WHERE"),
+				NewSymbol("DECLARE"),
+				NewSymbol("QUERY_WHERE"),
 				NewSymbol("GROUP_BY"),
 				NewSymbol("ORDER_BY"),
 				NewSymbol("HAVING"),
@@ -48,6 +53,7 @@ func startClauses() []*Clause {
 				NewTokenType(lexer.ItemData),
 				NewTokenType(lexer.ItemInto),
 				NewSymbol("OUTPUT_GRAPHS"),
+				NewSymbol("IF_ABSENT"),
 				NewTokenType(lexer.ItemLBracket),
 				NewTokenType(lexer.ItemNode),
 				NewTokenType(lexer.ItemPredicate),
@@ -60,15 +66,7 @@ func startClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemDelete),
-				NewTokenType(lexer.ItemData),
-				NewTokenType(lexer.ItemFrom),
-				NewSymbol("INPUT_GRAPHS"),
-				NewTokenType(lexer.ItemLBracket),
-				NewTokenType(lexer.ItemNode),
-				NewTokenType(lexer.ItemPredicate),
-				NewSymbol("DELETE_OBJECT"),
-				NewSymbol("DELETE_DATA"),
-				NewTokenType(lexer.ItemRBracket),
+				NewSymbol("DELETE_STATEMENT"),
 				NewTokenType(lexer.ItemSemicolon),
 			},
 		},
@@ -119,6 +117,67 @@ func startClauses() []*Clause {
 				NewTokenType(lexer.ItemSemicolon),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDump),
+				NewSymbol("DUMP_GRAPHS"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemClear),
+				NewSymbol("CLEAR_STORE"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemTruncate),
+				NewSymbol("TRUNCATE_GRAPHS"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemMerge),
+				NewSymbol("INPUT_GRAPHS"),
+				NewTokenType(lexer.ItemInto),
+				NewSymbol("OUTPUT_GRAPHS"),
+				NewSymbol("MERGE_CONFLICTS"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemAnchors),
+				NewSymbol("ANCHORS_LOOKUP"),
+				NewTokenType(lexer.ItemFrom),
+				NewSymbol("INPUT_GRAPHS"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemKill),
+				NewSymbol("KILL_QUERY"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemRefresh),
+				NewSymbol("REFRESH_VIEW"),
+				NewTokenType(lexer.ItemSemicolon),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemTrace),
+				NewTokenType(lexer.ItemLiteral),
+				NewSymbol("START"),
+			},
+		},
 	}
 }
 
@@ -127,9 +186,64 @@ func createGraphClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemGraph),
+				NewSymbol("IF_NOT_EXISTS"),
 				NewSymbol("GRAPHS"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemView),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemQuery),
+				NewSymbol("VARS"),
+				NewTokenType(lexer.ItemFrom),
+				NewSymbol("INPUT_GRAPHS"),
+				NewSymbol("DECLARE"),
+				NewSymbol("WHERE"),
+				NewSymbol("GROUP_BY"),
+				NewSymbol("ORDER_BY"),
+				NewSymbol("HAVING"),
+				NewSymbol("GLOBAL_TIME_BOUND"),
+				NewSymbol("LIMIT"),
+			},
+		},
+	}
+}
+
+func refreshViewClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemView),
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+	}
+}
+
+func ifNotExistsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemIf),
+				NewTokenType(lexer.ItemNot),
+				NewTokenType(lexer.ItemExists),
+			},
+		},
+		{},
+	}
+}
+
+func ifAbsentClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemIf),
+				NewTokenType(lexer.ItemAbsent),
+			},
+		},
+		{},
 	}
 }
 
@@ -138,14 +252,60 @@ func dropGraphClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemGraph),
+				NewSymbol("IF_EXISTS"),
 				NewSymbol("GRAPHS"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemView),
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+	}
+}
+
+func truncateGraphClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemGraph),
+				NewSymbol("GRAPHS"),
+			},
+		},
+	}
+}
+
+func ifExistsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemIf),
+				NewTokenType(lexer.ItemExists),
+			},
+		},
+		{},
+	}
+}
+
+func dumpGraphClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_GRAPHS"),
+			},
+		},
 	}
 }
 
 func varsClauses() []*Clause {
 	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemStar),
+			},
+		},
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemBinding),
@@ -167,26 +327,204 @@ func varsClauses() []*Clause {
 		},
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemSum),
-				NewTokenType(lexer.ItemLPar),
-				NewTokenType(lexer.ItemBinding),
-				NewTokenType(lexer.ItemRPar),
-				NewTokenType(lexer.ItemAs),
-				NewTokenType(lexer.ItemBinding),
-				NewSymbol("MORE_VARS"),
+				NewTokenType(lexer.ItemSum),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemGroupConcat),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("CONCAT_SEPARATOR"),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemCast),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteralType),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLang),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemKind),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDatatype),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemStrlen),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemSubstr),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteral),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteral),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemGroupIndex),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemHistogram),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewSymbol("HISTOGRAM_INTERVAL"),
+				NewTokenType(lexer.ItemRPar),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemGraph),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemGraphCount),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemTriple),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_VARS"),
+			},
+		},
+	}
+}
+
+func distinctRowsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDistinct),
+			},
+		},
+		{},
+	}
+}
+
+func countDistinctClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDistinct),
+			},
+		},
+		{},
+	}
+}
+
+func concatSeparatorClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemQuotedString),
 			},
 		},
+		{},
 	}
 }
 
-func countDistinctClauses() []*Clause {
+func histogramIntervalClauses() []*Clause {
 	return []*Clause{
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemDistinct),
+				NewTokenType(lexer.ItemQuotedString),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLiteral),
 			},
 		},
-		{},
 	}
 }
 
@@ -286,6 +624,46 @@ func moreOutputGraphClauses() []*Clause {
 	}
 }
 
+func declareClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDeclare),
+				NewSymbol("DECLARE_STRICT"),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemLiteralType),
+				NewSymbol("MORE_DECLARATIONS"),
+			},
+		},
+		{},
+	}
+}
+
+func declareStrictClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemStrict),
+			},
+		},
+		{},
+	}
+}
+
+func moreDeclarationsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemLiteralType),
+				NewSymbol("MORE_DECLARATIONS"),
+			},
+		},
+		{},
+	}
+}
+
 func whereClauses() []*Clause {
 	return []*Clause{
 		{
@@ -294,8 +672,43 @@ func whereClauses() []*Clause {
 				NewTokenType(lexer.ItemLBracket),
 				NewSymbol("FIRST_CLAUSE"),
 				NewTokenType(lexer.ItemRBracket),
+				NewSymbol("UNWIND"),
+			},
+		},
+	}
+}
+
+func queryWhereClauses() []*Clause {
+	return append(whereClauses(), &Clause{})
+}
+
+func unwindClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemUnwind),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_UNWIND"),
+			},
+		},
+		{},
+	}
+}
+
+func moreUnwindClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemAs),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("MORE_UNWIND"),
 			},
 		},
+		{},
 	}
 }
 
@@ -307,6 +720,7 @@ func firstClauses() []*Clause {
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_PREDICATE_OBJECT_PAIRS"),
 				NewSymbol("MORE_CLAUSES"),
 			},
 		},
@@ -316,11 +730,25 @@ func firstClauses() []*Clause {
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_PREDICATE_OBJECT_PAIRS"),
 				NewSymbol("MORE_CLAUSES"),
 			},
 		},
 	}
 }
+func morePredicateObjectPairsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemSemicolon),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_PREDICATE_OBJECT_PAIRS"),
+			},
+		},
+		{},
+	}
+}
 func moreClauses() []*Clause {
 	return []*Clause{
 		{
@@ -344,12 +772,22 @@ func clauses() []*Clause {
 				NewSymbol("MORE_CLAUSES"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNot),
+				NewTokenType(lexer.ItemLBracket),
+				NewSymbol("NEGATED_CLAUSE"),
+				NewTokenType(lexer.ItemRBracket),
+				NewSymbol("MORE_CLAUSES"),
+			},
+		},
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemNode),
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_PREDICATE_OBJECT_PAIRS"),
 				NewSymbol("MORE_CLAUSES"),
 			},
 		},
@@ -359,6 +797,7 @@ func clauses() []*Clause {
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_PREDICATE_OBJECT_PAIRS"),
 				NewSymbol("MORE_CLAUSES"),
 			},
 		},
@@ -398,13 +837,28 @@ func moreFilterArguments() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemComma),
-				NewTokenType(lexer.ItemLiteral),
+				NewSymbol("FILTER_VALUE"),
 			},
 		},
 		{},
 	}
 }
 
+func filterValue() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLiteral),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNode),
+			},
+		},
+	}
+}
+
 func optionalClauses() []*Clause {
 	return []*Clause{
 		{
@@ -413,6 +867,101 @@ func optionalClauses() []*Clause {
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_OPTIONAL_CLAUSES"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_OPTIONAL_CLAUSES"),
+			},
+		},
+	}
+}
+
+func moreOptionalClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDot),
+				NewSymbol("NEXT_OPTIONAL_CLAUSE"),
+			},
+		},
+		{},
+	}
+}
+
+func nextOptionalClause() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNode),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_OPTIONAL_CLAUSES"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_OPTIONAL_CLAUSES"),
+			},
+		},
+		{},
+	}
+}
+
+func negatedClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNode),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_NEGATED_CLAUSES"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_NEGATED_CLAUSES"),
+			},
+		},
+	}
+}
+
+func moreNegatedClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDot),
+				NewSymbol("NEXT_NEGATED_CLAUSE"),
+			},
+		},
+		{},
+	}
+}
+
+func nextNegatedClause() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNode),
+				NewSymbol("SUBJECT_EXTRACT"),
+				NewSymbol("PREDICATE"),
+				NewSymbol("OBJECT"),
+				NewSymbol("MORE_NEGATED_CLAUSES"),
 			},
 		},
 		{
@@ -421,8 +970,10 @@ func optionalClauses() []*Clause {
 				NewSymbol("SUBJECT_EXTRACT"),
 				NewSymbol("PREDICATE"),
 				NewSymbol("OBJECT"),
+				NewSymbol("MORE_NEGATED_CLAUSES"),
 			},
 		},
+		{},
 	}
 }
 
@@ -543,13 +1094,28 @@ func predicateIDClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemID),
-				NewTokenType(lexer.ItemBinding),
+				NewSymbol("PREDICATE_ID_VALUE"),
 			},
 		},
 		{},
 	}
 }
 
+func predicateIDValueClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemQuotedString),
+			},
+		},
+	}
+}
+
 func predicateAtClauses() []*Clause {
 	return []*Clause{
 		{
@@ -603,34 +1169,90 @@ func objectClauses() []*Clause {
 			Elements: []Element{
 				NewTokenType(lexer.ItemLiteral),
 				NewSymbol("OBJECT_LITERAL_AS"),
+				NewSymbol("OBJECT_GUARD"),
+				NewSymbol("OBJECT_LIMIT"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNode),
+				NewSymbol("OBJECT_NODE_EXTRACT"),
+				NewSymbol("OBJECT_GUARD"),
+				NewSymbol("OBJECT_LIMIT"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemPredicate),
+				NewSymbol("OBJECT_PREDICATE_AS"),
+				NewSymbol("OBJECT_PREDICATE_ID"),
+				NewSymbol("OBJECT_PREDICATE_AT"),
+				NewSymbol("OBJECT_GUARD"),
+				NewSymbol("OBJECT_LIMIT"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemPredicateBound),
+				NewSymbol("OBJECT_PREDICATE_AS"),
+				NewSymbol("OBJECT_PREDICATE_ID"),
+				NewSymbol("OBJECT_PREDICATE_BOUND_AT"),
+				NewSymbol("OBJECT_GUARD"),
+				NewSymbol("OBJECT_LIMIT"),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("OBJECT_BINDING_EXTRACT"),
+				NewSymbol("OBJECT_GUARD"),
+				NewSymbol("OBJECT_LIMIT"),
+			},
+		},
+	}
+}
+
+func objectGuardClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemWhere),
+				NewTokenType(lexer.ItemBinding),
+				NewSymbol("OBJECT_GUARD_COMPARATOR"),
+				NewTokenType(lexer.ItemLiteral),
 			},
 		},
+		{},
+	}
+}
+
+func objectLimitClauses() []*Clause {
+	return []*Clause{
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemNode),
-				NewSymbol("OBJECT_NODE_EXTRACT"),
+				NewTokenType(lexer.ItemLimit),
+				NewTokenType(lexer.ItemLiteral),
 			},
 		},
+		{},
+	}
+}
+
+func objectGuardComparatorClauses() []*Clause {
+	return []*Clause{
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemPredicate),
-				NewSymbol("OBJECT_PREDICATE_AS"),
-				NewSymbol("OBJECT_PREDICATE_ID"),
-				NewSymbol("OBJECT_PREDICATE_AT"),
+				NewTokenType(lexer.ItemLT),
 			},
 		},
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemPredicateBound),
-				NewSymbol("OBJECT_PREDICATE_AS"),
-				NewSymbol("OBJECT_PREDICATE_ID"),
-				NewSymbol("OBJECT_PREDICATE_BOUND_AT"),
+				NewTokenType(lexer.ItemGT),
 			},
 		},
 		{
 			Elements: []Element{
-				NewTokenType(lexer.ItemBinding),
-				NewSymbol("OBJECT_BINDING_EXTRACT"),
+				NewTokenType(lexer.ItemEQ),
 			},
 		},
 	}
@@ -801,7 +1423,7 @@ func objectBindingExtractClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemType),
-				NewTokenType(lexer.ItemBinding),
+				NewSymbol("OBJECT_BINDING_TYPE_VALUE"),
 				NewSymbol("OBJECT_BINDING_ID"),
 				NewSymbol("OBJECT_BINDING_AT"),
 			},
@@ -836,7 +1458,7 @@ func objectBindingIDTypePermutationClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemType),
-				NewTokenType(lexer.ItemBinding),
+				NewSymbol("OBJECT_BINDING_TYPE_VALUE"),
 				NewSymbol("OBJECT_BINDING_ID"),
 			},
 		},
@@ -849,13 +1471,28 @@ func objectBindingTypeClauses() []*Clause {
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemType),
-				NewTokenType(lexer.ItemBinding),
+				NewSymbol("OBJECT_BINDING_TYPE_VALUE"),
 			},
 		},
 		{},
 	}
 }
 
+func objectBindingTypeValueClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNodeType),
+			},
+		},
+	}
+}
+
 func objectBindingIDClauses() []*Clause {
 	return []*Clause{
 		{
@@ -886,7 +1523,7 @@ func groupByClauses() []*Clause {
 			Elements: []Element{
 				NewTokenType(lexer.ItemGroup),
 				NewTokenType(lexer.ItemBy),
-				NewTokenType(lexer.ItemBinding),
+				NewSymbol("GROUP_BY_BINDING"),
 				NewSymbol("GROUP_BY_BINDINGS"),
 			},
 		},
@@ -894,11 +1531,31 @@ func groupByClauses() []*Clause {
 	}
 }
 
+func groupByBindingClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemBucket),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewSymbol("BUCKET_INTERVAL"),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+	}
+}
+
 func groupByBindingsClauses() []*Clause {
 	return []*Clause{{
 		Elements: []Element{
 			NewTokenType(lexer.ItemComma),
-			NewTokenType(lexer.ItemBinding),
+			NewSymbol("GROUP_BY_BINDING"),
 			NewSymbol("GROUP_BY_BINDINGS"),
 		},
 	},
@@ -910,7 +1567,7 @@ func orderByClauses() []*Clause {
 		Elements: []Element{
 			NewTokenType(lexer.ItemOrder),
 			NewTokenType(lexer.ItemBy),
-			NewTokenType(lexer.ItemBinding),
+			NewSymbol("ORDER_BY_KEY"),
 			NewSymbol("ORDER_BY_DIRECTION"),
 			NewSymbol("ORDER_BY_BINDINGS"),
 		},
@@ -918,25 +1575,118 @@ func orderByClauses() []*Clause {
 		{},
 	}
 }
+func orderByKeyClauses() []*Clause {
+	return []*Clause{{
+		Elements: []Element{
+			NewTokenType(lexer.ItemBinding),
+		},
+	},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemCast),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteralType),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLang),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemKind),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemDatatype),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemStrlen),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemSubstr),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteral),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteral),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+	}
+}
 func orderByDirectionClauses() []*Clause {
 	return []*Clause{{
 		Elements: []Element{
 			NewTokenType(lexer.ItemAsc),
+			NewSymbol("ORDER_BY_NULLS"),
 		},
 	},
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemDesc),
+				NewSymbol("ORDER_BY_NULLS"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemNulls),
+				NewSymbol("ORDER_BY_NULLS_POSITION"),
+			},
+		},
+		{},
+	}
+}
+func orderByNullsClauses() []*Clause {
+	return []*Clause{{
+		Elements: []Element{
+			NewTokenType(lexer.ItemNulls),
+			NewSymbol("ORDER_BY_NULLS_POSITION"),
+		},
+	},
 		{},
 	}
 }
+func orderByNullsPositionClauses() []*Clause {
+	return []*Clause{{
+		Elements: []Element{
+			NewTokenType(lexer.ItemFirst),
+		},
+	},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLast),
+			},
+		},
+	}
+}
 func orderByBindingsClauses() []*Clause {
 	return []*Clause{{
 		Elements: []Element{
 			NewTokenType(lexer.ItemComma),
-			NewTokenType(lexer.ItemBinding),
+			NewSymbol("ORDER_BY_KEY"),
 			NewSymbol("ORDER_BY_DIRECTION"),
 			NewSymbol("ORDER_BY_BINDINGS"),
 		},
@@ -986,6 +1736,17 @@ func havingClauses() []*Clause {
 				NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemCast),
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemBinding),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemLiteralType),
+				NewTokenType(lexer.ItemRPar),
+				NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+			},
+		},
 		{
 			Elements: []Element{
 				NewTokenType(lexer.ItemNot),
@@ -1033,6 +1794,13 @@ func havingClausesBinaryCompositeClauses() []*Clause {
 				NewSymbol("HAVING_CLAUSE"),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemUnder),
+				NewTokenType(lexer.ItemNodeType),
+				NewSymbol("HAVING_CLAUSE_BINARY_COMPOSITE"),
+			},
+		},
 		{},
 	}
 }
@@ -1100,6 +1868,34 @@ func insertDataClauses() []*Clause {
 		{},
 	}
 }
+func deleteStatementClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemData),
+				NewTokenType(lexer.ItemFrom),
+				NewSymbol("INPUT_GRAPHS"),
+				NewTokenType(lexer.ItemLBracket),
+				NewTokenType(lexer.ItemNode),
+				NewTokenType(lexer.ItemPredicate),
+				NewSymbol("DELETE_OBJECT"),
+				NewSymbol("DELETE_DATA"),
+				NewTokenType(lexer.ItemRBracket),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemFrom),
+				NewSymbol("INPUT_GRAPHS"),
+				NewTokenType(lexer.ItemWhere),
+				NewTokenType(lexer.ItemPredicateKeyword),
+				NewTokenType(lexer.ItemAnchor),
+				NewTokenType(lexer.ItemBefore),
+				NewTokenType(lexer.ItemTime),
+			},
+		},
+	}
+}
 func deleteObjectClauses() []*Clause {
 	return []*Clause{{
 		Elements: []Element{
@@ -1282,6 +2078,79 @@ func graphShowClauses() []*Clause {
 				NewTokenType(lexer.ItemGraphs),
 			},
 		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemView),
+				NewTokenType(lexer.ItemBinding),
+			},
+		},
+	}
+}
+
+func mergeConflictsClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemReporting),
+				NewTokenType(lexer.ItemConflicts),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemReplacing),
+				NewTokenType(lexer.ItemConflicts),
+			},
+		},
+	}
+}
+
+func anchorsLookupClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLPar),
+				NewTokenType(lexer.ItemNode),
+				NewTokenType(lexer.ItemComma),
+				NewTokenType(lexer.ItemPredicate),
+				NewTokenType(lexer.ItemRPar),
+			},
+		},
+	}
+}
+
+func killQueryClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemQueryKeyword),
+				NewSymbol("KILL_QUERY_ID"),
+			},
+		},
+	}
+}
+
+func killQueryIDClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemQuotedString),
+			},
+		},
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemLiteral),
+			},
+		},
+	}
+}
+
+func clearStoreClauses() []*Clause {
+	return []*Clause{
+		{
+			Elements: []Element{
+				NewTokenType(lexer.ItemStore),
+			},
+		},
 	}
 }
 
@@ -1290,9 +2159,18 @@ func BQL() *Grammar {
 	return &Grammar{
 		"START":                                  startClauses(),
 		"CREATE_GRAPHS":                          createGraphClauses(),
+		"REFRESH_VIEW":                           refreshViewClauses(),
 		"DROP_GRAPHS":                            dropGraphClauses(),
+		"TRUNCATE_GRAPHS":                        truncateGraphClauses(),
+		"IF_NOT_EXISTS":                          ifNotExistsClauses(),
+		"IF_ABSENT":                              ifAbsentClauses(),
+		"IF_EXISTS":                              ifExistsClauses(),
+		"DUMP_GRAPHS":                            dumpGraphClauses(),
 		"VARS":                                   varsClauses(),
+		"DISTINCT_ROWS":                          distinctRowsClauses(),
 		"COUNT_DISTINCT":                         countDistinctClauses(),
+		"CONCAT_SEPARATOR":                       concatSeparatorClauses(),
+		"HISTOGRAM_INTERVAL":                     histogramIntervalClauses(),
 		"VARS_AS":                                varsAsClauses(),
 		"MORE_VARS":                              moreVarsClauses(),
 		"GRAPHS":                                 graphsClauses(),
@@ -1301,14 +2179,27 @@ func BQL() *Grammar {
 		"MORE_INPUT_GRAPHS":                      moreInputGraphClauses(),
 		"OUTPUT_GRAPHS":                          outputGraphClauses(),
 		"MORE_OUTPUT_GRAPHS":                     moreOutputGraphClauses(),
+		"DECLARE":                                declareClauses(),
+		"DECLARE_STRICT":                         declareStrictClauses(),
+		"MORE_DECLARATIONS":                      moreDeclarationsClauses(),
 		"WHERE":                                  whereClauses(),
+		"QUERY_WHERE":                            queryWhereClauses(),
+		"UNWIND":                                 unwindClauses(),
+		"MORE_UNWIND":                            moreUnwindClauses(),
 		"FIRST_CLAUSE":                           firstClauses(),
+		"MORE_PREDICATE_OBJECT_PAIRS":            morePredicateObjectPairsClauses(),
 		"MORE_CLAUSES":                           moreClauses(),
 		"CLAUSES":                                clauses(),
 		"OPTIONAL_CLAUSE":                        optionalClauses(),
+		"MORE_OPTIONAL_CLAUSES":                  moreOptionalClauses(),
+		"NEXT_OPTIONAL_CLAUSE":                   nextOptionalClause(),
+		"NEGATED_CLAUSE":                         negatedClauses(),
+		"MORE_NEGATED_CLAUSES":                   moreNegatedClauses(),
+		"NEXT_NEGATED_CLAUSE":                    nextNegatedClause(),
 		"FILTER_CLAUSES":                         filterClauses(),
 		"MORE_FILTER_CLAUSES":                    moreFilterClauses(),
 		"MORE_FILTER_ARGUMENTS":                  moreFilterArguments(),
+		"FILTER_VALUE":                           filterValue(),
 		"SUBJECT_EXTRACT":                        subjectExtractClauses(),
 		"SUBJECT_TYPE":                           subjectTypeClauses(),
 		"SUBJECT_ID":                             subjectIDClauses(),
@@ -1316,6 +2207,7 @@ func BQL() *Grammar {
 		"PREDICATE":                              predicateClauses(),
 		"PREDICATE_AS":                           predicateAsClauses(),
 		"PREDICATE_ID":                           predicateIDClauses(),
+		"PREDICATE_ID_VALUE":                     predicateIDValueClauses(),
 		"PREDICATE_AT":                           predicateAtClauses(),
 		"PREDICATE_BOUND_AT":                     predicateBoundAtClauses(),
 		"PREDICATE_BOUND_AT_BINDINGS":            predicateBoundAtBindingsClauses(),
@@ -1332,15 +2224,24 @@ func BQL() *Grammar {
 		"OBJECT_PREDICATE_BOUND_AT_BINDINGS":     objectPredicateBoundAtBindingsClauses(),
 		"OBJECT_PREDICATE_BOUND_AT_BINDINGS_END": objectPredicateBoundAtBindingsEndClauses(),
 		"OBJECT_LITERAL_AS":                      objectLiteralAsClauses(),
+		"OBJECT_GUARD":                           objectGuardClauses(),
+		"OBJECT_LIMIT":                           objectLimitClauses(),
+		"OBJECT_GUARD_COMPARATOR":                objectGuardComparatorClauses(),
 		"OBJECT_BINDING_EXTRACT":                 objectBindingExtractClauses(),
 		"OBJECT_BINDING_TYPE":                    objectBindingTypeClauses(),
+		"OBJECT_BINDING_TYPE_VALUE":              objectBindingTypeValueClauses(),
 		"OBJECT_BINDING_ID":                      objectBindingIDClauses(),
 		"OBJECT_BINDING_ID_TYPE_PERMUTATION":     objectBindingIDTypePermutationClauses(),
 		"OBJECT_BINDING_AT":                      objectBindingAtClauses(),
 		"GROUP_BY":                               groupByClauses(),
+		"GROUP_BY_BINDING":                       groupByBindingClauses(),
 		"GROUP_BY_BINDINGS":                      groupByBindingsClauses(),
+		"BUCKET_INTERVAL":                        histogramIntervalClauses(),
 		"ORDER_BY":                               orderByClauses(),
+		"ORDER_BY_KEY":                           orderByKeyClauses(),
 		"ORDER_BY_DIRECTION":                     orderByDirectionClauses(),
+		"ORDER_BY_NULLS":                         orderByNullsClauses(),
+		"ORDER_BY_NULLS_POSITION":                orderByNullsPositionClauses(),
 		"ORDER_BY_BINDINGS":                      orderByBindingsClauses(),
 		"HAVING":                                 topHavingClauses(),
 		"HAVING_CLAUSE":                          havingClauses(),
@@ -1349,6 +2250,7 @@ func BQL() *Grammar {
 		"LIMIT":                                  limitClauses(),
 		"INSERT_OBJECT":                          insertObjectClauses(),
 		"INSERT_DATA":                            insertDataClauses(),
+		"DELETE_STATEMENT":                       deleteStatementClauses(),
 		"DELETE_OBJECT":                          deleteObjectClauses(),
 		"DELETE_DATA":                            deleteDataClauses(),
 		"CONSTRUCT_FACTS":                        constructFactsClauses(),
@@ -1361,20 +2263,36 @@ func BQL() *Grammar {
 		"DECONSTRUCT_TRIPLES":                    deconstructTriplesClauses(),
 		"MORE_DECONSTRUCT_TRIPLES":               moreDeconstructTriplesClauses(),
 		"GRAPH_SHOW":                             graphShowClauses(),
+		"CLEAR_STORE":                            clearStoreClauses(),
+		"MERGE_CONFLICTS":                        mergeConflictsClauses(),
+		"ANCHORS_LOOKUP":                         anchorsLookupClauses(),
+		"KILL_QUERY":                             killQueryClauses(),
+		"KILL_QUERY_ID":                          killQueryIDClauses(),
 	}
 }
 
 func setClauseHook(g *Grammar, symbols []semantic.Symbol, start, end semantic.ClauseHook) {
+	setClauseHookIf(g, symbols, start, end, nil)
+}
+
+func setClauseHookIf(g *Grammar, symbols []semantic.Symbol, start, end semantic.ClauseHook, cnd condition) {
 	for _, sym := range symbols {
 		for _, cls := range (*g)[sym] {
-			cls.ProcessStart = start
-			cls.ProcessEnd = end
+			if cnd == nil || cnd(cls) {
+				cls.ProcessStart = start
+				cls.ProcessEnd = end
+			}
 		}
 	}
 }
 
 type condition func(*Clause) bool
 
+// viewClause returns true for the clauses operating on a materialized view.
+func viewClause(cls *Clause) bool {
+	return len(cls.Elements) > 0 && cls.Elements[0].Token() == lexer.ItemView
+}
+
 func setElementHook(g *Grammar, symbols []semantic.Symbol, hook semantic.ElementHook, cnd condition) {
 	for _, sym := range symbols {
 		for _, cls := range (*g)[sym] {
@@ -1393,9 +2311,15 @@ func SemanticBQL() *Grammar {
 	// Create and Drop semantic hooks for type.
 	setClauseHook(semanticBQL, []semantic.Symbol{"CREATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Create))
 	setClauseHook(semanticBQL, []semantic.Symbol{"DROP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Drop))
+	setClauseHook(semanticBQL, []semantic.Symbol{"TRUNCATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Truncate))
+	setClauseHook(semanticBQL, []semantic.Symbol{"DUMP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.Dump))
+	setClauseHook(semanticBQL, []semantic.Symbol{"IF_NOT_EXISTS"}, nil, semantic.IfNotExistsClauseHook())
+	setClauseHook(semanticBQL, []semantic.Symbol{"IF_ABSENT"}, nil, semantic.IfAbsentClauseHook())
+	setClauseHook(semanticBQL, []semantic.Symbol{"IF_EXISTS"}, nil, semantic.IfExistsClauseHook())
+	setClauseHook(semanticBQL, []semantic.Symbol{"DISTINCT_ROWS"}, nil, semantic.DistinctClauseHook())
 
 	// Add graph binding collection to GRAPHS and MORE_GRAPHS clauses.
-	graphSymbols := []semantic.Symbol{"GRAPHS", "MORE_GRAPHS"}
+	graphSymbols := []semantic.Symbol{"GRAPHS", "MORE_GRAPHS", "DUMP_GRAPHS"}
 	setElementHook(semanticBQL, graphSymbols, semantic.GraphAccumulatorHook(), nil)
 
 	// Add graph binding collection to INPUT_GRAPHS and MORE_INPUT_GRAPHS clauses.
@@ -1413,22 +2337,37 @@ func SemanticBQL() *Grammar {
 	setElementHook(semanticBQL, insertSymbols, dataAcc, nil)
 	setClauseHook(semanticBQL, []semantic.Symbol{"INSERT_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Insert))
 	setClauseHook(semanticBQL, []semantic.Symbol{"DELETE_OBJECT"}, nil, semantic.TypeBindingClauseHook(semantic.Delete))
+	setElementHook(semanticBQL, []semantic.Symbol{"DELETE_STATEMENT"}, dataAcc,
+		func(cls *Clause) bool {
+			return cls.Elements[0].Token() == lexer.ItemData
+		})
+	setElementHook(semanticBQL, []semantic.Symbol{"DELETE_STATEMENT"}, semantic.AnchorCutoffCollection(),
+		func(cls *Clause) bool {
+			return cls.Elements[0].Token() == lexer.ItemFrom
+		})
 
 	// Query semantic hooks.
-	setClauseHook(semanticBQL, []semantic.Symbol{"WHERE"}, semantic.WhereInitWorkingClauseHook(), semantic.VarBindingsGraphChecker())
+	declareSymbols := []semantic.Symbol{"DECLARE", "DECLARE_STRICT", "MORE_DECLARATIONS"}
+	setElementHook(semanticBQL, declareSymbols, semantic.BindingTypeDeclarations(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"WHERE", "QUERY_WHERE"}, semantic.WhereInitWorkingClauseHook(), semantic.VarBindingsGraphChecker())
+	setElementHook(semanticBQL, []semantic.Symbol{"START"}, semantic.DefaultGraphPattern(),
+		func(cls *Clause) bool {
+			return cls.Elements[0].Token() == lexer.ItemQuery
+		})
 
 	clauseSymbols := []semantic.Symbol{
-		"FIRST_CLAUSE", "CLAUSES", "MORE_CLAUSES",
+		"FIRST_CLAUSE", "CLAUSES", "MORE_CLAUSES", "NEXT_OPTIONAL_CLAUSE", "NEXT_NEGATED_CLAUSE",
 	}
 	setClauseHook(semanticBQL, clauseSymbols, semantic.WhereNextWorkingClauseHook(), semantic.WhereNextWorkingClauseHook())
+	setClauseHook(semanticBQL, []semantic.Symbol{"MORE_PREDICATE_OBJECT_PAIRS"}, semantic.WhereNextPredicateObjectPairClauseHook(), nil)
 
 	subSymbols := []semantic.Symbol{
-		"FIRST_CLAUSE", "CLAUSES", "OPTIONAL_CLAUSE", "SUBJECT_EXTRACT", "SUBJECT_TYPE", "SUBJECT_ID", "SUBJECT_ID_TYPE_PERMUTATION",
+		"FIRST_CLAUSE", "CLAUSES", "OPTIONAL_CLAUSE", "NEXT_OPTIONAL_CLAUSE", "NEGATED_CLAUSE", "NEXT_NEGATED_CLAUSE", "SUBJECT_EXTRACT", "SUBJECT_TYPE", "SUBJECT_ID", "SUBJECT_ID_TYPE_PERMUTATION",
 	}
 	setElementHook(semanticBQL, subSymbols, semantic.WhereSubjectClauseHook(), nil)
 
 	predSymbols := []semantic.Symbol{
-		"PREDICATE", "PREDICATE_AS", "PREDICATE_ID", "PREDICATE_AT",
+		"PREDICATE", "PREDICATE_AS", "PREDICATE_ID", "PREDICATE_ID_VALUE", "PREDICATE_AT",
 		"PREDICATE_BOUND_AT", "PREDICATE_BOUND_AT_BINDINGS", "PREDICATE_BOUND_AT_BINDINGS_END",
 	}
 	setElementHook(semanticBQL, predSymbols, semantic.WherePredicateClauseHook(), nil)
@@ -1438,30 +2377,38 @@ func SemanticBQL() *Grammar {
 		"OBJECT_NODE_ID_TYPE_PERMUTATION", "OBJECT_PREDICATE_AS", "OBJECT_PREDICATE_ID", "OBJECT_PREDICATE_AT",
 		"OBJECT_PREDICATE_BOUND_AT", "OBJECT_PREDICATE_BOUND_AT_BINDINGS",
 		"OBJECT_PREDICATE_BOUND_AT_BINDINGS_END", "OBJECT_LITERAL_AS",
-		"OBJECT_BINDING_EXTRACT", "OBJECT_BINDING_TYPE",
+		"OBJECT_BINDING_EXTRACT", "OBJECT_BINDING_TYPE", "OBJECT_BINDING_TYPE_VALUE",
 		"OBJECT_BINDING_ID", "OBJECT_BINDING_ID_TYPE_PERMUTATION", "OBJECT_BINDING_AT",
 	}
 	setElementHook(semanticBQL, objSymbols, semantic.WhereObjectClauseHook(), nil)
+	guardSymbols := []semantic.Symbol{"OBJECT_GUARD", "OBJECT_GUARD_COMPARATOR"}
+	setElementHook(semanticBQL, guardSymbols, semantic.WhereGuardClauseHook(), nil)
+	setElementHook(semanticBQL, []semantic.Symbol{"OBJECT_LIMIT"}, semantic.ClauseLimitHook(), nil)
 
 	// Filter clause hook.
 	filterSymbols := []semantic.Symbol{
-		"FILTER_CLAUSES", "MORE_FILTER_ARGUMENTS",
+		"FILTER_CLAUSES", "MORE_FILTER_ARGUMENTS", "FILTER_VALUE",
 	}
 	setElementHook(semanticBQL, filterSymbols, semantic.WhereFilterClauseHook(), nil)
 
 	// Collect binding variables variables.
 	varSymbols := []semantic.Symbol{
-		"VARS", "VARS_AS", "MORE_VARS", "COUNT_DISTINCT",
+		"VARS", "VARS_AS", "MORE_VARS", "COUNT_DISTINCT", "CONCAT_SEPARATOR", "HISTOGRAM_INTERVAL",
 	}
 	setElementHook(semanticBQL, varSymbols, semantic.VarAccumulatorHook(), nil)
 
+	// Collect and validate unwind bindings.
+	unwindSymbols := []semantic.Symbol{"UNWIND", "MORE_UNWIND"}
+	setElementHook(semanticBQL, unwindSymbols, semantic.UnwindCollection(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"UNWIND"}, nil, semantic.UnwindBindingsChecker())
+
 	// Collect and validate group by bindings.
-	grpSymbols := []semantic.Symbol{"GROUP_BY", "GROUP_BY_BINDINGS"}
+	grpSymbols := []semantic.Symbol{"GROUP_BY", "GROUP_BY_BINDING", "GROUP_BY_BINDINGS", "BUCKET_INTERVAL"}
 	setElementHook(semanticBQL, grpSymbols, semantic.GroupByBindings(), nil)
 	setClauseHook(semanticBQL, []semantic.Symbol{"GROUP_BY"}, nil, semantic.GroupByBindingsChecker())
 
 	// Collect and validate order by bindings.
-	ordSymbols := []semantic.Symbol{"ORDER_BY", "ORDER_BY_DIRECTION", "ORDER_BY_BINDINGS"}
+	ordSymbols := []semantic.Symbol{"ORDER_BY", "ORDER_BY_KEY", "ORDER_BY_DIRECTION", "ORDER_BY_NULLS", "ORDER_BY_NULLS_POSITION", "ORDER_BY_BINDINGS"}
 	setElementHook(semanticBQL, ordSymbols, semantic.OrderByBindings(), nil)
 	setClauseHook(semanticBQL, []semantic.Symbol{"ORDER_BY"}, nil, semantic.OrderByBindingsChecker())
 
@@ -1482,13 +2429,16 @@ func SemanticBQL() *Grammar {
 	// Global data accumulator hook.
 	setElementHook(semanticBQL, []semantic.Symbol{"START"}, dataAcc,
 		func(cls *Clause) bool {
-			if t := cls.Elements[0].Token(); t != lexer.ItemInsert && t != lexer.ItemDelete {
-				return false
-			}
-			return true
+			return cls.Elements[0].Token() == lexer.ItemInsert
 		})
 	setClauseHook(semanticBQL, []semantic.Symbol{"START"}, nil, semantic.GroupByBindingsChecker())
 
+	// TRACE wrapper semantic hook.
+	setElementHook(semanticBQL, []semantic.Symbol{"START"}, semantic.TraceLevelCollection(),
+		func(cls *Clause) bool {
+			return cls.Elements[0].Token() == lexer.ItemTrace
+		})
+
 	// CONSTRUCT and DECONSTRUCT clauses semantic hooks.
 	setClauseHook(semanticBQL, []semantic.Symbol{"CONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Construct))
 	setClauseHook(semanticBQL, []semantic.Symbol{"DECONSTRUCT_FACTS"}, semantic.InitWorkingConstructClauseHook(), semantic.TypeBindingClauseHook(semantic.Deconstruct))
@@ -1503,6 +2453,54 @@ func SemanticBQL() *Grammar {
 
 	// SHOW GRAPHS clause semantic hooks.
 	setClauseHook(semanticBQL, []semantic.Symbol{"GRAPH_SHOW"}, nil, semantic.ShowClauseHook())
+	setClauseHook(semanticBQL, []semantic.Symbol{"CLEAR_STORE"}, nil, semantic.TypeBindingClauseHook(semantic.Clear))
+
+	// View semantic hooks. They replace the graph ones set above for the
+	// clauses operating on views.
+	viewSymbols := []semantic.Symbol{"CREATE_GRAPHS", "REFRESH_VIEW", "DROP_GRAPHS", "GRAPH_SHOW"}
+	setElementHook(semanticBQL, viewSymbols, semantic.ViewNameCollection(), viewClause)
+	setClauseHookIf(semanticBQL, []semantic.Symbol{"CREATE_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.CreateView), viewClause)
+	setClauseHook(semanticBQL, []semantic.Symbol{"REFRESH_VIEW"}, nil, semantic.TypeBindingClauseHook(semantic.RefreshView))
+	setClauseHookIf(semanticBQL, []semantic.Symbol{"DROP_GRAPHS"}, nil, semantic.TypeBindingClauseHook(semantic.DropView), viewClause)
+	setClauseHookIf(semanticBQL, []semantic.Symbol{"GRAPH_SHOW"}, nil, semantic.TypeBindingClauseHook(semantic.ShowView), viewClause)
+	setClauseHook(semanticBQL, []semantic.Symbol{"MERGE_CONFLICTS"}, nil, semantic.TypeBindingClauseHook(semantic.Merge))
+	setElementHook(semanticBQL, []semantic.Symbol{"MERGE_CONFLICTS"}, semantic.MergeConflictsCollection(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"ANCHORS_LOOKUP"}, nil, semantic.TypeBindingClauseHook(semantic.Anchors))
+	setElementHook(semanticBQL, []semantic.Symbol{"ANCHORS_LOOKUP"}, semantic.AnchorsLookupHook(), nil)
+	setClauseHook(semanticBQL, []semantic.Symbol{"KILL_QUERY"}, nil, semantic.TypeBindingClauseHook(semantic.Kill))
+	setElementHook(semanticBQL, []semantic.Symbol{"KILL_QUERY", "KILL_QUERY_ID"}, semantic.KillQueryIDCollection(), nil)
 
 	return semanticBQL
 }
+
+// Validate parses the provided BQL statement and runs all the semantic checks
+// on it without requiring a store, which makes it suitable to check statements
+// as they are edited. It also checks that the bindings used by FILTER
+// functions exist in the graph pattern and that grouped HAVING clauses only use
+// the projected bindings. The first semantic error found is
+// returned as a *SemanticError carrying its position; statements that are not
+// valid BQL return the parsing error instead.
+func Validate(bql string) error {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		return err
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(bql, 1), st); err != nil {
+		var se *SemanticError
+		if errors.As(err, &se) {
+			return se
+		}
+		return err
+	}
+	if err := st.CheckHavingScope(); err != nil {
+		return err
+	}
+	bm := st.BindingsMap()
+	for _, f := range st.FilterClauses() {
+		if _, ok := bm[f.Binding]; !ok {
+			return fmt.Errorf("binding %q referenced by filter clause %q does not exist in the graph pattern", f.Binding, f)
+		}
+	}
+	return nil
+}
@@ -16,9 +16,13 @@ package grammar
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/triple/literal"
 )
 
 func TestAcceptByParse(t *testing.T) {
@@ -27,6 +31,15 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p ?o};`,
 		`select ?a, ?b from ?c where{?s ?p ?o};`,
 		`select ?a, ?b, ?c from ?d where{?s ?p ?o};`,
+		// Test all bindings can be projected and the where clause omitted.
+		`select * from ?a;`,
+		`select * from ?a where{?s ?p ?o};`,
+		`select ?s, ?o from ?a;`,
+		`select * from ?a order by ?s limit "10"^^type:int64;`,
+		// Test repeated rows can be dropped.
+		`select distinct ?o from ?a where{?s ?p ?o};`,
+		`select distinct * from ?a;`,
+		`select distinct ?s, count(?o) as ?n from ?a where{?s ?p ?o} group by ?s;`,
 		// Test aliases and functions.
 		`select ?a as ?b from ?c where{?s ?p ?o};`,
 		`select ?a as ?b, ?c as ?d from ?e where{?s ?p ?o};`,
@@ -45,8 +58,13 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p as ?x ?o};`,
 		`select ?a from ?b where{?s ?p as ?x id ?y ?o};`,
 		`select ?a from ?b where{?s ?p as ?x id ?y at ?z ?o};`,
+		`select ?a from ?b where{?s ?p id "bought" ?o};`,
+		`select ?a from ?b where{?s ?p as ?x id "bought" at ?z ?o};`,
 		`select ?a from ?b where{?s ?p ?o as ?x};`,
 		`select ?a from ?b where{?s ?p ?o as ?x type ?y};`,
+		`select ?a from ?b where{?s ?p ?o type /u};`,
+		`select ?a from ?b where{?s ?p ?o as ?x type /item/book id ?y at ?z};`,
+		`select ?a from ?b where{?s ?p ?o id ?y type /u . ?o ?q ?r};`,
 		`select ?a from ?b where{?s ?p ?o as ?x type ?y id ?z};`,
 		`select ?a from ?b where{?s ?p ?o as ?x id ?y type ?z};`,
 		`select ?a from ?b where{?s ?p ?o as ?x type ?y id ?z at ?t};`,
@@ -74,16 +92,65 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p ?o};`,
 		`select ?a from ?b where{?s ?p ?o . ?s ?p ?o};`,
 		`select ?a from ?b where{?s ?p ?o . ?s ?p ?o . ?s ?p ?o};`,
+		// Test multiple predicate-object pairs sharing a subject.
+		`select ?a from ?b where{?s ?p ?o; ?q ?r};`,
+		`select ?a from ?b where{?s ?p ?o; ?q ?r; ?t ?u};`,
+		`select ?a from ?b where{?s ?p ?o; ?q ?r . ?x ?y ?z};`,
+		`select ?a from ?b where{/_<foo> "foo"@[] ?o; "bar"@[,] as ?x ?r};`,
+		// Test unwind clauses.
+		`select ?i from ?b where{?s ?p ?l} unwind ?l as ?i;`,
+		`select ?i, ?j from ?b where{?s ?p ?l. ?s ?q ?m} unwind ?l as ?i, ?m as ?j;`,
+		`select ?i from ?b where{?s ?p ?l} unwind ?l as ?i group by ?i;`,
+		`select ?a from ?b where{?s ?p "["a"^^type:text, "1"^^type:int64]"^^type:list};`,
+		// Test cast functions.
+		`select cast(?o, type:int64) as ?n from ?b where{?s ?p ?o};`,
+		`select ?s, cast(?o, type:text) as ?t, ?p from ?b where{?s ?p ?o};`,
+		`select ?s from ?b where{?s ?p ?o} having cast(?o, type:int64) > "3"^^type:int64;`,
+		`select ?s from ?b where{?s ?p ?o} having ?s = cast(?o, type:text) and cast(?o, type:float64) < "3"^^type:float64;`,
+		// Test language tags.
+		`select lang(?o) as ?l from ?b where{?s ?p ?o};`,
+		`select ?s, lang(?o) as ?l, ?o from ?b where{?s ?p ?o} having ?o = "hola"^^type:text@es;`,
+		// Test the kind of predicates.
+		`select ?p, kind(?p) as ?kind from ?b where{?s ?p ?o};`,
+		`select ?s, ?o, group_index() as ?idx from ?b where{?s ?p ?o} group by ?s;`,
+		`select histogram(?t, "1mo") from ?b where{?s ?p ?o};`,
+		`select ?o, datatype(?o) as ?dt from ?b where{?s ?p ?o} order by datatype(?o);`,
+		`select ?s, histogram(?t, "1h"^^type:text) from ?b where{?s ?p ?o} group by ?s;`,
+		`select strlen(?o) as ?n, substr(?o, "0"^^type:int64, "3"^^type:int64) as ?sub from ?b where{?s ?p ?o};`,
+		// Test graph name projections.
+		`select ?s, graph() as ?g from ?a, ?b where{?s ?p ?o};`,
+		`select graph() as ?g, ?s from ?a where{?s ?p ?o} order by ?g;`,
+		`select ?s, ?p, ?o, graph_count() as ?gc from ?a, ?b where{?s ?p ?o} group by ?s, ?p, ?o;`,
+		// Test triple projections.
+		`select triple(?s, ?p, ?o) as ?t from ?b where{?s ?p ?o};`,
+		`select ?s from ?b where{?s ?p "hola"^^type:text@es};`,
+		// Test comments.
+		`# Find all the subjects.
+		 select ?s /* the subject */ from ?b where {
+			?s ?p ?o . # any triple
+			/* another
+			   triple */
+			?s ?q ?r
+		 };`,
 		// Test group by.
 		`select ?a from ?b where{?s ?p ?o} group by ?a;`,
 		`select ?a from ?b where{?s ?p ?o} group by ?a, ?b;`,
 		`select ?a from ?b where{?s ?p ?o} group by ?a, ?b, ?c;`,
+		`select ?a from ?b where{?s ?p ?o} group by bucket(?a, "1h"^^type:text);`,
+		`select ?a from ?b where{?s ?p ?o} group by ?a, bucket(?b, "1d"^^type:text), ?c;`,
+		`select ?a from ?b where{?s ?p ?o} group by bucket(?a, "1h");`,
+		`select ?a from ?b where{?s ?p ?o} group by ?a, bucket(?b, "1d"), ?c;`,
 		// Test order by.
 		`select ?a from ?b where{?s ?p ?o} order by ?a;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a asc;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a desc;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a asc, ?b desc;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a desc, ?b desc, ?c asc;`,
+		`select ?a from ?b where{?s ?p ?o} order by ?a asc nulls last;`,
+		`select ?a from ?b where{?s ?p ?o} order by ?a desc nulls first, ?b nulls last;`,
+		`select ?a from ?b where{?s ?p ?o} order by strlen(?a) desc, ?a asc;`,
+		`select ?a from ?b where{?s ?p ?o} order by lang(?a), kind(?p) nulls last;`,
+		`select ?a from ?b where{?s ?p ?o} order by cast(?a, type:int64), substr(?a, "0"^^type:int64, "2"^^type:int64) desc;`,
 		// Test having clause.
 		`select ?a from ?b where {?a ?p ?o} having not ?b;`,
 		`select ?a from ?b where {?a ?p ?o} having (not ?b);`,
@@ -101,6 +168,8 @@ func TestAcceptByParse(t *testing.T) {
 		`select ?a from ?b where {?a ?p ?o} having (?b and ?b) or not (?b = ?b);`,
 		`select ?a from ?b where {?a ?p ?o} having ((?b and ?b) or not (?b = ?b));`,
 		`select ?a from ?b where {?a ?p ?o} having ?b = "foo"@[];`,
+		`select ?a from ?b where {?a ?p ?o} having ?a under /item;`,
+		`select ?a from ?b where {?a ?p ?o} having ?a under /item/book and not(?o under /room);`,
 		`select ?a from ?b where {?a ?p ?o} having ?b = "foo"@[2016-04-01T00:00:00-08:00];`,
 		// Test global time bounds.
 		`select ?a from ?b where {?s ?p ?o} before 2006-01-01T15:04:05.999999999Z07:00;`,
@@ -118,12 +187,33 @@ func TestAcceptByParse(t *testing.T) {
 			optional {?x ?w ?z } .
 			optional {?x ?w ?z }
 		};`,
+		`select ?a from ?b where {
+			?s ?p ?o .
+			optional {?x ?w ?z . ?z ?v ?y}
+		};`,
+		`select ?a from ?b where {
+			?s ?p ?o .
+			optional {?x ?w ?z . ?z ?v ?y .}
+		};`,
+		// Test negated blocks.
+		`select ?s from ?b where {
+			?s ?p ?o .
+			not {?s "is_a"@[] ?t}
+		};`,
+		`select ?s from ?b where {
+			?s ?p ?o .
+			not {?s "is_a"@[] ?t . ?t "is_a"@[] /t<car> .} .
+			optional {?s ?w ?z}
+		};`,
 		// Insert data.
 		`insert data into ?a {/_<foo> "bar"@["1234"] /_<foo>};`,
 		`insert data into ?a {/_<foo> "bar"@["1234"] "bar"@["1234"]};`,
 		`insert data into ?a {/_<foo> "bar"@["1234"] "yeah"^^type:text};`,
 		// Insert into multiple graphs.
 		`insert data into ?a,?b,?c {/_<foo> "bar"@["1234"] /_<foo>};`,
+		// Insert only the absent data.
+		`insert data into ?a if absent {/_<foo> "bar"@["1234"] /_<foo>};`,
+		`insert data into ?a,?b IF ABSENT {/_<foo> "bar"@["1234"] /_<foo>};`,
 		// Insert multiple data.
 		`insert data into ?a {/_<foo> "bar"@["1234"] /_<foo> .
 		                      /_<foo> "bar"@["1234"] "bar"@["1234"] .
@@ -138,12 +228,17 @@ func TestAcceptByParse(t *testing.T) {
 		`delete data from ?a {/_<foo> "bar"@["1234"] /_<foo> .
 										      /_<foo> "bar"@["1234"] "bar"@["1234"] .
 													/_<foo> "bar"@["1234"] "yeah"^^type:text};`,
+		// Delete temporal triples by anchor.
+		`delete from ?a where predicate anchor before 2015-01-01T00:00:00Z;`,
+		`delete from ?a, ?b where predicate anchor before 2015-01-01T00:00:00Z;`,
 		// Create graphs.
 		`create graph ?a;`,
 		`create graph ?a, ?b, ?c;`,
+		`create graph if not exists ?a, ?b;`,
 		// Drop graphs.
 		`drop graph ?a;`,
 		`drop graph ?a, ?b, ?c;`,
+		`drop graph if exists ?a, ?b;`,
 		// Issue 39 (https://github.com/google/badwolf/issues/39)
 		`insert data into ?world {/room<000> "named"@[] "Hallway"^^type:text.
 		                          /room<000> "connects_to"@[] /room<001>};`,
@@ -194,6 +289,32 @@ func TestAcceptByParse(t *testing.T) {
 			?n "_object"@[] ?o};`,
 		// Show the graphs.
 		`show graphs;`,
+		// Dump graphs.
+		`dump ?a;`,
+		`dump ?a, ?b;`,
+		// Test clear store.
+		`clear store;`,
+		// Truncate graphs.
+		`truncate graph ?a;`,
+		`truncate graph ?a, ?b;`,
+		// Merge graphs.
+		`merge ?a into ?b reporting conflicts;`,
+		`merge ?a, ?b into ?c, ?d reporting conflicts;`,
+		`merge ?a into ?b replacing conflicts;`,
+		// List predicate anchors.
+		`anchors(/u<peter>, "bought"@[?t]) from ?g;`,
+		`anchors(/u<peter>, "bought"@[?t]) from ?g, ?h;`,
+		// Kill a running query.
+		`kill query "q1"^^type:text;`,
+		`KILL QUERY "q1"^^type:text;`,
+		`kill query "q1";`,
+		`KILL QUERY "q1";`,
+		// Test view statements.
+		`create view ?v as select ?s from ?g where {?s ?p ?o};`,
+		`CREATE VIEW ?v AS SELECT ?s, count(?o) as ?n FROM ?g WHERE {?s ?p ?o} GROUP BY ?s ORDER BY ?n DESC LIMIT "10"^^type:int64;`,
+		`refresh view ?v;`,
+		`drop view ?v;`,
+		`show view ?v;`,
 		// Test FILTER clause inside WHERE.
 		`select ?a
 		 from ?b
@@ -278,14 +399,65 @@ func TestRejectByParse(t *testing.T) {
 	table := []string{
 		// Reject missing comas on var bindings or missing bindings.
 		`select ?a ?wrong from ?b;`,
+		`select *, ?a from ?b;`,
+		`select * as ?a from ?b;`,
+		`select * ?a from ?b;`,
+		`select distinct distinct ?a from ?b;`,
+		`select distinct from ?b;`,
+		`select ?a distinct from ?b;`,
+		// Reject graph ops without a where clause.
+		`construct {?s ?p ?o} into ?a from ?b;`,
 		`select ?a , from ?b;`,
 		`select ?a as from ?b;`,
 		`select ?a as ?b, from ?b;`,
 		`select count(?a as ?b, from ?b;`,
+		`select group_concat(?a, ", ") from ?b where{?s ?p ?a};`,
+		`select group_concat(?a, ", "^^type:text) as ?c from ?b where{?s ?p ?a};`,
+		`select group_concat(?a ", ") as ?c from ?b where{?s ?p ?a};`,
 		`select count(distinct) as ?a, from ?c;`,
 		// Reject missing comas on var bindings or missing graphs.
 		`select ?a from ?b ?c;`,
 		`select ?a from ?b,;`,
+		// Reject dumps without graphs.
+		`dump;`,
+		`dump ?a ?b;`,
+		`dump ?a,;`,
+		// Reject incomplete clear store statements.
+		`clear;`,
+		`clear store ?a;`,
+		`clear ?a;`,
+		// Reject incomplete truncate statements.
+		`truncate;`,
+		`truncate ?a;`,
+		`truncate graph ;`,
+		`truncate graph if exists ?a;`,
+		// Reject incomplete delete by anchor statements.
+		`delete from ?a where predicate anchor before;`,
+		`delete from ?a where predicate anchor after 2015-01-01T00:00:00Z;`,
+		`delete from ?a predicate anchor before 2015-01-01T00:00:00Z;`,
+		`delete from where predicate anchor before 2015-01-01T00:00:00Z;`,
+		// Reject incomplete merge statements.
+		`merge ?a into ?b;`,
+		`merge ?a reporting conflicts;`,
+		`merge into ?b reporting conflicts;`,
+		`merge ?a into ?b reporting;`,
+		`merge ?a into ?b replacing;`,
+		`merge ?a into ?b replacing reporting conflicts;`,
+		// Reject incomplete anchors statements.
+		`anchors(/u<peter>, "bought"@[?t]);`,
+		`anchors(/u<peter>) from ?g;`,
+		`anchors /u<peter>, "bought"@[?t] from ?g;`,
+		// Reject incomplete kill statements.
+		`kill query;`,
+		`kill "q1"^^type:text;`,
+		`kill query "q1"^^type:text`,
+		`kill query "q1"`,
+		// Reject incomplete view statements.
+		`create view ?v select ?s from ?g where {?s ?p ?o};`,
+		`create view ?v as insert data into ?g {/u<a> "p"@[] /u<b>};`,
+		`refresh view;`,
+		`drop view ?v, ?w;`,
+		`show view;`,
 		// Reject empty where clause.
 		`select ?a from ?b where{};`,
 		// Reject incomplete empty where clause.
@@ -294,12 +466,48 @@ func TestRejectByParse(t *testing.T) {
 		// Reject incomplete clauses.
 		`select ?a from ?b where {?s ?p};`,
 		`select ?a from ?b where {?s ?p ?o . ?};`,
+		`select ?a from ?b where {?s ?p ?o ;};`,
+		`select ?a from ?b where {?s ?p ?o ; ?q};`,
+		// Reject incomplete unwind clauses.
+		`select ?a from ?b where {?s ?p ?o} unwind;`,
+		`select ?a from ?b where {?s ?p ?o} unwind ?o;`,
+		`select ?a from ?b where {?s ?p ?o} unwind ?o as;`,
+		`select ?a from ?b where {?s ?p ?o} unwind ?o as ?a,;`,
+		// Reject incomplete cast functions.
+		`select cast(?o) as ?n from ?b where{?s ?p ?o};`,
+		`select cast(?o, type:int64) from ?b where{?s ?p ?o};`,
+		`select cast(?o, int64) as ?n from ?b where{?s ?p ?o};`,
+		`select ?s from ?b where{?s ?p ?o} having cast(?o, type:int64 > "3"^^type:int64;`,
+		// Reject incomplete lang functions.
+		`select lang(?o) from ?b where{?s ?p ?o};`,
+		`select lang(?o, ?p) as ?l from ?b where{?s ?p ?o};`,
+		`select kind(?p) from ?b where{?s ?p ?o};`,
+		`select kind() as ?k from ?b where{?s ?p ?o};`,
+		`select group_index(?s) as ?idx from ?b where{?s ?p ?o};`,
+		`select group_index() from ?b where{?s ?p ?o};`,
+		`select histogram(?t) from ?b where{?s ?p ?o};`,
+		`select datatype(?o) from ?b where{?s ?p ?o};`,
+		`select datatype(?o, ?p) as ?dt from ?b where{?s ?p ?o};`,
+		`select histogram(?t, "1mo") as ?h from ?b where{?s ?p ?o};`,
+		`select histogram(?t, ?i) from ?b where{?s ?p ?o};`,
+		`select strlen(?o) from ?b where{?s ?p ?o};`,
+		`select substr(?o, "1"^^type:int64) as ?sub from ?b where{?s ?p ?o};`,
+		`select substr(?o, ?i, "1"^^type:int64) as ?sub from ?b where{?s ?p ?o};`,
+		`select graph() from ?b where{?s ?p ?o};`,
+		`select graph(?s) as ?g from ?b where{?s ?p ?o};`,
+		`select graph_count() from ?b where{?s ?p ?o};`,
+		`select graph_count(?s) as ?gc from ?b where{?s ?p ?o};`,
+		`select triple(?s, ?p, ?o) from ?b where{?s ?p ?o};`,
+		`select triple(?s, ?p) as ?t from ?b where{?s ?p ?o};`,
 		// Reject incomplete clause aliasing.
 		`select ?a from ?b where {?s id ?b as ?c ?d ?o};`,
 		`select ?a from ?b where {?s ?p at ?t as ?a ?o};`,
 		`select ?a from ?b where {?s ?p ?o at ?t id ?i};`,
 		// Reject incomplete group by.
 		`select ?a from ?b where{?s ?p ?o} group by;`,
+		`select ?a from ?b where{?s ?p ?o} group by bucket(?a);`,
+		`select ?a from ?b where{?s ?p ?o} group by bucket(?a, ?b);`,
+		`select ?a from ?b where{?s ?p ?o} group by bucket("1h"^^type:text);`,
 		`select ?a from ?b where{?s ?p ?o} group ?a;`,
 		`select ?a from ?b where{?s ?p ?o} by ?a;`,
 		// Reject incomplete order by.
@@ -308,6 +516,12 @@ func TestRejectByParse(t *testing.T) {
 		`select ?a from ?b where{?s ?p ?o} by ?a;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a, a;`,
 		`select ?a from ?b where{?s ?p ?o} order by ?a, ?b, desc;`,
+		`select ?a from ?b where{?s ?p ?o} order by ?a nulls;`,
+		`select ?a from ?b where{?s ?p ?o} order by ?a asc last;`,
+		`select ?a from ?b where{?s ?p ?o} order by ?a nulls last desc;`,
+		`select ?a from ?b where{?s ?p ?o} order by strlen(?a;`,
+		`select ?a from ?b where{?s ?p ?o} order by strlen(?a, ?b);`,
+		`select ?a from ?b where{?s ?p ?o} order by count(?a);`,
 		// Reject invalid having clauses.
 		`select ?a from ?b where {?a ?p ?o} having not ;`,
 		`select ?a from ?b where {?a ?p ?o} having not ?b ?b;`,
@@ -317,6 +531,9 @@ func TestRejectByParse(t *testing.T) {
 		`select ?a from ?b where {?a ?p ?o} having ?b  ?b;`,
 		`select ?a from ?b where {?a ?p ?o} having > ?b;`,
 		`select ?a from ?b where {?a ?p ?o} having ?b = ;`,
+		`select ?a from ?b where {?a ?p ?o} having ?a under ;`,
+		`select ?a from ?b where {?a ?p ?o} having ?a under ?b;`,
+		`select ?a from ?b where {?a ?p ?o} having ?a under /item<book>;`,
 		`select ?a from ?b where {?a ?p ?o} having () or not (?b = ?b);`,
 		`select ?a from ?b where {?a ?p ?o} having ((?b and ?b) (?b = ?b));`,
 		// Reject invalid global time bounds.
@@ -335,7 +552,19 @@ func TestRejectByParse(t *testing.T) {
 		};`,
 		`select ?a from ?b where {
 			?s ?p ?o .
-			optional {?x ?w ?z .?x ?w ?z }
+			optional {?x ?w ?z ?y}
+		};`,
+		`select ?a from ?b where {
+			?s ?p ?o .
+			optional {?x ?w ?z . . ?x ?w ?y}
+		};`,
+		// Test negated blocks.
+		`select ?s from ?b where {
+			not {?s ?p ?o}
+		};`,
+		`select ?s from ?b where {
+			?s ?p ?o .
+			not {}
 		};`,
 		// Insert incomplete data.
 		`insert data into ?a {"bar"@["1234"] /_<foo>};`,
@@ -343,6 +572,10 @@ func TestRejectByParse(t *testing.T) {
 		`insert data into ?a {/_<foo> "bar"@["1234"]};`,
 		// Insert into multiple incomplete graphs.
 		`insert data into ?a,?b, {/_<foo> "bar"@["1234"] /_<foo>};`,
+		// Insert with an incomplete if absent clause.
+		`insert data into ?a if {/_<foo> "bar"@["1234"] /_<foo>};`,
+		`insert data into ?a absent {/_<foo> "bar"@["1234"] /_<foo>};`,
+		`insert data into ?a {/_<foo> "bar"@["1234"] /_<foo>} if absent;`,
 		// Insert multiple incomplete data.
 		`insert data into ?a {/_<foo> "bar"@["1234"] /_<foo> .
 		                      /_<foo> "bar"@["1234"] "bar"@["1234"] .
@@ -363,6 +596,10 @@ func TestRejectByParse(t *testing.T) {
 		// Drop graphs.
 		`drop graph ;`,
 		`drop graph ?a ?b, ?c;`,
+		`create graph if exists ?a;`,
+		`create graph if not ?a;`,
+		`drop graph if not exists ?a;`,
+		`drop graph if ?a;`,
 		// Construct clause without source.
 		`construct {?s "foo"@[,] ?o} into ?a where{?s "foo"@[,] ?o} having ?s = ?o;`,
 		// Construct clause without destination.
@@ -469,7 +706,7 @@ func TestRejectByParse(t *testing.T) {
 		 from ?b
 		 where {
 			?s ?p ?o .
-			FILTER greaterThan(?o, /u<paul>)
+			FILTER greaterThan(?o, "paul"@[])
 		 };`,
 		// Test invalid trailing dot use inside WHERE.
 		`select ?a
@@ -521,6 +758,515 @@ func TestRejectByParse(t *testing.T) {
 	}
 }
 
+func TestSemanticStatementDisconnectedClauseGroups(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		want  [][]int
+	}{
+		{`select ?s from ?g where{?s ?p ?o};`, [][]int{{0}}},
+		{`select ?s from ?g where{?s ?p ?o . ?o ?q ?x};`, [][]int{{0, 1}}},
+		{`select ?s from ?g where{?s ?p ?o . ?k ?l ?m};`, [][]int{{0}, {1}}},
+		{`select ?s from ?g where{?s ?p ?o . ?k ?l ?m . ?o "p"@[] ?k};`, [][]int{{0, 1, 2}}},
+		{`select ?s from ?g where{?s ?p ?o . ?k ?l ?m . ?o "p"@[] ?x . ?m "q"@[] ?y};`, [][]int{{0, 2}, {1, 3}}},
+		{`select ?s from ?g where{?s "p"@[] ?o . /u<joe> "p"@[] /u<mary>};`, [][]int{{0}}},
+		{`select ?s, graph() as ?g from ?a, ?b where{?s ?p ?o . ?k ?l ?m};`, [][]int{{0}, {1}}},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		if got := st.DisconnectedClauseGroups(); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("Statement.DisconnectedClauseGroups() for %q returned %v; want %v", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestSemanticStatementGraphProjection(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		want  string
+	}{
+		{`select ?s, graph() as ?g from ?a, ?b where{?s ?p ?o . ?o ?q ?x};`, semantic.GraphBinding},
+		{`select ?s from ?a, ?b where{?s ?p ?o . ?o ?q ?x};`, ""},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		for _, cls := range st.GraphPatternClauses() {
+			if cls.GBinding != entry.want {
+				t.Errorf("Parser.consume: %q produced clause %v with graph binding %q; want %q", entry.query, cls, cls.GBinding, entry.want)
+			}
+		}
+	}
+}
+
+func TestSemanticStatementProjectAll(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		want  []string
+	}{
+		{`select * from ?a;`, []string{"?s", "?p", "?o"}},
+		{`select ?o from ?a;`, []string{"?o"}},
+		{`select * from ?a where{?x "knows"@[] ?y . ?y ?p ?z};`, []string{"?x", "?y", "?p", "?z"}},
+		{`select * from ?a where{?s ?p ?o as ?n} unwind ?n as ?m;`, []string{"?s", "?p", "?o", "?n", "?m"}},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		var got []string
+		for _, prj := range st.Projections() {
+			got = append(got, prj.Binding)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("Parser.consume: %q projected bindings %v; want %v", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestSemanticStatementDistinct(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		want  bool
+	}{
+		{`select ?o from ?a where{?s ?p ?o};`, false},
+		{`select distinct ?o from ?a where{?s ?p ?o};`, true},
+		{`SELECT DISTINCT * FROM ?a;`, true},
+		{`select ?s, count(distinct ?o) as ?n from ?a where{?s ?p ?o} group by ?s;`, false},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		if got := st.Distinct(); got != entry.want {
+			t.Errorf("Parser.consume: %q set distinct to %v; want %v", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestSemanticStatementMultipleAggregations(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	const q = `select ?s, count(?o) as ?objs, count(distinct ?p) as ?preds from ?a where{?s ?p ?o} group by ?s;`
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	want := []struct {
+		binding, alias string
+		op, modifier   lexer.TokenType
+	}{
+		{"?s", "", lexer.ItemError, lexer.ItemError},
+		{"?o", "?objs", lexer.ItemCount, lexer.ItemError},
+		{"?p", "?preds", lexer.ItemCount, lexer.ItemDistinct},
+	}
+	prjs := st.Projections()
+	if len(prjs) != len(want) {
+		t.Fatalf("Parser.consume: %q produced projections %v; want %d projections", q, prjs, len(want))
+	}
+	for i, w := range want {
+		if prj := prjs[i]; prj.Binding != w.binding || prj.Alias != w.alias || prj.OP != w.op || prj.Modifier != w.modifier {
+			t.Errorf("Parser.consume: %q produced projection %v; want binding %s, alias %q, op %v, modifier %v", q, prj, w.binding, w.alias, w.op, w.modifier)
+		}
+	}
+	if got, want := st.OutputBindings(), []string{"?s", "?objs", "?preds"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: %q produced output bindings %v; want %v", q, got, want)
+	}
+}
+
+func TestSemanticStatementBindingTypes(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query  string
+		want   map[string]literal.Type
+		strict bool
+	}{
+		{`select ?s from ?a where{?s ?p ?o};`, nil, false},
+		{`select ?s from ?a declare ?o type:int64 where{?s ?p ?o . ?o ?q ?x};`, map[string]literal.Type{"?o": literal.Int64}, false},
+		{`select ?s from ?a declare strict ?o type:int64, ?x type:text where{?s ?p ?o . ?o ?q ?x};`, map[string]literal.Type{"?o": literal.Int64, "?x": literal.Text}, true},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		for _, cls := range st.GraphPatternClauses() {
+			if !reflect.DeepEqual(cls.BindingTypes, entry.want) || cls.StrictBindingTypes != entry.strict {
+				t.Errorf("Parser.consume: %q produced clause %v with binding types %v (strict=%v); want %v (strict=%v)", entry.query, cls, cls.BindingTypes, cls.StrictBindingTypes, entry.want, entry.strict)
+			}
+		}
+	}
+}
+
+func TestSemanticStatementClauseGuards(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	q := `select ?s from ?a where{?s "height_cm"@[] ?h where ?h > "160"^^type:int64 . ?s ?p ?o};`
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	cls := st.GraphPatternClauses()
+	if len(cls) != 2 {
+		t.Fatalf("Parser.consume: %q produced %d clauses; want 2", q, len(cls))
+	}
+	g := cls[0].Guard
+	if g == nil || g.Binding != "?h" || g.Op != semantic.GT || g.Value.String() != `"160"^^type:int64` {
+		t.Errorf("Parser.consume: %q produced guard %v; want WHERE ?h > \"160\"^^type:int64", q, g)
+	}
+	if cls[1].Guard != nil {
+		t.Errorf("Parser.consume: %q produced guard %v for an unguarded clause", q, cls[1].Guard)
+	}
+}
+
+func TestSemanticStatementClauseLimits(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	q := `select ?s from ?a where{?s ?p ?o limit "1000"^^type:int64 . ?o "name"@[] ?n} limit "5"^^type:int64;`
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	cls := st.GraphPatternClauses()
+	if len(cls) != 2 {
+		t.Fatalf("Parser.consume: %q produced %d clauses; want 2", q, len(cls))
+	}
+	if got, want := cls[0].Limit, int64(1000); got != want {
+		t.Errorf("Parser.consume: %q produced clause limit %d; want %d", q, got, want)
+	}
+	if got := cls[1].Limit; got != 0 {
+		t.Errorf("Parser.consume: %q produced clause limit %d for a clause without limit", q, got)
+	}
+	if got, want := st.Limit(), int64(5); got != want {
+		t.Errorf("Parser.consume: %q produced statement limit %d; want %d", q, got, want)
+	}
+}
+
+func TestSemanticStatementTraceLevel(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		typ   semantic.StatementType
+		want  int
+	}{
+		{`select ?s from ?a where{?s ?p ?o};`, semantic.Query, 0},
+		{`trace "3"^^type:int64 select ?s from ?a where{?s ?p ?o};`, semantic.Query, 3},
+		{`trace "1"^^type:int64 insert data into ?a {/u<a> "p"@[] /u<b>};`, semantic.Insert, 1},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		if got := st.TraceLevel(); got != entry.want {
+			t.Errorf("Parser.consume: %q produced trace level %d; want %d", entry.query, got, entry.want)
+		}
+		if got := st.Type(); got != entry.typ {
+			t.Errorf("Parser.consume: %q produced statement type %v; want %v", entry.query, got, entry.typ)
+		}
+	}
+}
+
+func TestSemanticStatementAnchorsLookup(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	q := `anchors(/u<peter>, "bought"@[?t]) from ?a, ?b;`
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	if got, want := st.Type(), semantic.Anchors; got != want {
+		t.Errorf("Parser.consume: %q produced statement type %v; want %v", q, got, want)
+	}
+	s, pid, b := st.AnchorsLookup()
+	if s == nil || s.String() != "/u<peter>" || pid != "bought" || b != "?t" {
+		t.Errorf("Parser.consume: %q produced anchors lookup (%v, %q, %q); want (/u<peter>, \"bought\", \"?t\")", q, s, pid, b)
+	}
+	if got, want := st.InputGraphNames(), []string{"?a", "?b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: %q produced input graphs %v; want %v", q, got, want)
+	}
+}
+
+func TestSemanticStatementKillQuery(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, q := range []string{
+		`kill query "q42"^^type:text;`,
+		`kill query "q42";`,
+	} {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+		}
+		if got, want := st.Type(), semantic.Kill; got != want {
+			t.Errorf("Parser.consume: %q produced statement type %v; want %v", q, got, want)
+		}
+		if got, want := st.KillQueryID(), "q42"; got != want {
+			t.Errorf("Parser.consume: %q produced query ID %q; want %q", q, got, want)
+		}
+	}
+}
+
+func TestSemanticStatementViews(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	table := []struct {
+		query string
+		typ   semantic.StatementType
+	}{
+		{`create view ?v as select ?s from ?g where {?s ?p ?o};`, semantic.CreateView},
+		{`refresh view ?v;`, semantic.RefreshView},
+		{`drop view ?v;`, semantic.DropView},
+		{`show view ?v;`, semantic.ShowView},
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+		}
+		if got, want := st.Type(), entry.typ; got != want {
+			t.Errorf("Parser.consume: %q produced statement type %v; want %v", entry.query, got, want)
+		}
+		if got, want := st.ViewName(), "?v"; got != want {
+			t.Errorf("Parser.consume: %q produced view name %q; want %q", entry.query, got, want)
+		}
+	}
+
+	// The query of the view is collected as any other query.
+	q := `create view ?v as select ?s from ?g where {?s ?p ?o};`
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	if got, want := st.OutputBindings(), []string{"?s"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: %q produced output bindings %v; want %v", q, got, want)
+	}
+	if got, want := st.InputGraphNames(), []string{"?g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: %q produced input graphs %v; want %v", q, got, want)
+	}
+	if got, want := len(st.GraphPatternClauses()), 1; got != want {
+		t.Errorf("Parser.consume: %q produced %d graph pattern clauses; want %d", q, got, want)
+	}
+
+	// Graph statements are not affected.
+	q = `create graph ?v;`
+	st = &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	if st.Type() != semantic.Create || st.ViewName() != "" {
+		t.Errorf("Parser.consume: %q produced statement type %v and view %q; want %v and no view", q, st.Type(), st.ViewName(), semantic.Create)
+	}
+}
+
+func TestSemanticStatementMergeReplacingConflicts(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for q, want := range map[string]bool{
+		`merge ?a into ?b reporting conflicts;`: false,
+		`merge ?a into ?b replacing conflicts;`: true,
+	} {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+		}
+		if got := st.Type(); got != semantic.Merge {
+			t.Errorf("Parser.consume: %q produced statement type %v; want %v", q, got, semantic.Merge)
+		}
+		if got := st.ReplaceConflicts(); got != want {
+			t.Errorf("Parser.consume: %q produced ReplaceConflicts() = %v; want %v", q, got, want)
+		}
+	}
+}
+
+func TestSemanticStatementAnchorCutoff(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	q := `delete from ?a, ?b where predicate anchor before 2015-01-01T00:00:00Z;`
+	st := &semantic.Statement{}
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	if got, want := st.Type(), semantic.Delete; got != want {
+		t.Errorf("Parser.consume: %q produced statement type %v; want %v", q, got, want)
+	}
+	want := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := st.AnchorCutoff(); got == nil || !got.Equal(want) {
+		t.Errorf("Parser.consume: %q produced anchor cutoff %v; want %v", q, got, want)
+	}
+	if got, want := st.InputGraphNames(), []string{"?a", "?b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parser.consume: %q produced input graphs %v; want %v", q, got, want)
+	}
+	if len(st.Data()) != 0 {
+		t.Errorf("Parser.consume: %q produced data %v; want none", q, st.Data())
+	}
+}
+
+func TestSemanticStatementObjectTypeConstant(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	q := `select ?s from ?a where{?s ?p ?o type /item/book . ?o ?q ?r type ?t};`
+	if err := p.Parse(NewLLk(q, 1), st); err != nil {
+		t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+	}
+	cls := st.GraphPatternClauses()
+	if len(cls) != 2 {
+		t.Fatalf("Parser.consume: %q produced %d clauses; want 2", q, len(cls))
+	}
+	if got, want := cls[0].OTypeConstant, "/item/book"; got == nil || got.String() != want {
+		t.Errorf("Parser.consume: %q produced object type constant %v; want %s", q, got, want)
+	}
+	if got := cls[0].OTypeAlias; got != "" {
+		t.Errorf("Parser.consume: %q produced object type alias %q for a type constant", q, got)
+	}
+	if got, want := cls[1].OTypeAlias, "?t"; got != want || cls[1].OTypeConstant != nil {
+		t.Errorf("Parser.consume: %q produced object type alias %q and constant %v; want alias %q", q, got, cls[1].OTypeConstant, want)
+	}
+}
+
+func TestSemanticStatementCanonicalString(t *testing.T) {
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	canonical := func(q string) string {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(q, 1), st); err != nil {
+			t.Fatalf("Parser.consume: Failed to accept valid semantic entry %q with error %v", q, err)
+		}
+		return st.CanonicalString()
+	}
+	table := []struct {
+		q1, q2 string
+		equal  bool
+	}{
+		{
+			q1: `select ?s, count(?o) as ?n from ?g where {?s "p"@[] ?o} group by ?s order by ?n desc having ?n > "1"^^type:int64 limit "10"^^type:int64;`,
+			q2: `SELECT   ?s ,COUNT( ?o )  AS ?n
+			     FROM ?g
+			     WHERE {
+			       ?s   "p"@[]   ?o
+			     }
+			     GROUP BY ?s
+			     ORDER BY ?n DESC
+			     HAVING ?n > "1"^^type:int64
+			     LIMIT "10"^^type:int64;`,
+			equal: true,
+		},
+		{
+			q1:    `select ?s from ?g where {?s "p"@[] ?o . optional {?o "q"@[] ?x}} having not (?s = ?o) or ?x = ?o;`,
+			q2:    `SeLeCt ?s FrOm ?g WhErE { ?s "p"@[] ?o. OPTIONAL { ?o "q"@[] ?x } } HAVING NOT (?s = ?o) OR ?x = ?o;`,
+			equal: true,
+		},
+		{
+			q1:    `insert data into ?a {/u<joe> "knows"@[] /u<mary>};`,
+			q2:    `INSERT DATA INTO ?a { /u<joe>  "knows"@[]  /u<mary> };`,
+			equal: true,
+		},
+		{
+			q1:    `select ?s from ?g where {?s "p"@[] ?o};`,
+			q2:    `select ?s from ?g where {?s "q"@[] ?o};`,
+			equal: false,
+		},
+		{
+			q1:    `select ?s as ?a from ?g where {?s "p"@[] ?o};`,
+			q2:    `select ?s as ?b from ?g where {?s "p"@[] ?o};`,
+			equal: false,
+		},
+		{
+			q1:    `select ?s from ?g where {?s "p"@[] ?o} limit "10"^^type:int64;`,
+			q2:    `select ?s from ?g where {?s "p"@[] ?o} limit "20"^^type:int64;`,
+			equal: false,
+		},
+		{
+			q1:    `select ?s from ?g where {?s "p"@[] ?o} before 2015-01-01T00:00:00Z;`,
+			q2:    `select ?s from ?g where {?s "p"@[] ?o} after 2015-01-01T00:00:00Z;`,
+			equal: false,
+		},
+	}
+	for _, entry := range table {
+		c1, c2 := canonical(entry.q1), canonical(entry.q2)
+		if got := c1 == c2; got != entry.equal {
+			t.Errorf("Statement.CanonicalString() returned %q for %q and %q for %q; want equal=%v", c1, entry.q1, c2, entry.q2, entry.equal)
+		}
+	}
+}
+
+func TestSemanticStatementExistenceFlags(t *testing.T) {
+	table := []struct {
+		query       string
+		ifNotExists bool
+		ifExists    bool
+	}{
+		{`create graph ?a;`, false, false},
+		{`create graph if not exists ?a;`, true, false},
+		{`drop graph ?a;`, false, false},
+		{`drop graph if exists ?a;`, false, true},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("NewParser(SemanticBQL()) should have produced a valid BQL parser; %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to accept entry %q with error %v", entry.query, err)
+		}
+		if got, want := st.IfNotExists(), entry.ifNotExists; got != want {
+			t.Errorf("Statement.IfNotExists() for %q returned %v; want %v", entry.query, got, want)
+		}
+		if got, want := st.IfExists(), entry.ifExists; got != want {
+			t.Errorf("Statement.IfExists() for %q returned %v; want %v", entry.query, got, want)
+		}
+	}
+}
+
 func TestAcceptGraphOpsByParseAndSemantic(t *testing.T) {
 	var empty []string
 	table := []struct {
@@ -606,6 +1352,10 @@ func TestAcceptGraphOpsByParseAndSemantic(t *testing.T) {
 			[]string{"?c", "?d"},
 			[]string{"?a", "?b"},
 			0},
+
+		// Merge graphs. Sources are input graphs and destinations output graphs.
+		{`merge ?a into ?b reporting conflicts;`, empty, []string{"?a"}, []string{"?b"}, 0},
+		{`merge ?a, ?b into ?c, ?d reporting conflicts;`, empty, []string{"?a", "?b"}, []string{"?c", "?d"}, 0},
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -635,6 +1385,37 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 	table := []string{
 		// Test well type literals are accepted.
 		`select ?s from ?g where{?s ?p "1"^^type:int64};`,
+		`select ?s from ?g where{?s ?p "["1"^^type:int64]"^^type:list};`,
+		// Test unwind bindings are available to the projection.
+		`select ?s, ?i from ?g where{?s ?p ?o} unwind ?o as ?i;`,
+		// Test inline guards on the bindings of their clause.
+		`select ?s from ?g where{?s "height_cm"@[] ?h where ?h > "160"^^type:int64};`,
+		`select ?s from ?g where{?s "height_cm"@[] ?h where ?h = "160"^^type:int64 ; "name"@[] ?n where ?n < "m"^^type:text . ?s ?p ?o};`,
+		// Test clause limits, alone or after a guard.
+		`select ?s from ?g where{?s ?p ?o limit "1000"^^type:int64 . ?o "name"@[] ?n};`,
+		`select ?s from ?g where{?s "height_cm"@[] ?h where ?h > "160"^^type:int64 limit "10"^^type:int64} limit "5"^^type:int64;`,
+		`select ?s from ?g where{?s "height_cm"@[] ?o at ?t where ?t > "1"^^type:int64};`,
+		// Test group indices keep the bindings not listed on GROUP BY.
+		`select ?s, ?o, group_index() as ?idx from ?g where{?s ?p ?o} group by ?s order by ?o having ?idx < "3"^^type:int64;`,
+		// Test histograms, alone or per group.
+		`select histogram(?t, "1mo") from ?g where{?s "p"@[?t] ?o} order by ?bucket_start;`,
+		`select ?s, histogram(?t, "1d"^^type:text) from ?g where{?s "p"@[?t] ?o} group by ?s having ?count > "1"^^type:int64;`,
+		`select ?i, count(?s) as ?n from ?g where{?s ?p ?o} unwind ?o as ?i group by ?i;`,
+		// Test graph counts can be filtered by HAVING.
+		`select ?s, ?o, graph_count() as ?gc from ?a, ?b where{?s ?p ?o} group by ?s, ?o having ?gc = "2"^^type:int64;`,
+		// Test triple projections can be deduplicated, sorted and grouped by.
+		`select distinct triple(?s, ?p, ?o) as ?t from ?a, ?b where{?s ?p ?o} order by ?t;`,
+		`select triple(?s, ?p, ?o) as ?t, graph_count() as ?gc from ?a, ?b where{?s ?p ?o} group by ?t;`,
+		// Test cast functions are accepted.
+		`select ?s, cast(?o, type:int64) as ?n from ?g where{?s ?p ?o};`,
+		`select ?s from ?g where{?s ?p ?o} having cast(?o, type:int64) > "3"^^type:int64;`,
+		`select ?s from ?g where{?s ?p ?o} having ?s = cast(?o, type:text);`,
+		// Test predicates bound to several time ranges are accepted.
+		`select ?s from ?g where{?s "p"@[2015-01-01T00:00:00Z,2015-02-01T00:00:00Z; 2016-01-01T00:00:00Z,] ?o};`,
+		`select ?s from ?g where{?s ?p "p"@[,2015-02-01T00:00:00Z; 2014-01-01T00:00:00Z,2016-01-01T00:00:00Z] as ?o};`,
+		// Test the anonymous binding is accepted anywhere a binding is.
+		`select ?s from ?g where{?s "p"@[] ?_};`,
+		`select ?p from ?g where{?_ ?p ?_};`,
 		// Test predicates are accepted.
 		// Test invalid predicate time anchor are rejected.
 		`select ?s from ?b where{/_<foo> as ?s "id"@[2015] ?o};`,
@@ -659,10 +1440,25 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 		// Test group by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} group by ?s;`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?c;`,
+		`select ?s, group_concat(?o, ", ") as ?os from ?g where{?s ?p ?o} group by ?s;`,
+		`select ?s, group_concat(?o) as ?os, count(?o) as ?n from ?g where{?s ?p ?o} group by ?s;`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1h"^^type:text);`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1mo"^^type:text);`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1h");`,
 		// Test order by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} order by ?s;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?b DESC, ?a ASC, ?b DESC, ?c;`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by strlen(?o) desc, ?s asc, strlen(?o) desc;`,
+		// Test inline anchor comparisons.
+		`select ?s from ?b where {?s "bought"@[> 2016-02-01T00:00:00Z] ?o};`,
+		`select ?s from ?b where {?s "bought"@[<= 2016-02-01T00:00:00Z] AS ?p ?o . ?s "sold"@[>=2015-01-01T00:00:00Z] ?o2};`,
+		// Test statements wrapped in a TRACE clause.
+		`trace "2"^^type:int64 select ?s from ?b where {?s ?p ?o};`,
+		`trace "1"^^type:int64 show graphs;`,
+		// Test binding type declarations.
+		`select ?s, ?o from ?b declare ?o type:int64 where {?s ?p ?o};`,
+		`select ?s, ?o from ?b declare strict ?o type:int64, ?x type:text where {?s ?p ?o . ?o ?q ?x};`,
 		// Test valid FILTER clause for grammar with hooks.
 		`select ?p
 		 from ?b
@@ -670,6 +1466,26 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 			?s ?p ?o .
 			FILTER latest(?p)
 		 };`,
+		`select ?o
+		 from ?b
+		 where {
+			?s ?p ?o .
+			FILTER latest(?p) .
+			FILTER contains(?o, "model"^^type:text)
+		 };`,
+		`select ?o
+		 from ?b
+		 where {
+			?s ?p ?o .
+			FILTER lang(?o, "es"^^type:text)
+		 };`,
+		`select ?o
+		 from ?b
+		 where {
+			?s ?p ?o .
+			FILTER eq(?o, /u<mary>) .
+			FILTER neq(?o, "174"^^type:int64)
+		 };`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -684,21 +1500,103 @@ func TestAcceptQueryBySemanticParse(t *testing.T) {
 
 func TestRejectByParseAndSemantic(t *testing.T) {
 	table := []string{
+		// Test projected bindings must be in the default graph pattern.
+		`select ?x from ?g;`,
 		// Test wrong type literals are rejected.
 		`select ?s from ?g where{?s ?p "true"^^type:int64};`,
+		// Test unsupported cast types are rejected.
+		`select cast(?o, type:blob) as ?n from ?g where{?s ?p ?o};`,
+		`select cast(?o, type:int32) as ?n from ?g where{?s ?p ?o};`,
+		`select ?s from ?g where{?s ?p ?o} having cast(?o, type:list) = ?s;`,
 		// Test invalid predicate bounds are rejected.
 		`select ?s from ?b where{/_<foo> as ?s "id"@[2018-07-19T13:12:04.669618843-07:00, 2015-07-19T13:12:04.669618843-07:00] ?o};`,
 		`select ?s from ?b where{/_<foo> as ?s  ?p "id"@[2019-07-19T13:12:04.669618843-07:00, 2015-07-19T13:12:04.669618843-07:00] as ?o};`,
+		`select ?s from ?b where{?s "id"@[2015-01-01T00:00:00Z,2016-01-01T00:00:00Z; 2018-01-01T00:00:00Z,2017-01-01T00:00:00Z] ?o};`,
+		`select ?s from ?b where{?s "id"@[?lower,2016-01-01T00:00:00Z; 2018-01-01T00:00:00Z,] ?o};`,
 		// Check the bindings on the projection exist on the graph clauses.
 		`select ?foo from ?g where {?s ?p ?o};`,
+		// Predicate ID constants only restrict predicate bindings.
+		`select ?s from ?g where{?s "bought"@[] id "bought" ?o};`,
+		`select ?s from ?g where{?s ?p id "" ?o};`,
+		// Object node types only restrict object bindings.
+		`select ?s from ?g where{?s ?p /u<joe> type /u};`,
+		`select ?s from ?g where{?s type /u ?p ?o};`,
+		// Inline guards only refer to the bindings of their clause.
+		`select ?s from ?g where{?s ?p ?o . ?s "height_cm"@[] ?h where ?o > "160"^^type:int64};`,
+		`select ?s from ?g where{?s "height_cm"@[] ?_ where ?_ > "160"^^type:int64};`,
+		`select ?s from ?g where{?s "height_cm"@[] ?h where ?h > "foo"};`,
+		// Clause limits require a positive int64 literal.
+		`select ?s from ?g where{?s ?p ?o limit "0"^^type:int64};`,
+		`select ?s from ?g where{?s ?p ?o limit "-1"^^type:int64};`,
+		`select ?s from ?g where{?s ?p ?o limit "10"^^type:text};`,
+		// Anchors statements require a temporal predicate with an anchor binding.
+		`anchors(/u<peter>, "bought"@[]) from ?g;`,
+		`anchors(/u<peter>, "bought"@[2016-01-01T00:00:00Z]) from ?g;`,
+		`anchors(/u<peter>, "bought"@[?_]) from ?g;`,
+		// Kill statements require a non empty text literal ID.
+		`kill query "1"^^type:int64;`,
+		`kill query ""^^type:text;`,
+		`kill query "";`,
+		// Check the anonymous binding can not be projected.
+		`select ?_ from ?g where {?s ?p ?_};`,
+		`select ?s, count(?_) as ?n from ?g where {?s ?p ?_} group by ?s;`,
 		// Reject invalid group by.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} group by ?unknown;`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o};`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?b;`,
 		`select count(?s) as ?a, sum(?o) as ?b, ?o as ?c from ?g where{?s ?p ?o} group by ?a;`,
+		// Reject GROUP_CONCAT without GROUP BY or grouping by its result.
+		`select group_concat(?o, ", ") as ?os from ?g where{?s ?p ?o};`,
+		`select ?s, group_concat(?o, ", ") as ?os from ?g where{?s ?p ?o} group by ?os;`,
+		// Reject SUBSTR arguments that are not int64 literals.
+		`select substr(?o, "1"^^type:text, "1"^^type:int64) as ?sub from ?g where{?s ?p ?o};`,
+		`select substr(?o, "1"^^type:int64, "1.0"^^type:float64) as ?sub from ?g where{?s ?p ?o};`,
+		// Reject group indices without GROUP BY or mixed with aggregations.
+		`select ?s, group_index() as ?idx from ?g where{?s ?p ?o};`,
+		`select ?s, count(?o) as ?n, group_index() as ?idx from ?g where{?s ?p ?o} group by ?s;`,
+		`select ?s, group_index() as ?idx from ?g where{?s ?p ?o} group by ?idx;`,
+		// Reject graph counts without GROUP BY or grouped by.
+		`select ?s, graph_count() as ?gc from ?a, ?b where{?s ?p ?o};`,
+		`select ?s, graph_count() as ?gc from ?a, ?b where{?s ?p ?o} group by ?gc;`,
+		`select ?s, graph() as ?g, graph_count() as ?gc from ?a, ?b where{?s ?p ?o} group by ?s, ?g;`,
+		// Reject triple projections of bindings missing from the graph pattern.
+		`select triple(?s, ?p, ?x) as ?t from ?g where{?s ?p ?o};`,
+		// Reject invalid bucket intervals.
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1x"^^type:text);`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1x");`,
+		// Reject invalid histograms or mixed with other projections.
+		`select histogram(?t, "1x") from ?g where{?s "p"@[?t] ?o};`,
+		`select histogram(?t, "1"^^type:int64) from ?g where{?s "p"@[?t] ?o};`,
+		`select ?s, histogram(?t, "1mo") from ?g where{?s "p"@[?t] ?o};`,
+		`select ?s, histogram(?t, "1mo"), count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by ?s;`,
+		`select histogram(?t, "1mo"), histogram(?t, "1d") from ?g where{?s "p"@[?t] ?o};`,
+		`select ?s as ?count, histogram(?t, "1mo") from ?g where{?s "p"@[?t] ?o} group by ?count;`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "0d"^^type:text);`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?t, "1"^^type:int64);`,
+		`select ?t, count(?o) as ?n from ?g where{?s "p"@[?t] ?o} group by bucket(?unknown, "1h"^^type:text);`,
 		// Reject order by acceptance.
 		`select ?s from ?g where{/_<foo> as ?s  ?p "id"@[?foo, ?bar] as ?o} order by ?unknown_s;`,
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} order by ?a ASC, ?a DESC;`,
+		`select ?s from ?g where{?s ?p ?o} order by strlen(?o);`,
+		`select ?s, ?o from ?g where{?s ?p ?o} order by strlen(?o) asc, strlen(?o) desc;`,
+		// Reject invalid unwind bindings.
+		`select ?i from ?g where{?s ?p ?o} unwind ?unknown as ?i;`,
+		`select ?i from ?g where{?s ?p ?o} unwind ?o as ?s;`,
+		`select ?i from ?g where{?s ?p ?o} unwind ?o as ?i, ?p as ?i;`,
+		`select ?j from ?g where{?s ?p ?o} unwind ?o as ?i;`,
+		// Reject invalid inline anchor comparisons.
+		`select ?s from ?g where{?s "bought"@[> ?t] ?o};`,
+		`select ?s from ?g where{?s "bought"@[> yesterday] ?o};`,
+		// Reject invalid trace levels and nested TRACE clauses.
+		`trace "0"^^type:int64 select ?s from ?g where{?s ?p ?o};`,
+		`trace "4"^^type:int64 select ?s from ?g where{?s ?p ?o};`,
+		`trace "2"^^type:text select ?s from ?g where{?s ?p ?o};`,
+		`trace "2"^^type:int64 trace "3"^^type:int64 show graphs;`,
+		// Reject invalid binding type declarations.
+		`select ?s from ?g declare ?unknown type:int64 where{?s ?p ?o};`,
+		`select ?s from ?g declare ?o type:int32 where{?s ?p ?o};`,
+		`select ?s from ?g declare ?o type:int64, ?o type:text where{?s ?p ?o};`,
+		`select ?s from ?g declare ?_ type:int64 where{?s ?p ?_};`,
 		// Wrong limit literal.
 		`select ?s as ?a, ?o as ?b, ?o as ?c from ?g where{?s ?p ?o} LIMIT "true"^^type:bool;`,
 		// Reject not supported FILTER function.
@@ -715,6 +1613,24 @@ func TestRejectByParseAndSemantic(t *testing.T) {
 			/u<peter> ?p ?o .
 			FILTER latest(?p, "37"^^type:int64)
 		 };`,
+		`select ?p, ?o
+		 from ?test
+		 where {
+			/u<peter> ?p ?o .
+			FILTER contains(?o)
+		 };`,
+		`select ?p, ?o
+		 from ?test
+		 where {
+			/u<peter> ?p ?o .
+			FILTER lang(?o)
+		 };`,
+		`select ?p, ?o
+		 from ?test
+		 where {
+			/u<peter> ?p ?o .
+			FILTER eq(?o)
+		 };`,
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -745,6 +1661,14 @@ func TestSemanticStatementGraphClausesLengthCorrectness(t *testing.T) {
 			query: `SELECT ?o,?l FROM ?bbacl WHERE { ?o "some_id"@[,] ?x . ?x "some_id"@[,] ?y . ?y "some_id"@[,] ?l } LIMIT "20"^^type:int64;`,
 			want:  3,
 		},
+		{
+			query: `SELECT ?o,?l FROM ?bbacl WHERE { ?o "some_id"@[,] ?x ; "other_id"@[,] ?l } LIMIT "20"^^type:int64;`,
+			want:  2,
+		},
+		{
+			query: `SELECT ?o,?l FROM ?bbacl WHERE { ?o "some_id"@[,] ?x ; "other_id"@[,] ?y . ?y "some_id"@[,] ?l } LIMIT "20"^^type:int64;`,
+			want:  3,
+		},
 	}
 	p, err := NewParser(SemanticBQL())
 	if err != nil {
@@ -761,6 +1685,84 @@ func TestSemanticStatementGraphClausesLengthCorrectness(t *testing.T) {
 	}
 }
 
+func TestSemanticStatementOptionalGroups(t *testing.T) {
+	table := []struct {
+		query string
+		want  []int
+	}{
+		{
+			query: `select ?s from ?g where {?s "p"@[] ?o . optional {?o "a"@[] ?x . ?x "b"@[] ?y} . optional {?o "c"@[] ?z} . ?s "d"@[] ?w};`,
+			want:  []int{0, 1, 1, 2, 0},
+		},
+		{
+			query: `select ?s from ?g where {?s "p"@[] ?o . optional {?o "a"@[] ?x . ?x "b"@[] ?y .}};`,
+			want:  []int{0, 1, 1},
+		},
+		{
+			query: `select ?s from ?g where {?s "p"@[] ?o . optional {/u<joe> "a"@[] ?x . /u<joe> "b"@[] ?y}};`,
+			want:  []int{0, 1, 1},
+		},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		st := &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.query, 1), st); err != nil {
+			t.Errorf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.query, err)
+			continue
+		}
+		var got []int
+		for _, cls := range st.GraphPatternClauses() {
+			if cls.Optional != (cls.OptionalGroup != 0) {
+				t.Errorf("Parser.consume: %q produced clause %v with optional group %d", entry.query, cls, cls.OptionalGroup)
+			}
+			got = append(got, cls.OptionalGroup)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("Parser.consume: %q produced optional groups %v; want %v", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestSemanticStatementPredicateObjectPairsEquivalence(t *testing.T) {
+	table := []struct {
+		shorthand, expanded string
+	}{
+		{
+			shorthand: `select ?o, ?a from ?g where {/u<joe> "parent_of"@[] ?o; "age"@[] ?a};`,
+			expanded:  `select ?o, ?a from ?g where {/u<joe> "parent_of"@[] ?o. /u<joe> "age"@[] ?a};`,
+		},
+		{
+			shorthand: `select ?s, ?o, ?a from ?g where {?s "parent_of"@[] ?o; "age"@[,] ?a; "name"@[] ?n. ?o "age"@[,] ?b};`,
+			expanded:  `select ?s, ?o, ?a from ?g where {?s "parent_of"@[] ?o. ?s "age"@[,] ?a. ?s "name"@[] ?n. ?o "age"@[,] ?b};`,
+		},
+		{
+			shorthand: `select ?s, ?p from ?g where {?s as ?x ?p ?o; "age"@[?t] as ?y ?a};`,
+			expanded:  `select ?s, ?p from ?g where {?s as ?x ?p ?o. ?s "age"@[?t] as ?y ?a};`,
+		},
+	}
+	p, err := NewParser(SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: Should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		sst, est := &semantic.Statement{}, &semantic.Statement{}
+		if err := p.Parse(NewLLk(entry.shorthand, 1), sst); err != nil {
+			t.Errorf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.shorthand, err)
+			continue
+		}
+		if err := p.Parse(NewLLk(entry.expanded, 1), est); err != nil {
+			t.Errorf("Parser.consume: Failed to accept valid semantic entry %q with error %v", entry.expanded, err)
+			continue
+		}
+		if got, want := sst.GraphPatternClauses(), est.GraphPatternClauses(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Parser.consume: %q produced graph clauses %v; want %v as produced by %q", entry.shorthand, got, want, entry.expanded)
+		}
+	}
+}
+
 func TestSemanticStatementConstructDeconstructClausesLengthCorrectness(t *testing.T) {
 	table := []struct {
 		query string
@@ -926,3 +1928,88 @@ func TestSemanticStatementPredicateObjectPairsLengthCorrectness(t *testing.T) {
 		}
 	}
 }
+
+func TestValidate(t *testing.T) {
+	table := []struct {
+		q        string
+		want     string
+		line     int
+		col      int
+		semantic bool
+	}{
+		{q: `select ?s from ?g where {?s ?p ?o} order by ?s;`},
+		{q: `select ?s, count(?o) as ?n from ?g where {?s ?p ?o} group by ?s having ?n > "1"^^type:int64;`},
+		{q: `select ?s from ?g where {?s ?p ?o. filter latest(?p)};`},
+		{
+			q:        `select ?x from ?g where {?s ?p ?o};`,
+			want:     `specified binding ?x not found`,
+			line:     1,
+			col:      34,
+			semantic: true,
+		},
+		{
+			q: `select ?s
+			    from ?g
+			    where {?s ?p ?o}
+			    group by ?o;`,
+			want:     `invalid GROUP BY binging ?o`,
+			line:     4,
+			col:      17,
+			semantic: true,
+		},
+		{
+			q:        `select ?s from ?g where {?s ?p ?o} order by ?x;`,
+			want:     `order by binding "?x" unknown`,
+			line:     1,
+			col:      45,
+			semantic: true,
+		},
+		{
+			q:        `select ?s, count(?o) as ?n from ?g where {?s ?p ?o};`,
+			want:     `requires GROUP BY clause`,
+			line:     1,
+			col:      52,
+			semantic: true,
+		},
+		{
+			q:        `select ?s from ?g where {?s ?p ?o. filter foo(?p)};`,
+			want:     `filter function "foo" on filter clause is not supported`,
+			line:     1,
+			col:      43,
+			semantic: true,
+		},
+		{
+			q:    `select ?s from ?g where {?s ?p ?o. filter latest(?x)};`,
+			want: `binding "?x" referenced by filter clause`,
+		},
+		{
+			q:    `select ?s, count(?o) as ?n from ?g where {?s ?p ?o} group by ?s having ?o > "1"^^type:int64;`,
+			want: `binding "?o" referenced by the HAVING clause is not available after grouping`,
+		},
+		{
+			q:    `select ?s from ?g where {?s ?p ?o`,
+			want: `unexpected end of input at line 1 col 34`,
+		},
+	}
+	for _, entry := range table {
+		err := Validate(entry.q)
+		if entry.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%q) failed with error %v", entry.q, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("Validate(%q) returned error %v; want it to contain %q", entry.q, err, entry.want)
+			continue
+		}
+		se, ok := err.(*SemanticError)
+		if ok != entry.semantic {
+			t.Errorf("Validate(%q) returned error %T; want a *SemanticError to be %v", entry.q, err, entry.semantic)
+			continue
+		}
+		if ok && (se.Line != entry.line || se.Col != entry.col) {
+			t.Errorf("Validate(%q) reported the error at line %d col %d; want line %d col %d", entry.q, se.Line, se.Col, entry.line, entry.col)
+		}
+	}
+}
@@ -26,6 +26,7 @@ type LLk struct {
 	k    int
 	c    <-chan lexer.Token
 	tkns []lexer.Token
+	last lexer.Token
 }
 
 // NewLLk creates a LLk structure for the given string to parse and the
@@ -57,6 +58,12 @@ func (l *LLk) Current() *lexer.Token {
 	return &l.tkns[0]
 }
 
+// Last returns the last token consumed. Its type is ItemError if no token
+// has been consumed yet.
+func (l *LLk) Last() *lexer.Token {
+	return &l.last
+}
+
 // Peek returns the token for the k look ahead. It will return nil and failed
 // fail with an error if the provided k is bigger than the declared look ahead
 // on creation.
@@ -82,6 +89,7 @@ func (l *LLk) Consume(tt lexer.TokenType) bool {
 	if l.tkns[0].Type != tt {
 		return false
 	}
+	l.last = l.tkns[0]
 	l.tkns = l.tkns[1:]
 	appendNextToken(l)
 	return true
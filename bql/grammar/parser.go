@@ -96,6 +96,33 @@ func NewParser(grammar *Grammar) (*Parser, error) {
 	}, nil
 }
 
+// SemanticError is returned when a semantic hook rejects the statement being
+// parsed. It contains the position of the token being processed at the time.
+type SemanticError struct {
+	Line, Col int
+	Err       error
+}
+
+// Error returns the semantic error message along with its position.
+func (e *SemanticError) Error() string {
+	return fmt.Sprintf("%v at line %d col %d", e.Err, e.Line, e.Col)
+}
+
+// Unwrap returns the error returned by the semantic hook.
+func (e *SemanticError) Unwrap() error {
+	return e.Err
+}
+
+// newSemanticError wraps the provided hook error with the position of the
+// token.
+func newSemanticError(tkn *lexer.Token, err error) error {
+	return &SemanticError{
+		Line: tkn.Line,
+		Col:  tkn.Col,
+		Err:  err,
+	}
+}
+
 // Parse attempts to run the parser for the given input.
 func (p *Parser) Parse(llk *LLk, st *semantic.Statement) error {
 	b, err := p.consume(llk, st, "START")
@@ -123,25 +150,38 @@ func (p *Parser) consume(llk *LLk, st *semantic.Statement, s semantic.Symbol) (b
 			return p.expect(llk, st, s, clause)
 		}
 	}
-	return false, fmt.Errorf("Parser.consume: could not consume token %s in production %s", llk.Current(), s)
+	return false, fmt.Errorf("Parser.consume: %s; could not consume it in production %s", unexpectedToken(llk.Current()), s)
+}
+
+// unexpectedToken returns a readable description of the provided token and
+// its position in the input, to be used when reporting parsing errors.
+func unexpectedToken(tkn *lexer.Token) string {
+	switch tkn.Type {
+	case lexer.ItemEOF:
+		return fmt.Sprintf("unexpected end of input at line %d col %d", tkn.Line, tkn.Col)
+	case lexer.ItemError:
+		return fmt.Sprintf("invalid token '%s' at line %d col %d: %s", tkn.Text, tkn.Line, tkn.Col, tkn.ErrorMessage)
+	default:
+		return fmt.Sprintf("unexpected token '%s' at line %d col %d", tkn.Text, tkn.Line, tkn.Col)
+	}
 }
 
 // expect given the input, symbol, and clause attempts to satisfy all elements.
 func (p *Parser) expect(llk *LLk, st *semantic.Statement, s semantic.Symbol, cls *Clause) (bool, error) {
 	if cls.ProcessStart != nil {
 		if _, err := cls.ProcessStart(st, s); err != nil {
-			return false, err
+			return false, newSemanticError(llk.Current(), err)
 		}
 	}
 	for _, elem := range cls.Elements {
 		tkn := llk.Current()
 		if elem.isSymbol {
 			if b, err := p.consume(llk, st, elem.Symbol()); !b || err != nil {
-				return false, fmt.Errorf("Parser.parse: Failed to consume symbol %v, with error %v", elem.Symbol(), err)
+				return false, fmt.Errorf("Parser.parse: Failed to consume symbol %v, with error %w", elem.Symbol(), err)
 			}
 		} else {
 			if !llk.Consume(elem.Token()) {
-				return false, fmt.Errorf("Parser.parse: Failed to consume %s, got %s instead", elem.Token(), llk.Current().Type)
+				return false, fmt.Errorf("Parser.parse: Failed to consume %s; %s", elem.Token(), unexpectedToken(llk.Current()))
 			}
 		}
 		if cls.ProcessedElement != nil {
@@ -152,13 +192,13 @@ func (p *Parser) expect(llk *LLk, st *semantic.Statement, s semantic.Symbol, cls
 				ce = semantic.NewConsumedToken(tkn)
 			}
 			if _, err := cls.ProcessedElement(st, ce); err != nil {
-				return false, err
+				return false, newSemanticError(llk.Last(), err)
 			}
 		}
 	}
 	if cls.ProcessEnd != nil {
 		if _, err := cls.ProcessEnd(st, s); err != nil {
-			return false, err
+			return false, newSemanticError(llk.Last(), err)
 		}
 	}
 	return true, nil
@@ -15,6 +15,7 @@
 package grammar
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/badwolf/bql/lexer"
@@ -227,3 +228,45 @@ func TestComplexGrammarParse(t *testing.T) {
 		t.Errorf("Parser.consume: failed to accept derivation tokens; %v", err)
 	}
 }
+
+func TestParseErrorsReportTokenPosition(t *testing.T) {
+	table := []struct {
+		q    string
+		want string
+	}{
+		{
+			q:    "select ?s from ?g where {?s ?p ?o} filter;",
+			want: "unexpected token 'filter' at line 1 col 36",
+		},
+		{
+			q: `select ?s
+from ?g
+where {
+    FILTER ?s
+};`,
+			want: "unexpected token 'FILTER' at line 4 col 5",
+		},
+		{
+			q:    "select ?s from ?g where {?s ?p ?o}",
+			want: "unexpected end of input at line 1 col 35",
+		},
+		{
+			q:    `select ?s from ?g where {?s ?p "1"^^type:int64@es};`,
+			want: `invalid token '"1"^^type:int64@' at line 1 col 32`,
+		},
+	}
+	p, err := NewParser(BQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range table {
+		err := p.Parse(NewLLk(entry.q, 1), &semantic.Statement{})
+		if err == nil {
+			t.Errorf("Parser.Parse(%q) should have failed", entry.q)
+			continue
+		}
+		if !strings.Contains(err.Error(), entry.want) {
+			t.Errorf("Parser.Parse(%q) returned error %q; want it to contain %q", entry.q, err, entry.want)
+		}
+	}
+}
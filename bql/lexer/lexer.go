@@ -143,6 +143,92 @@ const (
 	ItemFilter
 	// ItemFilterFunction represents a filter function in BQL.
 	ItemFilterFunction
+	// ItemUnwind represents the unwind keyword in BQL.
+	ItemUnwind
+	// ItemDump represents the dump keyword in BQL.
+	ItemDump
+	// ItemIf represents the if keyword in BQL.
+	ItemIf
+	// ItemExists represents the exists keyword in BQL.
+	ItemExists
+	// ItemCast represents the cast function in BQL.
+	ItemCast
+	// ItemLiteralType represents a literal type name (e.g. type:int64) in BQL.
+	ItemLiteralType
+	// ItemBucket represents the bucket function in BQL.
+	ItemBucket
+	// ItemClear represents the clear keyword in BQL.
+	ItemClear
+	// ItemStore represents the store keyword in BQL.
+	ItemStore
+	// ItemLang represents the lang function in BQL.
+	ItemLang
+	// ItemTruncate represents the removal of all the triples of a graph in BQL.
+	ItemTruncate
+	// ItemNulls represents the nulls keyword used in ORDER BY in BQL.
+	ItemNulls
+	// ItemFirst represents the first keyword used in ORDER BY in BQL.
+	ItemFirst
+	// ItemLast represents the last keyword used in ORDER BY in BQL.
+	ItemLast
+	// ItemKind represents the kind function in BQL.
+	ItemKind
+	// ItemMerge represents the merge keyword in BQL.
+	ItemMerge
+	// ItemReporting represents the reporting keyword in BQL.
+	ItemReporting
+	// ItemConflicts represents the conflicts keyword in BQL.
+	ItemConflicts
+	// ItemGroupIndex represents the group_index function in BQL.
+	ItemGroupIndex
+	// ItemStrlen represents the strlen function in BQL.
+	ItemStrlen
+	// ItemSubstr represents the substr function in BQL.
+	ItemSubstr
+	// ItemDeclare represents the declare keyword in BQL.
+	ItemDeclare
+	// ItemStrict represents the strict keyword in BQL.
+	ItemStrict
+	// ItemTrace represents the trace keyword in BQL.
+	ItemTrace
+	// ItemAnchors represents the anchors keyword in BQL.
+	ItemAnchors
+	// ItemPredicateKeyword represents the predicate keyword in BQL.
+	ItemPredicateKeyword
+	// ItemAnchor represents the anchor keyword in BQL.
+	ItemAnchor
+	// ItemAbsent represents the absent keyword in BQL.
+	ItemAbsent
+	// ItemKill represents the kill keyword in BQL.
+	ItemKill
+	// ItemQueryKeyword represents the query keyword in BQL.
+	ItemQueryKeyword
+	// ItemQuotedString represents a quoted string that is neither a predicate
+	// nor a literal. It is used for predicate IDs following the ID keyword, as
+	// in ?p ID "bought", and for GROUP_CONCAT separators.
+	ItemQuotedString
+	// ItemGroupConcat represents the group_concat function in BQL.
+	ItemGroupConcat
+	// ItemReplacing represents the replacing keyword in BQL.
+	ItemReplacing
+	// ItemView represents the view keyword in BQL.
+	ItemView
+	// ItemRefresh represents the refresh keyword in BQL.
+	ItemRefresh
+	// ItemHistogram represents the histogram function in BQL.
+	ItemHistogram
+	// ItemDatatype represents the datatype function in BQL.
+	ItemDatatype
+	// ItemStar represents the projection of all the bindings in BQL.
+	ItemStar
+	// ItemUnder represents the under keyword in BQL.
+	ItemUnder
+	// ItemNodeType represents a node type path, like /item/book, in BQL.
+	ItemNodeType
+	// ItemGraphCount represents the graph_count function in BQL.
+	ItemGraphCount
+	// ItemTriple represents the triple function in BQL.
+	ItemTriple
 )
 
 func (tt TokenType) String() string {
@@ -261,6 +347,90 @@ func (tt TokenType) String() string {
 		return "FILTER"
 	case ItemFilterFunction:
 		return "FILTER_FUNCTION"
+	case ItemUnwind:
+		return "UNWIND"
+	case ItemDump:
+		return "DUMP"
+	case ItemIf:
+		return "IF"
+	case ItemExists:
+		return "EXISTS"
+	case ItemCast:
+		return "CAST"
+	case ItemLiteralType:
+		return "LITERAL_TYPE"
+	case ItemBucket:
+		return "BUCKET"
+	case ItemClear:
+		return "CLEAR"
+	case ItemStore:
+		return "STORE"
+	case ItemLang:
+		return "LANG"
+	case ItemTruncate:
+		return "TRUNCATE"
+	case ItemNulls:
+		return "NULLS"
+	case ItemFirst:
+		return "FIRST"
+	case ItemLast:
+		return "LAST"
+	case ItemKind:
+		return "KIND"
+	case ItemMerge:
+		return "MERGE"
+	case ItemReporting:
+		return "REPORTING"
+	case ItemConflicts:
+		return "CONFLICTS"
+	case ItemGroupIndex:
+		return "GROUP_INDEX"
+	case ItemStrlen:
+		return "STRLEN"
+	case ItemSubstr:
+		return "SUBSTR"
+	case ItemDeclare:
+		return "DECLARE"
+	case ItemStrict:
+		return "STRICT"
+	case ItemTrace:
+		return "TRACE"
+	case ItemAnchors:
+		return "ANCHORS"
+	case ItemPredicateKeyword:
+		return "PREDICATE"
+	case ItemAnchor:
+		return "ANCHOR"
+	case ItemAbsent:
+		return "ABSENT"
+	case ItemKill:
+		return "KILL"
+	case ItemQueryKeyword:
+		return "QUERY"
+	case ItemQuotedString:
+		return "QUOTED_STRING"
+	case ItemGroupConcat:
+		return "GROUP_CONCAT"
+	case ItemReplacing:
+		return "REPLACING"
+	case ItemView:
+		return "VIEW"
+	case ItemRefresh:
+		return "REFRESH"
+	case ItemHistogram:
+		return "HISTOGRAM"
+	case ItemDatatype:
+		return "DATATYPE"
+	case ItemStar:
+		return "STAR"
+	case ItemUnder:
+		return "UNDER"
+	case ItemNodeType:
+		return "NODE_TYPE"
+	case ItemGraphCount:
+		return "GRAPH_COUNT"
+	case ItemTriple:
+		return "TRIPLE"
 	default:
 		return "UNKNOWN"
 	}
@@ -289,6 +459,11 @@ const (
 	hat            = rune('^')
 	at             = rune('@')
 	newLine        = rune('\n')
+	hash           = rune('#')
+	minus          = rune('-')
+	star           = rune('*')
+	blockStart     = "/*"
+	blockEnd       = "*/"
 	query          = "select"
 	insert         = "insert"
 	delete         = "delete"
@@ -326,6 +501,44 @@ const (
 	inKeyword      = "in"
 	showKeyword    = "show"
 	graphsKeyword  = "graphs"
+	unwind         = "unwind"
+	dump           = "dump"
+	ifKeyword      = "if"
+	exists         = "exists"
+	cast           = "cast"
+	bucket         = "bucket"
+	clear          = "clear"
+	store          = "store"
+	lang           = "lang"
+	truncate       = "truncate"
+	nulls          = "nulls"
+	first          = "first"
+	last           = "last"
+	kind           = "kind"
+	merge          = "merge"
+	reporting      = "reporting"
+	replacing      = "replacing"
+	conflicts      = "conflicts"
+	groupIndex     = "group_index"
+	groupConcat    = "group_concat"
+	strlen         = "strlen"
+	substr         = "substr"
+	declare        = "declare"
+	strict         = "strict"
+	trace          = "trace"
+	anchors        = "anchors"
+	predicateWord  = "predicate"
+	anchorWord     = "anchor"
+	absent         = "absent"
+	kill           = "kill"
+	queryWord      = "query"
+	view           = "view"
+	refresh        = "refresh"
+	histogram      = "histogram"
+	datatype       = "datatype"
+	under          = "under"
+	graphCount     = "graph_count"
+	tripleWord     = "triple"
 	anchor         = "\"@["
 	literalType    = "\"^^type:"
 	literalBool    = "bool"
@@ -333,6 +546,7 @@ const (
 	literalFloat   = "float64"
 	literalText    = "text"
 	literalBlob    = "blob"
+	literalList    = "list"
 )
 
 // Token contains the type and text collected around the captured token.
@@ -340,6 +554,8 @@ type Token struct {
 	Type         TokenType
 	Text         string
 	ErrorMessage string
+	Line         int // Line of the first rune of the token, starting at 1.
+	Col          int // Column of the first rune of the token, starting at 1.
 }
 
 // String returns a readable form of the token.
@@ -361,7 +577,10 @@ type lexer struct {
 	lastLine      int        // last line number for error reporting.
 	col           int        // current column number for error reporting.
 	lastCol       int        // last column number for error reporting.
+	startLine     int        // line number where the current item starts.
+	startCol      int        // column number where the current item starts.
 	lastTokenType TokenType  // type of the last token parsed (useful when parsing specific predicates)
+	inStringArgs  bool       // whether the arguments of a GROUP_CONCAT, HISTOGRAM or BUCKET are being scanned.
 	tokens        chan Token // channel of scanned items.
 }
 
@@ -402,7 +621,15 @@ func lexToken(l *lexer) stateFn {
 			case binding:
 				l.next()
 				return lexBinding
+			case hash:
+				return lexLineComment
 			case slash:
+				if strings.HasPrefix(l.input[l.pos:], blockStart) {
+					return lexBlockComment
+				}
+				if l.lastTokenType == ItemUnder || l.lastTokenType == ItemType {
+					return lexNodeType
+				}
 				return lexNode
 			case underscore:
 				l.next()
@@ -438,6 +665,9 @@ func lexToken(l *lexer) stateFn {
 		if state := isSingleSymbolToken(l, ItemComma, comma); state != nil {
 			return state
 		}
+		if state := isSingleSymbolToken(l, ItemStar, star); state != nil {
+			return state
+		}
 		if state := isSingleSymbolToken(l, ItemLT, lt); state != nil {
 			return state
 		}
@@ -496,11 +726,38 @@ func lexSpace(l *lexer) stateFn {
 	return lexToken
 }
 
+// lexLineComment consumes a comment that runs until the end of the line
+// without emitting any token.
+func lexLineComment(l *lexer) stateFn {
+	for {
+		if r := l.next(); r == newLine || r == eof {
+			break
+		}
+	}
+	l.ignore()
+	return lexSpace
+}
+
+// lexBlockComment consumes a comment delimited by /* and */ without emitting
+// any token. Block comments may span multiple lines.
+func lexBlockComment(l *lexer) stateFn {
+	l.consume(blockStart)
+	for !strings.HasPrefix(l.input[l.pos:], blockEnd) {
+		if r := l.next(); r == eof {
+			l.emitError("block comment is not properly terminated; missing final */")
+			return nil
+		}
+	}
+	l.consume(blockEnd)
+	l.ignore()
+	return lexSpace
+}
+
 // lexKeyword lexes the BQL keywords.
 func lexKeyword(l *lexer) stateFn {
 	input := l.input[l.pos:]
 	f := func(r rune) bool {
-		return !unicode.IsLetter(r)
+		return !isKeywordRune(r)
 	}
 	if idx := strings.IndexFunc(input, f); idx >= 0 {
 		input = input[:idx]
@@ -634,6 +891,9 @@ func lexKeyword(l *lexer) stateFn {
 		return lexSpace
 	}
 	if strings.EqualFold(input, typeKeyword) {
+		if strings.HasPrefix(l.input[l.pos+len(input):], ":") {
+			return lexLiteralType
+		}
 		consumeKeyword(l, ItemType)
 		return lexSpace
 	}
@@ -653,6 +913,158 @@ func lexKeyword(l *lexer) stateFn {
 		consumeKeyword(l, ItemGraphs)
 		return lexSpace
 	}
+	if strings.EqualFold(input, unwind) {
+		consumeKeyword(l, ItemUnwind)
+		return lexSpace
+	}
+	if strings.EqualFold(input, dump) {
+		consumeKeyword(l, ItemDump)
+		return lexSpace
+	}
+	if strings.EqualFold(input, ifKeyword) {
+		consumeKeyword(l, ItemIf)
+		return lexSpace
+	}
+	if strings.EqualFold(input, exists) {
+		consumeKeyword(l, ItemExists)
+		return lexSpace
+	}
+	if strings.EqualFold(input, cast) {
+		consumeKeyword(l, ItemCast)
+		return lexSpace
+	}
+	if strings.EqualFold(input, bucket) {
+		consumeKeyword(l, ItemBucket)
+		return lexSpace
+	}
+	if strings.EqualFold(input, clear) {
+		consumeKeyword(l, ItemClear)
+		return lexSpace
+	}
+	if strings.EqualFold(input, store) {
+		consumeKeyword(l, ItemStore)
+		return lexSpace
+	}
+	if strings.EqualFold(input, lang) {
+		consumeKeyword(l, ItemLang)
+		return lexSpace
+	}
+	if strings.EqualFold(input, truncate) {
+		consumeKeyword(l, ItemTruncate)
+		return lexSpace
+	}
+	if strings.EqualFold(input, nulls) {
+		consumeKeyword(l, ItemNulls)
+		return lexSpace
+	}
+	if strings.EqualFold(input, first) {
+		consumeKeyword(l, ItemFirst)
+		return lexSpace
+	}
+	if strings.EqualFold(input, last) {
+		consumeKeyword(l, ItemLast)
+		return lexSpace
+	}
+	if strings.EqualFold(input, kind) {
+		consumeKeyword(l, ItemKind)
+		return lexSpace
+	}
+	if strings.EqualFold(input, merge) {
+		consumeKeyword(l, ItemMerge)
+		return lexSpace
+	}
+	if strings.EqualFold(input, reporting) {
+		consumeKeyword(l, ItemReporting)
+		return lexSpace
+	}
+	if strings.EqualFold(input, replacing) {
+		consumeKeyword(l, ItemReplacing)
+		return lexSpace
+	}
+	if strings.EqualFold(input, conflicts) {
+		consumeKeyword(l, ItemConflicts)
+		return lexSpace
+	}
+	if strings.EqualFold(input, groupIndex) {
+		consumeKeyword(l, ItemGroupIndex)
+		return lexSpace
+	}
+	if strings.EqualFold(input, groupConcat) {
+		consumeKeyword(l, ItemGroupConcat)
+		return lexSpace
+	}
+	if strings.EqualFold(input, strlen) {
+		consumeKeyword(l, ItemStrlen)
+		return lexSpace
+	}
+	if strings.EqualFold(input, substr) {
+		consumeKeyword(l, ItemSubstr)
+		return lexSpace
+	}
+	if strings.EqualFold(input, declare) {
+		consumeKeyword(l, ItemDeclare)
+		return lexSpace
+	}
+	if strings.EqualFold(input, strict) {
+		consumeKeyword(l, ItemStrict)
+		return lexSpace
+	}
+	if strings.EqualFold(input, trace) {
+		consumeKeyword(l, ItemTrace)
+		return lexSpace
+	}
+	if strings.EqualFold(input, anchors) {
+		consumeKeyword(l, ItemAnchors)
+		return lexSpace
+	}
+	if strings.EqualFold(input, predicateWord) {
+		consumeKeyword(l, ItemPredicateKeyword)
+		return lexSpace
+	}
+	if strings.EqualFold(input, anchorWord) {
+		consumeKeyword(l, ItemAnchor)
+		return lexSpace
+	}
+	if strings.EqualFold(input, absent) {
+		consumeKeyword(l, ItemAbsent)
+		return lexSpace
+	}
+	if strings.EqualFold(input, kill) {
+		consumeKeyword(l, ItemKill)
+		return lexSpace
+	}
+	if strings.EqualFold(input, queryWord) {
+		consumeKeyword(l, ItemQueryKeyword)
+		return lexSpace
+	}
+	if strings.EqualFold(input, view) {
+		consumeKeyword(l, ItemView)
+		return lexSpace
+	}
+	if strings.EqualFold(input, refresh) {
+		consumeKeyword(l, ItemRefresh)
+		return lexSpace
+	}
+	if strings.EqualFold(input, histogram) {
+		consumeKeyword(l, ItemHistogram)
+		return lexSpace
+	}
+	if strings.EqualFold(input, datatype) {
+		consumeKeyword(l, ItemDatatype)
+		return lexSpace
+	}
+	if strings.EqualFold(input, under) {
+		consumeKeyword(l, ItemUnder)
+		return lexSpace
+	}
+	if strings.EqualFold(input, graphCount) {
+		consumeKeyword(l, ItemGraphCount)
+		return lexSpace
+	}
+	if strings.EqualFold(input, tripleWord) {
+		consumeKeyword(l, ItemTriple)
+		return lexSpace
+	}
 	for {
 		r := l.next()
 		if unicode.IsSpace(r) || r == eof {
@@ -664,6 +1076,28 @@ func lexKeyword(l *lexer) stateFn {
 	return nil
 }
 
+// lexLiteralType lexes a literal type name of the form type:<name> (used in
+// CAST functions).
+func lexLiteralType(l *lexer) stateFn {
+	for r := l.next(); r != ':'; r = l.next() {
+	}
+	n := 0
+	for {
+		r := l.next()
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r == eof {
+			l.backup()
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		l.emitError("literal type names require a type after type:")
+		return nil
+	}
+	l.emit(ItemLiteralType)
+	return lexSpace
+}
+
 // lexFilterFunction lexes a filter function out of the input (used in FILTER clauses).
 func lexFilterFunction(l *lexer) stateFn {
 	l.next()
@@ -688,7 +1122,8 @@ func lexNode(l *lexer) stateFn {
 	for done := false; !done; {
 		switch r := l.next(); r {
 		case backSlash:
-			if nr := l.peek(); nr == lt {
+			// Escaped characters, including the delimiters, are part of the node.
+			if nr := l.peek(); nr != eof {
 				l.next()
 				continue
 			}
@@ -709,6 +1144,24 @@ func lexNode(l *lexer) stateFn {
 	return lexSpace
 }
 
+// lexNodeType lexes a node type path, which runs until the next space or
+// punctuation.
+func lexNodeType(l *lexer) stateFn {
+	for {
+		r := l.next()
+		if unicode.IsSpace(r) || r == eof || r == rightPar || r == rightBracket || r == semicolon {
+			l.backup()
+			break
+		}
+		if r == lt || r == gt {
+			l.emitError("node type paths cannot contain an ID section")
+			return nil
+		}
+	}
+	l.emit(ItemNodeType)
+	return lexSpace
+}
+
 // lexBlankNode tries to lex a blank node out of the input
 func lexBlankNode(l *lexer) stateFn {
 	if r := l.next(); r != colon {
@@ -732,6 +1185,10 @@ func lexBlankNode(l *lexer) stateFn {
 // lexPredicateOrLiteral tries to lex a predicate or a literal out of the input.
 func lexPredicateOrLiteral(l *lexer) stateFn {
 	text := l.input[l.pos:]
+	quotable := l.lastTokenType == ItemID || l.lastTokenType == ItemQueryKeyword || (l.lastTokenType == ItemComma && l.inStringArgs)
+	if quotable && isQuotedString(text) {
+		return lexQuotedString
+	}
 	// Fix issue 39 (https://github.com/google/badwolf/issues/39)
 	pIdx, lIdx := strings.Index(text, "\"@["), strings.Index(text, "\"^^type:")
 	if pIdx < 0 && lIdx < 0 {
@@ -744,6 +1201,38 @@ func lexPredicateOrLiteral(l *lexer) stateFn {
 	return lexLiteral
 }
 
+// isQuotedString returns true if the text starts with a quoted string that is not
+// followed by the anchor of a predicate or the type of a literal.
+func isQuotedString(text string) bool {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			rest := text[i+1:]
+			return !strings.HasPrefix(rest, "@[") && !strings.HasPrefix(rest, "^^")
+		}
+	}
+	return false
+}
+
+// lexQuotedString lexes a quoted string out of the input.
+func lexQuotedString(l *lexer) stateFn {
+	l.next()
+	for {
+		switch r := l.next(); r {
+		case backSlash:
+			l.next()
+		case quote:
+			l.emit(ItemQuotedString)
+			return lexSpace
+		case eof:
+			l.emitError("quoted strings need to be properly terminated; missing \"")
+			return nil
+		}
+	}
+}
+
 // lexPredicate lexes a predicate out of the input.
 func lexPredicate(l *lexer) stateFn {
 	l.next()
@@ -761,14 +1250,24 @@ func lexPredicate(l *lexer) stateFn {
 				return nil
 			}
 			var (
-				nr     rune
-				commas = 0
+				nr         rune
+				first      rune // First non space rune of the time anchor.
+				commas     = 0
+				semicolons = 0
+				ranges     = true // Whether all the ; separated ranges have one ,.
 			)
 			for {
 				nr = l.next()
+				if first == 0 && !unicode.IsSpace(nr) {
+					first = nr
+				}
 				if nr == comma {
 					commas++
 				}
+				if nr == semicolon {
+					ranges = ranges && commas == 1
+					semicolons, commas = semicolons+1, 0
+				}
 				if nr == rightSquarePar || nr == eof {
 					break
 				}
@@ -777,10 +1276,27 @@ func lexPredicate(l *lexer) stateFn {
 				l.emitError("predicate's time anchors should end with ] delimiter")
 				return nil
 			}
+			if semicolons > 0 {
+				if !ranges || commas != 1 {
+					l.emitError("predicate bound ranges should be separated by ; and each have one , to separate bounds")
+					return nil
+				}
+				l.emit(ItemPredicateBound)
+				return lexSpace
+			}
 			if commas > 1 {
 				l.emitError("predicate bounds should only have one , to separate bounds")
 				return nil
 			}
+			if first == lt || first == gt {
+				// Inline anchor comparisons (eg: "bought"@[> 2016-02-01T00:00:00Z]) bound the predicate.
+				if commas > 0 {
+					l.emitError("predicate anchor comparisons cannot be combined with , separated bounds")
+					return nil
+				}
+				l.emit(ItemPredicateBound)
+				return lexSpace
+			}
 			if commas == 0 {
 				l.emit(ItemPredicate)
 			} else {
@@ -856,6 +1372,13 @@ func lexTime(l *lexer) stateFn {
 
 // lexLiteral lexes a literal out of the input.
 func lexLiteral(l *lexer) stateFn {
+	if n := listLiteralLength(l.input[l.pos:]); n > 0 {
+		for end := l.pos + n; l.pos < end; {
+			l.next()
+		}
+		l.emit(ItemLiteral)
+		return lexSpace
+	}
 	l.next()
 	for done := false; !done; {
 		switch r := l.next(); r {
@@ -871,8 +1394,9 @@ func lexLiteral(l *lexer) stateFn {
 				return nil
 			}
 			literalT := ""
+			var r rune
 			for {
-				r := l.next()
+				r = l.next()
 				if !(unicode.IsLetter(r) || unicode.IsDigit(r)) || r == eof {
 					break
 				}
@@ -881,6 +1405,20 @@ func lexLiteral(l *lexer) stateFn {
 			literalT = strings.ToLower(literalT)
 			switch literalT {
 			case literalBool, literalInt, literalFloat, literalText, literalBlob:
+				if r == at && literalT != literalText {
+					l.emitError("only text literals can carry a language tag")
+					return nil
+				}
+				if r == at {
+					// Text literals may carry a language tag; for instance,
+					// "hola"^^type:text@es.
+					for {
+						r = l.next()
+						if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == minus) || r == eof {
+							break
+						}
+					}
+				}
 				l.backup()
 				l.emit(ItemLiteral)
 				done = true
@@ -896,10 +1434,62 @@ func lexLiteral(l *lexer) stateFn {
 	return lexSpace
 }
 
+// listLiteralLength returns the length of the list literal at the beginning of
+// the provided text or -1 if the text does not start with a list literal. List
+// literals wrap comma separated scalar literals in [ and ]; for instance,
+// "["foo"^^type:text, "1"^^type:int64]"^^type:list.
+func listLiteralLength(text string) int {
+	if !strings.HasPrefix(text, "\"[") {
+		return -1
+	}
+	skipSpaces := func(i int) int {
+		for i < len(text) && unicode.IsSpace(rune(text[i])) {
+			i++
+		}
+		return i
+	}
+	i := skipSpaces(2)
+	for i < len(text) && text[i] != ']' {
+		if text[i] != '"' {
+			return -1
+		}
+		idx := strings.Index(text[i+1:], literalType)
+		if idx < 0 {
+			return -1
+		}
+		i += 1 + idx + len(literalType)
+		start := i
+		for i < len(text) && (unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i]))) {
+			i++
+		}
+		switch strings.ToLower(text[start:i]) {
+		case literalBool, literalInt, literalFloat, literalText, literalBlob:
+		default:
+			return -1
+		}
+		i = skipSpaces(i)
+		if i >= len(text) || text[i] != ',' {
+			break
+		}
+		i = skipSpaces(i + 1)
+		if i < len(text) && text[i] == ']' {
+			return -1
+		}
+	}
+	if !strings.HasPrefix(text[i:], "]"+literalType+literalList) {
+		return -1
+	}
+	i += len("]" + literalType + literalList)
+	if i < len(text) && (unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i]))) {
+		return -1
+	}
+	return i
+}
+
 // consumeKeyword consume and emits a valid token
 func consumeKeyword(l *lexer, t TokenType) {
 	for {
-		if r := l.next(); !unicode.IsLetter(r) || r == eof {
+		if r := l.next(); !isKeywordRune(r) || r == eof {
 			l.backup()
 			l.emit(t)
 			break
@@ -907,6 +1497,13 @@ func consumeKeyword(l *lexer, t TokenType) {
 	}
 }
 
+// isKeywordRune returns true if the rune can be part of a keyword. Keywords
+// are formed by letters, and underscores to separate the words of multiword
+// functions such as GROUP_INDEX.
+func isKeywordRune(r rune) bool {
+	return unicode.IsLetter(r) || r == underscore
+}
+
 // run lexes the input by executing state functions until the state is nil.
 func (l *lexer) run() {
 	for state := lexToken(l); state != nil; {
@@ -920,9 +1517,17 @@ func (l *lexer) emit(t TokenType) {
 	l.tokens <- Token{
 		Type: t,
 		Text: l.input[l.start:l.pos],
+		Line: l.startLine + 1,
+		Col:  l.startCol + 1,
 	}
-	l.start = l.pos
+	l.ignore()
 	l.lastTokenType = t
+	switch t {
+	case ItemGroupConcat, ItemHistogram, ItemBucket:
+		l.inStringArgs = true
+	case ItemRPar:
+		l.inStringArgs = false
+	}
 }
 
 // emitError passes and error to the client with proper error messaging.
@@ -931,13 +1536,16 @@ func (l *lexer) emitError(msg string) {
 		Type:         ItemError,
 		Text:         l.input[l.start:l.pos],
 		ErrorMessage: fmt.Sprintf("[lexer:%d:%d] %s", l.line, l.col, msg),
+		Line:         l.startLine + 1,
+		Col:          l.startCol + 1,
 	}
-	l.start = l.pos
+	l.ignore()
 }
 
 // ignore skips over the pending input before this point.
 func (l *lexer) ignore() {
 	l.start = l.pos
+	l.startLine, l.startCol = l.line, l.col
 }
 
 // backup steps back one rune. Can be called only once per call of next.
@@ -950,6 +1558,7 @@ func (l *lexer) backup() {
 func (l *lexer) next() rune {
 	if l.pos >= len(l.input) {
 		l.width = 0
+		l.lastCol, l.lastLine = l.col, l.line
 		return eof
 	}
 	var r rune
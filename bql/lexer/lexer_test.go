@@ -78,6 +78,48 @@ func TestTokenTypeString(t *testing.T) {
 		{ItemOptional, "OPTIONAL"},
 		{ItemFilter, "FILTER"},
 		{ItemFilterFunction, "FILTER_FUNCTION"},
+		{ItemUnwind, "UNWIND"},
+		{ItemDump, "DUMP"},
+		{ItemIf, "IF"},
+		{ItemExists, "EXISTS"},
+		{ItemCast, "CAST"},
+		{ItemLiteralType, "LITERAL_TYPE"},
+		{ItemBucket, "BUCKET"},
+		{ItemClear, "CLEAR"},
+		{ItemStore, "STORE"},
+		{ItemLang, "LANG"},
+		{ItemTruncate, "TRUNCATE"},
+		{ItemNulls, "NULLS"},
+		{ItemFirst, "FIRST"},
+		{ItemLast, "LAST"},
+		{ItemKind, "KIND"},
+		{ItemMerge, "MERGE"},
+		{ItemReporting, "REPORTING"},
+		{ItemConflicts, "CONFLICTS"},
+		{ItemGroupIndex, "GROUP_INDEX"},
+		{ItemStrlen, "STRLEN"},
+		{ItemSubstr, "SUBSTR"},
+		{ItemDeclare, "DECLARE"},
+		{ItemStrict, "STRICT"},
+		{ItemTrace, "TRACE"},
+		{ItemAnchors, "ANCHORS"},
+		{ItemPredicateKeyword, "PREDICATE"},
+		{ItemAnchor, "ANCHOR"},
+		{ItemAbsent, "ABSENT"},
+		{ItemKill, "KILL"},
+		{ItemQueryKeyword, "QUERY"},
+		{ItemQuotedString, "QUOTED_STRING"},
+		{ItemGroupConcat, "GROUP_CONCAT"},
+		{ItemReplacing, "REPLACING"},
+		{ItemView, "VIEW"},
+		{ItemRefresh, "REFRESH"},
+		{ItemHistogram, "HISTOGRAM"},
+		{ItemDatatype, "DATATYPE"},
+		{ItemStar, "STAR"},
+		{ItemUnder, "UNDER"},
+		{ItemNodeType, "NODE_TYPE"},
+		{ItemGraphCount, "GRAPH_COUNT"},
+		{ItemTriple, "TRIPLE"},
 		{TokenType(-1), "UNKNOWN"},
 	}
 
@@ -183,6 +225,49 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF},
 			},
 		},
+		{
+			`/_<a\>b> /_<c\\> /_<\<d\>>`,
+			[]Token{
+				{Type: ItemNode, Text: `/_<a\>b>`},
+				{Type: ItemNode, Text: `/_<c\\>`},
+				{Type: ItemNode, Text: `/_<\<d\>>`},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			"?a # a comment at the end of the line\n?b #",
+			[]Token{
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			"?a/* a comment\nspanning lines */?b /**/ ?c",
+			[]Token{
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemBinding, Text: "?c"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			"?a /* a comment that never ends",
+			[]Token{
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemError, Text: "/* a comment that never ends",
+					ErrorMessage: "[lexer:0:31] block comment is not properly terminated; missing final */"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			"# a comment\n/* another\n comment */ /_foo>",
+			[]Token{
+				{Type: ItemError, Text: "/_foo>",
+					ErrorMessage: "[lexer:2:18] node should start ID section with a < delimiter"},
+				{Type: ItemEOF},
+			},
+		},
 		{
 			"/_foo>",
 			[]Token{
@@ -278,6 +363,16 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF},
 			},
 		},
+		{
+			`"p"@[2015-01-01T00:00:00Z,2015-02-01T00:00:00Z; 2016-01-01T00:00:00Z,] "p"@[a,b;c]`,
+			[]Token{
+				{Type: ItemPredicateBound, Text: `"p"@[2015-01-01T00:00:00Z,2015-02-01T00:00:00Z; 2016-01-01T00:00:00Z,]`},
+				{Type: ItemError,
+					Text:         `"p"@[a,b;c]`,
+					ErrorMessage: "[lexer:0:82] predicate bound ranges should be separated by ; and each have one , to separate bounds"},
+				{Type: ItemEOF},
+			},
+		},
 		{
 			`"p\"1"@[]`,
 			[]Token{
@@ -357,6 +452,23 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF},
 			},
 		},
+		{
+			// Relaxed time anchors are left to predicate.ParseTimeAnchor.
+			`BEFORE 2010-03-10T4:00:00.5-08:00;`,
+			[]Token{
+				{Type: ItemBefore, Text: "BEFORE"},
+				{Type: ItemTime, Text: `2010-03-10T4:00:00.5-08:00`},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`"met"@[2010-03-10T4:00:00Z]`,
+			[]Token{
+				{Type: ItemPredicate, Text: `"met"@[2010-03-10T4:00:00Z]`},
+				{Type: ItemEOF},
+			},
+		},
 		{
 			`HAVING ?time < 2010-03-10T00:00:00-08:00;`,
 			[]Token{
@@ -401,6 +513,472 @@ func TestIndividualTokens(t *testing.T) {
 				{Type: ItemEOF},
 			},
 		},
+		{
+			`CREATE GRAPH IF NOT EXISTS ?a; drop graph if exists ?a;`,
+			[]Token{
+				{Type: ItemCreate, Text: "CREATE"},
+				{Type: ItemGraph, Text: "GRAPH"},
+				{Type: ItemIf, Text: "IF"},
+				{Type: ItemNot, Text: "NOT"},
+				{Type: ItemExists, Text: "EXISTS"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemDrop, Text: "drop"},
+				{Type: ItemGraph, Text: "graph"},
+				{Type: ItemIf, Text: "if"},
+				{Type: ItemExists, Text: "exists"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`CAST(?x, type:int64) cast(?y,TYPE:text) ?s TYPE ?t`,
+			[]Token{
+				{Type: ItemCast, Text: "CAST"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?x"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemLiteralType, Text: "type:int64"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemCast, Text: "cast"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?y"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemLiteralType, Text: "TYPE:text"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemType, Text: "TYPE"},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`GROUP BY BUCKET(?t, "1h"^^type:text)`,
+			[]Token{
+				{Type: ItemGroup, Text: "GROUP"},
+				{Type: ItemBy, Text: "BY"},
+				{Type: ItemBucket, Text: "BUCKET"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemLiteral, Text: `"1h"^^type:text`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`GROUP BY BUCKET(?t, "1h")`,
+			[]Token{
+				{Type: ItemGroup, Text: "GROUP"},
+				{Type: ItemBy, Text: "BY"},
+				{Type: ItemBucket, Text: "BUCKET"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemQuotedString, Text: `"1h"`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`CLEAR STORE; clear store;`,
+			[]Token{
+				{Type: ItemClear, Text: "CLEAR"},
+				{Type: ItemStore, Text: "STORE"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemClear, Text: "clear"},
+				{Type: ItemStore, Text: "store"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`TRUNCATE GRAPH ?a; truncate graph ?b;`,
+			[]Token{
+				{Type: ItemTruncate, Text: "TRUNCATE"},
+				{Type: ItemGraph, Text: "GRAPH"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemTruncate, Text: "truncate"},
+				{Type: ItemGraph, Text: "graph"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`?a ASC NULLS LAST, ?b nulls first`,
+			[]Token{
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemAsc, Text: "ASC"},
+				{Type: ItemNulls, Text: "NULLS"},
+				{Type: ItemLast, Text: "LAST"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemNulls, Text: "nulls"},
+				{Type: ItemFirst, Text: "first"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`LANG(?o) "hola"^^type:text@es "hi"^^type:text@en-US, "1"^^type:int64@es`,
+			[]Token{
+				{Type: ItemLang, Text: "LANG"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemLiteral, Text: `"hola"^^type:text@es`},
+				{Type: ItemLiteral, Text: `"hi"^^type:text@en-US`},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemError, Text: `"1"^^type:int64@`, ErrorMessage: "[lexer:0:69] only text literals can carry a language tag"},
+			},
+		},
+		{
+			`KIND(?p) kind`,
+			[]Token{
+				{Type: ItemKind, Text: "KIND"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?p"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemKind, Text: "kind"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`DATATYPE(?o) AS ?dt`,
+			[]Token{
+				{Type: ItemDatatype, Text: "DATATYPE"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemAs, Text: "AS"},
+				{Type: ItemBinding, Text: "?dt"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`SELECT * FROM ?g;`,
+			[]Token{
+				{Type: ItemQuery, Text: "SELECT"},
+				{Type: ItemStar, Text: "*"},
+				{Type: ItemFrom, Text: "FROM"},
+				{Type: ItemBinding, Text: "?g"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`HAVING ?s UNDER /item/book AND (?o under /room);`,
+			[]Token{
+				{Type: ItemHaving, Text: "HAVING"},
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemUnder, Text: "UNDER"},
+				{Type: ItemNodeType, Text: "/item/book"},
+				{Type: ItemAnd, Text: "AND"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemUnder, Text: "under"},
+				{Type: ItemNodeType, Text: "/room"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`{?s ?p ?o TYPE /u} {?s ?p ?o type ?t}`,
+			[]Token{
+				{Type: ItemLBracket, Text: "{"},
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemBinding, Text: "?p"},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemType, Text: "TYPE"},
+				{Type: ItemNodeType, Text: "/u"},
+				{Type: ItemRBracket, Text: "}"},
+				{Type: ItemLBracket, Text: "{"},
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemBinding, Text: "?p"},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemType, Text: "type"},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemRBracket, Text: "}"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`GROUP_INDEX() AS ?idx group_index`,
+			[]Token{
+				{Type: ItemGroupIndex, Text: "GROUP_INDEX"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemAs, Text: "AS"},
+				{Type: ItemBinding, Text: "?idx"},
+				{Type: ItemGroupIndex, Text: "group_index"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`GRAPH_COUNT() AS ?gc graph_count graph`,
+			[]Token{
+				{Type: ItemGraphCount, Text: "GRAPH_COUNT"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemAs, Text: "AS"},
+				{Type: ItemBinding, Text: "?gc"},
+				{Type: ItemGraphCount, Text: "graph_count"},
+				{Type: ItemGraph, Text: "graph"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`TRIPLE(?s, ?p, ?o) AS ?t`,
+			[]Token{
+				{Type: ItemTriple, Text: "TRIPLE"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?p"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemAs, Text: "AS"},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`STRLEN(?o) substr(?o, "1"^^type:int64, "2"^^type:int64)`,
+			[]Token{
+				{Type: ItemStrlen, Text: "STRLEN"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemSubstr, Text: "substr"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemLiteral, Text: `"1"^^type:int64`},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemLiteral, Text: `"2"^^type:int64`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`"bought"@[> 2016-02-01T00:00:00Z] "bought"@[<=2016-02-01T00:00:00Z]`,
+			[]Token{
+				{Type: ItemPredicateBound, Text: `"bought"@[> 2016-02-01T00:00:00Z]`},
+				{Type: ItemPredicateBound, Text: `"bought"@[<=2016-02-01T00:00:00Z]`},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`ANCHORS(/u<peter>, "bought"@[?t]) FROM ?g;`,
+			[]Token{
+				{Type: ItemAnchors, Text: "ANCHORS"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemNode, Text: "/u<peter>"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemPredicate, Text: `"bought"@[?t]`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemFrom, Text: "FROM"},
+				{Type: ItemBinding, Text: "?g"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`DELETE FROM ?g WHERE PREDICATE ANCHOR BEFORE 2015-01-01T00:00:00Z;`,
+			[]Token{
+				{Type: ItemDelete, Text: "DELETE"},
+				{Type: ItemFrom, Text: "FROM"},
+				{Type: ItemBinding, Text: "?g"},
+				{Type: ItemWhere, Text: "WHERE"},
+				{Type: ItemPredicateKeyword, Text: "PREDICATE"},
+				{Type: ItemAnchor, Text: "ANCHOR"},
+				{Type: ItemBefore, Text: "BEFORE"},
+				{Type: ItemTime, Text: "2015-01-01T00:00:00Z"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`INSERT DATA INTO ?g IF ABSENT {/u<a> "p"@[] /u<b>};`,
+			[]Token{
+				{Type: ItemInsert, Text: "INSERT"},
+				{Type: ItemData, Text: "DATA"},
+				{Type: ItemInto, Text: "INTO"},
+				{Type: ItemBinding, Text: "?g"},
+				{Type: ItemIf, Text: "IF"},
+				{Type: ItemAbsent, Text: "ABSENT"},
+				{Type: ItemLBracket, Text: "{"},
+				{Type: ItemNode, Text: "/u<a>"},
+				{Type: ItemPredicate, Text: `"p"@[]`},
+				{Type: ItemNode, Text: "/u<b>"},
+				{Type: ItemRBracket, Text: "}"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`KILL QUERY "q1"^^type:text;`,
+			[]Token{
+				{Type: ItemKill, Text: "KILL"},
+				{Type: ItemQueryKeyword, Text: "QUERY"},
+				{Type: ItemLiteral, Text: `"q1"^^type:text`},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`KILL QUERY "q1";`,
+			[]Token{
+				{Type: ItemKill, Text: "KILL"},
+				{Type: ItemQueryKeyword, Text: "QUERY"},
+				{Type: ItemQuotedString, Text: `"q1"`},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`?s ?p ID "bought" ?o ID "p\"q"@[]`,
+			[]Token{
+				{Type: ItemBinding, Text: "?s"},
+				{Type: ItemBinding, Text: "?p"},
+				{Type: ItemID, Text: "ID"},
+				{Type: ItemQuotedString, Text: `"bought"`},
+				{Type: ItemBinding, Text: "?o"},
+				{Type: ItemID, Text: "ID"},
+				{Type: ItemPredicate, Text: `"p\"q"@[]`},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`group_concat(?n, ", ") as ?ns, ?a, ", "`,
+			[]Token{
+				{Type: ItemGroupConcat, Text: "group_concat"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?n"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemQuotedString, Text: `", "`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemAs, Text: "as"},
+				{Type: ItemBinding, Text: "?ns"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemError, Text: "",
+					ErrorMessage: "[lexer:0:35] failed to parse predicate or literal for opening \" delimiter"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`TRACE "2"^^type:int64 SHOW GRAPHS;`,
+			[]Token{
+				{Type: ItemTrace, Text: "TRACE"},
+				{Type: ItemLiteral, Text: `"2"^^type:int64`},
+				{Type: ItemShow, Text: "SHOW"},
+				{Type: ItemGraphs, Text: "GRAPHS"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`DECLARE strict ?h type:int64, ?n type:text`,
+			[]Token{
+				{Type: ItemDeclare, Text: "DECLARE"},
+				{Type: ItemStrict, Text: "strict"},
+				{Type: ItemBinding, Text: "?h"},
+				{Type: ItemLiteralType, Text: "type:int64"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?n"},
+				{Type: ItemLiteralType, Text: "type:text"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`MERGE ?a INTO ?b reporting conflicts;`,
+			[]Token{
+				{Type: ItemMerge, Text: "MERGE"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemInto, Text: "INTO"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemReporting, Text: "reporting"},
+				{Type: ItemConflicts, Text: "conflicts"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`MERGE ?a INTO ?b REPLACING CONFLICTS;`,
+			[]Token{
+				{Type: ItemMerge, Text: "MERGE"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemInto, Text: "INTO"},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemReplacing, Text: "REPLACING"},
+				{Type: ItemConflicts, Text: "CONFLICTS"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`REFRESH VIEW ?v;`,
+			[]Token{
+				{Type: ItemRefresh, Text: "REFRESH"},
+				{Type: ItemView, Text: "VIEW"},
+				{Type: ItemBinding, Text: "?v"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`HISTOGRAM(?t, "1mo")`,
+			[]Token{
+				{Type: ItemHistogram, Text: "HISTOGRAM"},
+				{Type: ItemLPar, Text: "("},
+				{Type: ItemBinding, Text: "?t"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemQuotedString, Text: `"1mo"`},
+				{Type: ItemRPar, Text: ")"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`DUMP ?a, ?b;`,
+			[]Token{
+				{Type: ItemDump, Text: "DUMP"},
+				{Type: ItemBinding, Text: "?a"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?b"},
+				{Type: ItemSemicolon, Text: ";"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`UNWIND ?l AS ?i, ?m as ?j`,
+			[]Token{
+				{Type: ItemUnwind, Text: "UNWIND"},
+				{Type: ItemBinding, Text: "?l"},
+				{Type: ItemAs, Text: "AS"},
+				{Type: ItemBinding, Text: "?i"},
+				{Type: ItemComma, Text: ","},
+				{Type: ItemBinding, Text: "?m"},
+				{Type: ItemAs, Text: "as"},
+				{Type: ItemBinding, Text: "?j"},
+				{Type: ItemEOF},
+			},
+		},
+		{
+			`"["a, b"^^type:text, "1"^^type:int64]"^^type:list "[]"^^type:list "[ ]"^^type:list "[]"^^type:blob`,
+			[]Token{
+				{Type: ItemLiteral, Text: `"["a, b"^^type:text, "1"^^type:int64]"^^type:list`},
+				{Type: ItemLiteral, Text: `"[]"^^type:list`},
+				{Type: ItemLiteral, Text: `"[ ]"^^type:list`},
+				{Type: ItemLiteral, Text: `"[]"^^type:blob`},
+				{Type: ItemEOF},
+			},
+		},
 		{
 			`FILTER latest(?p) .`,
 			[]Token{
@@ -422,7 +1000,7 @@ func TestIndividualTokens(t *testing.T) {
 			if idx >= len(test.tokens) {
 				t.Fatalf("lex(%q) has not finished producing tokens when it should have.", test.input)
 			}
-			if want := test.tokens[idx]; got != want {
+			if want := test.tokens[idx]; got.Type != want.Type || got.Text != want.Text || got.ErrorMessage != want.ErrorMessage {
 				t.Errorf("lex(%q) failed to provide %+v, got %+v instead", test.input, want, got)
 			}
 			idx++
@@ -430,6 +1008,35 @@ func TestIndividualTokens(t *testing.T) {
 	}
 }
 
+func TestTokenPositions(t *testing.T) {
+	input := "select ?s\nfrom ?g\n  where {?s ?p ?o};"
+	want := []Token{
+		{Type: ItemQuery, Text: "select", Line: 1, Col: 1},
+		{Type: ItemBinding, Text: "?s", Line: 1, Col: 8},
+		{Type: ItemFrom, Text: "from", Line: 2, Col: 1},
+		{Type: ItemBinding, Text: "?g", Line: 2, Col: 6},
+		{Type: ItemWhere, Text: "where", Line: 3, Col: 3},
+		{Type: ItemLBracket, Text: "{", Line: 3, Col: 9},
+		{Type: ItemBinding, Text: "?s", Line: 3, Col: 10},
+		{Type: ItemBinding, Text: "?p", Line: 3, Col: 13},
+		{Type: ItemBinding, Text: "?o", Line: 3, Col: 16},
+		{Type: ItemRBracket, Text: "}", Line: 3, Col: 18},
+		{Type: ItemSemicolon, Text: ";", Line: 3, Col: 19},
+		{Type: ItemEOF, Line: 3, Col: 20},
+	}
+	_, c := lex(input, 0)
+	idx := 0
+	for got := range c {
+		if idx >= len(want) {
+			t.Fatalf("lex(%q) has not finished producing tokens when it should have.", input)
+		}
+		if got != want[idx] {
+			t.Errorf("lex(%q) failed to provide %+v, got %+v instead", input, want[idx], got)
+		}
+		idx++
+	}
+}
+
 func TestValidTokenQuery(t *testing.T) {
 	table := []struct {
 		input  string
@@ -456,6 +1063,22 @@ func TestValidTokenQuery(t *testing.T) {
 				ItemLiteral, ItemRBracket, ItemSemicolon, ItemEOF,
 			},
 		},
+		{
+			`# Select the subjects with some int64 literal.
+			select ?s /* the subject */
+		    from ?foo
+		    where {
+				?s "bar"@["123"] /_<foo> . # bars
+				/* foos, which
+				   span two lines */
+				?s "foo"@[] "1"^^type:int64
+			}; # done`,
+			[]TokenType{
+				ItemQuery, ItemBinding, ItemFrom, ItemBinding, ItemWhere, ItemLBracket,
+				ItemBinding, ItemPredicate, ItemNode, ItemDot, ItemBinding, ItemPredicate,
+				ItemLiteral, ItemRBracket, ItemSemicolon, ItemEOF,
+			},
+		},
 		{
 			`select count(?foo) as ?foo
 		    from ?foo
@@ -643,7 +1266,7 @@ func TestValidTokenQuery(t *testing.T) {
 				t.Fatalf("lex(%q) has not finished producing tokens when it should have.", test.input)
 			}
 			if want := test.tokens[idx]; got.Type != want {
-				t.Errorf("lex(%q) failed to provide token %s; got %s instead", test.input, got, want)
+				t.Errorf("lex(%q) failed to provide token %s; got %s instead", test.input, want, got.Type)
 			}
 			idx++
 		}
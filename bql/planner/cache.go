@@ -0,0 +1,180 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// CacheStats contains the counters collected by a result cache.
+type CacheStats struct {
+	// Hits is the number of queries answered from the cache.
+	Hits uint64
+
+	// Misses is the number of queries that had to be executed.
+	Misses uint64
+}
+
+// ResultCache is a LRU cache for query results. Entries are keyed by the
+// normalized statement and remember the version of each graph the query
+// read. An entry is invalidated as soon as any of those graphs is written.
+type ResultCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+// cacheEntry holds a cached query result.
+type cacheEntry struct {
+	key      string
+	versions []uint64
+	tbl      *table.Table
+}
+
+// NewResultCache returns a new result cache that holds at most size results.
+func NewResultCache(size int) (*ResultCache, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("planner.NewResultCache: cache size should be positive, got %d", size)
+	}
+	return &ResultCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// Stats returns the hit and miss counters of the cache.
+func (c *ResultCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Len returns the number of results currently cached.
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// get returns a copy of the cached result for the provided key if the
+// versions of the graphs match the ones recorded when it was cached. Stale
+// entries are dropped.
+func (c *ResultCache) get(key string, versions []uint64) (*table.Table, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false, nil
+	}
+	ce := e.Value.(*cacheEntry)
+	if !equalVersions(ce.versions, versions) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		c.stats.Misses++
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(e)
+	c.stats.Hits++
+	t, err := copyTable(ce.tbl)
+	if err != nil {
+		return nil, false, err
+	}
+	return t, true, nil
+}
+
+// put caches a copy of the provided result, evicting the least recently used
+// entry if the cache is full.
+func (c *ResultCache) put(key string, versions []uint64, tbl *table.Table) error {
+	t, err := copyTable(tbl)
+	if err != nil {
+		return err
+	}
+	ce := &cacheEntry{
+		key:      key,
+		versions: versions,
+		tbl:      t,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value = ce
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	c.entries[key] = c.lru.PushFront(ce)
+	if c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).key)
+	}
+	return nil
+}
+
+// equalVersions returns true if both lists of versions are identical.
+func equalVersions(v1, v2 []uint64) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i := range v1 {
+		if v1[i] != v2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// graphVersions returns the current version of each of the provided graphs.
+func graphVersions(ctx context.Context, gs []storage.Graph) ([]uint64, error) {
+	var vs []uint64
+	for _, g := range gs {
+		v, err := g.Version(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// copyTable returns a copy of the provided table, so cached results cannot be
+// altered by the callers.
+func copyTable(t *table.Table) (*table.Table, error) {
+	nt, err := table.New(append([]string{}, t.Bindings()...))
+	if err != nil {
+		return nil, err
+	}
+	err = t.EachRow(func(r table.Row) error {
+		nr := make(table.Row, len(r))
+		for k, v := range r {
+			nr[k] = v
+		}
+		nt.AddRow(nr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
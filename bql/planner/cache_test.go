@@ -0,0 +1,195 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+func executeWithCache(ctx context.Context, s storage.Store, q string, c *ResultCache, t *testing.T) *table.Table {
+	t.Helper()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	plnr, err := NewWithCache(ctx, s, st, 0, 10, nil, c)
+	if err != nil {
+		t.Fatalf("planner.NewWithCache failed to create a valid query plan with error: %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+	}
+	return tbl
+}
+
+func TestNewResultCacheRejectsInvalidSize(t *testing.T) {
+	for _, size := range []int{-1, 0} {
+		if _, err := NewResultCache(size); err == nil {
+			t.Errorf("planner.NewResultCache(%d) should have failed", size)
+		}
+	}
+}
+
+func TestResultCacheHitsAndInvalidation(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	c, err := NewResultCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := `select ?o from ?test where {/u<joe> "parent_of"@[] ?o};`
+	// Same query with a different formatting.
+	fq := `SELECT ?o
+	       FROM ?test
+	       WHERE {
+	         /u<joe> "parent_of"@[] ?o
+	       };`
+
+	if got, want := executeWithCache(ctx, s, q, c, t).NumRows(), 2; got != want {
+		t.Fatalf("planner.Execute(%s) returned %d rows; want %d", q, got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 0, Misses: 1}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v after the first execution; want %+v", got, want)
+	}
+	tbl := executeWithCache(ctx, s, fq, c, t)
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Fatalf("planner.Execute(%s) returned %d cached rows; want %d", fq, got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 1, Misses: 1}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v after the second execution; want %+v", got, want)
+	}
+	// Altering a returned table should not alter the cached one.
+	tbl.Truncate()
+	if got, want := executeWithCache(ctx, s, q, c, t).NumRows(), 2; got != want {
+		t.Fatalf("planner.Execute(%s) returned %d cached rows; want %d", q, got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 2, Misses: 1}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v after the third execution; want %+v", got, want)
+	}
+
+	// Writing the graph should invalidate the cached results.
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.Parse(`/u<joe>	"parent_of"@[]	/u<zoe>`, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTriples(ctx, []*triple.Triple{trpl}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := executeWithCache(ctx, s, q, c, t).NumRows(), 3; got != want {
+		t.Fatalf("planner.Execute(%s) returned %d rows after insertion; want %d", q, got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 2, Misses: 2}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v after the insertion; want %+v", got, want)
+	}
+}
+
+func TestResultCacheDistinguishesAliases(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	c, err := NewResultCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executeWithCache(ctx, s, `select ?o as ?a from ?test where {/u<joe> "parent_of"@[] ?o};`, c, t)
+	tbl := executeWithCache(ctx, s, `select ?o as ?b from ?test where {/u<joe> "parent_of"@[] ?o};`, c, t)
+	if got, want := tbl.Bindings(), []string{"?b"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("planner.Execute returned bindings %v; want %v", got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 0, Misses: 2}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v; want %+v", got, want)
+	}
+}
+
+func TestResultCacheDistinguishesOptions(t *testing.T) {
+	const heightTriples = `/u<alice>	"height_cm"@[]	"174"^^type:int64
+/u<bob>	"height_cm"@[]	"174.0"^^type:float64
+`
+	const q = `select ?s from ?test where {?s "height_cm"@[] ?h} having ?h = "174"^^type:int64;`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", heightTriples, t)
+	c, err := NewResultCache(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+	}
+	for _, entry := range []struct {
+		opts *Options
+		want int
+	}{
+		{&Options{Cache: c, NumericComparison: semantic.StrictNumericComparison}, 1},
+		{&Options{Cache: c, NumericComparison: semantic.PromotedNumericComparison}, 2},
+		{&Options{Cache: c, NumericComparison: semantic.StrictNumericComparison}, 1},
+	} {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, entry.opts)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+		}
+		if got, want := tbl.NumRows(), entry.want; got != want {
+			t.Errorf("planner.Execute(%s) with mode %d returned %d rows; want %d", q, entry.opts.NumericComparison, got, want)
+		}
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 1, Misses: 2}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v; want %+v", got, want)
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	c, err := NewResultCache(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q1 := `select ?o from ?test where {/u<joe> "parent_of"@[] ?o};`
+	q2 := `select ?s from ?test where {?s "parent_of"@[] /u<john>};`
+	executeWithCache(ctx, s, q1, c, t)
+	executeWithCache(ctx, s, q2, c, t)
+	executeWithCache(ctx, s, q1, c, t)
+	if got, want := c.Stats(), (CacheStats{Hits: 0, Misses: 3}); got != want {
+		t.Errorf("ResultCache.Stats() = %+v; want %+v", got, want)
+	}
+	if got, want := c.Len(), 1; got != want {
+		t.Errorf("ResultCache.Len() = %d; want %d", got, want)
+	}
+}
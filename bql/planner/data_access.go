@@ -15,17 +15,20 @@
 package planner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/google/badwolf/bql/planner/tracer"
 	"github.com/google/badwolf/bql/semantic"
 	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
 )
@@ -41,28 +44,53 @@ func (e *skippableError) Error() string {
 
 var _ error = (*skippableError)(nil)
 
-// updateTimeBounds updates the time bounds use for the lookup based on the
-// provided graph clause.
+// updateTimeBounds updates the time bounds and the limit used for the lookup
+// based on the provided graph clause.
 func updateTimeBounds(lo *storage.LookupOptions, cls *semantic.GraphClause) *storage.LookupOptions {
 	nlo := &storage.LookupOptions{
 		MaxElements:   lo.MaxElements,
 		LowerAnchor:   lo.LowerAnchor,
 		UpperAnchor:   lo.UpperAnchor,
+		AnchorRanges:  lo.AnchorRanges,
 		FilterOptions: lo.FilterOptions,
 	}
-	if cls.PLowerBound != nil {
-		if lo.LowerAnchor == nil || (lo.LowerAnchor != nil && cls.PLowerBound.After(*lo.LowerAnchor)) {
-			nlo.LowerAnchor = cls.PLowerBound
+	if len(cls.PAnchorRanges) > 0 {
+		nlo.AnchorRanges = cls.PAnchorRanges
+	}
+	lower, upper := anchorBounds(cls)
+	if lower != nil {
+		if lo.LowerAnchor == nil || (lo.LowerAnchor != nil && lower.After(*lo.LowerAnchor)) {
+			nlo.LowerAnchor = lower
 		}
 	}
-	if cls.PUpperBound != nil {
-		if lo.UpperAnchor == nil || (lo.UpperAnchor != nil && cls.PUpperBound.Before(*lo.UpperAnchor)) {
-			nlo.UpperAnchor = cls.PUpperBound
+	if upper != nil {
+		if lo.UpperAnchor == nil || (lo.UpperAnchor != nil && upper.Before(*lo.UpperAnchor)) {
+			nlo.UpperAnchor = upper
 		}
 	}
+	// The clause limit caps each lookup of the clause.
+	if cls.Limit > 0 && (nlo.MaxElements == 0 || cls.Limit < int64(nlo.MaxElements)) {
+		nlo.MaxElements = int(cls.Limit)
+	}
 	return nlo
 }
 
+// anchorBounds returns the inclusive bounds of the predicate anchors matched
+// by the clause. Since time anchors have nanosecond resolution, excluded
+// bounds are turned into inclusive ones one nanosecond closer.
+func anchorBounds(cls *semantic.GraphClause) (*time.Time, *time.Time) {
+	lower, upper := cls.PLowerBound, cls.PUpperBound
+	if lower != nil && cls.PLowerBoundOpen {
+		l := lower.Add(time.Nanosecond)
+		lower = &l
+	}
+	if upper != nil && cls.PUpperBoundOpen {
+		u := upper.Add(-time.Nanosecond)
+		upper = &u
+	}
+	return lower, upper
+}
+
 // updateTimeBoundsForRow updates the time bounds use for the lookup based on
 // the provided graph clause.
 func updateTimeBoundsForRow(lo *storage.LookupOptions, cls *semantic.GraphClause, r table.Row) (*storage.LookupOptions, error) {
@@ -85,12 +113,25 @@ func updateTimeBoundsForRow(lo *storage.LookupOptions, cls *semantic.GraphClause
 			lo.UpperAnchor = v.T
 		}
 	}
+	if cls.PAnchorAlias != "" {
+		// An anchor already bound by another clause only allows predicates
+		// anchored at that same time.
+		if v, ok := r[cls.PAnchorAlias]; ok && v.T != nil {
+			if lo.LowerAnchor == nil || v.T.After(*lo.LowerAnchor) {
+				lo.LowerAnchor = v.T
+			}
+			if lo.UpperAnchor == nil || v.T.Before(*lo.UpperAnchor) {
+				lo.UpperAnchor = v.T
+			}
+		}
+	}
 	nlo := updateTimeBounds(lo, cls)
 	return nlo, nil
 }
 
 // simpleExist returns true if the triple exist. Return the unfeasible state,
-// the table and the error if present.
+// the table and the error if present. The rows are populated using the triples
+// stored in the graphs, so bindings get the stored predicate values.
 func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, t *triple.Triple, w io.Writer) (bool, *table.Table, error) {
 	unfeasible := true
 	tbl, err := table.New(cls.Bindings())
@@ -101,19 +142,19 @@ func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		gID := g.ID(ctx)
 		tracer.V(2).Trace(w, func() *tracer.Arguments {
 			return &tracer.Arguments{
-				Msgs: []string{fmt.Sprintf("g.Exist(%v), graph: %s", t, gID)},
+				Msgs: []string{fmt.Sprintf("g.TriplesForSubjectPredicateObject(%v), graph: %s", t, gID)},
 			}
 		})
-		b, err := g.Exist(ctx, t)
+		st, err := g.TriplesForSubjectPredicateObject(ctx, t.Subject(), t.Predicate(), t.Object())
 		if err != nil {
 			return true, nil, err
 		}
-		if b {
+		if st != nil {
 			unfeasible = false
 			ts := make(chan *triple.Triple, 1)
-			ts <- t
+			ts <- st
 			close(ts)
-			if err := addTriples(ts, cls, tbl, w); err != nil {
+			if err := addTriples(ts, cls, tbl, gID, w); err != nil {
 				return true, nil, err
 			}
 		}
@@ -123,10 +164,17 @@ func simpleExist(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 
 // simpleFetch returns a table containing the data specified by the graph
 // clause by querying the provided stora. Will return an error if it had poblems
-// retrieveing the data.
-func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, stmLimit int64, chanSize int, w io.Writer) (*table.Table, error) {
+// retrieveing the data. If distinct is set, the repeated objects of a clause
+// with a specified subject and predicate are dropped by the storage.
+func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, lo *storage.LookupOptions, stmLimit int64, distinct bool, chanSize int, w io.Writer) (*table.Table, error) {
+	cls = specifySelfLoop(cls)
 	s, p, o := cls.S, cls.P, cls.O
 	lo = updateTimeBounds(lo, cls)
+	if hasRepeatedBindings(cls) || cls.OTypeConstant != nil {
+		// Rows breaking the equality or object type constraints are dropped after
+		// fetching them, so the global limit cannot be pushed down to the storage.
+		stmLimit = 0
+	}
 	loStr := lo.String()
 	tbl, err := table.New(cls.Bindings())
 	if err != nil {
@@ -153,7 +201,7 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 				ts := make(chan *triple.Triple, 1)
 				ts <- t
 				close(ts)
-				if err := addTriples(ts, cls, tbl, w); err != nil {
+				if err := addTriples(ts, cls, tbl, gID, w); err != nil {
 					return nil, err
 				}
 			}
@@ -162,52 +210,64 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 	}
 	if s != nil && p != nil && o == nil {
 		// SP request.
+		if onlyObjectExistence(cls, lo) {
+			// The object is discarded, so a single object is enough to prove that the
+			// subject and predicate pair exists.
+			nlo := *lo
+			nlo.MaxElements = 1
+			lo, loStr = &nlo, nlo.String()
+		}
+		op := "g.Objects"
+		if distinct {
+			op = "storage.DistinctObjects"
+		}
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				oErr error
-				aErr error
-				lErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
-					Msgs: []string{fmt.Sprintf("g.Objects(%v, %v, %s), graph: %s", s, p, loStr, gID)},
+					Msgs: []string{fmt.Sprintf("%s(%v, %v, %s), graph: %s", op, s, p, loStr, gID)},
 				}
 			})
-			wg.Add(2)
-			os := make(chan *triple.Object, chanSize)
-			go func() {
-				defer wg.Done()
-				oErr = g.Objects(ctx, s, p, lo, os)
-			}()
-			ts := make(chan *triple.Triple, chanSize)
-			go func() {
-				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl, w)
-			}()
-			for o := range os {
-				if lErr != nil {
-					// Drain the channel to avoid leaking goroutines.
-					continue
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				defer close(ts)
+				var (
+					gErr error
+					lErr error
+					wg   sync.WaitGroup
+				)
+				os := make(chan *triple.Object, chanSize)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if distinct {
+						gErr = storage.DistinctObjects(ctx, g, s, p, lo, os)
+						return
+					}
+					gErr = g.Objects(ctx, s, p, lo, os)
+				}()
+				for o := range os {
+					if lErr != nil || ctx.Err() != nil {
+						// Drain the channel to avoid leaking goroutines.
+						continue
+					}
+					t, err := triple.New(s, p, o)
+					if err != nil {
+						lErr = err
+						continue
+					}
+					select {
+					case ts <- t:
+					case <-ctx.Done():
+					}
 				}
-				t, err := triple.New(s, p, o)
-				if err != nil {
-					lErr = err
-					continue
+				wg.Wait()
+				if gErr != nil {
+					return gErr
 				}
-				ts <- t
+				return lErr
 			}
-			close(ts)
-			wg.Wait()
-			if oErr != nil {
-				return nil, oErr
-			}
-			if aErr != nil {
-				return nil, aErr
-			}
-			if lErr != nil {
-				return nil, lErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -216,50 +276,47 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// SO request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				pErr error
-				aErr error
-				lErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.PredicatesForSubjectAndObject(%v, %v, %s), graph: %s", s, o, loStr, gID)},
 				}
 			})
-			wg.Add(2)
-			ps := make(chan *predicate.Predicate, chanSize)
-			go func() {
-				defer wg.Done()
-				pErr = g.PredicatesForSubjectAndObject(ctx, s, o, lo, ps)
-			}()
-			ts := make(chan *triple.Triple, chanSize)
-			go func() {
-				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl, w)
-			}()
-			for p := range ps {
-				if lErr != nil {
-					// Drain the channel to avoid leaking goroutines.
-					continue
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				defer close(ts)
+				var (
+					gErr error
+					lErr error
+					wg   sync.WaitGroup
+				)
+				ps := make(chan *predicate.Predicate, chanSize)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					gErr = g.PredicatesForSubjectAndObject(ctx, s, o, lo, ps)
+				}()
+				for p := range ps {
+					if lErr != nil || ctx.Err() != nil {
+						// Drain the channel to avoid leaking goroutines.
+						continue
+					}
+					t, err := triple.New(s, p, o)
+					if err != nil {
+						lErr = err
+						continue
+					}
+					select {
+					case ts <- t:
+					case <-ctx.Done():
+					}
 				}
-				t, err := triple.New(s, p, o)
-				if err != nil {
-					lErr = err
-					continue
+				wg.Wait()
+				if gErr != nil {
+					return gErr
 				}
-				ts <- t
-			}
-			close(ts)
-			wg.Wait()
-			if pErr != nil {
-				return nil, pErr
-			}
-			if aErr != nil {
-				return nil, aErr
+				return lErr
 			}
-			if lErr != nil {
-				return nil, lErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -268,50 +325,47 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// PO request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				pErr error
-				aErr error
-				lErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.Subjects(%v, %v, %s), graph: %s", p, o, loStr, gID)},
 				}
 			})
-			wg.Add(2)
-			ss := make(chan *node.Node, chanSize)
-			go func() {
-				defer wg.Done()
-				pErr = g.Subjects(ctx, p, o, lo, ss)
-			}()
-			ts := make(chan *triple.Triple, chanSize)
-			go func() {
-				defer wg.Done()
-				aErr = addTriples(ts, cls, tbl, w)
-			}()
-			for s := range ss {
-				if lErr != nil {
-					// Drain the channel to avoid leaking goroutines.
-					continue
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				defer close(ts)
+				var (
+					gErr error
+					lErr error
+					wg   sync.WaitGroup
+				)
+				ss := make(chan *node.Node, chanSize)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					gErr = g.Subjects(ctx, p, o, lo, ss)
+				}()
+				for s := range ss {
+					if lErr != nil || ctx.Err() != nil {
+						// Drain the channel to avoid leaking goroutines.
+						continue
+					}
+					t, err := triple.New(s, p, o)
+					if err != nil {
+						lErr = err
+						continue
+					}
+					select {
+					case ts <- t:
+					case <-ctx.Done():
+					}
 				}
-				t, err := triple.New(s, p, o)
-				if err != nil {
-					lErr = err
-					continue
+				wg.Wait()
+				if gErr != nil {
+					return gErr
 				}
-				ts <- t
-			}
-			close(ts)
-			wg.Wait()
-			if pErr != nil {
-				return nil, pErr
-			}
-			if aErr != nil {
-				return nil, aErr
+				return lErr
 			}
-			if lErr != nil {
-				return nil, lErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -320,29 +374,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// S request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				tErr error
-				aErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.TriplesForSubject(%v, %s), graph: %s", s, loStr, gID)},
 				}
 			})
-			ts := make(chan *triple.Triple, chanSize)
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				tErr = g.TriplesForSubject(ctx, s, lo, ts)
-			}()
-			aErr = addTriples(ts, cls, tbl, w)
-			wg.Wait()
-			if tErr != nil {
-				return nil, tErr
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				return g.TriplesForSubject(ctx, s, lo, ts)
 			}
-			if aErr != nil {
-				return nil, aErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -351,29 +392,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// P request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				tErr error
-				aErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.TriplesForPredicate(%v, %s), graph: %s", p, loStr, gID)},
 				}
 			})
-			ts := make(chan *triple.Triple, chanSize)
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				tErr = g.TriplesForPredicate(ctx, p, lo, ts)
-			}()
-			aErr = addTriples(ts, cls, tbl, w)
-			wg.Wait()
-			if tErr != nil {
-				return nil, tErr
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				return g.TriplesForPredicate(ctx, p, lo, ts)
 			}
-			if aErr != nil {
-				return nil, aErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -382,28 +410,16 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// O request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				tErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.TriplesForObject(%v, %s), graph: %s", o, loStr, gID)},
 				}
 			})
-			ts := make(chan *triple.Triple, chanSize)
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				tErr = g.TriplesForObject(ctx, o, lo, ts)
-			}()
-			aErr := addTriples(ts, cls, tbl, w)
-			wg.Wait()
-			if tErr != nil {
-				return nil, tErr
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+				return g.TriplesForObject(ctx, o, lo, ts)
 			}
-			if aErr != nil {
-				return nil, aErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -412,34 +428,21 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 		// Full data request.
 		for _, g := range gs {
 			gID := g.ID(ctx)
-			var (
-				tErr error
-				aErr error
-				wg   sync.WaitGroup
-			)
 			tracer.V(2).Trace(w, func() *tracer.Arguments {
 				return &tracer.Arguments{
 					Msgs: []string{fmt.Sprintf("g.Triples(%s), graph: %s", loStr, gID)},
 				}
 			})
-			ts := make(chan *triple.Triple, chanSize)
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
+			fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
 				// Push global limit down.
 				nlo := *lo
-				if stmLimit > 0 {
+				if stmLimit > 0 && (nlo.MaxElements == 0 || stmLimit < int64(nlo.MaxElements)) {
 					nlo.MaxElements = int(stmLimit)
 				}
-				tErr = g.Triples(ctx, &nlo, ts)
-			}()
-			aErr = addTriples(ts, cls, tbl, w)
-			wg.Wait()
-			if tErr != nil {
-				return nil, tErr
+				return g.Triples(ctx, &nlo, ts)
 			}
-			if aErr != nil {
-				return nil, aErr
+			if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+				return nil, err
 			}
 		}
 		return tbl, nil
@@ -448,8 +451,42 @@ func simpleFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphCla
 	return nil, fmt.Errorf("planner.simpleFetch could not recognize request in clause %v", cls)
 }
 
+// subjectsFetch returns a table with the data of the provided clause for all
+// the provided subjects, retrieving the triples of each graph in a single
+// batched lookup. The clause should not bind its subject.
+func subjectsFetch(ctx context.Context, gs []storage.Graph, cls *semantic.GraphClause, subjects []*node.Node, lo *storage.LookupOptions, chanSize int, w io.Writer) (*table.Table, error) {
+	lo = updateTimeBounds(lo, cls)
+	loStr := lo.String()
+	tbl, err := table.New(cls.Bindings())
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range gs {
+		gID := g.ID(ctx)
+		tracer.V(2).Trace(w, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("g.TriplesForSubjects(%d subjects, %s), graph: %s", len(subjects), loStr, gID)},
+			}
+		})
+		fetch := func(ctx context.Context, ts chan<- *triple.Triple) error {
+			return storage.TriplesForSubjects(ctx, g, subjects, lo, ts)
+		}
+		if err := fetchTriples(ctx, fetch, cls, tbl, gID, chanSize, w); err != nil {
+			return nil, err
+		}
+	}
+	return tbl, nil
+}
+
 // shouldIgnoreTriple indicates if the given triple should be ignored in addTriples.
 func shouldIgnoreTriple(t *triple.Triple, cls *semantic.GraphClause) (bool, error) {
+	if cls.PIDConstant != "" && string(t.Predicate().ID()) != cls.PIDConstant {
+		// Predicate bindings restricted to an ID match any of its anchors.
+		return true, nil
+	}
+	if !matchesObjectType(t.Object(), cls) {
+		return true, nil
+	}
 	if cls.PID != "" {
 		// The triples need to be filtered.
 		if string(t.Predicate().ID()) != cls.PID {
@@ -464,10 +501,14 @@ func shouldIgnoreTriple(t *triple.Triple, cls *semantic.GraphClause) (bool, erro
 				return true, fmt.Errorf("failed to retrieve time anchor from time predicate in triple %s with error %v", t, err)
 			}
 			// Need to check the bounds of the triple.
-			if cls.PLowerBound != nil && cls.PLowerBound.After(*ta) {
+			lower, upper := anchorBounds(cls)
+			if lower != nil && lower.After(*ta) {
+				return true, nil
+			}
+			if upper != nil && upper.Before(*ta) {
 				return true, nil
 			}
-			if cls.PUpperBound != nil && cls.PUpperBound.Before(*ta) {
+			if !storage.InTimeRanges(cls.PAnchorRanges, *ta) {
 				return true, nil
 			}
 		}
@@ -493,6 +534,9 @@ func shouldIgnoreTriple(t *triple.Triple, cls *semantic.GraphClause) (bool, erro
 				if cls.OUpperBound != nil && cls.OUpperBound.Before(*ta) {
 					return true, nil
 				}
+				if !storage.InTimeRanges(cls.OAnchorRanges, *ta) {
+					return true, nil
+				}
 			}
 		}
 	}
@@ -500,6 +544,20 @@ func shouldIgnoreTriple(t *triple.Triple, cls *semantic.GraphClause) (bool, erro
 	return false, nil
 }
 
+// matchesObjectType returns true if the object is a node of the type the
+// clause restricts its object binding to, if any. Literal and predicate
+// objects never match a type restriction.
+func matchesObjectType(o *triple.Object, cls *semantic.GraphClause) bool {
+	if cls.OTypeConstant == nil {
+		return true
+	}
+	n, err := o.Node()
+	if err != nil {
+		return false
+	}
+	return *n.Type() == *cls.OTypeConstant
+}
+
 // drainChannel drains the given channel to avoid leaking goroutines, consuming remaining elements sent through it if that is the case.
 func drainChannel(ch <-chan *triple.Triple) {
 	for range ch {
@@ -507,13 +565,132 @@ func drainChannel(ch <-chan *triple.Triple) {
 	}
 }
 
+// hasRepeatedBindings returns true if a binding appears more than once in the
+// clause. Repeated bindings are equality constraints enforced when the fetched
+// triples are turned into rows.
+func hasRepeatedBindings(cls *semantic.GraphClause) bool {
+	for _, n := range cls.BindingsMap() {
+		if n > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// specifySelfLoop returns a copy of the clause with the missing subject or
+// object specified if they share a binding, since the binding makes both equal.
+// For instance, `/room<Kitchen> as ?x "connects_to"@[] ?x` becomes a fully
+// qualified triple. Otherwise the provided clause is returned.
+func specifySelfLoop(cls *semantic.GraphClause) *semantic.GraphClause {
+	sbs := []string{cls.SBinding, cls.SAlias}
+	shared := false
+	for _, ob := range []string{cls.OBinding, cls.OAlias} {
+		for _, sb := range sbs {
+			if ob != "" && ob != semantic.AnonymousBinding && ob == sb {
+				shared = true
+			}
+		}
+	}
+	if !shared || (cls.S == nil) == (cls.O == nil) {
+		return cls
+	}
+	ncls := *cls
+	if ncls.S != nil {
+		ncls.O = triple.NewNodeObject(ncls.S)
+	} else if n, err := ncls.O.Node(); err == nil {
+		ncls.S = n
+	}
+	return &ncls
+}
+
+// distinctObjectsOnly returns true if the object is the only binding of the
+// clause and it has a specified subject and predicate, so dropping the
+// repeated objects while fetching them drops the repeated rows.
+func distinctObjectsOnly(cls *semantic.GraphClause) bool {
+	if cls.S == nil || cls.P == nil || cls.O != nil || cls.Guard != nil || len(cls.BindingTypes) > 0 {
+		return false
+	}
+	if cls.OID != "" || cls.OLowerBound != nil || cls.OUpperBound != nil || len(cls.OAnchorRanges) > 0 {
+		return false
+	}
+	bs := cls.Bindings()
+	return len(bs) == 1 && bs[0] == cls.OBinding
+}
+
+// onlyObjectExistence returns true if the clause discards its object and no
+// other part of the request depends on the object values retrieved.
+func onlyObjectExistence(cls *semantic.GraphClause, lo *storage.LookupOptions) bool {
+	if cls.OBinding != semantic.AnonymousBinding || lo.FilterOptions != nil || cls.PID != "" || cls.OID != "" || cls.OTypeConstant != nil {
+		return false
+	}
+	for _, b := range []string{cls.OAlias, cls.OTypeAlias, cls.OIDAlias, cls.OAnchorAlias, cls.OAnchorBinding, cls.OLowerBoundAlias, cls.OUpperBoundAlias} {
+		if b != "" && b != semantic.AnonymousBinding {
+			return false
+		}
+	}
+	return true
+}
+
 // addTriples add all the retrieved triples from the graphs into the results
 // table. The semantic graph clause is also passed to be able to identify what
-// bindings to set.
-func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.Table, w io.Writer) error {
+// bindings to set, and gID is the name of the graph the triples come from.
+// Clauses using the anonymous binding only add distinct rows, since the values
+// that would tell them apart are discarded.
+func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.Table, gID string, w io.Writer) error {
 	// Drain the channel to avoid leaking goroutines in the case the loop below is interrupted by an error.
 	defer drainChannel(ts)
+	return appendTriples(ts, cls, tbl, gID, w)
+}
+
+// fetchTriples adds to the table the triples that fetch sends to the channel
+// it is provided. fetch runs on its own goroutine and must close the channel
+// once done. If the triples stop being consumed, the context provided to fetch
+// gets cancelled and the channel drained, so the producer never outlives the
+// call.
+func fetchTriples(ctx context.Context, fetch func(context.Context, chan<- *triple.Triple) error, cls *semantic.GraphClause, tbl *table.Table, gID string, chanSize int, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		fErr error
+		wg   sync.WaitGroup
+	)
+	ts := make(chan *triple.Triple, chanSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fErr = fetch(ctx, ts)
+	}()
+	aErr := appendTriples(ts, cls, tbl, gID, w)
+	if aErr != nil {
+		// Signal the producer to stop before draining what is left.
+		cancel()
+	}
+	drainChannel(ts)
+	wg.Wait()
+	if aErr != nil {
+		return aErr
+	}
+	return fErr
+}
+
+// appendTriples adds the triples received on the channel to the table until the
+// channel gets closed or an error is found.
+func appendTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.Table, gID string, w io.Writer) error {
+	var gc *table.Cell
+	if cls.GBinding != "" {
+		l, err := literal.DefaultBuilder().Build(literal.Text, gID)
+		if err != nil {
+			return err
+		}
+		gc = table.NewLiteralCell(l)
+	}
 
+	var seen map[string]bool
+	if cls.HasAnonymousBinding() {
+		seen = make(map[string]bool)
+	}
+	bs := tbl.Bindings()
 	nTrpls := 0
 	nRowsAdded := 0
 	for t := range ts {
@@ -536,6 +713,29 @@ func addTriples(ts <-chan *triple.Triple, cls *semantic.GraphClause, tbl *table.
 		if r == nil {
 			continue
 		}
+		if ok, err := matchesBindingTypes(r, cls); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if ok, err := passesGuard(r, cls); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if gc != nil {
+			r[cls.GBinding] = gc
+		}
+		if seen != nil {
+			var key bytes.Buffer
+			if err := r.ToTextLine(&key, bs, "\t"); err != nil {
+				return err
+			}
+			if seen[key.String()] {
+				continue
+			}
+			seen[key.String()] = true
+		}
 		tbl.AddRow(r)
 		nRowsAdded++
 	}
@@ -571,6 +771,39 @@ func objectToCell(o *triple.Object) (*table.Cell, error) {
 	return nil, fmt.Errorf("unknown object type in object %q", o)
 }
 
+// matchesBindingTypes returns true if the cells of the row bound to declared
+// bindings hold literals of the declared type. If the clause requires strict
+// binding types, a mismatch is reported as an error instead.
+func matchesBindingTypes(r table.Row, cls *semantic.GraphClause) (bool, error) {
+	for b, t := range cls.BindingTypes {
+		c, ok := r[b]
+		if !ok {
+			continue
+		}
+		if c.L != nil && c.L.Type() == t {
+			continue
+		}
+		if cls.StrictBindingTypes {
+			return false, fmt.Errorf("binding %s was declared as type:%s but got value %s", b, t, c)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// passesGuard returns true if the row satisfies the inline guard of the clause.
+// Rows that do not hold the guarded binding pass, since the binding was
+// already checked on the row the clause was specified with.
+func passesGuard(r table.Row, cls *semantic.GraphClause) (bool, error) {
+	if cls.Guard == nil {
+		return true, nil
+	}
+	if _, ok := r[cls.Guard.Binding]; !ok {
+		return true, nil
+	}
+	return cls.Guard.Evaluate(r)
+}
+
 // tripleToRow converts a triple into a row using the bindings specified
 // in the graph clause.
 func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error) {
@@ -579,11 +812,18 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 	// Enforce binding validity inside te clause.
 	bnd := make(map[string]*table.Cell)
 	validBinding := func(k string, v *table.Cell) bool {
+		if k == semantic.AnonymousBinding {
+			// Each occurrence of the anonymous binding is independent.
+			return true
+		}
 		c, ok := bnd[k]
 		bnd[k] = v
 		if !ok {
 			return true
 		}
+		if c.T != nil && v.T != nil {
+			return c.T.Equal(*v.T)
+		}
 		if reflect.DeepEqual(c, v) {
 			return true
 		}
@@ -592,28 +832,28 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 
 	// Subject related bindings.
 	if cls.SBinding != "" {
-		c := &table.Cell{N: s}
+		c := table.NewNodeCell(s)
 		r[cls.SBinding] = c
 		if !validBinding(cls.SBinding, c) {
 			return nil, nil
 		}
 	}
 	if cls.SAlias != "" {
-		c := &table.Cell{N: s}
+		c := table.NewNodeCell(s)
 		r[cls.SAlias] = c
 		if !validBinding(cls.SAlias, c) {
 			return nil, nil
 		}
 	}
 	if cls.STypeAlias != "" {
-		c := &table.Cell{S: table.CellString(s.Type().String())}
+		c := table.NewStringCell(s.Type().String())
 		r[cls.STypeAlias] = c
 		if !validBinding(cls.STypeAlias, c) {
 			return nil, nil
 		}
 	}
 	if cls.SIDAlias != "" {
-		c := &table.Cell{S: table.CellString(s.ID().String())}
+		c := table.NewStringCell(s.ID().String())
 		r[cls.SIDAlias] = c
 		if !validBinding(cls.SIDAlias, c) {
 			return nil, nil
@@ -622,21 +862,21 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 
 	// Predicate related bindings.
 	if cls.PBinding != "" {
-		c := &table.Cell{P: p}
+		c := table.NewPredicateCell(p)
 		r[cls.PBinding] = c
 		if !validBinding(cls.PBinding, c) {
 			return nil, nil
 		}
 	}
 	if cls.PAlias != "" {
-		c := &table.Cell{P: p}
+		c := table.NewPredicateCell(p)
 		r[cls.PAlias] = c
 		if !validBinding(cls.PAlias, c) {
 			return nil, nil
 		}
 	}
 	if cls.PIDAlias != "" {
-		c := &table.Cell{S: table.CellString(string(p.ID()))}
+		c := table.NewStringCell(string(p.ID()))
 		r[cls.PIDAlias] = c
 		if !validBinding(cls.PIDAlias, c) {
 			return nil, nil
@@ -655,7 +895,7 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 			if err != nil {
 				return nil, fmt.Errorf("failed to retrieve the time anchor value for predicate %q in binding %q with error: %v", p, cls.PAnchorBinding, err)
 			}
-			c = &table.Cell{T: t}
+			c = table.NewTimeCell(*t)
 		}
 		r[cls.PAnchorBinding] = c
 		if !validBinding(cls.PAnchorBinding, c) {
@@ -675,7 +915,7 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 			if err != nil {
 				return nil, fmt.Errorf("failed to retrieve the time anchor value for predicate %q in binding %q with error: %v", p, cls.PAnchorAlias, err)
 			}
-			c = &table.Cell{T: t}
+			c = table.NewTimeCell(*t)
 		}
 		r[cls.PAnchorAlias] = c
 		if !validBinding(cls.PAnchorAlias, c) {
@@ -716,7 +956,7 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 			}
 			c = &table.Cell{}
 		} else {
-			c = &table.Cell{S: table.CellString(n.Type().String())}
+			c = table.NewStringCell(n.Type().String())
 		}
 		r[cls.OTypeAlias] = c
 		if !validBinding(cls.OTypeAlias, c) {
@@ -726,13 +966,13 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 	if cls.OIDAlias != "" {
 		n, err := o.Node()
 		if err == nil {
-			r[cls.OIDAlias] = &table.Cell{S: table.CellString(n.ID().String())}
+			r[cls.OIDAlias] = table.NewStringCell(n.ID().String())
 		} else {
 			p, err := o.Predicate()
 			if err != nil {
 				return nil, err
 			}
-			c := &table.Cell{S: table.CellString(string(p.ID()))}
+			c := table.NewStringCell(string(p.ID()))
 			r[cls.OIDAlias] = c
 			if !validBinding(cls.OIDAlias, c) {
 				return nil, nil
@@ -759,7 +999,7 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 			if err != nil {
 				return nil, fmt.Errorf("failed to retrieve the time anchor value for predicate %q in binding %q with error: %v", p, cls.OAnchorBinding, err)
 			}
-			c = &table.Cell{T: t}
+			c = table.NewTimeCell(*t)
 		}
 		r[cls.OAnchorBinding] = c
 		if !validBinding(cls.OAnchorBinding, c) {
@@ -786,13 +1026,14 @@ func tripleToRow(t *triple.Triple, cls *semantic.GraphClause) (table.Row, error)
 			if err != nil {
 				return nil, fmt.Errorf("failed to retrieve the time anchor value for predicate %q in binding %q with error: %v", p, cls.OAnchorAlias, err)
 			}
-			c = &table.Cell{T: t}
+			c = table.NewTimeCell(*t)
 		}
 		r[cls.OAnchorAlias] = c
 		if !validBinding(cls.OAnchorAlias, c) {
 			return nil, nil
 		}
 	}
+	delete(r, semantic.AnonymousBinding)
 
 	return r, nil
 }
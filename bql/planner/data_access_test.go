@@ -19,8 +19,10 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/badwolf/bql/semantic"
 	"github.com/google/badwolf/bql/table"
@@ -92,7 +94,7 @@ func TestDataAccessSimpleFetch(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	tbl, err := simpleFetch(ctx, []storage.Graph{g}, cls, &storage.LookupOptions{}, 0, 0, nil)
+	tbl, err := simpleFetch(ctx, []storage.Graph{g}, cls, &storage.LookupOptions{}, 0, false, 0, nil)
 	if err != nil {
 		t.Errorf("simpleFetch failed with errorf %v", err)
 	}
@@ -127,7 +129,7 @@ func TestDataAccessSimpleFetchIssue40(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tbl, err := simpleFetch(ctx, []storage.Graph{g}, cls, &storage.LookupOptions{}, 0, 0, nil)
+	tbl, err := simpleFetch(ctx, []storage.Graph{g}, cls, &storage.LookupOptions{}, 0, false, 0, nil)
 	if err != nil {
 		t.Errorf("simpleFetch failed with errorf %v", err)
 	}
@@ -143,6 +145,118 @@ func TestDataAccessSimpleFetchIssue40(t *testing.T) {
 		}
 	}
 }
+
+func TestDataAccessSimpleFetchDistinctObjects(t *testing.T) {
+	ctx := context.Background()
+	trpls := []string{
+		"/u<peter>\t\"mood\"@[2016-01-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-02-01T00:00:00Z]\t/m<sad>",
+		"/u<peter>\t\"mood\"@[2016-03-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-04-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-05-01T00:00:00Z]\t/m<sad>",
+	}
+	g, err := getTestStore(t, trpls).Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peter, err := node.Parse("/u<peter>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mood, err := predicate.NewImmutable("mood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cls := &semantic.GraphClause{
+		S:        peter,
+		P:        mood,
+		OBinding: "?o",
+	}
+	if !distinctObjectsOnly(cls) {
+		t.Fatalf("distinctObjectsOnly(%v) = false; want true", cls)
+	}
+	for _, entry := range []struct {
+		distinct bool
+		want     int
+	}{
+		{false, 5},
+		{true, 2},
+	} {
+		tbl, err := simpleFetch(ctx, []storage.Graph{g}, cls, &storage.LookupOptions{}, 0, entry.distinct, 0, nil)
+		if err != nil {
+			t.Fatalf("simpleFetch(%v, distinct=%v) failed with error %v", cls, entry.distinct, err)
+		}
+		if got := tbl.NumRows(); got != entry.want {
+			t.Errorf("simpleFetch(%v, distinct=%v) returned %d rows; want %d", cls, entry.distinct, got, entry.want)
+		}
+	}
+	// Clauses binding anything but the object cannot drop repeated objects.
+	cls.PAnchorBinding = "?t"
+	if distinctObjectsOnly(cls) {
+		t.Errorf("distinctObjectsOnly(%v) = true; want false", cls)
+	}
+}
+
+func TestDataAccessSimpleFetchDoesNotLeakGoroutines(t *testing.T) {
+	var trpls []string
+	for i := 0; i < 5000; i++ {
+		trpls = append(trpls, fmt.Sprintf("/u<john>\t\"knows\"@[]\t/u<person_%d>", i))
+	}
+	bctx := context.Background()
+	g, err := getTestStore(t, trpls).Graph(bctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	john, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	knows, err := predicate.NewImmutable("knows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, cancel := context.WithCancel(bctx)
+	cancel()
+	table := []struct {
+		ctx   context.Context
+		cls   *semantic.GraphClause
+		limit int64
+	}{
+		{
+			ctx:   bctx,
+			cls:   &semantic.GraphClause{SBinding: "?s", PBinding: "?p", OBinding: "?o"},
+			limit: 1,
+		},
+		{
+			ctx: cancelled,
+			cls: &semantic.GraphClause{SBinding: "?s", PBinding: "?p", OBinding: "?o"},
+		},
+		{
+			ctx: cancelled,
+			cls: &semantic.GraphClause{S: john, P: knows, OBinding: "?o"},
+		},
+		{
+			ctx: cancelled,
+			cls: &semantic.GraphClause{SBinding: "?s", P: knows, OBinding: "?o"},
+		},
+	}
+	before := runtime.NumGoroutine()
+	for _, entry := range table {
+		// Unbuffered channels make any producer left behind block forever.
+		if _, err := simpleFetch(entry.ctx, []storage.Graph{g}, entry.cls, &storage.LookupOptions{}, entry.limit, false, 0, nil); err != nil && err != context.Canceled {
+			t.Errorf("simpleFetch(%v) failed with error %v", entry.cls, err)
+		}
+	}
+	after := runtime.NumGoroutine()
+	for i := 0; i < 100 && after > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		t.Errorf("simpleFetch left goroutines behind; got %d goroutines, want at most %d", after, before)
+	}
+}
+
 func TestDataAccessFeasibleSimpleExist(t *testing.T) {
 	ctx := context.Background()
 	g, err := getTestStore(t, testImmutatbleTriples).Graph(ctx, "?test")
@@ -228,7 +342,7 @@ func TestDataAccessAddTriples(t *testing.T) {
 	}()
 	go func() {
 		defer wg.Done()
-		if err := addTriples(ts, cls, tbl, nil); err != nil {
+		if err := addTriples(ts, cls, tbl, "?test", nil); err != nil {
 			t.Errorf("addTriple failed with errorf %v", err)
 		}
 	}()
@@ -27,6 +27,10 @@ const (
 	Latest Operation = iota + 1
 	IsImmutable
 	IsTemporal
+	Contains
+	Lang
+	Equal
+	NotEqual
 )
 
 // Field represents the position of the semantic.GraphClause that will be operated by the filter at storage level.
@@ -45,10 +49,28 @@ var SupportedOperations = map[string]Operation{
 	"latest":      Latest,
 	"isimmutable": IsImmutable,
 	"istemporal":  IsTemporal,
+	"contains":    Contains,
+	"lang":        Lang,
+	"eq":          Equal,
+	"neq":         NotEqual,
 }
 
 // OperationRequiresValue keeps track of the filter Operations that require Value in the filter clause.
-var OperationRequiresValue = map[Operation]bool{}
+var OperationRequiresValue = map[Operation]bool{
+	Contains: true,
+	Lang:     true,
+	Equal:    true,
+	NotEqual: true,
+}
+
+// TableLevelOperations keeps track of the filter Operations that are applied by the planner on the
+// resulting table instead of being pushed down to the storage level.
+var TableLevelOperations = map[Operation]bool{
+	Contains: true,
+	Lang:     true,
+	Equal:    true,
+	NotEqual: true,
+}
 
 // StorageOptions represent the storage level specifications for the filtering to be executed.
 // Operation below refers to the filter function being applied (eg: Latest), Field refers to the position of the graph clause it
@@ -69,6 +91,14 @@ func (op Operation) String() string {
 		return "isImmutable"
 	case IsTemporal:
 		return "isTemporal"
+	case Contains:
+		return "contains"
+	case Lang:
+		return "lang"
+	case Equal:
+		return "eq"
+	case NotEqual:
+		return "neq"
 	default:
 		return fmt.Sprintf(`not defined filter operation "%d"`, op)
 	}
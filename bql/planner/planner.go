@@ -24,9 +24,11 @@ import (
 	"io"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/badwolf/bql/lexer"
@@ -37,9 +39,10 @@ import (
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
 // Executor interface unifies the execution of statements.
@@ -73,7 +76,7 @@ func (p *createPlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err != nil {
 		return nil, err
 	}
-	errs := []string{}
+	var errs errorList
 	for _, gName := range p.stm.GraphNames() {
 		gNameCopy := gName // creating a local copy of the loop variable to not pass it by reference to the closure of the lazy tracer.
 		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
@@ -82,11 +85,19 @@ func (p *createPlan) Execute(ctx context.Context) (*table.Table, error) {
 			}
 		})
 		if _, err := p.store.NewGraph(ctx, gName); err != nil {
-			errs = append(errs, err.Error())
+			if p.stm.IfNotExists() && errors.Is(err, storage.ErrGraphExists) {
+				tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+					return &tracer.Arguments{
+						Msgs: []string{fmt.Sprintf("Graph %q already exists", gNameCopy)},
+					}
+				})
+				continue
+			}
+			errs = append(errs, err)
 		}
 	}
 	if len(errs) > 0 {
-		return nil, errors.New(strings.Join(errs, "; "))
+		return nil, errs
 	}
 	return t, nil
 }
@@ -96,6 +107,29 @@ func (p *createPlan) String(ctx context.Context) string {
 	return fmt.Sprintf("CREATE plan:\n\nstore(%q).NewGraph(_, %v)", p.store.Name(nil), p.stm.Graphs())
 }
 
+// errorList collects the errors found while operating on several graphs.
+type errorList []error
+
+// Error returns the messages of all the collected errors.
+func (e errorList) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is returns true if any of the collected errors matches the target, so
+// callers can check for the storage sentinel errors using errors.Is.
+func (e errorList) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // dropPlan encapsulates the sequence of instructions that need to be
 // executed in order to satisfy the execution of a valid drop BQL statement.
 type dropPlan struct {
@@ -115,7 +149,7 @@ func (p *dropPlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err != nil {
 		return nil, err
 	}
-	errs := []string{}
+	var errs errorList
 	for _, gName := range p.stm.GraphNames() {
 		gNameCopy := gName // creating a local copy of the loop variable to not pass it by reference to the closure of the lazy tracer.
 		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
@@ -124,11 +158,19 @@ func (p *dropPlan) Execute(ctx context.Context) (*table.Table, error) {
 			}
 		})
 		if err := p.store.DeleteGraph(ctx, gName); err != nil {
-			errs = append(errs, err.Error())
+			if p.stm.IfExists() && errors.Is(err, storage.ErrGraphNotFound) {
+				tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+					return &tracer.Arguments{
+						Msgs: []string{fmt.Sprintf("Graph %q does not exist", gNameCopy)},
+					}
+				})
+				continue
+			}
+			errs = append(errs, err)
 		}
 	}
 	if len(errs) > 0 {
-		return nil, errors.New(strings.Join(errs, "; "))
+		return nil, errs
 	}
 	return t, nil
 }
@@ -138,14 +180,64 @@ func (p *dropPlan) String(ctx context.Context) string {
 	return fmt.Sprintf("DROP plan:\n\nstore(%q).DeleteGraph(_, %v)", p.store.Name(nil), p.stm.Graphs())
 }
 
-// insertPlan encapsulates the sequence of instructions that need to be
-// executed in order to satisfy the execution of a valid insert BQL statement.
-type insertPlan struct {
+// truncatePlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid truncate BQL statement.
+type truncatePlan struct {
 	stm    *semantic.Statement
 	store  storage.Store
 	tracer io.Writer
 }
 
+// Type returns the type of plan used by the executor.
+func (p *truncatePlan) Type() string {
+	return "TRUNCATE"
+}
+
+// Execute removes all the triples from the indicated graphs, keeping the
+// graphs themselves.
+func (p *truncatePlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	var errs errorList
+	for _, gName := range p.stm.GraphNames() {
+		gNameCopy := gName // creating a local copy of the loop variable to not pass it by reference to the closure of the lazy tracer.
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Removing all triples from graph %q", gNameCopy)},
+			}
+		})
+		g, err := p.store.Graph(ctx, gName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := g.RemoveAllTriples(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return t, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *truncatePlan) String(ctx context.Context) string {
+	return fmt.Sprintf("TRUNCATE plan:\n\nstore(%q).Graph(_, %v).RemoveAllTriples(_)", p.store.Name(ctx), p.stm.GraphNames())
+}
+
+// insertPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid insert BQL statement.
+type insertPlan struct {
+	stm         *semantic.Statement
+	store       storage.Store
+	tracer      io.Writer
+	omitCount   bool
+	concurrency int
+}
+
 // Type returns the type of plan used by the executor.
 func (p *insertPlan) Type() string {
 	return "INSERT"
@@ -153,22 +245,33 @@ func (p *insertPlan) Type() string {
 
 type updater func(storage.Graph, []*triple.Triple) error
 
-func update(ctx context.Context, ts []*triple.Triple, gbs []string, store storage.Store, f updater) error {
+// update calls f on each of the provided graphs with the provided triples,
+// running it on at most concurrency graphs at a time. If concurrency is not
+// positive, it defaults to GOMAXPROCS.
+func update(ctx context.Context, ts []*triple.Triple, gbs []string, store storage.Store, concurrency int, f updater) error {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
 	var (
 		mu   sync.Mutex
 		wg   sync.WaitGroup
-		errs []string
+		errs []error
+		sem  = make(chan struct{}, concurrency)
 	)
 	appendError := func(err error) {
 		mu.Lock()
 		defer mu.Unlock()
-		errs = append(errs, err.Error())
+		errs = append(errs, err)
 	}
 
 	for _, graphBinding := range gbs {
 		wg.Add(1)
+		sem <- struct{}{}
 		go func(graph string) {
-			defer wg.Done()
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
 			g, err := store.Graph(ctx, graph)
 			if err != nil {
 				appendError(err)
@@ -181,35 +284,173 @@ func update(ctx context.Context, ts []*triple.Triple, gbs []string, store storag
 		}(graphBinding)
 	}
 	wg.Wait()
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, "; "))
+	if len(errs) == 0 {
+		return nil
 	}
-	return nil
+	// The first error is wrapped so callers can still check storage errors,
+	// like storage.ErrCapacityExceeded, using errors.Is.
+	var msgs []string
+	for _, err := range errs[1:] {
+		msgs = append(msgs, err.Error())
+	}
+	if len(msgs) == 0 {
+		return errs[0]
+	}
+	return fmt.Errorf("%w; %s", errs[0], strings.Join(msgs, "; "))
+}
+
+// AffectedBinding is the binding of the table returned by INSERT and DELETE
+// statements holding the number of triples affected.
+const AffectedBinding = "?affected"
+
+// SkippedBinding is the binding of the table returned by statements with
+// lenient data holding the number of triples skipped.
+const SkippedBinding = "?skipped"
+
+// SkippedErrorsBinding is the binding of the table returned by statements with
+// lenient data holding the reasons why triples were skipped, separated by
+// semicolons.
+const SkippedErrorsBinding = "?skipped_errors"
+
+// affectedTable returns the table reporting the number of triples affected by
+// an update. If the statement accepts lenient data, the table also reports the
+// triples skipped. If the count is omitted, the table has no bindings.
+func affectedTable(n int, stm *semantic.Statement, omit bool) (*table.Table, error) {
+	if omit {
+		return table.New([]string{})
+	}
+	bs := []string{AffectedBinding}
+	if stm.LenientData() {
+		bs = append(bs, SkippedBinding, SkippedErrorsBinding)
+	}
+	t, err := table.New(bs)
+	if err != nil {
+		return nil, err
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Int64, int64(n))
+	if err != nil {
+		return nil, err
+	}
+	r := table.Row{AffectedBinding: table.NewLiteralCell(l)}
+	if stm.LenientData() {
+		skipped := stm.SkippedData()
+		l, err := literal.DefaultBuilder().Build(literal.Int64, int64(len(skipped)))
+		if err != nil {
+			return nil, err
+		}
+		msgs := make([]string, 0, len(skipped))
+		for _, err := range skipped {
+			msgs = append(msgs, err.Error())
+		}
+		r[SkippedBinding] = table.NewLiteralCell(l)
+		r[SkippedErrorsBinding] = table.NewStringCell(strings.Join(msgs, "; "))
+	}
+	t.AddRow(r)
+	return t, nil
 }
 
-// Execute inserts the provided data into the indicated graphs.
+// Execute inserts the provided data into the indicated graphs. It returns a
+// table with the number of triples actually added across all graphs, counting
+// the triples streamed while parsing. Triples already present in a graph are
+// not counted. NOW() time anchors are resolved to the current UTC time.
 func (p *insertPlan) Execute(ctx context.Context) (*table.Table, error) {
-	t, err := table.New([]string{})
+	gbs := p.stm.OutputGraphNames()
+	add := insert
+	if p.stm.IfAbsent() {
+		add = insertIfAbsent
+	}
+	ts, err := p.stm.ResolveNow(p.stm.Data(), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	n, err := add(ctx, ts, gbs, p.store, p.concurrency, p.tracer)
 	if err != nil {
 		return nil, err
 	}
-	return t, update(ctx, p.stm.Data(), p.stm.OutputGraphNames(), p.store, func(g storage.Graph, d []*triple.Triple) error {
+	return affectedTable(n+p.stm.StreamedAdded(), p.stm, p.omitCount)
+}
+
+// insert adds the provided data to the indicated graphs, and returns the
+// number of triples added across all of them that were not already present.
+func insert(ctx context.Context, ts []*triple.Triple, gbs []string, store storage.Store, concurrency int, w io.Writer) (int, error) {
+	var added int64
+	err := update(ctx, ts, gbs, store, concurrency, func(g storage.Graph, d []*triple.Triple) error {
 		gID := g.ID(ctx)
 		nTrpls := len(d)
-		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+		tracer.V(2).Trace(w, func() *tracer.Arguments {
 			return &tracer.Arguments{
 				Msgs: []string{fmt.Sprintf("Inserting %d triples to graph %q", nTrpls, gID)},
 			}
 		})
-		return g.AddTriples(ctx, d)
+		n, err := storage.AddTriples(ctx, g, d)
+		atomic.AddInt64(&added, int64(n))
+		return err
+	})
+	return int(added), err
+}
+
+// insertIfAbsent adds the provided data not already present to the indicated
+// graphs, and returns the number of triples added across all of them.
+func insertIfAbsent(ctx context.Context, ts []*triple.Triple, gbs []string, store storage.Store, concurrency int, w io.Writer) (int, error) {
+	var added int64
+	err := update(ctx, ts, gbs, store, concurrency, func(g storage.Graph, d []*triple.Triple) error {
+		gID := g.ID(ctx)
+		nTrpls := len(d)
+		tracer.V(2).Trace(w, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Inserting %d triples if absent to graph %q", nTrpls, gID)},
+			}
+		})
+		n, err := storage.AddTriplesIfAbsent(ctx, g, d)
+		atomic.AddInt64(&added, int64(n))
+		return err
+	})
+	return int(added), err
+}
+
+// StreamInsertData sets the provided statement, before it gets parsed, to
+// insert the data of an INSERT statement into its output graphs in batches
+// of bulkSize triples as they are parsed, instead of holding all of them in
+// memory. The triples that do not fill a whole batch are inserted when the
+// resulting plan is executed. Explained statements are rejected since the
+// flushed batches are inserted during parsing.
+//
+// The flushed batches stay inserted if parsing fails afterwards. The number
+// of triples already flushed is available via the StreamedData method of the
+// statement, and the number of those actually added via StreamedAdded. The
+// batches are inserted into at most GOMAXPROCS graphs at a time.
+func StreamInsertData(ctx context.Context, store storage.Store, stm *semantic.Statement, bulkSize int, w io.Writer) error {
+	if stm.Explain() {
+		return errors.New("planner.StreamInsertData: explained statements cannot be streamed")
+	}
+	stm.StreamData(bulkSize, func(d []*triple.Triple) error {
+		add := insert
+		if stm.IfAbsent() {
+			add = insertIfAbsent
+		}
+		d, err := stm.ResolveNow(d, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		n, err := add(ctx, d, stm.OutputGraphNames(), store, 0, w)
+		stm.AddStreamedAdded(n)
+		return err
 	})
+	return nil
 }
 
 // String returns a readable description of the execution plan.
 func (p *insertPlan) String(ctx context.Context) string {
 	b := bytes.NewBufferString("INSERT plan:\n\n")
+	op := "AddTriples"
+	if p.stm.IfAbsent() {
+		op = "AddTriplesIfAbsent"
+	}
 	for _, g := range p.stm.OutputGraphs() {
-		b.WriteString(fmt.Sprintf("store(%q).Graph(%q).AddTriples(_, data)\n", p.store.Name(nil), g))
+		b.WriteString(fmt.Sprintf("store(%q).Graph(%q).%s(_, data)\n", p.store.Name(nil), g, op))
+	}
+	if n := p.stm.StreamedData(); n > 0 {
+		b.WriteString(fmt.Sprintf("with %d triples already streamed\n", n))
 	}
 	b.WriteString("where data:\n")
 	for _, t := range p.stm.Data() {
@@ -223,9 +464,13 @@ func (p *insertPlan) String(ctx context.Context) string {
 // deletePlan encapsulates the sequence of instructions that need to be
 // executed in order to satisfy the execution of a valid delete BQL statement.
 type deletePlan struct {
-	stm    *semantic.Statement
-	store  storage.Store
-	tracer io.Writer
+	stm         *semantic.Statement
+	store       storage.Store
+	chanSize    int
+	bulkSize    int
+	tracer      io.Writer
+	omitCount   bool
+	concurrency int
 }
 
 // Type returns the type of plan used by the executor.
@@ -233,13 +478,14 @@ func (p *deletePlan) Type() string {
 	return "DELETE"
 }
 
-// Execute deletes the provided data into the indicated graphs.
+// Execute deletes the provided data into the indicated graphs. It returns a
+// table with the number of triples actually removed across all graphs.
 func (p *deletePlan) Execute(ctx context.Context) (*table.Table, error) {
-	t, err := table.New([]string{})
-	if err != nil {
-		return nil, err
+	if cutoff := p.stm.AnchorCutoff(); cutoff != nil {
+		return p.purge(ctx, *cutoff)
 	}
-	return t, update(ctx, p.stm.Data(), p.stm.InputGraphNames(), p.store, func(g storage.Graph, d []*triple.Triple) error {
+	var affected int64
+	err := update(ctx, p.stm.Data(), p.stm.InputGraphNames(), p.store, p.concurrency, func(g storage.Graph, d []*triple.Triple) error {
 		gID := g.ID(ctx)
 		nTrpls := len(d)
 		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
@@ -247,13 +493,84 @@ func (p *deletePlan) Execute(ctx context.Context) (*table.Table, error) {
 				Msgs: []string{fmt.Sprintf("Removing %d triples from graph %q", nTrpls, gID)},
 			}
 		})
-		return g.RemoveTriples(ctx, d)
+		n, err := storage.RemoveTriples(ctx, g, d)
+		atomic.AddInt64(&affected, int64(n))
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	return affectedTable(int(affected), p.stm, p.omitCount)
+}
+
+// purge removes from the input graphs all the triples with a temporal
+// predicate anchored at or before the provided cutoff, leaving the triples with
+// immutable predicates untouched. The triples are removed in batches of the
+// bulk size once they have all been retrieved. It returns a table with the
+// number of triples removed across all graphs.
+func (p *deletePlan) purge(ctx context.Context, cutoff time.Time) (*table.Table, error) {
+	var affected int
+	lo := &storage.LookupOptions{UpperAnchor: &cutoff}
+	for _, gName := range p.stm.InputGraphNames() {
+		g, err := p.store.Graph(ctx, gName)
+		if err != nil {
+			return nil, err
+		}
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("g.Triples(%s), graph: %s", lo, gName)},
+			}
+		})
+		var (
+			ts   []*triple.Triple
+			tErr error
+			wg   sync.WaitGroup
+		)
+		ch := make(chan *triple.Triple, p.chanSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tErr = g.Triples(ctx, lo, ch)
+		}()
+		for t := range ch {
+			if t.Predicate().Type() == predicate.Temporal {
+				ts = append(ts, t)
+			}
+		}
+		wg.Wait()
+		if tErr != nil {
+			return nil, tErr
+		}
+		for len(ts) > 0 {
+			n := len(ts)
+			if p.bulkSize > 0 && n > p.bulkSize {
+				n = p.bulkSize
+			}
+			tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+				return &tracer.Arguments{
+					Msgs: []string{fmt.Sprintf("Removing %d triples from graph %q", n, gName)},
+				}
+			})
+			rm, err := storage.RemoveTriples(ctx, g, ts[:n])
+			affected += rm
+			if err != nil {
+				return nil, err
+			}
+			ts = ts[n:]
+		}
+	}
+	return affectedTable(affected, p.stm, p.omitCount)
 }
 
 // String returns a readable description of the execution plan.
 func (p *deletePlan) String(ctx context.Context) string {
 	b := bytes.NewBufferString("DELETE plan:\n\n")
+	if cutoff := p.stm.AnchorCutoff(); cutoff != nil {
+		for _, g := range p.stm.InputGraphs() {
+			b.WriteString(fmt.Sprintf("store(%q).Graph(%q).RemoveTriples(_, temporal triples anchored at or before %s)\n", p.store.Name(nil), g, cutoff.Format(time.RFC3339Nano)))
+		}
+		return b.String()
+	}
 	for _, g := range p.stm.InputGraphs() {
 		b.WriteString(fmt.Sprintf("store(%q).Graph(%q).RemoveTriples(_, data)\n", p.store.Name(nil), g))
 	}
@@ -273,14 +590,25 @@ type queryPlan struct {
 	stm   *semantic.Statement
 	store storage.Store
 	// Prepared plan information.
-	bndgs     []string
-	grfsNames []string
-	grfs      []storage.Graph
-	clauses   []*semantic.GraphClause
-	filters   []*semantic.FilterClause
-	tbl       *table.Table
-	chanSize  int
-	tracer    io.Writer
+	bndgs      []string
+	grfsNames  []string
+	grfs       []storage.Graph
+	clauses    []*semantic.GraphClause
+	negated    [][]*semantic.GraphClause
+	filters    []*semantic.FilterClause
+	tblFilters []*tableFilter
+	tbl        *table.Table
+	chanSize   int
+	tracer     io.Writer
+	cache      *ResultCache
+	// parallelism is the maximum number of rows processed concurrently.
+	parallelism int
+	// numbers is how numbers of different types are compared in HAVING.
+	numbers semantic.NumericComparison
+	// provenance, if not nil, records the clause that produced each cell.
+	provenance *table.Provenance
+	// emptyBuckets emits the empty buckets of HISTOGRAM with a zero count.
+	emptyBuckets bool
 }
 
 // Type returns the type of plan used by the executor.
@@ -294,19 +622,60 @@ func newQueryPlan(ctx context.Context, store storage.Store, stm *semantic.Statem
 	if err != nil {
 		return nil, err
 	}
+	var (
+		filters    []*semantic.FilterClause
+		tblFilters []*tableFilter
+	)
+	bm := stm.BindingsMap()
+	for _, f := range stm.FilterClauses() {
+		if !filter.TableLevelOperations[f.Operation] {
+			filters = append(filters, f)
+			continue
+		}
+		if _, ok := bm[f.Binding]; !ok {
+			return nil, fmt.Errorf("binding %q referenced by filter clause %q does not exist in the graph pattern", f.Binding, f)
+		}
+		tf, err := newTableFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		tblFilters = append(tblFilters, tf)
+	}
+	if err := stm.CheckHavingScope(); err != nil {
+		return nil, err
+	}
 	return &queryPlan{
-		stm:       stm,
-		store:     store,
-		bndgs:     stm.Bindings(),
-		grfsNames: stm.InputGraphNames(),
-		clauses:   stm.GraphPatternClauses(),
-		filters:   stm.FilterClauses(),
-		tbl:       t,
-		chanSize:  chanSize,
-		tracer:    w,
+		stm:         stm,
+		store:       store,
+		bndgs:       stm.Bindings(),
+		grfsNames:   stm.InputGraphNames(),
+		clauses:     stm.GraphPatternClauses(),
+		negated:     stm.NegatedClauses(),
+		filters:     filters,
+		tblFilters:  tblFilters,
+		tbl:         t,
+		chanSize:    chanSize,
+		tracer:      w,
+		parallelism: runtime.GOMAXPROCS(0),
 	}, nil
 }
 
+// fetchesDistinctObjects returns true if the repeated objects of the provided
+// clause can be dropped by the storage. It requires the statement to drop its
+// repeated rows, and the clause to be the only one and to only bind its object,
+// which is then projected without aggregating it.
+func (p *queryPlan) fetchesDistinctObjects(cls *semantic.GraphClause) bool {
+	if !p.stm.Distinct() || len(p.stm.GraphPatternClauses()) != 1 || len(p.stm.GroupBy()) != 0 || len(p.stm.UnwindClauses()) != 0 {
+		return false
+	}
+	for _, prj := range p.stm.Projection() {
+		if prj.OP != lexer.ItemError {
+			return false
+		}
+	}
+	return distinctObjectsOnly(cls)
+}
+
 // processClause retrieves the triples for the provided triple given the
 // information available.
 func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) (bool, error) {
@@ -358,10 +727,10 @@ func (p *queryPlan) processClause(ctx context.Context, cls *semantic.GraphClause
 		})
 		// Data is new.
 		stmLimit := int64(0)
-		if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 {
+		if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 && !p.stm.Distinct() {
 			stmLimit = p.stm.Limit()
 		}
-		tbl, err := simpleFetch(ctx, p.grfs, cls, lo, stmLimit, p.chanSize, p.tracer)
+		tbl, err := simpleFetch(ctx, p.grfs, cls, lo, stmLimit, p.fetchesDistinctObjects(cls), p.chanSize, p.tracer)
 		if err != nil {
 			return true, err
 		}
@@ -406,6 +775,12 @@ func getBoundValueForComponent(r table.Row, bs []string) *table.Cell {
 // addSpecifiedData specializes the clause given the row provided and attempt to
 // retrieve the corresponding clause data.
 func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
+	if !cls.Optional {
+		// Rows failing the guard on their bound values cannot match.
+		if ok, err := passesGuard(r, cls); err != nil || !ok {
+			return err
+		}
+	}
 	if cls.S == nil {
 		v := getBoundValueForComponent(r, []string{cls.SBinding, cls.SAlias})
 		if v != nil {
@@ -461,6 +836,9 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 		}
 		lo = nlo
 	}
+	// A binding shared by the subject and the object makes them equal, so a
+	// bound subject also specifies the object and the other way around.
+	cls = specifySelfLoop(cls)
 
 	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
 		return &tracer.Arguments{
@@ -469,10 +847,14 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 	})
 
 	stmLimit := int64(0)
-	if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 {
+	if len(p.stm.GraphPatternClauses()) == 1 && len(p.stm.GroupBy()) == 0 && len(p.stm.HavingExpression()) == 0 && !p.stm.Distinct() {
 		stmLimit = p.stm.Limit()
 	}
-	tbl, err := simpleFetch(ctx, p.grfs, cls, lo, stmLimit, p.chanSize, p.tracer)
+	grfs, err := p.graphsForRow(ctx, r, cls)
+	if err != nil {
+		return err
+	}
+	tbl, err := simpleFetch(ctx, grfs, cls, lo, stmLimit, false, p.chanSize, p.tracer)
 	if err != nil {
 		return err
 	}
@@ -489,55 +871,208 @@ func (p *queryPlan) addSpecifiedData(ctx context.Context, r table.Row, cls *sema
 		return nil
 	}
 	for _, nr := range tbl.Rows() {
+		if !sameAnchors(r, nr, cls) {
+			continue
+		}
 		p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
 	}
 	return nil
 }
 
+// sameAnchors returns true if the time anchors the clause bound in the new row
+// match the ones already bound in the row being specified. This makes a time
+// anchor binding shared by several clauses behave as a join constraint.
+func sameAnchors(r, nr table.Row, cls *semantic.GraphClause) bool {
+	for _, b := range []string{cls.PAnchorBinding, cls.PAnchorAlias, cls.OAnchorBinding, cls.OAnchorAlias} {
+		if b == "" {
+			continue
+		}
+		v, nv := r[b], nr[b]
+		if v == nil || nv == nil || v.T == nil || nv.T == nil {
+			continue
+		}
+		if !v.T.Equal(*nv.T) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphsForRow returns the graphs the clause should be fetched from for the
+// provided row. If the row is already bound to a graph via GRAPH(), only that
+// graph is returned.
+func (p *queryPlan) graphsForRow(ctx context.Context, r table.Row, cls *semantic.GraphClause) ([]storage.Graph, error) {
+	v := r[cls.GBinding]
+	if cls.GBinding == "" || v == nil || v.L == nil {
+		return p.grfs, nil
+	}
+	name, err := v.L.Text()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range p.grfs {
+		if g.ID(ctx) == name {
+			return []storage.Graph{g}, nil
+		}
+	}
+	return nil, nil
+}
+
 // specifyClauseWithTable runs the clause, but it specifies it further based on
 // the current row being processed.
 func (p *queryPlan) specifyClauseWithTable(ctx context.Context, cls *semantic.GraphClause, lo *storage.LookupOptions) error {
+	if sb := p.batchableSubjectBinding(cls); sb != "" {
+		return p.specifyClauseBySubjects(ctx, cls, sb, lo)
+	}
+	rws := p.tbl.Rows()
+	p.tbl.Truncate()
+	return p.forEachRow(ctx, rws, func(gCtx context.Context, r table.Row) error {
+		var tmpCls = *cls
+		// The table manipulations are now thread safe.
+		return p.addSpecifiedData(gCtx, r, &tmpCls, lo)
+	})
+}
+
+// batchableSubjectBinding returns the binding of the current table that holds
+// the subject of the provided clause, if the clause can be resolved for all the
+// rows with a single batched lookup per graph. That is the case when the
+// clause does not fix its predicate or object, the rows only bind the subject
+// of the clause to nodes, and all the queried graphs implement
+// storage.SubjectsBatcher. Otherwise, it returns an empty string.
+func (p *queryPlan) batchableSubjectBinding(cls *semantic.GraphClause) string {
+	if cls.S != nil || cls.P != nil || cls.O != nil || len(p.grfs) == 0 {
+		return ""
+	}
+	for _, g := range p.grfs {
+		if _, ok := g.(storage.SubjectsBatcher); !ok {
+			return ""
+		}
+	}
+	sb := ""
+	for _, b := range cls.Bindings() {
+		if !p.tbl.HasBinding(b) {
+			continue
+		}
+		if sb != "" || (b != cls.SBinding && b != cls.SAlias) {
+			return ""
+		}
+		sb = b
+	}
+	if sb == "" {
+		return ""
+	}
+	for _, r := range p.tbl.Rows() {
+		if c := r[sb]; c == nil || c.N == nil {
+			return ""
+		}
+	}
+	return sb
+}
+
+// specifyClauseBySubjects runs the clause for all the subjects bound to the
+// provided binding in the current table at once, and joins the results with
+// the rows that bound each subject.
+func (p *queryPlan) specifyClauseBySubjects(ctx context.Context, cls *semantic.GraphClause, sb string, lo *storage.LookupOptions) error {
 	rws := p.tbl.Rows()
 	p.tbl.Truncate()
+	var (
+		subjects []*node.Node
+		seen     = make(map[string]bool)
+	)
+	for _, r := range rws {
+		n := r[sb].N
+		if k := n.String(); !seen[k] {
+			seen[k] = true
+			subjects = append(subjects, n)
+		}
+	}
+	nSubjects := len(subjects)
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{fmt.Sprintf("Batching clause %v for %d subjects", cls, nSubjects)},
+		}
+	})
+	tbl, err := subjectsFetch(ctx, p.grfs, cls, subjects, lo, p.chanSize, p.tracer)
+	if err != nil {
+		return err
+	}
+	bySubject := make(map[string][]table.Row)
+	for _, nr := range tbl.Rows() {
+		k := nr[sb].N.String()
+		bySubject[k] = append(bySubject[k], nr)
+	}
+	p.tbl.AddBindings(tbl.Bindings())
+	for _, r := range rws {
+		nrs := bySubject[r[sb].N.String()]
+		if len(nrs) == 0 && cls.Optional {
+			nr := make(table.Row)
+			for _, k := range tbl.Bindings() {
+				if _, ok := r[k]; !ok {
+					nr[k] = &table.Cell{}
+				}
+			}
+			p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
+			continue
+		}
+		for _, nr := range nrs {
+			p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
+		}
+	}
+	return nil
+}
+
+// forEachRow calls fn for each of the provided rows using a pool of at most
+// p.parallelism workers. It stops at the first error returned by fn.
+func (p *queryPlan) forEachRow(ctx context.Context, rws []table.Row, fn func(context.Context, table.Row) error) error {
 	grp, gCtx := errgroup.WithContext(ctx)
+	rc := make(chan table.Row)
 	grp.Go(func() error {
-		sem := semaphore.NewWeighted(int64(runtime.GOMAXPROCS(0)))
-		for _, tmpRow := range rws {
-			if gCtx.Err() != nil {
+		defer close(rc)
+		for _, r := range rws {
+			select {
+			case <-gCtx.Done():
 				return gCtx.Err()
+			case rc <- r:
 			}
-			if err := sem.Acquire(gCtx, 1); err != nil {
-				return err
-			}
-			r := tmpRow
-			grp.Go(func() error {
-				defer sem.Release(1)
-				var tmpCls = *cls
-				// The table manipulations are now thread safe.
-				return p.addSpecifiedData(gCtx, r, &tmpCls, lo)
-			})
 		}
 		return nil
 	})
+	n := p.parallelism
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n > len(rws) {
+		n = len(rws)
+	}
+	for i := 0; i < n; i++ {
+		grp.Go(func() error {
+			for r := range rc {
+				if err := fn(gCtx, r); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
 	return grp.Wait()
 }
 
 // cellToObject returns an object for the given cell.
 func cellToObject(c *table.Cell) (*triple.Object, error) {
-	if c == nil {
+	if c.IsEmpty() {
 		return nil, errors.New("cannot create an object out of and empty cell")
 	}
-	if c.N != nil {
-		return triple.NewNodeObject(c.N), nil
+	if n, ok := c.Node(); ok {
+		return triple.NewNodeObject(n), nil
 	}
-	if c.P != nil {
-		return triple.NewPredicateObject(c.P), nil
+	if p, ok := c.Predicate(); ok {
+		return triple.NewPredicateObject(p), nil
 	}
-	if c.L != nil {
-		return triple.NewLiteralObject(c.L), nil
+	if l, ok := c.Literal(); ok {
+		return triple.NewLiteralObject(l), nil
 	}
-	if c.S != nil {
-		l, err := literal.DefaultBuilder().Parse(fmt.Sprintf(`"%s"^^type:string`, *c.S))
+	if s, ok := c.StringValue(); ok {
+		l, err := literal.DefaultBuilder().Parse(fmt.Sprintf(`"%s"^^type:string`, s))
 		if err != nil {
 			return nil, err
 		}
@@ -552,123 +1087,116 @@ func (p *queryPlan) filterOnExistence(ctx context.Context, cls *semantic.GraphCl
 	data := p.tbl.Rows()
 	p.tbl.Truncate()
 	ocls := *cls
-	grp, gCtx := errgroup.WithContext(ctx)
-	grp.Go(func() error {
-		sem := semaphore.NewWeighted(int64(runtime.GOMAXPROCS(0)))
-		for _, tmp := range data {
-			if gCtx.Err() != nil {
-				return gCtx.Err()
+	return p.forEachRow(ctx, data, func(gCtx context.Context, r table.Row) error {
+		cls := ocls
+		sbj, prd, obj := cls.S, cls.P, cls.O
+		// Attempt to rebind the subject.
+		if sbj == nil && p.tbl.HasBinding(cls.SBinding) {
+			v, ok := r[cls.SBinding]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.SBinding)
+			}
+			if v.N == nil {
+				return fmt.Errorf("binding %q requires a node, got %+v instead", cls.SBinding, v)
+			}
+			sbj = v.N
+		}
+		if sbj == nil && p.tbl.HasBinding(cls.SAlias) {
+			v, ok := r[cls.SAlias]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.SAlias)
+			}
+			if v.N == nil {
+				return fmt.Errorf("binding %q requires a node, got %+v instead", cls.SAlias, v)
+			}
+			sbj = v.N
+		}
+		// Attempt to rebind the predicate.
+		if prd == nil && p.tbl.HasBinding(cls.PBinding) {
+			v, ok := r[cls.PBinding]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.PBinding)
+			}
+			if v.P == nil {
+				return fmt.Errorf("binding %q requires a predicate, got %+v instead", cls.PBinding, v)
+			}
+			prd = v.P
+		}
+		if prd == nil && p.tbl.HasBinding(cls.PAlias) {
+			v, ok := r[cls.PAlias]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.SAlias)
+			}
+			if v.N == nil {
+				return fmt.Errorf("binding %q requires a predicate, got %+v instead", cls.SAlias, v)
+			}
+			prd = v.P
+		}
+		// Attempt to rebind the object.
+		if obj == nil && p.tbl.HasBinding(cls.OBinding) {
+			v, ok := r[cls.OBinding]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.OBinding)
 			}
-			if err := sem.Acquire(gCtx, 1); err != nil {
+			co, err := cellToObject(v)
+			if err != nil {
 				return err
 			}
-			r := tmp
-			cls := ocls
-			grp.Go(func() error {
-				defer sem.Release(1)
-				sbj, prd, obj := cls.S, cls.P, cls.O
-				// Attempt to rebind the subject.
-				if sbj == nil && p.tbl.HasBinding(cls.SBinding) {
-					v, ok := r[cls.SBinding]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.SBinding)
-					}
-					if v.N == nil {
-						return fmt.Errorf("binding %q requires a node, got %+v instead", cls.SBinding, v)
-					}
-					sbj = v.N
-				}
-				if sbj == nil && p.tbl.HasBinding(cls.SAlias) {
-					v, ok := r[cls.SAlias]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.SAlias)
-					}
-					if v.N == nil {
-						return fmt.Errorf("binding %q requires a node, got %+v instead", cls.SAlias, v)
-					}
-					sbj = v.N
-				}
-				// Attempt to rebind the predicate.
-				if prd == nil && p.tbl.HasBinding(cls.PBinding) {
-					v, ok := r[cls.PBinding]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.PBinding)
-					}
-					if v.P == nil {
-						return fmt.Errorf("binding %q requires a predicate, got %+v instead", cls.PBinding, v)
-					}
-					prd = v.P
-				}
-				if prd == nil && p.tbl.HasBinding(cls.PAlias) {
-					v, ok := r[cls.PAlias]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.SAlias)
-					}
-					if v.N == nil {
-						return fmt.Errorf("binding %q requires a predicate, got %+v instead", cls.SAlias, v)
-					}
-					prd = v.P
-				}
-				// Attempt to rebind the object.
-				if obj == nil && p.tbl.HasBinding(cls.OBinding) {
-					v, ok := r[cls.OBinding]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.OBinding)
-					}
-					co, err := cellToObject(v)
-					if err != nil {
-						return err
-					}
-					obj = co
-				}
-				if obj == nil && p.tbl.HasBinding(cls.OAlias) {
-					v, ok := r[cls.OAlias]
-					if !ok {
-						return fmt.Errorf("row %+v misses binding %q", r, cls.OAlias)
-					}
-					if v.N == nil {
-						return fmt.Errorf("binding %q requires a object, got %+v instead", cls.OAlias, v)
-					}
-					co, err := cellToObject(v)
-					if err != nil {
-						return err
-					}
-					obj = co
-				}
-				// Attempt to filter.
-				if sbj == nil || prd == nil || obj == nil {
-					return fmt.Errorf("failed to fully specify clause %v for row %+v", cls, r)
-				}
-				exist := false
-				for _, g := range p.stm.InputGraphs() {
-					gID := g.ID(gCtx)
-					t, err := triple.New(sbj, prd, obj)
-					if err != nil {
-						return err
-					}
-					tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
-						return &tracer.Arguments{
-							Msgs: []string{fmt.Sprintf("g.Exist(%v), graph: %s", t, gID)},
-						}
-					})
-					b, err := g.Exist(gCtx, t)
-					if err != nil {
-						return err
-					}
-					exist = exist || b
-					if exist || gCtx.Err() != nil {
-						break
-					}
-				}
-				if exist {
-					p.tbl.AddRow(r)
+			obj = co
+		}
+		if obj == nil && p.tbl.HasBinding(cls.OAlias) {
+			v, ok := r[cls.OAlias]
+			if !ok {
+				return fmt.Errorf("row %+v misses binding %q", r, cls.OAlias)
+			}
+			if v.N == nil {
+				return fmt.Errorf("binding %q requires a object, got %+v instead", cls.OAlias, v)
+			}
+			co, err := cellToObject(v)
+			if err != nil {
+				return err
+			}
+			obj = co
+		}
+		// Attempt to filter.
+		if sbj == nil || prd == nil || obj == nil {
+			return fmt.Errorf("failed to fully specify clause %v for row %+v", cls, r)
+		}
+		if ok, err := passesGuard(r, &cls); err != nil || !ok {
+			return err
+		}
+		if cls.PIDConstant != "" && string(prd.ID()) != cls.PIDConstant {
+			return nil
+		}
+		if !matchesObjectType(obj, &cls) {
+			return nil
+		}
+		exist := false
+		for _, g := range p.stm.InputGraphs() {
+			gID := g.ID(gCtx)
+			t, err := triple.New(sbj, prd, obj)
+			if err != nil {
+				return err
+			}
+			tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+				return &tracer.Arguments{
+					Msgs: []string{fmt.Sprintf("g.Exist(%v), graph: %s", t, gID)},
 				}
-				return nil
 			})
+			b, err := g.Exist(gCtx, t)
+			if err != nil {
+				return err
+			}
+			exist = exist || b
+			if exist || gCtx.Err() != nil {
+				break
+			}
+		}
+		if exist {
+			p.tbl.AddRow(r)
 		}
 		return nil
 	})
-	return grp.Wait()
 }
 
 // organizeClausesByBinding takes the graph clauses received as input and organize them in a map
@@ -799,7 +1327,14 @@ func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupO
 		}
 	})
 	tStartClauses := time.Now()
-	for i, cls := range p.clauses {
+	// OPTIONAL blocks with several clauses are evaluated as a unit once the
+	// rest of the graph pattern has been processed.
+	clauses, blocks := splitOptionalBlocks(p.clauses)
+	for i, cls := range clauses {
+		// Stop as soon as the query is cancelled, for instance by KILL.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		iCopy, clsCopy := i, cls // creating local copies of the loop variables to not pass them by reference to the closure of the lazy tracer.
 		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
 			return &tracer.Arguments{
@@ -825,6 +1360,23 @@ func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupO
 			p.tbl.Truncate()
 			return nil
 		}
+		p.recordProvenance(p.tbl, cls)
+	}
+	for _, blk := range blocks {
+		if err := p.processOptionalBlock(ctx, blk, lo, filterOptionsByClause); err != nil {
+			return err
+		}
+		// Empty cells added for rows the block did not match.
+		p.recordProvenance(p.tbl, blk[0])
+	}
+	// NOT blocks only remove rows, so they are evaluated last.
+	for _, blk := range p.negated {
+		if p.tbl.NumRows() == 0 {
+			break
+		}
+		if err := p.processNegatedBlock(ctx, blk, lo, filterOptionsByClause); err != nil {
+			return err
+		}
 	}
 	tElapsedClauses := time.Now().Sub(tStartClauses)
 	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
@@ -832,13 +1384,330 @@ func (p *queryPlan) processGraphPattern(ctx context.Context, lo *storage.LookupO
 			Msgs: []string{fmt.Sprintf("Finished processing all clauses, total latency: %v", tElapsedClauses)},
 		}
 	})
+	p.traceProvenance()
+
+	return nil
+}
+
+// recordProvenance attributes the cells of the rows of the provided table that
+// have no provenance yet to the provided clause. It does nothing unless
+// provenance is being tracked.
+func (p *queryPlan) recordProvenance(tbl *table.Table, cls *semantic.GraphClause) {
+	if p.provenance == nil {
+		return
+	}
+	idx := -1
+	for i, c := range p.stm.GraphPatternClauses() {
+		if c == cls {
+			idx = i
+			break
+		}
+	}
+	tbl.EachRow(func(r table.Row) error {
+		p.provenance.Record(r, idx)
+		return nil
+	})
+}
+
+// traceProvenance traces the clauses that produced the cells of each row of
+// the table. The messages are built right away, since the rows are modified
+// by the following steps of the plan.
+func (p *queryPlan) traceProvenance() {
+	if p.provenance == nil || p.tracer == nil {
+		return
+	}
+	var msgs []string
+	p.tbl.EachRow(func(r table.Row) error {
+		msgs = append(msgs, fmt.Sprintf("Row %d provenance: %s", len(msgs), p.provenance.Describe(r)))
+		return nil
+	})
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: msgs,
+		}
+	})
+}
+
+// splitOptionalBlocks separates the clauses of the OPTIONAL blocks that contain
+// more than one clause from the rest of the clauses. The relative order of the
+// clauses is preserved.
+func splitOptionalBlocks(clauses []*semantic.GraphClause) ([]*semantic.GraphClause, [][]*semantic.GraphClause) {
+	sizes := make(map[int]int)
+	for _, cls := range clauses {
+		if cls.Optional && cls.OptionalGroup != 0 {
+			sizes[cls.OptionalGroup]++
+		}
+	}
+	var (
+		rest   []*semantic.GraphClause
+		blocks [][]*semantic.GraphClause
+	)
+	idx := make(map[int]int)
+	for _, cls := range clauses {
+		if !cls.Optional || sizes[cls.OptionalGroup] < 2 {
+			rest = append(rest, cls)
+			continue
+		}
+		i, ok := idx[cls.OptionalGroup]
+		if !ok {
+			i = len(blocks)
+			idx[cls.OptionalGroup] = i
+			blocks = append(blocks, nil)
+		}
+		blocks[i] = append(blocks[i], cls)
+	}
+	return rest, blocks
+}
+
+// processOptionalBlock evaluates the clauses of an OPTIONAL block into their
+// own table and left joins it to the current results. This makes the block
+// atomic: rows are either extended with a full match of the block or with
+// empty cells for all the block bindings.
+func (p *queryPlan) processOptionalBlock(ctx context.Context, blk []*semantic.GraphClause, lo *storage.LookupOptions, filterOptionsByClause map[*semantic.GraphClause]*filter.StorageOptions) error {
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{fmt.Sprintf("Processing optional block: %v", blk)},
+		}
+	})
+	sub, err := p.evaluateBlock(ctx, blk, lo, filterOptionsByClause)
+	if err != nil {
+		return err
+	}
+	var bs []string
+	for _, cls := range blk {
+		bs = append(bs, cls.Bindings()...)
+	}
+	if len(p.tbl.Bindings()) == 0 {
+		return p.tbl.AppendTable(sub)
+	}
+	if sub.NumRows() > 0 {
+		return p.tbl.LeftOptionalJoin(sub)
+	}
+	// The block did not match; it only contributes empty cells.
+	rws := p.tbl.Rows()
+	p.tbl.Truncate()
+	p.tbl.AddBindings(bs)
+	for _, r := range rws {
+		nr := make(table.Row)
+		for _, b := range bs {
+			if _, ok := r[b]; !ok {
+				nr[b] = &table.Cell{}
+			}
+		}
+		p.tbl.AddRow(table.MergeRows([]table.Row{r, nr}))
+	}
+	return nil
+}
+
+// processNegatedBlock evaluates the clauses of a NOT block into their own
+// table and removes the current rows that agree with any of its rows on the
+// bindings they share. If they share no bindings, any match of the block
+// removes all the rows.
+func (p *queryPlan) processNegatedBlock(ctx context.Context, blk []*semantic.GraphClause, lo *storage.LookupOptions, filterOptionsByClause map[*semantic.GraphClause]*filter.StorageOptions) error {
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{fmt.Sprintf("Processing negated block: %v", blk)},
+		}
+	})
+	sub, err := p.evaluateBlock(ctx, blk, lo, filterOptionsByClause)
+	if err != nil {
+		return err
+	}
+	p.tbl.AntiJoin(sub)
+	return nil
+}
+
+// evaluateBlock evaluates the provided clauses into a new table, independently
+// of the rows already computed.
+func (p *queryPlan) evaluateBlock(ctx context.Context, blk []*semantic.GraphClause, lo *storage.LookupOptions, filterOptionsByClause map[*semantic.GraphClause]*filter.StorageOptions) (*table.Table, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	sub := &queryPlan{
+		stm:         p.stm,
+		store:       p.store,
+		grfsNames:   p.grfsNames,
+		grfs:        p.grfs,
+		clauses:     blk,
+		tbl:         t,
+		chanSize:    p.chanSize,
+		tracer:      p.tracer,
+		parallelism: p.parallelism,
+		provenance:  p.provenance,
+	}
+	for _, cls := range blk {
+		c := *cls
+		c.Optional = false
+		addFilterOptions(lo, cls, filterOptionsByClause)
+		unresolvable, err := sub.processClause(ctx, &c, lo)
+		resetFilterOptions(lo)
+		if err != nil {
+			return nil, err
+		}
+		if unresolvable {
+			sub.tbl.Truncate()
+			break
+		}
+		p.recordProvenance(sub.tbl, cls)
+	}
+	return sub.tbl, nil
+}
+
+// tableFilter represents a filter applied by the planner on the rows of the
+// resulting table instead of being pushed down to the storage level.
+type tableFilter struct {
+	clause *semantic.FilterClause
+	value  string
+	cell   *table.Cell
+}
+
+// newTableFilter returns a new table filter for the provided filter clause. The
+// value of the clause is parsed and validated at plan time.
+func newTableFilter(f *semantic.FilterClause) (*tableFilter, error) {
+	switch f.Operation {
+	case filter.Contains, filter.Lang:
+		l, err := literal.DefaultBuilder().Parse(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for filter function %q: %v", f.Value, f.Operation, err)
+		}
+		v, err := l.Text()
+		if err != nil {
+			return nil, fmt.Errorf("filter function %q requires a text literal; found %s instead", f.Operation, l)
+		}
+		if f.Operation == filter.Lang {
+			// Language tags are case insensitive; the empty tag selects
+			// untagged text.
+			if v != "" {
+				tl, err := l.WithLang(v)
+				if err != nil {
+					return nil, fmt.Errorf("filter function %q requires a valid language tag: %v", f.Operation, err)
+				}
+				v = tl.Lang()
+			}
+		}
+		return &tableFilter{clause: f, value: v}, nil
+	case filter.Equal, filter.NotEqual:
+		if strings.HasPrefix(f.Value, "/") {
+			n, err := node.Parse(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid node %q for filter function %q: %v", f.Value, f.Operation, err)
+			}
+			return &tableFilter{clause: f, cell: &table.Cell{N: n}}, nil
+		}
+		l, err := literal.DefaultBuilder().Parse(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for filter function %q: %v", f.Value, f.Operation, err)
+		}
+		return &tableFilter{clause: f, cell: &table.Cell{L: l}}, nil
+	default:
+		return nil, fmt.Errorf("filter function %q cannot be applied at table level", f.Operation)
+	}
+}
+
+// keep returns true if the provided row satisfies the filter.
+func (f *tableFilter) keep(r table.Row) bool {
+	c, ok := r[f.clause.Binding]
+	if !ok {
+		return false
+	}
+	switch f.clause.Operation {
+	case filter.Contains:
+		var s string
+		switch {
+		case c.L != nil && c.L.Type() == literal.Text:
+			s, _ = c.L.Text()
+		case c.S != nil:
+			s = *c.S
+		default:
+			return false
+		}
+		return strings.Contains(s, f.value)
+	case filter.Lang:
+		switch {
+		case c.L != nil && c.L.Type() == literal.Text:
+			return c.L.Lang() == f.value
+		case c.S != nil:
+			return f.value == ""
+		default:
+			return false
+		}
+	case filter.Equal:
+		return f.equal(c)
+	case filter.NotEqual:
+		return !f.equal(c)
+	default:
+		return false
+	}
+}
 
+// equal returns true if the provided cell holds the same node or literal as the
+// operand of the filter.
+func (f *tableFilter) equal(c *table.Cell) bool {
+	switch {
+	case f.cell.N != nil:
+		return c.N != nil && c.N.String() == f.cell.N.String()
+	case f.cell.L != nil:
+		return c.L != nil && c.L.String() == f.cell.L.String()
+	default:
+		return false
+	}
+}
+
+// filterTable removes the rows of the resulting table that do not satisfy the
+// table level filters. It runs after all the storage level filters were
+// applied while resolving the graph pattern.
+func (p *queryPlan) filterTable() {
+	for _, f := range p.tblFilters {
+		fStr := f.clause.String()
+		nRowsRemoved := p.tbl.Filter(func(r table.Row) bool {
+			return !f.keep(r)
+		})
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Table filter %s removed %d rows", fStr, nRowsRemoved)},
+			}
+		})
+	}
+}
+
+// bucketGroupBy truncates the time anchors of the group by bindings that
+// specify a BUCKET interval, so rows in the same bucket are reduced together.
+func (p *queryPlan) bucketGroupBy() error {
+	for _, g := range p.stm.GroupByBindings() {
+		i := p.stm.GroupByBucket(g)
+		if i == nil {
+			continue
+		}
+		for _, prj := range p.stm.Projections() {
+			if g != prj.Alias && (prj.Alias != "" || g != prj.Binding) {
+				continue
+			}
+			gCopy, iStr := g, i.String()
+			tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+				return &tracer.Arguments{
+					Msgs: []string{fmt.Sprintf("Bucketing group by binding %q using interval %q", gCopy, iStr)},
+				}
+			})
+			for _, row := range p.tbl.Rows() {
+				c, ok := row[prj.Binding]
+				if !ok || c.T == nil {
+					return fmt.Errorf("BUCKET(%s, %q) can only be applied to time anchors; found %v instead", g, i, c)
+				}
+				t := i.Truncate(*c.T)
+				row[prj.Binding] = table.NewTimeCell(t)
+			}
+		}
+	}
 	return nil
 }
 
 // projectAndGroupBy takes the resulting table and projects its contents and
 // groups it by if needed.
 func (p *queryPlan) projectAndGroupBy() error {
+	if h := p.histogramProjection(); h != nil {
+		return p.histogram(h)
+	}
 	grp := p.stm.GroupByBindings()
 	if len(grp) == 0 { // The table only needs to be projected.
 		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
@@ -853,6 +1722,9 @@ func (p *queryPlan) projectAndGroupBy() error {
 				row[prj.Alias] = row[prj.Binding]
 			}
 		}
+		if err := p.typeProjections(); err != nil {
+			return err
+		}
 		outputBindings := p.stm.OutputBindings()
 		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
 			return &tracer.Arguments{
@@ -861,11 +1733,21 @@ func (p *queryPlan) projectAndGroupBy() error {
 		})
 		return p.tbl.ProjectBindings(outputBindings)
 	}
+	if idx := p.groupIndexProjection(); idx != nil {
+		return p.groupIndex(idx)
+	}
 	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
 		return &tracer.Arguments{
 			Msgs: []string{"Starting group reduce and projection"},
 		}
 	})
+	if err := p.bucketGroupBy(); err != nil {
+		return err
+	}
+	if p.tbl.NumRows() == 0 {
+		// There are no groups to reduce; the bindings are fixed once executed.
+		return nil
+	}
 	// The table needs to be group reduced.
 	// Project only binding involved in the group operation.
 	tmpBindings := []string{}
@@ -885,7 +1767,7 @@ func (p *queryPlan) projectAndGroupBy() error {
 		// Update sorting configuration.
 		found := false
 		for _, g := range p.stm.GroupByBindings() {
-			if prj.Binding == g {
+			if prj.Binding == g || prj.Alias == g {
 				found = true
 			}
 		}
@@ -922,6 +1804,10 @@ func (p *queryPlan) projectAndGroupBy() error {
 			default:
 				return fmt.Errorf("can only sum int64 and float64 literals; found literal type %s instead for binding %q", cell.L.Type(), prj.Binding)
 			}
+		case lexer.ItemGroupConcat:
+			aap.Acc = table.NewConcatAccumulator(prj.Separator)
+		case lexer.ItemGraphCount:
+			aap.Acc = table.NewCountDistinctAccumulator()
 		}
 		aaps = append(aaps, aap)
 	}
@@ -938,69 +1824,386 @@ func (p *queryPlan) projectAndGroupBy() error {
 			Msgs: []string{"Reducing the table using configuration " + cfg.String()},
 		}
 	})
-	p.tbl.Reduce(cfg, aaps)
+	if err := p.tbl.HashReduce(cfg, aaps); err != nil {
+		return err
+	}
+	return p.typeProjections()
+}
+
+// histogramProjection returns the projection requesting HISTOGRAM in the
+// select clause, if any.
+func (p *queryPlan) histogramProjection() *semantic.Projection {
+	for _, prj := range p.stm.Projections() {
+		if prj.OP == lexer.ItemHistogram {
+			return prj
+		}
+	}
 	return nil
 }
 
-// orderBy takes the resulting table and sorts its contents according to the
-// specifications of the ORDER BY clause.
-func (p *queryPlan) orderBy() {
-	order := p.stm.OrderByConfig()
-	if len(order) <= 0 {
-		return
+// histogram replaces each GROUP BY group, or the whole table if there is no
+// GROUP BY clause, with one row per bucket of the interval requested via
+// HISTOGRAM holding the start of the bucket and its number of rows.
+func (p *queryPlan) histogram(h *semantic.Projection) error {
+	if err := p.bucketGroupBy(); err != nil {
+		return err
 	}
-	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+	grp := p.stm.GroupByBindings()
+	p.tbl.AddBindings(grp)
+	for _, prj := range p.stm.Projections() {
+		if prj == h || prj.Alias == "" {
+			continue
+		}
+		for _, row := range p.tbl.Rows() {
+			row[prj.Alias] = row[prj.Binding]
+		}
+	}
+	hStr, empty := h.String(), p.emptyBuckets
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
 		return &tracer.Arguments{
-			Msgs: []string{"Ordering by " + order.String()},
+			Msgs: []string{fmt.Sprintf("Counting rows for projection %q grouped by %v with empty buckets %v", hStr, grp, empty)},
 		}
 	})
-	p.tbl.Sort(order)
+	return p.tbl.Histogram(grp, h.Binding, h.Interval, semantic.HistogramStartBinding, semantic.HistogramCountBinding, p.emptyBuckets)
 }
 
-// having runs the filtering based on the having clause if needed.
-func (p *queryPlan) having() error {
-	if p.stm.HasHavingClause() {
-		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
-			return &tracer.Arguments{
-				Msgs: []string{"Starting to process HAVING clause"},
-			}
-		})
-		eval := p.stm.HavingEvaluator()
-		ok := true
-		var eErr error
-		nRowsRemoved := p.tbl.Filter(func(r table.Row) bool {
-			b, err := eval.Evaluate(r)
-			if err != nil {
-				ok, eErr = false, err
-			}
-			return !b
-		})
-		if !ok {
-			tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
-				return &tracer.Arguments{
-					Msgs: []string{eErr.Error()},
-				}
-			})
-			return eErr
+// groupIndexProjection returns the projection requesting GROUP_INDEX() in the
+// select clause, if any.
+func (p *queryPlan) groupIndexProjection() *semantic.Projection {
+	for _, prj := range p.stm.Projections() {
+		if prj.OP == lexer.ItemGroupIndex {
+			return prj
 		}
-		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
-			return &tracer.Arguments{
-				Msgs: []string{fmt.Sprintf("Finished processing HAVING clause, %d rows were removed from table", nRowsRemoved)},
-			}
-		})
 	}
 	return nil
 }
 
-// limit truncates the table if the limit clause if available.
-func (p *queryPlan) limit() {
-	if p.stm.IsLimitSet() {
-		stmLimit := p.stm.Limit()
-		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
-			return &tracer.Arguments{
-				Msgs: []string{fmt.Sprintf("Limit results to %s", strconv.Itoa(int(stmLimit)))},
-			}
-		})
+// groupIndex projects the table and, instead of reducing each GROUP BY group
+// to a single row, keeps all the rows of each group numbering them from 0
+// following the ORDER BY order. The number is bound to the alias of all the
+// projections requesting GROUP_INDEX().
+func (p *queryPlan) groupIndex(idx *semantic.Projection) error {
+	if err := p.bucketGroupBy(); err != nil {
+		return err
+	}
+	p.tbl.AddBindings(p.stm.OutputBindings())
+	var idxAliases []string
+	for _, prj := range p.stm.Projections() {
+		if prj.OP == lexer.ItemGroupIndex {
+			idxAliases = append(idxAliases, prj.Alias)
+			continue
+		}
+		for _, row := range p.tbl.Rows() {
+			row[prj.Alias] = row[prj.Binding]
+		}
+	}
+	if err := p.typeProjections(); err != nil {
+		return err
+	}
+	// Sort the groups together, and each group in the ORDER BY order.
+	cfg := table.SortConfig{}
+	for _, g := range p.stm.GroupByBindings() {
+		cfg = append(cfg, table.SortConfig{{Binding: g}}...)
+	}
+	for _, o := range p.stm.OrderByConfig() {
+		if o.Binding != idx.Alias {
+			cfg = append(cfg, o)
+		}
+	}
+	cfgStr := cfg.String()
+	tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{"Numbering the rows of each group using configuration " + cfgStr},
+		}
+	})
+	p.tbl.Sort(cfg)
+	last, n := "", int64(0)
+	for i, row := range p.tbl.Rows() {
+		var b strings.Builder
+		for _, g := range p.stm.GroupByBindings() {
+			b.WriteString(row[g].String())
+			b.WriteString(";")
+		}
+		if cur := b.String(); i == 0 || cur != last {
+			last, n = cur, 0
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Int64, n)
+		if err != nil {
+			return err
+		}
+		for _, a := range idxAliases {
+			row[a] = table.NewLiteralCell(l)
+		}
+		n++
+	}
+	return p.tbl.ProjectBindings(p.stm.OutputBindings())
+}
+
+// typeProjections replaces the values of the projected aliases that were
+// requested via KIND or DATATYPE in the select clause.
+func (p *queryPlan) typeProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Func != lexer.ItemKind && prj.Func != lexer.ItemDatatype {
+			continue
+		}
+		prjStr := prj.String()
+		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Extracting types for projection %q", prjStr)},
+			}
+		})
+		alias := prj.Alias
+		if alias == "" {
+			alias = prj.Binding
+		}
+		fn, name := semantic.KindCell, "KIND"
+		if prj.Func == lexer.ItemDatatype {
+			fn, name = semantic.DatatypeCell, "DATATYPE"
+		}
+		for _, row := range p.tbl.Rows() {
+			c, ok := row[alias]
+			if !ok || c == nil {
+				return fmt.Errorf("cannot extract the %s of missing value for binding %q", strings.ToLower(name), alias)
+			}
+			tc, err := fn(c)
+			if err != nil {
+				return fmt.Errorf("%s(%s) failed: %v", name, prj.Binding, err)
+			}
+			row[alias] = tc
+		}
+	}
+	return nil
+}
+
+// castProjections casts the values of the projected aliases that were
+// requested via CAST in the select clause.
+func (p *queryPlan) castProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Func != lexer.ItemCast {
+			continue
+		}
+		prjStr := prj.String()
+		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Casting projection %q", prjStr)},
+			}
+		})
+		for _, row := range p.tbl.Rows() {
+			c, ok := row[prj.Alias]
+			if !ok {
+				return fmt.Errorf("cannot cast missing value for binding %q", prj.Alias)
+			}
+			cc, err := semantic.CastCell(c, prj.CastType)
+			if err != nil {
+				return fmt.Errorf("CAST(%s, type:%s) failed: %v", prj.Binding, prj.CastType, err)
+			}
+			row[prj.Alias] = cc
+		}
+	}
+	return nil
+}
+
+// langProjections replaces the values of the projected aliases that were
+// requested via LANG in the select clause by their language tags.
+func (p *queryPlan) langProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Func != lexer.ItemLang {
+			continue
+		}
+		prjStr := prj.String()
+		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Extracting language tags for projection %q", prjStr)},
+			}
+		})
+		for _, row := range p.tbl.Rows() {
+			c, ok := row[prj.Alias]
+			if !ok {
+				return fmt.Errorf("cannot extract the language tag of missing value for binding %q", prj.Alias)
+			}
+			lc, err := semantic.LangCell(c)
+			if err != nil {
+				return fmt.Errorf("LANG(%s) failed: %v", prj.Binding, err)
+			}
+			row[prj.Alias] = lc
+		}
+	}
+	return nil
+}
+
+// stringProjections replaces the values of the projected aliases that were
+// requested via STRLEN or SUBSTR in the select clause.
+func (p *queryPlan) stringProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Func != lexer.ItemStrlen && prj.Func != lexer.ItemSubstr {
+			continue
+		}
+		prjStr := prj.String()
+		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Applying string function for projection %q", prjStr)},
+			}
+		})
+		for _, row := range p.tbl.Rows() {
+			c, ok := row[prj.Alias]
+			if !ok {
+				return fmt.Errorf("cannot apply string function to missing value for binding %q", prj.Alias)
+			}
+			if prj.Func == lexer.ItemStrlen {
+				sc, err := semantic.StrlenCell(c)
+				if err != nil {
+					return fmt.Errorf("STRLEN(%s) failed: %v", prj.Binding, err)
+				}
+				row[prj.Alias] = sc
+				continue
+			}
+			sc, err := semantic.SubstrCell(c, prj.SubstrStart, prj.SubstrLen)
+			if err != nil {
+				return fmt.Errorf("SUBSTR(%s, %d, %d) failed: %v", prj.Binding, prj.SubstrStart, prj.SubstrLen, err)
+			}
+			row[prj.Alias] = sc
+		}
+	}
+	return nil
+}
+
+// tripleProjections packs the subject, predicate and object values of the
+// projections requested via TRIPLE in the select clause into triple cells.
+// Rows missing any of the values get an empty cell.
+func (p *queryPlan) tripleProjections() error {
+	for _, prj := range p.stm.Projections() {
+		if prj.Func != lexer.ItemTriple {
+			continue
+		}
+		prjStr := prj.String()
+		tracer.V(3).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Packing triples for projection %q", prjStr)},
+			}
+		})
+		p.tbl.AddBindings([]string{prj.Binding})
+		for _, row := range p.tbl.Rows() {
+			sc, pc, oc := row[prj.Args[0]], row[prj.Args[1]], row[prj.Args[2]]
+			if sc.IsEmpty() || pc.IsEmpty() || oc.IsEmpty() {
+				row[prj.Binding] = &table.Cell{}
+				continue
+			}
+			s, ok := sc.Node()
+			if !ok {
+				return fmt.Errorf("%s failed: subject %s is not a node", prj.Binding, sc)
+			}
+			pred, ok := pc.Predicate()
+			if !ok {
+				return fmt.Errorf("%s failed: predicate %s is not a predicate", prj.Binding, pc)
+			}
+			o, err := cellToObject(oc)
+			if err != nil {
+				return fmt.Errorf("%s failed: %v", prj.Binding, err)
+			}
+			t, err := triple.New(s, pred, o)
+			if err != nil {
+				return fmt.Errorf("%s failed: %v", prj.Binding, err)
+			}
+			row[prj.Binding] = table.NewTripleCell(t)
+		}
+	}
+	return nil
+}
+
+// unwind expands the rows of the resulting table according to the
+// specifications of the UNWIND clause.
+func (p *queryPlan) unwind() error {
+	if p.tbl.NumRows() == 0 {
+		return nil
+	}
+	for _, u := range p.stm.UnwindClauses() {
+		uStr := u.String()
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{"Unwinding " + uStr},
+			}
+		})
+		if err := p.tbl.Unwind(u.Binding, u.Alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderBy takes the resulting table and sorts its contents according to the
+// specifications of the ORDER BY clause.
+func (p *queryPlan) orderBy() {
+	order := p.stm.OrderByConfig()
+	if len(order) <= 0 {
+		return
+	}
+	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{"Ordering by " + order.String()},
+		}
+	})
+	p.tbl.Sort(order)
+}
+
+// having runs the filtering based on the having clause if needed.
+func (p *queryPlan) having() error {
+	// Projecting the table only changes its bindings and keeps all the values of
+	// each row, so ungrouped queries can filter on any binding of the graph
+	// pattern even if it is not projected.
+	if p.stm.HasHavingClause() {
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{"Starting to process HAVING clause"},
+			}
+		})
+		eval := semantic.WithNumericComparison(p.stm.HavingEvaluator(), p.numbers)
+		ok := true
+		var eErr error
+		nRowsRemoved := p.tbl.Filter(func(r table.Row) bool {
+			b, err := eval.Evaluate(r)
+			if err != nil {
+				ok, eErr = false, err
+			}
+			return !b
+		})
+		if !ok {
+			tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+				return &tracer.Arguments{
+					Msgs: []string{eErr.Error()},
+				}
+			})
+			return eErr
+		}
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Finished processing HAVING clause, %d rows were removed from table", nRowsRemoved)},
+			}
+		})
+	}
+	return nil
+}
+
+// distinct drops the repeated rows of the table if the statement requires it.
+func (p *queryPlan) distinct() {
+	if p.stm.Distinct() {
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{"Dropping repeated rows"},
+			}
+		})
+		p.tbl.Distinct()
+	}
+}
+
+// limit truncates the table if the limit clause if available.
+func (p *queryPlan) limit() {
+	if p.stm.IsLimitSet() {
+		stmLimit := p.stm.Limit()
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Limit results to %s", strconv.Itoa(int(stmLimit)))},
+			}
+		})
 		p.tbl.Limit(stmLimit)
 	}
 }
@@ -1018,6 +2221,46 @@ func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 		return nil, err
 	}
 	p.grfs = p.stm.InputGraphs()
+	if p.cache == nil {
+		return p.execute(ctx)
+	}
+	key := p.cacheKey(ctx)
+	versions, err := graphVersions(ctx, p.grfs)
+	if err != nil {
+		return nil, err
+	}
+	tbl, ok, err := p.cache.get(key, versions)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{"Returning cached results"},
+			}
+		})
+		return tbl, nil
+	}
+	tbl, err = p.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.cache.put(key, versions, tbl); err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
+
+// cacheKey returns the key of the results of the plan in the result cache. The
+// canonical form of the statement is qualified with the store queried and the
+// plan options that change the results, so plans created with different
+// options can share a cache.
+func (p *queryPlan) cacheKey(ctx context.Context) string {
+	return fmt.Sprintf("%s\n%s\nnumbers=%d\nemptyBuckets=%v", p.store.Name(ctx), p.stm.CanonicalString(), p.numbers, p.emptyBuckets)
+}
+
+// execute runs the query plan against the already initialized graphs.
+func (p *queryPlan) execute(ctx context.Context) (*table.Table, error) {
 	// Retrieve the data.
 	lo := p.stm.GlobalLookupOptions()
 	loStr := lo.String()
@@ -1029,14 +2272,31 @@ func (p *queryPlan) Execute(ctx context.Context) (*table.Table, error) {
 	if err := p.processGraphPattern(ctx, lo); err != nil {
 		return nil, err
 	}
+	p.filterTable()
+	if err := p.unwind(); err != nil {
+		return nil, err
+	}
+	if err := p.tripleProjections(); err != nil {
+		return nil, err
+	}
 	if err := p.projectAndGroupBy(); err != nil {
 		return nil, err
 	}
+	if err := p.castProjections(); err != nil {
+		return nil, err
+	}
+	if err := p.langProjections(); err != nil {
+		return nil, err
+	}
+	if err := p.stringProjections(); err != nil {
+		return nil, err
+	}
 	p.orderBy()
 	err := p.having()
 	if err != nil {
 		return nil, err
 	}
+	p.distinct()
 	p.limit()
 	if p.tbl.NumRows() == 0 {
 		// Correct the bindings.
@@ -1060,12 +2320,33 @@ func (p *queryPlan) String(ctx context.Context) string {
 		b.WriteString(c.String())
 		b.WriteString("\n")
 	}
+	for _, blk := range p.negated {
+		b.WriteString("excluding matches of\n")
+		for _, c := range blk {
+			b.WriteString("\t")
+			b.WriteString(c.String())
+			b.WriteString("\n")
+		}
+	}
 	b.WriteString("with filters\n")
 	for _, f := range p.filters {
 		b.WriteString("\t")
 		b.WriteString(f.String())
 		b.WriteString("\n")
 	}
+	for _, f := range p.tblFilters {
+		b.WriteString("\t")
+		b.WriteString(f.clause.String())
+		b.WriteString(" (table level)\n")
+	}
+	if us := p.stm.UnwindClauses(); us != nil {
+		b.WriteString("unwind results using\n")
+		for _, u := range us {
+			b.WriteString("\t")
+			b.WriteString(u.String())
+			b.WriteString("\n")
+		}
+	}
 	b.WriteString("project results using\n")
 	for _, p := range p.stm.Projection() {
 		b.WriteString("\t")
@@ -1093,6 +2374,9 @@ func (p *queryPlan) String(ctx context.Context) string {
 			b.WriteString("\n")
 		}
 	}
+	if p.stm.Distinct() {
+		b.WriteString("drop repeated rows\n")
+	}
 	if p.stm.HasLimit() {
 		b.WriteString("limit results to ")
 		b.WriteString(fmt.Sprintf("%d", p.stm.Limit()))
@@ -1105,12 +2389,14 @@ func (p *queryPlan) String(ctx context.Context) string {
 // executed in order to satisfy the execution of a valid construct or deconstruct
 // BQL statement.
 type constructPlan struct {
-	stm       *semantic.Statement
-	store     storage.Store
-	tracer    io.Writer
-	bulkSize  int
-	queryPlan *queryPlan
-	construct bool
+	stm           *semantic.Statement
+	store         storage.Store
+	tracer        io.Writer
+	bulkSize      int
+	queryPlan     *queryPlan
+	construct     bool
+	deterministic bool
+	concurrency   int
 }
 
 // Type returns the type of plan used by the executor.
@@ -1202,6 +2488,29 @@ func (p *constructPlan) processConstructClause(cc *semantic.ConstructClause, tbl
 	return t, err
 }
 
+// blankNode returns the blank node used to reify the construct clause for the
+// provided row. Unless deterministic blank nodes were requested, a new one is
+// created each time. Otherwise, its ID is derived from the clause and the
+// values of all the row bindings, so identical rows of the same clause reuse it.
+func (p *constructPlan) blankNode(cc *semantic.ConstructClause, tbl *table.Table, r table.Row) (*node.Node, error) {
+	if !p.deterministic {
+		return node.NewBlankNode(), nil
+	}
+	bs := append([]string{}, tbl.Bindings()...)
+	sort.Strings(bs)
+	var b bytes.Buffer
+	b.WriteString(cc.String())
+	for _, bn := range bs {
+		b.WriteString("\x00")
+		b.WriteString(bn)
+		b.WriteString("\x00")
+		if c, ok := r[bn]; ok {
+			b.WriteString(c.String())
+		}
+	}
+	return node.NewNodeFromStrings("/_", uuid.NewSHA1(uuid.NIL, b.Bytes()).String())
+}
+
 func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 	tbl, err := p.queryPlan.Execute(ctx)
 	if err != nil {
@@ -1238,12 +2547,12 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 		for elem := range tripChan {
 			ts = append(ts, elem)
 			if len(ts) >= p.bulkSize {
-				update(ctx, ts, p.stm.OutputGraphNames(), p.store, updateFunc)
+				update(ctx, ts, p.stm.OutputGraphNames(), p.store, p.concurrency, updateFunc)
 				ts = []*triple.Triple{}
 			}
 		}
 		if len(ts) > 0 {
-			update(ctx, ts, p.stm.OutputGraphNames(), p.store, updateFunc)
+			update(ctx, ts, p.stm.OutputGraphNames(), p.store, p.concurrency, updateFunc)
 		}
 		done <- true
 	}()
@@ -1256,7 +2565,11 @@ func (p *constructPlan) Execute(ctx context.Context) (*table.Table, error) {
 			}
 			if len(cc.PredicateObjectPairs()) > 1 {
 				// We need to reify a blank node.
-				rts, bn, err := t.Reify()
+				bn, err := p.blankNode(cc, tbl, r)
+				if err != nil {
+					return nil, err
+				}
+				rts, err := t.ReifyWith(bn)
 				if err != nil {
 					return nil, fmt.Errorf("triple.Reify failed to reify %v with error %v", t, err)
 				}
@@ -1303,6 +2616,9 @@ func (p *constructPlan) String(ctx context.Context) string {
 	for _, cc := range p.stm.ConstructClauses() {
 		b.WriteString(fmt.Sprintf("\t%v\n", cc))
 	}
+	if p.deterministic {
+		b.WriteString("Blank nodes derived from the matched rows\n")
+	}
 	b.WriteString(fmt.Sprintf("\n%v", p.queryPlan.String(ctx)))
 	return b.String()
 }
@@ -1328,19 +2644,17 @@ func (p *showPlan) Execute(ctx context.Context) (*table.Table, error) {
 	errs := make(chan error)
 	names := make(chan string)
 	go func() {
-		errs <- p.store.GraphNames(ctx, names)
+		errs <- storage.GraphNamesSorted(ctx, p.store, names)
 		close(errs)
 	}()
 
 	for name := range names {
 		id := name
 		t.AddRow(table.Row{
-			"?graph_id": &table.Cell{
-				S: &id,
-			},
+			"?graph_id": table.NewStringCell(id),
 		})
 	}
-	if <-errs != nil {
+	if err := <-errs; err != nil {
 		return nil, err
 	}
 	return t, nil
@@ -1348,28 +2662,906 @@ func (p *showPlan) Execute(ctx context.Context) (*table.Table, error) {
 
 // String returns a readable description of the execution plan.
 func (p *showPlan) String(ctx context.Context) string {
-	return fmt.Sprintf("SHOW plan:\n\nstore(%q).GraphNames(_, _)", p.store.Name(ctx))
+	return fmt.Sprintf("SHOW plan:\n\nstore(%q).GraphNamesSorted(_, _)", p.store.Name(ctx))
 }
 
-// New create a new executable plan given a semantic BQL statement.
-func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer) (Executor, error) {
-	switch stm.Type() {
-	case semantic.Query:
-		return newQueryPlan(ctx, store, stm, chanSize, w)
-	case semantic.Insert:
-		return &insertPlan{
-			stm:    stm,
-			store:  store,
-			tracer: w,
-		}, nil
-	case semantic.Delete:
-		return &deletePlan{
-			stm:    stm,
-			store:  store,
-			tracer: w,
-		}, nil
-	case semantic.Create:
-		return &createPlan{
+// clearPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid clear BQL statement.
+type clearPlan struct {
+	stm     *semantic.Statement
+	store   storage.Store
+	clearer storage.Clearer
+	tracer  io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *clearPlan) Type() string {
+	return "CLEAR"
+}
+
+// Execute the clear statement.
+func (p *clearPlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{"Clearing all the graphs in the store"},
+		}
+	})
+	if err := p.clearer.Clear(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *clearPlan) String(ctx context.Context) string {
+	return fmt.Sprintf("CLEAR plan:\n\nstore(%q).Clear(_)", p.store.Name(ctx))
+}
+
+// killPlan cancels a query tracked by a registry.
+type killPlan struct {
+	stm      *semantic.Statement
+	registry *Registry
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *killPlan) Type() string {
+	return "KILL"
+}
+
+// Execute the kill statement.
+func (p *killPlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	id := p.stm.KillQueryID()
+	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{fmt.Sprintf("Killing query %q", id)},
+		}
+	})
+	if err := p.registry.Kill(id); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *killPlan) String(ctx context.Context) string {
+	return fmt.Sprintf("KILL plan:\n\nregistry.Kill(%q)", p.stm.KillQueryID())
+}
+
+// materializedView is the value kept in the store metadata for each view. It
+// holds the statement defining the view and the table it last produced.
+type materializedView struct {
+	stm *semantic.Statement
+	tbl *table.Table
+}
+
+// viewKey returns the metadata key used to store the provided view.
+func viewKey(name string) string {
+	return "view:" + name
+}
+
+// viewPlan encapsulates the sequence of instructions that need to be executed
+// in order to satisfy the execution of a valid materialized view statement.
+// Views are not maintained automatically; their results are only recomputed
+// by REFRESH VIEW.
+type viewPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	meta     storage.MetadataStore
+	chanSize int
+	opts     *Options
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *viewPlan) Type() string {
+	return p.stm.Type().String()
+}
+
+// view returns the materialized view stored under the name of the statement.
+func (p *viewPlan) view(ctx context.Context) (*materializedView, error) {
+	v, err := p.meta.Metadata(ctx, viewKey(p.stm.ViewName()))
+	if err != nil {
+		if errors.Is(err, storage.ErrMetadataNotFound) {
+			return nil, fmt.Errorf("view %q does not exist", p.stm.ViewName())
+		}
+		return nil, err
+	}
+	mv, ok := v.(*materializedView)
+	if !ok {
+		return nil, fmt.Errorf("metadata %q does not hold a view; got %T instead", viewKey(p.stm.ViewName()), v)
+	}
+	return mv, nil
+}
+
+// materialize runs the provided query statement and stores its result as the
+// content of the view.
+func (p *viewPlan) materialize(ctx context.Context, stm *semantic.Statement) error {
+	qp, err := newQueryPlan(ctx, p.store, stm, p.chanSize, p.tracer)
+	if err != nil {
+		return err
+	}
+	p.opts.apply(qp)
+	tbl, err := qp.Execute(ctx)
+	if err != nil {
+		return err
+	}
+	return p.meta.SetMetadata(ctx, viewKey(p.stm.ViewName()), &materializedView{
+		stm: stm,
+		tbl: tbl,
+	})
+}
+
+// Execute the view statement.
+func (p *viewPlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{})
+	if err != nil {
+		return nil, err
+	}
+	name := p.stm.ViewName()
+	tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+		return &tracer.Arguments{
+			Msgs: []string{fmt.Sprintf("Executing %s on view %q", p.stm.Type(), name)},
+		}
+	})
+	switch p.stm.Type() {
+	case semantic.CreateView:
+		if _, err := p.view(ctx); err == nil {
+			return nil, fmt.Errorf("view %q already exists", name)
+		}
+		if err := p.materialize(ctx, p.stm); err != nil {
+			return nil, err
+		}
+	case semantic.RefreshView:
+		mv, err := p.view(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.materialize(ctx, mv.stm); err != nil {
+			return nil, err
+		}
+	case semantic.DropView:
+		if _, err := p.view(ctx); err != nil {
+			return nil, err
+		}
+		if err := p.meta.DeleteMetadata(ctx, viewKey(name)); err != nil {
+			return nil, err
+		}
+	case semantic.ShowView:
+		mv, err := p.view(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Return a copy so callers can not alter the materialized rows.
+		t, err = table.New(mv.tbl.Bindings())
+		if err != nil {
+			return nil, err
+		}
+		if err := t.AppendTable(mv.tbl); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported view statement type %s", p.stm.Type())
+	}
+	return t, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *viewPlan) String(ctx context.Context) string {
+	return fmt.Sprintf("%s plan:\n\nstore(%q).Metadata(%q)", p.stm.Type(), p.store.Name(ctx), viewKey(p.stm.ViewName()))
+}
+
+// dumpPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid dump BQL statement.
+type dumpPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	bulkSize int
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *dumpPlan) Type() string {
+	return "DUMP"
+}
+
+// Execute returns a table with one row per BQL insert statement required to
+// recreate the dumped graphs. Each statement contains at most bulkSize
+// triples.
+func (p *dumpPlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{"?statement"})
+	if err != nil {
+		return nil, err
+	}
+	for _, gName := range p.stm.GraphNames() {
+		g, err := p.store.Graph(ctx, gName)
+		if err != nil {
+			return nil, err
+		}
+		gNameCopy := gName // creating a local copy of the loop variable to not pass it by reference to the closure of the lazy tracer.
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("Dumping graph %q", gNameCopy)},
+			}
+		})
+		errs := make(chan error, 1)
+		trpls := make(chan *triple.Triple, p.chanSize)
+		go func() {
+			errs <- g.Triples(ctx, storage.DefaultLookup, trpls)
+		}()
+		var (
+			ts   []*triple.Triple
+			dErr error
+		)
+		for trpl := range trpls {
+			if dErr != nil {
+				// Drain the channel to avoid leaking goroutines.
+				continue
+			}
+			ts = append(ts, trpl)
+			if p.bulkSize > 0 && len(ts) >= p.bulkSize {
+				var r table.Row
+				if r, dErr = insertStatementRow(gName, ts); dErr == nil {
+					t.AddRow(r)
+				}
+				ts = nil
+			}
+		}
+		if err := <-errs; err != nil {
+			return nil, err
+		}
+		if dErr != nil {
+			return nil, dErr
+		}
+		if len(ts) > 0 {
+			r, err := insertStatementRow(gName, ts)
+			if err != nil {
+				return nil, err
+			}
+			t.AddRow(r)
+		}
+	}
+	return t, nil
+}
+
+// insertStatementRow returns a row containing the BQL insert statement that
+// adds the provided triples to the graph. It fails if any of the literals
+// cannot be written in a way that parses back to the same value.
+func insertStatementRow(gName string, ts []*triple.Triple) (table.Row, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "INSERT DATA INTO %s {\n", gName)
+	for i, trpl := range ts {
+		if l, err := trpl.Object().Literal(); err == nil {
+			if err := checkDumpableLiteral(l); err != nil {
+				return nil, fmt.Errorf("cannot dump triple %s of graph %s: %v", trpl, gName, err)
+			}
+		}
+		fmt.Fprintf(&b, "  %s %s %s", trpl.Subject(), trpl.Predicate(), trpl.Object())
+		if i < len(ts)-1 {
+			b.WriteString(" .")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("};")
+	stm := b.String()
+	return table.Row{
+		"?statement": table.NewStringCell(stm),
+	}, nil
+}
+
+// checkDumpableLiteral returns an error if the text representation of the
+// literal does not lex as a single literal that parses back to the same value.
+// BQL literals are not unescaped, so text values holding an unescaped double
+// quote, for instance, cannot be represented.
+func checkDumpableLiteral(l *literal.Literal) error {
+	s := l.String()
+	var tkns []lexer.Token
+	for tkn := range lexer.New(s, 0) {
+		tkns = append(tkns, tkn)
+	}
+	if len(tkns) != 2 || tkns[0].Type != lexer.ItemLiteral || tkns[0].Text != s {
+		return fmt.Errorf("literal %s cannot be represented in BQL", s)
+	}
+	pl, err := literal.DefaultBuilder().Parse(tkns[0].Text)
+	if err != nil || pl.String() != s {
+		return fmt.Errorf("literal %s cannot be represented in BQL", s)
+	}
+	return nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *dumpPlan) String(ctx context.Context) string {
+	b := bytes.NewBufferString("DUMP plan:\n\n")
+	for _, gName := range p.stm.GraphNames() {
+		fmt.Fprintf(b, "store(%q).Graph(%q).Triples(_, _) in batches of %d triples\n", p.store.Name(ctx), gName, p.bulkSize)
+	}
+	return b.String()
+}
+
+// mergePlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid merge BQL statement.
+type mergePlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	bulkSize int
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *mergePlan) Type() string {
+	return "MERGE"
+}
+
+// Execute merges each source graph into each destination graph, in the order
+// they were provided. Source triples whose subject and predicate already have
+// a different object in the destination graph are not inserted and are
+// reported instead, one row per conflicting object, using the ?s, ?p, ?src_o,
+// and ?dest_o bindings. When replacing conflicts, the source triples replace
+// instead the destination triples with the same subject and predicate ID, and
+// a row is reported for each destination triple replaced.
+func (p *mergePlan) Execute(ctx context.Context) (*table.Table, error) {
+	t, err := table.New([]string{"?s", "?p", "?src_o", "?dest_o"})
+	if err != nil {
+		return nil, err
+	}
+	for _, dName := range p.stm.OutputGraphNames() {
+		dst, err := p.store.Graph(ctx, dName)
+		if err != nil {
+			return nil, err
+		}
+		for _, sName := range p.stm.InputGraphNames() {
+			src, err := p.store.Graph(ctx, sName)
+			if err != nil {
+				return nil, err
+			}
+			sNameCopy, dNameCopy := sName, dName // creating local copies of the loop variables to not pass them by reference to the closure of the lazy tracer.
+			tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+				return &tracer.Arguments{
+					Msgs: []string{fmt.Sprintf("Merging graph %q into graph %q", sNameCopy, dNameCopy)},
+				}
+			})
+			merge := p.merge
+			if p.stm.ReplaceConflicts() {
+				merge = p.replace
+			}
+			if err := merge(ctx, src, dst, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// merge inserts into dst the triples of src that do not conflict with the
+// ones already in dst, and adds a row to t for each conflict found. Conflicts
+// are checked against the contents of dst before any triple is inserted, so
+// multivalued predicates in src are not reported as conflicting with
+// themselves.
+func (p *mergePlan) merge(ctx context.Context, src, dst storage.Graph, t *table.Table) error {
+	errs := make(chan error, 1)
+	trpls := make(chan *triple.Triple, p.chanSize)
+	go func() {
+		errs <- src.Triples(ctx, storage.DefaultLookup, trpls)
+	}()
+	var (
+		ts  []*triple.Triple
+		err error
+	)
+	// prds caches the UUIDs of the predicates each subject has in dst.
+	prds := make(map[string]map[string]bool)
+	for trpl := range trpls {
+		if err != nil {
+			continue
+		}
+		var os []*triple.Object
+		os, err = p.destinationObjects(ctx, dst, trpl, prds)
+		if err != nil {
+			continue
+		}
+		if len(os) == 0 {
+			ts = append(ts, trpl)
+			continue
+		}
+		err = addConflicts(t, trpl, os)
+	}
+	if serr := <-errs; serr != nil {
+		return serr
+	}
+	if err != nil {
+		return err
+	}
+	for len(ts) > 0 {
+		n := len(ts)
+		if p.bulkSize > 0 && n > p.bulkSize {
+			n = p.bulkSize
+		}
+		if err := dst.AddTriples(ctx, ts[:n]); err != nil {
+			return err
+		}
+		ts = ts[n:]
+	}
+	return nil
+}
+
+// replace inserts into dst the triples of src, replacing the triples of dst
+// with the same subject and predicate ID, regardless of their time anchors.
+// Source triples sharing subject and predicate ID are all kept, since the
+// replacement only applies to the triples already in dst. A row is added to
+// t for each triple of dst replaced.
+func (p *mergePlan) replace(ctx context.Context, src, dst storage.Graph, t *table.Table) error {
+	errs := make(chan error, 1)
+	trpls := make(chan *triple.Triple, p.chanSize)
+	go func() {
+		errs <- src.Triples(ctx, storage.DefaultLookup, trpls)
+	}()
+	var keys []string
+	groups := make(map[string][]*triple.Triple)
+	for trpl := range trpls {
+		k := trpl.Subject().UUID().String() + string(trpl.Predicate().ID())
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], trpl)
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	var ts []*triple.Triple
+	for _, k := range keys {
+		g := groups[k]
+		removed, err := storage.ReplaceObject(ctx, dst, g[0].Subject(), g[0].Predicate(), g[0].Object())
+		if err != nil {
+			return err
+		}
+		kept := make(map[string]bool)
+		for _, trpl := range g {
+			kept[trpl.UUID().String()] = true
+		}
+		for _, rt := range removed {
+			if kept[rt.UUID().String()] {
+				continue
+			}
+			if err := addReplaced(t, g[0], rt); err != nil {
+				return err
+			}
+		}
+		ts = append(ts, g[1:]...)
+	}
+	for len(ts) > 0 {
+		n := len(ts)
+		if p.bulkSize > 0 && n > p.bulkSize {
+			n = p.bulkSize
+		}
+		if err := dst.AddTriples(ctx, ts[:n]); err != nil {
+			return err
+		}
+		ts = ts[n:]
+	}
+	return nil
+}
+
+// addReplaced adds to t a row for the destination triple replaced by the
+// provided source triple. The predicate reported is the one of the replaced
+// triple, since their time anchors may differ.
+func addReplaced(t *table.Table, src, dst *triple.Triple) error {
+	srcO, err := objectToCell(src.Object())
+	if err != nil {
+		return err
+	}
+	dstO, err := objectToCell(dst.Object())
+	if err != nil {
+		return err
+	}
+	t.AddRow(table.Row{
+		"?s":      table.NewNodeCell(dst.Subject()),
+		"?p":      table.NewPredicateCell(dst.Predicate()),
+		"?src_o":  srcO,
+		"?dest_o": dstO,
+	})
+	return nil
+}
+
+// destinationObjects returns the objects that dst holds for the subject and
+// predicate of the provided triple, if they are all different from its
+// object. If dst already contains the triple, no objects are returned.
+func (p *mergePlan) destinationObjects(ctx context.Context, dst storage.Graph, trpl *triple.Triple, prds map[string]map[string]bool) ([]*triple.Object, error) {
+	s, prd := trpl.Subject(), trpl.Predicate()
+	sID := s.UUID().String()
+	ps, ok := prds[sID]
+	if !ok {
+		ps = make(map[string]bool)
+		pc := make(chan *predicate.Predicate, p.chanSize)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- dst.PredicatesForSubject(ctx, s, storage.DefaultLookup, pc)
+		}()
+		for dp := range pc {
+			ps[dp.UUID().String()] = true
+		}
+		if err := <-errs; err != nil {
+			return nil, err
+		}
+		prds[sID] = ps
+	}
+	if !ps[prd.UUID().String()] {
+		return nil, nil
+	}
+	tc := make(chan *triple.Triple, p.chanSize)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- dst.TriplesForSubjectAndPredicate(ctx, s, prd, storage.DefaultLookup, tc)
+	}()
+	var (
+		os     []*triple.Object
+		exists bool
+	)
+	oID := trpl.Object().UUID()
+	for dt := range tc {
+		if uuid.Equal(dt.Object().UUID(), oID) {
+			exists = true
+			continue
+		}
+		os = append(os, dt.Object())
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, nil
+	}
+	return os, nil
+}
+
+// addConflicts adds to t one row for each object in os conflicting with the
+// object of the provided triple.
+func addConflicts(t *table.Table, trpl *triple.Triple, os []*triple.Object) error {
+	srcO, err := objectToCell(trpl.Object())
+	if err != nil {
+		return err
+	}
+	for _, o := range os {
+		dstO, err := objectToCell(o)
+		if err != nil {
+			return err
+		}
+		t.AddRow(table.Row{
+			"?s":      table.NewNodeCell(trpl.Subject()),
+			"?p":      table.NewPredicateCell(trpl.Predicate()),
+			"?src_o":  srcO,
+			"?dest_o": dstO,
+		})
+	}
+	return nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *mergePlan) String(ctx context.Context) string {
+	b := bytes.NewBufferString("MERGE plan:\n\n")
+	for _, dName := range p.stm.OutputGraphNames() {
+		for _, sName := range p.stm.InputGraphNames() {
+			if p.stm.ReplaceConflicts() {
+				fmt.Fprintf(b, "store(%q).Graph(%q).Triples(_, _), replacing the objects of each subject and predicate ID with store(%q).Graph(%q).ReplaceObject(_, _, _) and inserting the rest in batches of %d triples\n", p.store.Name(ctx), sName, p.store.Name(ctx), dName, p.bulkSize)
+				continue
+			}
+			fmt.Fprintf(b, "store(%q).Graph(%q).Triples(_, _), checking conflicts with store(%q).Graph(%q).TriplesForSubjectAndPredicate(_, _) and inserting the rest in batches of %d triples\n", p.store.Name(ctx), sName, p.store.Name(ctx), dName, p.bulkSize)
+		}
+	}
+	return b.String()
+}
+
+// anchorsPlan encapsulates the sequence of instructions that need to be
+// executed in order to satisfy the execution of a valid anchors BQL statement.
+type anchorsPlan struct {
+	stm      *semantic.Statement
+	store    storage.Store
+	chanSize int
+	tracer   io.Writer
+}
+
+// Type returns the type of plan used by the executor.
+func (p *anchorsPlan) Type() string {
+	return "ANCHORS"
+}
+
+// Execute returns one row per distinct time anchor of the requested subject
+// and predicate ID across all the input graphs, in ascending order.
+func (p *anchorsPlan) Execute(ctx context.Context) (*table.Table, error) {
+	s, pid, b := p.stm.AnchorsLookup()
+	t, err := table.New([]string{b})
+	if err != nil {
+		return nil, err
+	}
+	var tas []*time.Time
+	seen := make(map[int64]bool)
+	for _, gName := range p.stm.InputGraphNames() {
+		g, err := p.store.Graph(ctx, gName)
+		if err != nil {
+			return nil, err
+		}
+		gNameCopy := gName // creating a local copy of the loop variable to not pass it by reference to the closure of the lazy tracer.
+		tracer.V(2).Trace(p.tracer, func() *tracer.Arguments {
+			return &tracer.Arguments{
+				Msgs: []string{fmt.Sprintf("g.PredicateAnchors(%v, %q), graph: %s", s, pid, gNameCopy)},
+			}
+		})
+		ch := make(chan *time.Time, p.chanSize)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- storage.PredicateAnchors(ctx, g, s, pid, storage.DefaultLookup, ch)
+		}()
+		for ta := range ch {
+			if !seen[ta.UnixNano()] {
+				seen[ta.UnixNano()] = true
+				tas = append(tas, ta)
+			}
+		}
+		if err := <-errc; err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(tas, func(i, j int) bool {
+		return tas[i].Before(*tas[j])
+	})
+	for _, ta := range tas {
+		t.AddRow(table.Row{b: table.NewTimeCell(*ta)})
+	}
+	return t, nil
+}
+
+// String returns a readable description of the execution plan.
+func (p *anchorsPlan) String(ctx context.Context) string {
+	s, pid, _ := p.stm.AnchorsLookup()
+	b := bytes.NewBufferString("ANCHORS plan:\n\n")
+	for _, gName := range p.stm.InputGraphNames() {
+		fmt.Fprintf(b, "store(%q).Graph(%q).PredicateAnchors(%v, %q)\n", p.store.Name(ctx), gName, s, pid)
+	}
+	return b.String()
+}
+
+// New create a new executable plan given a semantic BQL statement.
+func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer) (Executor, error) {
+	return NewWithCache(ctx, store, stm, chanSize, bulkSize, w, nil)
+}
+
+// NewWithCache works like New, but the results of query statements are
+// served from the provided cache when none of the queried graphs changed
+// since they were cached. A nil cache disables caching.
+func NewWithCache(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer, c *ResultCache) (Executor, error) {
+	return NewWithOptions(ctx, store, stm, chanSize, bulkSize, w, &Options{Cache: c})
+}
+
+// Options contains the optional settings used when creating a new plan.
+type Options struct {
+	// Cache, if not nil, is used to serve the results of query statements
+	// when none of the queried graphs changed since they were cached.
+	Cache *ResultCache
+
+	// Parallelism is the maximum number of rows of an intermediate table
+	// processed concurrently when specifying clauses with the rows already
+	// bound. If not positive, it defaults to GOMAXPROCS.
+	Parallelism int
+
+	// GraphConcurrency is the maximum number of graphs INSERT, DELETE,
+	// CONSTRUCT and DECONSTRUCT statements update simultaneously. If not
+	// positive, it defaults to GOMAXPROCS. Queries read their input graphs
+	// one at a time.
+	GraphConcurrency int
+
+	// AllowAdmin enables administrative statements, such as CLEAR STORE,
+	// that affect the whole store. They are rejected otherwise.
+	AllowAdmin bool
+
+	// NumericComparison sets how HAVING compares numbers of different types.
+	// It defaults to semantic.StrictNumericComparison.
+	NumericComparison semantic.NumericComparison
+
+	// CrossProducts sets how statements whose graph pattern contains groups
+	// of clauses sharing no bindings are handled, since they compute the
+	// cartesian product of the results of each group. It defaults to
+	// AllowCrossProducts.
+	CrossProducts CrossProductPolicy
+
+	// OmitAffectedCount makes INSERT and DELETE statements return a table
+	// without bindings, as they used to, instead of a single row reporting the
+	// number of triples affected under AffectedBinding.
+	OmitAffectedCount bool
+
+	// DeterministicBlankNodes makes CONSTRUCT statements derive the ID of the
+	// blank nodes used to reify their clauses from the clause and the matched
+	// row, instead of generating a new random ID for each one.
+	DeterministicBlankNodes bool
+
+	// EmptyHistogramBuckets makes HISTOGRAM emit the buckets without rows
+	// between the first and the last bucket of each group with a zero count.
+	// They are omitted otherwise.
+	EmptyHistogramBuckets bool
+
+	// Registry, if not nil, tracks the execution of the created plans so
+	// they can be listed and cancelled. KILL statements require it.
+	Registry *Registry
+
+	// Provenance, if not nil, records the index of the graph pattern clause
+	// that produced each cell bound by query statements, and traces it at
+	// verbosity level 3. It is meant for debugging joins. Cells created after
+	// the graph pattern is processed, such as aggregations, and results
+	// served from the cache have no provenance.
+	Provenance *table.Provenance
+}
+
+// CrossProductPolicy sets how the planner handles graph patterns whose
+// clauses compute a cartesian product.
+type CrossProductPolicy int
+
+const (
+	// AllowCrossProducts computes cartesian products silently.
+	AllowCrossProducts CrossProductPolicy = iota
+	// WarnCrossProducts computes cartesian products, but traces a warning
+	// naming the disconnected groups of clauses.
+	WarnCrossProducts
+	// ErrorCrossProducts rejects the statements computing cartesian products
+	// before executing them.
+	ErrorCrossProducts
+)
+
+// String returns a readable name of the policy.
+func (c CrossProductPolicy) String() string {
+	switch c {
+	case AllowCrossProducts:
+		return "allow"
+	case WarnCrossProducts:
+		return "warn"
+	case ErrorCrossProducts:
+		return "error"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// crossProductPolicy returns the cross product policy set by the options.
+func (o *Options) crossProductPolicy() CrossProductPolicy {
+	if o == nil {
+		return AllowCrossProducts
+	}
+	return o.CrossProducts
+}
+
+// describeCrossProducts returns a description of the groups of clauses of the
+// graph pattern that share no bindings, or an empty string if there are not
+// at least two of them.
+func describeCrossProducts(stm *semantic.Statement) string {
+	groups := stm.DisconnectedClauseGroups()
+	if len(groups) < 2 {
+		return ""
+	}
+	cls := stm.GraphPatternClauses()
+	var ds []string
+	for _, g := range groups {
+		var (
+			ns []string
+			bm = make(map[string]bool)
+		)
+		for _, i := range g {
+			ns = append(ns, strconv.Itoa(i+1))
+			for b := range cls[i].BindingsMap() {
+				if b != semantic.GraphBinding {
+					bm[b] = true
+				}
+			}
+		}
+		var bs []string
+		for b := range bm {
+			bs = append(bs, b)
+		}
+		sort.Strings(bs)
+		ds = append(ds, fmt.Sprintf("clauses %s with bindings %s", strings.Join(ns, ", "), strings.Join(bs, ", ")))
+	}
+	return fmt.Sprintf("the graph pattern has %d groups of clauses sharing no bindings, which would produce a cartesian product: {%s}", len(groups), strings.Join(ds, "} and {"))
+}
+
+// graphConcurrency returns the maximum number of graphs updated
+// simultaneously set by the options, or zero to use the default.
+func (o *Options) graphConcurrency() int {
+	if o == nil {
+		return 0
+	}
+	return o.GraphConcurrency
+}
+
+// apply sets the provided options on the query plan.
+func (o *Options) apply(qp *queryPlan) {
+	if o == nil {
+		return
+	}
+	if o.Parallelism > 0 {
+		qp.parallelism = o.Parallelism
+	}
+	qp.numbers = o.NumericComparison
+	qp.emptyBuckets = o.EmptyHistogramBuckets
+	qp.provenance = o.Provenance
+}
+
+// NewWithOptions works like New, but allows to customize the plan using the
+// provided options. A nil value uses the default options.
+func NewWithOptions(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer, opts *Options) (Executor, error) {
+	e, err := newPlan(ctx, store, stm, chanSize, bulkSize, w, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.Registry != nil && stm.Type() != semantic.Kill {
+		return &registeredPlan{
+			Executor: e,
+			registry: opts.Registry,
+		}, nil
+	}
+	return e, nil
+}
+
+// newPlan creates the plan for the provided statement.
+func newPlan(ctx context.Context, store storage.Store, stm *semantic.Statement, chanSize, bulkSize int, w io.Writer, opts *Options) (Executor, error) {
+	if lvl := stm.TraceLevel(); lvl > 0 && w != nil {
+		w = tracer.WithVerbosity(w, lvl)
+	}
+	switch stm.Type() {
+	case semantic.Query, semantic.Construct, semantic.Deconstruct:
+		switch opts.crossProductPolicy() {
+		case WarnCrossProducts:
+			if d := describeCrossProducts(stm); d != "" {
+				tracer.V(1).Trace(w, func() *tracer.Arguments {
+					return &tracer.Arguments{
+						Msgs: []string{"planner.New: warning: " + d},
+					}
+				})
+			}
+		case ErrorCrossProducts:
+			if d := describeCrossProducts(stm); d != "" {
+				return nil, fmt.Errorf("planner.New: %s", d)
+			}
+		}
+	}
+	switch stm.Type() {
+	case semantic.Query:
+		qp, err := newQueryPlan(ctx, store, stm, chanSize, w)
+		if err != nil {
+			return nil, err
+		}
+		opts.apply(qp)
+		if opts != nil {
+			qp.cache = opts.Cache
+		}
+		return qp, nil
+	case semantic.Insert:
+		return &insertPlan{
+			stm:         stm,
+			store:       store,
+			tracer:      w,
+			omitCount:   opts != nil && opts.OmitAffectedCount,
+			concurrency: opts.graphConcurrency(),
+		}, nil
+	case semantic.Delete:
+		if stm.HasNowData() {
+			return nil, errors.New("planner.New: NOW() time anchors are only supported when inserting data")
+		}
+		return &deletePlan{
+			stm:         stm,
+			store:       store,
+			chanSize:    chanSize,
+			bulkSize:    bulkSize,
+			tracer:      w,
+			omitCount:   opts != nil && opts.OmitAffectedCount,
+			concurrency: opts.graphConcurrency(),
+		}, nil
+	case semantic.Create:
+		return &createPlan{
 			stm:    stm,
 			store:  store,
 			tracer: w,
@@ -1380,25 +3572,37 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 			store:  store,
 			tracer: w,
 		}, nil
+	case semantic.Truncate:
+		return &truncatePlan{
+			stm:    stm,
+			store:  store,
+			tracer: w,
+		}, nil
 	case semantic.Construct:
 		qp, _ := newQueryPlan(ctx, store, stm, chanSize, w)
+		opts.apply(qp)
 		return &constructPlan{
-			stm:       stm,
-			store:     store,
-			tracer:    w,
-			bulkSize:  bulkSize,
-			queryPlan: qp,
-			construct: true,
+			stm:           stm,
+			store:         store,
+			tracer:        w,
+			bulkSize:      bulkSize,
+			queryPlan:     qp,
+			construct:     true,
+			deterministic: opts != nil && opts.DeterministicBlankNodes,
+			concurrency:   opts.graphConcurrency(),
 		}, nil
 	case semantic.Deconstruct:
 		qp, _ := newQueryPlan(ctx, store, stm, chanSize, w)
+		opts.apply(qp)
 		return &constructPlan{
-			stm:       stm,
-			store:     store,
-			tracer:    w,
-			bulkSize:  bulkSize,
-			queryPlan: qp,
-			construct: false,
+			stm:           stm,
+			store:         store,
+			tracer:        w,
+			bulkSize:      bulkSize,
+			queryPlan:     qp,
+			construct:     false,
+			deterministic: opts != nil && opts.DeterministicBlankNodes,
+			concurrency:   opts.graphConcurrency(),
 		}, nil
 	case semantic.Show:
 		return &showPlan{
@@ -1406,6 +3610,65 @@ func New(ctx context.Context, store storage.Store, stm *semantic.Statement, chan
 			store:  store,
 			tracer: w,
 		}, nil
+	case semantic.Dump:
+		return &dumpPlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			bulkSize: bulkSize,
+			tracer:   w,
+		}, nil
+	case semantic.Anchors:
+		return &anchorsPlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			tracer:   w,
+		}, nil
+	case semantic.Merge:
+		return &mergePlan{
+			stm:      stm,
+			store:    store,
+			chanSize: chanSize,
+			bulkSize: bulkSize,
+			tracer:   w,
+		}, nil
+	case semantic.Clear:
+		if opts == nil || !opts.AllowAdmin {
+			return nil, fmt.Errorf("planner.New: %s statements require administrative statements to be allowed", stm.Type())
+		}
+		c, ok := store.(storage.Clearer)
+		if !ok {
+			return nil, fmt.Errorf("planner.New: store %q does not support clearing all its graphs", store.Name(ctx))
+		}
+		return &clearPlan{
+			stm:     stm,
+			store:   store,
+			clearer: c,
+			tracer:  w,
+		}, nil
+	case semantic.Kill:
+		if opts == nil || opts.Registry == nil {
+			return nil, fmt.Errorf("planner.New: %s statements require a registry of running queries", stm.Type())
+		}
+		return &killPlan{
+			stm:      stm,
+			registry: opts.Registry,
+			tracer:   w,
+		}, nil
+	case semantic.CreateView, semantic.RefreshView, semantic.DropView, semantic.ShowView:
+		m, ok := store.(storage.MetadataStore)
+		if !ok {
+			return nil, fmt.Errorf("planner.New: store %q does not support materialized views", store.Name(ctx))
+		}
+		return &viewPlan{
+			stm:      stm,
+			store:    store,
+			meta:     m,
+			chanSize: chanSize,
+			opts:     opts,
+			tracer:   w,
+		}, nil
 	default:
 		return nil, fmt.Errorf("planner.New: unknown statement type in statement %v", stm)
 	}
@@ -17,17 +17,27 @@ package planner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/badwolf/bql/grammar"
 	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/io"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/storage/memory"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 const (
@@ -190,6 +200,407 @@ func TestPlannerInsertDeleteDoesNotFail(t *testing.T) {
 	}
 }
 
+func TestPlannerInsertCapacityExceeded(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStoreWithLimits(3)
+	for _, g := range []string{"?a", "?b"} {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("s.NewGraph(%q) failed with error %v", g, err)
+		}
+	}
+	testTable := []struct {
+		q       string
+		wantErr bool
+	}{
+		{q: `insert data into ?a {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<peter>};`},
+		{q: `insert data into ?b {/u<joe> "knows"@[] /u<mary>};`},
+		{q: `insert data into ?b {/u<joe> "knows"@[] /u<mary>};`},
+		{q: `insert data into ?b {/u<joe> "knows"@[] /u<peter>};`, wantErr: true},
+		{q: `insert data into ?a, ?b if absent {/u<joe> "knows"@[] /u<john>};`, wantErr: true},
+	}
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if got := errors.Is(err, storage.ErrCapacityExceeded); got != entry.wantErr {
+			t.Errorf("planner.Execute(%s) returned error %v; want capacity exceeded %v", entry.q, err, entry.wantErr)
+		}
+	}
+}
+
+func TestPlannerGraphConcurrency(t *testing.T) {
+	const (
+		maxGraphs = 3
+		nGraphs   = 40
+	)
+	ctx, s := context.Background(), memory.NewStore()
+	var gs []string
+	for i := 0; i < nGraphs; i++ {
+		g := fmt.Sprintf("?g%d", i)
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("s.NewGraph(%q) failed with error %v", g, err)
+		}
+		gs = append(gs, g)
+	}
+
+	// Track how many graphs are updated at the same time.
+	var running, maxRunning int64
+	f := func(g storage.Graph, ts []*triple.Triple) error {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			m := atomic.LoadInt64(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt64(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return g.AddTriples(ctx, ts)
+	}
+	var ts []*triple.Triple
+	for _, l := range []string{`/u<joe>	"knows"@[]	/u<mary>`, `/u<joe>	"knows"@[]	/u<peter>`} {
+		trpl, err := triple.Parse(l, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", l, err)
+		}
+		ts = append(ts, trpl)
+	}
+	if err := update(ctx, ts[:1], gs, s, maxGraphs, f); err != nil {
+		t.Fatalf("update failed with error %v", err)
+	}
+	if got := atomic.LoadInt64(&maxRunning); got > maxGraphs {
+		t.Errorf("update updated %d graphs at the same time; want at most %d", got, maxGraphs)
+	}
+
+	// All the graphs are still updated under the cap.
+	q := fmt.Sprintf(`insert data into %s {/u<joe> "knows"@[] /u<peter>};`, strings.Join(gs, ", "))
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{GraphConcurrency: maxGraphs})
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error: %v", err)
+	}
+	if got := plnr.(*insertPlan).concurrency; got != maxGraphs {
+		t.Errorf("planner.NewWithOptions set the graph concurrency to %d; want %d", got, maxGraphs)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+	}
+	if got, want := tbl.Rows()[0][AffectedBinding].String(), fmt.Sprintf(`"%d"^^type:int64`, nGraphs); got != want {
+		t.Errorf("planner.Execute(%s) affected %s triples; want %s", q, got, want)
+	}
+	for _, g := range gs {
+		sg, err := s.Graph(ctx, g)
+		if err != nil {
+			t.Fatalf("s.Graph(%q) failed with error %v", g, err)
+		}
+		for _, trpl := range ts {
+			if ok, err := sg.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("graph %s is missing triple %s after the update; error %v", g, trpl, err)
+			}
+		}
+	}
+}
+
+func TestPlannerAffectedCount(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?a"); err != nil {
+		t.Fatalf("s.NewGraph(%q) failed with error %v", "?a", err)
+	}
+	if _, err := s.NewGraph(ctx, "?b"); err != nil {
+		t.Fatalf("s.NewGraph(%q) failed with error %v", "?b", err)
+	}
+	testTable := []struct {
+		q    string
+		opts *Options
+		want int64
+	}{
+		{
+			q:    `insert data into ?a, ?b {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<peter>};`,
+			want: 4,
+		},
+		{
+			q:    `delete data from ?a {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<john>};`,
+			want: 1,
+		},
+		{
+			q:    `delete data from ?a, ?b {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<peter>};`,
+			want: 3,
+		},
+		{
+			q:    `delete data from ?a {/u<joe> "knows"@[] /u<peter>};`,
+			want: 0,
+		},
+		{
+			q:    `insert data into ?a {/u<joe> "knows"@[] /u<mary>};`,
+			opts: &Options{OmitAffectedCount: true},
+			want: -1,
+		},
+		{
+			q:    `insert data into ?a, ?b if absent {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<john> . /u<joe> "knows"@[] /u<john>};`,
+			want: 3,
+		},
+		{
+			q:    `insert data into ?a, ?b if absent {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<john>};`,
+			want: 0,
+		},
+		{
+			// Re-inserted triples are not counted.
+			q:    `insert data into ?a, ?b {/u<joe> "knows"@[] /u<mary> . /u<joe> "knows"@[] /u<zoe> . /u<joe> "knows"@[] /u<zoe>};`,
+			want: 2,
+		},
+	}
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error %v", entry.q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, entry.opts)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		if entry.want < 0 {
+			if len(tbl.Bindings()) != 0 || tbl.NumRows() != 0 {
+				t.Errorf("planner.Execute(%q) returned %v; want an empty table", entry.q, tbl)
+			}
+			continue
+		}
+		if got, want := tbl.Bindings(), []string{AffectedBinding}; !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute(%q) returned bindings %v; want %v", entry.q, got, want)
+		}
+		r, ok := tbl.Row(0)
+		if !ok || tbl.NumRows() != 1 {
+			t.Fatalf("planner.Execute(%q) returned %d rows; want 1", entry.q, tbl.NumRows())
+		}
+		got, err := r[AffectedBinding].L.Int64()
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) returned a non integer count %v", entry.q, r[AffectedBinding])
+		}
+		if got != entry.want {
+			t.Errorf("planner.Execute(%q) affected %d triples; want %d", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerInsertLenientData(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?a"); err != nil {
+		t.Fatalf("s.NewGraph(%q) failed with error %v", "?a", err)
+	}
+	const q = `insert data into ?a {/u<joe> "knows"@[] /u<mary> . /u<joe> "height_cm"@[] "tall"^^type:int64 . /u<joe> "knows"@[] /u<peter>};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+
+	// By default the malformed triple rejects the whole statement.
+	if err := p.Parse(grammar.NewLLk(q, 1), &semantic.Statement{}); err == nil {
+		t.Errorf("parser.Parse(%q) should have failed for the malformed triple", q)
+	}
+
+	st := &semantic.Statement{}
+	st.SetLenientData()
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for lenient query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+	}
+	if got, want := tbl.Bindings(), []string{AffectedBinding, SkippedBinding, SkippedErrorsBinding}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute(%q) returned bindings %v; want %v", q, got, want)
+	}
+	r, ok := tbl.Row(0)
+	if !ok || tbl.NumRows() != 1 {
+		t.Fatalf("planner.Execute(%q) returned %d rows; want 1", q, tbl.NumRows())
+	}
+	if got, want := r[AffectedBinding].String(), `"2"^^type:int64`; got != want {
+		t.Errorf("planner.Execute(%q) affected %s triples; want %s", q, got, want)
+	}
+	if got, want := r[SkippedBinding].String(), `"1"^^type:int64`; got != want {
+		t.Errorf("planner.Execute(%q) skipped %s triples; want %s", q, got, want)
+	}
+	if got := r[SkippedErrorsBinding].S; got == nil || !strings.Contains(*got, `"tall"^^type:int64`) {
+		t.Errorf("planner.Execute(%q) reported skipped errors %v; want them to mention the malformed object", q, r[SkippedErrorsBinding])
+	}
+}
+
+func TestPlannerInsertNowAnchors(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	g, err := s.NewGraph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("s.NewGraph(%q) failed with error %v", "?a", err)
+	}
+	const q = `insert data into ?a {/sensor<1> "reading"@[NOW()] "42"^^type:int64 . /sensor<1> "last_read"@[] "reading"@[ now() ]};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid plan with error %v", err)
+	}
+	before := time.Now()
+	if _, err := plnr.Execute(ctx); err != nil {
+		t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+	}
+	after := time.Now()
+
+	ts := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Errorf("g.Triples failed with error %v", err)
+		}
+	}()
+	var anchors []time.Time
+	for trpl := range ts {
+		p := trpl.Predicate()
+		if p.ID() == "last_read" {
+			op, err := trpl.Object().Predicate()
+			if err != nil {
+				t.Fatalf("%v should have a predicate as object, %v", trpl, err)
+			}
+			p = op
+		}
+		ta, err := p.TimeAnchor()
+		if err != nil {
+			t.Fatalf("%v should have a temporal predicate, %v", trpl, err)
+		}
+		anchors = append(anchors, *ta)
+	}
+	if len(anchors) != 2 {
+		t.Fatalf("planner.Execute(%q) inserted %d triples; want 2", q, len(anchors))
+	}
+	const tolerance = time.Second
+	for _, ta := range anchors {
+		if ta.Location() != time.UTC {
+			t.Errorf("planner.Execute(%q) anchored a triple at %v; want a UTC time", q, ta)
+		}
+		if ta.Before(before.Add(-tolerance)) || ta.After(after.Add(tolerance)) {
+			t.Errorf("planner.Execute(%q) anchored a triple at %v; want a time between %v and %v", q, ta, before, after)
+		}
+	}
+	if !anchors[0].Equal(anchors[1]) {
+		t.Errorf("planner.Execute(%q) anchored the triples at %v; want the same instant", q, anchors)
+	}
+
+	// NOW() makes no sense when deleting data.
+	const dq = `delete data from ?a {/sensor<1> "reading"@[NOW()] "42"^^type:int64};`
+	st = &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(dq, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error %v", dq, err)
+	}
+	if _, err := New(ctx, s, st, 0, 10, nil); err == nil {
+		t.Errorf("planner.New(%q) should have rejected the NOW() time anchor", dq)
+	}
+}
+
+func TestPlannerDeleteByAnchor(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	g, err := s.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatalf("memory.Store.Graph(_, %q) failed with error %v", "?test", err)
+	}
+	count := func() int {
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Errorf("g.Triples failed with error %v", err)
+			}
+		}()
+		n := 0
+		for range ts {
+			n++
+		}
+		return n
+	}
+	before := count()
+
+	// Purging with a bulk size of 1 removes the triples one batch at a time.
+	q := `delete from ?test where predicate anchor before 2016-02-01T00:00:00-08:00;`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 1, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%q) failed with error %v", q, err)
+	}
+	r, ok := tbl.Row(0)
+	if !ok || tbl.NumRows() != 1 {
+		t.Fatalf("planner.Execute(%q) returned %d rows; want 1", q, tbl.NumRows())
+	}
+	got, err := r[AffectedBinding].L.Int64()
+	if err != nil {
+		t.Fatalf("planner.Execute(%q) returned a non integer count %v", q, r[AffectedBinding])
+	}
+	// The cutoff is inclusive, and the triples with immutable predicates are
+	// kept even if their object is a temporal predicate.
+	if want := int64(3); got != want {
+		t.Errorf("planner.Execute(%q) affected %d triples; want %d", q, got, want)
+	}
+	if got, want := count(), before-3; got != want {
+		t.Errorf("planner.Execute(%q) left %d triples; want %d", q, got, want)
+	}
+	for _, trpl := range []string{
+		`/u<peter>	"bought"@[2016-03-01T00:00:00-08:00]	/c<model x>`,
+		`/l<barcelona>	"predicate"@[]	"turned"@[2016-01-01T00:00:00-08:00]`,
+	} {
+		tt, err := triple.Parse(trpl, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse %q with error %v", trpl, err)
+		}
+		if ok, err := g.Exist(ctx, tt); err != nil || !ok {
+			t.Errorf("g.Exist(%v) returned (%v, %v) after purging; want (true, nil)", tt, ok, err)
+		}
+	}
+}
+
 func TestPlannerCreateGraph(t *testing.T) {
 	ctx := context.Background()
 	memory.DefaultStore.DeleteGraph(ctx, "?foo")
@@ -250,6 +661,130 @@ func TestPlannerDropGraph(t *testing.T) {
 	}
 }
 
+func TestPlannerTruncateGraph(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?foo", originalTriples, t)
+	if _, err := s.NewGraph(ctx, "?bar"); err != nil {
+		t.Fatal(err)
+	}
+	run := func(bql string) error {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		stm := &semantic.Statement{}
+		if err = p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
+			t.Fatalf("Parser.consume: failed to accept BQL %q with error %v", bql, err)
+		}
+		pln, err := New(ctx, s, stm, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New: should have not failed to create a plan for statement %v with error %v", stm, err)
+		}
+		_, err = pln.Execute(ctx)
+		return err
+	}
+	if err := run(`truncate graph ?foo, ?bar;`); err != nil {
+		t.Fatalf("planner.Execute: failed to execute truncate plan with error %v", err)
+	}
+	for _, gn := range []string{"?foo", "?bar"} {
+		g, err := s.Graph(ctx, gn)
+		if err != nil {
+			t.Fatalf("planner.Execute: graph %q should still exist after truncate; got error %v", gn, err)
+		}
+		ts := make(chan *triple.Triple, 10)
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(ts); got != 0 {
+			t.Errorf("planner.Execute: graph %q should be empty after truncate; got %d triples", gn, got)
+		}
+	}
+	if err := run(`truncate graph ?foo, ?baz;`); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("planner.Execute: truncate of a missing graph returned %v; want %v", err, storage.ErrGraphNotFound)
+	}
+}
+
+func TestPlannerCreateAndDropGraphErrors(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	if _, err := s.NewGraph(ctx, "?foo"); err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		bql  string
+		want error
+	}{
+		{`create graph ?foo, ?bar;`, storage.ErrGraphExists},
+		{`drop graph ?bar, ?baz;`, storage.ErrGraphNotFound},
+	}
+	for _, entry := range table {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		stm := &semantic.Statement{}
+		if err = p.Parse(grammar.NewLLk(entry.bql, 1), stm); err != nil {
+			t.Fatalf("Parser.consume: failed to accept BQL %q with error %v", entry.bql, err)
+		}
+		pln, err := New(ctx, s, stm, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New: should have not failed to create a plan for statement %v with error %v", stm, err)
+		}
+		if _, err := pln.Execute(ctx); !errors.Is(err, entry.want) {
+			t.Errorf("planner.Execute(%q) returned error %v; want %v", entry.bql, err, entry.want)
+		}
+	}
+	// Graphs without errors should still be processed.
+	if _, err := s.Graph(ctx, "?bar"); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("graph %q should have been created and dropped; got error %v", "?bar", err)
+	}
+}
+
+func TestPlannerCreateAndDropGraphExistenceFlags(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	run := func(bql string) error {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		stm := &semantic.Statement{}
+		if err = p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
+			t.Fatalf("Parser.consume: failed to accept BQL %q with error %v", bql, err)
+		}
+		pln, err := New(ctx, s, stm, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New: should have not failed to create a plan for statement %v with error %v", stm, err)
+		}
+		_, err = pln.Execute(ctx)
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := run(`create graph if not exists ?foo, ?bar;`); err != nil {
+			t.Errorf("planner.Execute: repeated create graph if not exists failed on run %d with error %v", i, err)
+		}
+	}
+	for _, g := range []string{"?foo", "?bar"} {
+		if _, err := s.Graph(ctx, g); err != nil {
+			t.Errorf("planner.Execute: failed to create graph %q with error %v", g, err)
+		}
+	}
+	if err := run(`create graph ?foo;`); !errors.Is(err, storage.ErrGraphExists) {
+		t.Errorf("planner.Execute: create graph without the flag returned %v; want %v", err, storage.ErrGraphExists)
+	}
+	for i := 0; i < 2; i++ {
+		if err := run(`drop graph if exists ?foo, ?bar;`); err != nil {
+			t.Errorf("planner.Execute: repeated drop graph if exists failed on run %d with error %v", i, err)
+		}
+	}
+	for _, g := range []string{"?foo", "?bar"} {
+		if _, err := s.Graph(ctx, g); err == nil {
+			t.Errorf("planner.Execute: failed to drop graph %q", g)
+		}
+	}
+	if err := run(`drop graph ?foo;`); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("planner.Execute: drop graph without the flag returned %v; want %v", err, storage.ErrGraphNotFound)
+	}
+}
+
 func populateStoreWithTriples(ctx context.Context, s storage.Store, gn string, triples string, tb testing.TB) {
 	g, err := s.NewGraph(ctx, gn)
 	if err != nil {
@@ -401,24 +936,49 @@ func TestPlannerQuery(t *testing.T) {
 			nRows:     4,
 		},
 		{
-			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[,] as ?o};`,
+			q:         `select ?o from ?test where {/u<peter> "bought"@[,2016-01-15T00:00:00-08:00; 2016-03-01T00:00:00-08:00,2016-03-15T00:00:00-08:00] ?o};`,
 			nBindings: 1,
+			nRows:     2,
+		},
+		{
+			q:         `select ?s, ?o from ?test where {?s "bought"@[2016-01-01T00:00:00-08:00,2016-01-15T00:00:00-08:00; 2016-03-15T00:00:00-08:00,] ?o};`,
+			nBindings: 2,
 			nRows:     4,
 		},
 		{
-			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[,2015-01-01T00:00:00-08:00] as ?o};`,
+			q:         `select ?o from ?test where {/u<peter> "bought"@[2016-01-01T00:00:00-08:00,2016-03-01T00:00:00-08:00; 2016-02-01T00:00:00-08:00,2016-04-01T00:00:00-08:00] ?o};`,
 			nBindings: 1,
-			nRows:     0,
+			nRows:     4,
 		},
 		{
-			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[2017-01-01T00:00:00-08:00,] as ?o};`,
+			q:         `select ?o from ?test where {/u<peter> "bought"@[2014-01-01T00:00:00-08:00,2014-02-01T00:00:00-08:00; 2017-01-01T00:00:00-08:00,] ?o};`,
 			nBindings: 1,
 			nRows:     0,
 		},
 		{
-			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[2015-01-01T00:00:00-08:00,2017-01-01T00:00:00-08:00] as ?o};`,
+			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[,2016-01-15T00:00:00-08:00; 2016-04-01T00:00:00-08:00,] as ?o};`,
 			nBindings: 1,
-			nRows:     4,
+			nRows:     2,
+		},
+		{
+			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[,] as ?o};`,
+			nBindings: 1,
+			nRows:     4,
+		},
+		{
+			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[,2015-01-01T00:00:00-08:00] as ?o};`,
+			nBindings: 1,
+			nRows:     0,
+		},
+		{
+			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[2017-01-01T00:00:00-08:00,] as ?o};`,
+			nBindings: 1,
+			nRows:     0,
+		},
+		{
+			q:         `select ?o from ?test where {/l<barcelona> "predicate"@[] "turned"@[2015-01-01T00:00:00-08:00,2017-01-01T00:00:00-08:00] as ?o};`,
+			nBindings: 1,
+			nRows:     4,
 		},
 		{
 			q:         `select ?grandparent, count(?name) as ?grandchildren from ?test where {/u<joe> as ?grandparent "parent_of"@[] ?offspring . ?offspring "parent_of"@[] ?name} group by ?grandparent;`,
@@ -495,6 +1055,41 @@ func TestPlannerQuery(t *testing.T) {
 			nBindings: 2,
 			nRows:     1,
 		},
+		{
+			q:         `select ?t1, ?t2 from ?test where {/u<peter> "bought"@[?t1] ?o1 . /u<paul> "bought"@[?t2] ?o2} having ?t1 < ?t2;`,
+			nBindings: 2,
+			nRows:     3,
+		},
+		{
+			q:         `select ?t1, ?t2 from ?test where {/u<peter> "bought"@[?t1] ?o1 . /u<paul> "bought"@[?t2] ?o2} having ?t1 = ?t2;`,
+			nBindings: 2,
+			nRows:     2,
+		},
+		{
+			q:         `select ?p, count(?o) as ?n from ?test where {/u<peter> ?p ?o} group by ?p having (?n = "2"^^type:int64) or (?n = "3"^^type:int64);`,
+			nBindings: 2,
+			nRows:     1,
+		},
+		{
+			q:         `select ?s, ?o from ?test where {?s ?p ?o} having ?s under /item;`,
+			nBindings: 2,
+			nRows:     3,
+		},
+		{
+			q:         `select ?s, ?o from ?test where {?s "in"@[?t] ?o} having ?s under /room;`,
+			nBindings: 2,
+			nRows:     0,
+		},
+		{
+			q:         `select ?s, ?o from ?test where {?s ?p ?o} having ?o under /item;`,
+			nBindings: 2,
+			nRows:     0,
+		},
+		{
+			q:         `select ?s, ?o from ?test where {?s ?p ?o} having (?s under /item) and (?o = /room<Kitchen>);`,
+			nBindings: 2,
+			nRows:     1,
+		},
 		/*
 			/c<model s> "is_a"@[] /t<car>
 			/c<model x> "is_a"@[] /t<car>
@@ -1247,107 +1842,46 @@ func TestPlannerQuery(t *testing.T) {
 		if got, want := len(tbl.Bindings()), entry.nBindings; got != want {
 			t.Errorf("planner.Execute(%s)\n= a Table with %d bindings; want %d", entry.q, got, want)
 		}
-		if got, want := len(tbl.Rows()), entry.nRows; got != want {
+		if got, want := tbl.NumRows(), entry.nRows; got != want {
 			t.Errorf("planner.Execute(%s)\n= a Table with %d rows; want %d\nTable:\n%v\n", entry.q, got, want, tbl)
 		}
 	}
 }
 
-func TestPlannerQueryError(t *testing.T) {
+func TestPlannerUnwind(t *testing.T) {
+	const listTriples = `/u<joe>	"tags"@[]	"["a"^^type:text, "b"^^type:text]"^^type:list
+/u<mary>	"tags"@[]	"["c"^^type:text]"^^type:list
+/u<peter>	"tags"@[]	"[]"^^type:list
+/u<peter>	"height_cm"@[]	"180"^^type:int64
+`
 	testTable := []struct {
-		q string
+		q         string
+		nBindings int
+		nRows     int
 	}{
 		{
-			q: `SELECT ?s_id, ?height
-				FROM ?test
-				WHERE {
-					?s ID ?s_id "height_cm"@[] ?height
-				}
-				HAVING ?s_id > "37"^^type:int64;`,
-		},
-		{
-			q: `SELECT ?s_id, ?height
-				FROM ?test
-				WHERE {
-					?s ID ?s_id "height_cm"@[] ?height
-				}
-				HAVING ?s_id = /u<alice>;`,
-		},
-		{
-			q: `SELECT ?s, ?p, ?o
-				FROM ?test
-				WHERE {
-					?s ?p ?o
-				}
-				HAVING ?p < "height_cm"@[];`,
-		},
-		{
-			q: `SELECT ?s, ?p, ?o
-				FROM ?test
-				WHERE {
-					?s ?p ?o
-				}
-				HAVING ?p > "bought"@[2016-01-01T00:00:00-08:00];`,
-		},
-		{
-			q: `SELECT ?s, ?height
-				FROM ?test
-				WHERE {
-					?s "height_cm"@[] ?height
-				}
-				HAVING ?s < /u<zzzzz>;`,
-		},
-		{
-			q: `SELECT ?s, ?height
-				FROM ?test
-				WHERE {
-					?s "height_cm"@[] ?height
-				}
-				HAVING ?s > /u<alice>;`,
-		},
-		{
-			q: `SELECT ?p, ?o
-				FROM ?test
-				WHERE {
-					/u<peter> ?p ?o .
-					FILTER latest(?p) .
-					FILTER latest(?p)
-				};`,
-		},
-		{
-			q: `SELECT ?p, ?o
-				FROM ?test
-				WHERE {
-					/l<barcelona> ?p ?o .
-					FILTER latest(?p) .
-					FILTER latest(?o)
-				};`,
+			q:         `SELECT ?s, ?tag FROM ?test WHERE {?s "tags"@[] ?tags} UNWIND ?tags AS ?tag;`,
+			nBindings: 2,
+			nRows:     3,
 		},
 		{
-			q: `SELECT ?p, ?o
-				FROM ?test
-				WHERE {
-					/u<peter> ?p ?o .
-					FILTER latest(?b_not_exist)
-				};`,
+			q:         `SELECT ?tag FROM ?test WHERE {/u<peter> "tags"@[] ?tags} UNWIND ?tags AS ?tag;`,
+			nBindings: 1,
+			nRows:     0,
 		},
 		{
-			q: `SELECT ?s, ?p, ?o
-				FROM ?test
-				WHERE {
-					?s ID ?sID ?p ?o .
-					FILTER latest(?sID)
-				};`,
+			q:         `SELECT ?s, COUNT(?tag) AS ?n FROM ?test WHERE {?s "tags"@[] ?tags} UNWIND ?tags AS ?tag GROUP BY ?s;`,
+			nBindings: 2,
+			nRows:     2,
 		},
 	}
 
 	s, ctx := memory.NewStore(), context.Background()
-	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	populateStoreWithTriples(ctx, s, "?test", listTriples, t)
 	for _, entry := range testTable {
-		// Setup for test:
 		p, err := grammar.NewParser(grammar.SemanticBQL())
 		if err != nil {
-			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
 		st := &semantic.Statement{}
 		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
@@ -1357,598 +1891,4156 @@ func TestPlannerQueryError(t *testing.T) {
 		if err != nil {
 			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 		}
-
-		// Actual test:
-		_, err = plnr.Execute(ctx)
-		if err == nil {
-			t.Errorf("planner.Execute(%s)\n= _, nil; want _, error", entry.q)
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := len(tbl.Bindings()), entry.nBindings; got != want {
+			t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", entry.q, got, want)
 		}
+		if got, want := tbl.NumRows(), entry.nRows; got != want {
+			t.Errorf("planner.Execute(%s) returned the wrong number of rows; got %d, want %d\n%s", entry.q, got, want, tbl)
+		}
+	}
+
+	// Unwinding a binding that does not hold lists should fail.
+	q := `SELECT ?h FROM ?test WHERE {?s "height_cm"@[] ?o} UNWIND ?o AS ?h;`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%s) should have failed unwinding a non list binding", q)
 	}
 }
 
-func TestPlannerConstructAddsCorrectNumberofTriples(t *testing.T) {
-	sts, dts := len(strings.Split(constructTestSrcTriples, "\n"))-1, len(strings.Split(constructTestDestTriples, "\n"))-1
+func TestPlannerCast(t *testing.T) {
+	const castTriples = `/u<1>	"height_cm"@[]	"151"^^type:int64
+/u<40>	"height_cm"@[]	"180"^^type:int64
+/u<50>	"height_cm"@[]	"180"^^type:int64
+`
 	testTable := []struct {
-		s    string
-		trps int
+		q     string
+		b     string
+		want  []string
+		nRows int
 	}{
 		{
-			s: `construct {?s ?p ?o}
-			    into ?dest
-			    from ?src
-			    where {?s ?p ?o};`,
-			trps: sts + dts,
-		},
-		{
-			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>}
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o};`,
-			// 3 matching triples * 4 new triples per matched triple due to reification + 1 triple in dest graph.
-			trps: 3*4 + dts,
-		},
-		{
-			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>;
-			                               "outcome"@[] "good"^^type:text }
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o};`,
-			// 3 matching triples * 5 new triples per matched triple due to reification + 1 triple in dest graph.
-			trps: 3*5 + dts,
-		},
-		{
-			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-			                                 "outcome"@[] "good"^^type:text .
-			               ?s "connected_to"@[] ?o}
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o.
-			           ?s "met_at"@[?t] ?o};`,
-			// 2 matching triples * (5 new triples due to reification + 1 explicitly constructed triple per matched triple) +
-			// 1 triple in dest graph.
-			trps: 2*6 + dts,
+			q:     `SELECT ?s_id FROM ?test WHERE {?s ID ?s_id "height_cm"@[] ?h} HAVING CAST(?s_id, type:int64) > "37"^^type:int64;`,
+			nRows: 2,
 		},
 		{
-			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-			                                 "outcome"@[] "good"^^type:text .
-			               ?s "connected_to"@[] ?o; "at"@[?t] /city<New York> }
-			    into ?dest
-			    from ?src
-			    where {?s "met"@[] ?o.
-			           ?s "met_at"@[?t] ?o};`,
-			// 2 matching triples * 9 new triples due to reification + 1 triple in dest graph.
-			trps: 2*9 + dts,
+			q:     `SELECT ?s, CAST(?h, type:text) AS ?ht FROM ?test WHERE {?s "height_cm"@[] ?h} HAVING ?ht = "180"^^type:text;`,
+			b:     "?ht",
+			want:  []string{`"180"^^type:text`, `"180"^^type:text`},
+			nRows: 2,
 		},
 		{
-			s: `construct {?d2 "is_2_hops_from"@[] ?s1 }
-			    into ?dest
-			    from ?src
-			    where {?s1 "is_connected_to"@[] ?d1.
-			           ?d1 "is_connected_to"@[] ?d2};`,
-			// 2 new triples (/city<A> "is_2_hops_from"@[] /city<D>, /city<A> "is_2_hops_from"@[] /city<E>) +  1 triple in dest graph.
-			trps: 3,
+			q:     `SELECT ?s, CAST(?h, type:float64) AS ?hf FROM ?test WHERE {?s "height_cm"@[] ?h} ORDER BY ?hf DESC;`,
+			b:     "?hf",
+			want:  []string{`"180"^^type:float64`, `"180"^^type:float64`, `"151"^^type:float64`},
+			nRows: 3,
 		},
 	}
 
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", castTriples, t)
 	for _, entry := range testTable {
 		p, err := grammar.NewParser(grammar.SemanticBQL())
 		if err != nil {
-			t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-
-		s, ctx := memory.NewStore(), context.Background()
-		populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
-		populateStoreWithTriples(ctx, s, "?dest", constructTestDestTriples, t)
-
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
-			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
 		}
 		plnr, err := New(ctx, s, st, 0, 10, nil)
 		if err != nil {
-			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 		}
-		_, err = plnr.Execute(ctx)
+		tbl, err := plnr.Execute(ctx)
 		if err != nil {
-			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), entry.nRows; got != want {
+			t.Errorf("planner.Execute(%s) returned the wrong number of rows; got %d, want %d\n%s", entry.q, got, want, tbl)
+		}
+		if entry.b == "" {
 			continue
 		}
-
-		g, err := s.Graph(ctx, "?dest")
-		if err != nil {
-			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[entry.b].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v for binding %q; want %v", entry.q, got, entry.b, entry.want)
 		}
+	}
+}
 
-		i := 0
-		ts := make(chan *triple.Triple)
-		go func() {
-			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-				t.Error(err)
-			}
-		}()
-		for range ts {
-			i++
+func TestPlannerHavingAggregation(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	parse := func(q string) *semantic.Statement {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-		if i != entry.trps {
-			t.Errorf("g.Triples should have returned %v triples, returned %v instead", entry.trps, i)
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
 		}
+		return st
 	}
 
-}
-
-func TestPlannerConstructAddsCorrectTriples(t *testing.T) {
-	bql := `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
-	                                     "outcome"@[] "good"^^type:text.
-	                   ?s "connected_to"@[] ?o }
-	        into ?dest
-	        from ?src
-	        where {?s "met"@[] ?o.
-		       ?s "met_at"@[?t] ?o};`
-	p, err := grammar.NewParser(grammar.SemanticBQL())
+	// Groups are filtered by the aliases of the aggregations.
+	q := `SELECT ?grandparent, COUNT(?grandchild) AS ?grandchildren
+	      FROM ?test
+	      WHERE {
+	        ?grandparent "parent_of"@[] ?parent .
+	        ?parent "parent_of"@[] ?grandchild
+	      }
+	      GROUP BY ?grandparent
+	      HAVING ?grandchildren > "1"^^type:int64;`
+	plnr, err := New(ctx, s, parse(q), 0, 10, nil)
 	if err != nil {
-		t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+	}
+	rows := tbl.Rows()
+	if len(rows) != 1 {
+		t.Fatalf("planner.Execute(%s) returned %d rows; want 1\n%s", q, len(rows), tbl)
+	}
+	if got, want := rows[0]["?grandparent"].String(), "/u<joe>"; got != want {
+		t.Errorf("planner.Execute(%s) returned grandparent %s; want %s", q, got, want)
+	}
+	if got, want := rows[0]["?grandchildren"].String(), `"2"^^type:int64`; got != want {
+		t.Errorf("planner.Execute(%s) returned %s grandchildren; want %s", q, got, want)
+	}
+
+	// Bindings reduced away by the aggregation are rejected at plan time.
+	q = `SELECT ?grandparent, COUNT(?grandchild) AS ?grandchildren
+	     FROM ?test
+	     WHERE {
+	       ?grandparent "parent_of"@[] ?parent .
+	       ?parent "parent_of"@[] ?grandchild
+	     }
+	     GROUP BY ?grandparent
+	     HAVING ?parent = /u<peter>;`
+	if _, err := New(ctx, s, parse(q), 0, 10, nil); err == nil {
+		t.Errorf("planner.New(%s) should have failed to create a query plan", q)
 	}
+}
+
+func TestPlannerGroupIndex(t *testing.T) {
+	const scoreTriples = `/team<red>	"player"@[]	/u<ann>
+/team<red>	"player"@[]	/u<bob>
+/team<red>	"player"@[]	/u<cid>
+/team<red>	"player"@[]	/u<dan>
+/team<blue>	"player"@[]	/u<eve>
+/team<blue>	"player"@[]	/u<fay>
+/u<ann>	"score"@[]	"10"^^type:int64
+/u<bob>	"score"@[]	"40"^^type:int64
+/u<cid>	"score"@[]	"30"^^type:int64
+/u<dan>	"score"@[]	"20"^^type:int64
+/u<eve>	"score"@[]	"5"^^type:int64
+/u<fay>	"score"@[]	"15"^^type:int64
+`
 	s, ctx := memory.NewStore(), context.Background()
-	populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
-	populateStoreWithTriples(ctx, s, "?dest", "", t)
+	populateStoreWithTriples(ctx, s, "?test", scoreTriples, t)
 
+	q := `SELECT ?team, ?player, ?score, GROUP_INDEX() AS ?idx
+	      FROM ?test
+	      WHERE {
+	        ?team "player"@[] ?player .
+	        ?player "score"@[] ?score
+	      }
+	      GROUP BY ?team
+	      ORDER BY ?team, ?score DESC
+	      HAVING ?idx < "3"^^type:int64;`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
 	st := &semantic.Statement{}
-	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
-		t.Errorf("Parser.consume: failed to parse query %q with error %v", bql, err)
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
 	}
 	plnr, err := New(ctx, s, st, 0, 10, nil)
 	if err != nil {
-		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 	}
-	_, err = plnr.Execute(ctx)
+	tbl, err := plnr.Execute(ctx)
 	if err != nil {
-		t.Errorf("planner.Execute failed for query %q with error %v", bql, err)
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
 	}
-
-	g, err := s.Graph(ctx, "?dest")
-	if err != nil {
-		t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, fmt.Sprintf("%s %s %s", r["?team"], r["?player"], r["?idx"]))
 	}
+	want := []string{
+		`/team<blue> /u<fay> "0"^^type:int64`,
+		`/team<blue> /u<eve> "1"^^type:int64`,
+		`/team<red> /u<bob> "0"^^type:int64`,
+		`/team<red> /u<cid> "1"^^type:int64`,
+		`/team<red> /u<dan> "2"^^type:int64`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute(%s) returned %v; want %v", q, got, want)
+	}
+}
 
-	ts := make(chan *triple.Triple)
-	go func() {
-		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-			t.Error(err)
-		}
-	}()
-
-	bnm := make(map[string]map[string]bool)
-	bns := make(map[string]string)
-	bna := map[string]bool{
-		"/_<b1>": true,
-		"/_<b2>": true,
+func TestPlannerHistogram(t *testing.T) {
+	testTable := []struct {
+		q     string
+		empty bool
+		want  []string
+	}{
+		{
+			q: `SELECT ?s, HISTOGRAM(?t, "1mo") FROM ?test WHERE {?s "bought"@[?t] ?o} GROUP BY ?s ORDER BY ?s, ?bucket_start;`,
+			want: []string{
+				`/u<paul> 2016-01-01T00:00:00Z "1"^^type:int64`,
+				`/u<paul> 2016-04-01T00:00:00Z "1"^^type:int64`,
+				`/u<peter> 2016-01-01T00:00:00Z "1"^^type:int64`,
+				`/u<peter> 2016-02-01T00:00:00Z "1"^^type:int64`,
+				`/u<peter> 2016-03-01T00:00:00Z "1"^^type:int64`,
+				`/u<peter> 2016-04-01T00:00:00Z "1"^^type:int64`,
+			},
+		},
+		{
+			q:     `SELECT ?s, HISTOGRAM(?t, "1mo") FROM ?test WHERE {?s "bought"@[?t] ?o} GROUP BY ?s ORDER BY ?s, ?bucket_start HAVING ?s = /u<paul>;`,
+			empty: true,
+			want: []string{
+				`/u<paul> 2016-01-01T00:00:00Z "1"^^type:int64`,
+				`/u<paul> 2016-02-01T00:00:00Z "0"^^type:int64`,
+				`/u<paul> 2016-03-01T00:00:00Z "0"^^type:int64`,
+				`/u<paul> 2016-04-01T00:00:00Z "1"^^type:int64`,
+			},
+		},
+		{
+			q: `SELECT HISTOGRAM(?t, "2mo"^^type:text) FROM ?test WHERE {?s "bought"@[?t] ?o} ORDER BY ?count DESC, ?bucket_start;`,
+			want: []string{
+				`2016-01-01T00:00:00Z "3"^^type:int64`,
+				`2016-03-01T00:00:00Z "3"^^type:int64`,
+			},
+		},
 	}
-	dtm := map[string]bool{
-		fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"connected_to"@[]`, `/person<B>`):                                 false,
-		fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"connected_to"@[]`, `/person<C>`):                                 false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<A>`):                     false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<B>`):                      false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"location"@[]`, `/city<New York>`):                                    false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b1>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<B>`):                     false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"_object"@[2016-04-10T04:25:00Z]`, `/person<C>`):                      false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"location"@[]`, `/city<New York>`):                                    false,
-		fmt.Sprintf("%s\t%s\t%s", `/_<b2>`, `"outcome"@[]`, `"good"^^type:text`):                                   false,
-	}
-
-	// First, we map each blank node generated to a potential blank node placeholder (such as b1 or b2.)
-	sts := []*triple.Triple{}
-	for elem := range ts {
-		sts = append(sts, elem)
-		if elem.Subject().Type().String() == "/_" {
-			for k := range dtm {
-				trp, err := triple.Parse(k, literal.DefaultBuilder())
-				if err != nil {
-					t.Errorf("Unable to parse triple: %v with error %v", k, err)
-				}
-				if trp.Subject().Type().String() == "/_" &&
-					trp.Predicate().String() == elem.Predicate().String() &&
-					trp.Object().String() == elem.Object().String() {
-					if mp, ok := bnm[elem.Subject().String()]; !ok {
-						bnm[elem.Subject().String()] = map[string]bool{
-							trp.Subject().String(): true,
-						}
-					} else {
-						mp[trp.Subject().String()] = true
-					}
-				}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{EmptyHistogramBuckets: entry.empty})
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var cs []string
+			for _, b := range tbl.Bindings() {
+				cs = append(cs, r[b].String())
 			}
-
+			got = append(got, strings.Join(cs, " "))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
 		}
 	}
+}
 
-	// Then, we decide which place holder blank nodes can be used to substiute for a given blank node
-	// by substituting the place holder in every triple where the given blank node is the subject and
-	// checking if the triple exists in the map of expected triples.
-	for _, t := range sts {
-		if t.Subject().Type().String() == "/_" {
-			for bn := range bnm[t.Subject().String()] {
-				rep := fmt.Sprintf("%s\t%s\t%s", bn, t.Predicate().String(), t.Object().String())
-				if _, ok := dtm[rep]; !ok {
-					bnm[t.Subject().String()][bn] = false
-				}
-			}
-		}
+func TestPlannerMultiClauseOptional(t *testing.T) {
+	const optionalTriples = `/u<a>	"knows"@[]	/u<b>
+/u<a>	"knows"@[]	/u<c>
+/u<b>	"lives"@[]	/u<x>
+/u<x>	"in"@[]	/u<country>
+/u<c>	"lives"@[]	/u<y>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			// Either both clauses of the block match or none contributes data.
+			q:    `SELECT ?p, ?city, ?country FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {?p "lives"@[] ?city . ?city "in"@[] ?country}} ORDER BY ?p;`,
+			want: []string{"/u<b> /u<x> /u<country>", "/u<c> <NULL> <NULL>"},
+		},
+		{
+			q:    `SELECT ?p, ?city, ?country FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {?p "lives"@[] ?city . ?city "in"@[] ?country .}} ORDER BY ?p;`,
+			want: []string{"/u<b> /u<x> /u<country>", "/u<c> <NULL> <NULL>"},
+		},
+		{
+			q:    `SELECT ?p, ?city, ?country FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {?p "lives"@[] ?city . ?city "named"@[] ?country}} ORDER BY ?p;`,
+			want: []string{"/u<b> <NULL> <NULL>", "/u<c> <NULL> <NULL>"},
+		},
+		{
+			// Blocks not sharing bindings with the rest are cross joined.
+			q:    `SELECT ?p, ?city, ?country FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {/u<b> "lives"@[] ?city . ?city "in"@[] ?country}} ORDER BY ?p;`,
+			want: []string{"/u<b> /u<x> /u<country>", "/u<c> /u<x> /u<country>"},
+		},
+		{
+			q:    `SELECT ?p, ?city, ?country FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {?p "lives"@[] ?city . ?city "in"@[] ?country}} ORDER BY ?country DESC NULLS FIRST;`,
+			want: []string{"/u<c> <NULL> <NULL>", "/u<b> /u<x> /u<country>"},
+		},
+		{
+			// Single clause blocks keep their partial semantics.
+			q:    `SELECT ?p, ?city FROM ?test WHERE {/u<a> "knows"@[] ?p . OPTIONAL {?p "lives"@[] ?city}} ORDER BY ?p;`,
+			want: []string{"/u<b> /u<x>", "/u<c> /u<y>"},
+		},
 	}
 
-	// Finally, we assign a blank node to a place-holder blank node, if the place-holder blank node is
-	// not used to substitute any other blank node.
-	for k, v := range bnm {
-		for bn, p := range v {
-			if p && bna[bn] {
-				bns[k] = bn
-				bna[bn] = false
-				break
-			}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", optionalTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-	}
-	if len(sts) != len(dtm) {
-		t.Errorf("g.Triples should have returned %v triples, returned %v instead", len(dtm), len(sts))
-	}
-	for _, elem := range sts {
-		if elem.Subject().Type().String() == "/_" {
-			if val, ok := bns[elem.Subject().String()]; ok {
-				// Substitute the blank node with the mapped place holder blank node id.
-				rep := fmt.Sprintf("%s\t%s\t%s", val, elem.Predicate().String(), elem.Object().String())
-				if _, ok := dtm[rep]; !ok {
-					t.Errorf("unexpected triple: %v added to graph", elem)
-				}
-				dtm[rep] = true
-			} else {
-				t.Errorf("unexpected triple: %v added to graph", elem)
-			}
-		} else {
-			sr := elem.String()
-			if _, ok := dtm[sr]; !ok {
-				t.Errorf("unexpected triple: %v added to graph", elem)
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
 			}
-			dtm[sr] = true
+			got = append(got, strings.Join(vs, " "))
 		}
-	}
-	for k, v := range dtm {
-		if v == false {
-			t.Errorf("g.Triples did not return triple: %v", k)
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
 		}
 	}
 }
 
-func TestPlannerDeconstructRemovesCorrectTriples(t *testing.T) {
+func TestPlannerNegatedBlocks(t *testing.T) {
+	const negatedTriples = `/u<a>	"is_a"@[]	/t<car>
+/u<b>	"is_a"@[]	/t<bike>
+/u<c>	"color"@[]	"red"^^type:text
+/u<a>	"color"@[]	"blue"^^type:text
+/u<b>	"color"@[]	"green"^^type:text
+`
 	testTable := []struct {
-		s    string
-		trps []string
+		q    string
+		want []string
 	}{
 		{
-			s: `deconstruct {?p1 "met"@[] ?p2}
-			    in ?dest
-			    from ?src
-			    where {?p1 "lives_in"@[] /city<A>.
-				   ?p2 "lives_in"@[] /city<B>};`,
-			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<D>`, `"met"@[]`, `/person<A>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?s "is_a"@[] ?t}} ORDER BY ?s;`,
+			want: []string{"/u<c>"},
 		},
 		{
-			s: `deconstruct {?p1 "met"@[] ?p2.
-		                         ?p2 "met"@[] ?p1}
-			    in ?dest
-			    from ?src
-			    where {?p1 "lives_in"@[] /city<A>.
-				   ?p2 "lives_in"@[] /city<B>};`,
-			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
-				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?s "is_a"@[] /t<car>}} ORDER BY ?s;`,
+			want: []string{"/u<b>", "/u<c>"},
+		},
+		{
+			// All the clauses of the block need to match to remove a row.
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?s "is_a"@[] ?t . ?s "color"@[] "green"^^type:text}} ORDER BY ?s;`,
+			want: []string{"/u<a>", "/u<c>"},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?s "is_a"@[] /t<car>} . NOT {?s "is_a"@[] /t<bike>}} ORDER BY ?s;`,
+			want: []string{"/u<c>"},
+		},
+		{
+			// Blocks not sharing bindings with the rest remove all rows if they match.
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?x "is_a"@[] /t<car>}} ORDER BY ?s;`,
+			want: nil,
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "color"@[] ?c . NOT {?x "is_a"@[] /t<plane>}} ORDER BY ?s;`,
+			want: []string{"/u<a>", "/u<b>", "/u<c>"},
 		},
 	}
 
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", negatedTriples, t)
 	for _, entry := range testTable {
 		p, err := grammar.NewParser(grammar.SemanticBQL())
 		if err != nil {
-			t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-
-		s, ctx := memory.NewStore(), context.Background()
-		populateStoreWithTriples(ctx, s, "?src", deconstructTestSrcTriples, t)
-		populateStoreWithTriples(ctx, s, "?dest", deconstructTestDestTriples, t)
-
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
-			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
 		}
 		plnr, err := New(ctx, s, st, 0, 10, nil)
 		if err != nil {
-			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 		}
-		_, err = plnr.Execute(ctx)
+		tbl, err := plnr.Execute(ctx)
 		if err != nil {
-			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
-			continue
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
 		}
-
-		g, err := s.Graph(ctx, "?dest")
-		if err != nil {
-			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
 		}
+	}
+}
 
-		ts := make(chan *triple.Triple)
-		go func() {
-			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
-				t.Error(err)
-			}
-		}()
+func TestPlannerPredicateIDConstant(t *testing.T) {
+	const idTriples = `/u<peter>	"bought"@[]	/c<bike>
+/u<peter>	"bought"@[2016-01-01T00:00:00-08:00]	/c<mini>
+/u<peter>	"bought"@[2016-02-01T00:00:00-08:00]	/c<model s>
+/u<peter>	"sold"@[2016-03-01T00:00:00-08:00]	/c<mini>
+/u<peter>	"knows"@[]	/u<paul>
+/u<paul>	"bought"@[2016-04-01T00:00:00-08:00]	/c<model r>
+/u<paul>	"sold"@[]	/c<bike>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			// The ID constant matches both immutable and temporal predicates.
+			q:    `SELECT ?o FROM ?test WHERE {/u<peter> ?p ID "bought" ?o} ORDER BY ?o;`,
+			want: []string{"/c<bike>", "/c<mini>", "/c<model s>"},
+		},
+		{
+			// The "bought"@[,] form only matches temporal predicates.
+			q:    `SELECT ?o FROM ?test WHERE {/u<peter> "bought"@[,] ?o} ORDER BY ?o;`,
+			want: []string{"/c<mini>", "/c<model s>"},
+		},
+		{
+			q:    `SELECT ?o FROM ?test WHERE {?s ?p ID "sold" ?o} ORDER BY ?o;`,
+			want: []string{"/c<bike>", "/c<mini>"},
+		},
+		{
+			// The constant applies when the clause is specified with the rows of the table.
+			q:    `SELECT ?o FROM ?test WHERE {/u<peter> "knows"@[] ?s . ?s ?p ID "bought" ?o} ORDER BY ?o;`,
+			want: []string{"/c<model r>"},
+		},
+		{
+			// The constant applies when the clause is fully specified by the table.
+			q:    `SELECT ?o FROM ?test WHERE {/u<peter> ?p ?o . /u<peter> ?p ID "sold" ?o} ORDER BY ?o;`,
+			want: []string{"/c<mini>"},
+		},
+	}
 
-		dt := make(map[string]bool)
-		for _, trp := range entry.trps {
-			dt[trp] = false
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", idTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-
-		i := 0
-		for trp := range ts {
-			if val, ok := dt[trp.String()]; ok {
-				if !val {
-					i++
-				}
-				dt[trp.String()] = true
-			} else {
-				t.Errorf("unexpected triple: %v added to graph", trp)
-			}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
 		}
-		if i != len(entry.trps) {
-			t.Errorf("g.Triples did not return some of the triples.")
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?o"].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
 		}
 	}
-
 }
 
-func TestTreeTraversalToRoot(t *testing.T) {
-	// Graph traversal data.
-	traversalTriples := `/person<Gavin Belson>  "born in"@[]    /city<Springfield>
-		/person<Gavin Belson>  "parent of"@[]  /person<Peter Belson>
-		/person<Gavin Belson>  "parent of"@[]  /person<Mary Belson>
-		/person<Mary Belson>   "parent of"@[]  /person<Amy Schumer>
-		/person<Mary Belson>   "parent of"@[]  /person<Joe Schumer>`
-
-	traversalQuery := `SELECT ?grandparent
-		                 FROM ?test
-										 WHERE {
-										   ?s "parent of"@[] /person<Amy Schumer> .
-											 ?grandparent "parent of"@[] ?s
-										 };`
+func TestPlannerObjectTypeConstant(t *testing.T) {
+	const typeTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<joe>	"knows"@[]	"bob"^^type:text
+/u<joe>	"knows"@[]	/robot<r2d2>
+/u<joe>	"knows"@[]	/u/admin<root>
+/u<joe>	"knows"@[]	"knows"@[]
+/u<joe>	"likes"@[]	/u<mary>
+/u<mary>	"owns"@[]	/robot<c3po>
+/u<mary>	"owns"@[]	/car<mini>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			// Literal and predicate objects never match, and neither do subtypes.
+			q:    `SELECT ?o FROM ?test WHERE {/u<joe> "knows"@[] ?o TYPE /u} ORDER BY ?o;`,
+			want: []string{"/u<mary>", "/u<peter>"},
+		},
+		{
+			q:    `SELECT ?o FROM ?test WHERE {/u<joe> "knows"@[] ?o TYPE /robot};`,
+			want: []string{"/robot<r2d2>"},
+		},
+		{
+			q:    `SELECT ?o FROM ?test WHERE {?s ?p ?o TYPE /robot} ORDER BY ?o;`,
+			want: []string{"/robot<c3po>", "/robot<r2d2>"},
+		},
+		{
+			q:    `SELECT ?o FROM ?test WHERE {/u<joe> "knows"@[] ?o TYPE /car};`,
+			want: nil,
+		},
+		{
+			// The constraint applies when the clause is specified with the rows of the table.
+			q:    `SELECT ?o FROM ?test WHERE {/u<joe> "likes"@[] ?s . ?s "owns"@[] ?o ID ?id TYPE /car};`,
+			want: []string{"/car<mini>"},
+		},
+		{
+			// The constraint applies when the clause is fully specified by the table.
+			q:    `SELECT ?o FROM ?test WHERE {/u<joe> "knows"@[] ?o . /u<joe> "knows"@[] ?o TYPE /robot};`,
+			want: []string{"/robot<r2d2>"},
+		},
+		{
+			// The limit is not pushed down to the storage, since objects are dropped after fetching them.
+			q:    `SELECT ?o FROM ?test WHERE {?s ?p ?o TYPE /car} LIMIT "1"^^type:int64;`,
+			want: []string{"/car<mini>"},
+		},
+	}
 
-	// Load traversing data
 	s, ctx := memory.NewStore(), context.Background()
-	g, gErr := s.NewGraph(ctx, "?test")
-	if gErr != nil {
-		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
-	}
-	b := bytes.NewBufferString(traversalTriples)
-	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
-		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
-	}
-	p, pErr := grammar.NewParser(grammar.SemanticBQL())
-	if pErr != nil {
-		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	populateStoreWithTriples(ctx, s, "?test", typeTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?o"].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerClauseGuards(t *testing.T) {
+	const guardTriples = `/u<a>	"height_cm"@[]	"150"^^type:int64
+/u<b>	"height_cm"@[]	"170"^^type:int64
+/u<c>	"height_cm"@[]	"190"^^type:int64
+/u<c>	"height_cm"@[]	/u<unknown>
+/u<a>	"knows"@[]	/u<b>
+/u<a>	"knows"@[]	/u<c>
+/u<b>	"knows"@[]	/u<a>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h WHERE ?h > "160"^^type:int64} ORDER BY ?s;`,
+			want: []string{"/u<b>", "/u<c>"},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h WHERE ?h = "170"^^type:int64} ORDER BY ?s;`,
+			want: []string{"/u<b>"},
+		},
+		{
+			// The guard is applied when the clause is specified with the rows of the table.
+			q:    `SELECT ?s FROM ?test WHERE {/u<a> "knows"@[] ?s . ?s "height_cm"@[] ?h WHERE ?h < "180"^^type:int64} ORDER BY ?s;`,
+			want: []string{"/u<b>"},
+		},
+		{
+			// The guard is applied when the clause is fully specified by the table.
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . ?s "height_cm"@[] ?h WHERE ?h > "180"^^type:int64} ORDER BY ?s;`,
+			want: []string{"/u<c>"},
+		},
+		{
+			// Guards on optional clauses only leave their bindings empty.
+			q:    `SELECT ?s, ?h FROM ?test WHERE {/u<b> "knows"@[] ?s . OPTIONAL {?s "height_cm"@[] ?h WHERE ?h > "160"^^type:int64}} ORDER BY ?s;`,
+			want: []string{"/u<a> <NULL>"},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", guardTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerScientificFloats(t *testing.T) {
+	const floatTriples = `/u<a>	"mass"@[]	"1.5e10"^^type:float64
+/u<b>	"mass"@[]	"2e-10"^^type:float64
+/u<c>	"mass"@[]	"-3.5E+2"^^type:float64
+/u<d>	"mass"@[]	"1e-10"^^type:float64
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s, ?m FROM ?test WHERE {?s "mass"@[] ?m} ORDER BY ?m;`,
+			want: []string{"/u<c>", "/u<d>", "/u<b>", "/u<a>"},
+		},
+		{
+			q:    `SELECT ?s, ?m FROM ?test WHERE {?s "mass"@[] ?m} ORDER BY ?m DESC;`,
+			want: []string{"/u<a>", "/u<b>", "/u<d>", "/u<c>"},
+		},
+		{
+			q:    `SELECT ?s, ?m FROM ?test WHERE {?s "mass"@[] ?m} ORDER BY ?s HAVING ?m > "1.5e-10"^^type:float64;`,
+			want: []string{"/u<a>", "/u<b>"},
+		},
+		{
+			q:    `SELECT ?s, ?m FROM ?test WHERE {?s "mass"@[] ?m} HAVING ?m < "-1e2"^^type:float64;`,
+			want: []string{"/u<c>"},
+		},
+		{
+			q:    `SELECT ?s, ?m FROM ?test WHERE {?s "mass"@[] ?m} HAVING ?m = "15000000000"^^type:float64;`,
+			want: []string{"/u<a>"},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", floatTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerLang(t *testing.T) {
+	const langTriples = `/u<joe>	"greeting"@[]	"hola"^^type:text@es
+/u<mary>	"greeting"@[]	"hello"^^type:text@en-US
+/u<peter>	"greeting"@[]	"hello"^^type:text
+/u<peter>	"age"@[]	"37"^^type:int64
+`
+	testTable := []struct {
+		q     string
+		b     string
+		want  []string
+		nRows int
+	}{
+		{
+			q:     `SELECT ?s, LANG(?o) AS ?l FROM ?test WHERE {?s "greeting"@[] ?o} ORDER BY ?l;`,
+			b:     "?l",
+			want:  []string{`""^^type:text`, `"en-us"^^type:text`, `"es"^^type:text`},
+			nRows: 3,
+		},
+		{
+			q:     `SELECT ?s, ?o FROM ?test WHERE {?s ?p ?o . FILTER lang(?o, "ES"^^type:text)};`,
+			b:     "?o",
+			want:  []string{`"hola"^^type:text@es`},
+			nRows: 1,
+		},
+		{
+			q:     `SELECT ?s, ?o FROM ?test WHERE {?s ?p ?o . FILTER lang(?o, ""^^type:text)};`,
+			b:     "?o",
+			want:  []string{`"hello"^^type:text`},
+			nRows: 1,
+		},
+		{
+			q:     `SELECT ?s FROM ?test WHERE {?s "greeting"@[] ?o} HAVING ?o = "hello"^^type:text;`,
+			nRows: 2,
+		},
+		{
+			q:     `SELECT ?s FROM ?test WHERE {?s "greeting"@[] ?o} HAVING ?o = "hello"^^type:text@en-us;`,
+			nRows: 1,
+		},
+		{
+			q:     `SELECT ?s FROM ?test WHERE {?s "greeting"@[] "hola"^^type:text@es};`,
+			nRows: 1,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", langTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), entry.nRows; got != want {
+			t.Errorf("planner.Execute(%s) returned the wrong number of rows; got %d, want %d\n%s", entry.q, got, want, tbl)
+		}
+		if entry.b == "" {
+			continue
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[entry.b].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v for binding %q; want %v", entry.q, got, entry.b, entry.want)
+		}
+	}
+
+	// The language tag must be well formed, and only literals have tags.
+	parse := func(q string) *semantic.Statement {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		return st
+	}
+	q := `SELECT ?o FROM ?test WHERE {?s ?p ?o . FILTER lang(?o, "e$"^^type:text)};`
+	if _, err := New(ctx, s, parse(q), 0, 10, nil); err == nil {
+		t.Errorf("planner.New(%s) should have failed to create a query plan", q)
+	}
+	q = `SELECT LANG(?s) AS ?l FROM ?test WHERE {?s ?p ?o};`
+	plnr, err := New(ctx, s, parse(q), 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%s) should have failed to extract the language tag of a node", q)
+	}
+}
+
+func TestPlannerStringFunctions(t *testing.T) {
+	const textTriples = `/u<a>	"name"@[]	"España"^^type:text
+/u<b>	"name"@[]	"日本語"^^type:text@ja
+/u<c>	"name"@[]	"Zoë"^^type:text
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s, STRLEN(?n) AS ?len FROM ?test WHERE {?s "name"@[] ?n} ORDER BY ?s;`,
+			want: []string{`/u<a> "6"^^type:int64`, `/u<b> "3"^^type:int64`, `/u<c> "3"^^type:int64`},
+		},
+		{
+			q:    `SELECT ?s, SUBSTR(?n, "1"^^type:int64, "2"^^type:int64) AS ?sub FROM ?test WHERE {?s "name"@[] ?n} ORDER BY ?s;`,
+			want: []string{`/u<a> "sp"^^type:text`, `/u<b> "本語"^^type:text@ja`, `/u<c> "oë"^^type:text`},
+		},
+		{
+			q:    `SELECT ?s, SUBSTR(?n, "4"^^type:int64, "10"^^type:int64) AS ?sub FROM ?test WHERE {?s "name"@[] ?n} ORDER BY ?s;`,
+			want: []string{`/u<a> "ña"^^type:text`, `/u<b> ""^^type:text@ja`, `/u<c> ""^^type:text`},
+		},
+		{
+			q:    `SELECT ?s, STRLEN(?n) AS ?len FROM ?test WHERE {?s "name"@[] ?n} HAVING ?len > "3"^^type:int64;`,
+			want: []string{`/u<a> "6"^^type:int64`},
+		},
+		{
+			q:    `SELECT ?s, ?n FROM ?test WHERE {?s "name"@[] ?n} ORDER BY STRLEN(?n) DESC, ?s ASC;`,
+			want: []string{`/u<a> "España"^^type:text`, `/u<b> "日本語"^^type:text@ja`, `/u<c> "Zoë"^^type:text`},
+		},
+		{
+			q:    `SELECT ?s, ?n FROM ?test WHERE {?s "name"@[] ?n} ORDER BY STRLEN(?n), ?s DESC;`,
+			want: []string{`/u<c> "Zoë"^^type:text`, `/u<b> "日本語"^^type:text@ja`, `/u<a> "España"^^type:text`},
+		},
+		{
+			q:    `SELECT ?s, ?n FROM ?test WHERE {?s "name"@[] ?n} ORDER BY LANG(?n) DESC NULLS LAST, SUBSTR(?n, "0"^^type:int64, "1"^^type:int64);`,
+			want: []string{`/u<b> "日本語"^^type:text@ja`, `/u<a> "España"^^type:text`, `/u<c> "Zoë"^^type:text`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", textTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			bs := tbl.Bindings()
+			got = append(got, fmt.Sprintf("%s %s", r[bs[0]], r[bs[1]]))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerBindingTypes(t *testing.T) {
+	const mixedTriples = `/u<a>	"height"@[]	"180"^^type:int64
+/u<b>	"height"@[]	"tall"^^type:text
+/u<c>	"height"@[]	"175.5"^^type:float64
+/u<d>	"height"@[]	/u<unknown>
+/u<e>	"height"@[]	"165"^^type:int64
+`
+	testTable := []struct {
+		q       string
+		want    []string
+		wantErr bool
+	}{
+		{
+			q:    `SELECT ?s, ?h FROM ?test DECLARE ?h type:int64 WHERE {?s "height"@[] ?h} ORDER BY ?s;`,
+			want: []string{`/u<a> "180"^^type:int64`, `/u<e> "165"^^type:int64`},
+		},
+		{
+			q:    `SELECT ?s, ?h FROM ?test DECLARE ?h type:text WHERE {?s "height"@[] ?h} ORDER BY ?s;`,
+			want: []string{`/u<b> "tall"^^type:text`},
+		},
+		{
+			q:       `SELECT ?s, ?h FROM ?test DECLARE STRICT ?h type:int64 WHERE {?s "height"@[] ?h} ORDER BY ?s;`,
+			wantErr: true,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", mixedTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if entry.wantErr {
+			if err == nil {
+				t.Errorf("planner.Execute(%s) should have failed on mismatched binding types", entry.q)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			bs := tbl.Bindings()
+			got = append(got, fmt.Sprintf("%s %s", r[bs[0]], r[bs[1]]))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerHavingNonProjectedBindings(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h} ORDER BY ?s HAVING ?h > "160"^^type:int64;`,
+			want: []string{`/u<alice>`, `/u<charlie>`, `/u<delta>`},
+		},
+		{
+			q:    `SELECT ?s AS ?who FROM ?test WHERE {?s "height_cm"@[] ?h} HAVING ?h < "160"^^type:int64;`,
+			want: []string{`/u<bob>`},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . ?s "tag"@[] ?tag} HAVING (?h > "160"^^type:int64) AND (?tag = "abc"^^type:text);`,
+			want: []string{`/u<alice>`},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got := tbl.Bindings(); len(got) != 1 {
+			t.Fatalf("planner.Execute(%s) returned bindings %v; want only the projected one", entry.q, got)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[tbl.Bindings()[0]].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerOrderByAggregation(t *testing.T) {
+	const familyTriples = `/u<joe>	"parent_of"@[]	/u<mary>
+/u<joe>	"parent_of"@[]	/u<peter>
+/u<mary>	"parent_of"@[]	/u<ann>
+/u<peter>	"parent_of"@[]	/u<john>
+/u<peter>	"parent_of"@[]	/u<eve>
+/u<ada>	"parent_of"@[]	/u<tim>
+/u<tim>	"parent_of"@[]	/u<liz>
+/u<tim>	"parent_of"@[]	/u<bob>
+/u<tim>	"parent_of"@[]	/u<kim>
+/u<tim>	"parent_of"@[]	/u<sam>
+/u<bea>	"parent_of"@[]	/u<ned>
+/u<ned>	"parent_of"@[]	/u<ida>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `SELECT ?grandparent, COUNT(?grandchild) AS ?count
+			    FROM ?test
+			    WHERE {?grandparent "parent_of"@[] ?parent . ?parent "parent_of"@[] ?grandchild}
+			    GROUP BY ?grandparent
+			    ORDER BY ?count DESC;`,
+			want: []string{`/u<ada>:"4"^^type:int64`, `/u<joe>:"3"^^type:int64`, `/u<bea>:"1"^^type:int64`},
+		},
+		{
+			q: `SELECT ?grandparent, COUNT(?grandchild) AS ?count
+			    FROM ?test
+			    WHERE {?grandparent "parent_of"@[] ?parent . ?parent "parent_of"@[] ?grandchild}
+			    GROUP BY ?grandparent
+			    ORDER BY ?count ASC;`,
+			want: []string{`/u<bea>:"1"^^type:int64`, `/u<joe>:"3"^^type:int64`, `/u<ada>:"4"^^type:int64`},
+		},
+		{
+			q: `SELECT ?grandparent, COUNT(?grandchild) AS ?count
+			    FROM ?test
+			    WHERE {?grandparent "parent_of"@[] ?parent . ?parent "parent_of"@[] ?grandchild}
+			    GROUP BY ?grandparent
+			    ORDER BY ?count DESC
+			    LIMIT "2"^^type:int64;`,
+			want: []string{`/u<ada>:"4"^^type:int64`, `/u<joe>:"3"^^type:int64`},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", familyTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?grandparent"].String()+":"+r["?count"].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerSelfLoops(t *testing.T) {
+	const selfLoopTriples = `/room<Kitchen>	"connects_to"@[]	/room<Kitchen>
+/room<Bedroom>	"connects_to"@[]	/room<Bedroom>
+`
+	testTable := []struct {
+		q         string
+		selfLoops bool
+		want      []string
+	}{
+		{
+			q:    `SELECT ?x FROM ?test WHERE {?x "connects_to"@[] ?x};`,
+			want: nil,
+		},
+		{
+			q:    `SELECT ?x FROM ?test WHERE {?x ?p ?x};`,
+			want: nil,
+		},
+		{
+			q:         `SELECT ?x FROM ?test WHERE {?x "connects_to"@[] ?x} ORDER BY ?x;`,
+			selfLoops: true,
+			want:      []string{`/room<Bedroom>`, `/room<Kitchen>`},
+		},
+		{
+			q:         `SELECT ?x FROM ?test WHERE {?x ?p ?x} ORDER BY ?x LIMIT "1"^^type:int64;`,
+			selfLoops: true,
+			want:      []string{`/room<Bedroom>`},
+		},
+		{
+			q:         `SELECT ?x FROM ?test WHERE {/room<Kitchen> AS ?x "connects_to"@[] ?x};`,
+			selfLoops: true,
+			want:      []string{`/room<Kitchen>`},
+		},
+		{
+			q:         `SELECT ?x FROM ?test WHERE {/room<Hallway> AS ?x "connects_to"@[] ?x};`,
+			selfLoops: true,
+			want:      nil,
+		},
+		{
+			q:         `SELECT ?y FROM ?test WHERE {/room<Fire Escape> "connects_to"@[] ?y . ?y "connects_to"@[] ?y};`,
+			selfLoops: true,
+			want:      []string{`/room<Kitchen>`},
+		},
+	}
+
+	for _, entry := range testTable {
+		s, ctx := memory.NewStore(), context.Background()
+		data := tripleFromIssue40
+		if entry.selfLoops {
+			data += selfLoopTriples
+		}
+		populateStoreWithTriples(ctx, s, "?test", data, t)
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[tbl.Bindings()[0]].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerInlineAnchorComparisons(t *testing.T) {
+	const boughtTriples = `/u<a>	"bought"@[2016-01-01T00:00:00Z]	/c<x>
+/u<b>	"bought"@[2016-02-01T00:00:00Z]	/c<x>
+/u<c>	"bought"@[2016-03-01T00:00:00Z]	/c<x>
+/u<a>	"sold"@[2016-01-15T00:00:00Z]	/c<y>
+/u<b>	"sold"@[2016-02-15T00:00:00Z]	/c<y>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[> 2016-02-01T00:00:00Z] ?o} ORDER BY ?s;`,
+			want: []string{`/u<c>`},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[>= 2016-02-01T00:00:00Z] ?o} ORDER BY ?s;`,
+			want: []string{`/u<b>`, `/u<c>`},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[< 2016-02-01T00:00:00Z] ?o} ORDER BY ?s;`,
+			want: []string{`/u<a>`},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[<=2016-02-01T00:00:00Z] ?o} ORDER BY ?s;`,
+			want: []string{`/u<a>`, `/u<b>`},
+		},
+		{
+			// Each clause keeps its own bound.
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[< 2016-02-01T00:00:00Z] ?o . ?s "sold"@[> 2016-01-01T00:00:00Z] ?o2} ORDER BY ?s;`,
+			want: []string{`/u<a>`},
+		},
+		{
+			// Inline bounds are combined with the global ones.
+			q:    `SELECT ?s FROM ?test WHERE {?s "bought"@[> 2016-01-01T00:00:00Z] ?o} BEFORE 2016-02-15T00:00:00Z;`,
+			want: []string{`/u<b>`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", boughtTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerSharedAnchorBindings(t *testing.T) {
+	const anchorTriples = `/u<a>	"a"@[2016-01-01T00:00:00Z]	/u<x>
+/u<a>	"b"@[2016-01-01T00:00:00Z]	/u<y>
+/u<a>	"b"@[2017-01-01T00:00:00Z]	/u<z>
+/u<a>	"c"@[2015-12-31T16:00:00-08:00]	/u<w>
+/u<c>	"a"@[2018-01-01T00:00:00Z]	/u<x>
+/u<c>	"b"@[2019-01-01T00:00:00Z]	/u<y>
+/u<d>	"p"@[]	"b"@[2016-01-01T00:00:00Z]
+/u<d>	"p"@[]	"b"@[2018-06-01T00:00:00Z]
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s, ?o2 FROM ?test WHERE {?s "a"@[?t] ?o . ?s "b"@[?t] ?o2} ORDER BY ?s, ?o2;`,
+			want: []string{`/u<a> /u<y>`},
+		},
+		{
+			q:    `SELECT ?s, ?o2 FROM ?test WHERE {?s "a"@[?t] ?o . ?s ?p AT ?t ?o2} ORDER BY ?s, ?o2;`,
+			want: []string{`/u<a> /u<w>`, `/u<a> /u<x>`, `/u<a> /u<y>`, `/u<c> /u<x>`},
+		},
+		{
+			q:    `SELECT ?s, ?o2 FROM ?test WHERE {?s ?p AT ?t ?o2 . ?s "a"@[?t] ?o} ORDER BY ?s, ?o2;`,
+			want: []string{`/u<a> /u<w>`, `/u<a> /u<x>`, `/u<a> /u<y>`, `/u<c> /u<x>`},
+		},
+		{
+			q:    `SELECT ?s, ?d FROM ?test WHERE {?s "a"@[?t] ?o . ?d "p"@[] "b"@[?t]} ORDER BY ?s, ?d;`,
+			want: []string{`/u<a> /u<d>`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", anchorTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			bs := tbl.Bindings()
+			got = append(got, fmt.Sprintf("%s %s", r[bs[0]], r[bs[1]]))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+// traceCollector is a writer that signals every trace written to it.
+type traceCollector chan string
+
+func (c traceCollector) Write(p []byte) (int, error) {
+	select {
+	case c <- string(p):
+	default:
+	}
+	return len(p), nil
+}
+
+func TestPlannerTraceLevel(t *testing.T) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	// The planner traces at level 2, above the default global verbosity.
+	q := `TRACE "2"^^type:int64 SELECT ?s FROM ?test WHERE {?s ?p ?o};`
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	w := make(traceCollector, 1000)
+	plnr, err := New(ctx, s, st, 0, 10, w)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err != nil {
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+	}
+	select {
+	case <-w:
+	case <-time.After(5 * time.Second):
+		t.Errorf("planner.Execute(%s) did not write any trace", q)
+	}
+}
+
+func TestPlannerAnchors(t *testing.T) {
+	const anchorTriples = `/u<peter>	"bought"@[2016-03-01T00:00:00Z]	/c<mini>
+/u<peter>	"bought"@[2016-01-01T00:00:00Z]	/c<model_s>
+/u<peter>	"bought"@[2016-01-01T00:00:00Z]	/c<model_x>
+/u<peter>	"sold"@[2016-02-01T00:00:00Z]	/c<mini>
+/u<mary>	"bought"@[2016-02-01T00:00:00Z]	/c<mini>
+`
+	const moreAnchorTriples = `/u<peter>	"bought"@[2016-02-01T00:00:00Z]	/c<mini>
+/u<peter>	"bought"@[2016-03-01T00:00:00Z]	/c<model_x>
+`
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?a", anchorTriples, t)
+	populateStoreWithTriples(ctx, s, "?b", moreAnchorTriples, t)
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `ANCHORS(/u<peter>, "bought"@[?t]) FROM ?a;`,
+			want: []string{"2016-01-01T00:00:00Z", "2016-03-01T00:00:00Z"},
+		},
+		{
+			q:    `ANCHORS(/u<peter>, "bought"@[?t]) FROM ?a, ?b;`,
+			want: []string{"2016-01-01T00:00:00Z", "2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z"},
+		},
+		{
+			q:    `ANCHORS(/u<mary>, "sold"@[?t]) FROM ?a, ?b;`,
+			want: nil,
+		},
+	}
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := tbl.Bindings(), []string{"?t"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute(%s) returned bindings %v; want %v", entry.q, got, want)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			ta, ok := r["?t"].Time()
+			if !ok {
+				t.Fatalf("planner.Execute(%s) returned non time cell %v", entry.q, r["?t"])
+			}
+			got = append(got, ta.UTC().Format(time.RFC3339))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned anchors %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerKind(t *testing.T) {
+	const kindTriples = `/l<barcelona>	"predicate"@[]	"turned"@[2016-01-01T00:00:00-08:00]
+/l<barcelona>	"predicate"@[]	"immutable_predicate"@[]
+/u<joe>	"parent_of"@[]	/u<mary>
+/u<peter>	"bought"@[2016-01-01T00:00:00-08:00]	/c<mini>
+`
+	testTable := []struct {
+		q    string
+		b    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?o, KIND(?o) AS ?kind FROM ?test WHERE {/l<barcelona> "predicate"@[] ?o} ORDER BY ?kind;`,
+			b:    "?kind",
+			want: []string{`"immutable"^^type:text`, `"temporal"^^type:text`},
+		},
+		{
+			q:    `SELECT KIND(?p) AS ?kind FROM ?test WHERE {?s ?p ?o} ORDER BY ?kind;`,
+			b:    "?kind",
+			want: []string{`"immutable"^^type:text`, `"immutable"^^type:text`, `"immutable"^^type:text`, `"temporal"^^type:text`},
+		},
+		{
+			q:    `SELECT ?p, KIND(?p) AS ?kind FROM ?test WHERE {/u<peter> ?p ?o};`,
+			b:    "?kind",
+			want: []string{`"temporal"^^type:text`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", kindTriples, t)
+	parse := func(q string) *semantic.Statement {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		return st
+	}
+	for _, entry := range testTable {
+		plnr, err := New(ctx, s, parse(entry.q), 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[entry.b].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v for binding %q; want %v", entry.q, got, entry.b, entry.want)
+		}
+	}
+
+	// Only predicates have a kind.
+	q := `SELECT KIND(?s) AS ?kind FROM ?test WHERE {?s ?p ?o};`
+	plnr, err := New(ctx, s, parse(q), 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%s) should have failed to extract the kind of a node", q)
+	}
+}
+
+func TestPlannerDatatype(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?o, DATATYPE(?o) AS ?dt FROM ?test WHERE {/u<alice> ?p ?o} ORDER BY ?dt;`,
+			want: []string{`"174"^^type:int64 "int64"^^type:text`, `"abc"^^type:text "text"^^type:text`},
+		},
+		{
+			q:    `SELECT ?o, ?p FROM ?test WHERE {/u<alice> ?p ?o} ORDER BY DATATYPE(?o) DESC;`,
+			want: []string{`"abc"^^type:text "tag"@[]`, `"174"^^type:int64 "height_cm"@[]`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	parse := func(q string) *semantic.Statement {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		return st
+	}
+	for _, entry := range testTable {
+		plnr, err := New(ctx, s, parse(entry.q), 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			bs := tbl.Bindings()
+			got = append(got, fmt.Sprintf("%s %s", r[bs[0]], r[bs[1]]))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+
+	// Only literals have a datatype.
+	q := `SELECT DATATYPE(?s) AS ?dt FROM ?test WHERE {?s "tag"@[] ?o};`
+	plnr, err := New(ctx, s, parse(q), 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%s) should have failed to extract the datatype of a node", q)
+	}
+}
+
+func TestPlannerSelectAll(t *testing.T) {
+	testTable := []struct {
+		q, explicit string
+	}{
+		{
+			q:        `SELECT * FROM ?test;`,
+			explicit: `SELECT ?s, ?p, ?o FROM ?test WHERE {?s ?p ?o};`,
+		},
+		{
+			q:        `SELECT ?o FROM ?test;`,
+			explicit: `SELECT ?o FROM ?test WHERE {?s ?p ?o};`,
+		},
+		{
+			q:        `SELECT * FROM ?test WHERE {/u<alice> ?p ?o};`,
+			explicit: `SELECT ?p, ?o FROM ?test WHERE {/u<alice> ?p ?o};`,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", originalTriples, t)
+	run := func(q string) ([]string, []string) {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+		}
+		var rows []string
+		for _, r := range tbl.Rows() {
+			var b bytes.Buffer
+			if err := r.ToTextLine(&b, tbl.Bindings(), "\t"); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, b.String())
+		}
+		sort.Strings(rows)
+		return tbl.Bindings(), rows
+	}
+	for _, entry := range testTable {
+		gotBs, got := run(entry.q)
+		wantBs, want := run(entry.explicit)
+		if !reflect.DeepEqual(gotBs, wantBs) {
+			t.Errorf("planner.Execute(%s) returned bindings %v; want %v", entry.q, gotBs, wantBs)
+		}
+		if len(got) == 0 || !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute(%s) returned rows %v; want %v", entry.q, got, want)
+		}
+	}
+}
+
+func TestPlannerSelectDistinct(t *testing.T) {
+	const moods = `/u<peter> "mood"@[2016-01-01T00:00:00Z] /m<happy>
+		/u<peter> "mood"@[2016-02-01T00:00:00Z] /m<sad>
+		/u<peter> "mood"@[2016-03-01T00:00:00Z] /m<happy>
+		/u<peter> "mood"@[2016-04-01T00:00:00Z] /m<happy>
+		/u<peter> "mood"@[2016-05-01T00:00:00Z] /m<sad>
+		/u<peter> "mood"@[2016-06-01T00:00:00Z] /m<angry>
+		/u<mary> "mood"@[2016-01-01T00:00:00Z] /m<happy>
+		/u<mary> "mood"@[2016-02-01T00:00:00Z] /m<happy>
+		`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?o from ?test where {/u<peter> "mood"@[] ?o};`,
+			want: []string{"/m<angry>", "/m<happy>", "/m<happy>", "/m<happy>", "/m<sad>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?o from ?test where {/u<peter> "mood"@[] ?o};`,
+			want: []string{"/m<angry>", "/m<happy>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?o from ?test where {/u<peter> "mood"@[,] ?o};`,
+			want: []string{"/m<angry>", "/m<happy>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?o from ?test where {/u<peter> "mood"@[2016-02-01T00:00:00Z, 2016-04-01T00:00:00Z] ?o};`,
+			want: []string{"/m<happy>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?o from ?test where {?s "mood"@[,] ?o};`,
+			want: []string{"/m<angry>", "/m<happy>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?o from ?test where {/u<peter> "mood"@[] ?o} order by ?o desc limit "2"^^type:int64;`,
+			want: []string{"/m<happy>", "/m<sad>"},
+		},
+		{
+			q:    `select distinct ?s from ?test where {?s "mood"@[,] /m<happy>};`,
+			want: []string{"/u<mary>", "/u<peter>"},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", moods, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			for _, b := range tbl.Bindings() {
+				got = append(got, r[b].String())
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerDefaultTimeZone(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `select ?o from ?test where {/u<peter> "bought"@[2016-01-01T00:00:00] ?o};`,
+			want: []string{"/c<mini>"},
+		},
+		{
+			q:    `select ?o from ?test where {/u<peter> "bought"@[2016-02-01T00:00:00,2016-03-01T00:00:00-08:00] ?o};`,
+			want: []string{"/c<model s>", "/c<model x>"},
+		},
+		{
+			q:    `select ?o from ?test where {/u<peter> "bought"@[,] ?o} before 2016-01-31T23:59:59;`,
+			want: []string{"/c<mini>"},
+		},
+		{
+			q:    `select ?o from ?test where {/u<peter> "bought"@[,] ?o} between 2016-03-01T00:00:00, 2016-04-01T00:00:00;`,
+			want: []string{"/c<model x>", "/c<model y>"},
+		},
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	loc := time.FixedZone("PST", -8*60*60)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		st.SetDefaultTimeZone(loc)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?o"].String())
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+
+	// Without a default time zone bare timestamps are rejected.
+	for _, q := range []string{
+		`select ?o from ?test where {/u<peter> "bought"@[2016-02-01T00:00:00,2016-03-01T00:00:00-08:00] ?o};`,
+		`select ?o from ?test where {/u<peter> "bought"@[,] ?o} before 2016-01-31T23:59:59;`,
+	} {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		if err := p.Parse(grammar.NewLLk(q, 1), &semantic.Statement{}); err == nil {
+			t.Errorf("parser.Parse(%q) should have rejected timestamps without offset", q)
+		}
+	}
+}
+
+func TestPlannerContainsFilter(t *testing.T) {
+	const descriptionTriples = `/c<mini>	"description"@[2016-01-01T00:00:00-08:00]	"a tiny model"^^type:text
+/c<mini>	"description"@[2016-02-01T00:00:00-08:00]	"a tiny car"^^type:text
+/c<model s>	"description"@[2016-01-01T00:00:00-08:00]	"a sedan"^^type:text
+/c<model s>	"description"@[2016-02-01T00:00:00-08:00]	"a model s sedan"^^type:text
+/c<model s>	"seats"@[]	"5"^^type:int64
+`
+	testTable := []struct {
+		q     string
+		nRows int
+	}{
+		{
+			q: `SELECT ?c, ?d
+				FROM ?test
+				WHERE {
+					?c ?p ?d .
+					FILTER contains(?d, "model"^^type:text)
+				};`,
+			nRows: 2,
+		},
+		{
+			q: `SELECT ?c, ?d
+				FROM ?test
+				WHERE {
+					?c ?p ?d .
+					FILTER latest(?p) .
+					FILTER contains(?d, "model"^^type:text)
+				};`,
+			nRows: 1,
+		},
+		{
+			q: `SELECT ?c, ?d
+				FROM ?test
+				WHERE {
+					?c ?p ?d .
+					FILTER contains(?d, "5"^^type:text)
+				};`,
+			nRows: 0,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", descriptionTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := tbl.NumRows(), entry.nRows; got != want {
+			t.Errorf("planner.Execute(%s) returned the wrong number of rows; got %d, want %d\n%s", entry.q, got, want, tbl)
+		}
+	}
+
+	// The substring must be a text literal and the binding must exist.
+	for _, q := range []string{
+		`SELECT ?c FROM ?test WHERE {?c ?p ?d . FILTER contains(?d, "5"^^type:int64)};`,
+		`SELECT ?c FROM ?test WHERE {?c ?p ?d . FILTER contains(?unknown, "5"^^type:text)};`,
+	} {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		if _, err := New(ctx, s, st, 0, 10, nil); err == nil {
+			t.Errorf("planner.New(%s) should have failed to create a query plan", q)
+		}
+	}
+}
+
+func TestPlannerEqualityFilters(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?c FROM ?test WHERE {?p "parent_of"@[] ?c . FILTER eq(?c, /u<mary>)};`,
+			want: []string{"/u<mary>"},
+		},
+		{
+			q:    `SELECT ?c FROM ?test WHERE {?p "parent_of"@[] ?c . FILTER neq(?c, /u<mary>)} ORDER BY ?c;`,
+			want: []string{"/u<eve>", "/u<john>", "/u<peter>"},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . FILTER eq(?h, "174"^^type:int64)} ORDER BY ?s;`,
+			want: []string{"/u<alice>", "/u<charlie>", "/u<delta>"},
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . FILTER neq(?h, "174"^^type:int64)};`,
+			want: []string{"/u<bob>"},
+		},
+		{
+			// Literals of other types are never equal.
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . FILTER eq(?h, "174"^^type:text)};`,
+			want: nil,
+		},
+		{
+			q:    `SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . FILTER neq(?h, "174"^^type:int64) . FILTER neq(?s, /u<bob>)};`,
+			want: nil,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r[tbl.Bindings()[0]].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+
+	// The operand must be a valid node or literal.
+	for _, q := range []string{
+		`SELECT ?c FROM ?test WHERE {?p "parent_of"@[] ?c . FILTER eq(?c, /<mary>)};`,
+		`SELECT ?s FROM ?test WHERE {?s "height_cm"@[] ?h . FILTER eq(?h, "tall"^^type:int64)};`,
+	} {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		if _, err := New(ctx, s, st, 0, 10, nil); err == nil {
+			t.Errorf("planner.New(%s) should have failed to create a query plan", q)
+		}
+	}
+}
+
+func TestPlannerGroupByBucket(t *testing.T) {
+	const visitTriples = `/u<joe>	"visited"@[2016-04-10T04:21:00Z]	/l<a>
+/u<joe>	"visited"@[2016-04-10T04:45:00Z]	/l<b>
+/u<joe>	"visited"@[2016-04-10T05:10:00Z]	/l<c>
+/u<joe>	"visited"@[2016-04-11T01:00:00Z]	/l<a>
+/u<joe>	"visited"@[2016-05-02T01:00:00Z]	/l<b>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?t, COUNT(?l) AS ?n FROM ?test WHERE {/u<joe> "visited"@[?t] ?l} GROUP BY BUCKET(?t, "1h"^^type:text) ORDER BY ?t;`,
+			want: []string{"2016-04-10T04:00:00Z 2", "2016-04-10T05:00:00Z 1", "2016-04-11T01:00:00Z 1", "2016-05-02T01:00:00Z 1"},
+		},
+		{
+			q:    `SELECT ?t, COUNT(?l) AS ?n FROM ?test WHERE {/u<joe> "visited"@[?t] ?l} GROUP BY BUCKET(?t, "1d"^^type:text) ORDER BY ?t;`,
+			want: []string{"2016-04-10T00:00:00Z 3", "2016-04-11T00:00:00Z 1", "2016-05-02T00:00:00Z 1"},
+		},
+		{
+			q:    `SELECT ?t, COUNT(?l) AS ?n FROM ?test WHERE {/u<joe> "visited"@[?t] ?l} GROUP BY BUCKET(?t, "1mo"^^type:text) ORDER BY ?t;`,
+			want: []string{"2016-04-01T00:00:00Z 4", "2016-05-01T00:00:00Z 1"},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", visitTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			n, err := r["?n"].L.Int64()
+			if err != nil {
+				t.Fatalf("planner.Execute(%s) returned an invalid count in row %v: %v", entry.q, r, err)
+			}
+			got = append(got, fmt.Sprintf("%s %d", r["?t"].T.Format(time.RFC3339), n))
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+
+	// Bucketing a binding that does not hold time anchors should fail.
+	q := `SELECT ?l, COUNT(?t) AS ?n FROM ?test WHERE {/u<joe> "visited"@[?t] ?l} GROUP BY BUCKET(?l, "1h"^^type:text);`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+	}
+	if _, err := plnr.Execute(ctx); err == nil {
+		t.Errorf("planner.Execute(%s) should have failed bucketing a non time binding", q)
+	}
+}
+
+func TestPlannerQueryError(t *testing.T) {
+	testTable := []struct {
+		q string
+	}{
+		{
+			q: `SELECT ?s_id, ?height
+				FROM ?test
+				WHERE {
+					?s ID ?s_id "height_cm"@[] ?height
+				}
+				HAVING ?s_id > "37"^^type:int64;`,
+		},
+		{
+			q: `SELECT ?s_id, ?height
+				FROM ?test
+				WHERE {
+					?s ID ?s_id "height_cm"@[] ?height
+				}
+				HAVING CAST(?s_id, type:int64) > "37"^^type:int64;`,
+		},
+		{
+			q: `SELECT CAST(?s_id, type:int64) AS ?n
+				FROM ?test
+				WHERE {
+					?s ID ?s_id "height_cm"@[] ?height
+				};`,
+		},
+		{
+			q: `SELECT ?s_id, ?height
+				FROM ?test
+				WHERE {
+					?s ID ?s_id "height_cm"@[] ?height
+				}
+				HAVING ?s_id = /u<alice>;`,
+		},
+		{
+			q: `SELECT ?s, ?p, ?o
+				FROM ?test
+				WHERE {
+					?s ?p ?o
+				}
+				HAVING ?p < "height_cm"@[];`,
+		},
+		{
+			q: `SELECT ?s, ?p, ?o
+				FROM ?test
+				WHERE {
+					?s ?p ?o
+				}
+				HAVING ?p > "bought"@[2016-01-01T00:00:00-08:00];`,
+		},
+		{
+			q: `SELECT ?s, ?height
+				FROM ?test
+				WHERE {
+					?s "height_cm"@[] ?height
+				}
+				HAVING ?s < /u<zzzzz>;`,
+		},
+		{
+			q: `SELECT ?s, ?height
+				FROM ?test
+				WHERE {
+					?s "height_cm"@[] ?height
+				}
+				HAVING ?s > /u<alice>;`,
+		},
+		{
+			q: `SELECT ?p, ?o
+				FROM ?test
+				WHERE {
+					/u<peter> ?p ?o .
+					FILTER latest(?p) .
+					FILTER latest(?p)
+				};`,
+		},
+		{
+			q: `SELECT ?p, ?o
+				FROM ?test
+				WHERE {
+					/l<barcelona> ?p ?o .
+					FILTER latest(?p) .
+					FILTER latest(?o)
+				};`,
+		},
+		{
+			q: `SELECT ?p, ?o
+				FROM ?test
+				WHERE {
+					/u<peter> ?p ?o .
+					FILTER latest(?b_not_exist)
+				};`,
+		},
+		{
+			q: `SELECT ?s, ?p, ?o
+				FROM ?test
+				WHERE {
+					?s ID ?sID ?p ?o .
+					FILTER latest(?sID)
+				};`,
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
+		// Setup for test:
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+
+		// Actual test:
+		_, err = plnr.Execute(ctx)
+		if err == nil {
+			t.Errorf("planner.Execute(%s)\n= _, nil; want _, error", entry.q)
+		}
+	}
+}
+
+func TestPlannerConstructAddsCorrectNumberofTriples(t *testing.T) {
+	sts, dts := len(strings.Split(constructTestSrcTriples, "\n"))-1, len(strings.Split(constructTestDestTriples, "\n"))-1
+	testTable := []struct {
+		s    string
+		trps int
+	}{
+		{
+			s: `construct {?s ?p ?o}
+			    into ?dest
+			    from ?src
+			    where {?s ?p ?o};`,
+			trps: sts + dts,
+		},
+		{
+			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>}
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o};`,
+			// 3 matching triples * 4 new triples per matched triple due to reification + 1 triple in dest graph.
+			trps: 3*4 + dts,
+		},
+		{
+			s: `construct {?s "met"@[] ?o; "location"@[] /city<New York>;
+			                               "outcome"@[] "good"^^type:text }
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o};`,
+			// 3 matching triples * 5 new triples per matched triple due to reification + 1 triple in dest graph.
+			trps: 3*5 + dts,
+		},
+		{
+			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+			                                 "outcome"@[] "good"^^type:text .
+			               ?s "connected_to"@[] ?o}
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o.
+			           ?s "met_at"@[?t] ?o};`,
+			// 2 matching triples * (5 new triples due to reification + 1 explicitly constructed triple per matched triple) +
+			// 1 triple in dest graph.
+			trps: 2*6 + dts,
+		},
+		{
+			s: `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+			                                 "outcome"@[] "good"^^type:text .
+			               ?s "connected_to"@[] ?o; "at"@[?t] /city<New York> }
+			    into ?dest
+			    from ?src
+			    where {?s "met"@[] ?o.
+			           ?s "met_at"@[?t] ?o};`,
+			// 2 matching triples * 9 new triples due to reification + 1 triple in dest graph.
+			trps: 2*9 + dts,
+		},
+		{
+			s: `construct {?d2 "is_2_hops_from"@[] ?s1 }
+			    into ?dest
+			    from ?src
+			    where {?s1 "is_connected_to"@[] ?d1.
+			           ?d1 "is_connected_to"@[] ?d2};`,
+			// 2 new triples (/city<A> "is_2_hops_from"@[] /city<D>, /city<A> "is_2_hops_from"@[] /city<E>) +  1 triple in dest graph.
+			trps: 3,
+		},
+	}
+
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+		populateStoreWithTriples(ctx, s, "?dest", constructTestDestTriples, t)
+
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
+			continue
+		}
+
+		g, err := s.Graph(ctx, "?dest")
+		if err != nil {
+			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		}
+
+		i := 0
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+		for range ts {
+			i++
+		}
+		if i != entry.trps {
+			t.Errorf("g.Triples should have returned %v triples, returned %v instead", entry.trps, i)
+		}
+	}
+
+}
+
+func TestPlannerConstructAddsCorrectTriples(t *testing.T) {
+	bql := `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>;
+	                                     "outcome"@[] "good"^^type:text.
+	                   ?s "connected_to"@[] ?o }
+	        into ?dest
+	        from ?src
+	        where {?s "met"@[] ?o.
+		       ?s "met_at"@[?t] ?o};`
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+	}
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+	populateStoreWithTriples(ctx, s, "?dest", "", t)
+
+	// Make the reified blank nodes predictable.
+	old := node.SetUUIDSource(node.SequentialUUIDSource())
+	defer node.SetUUIDSource(old)
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+		t.Errorf("Parser.consume: failed to parse query %q with error %v", bql, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	_, err = plnr.Execute(ctx)
+	if err != nil {
+		t.Errorf("planner.Execute failed for query %q with error %v", bql, err)
+	}
+
+	g, err := s.Graph(ctx, "?dest")
+	if err != nil {
+		t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+	}
+
+	ts := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	b1, b2 := `/_<00000000-0000-0000-0000-000000000001>`, `/_<00000000-0000-0000-0000-000000000002>`
+	dtm := map[string]bool{
+		fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"connected_to"@[]`, `/person<B>`):                           false,
+		fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"connected_to"@[]`, `/person<C>`):                           false,
+		fmt.Sprintf("%s\t%s\t%s", b1, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<A>`):                     false,
+		fmt.Sprintf("%s\t%s\t%s", b1, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
+		fmt.Sprintf("%s\t%s\t%s", b1, `"_object"@[2016-04-10T04:25:00Z]`, `/person<B>`):                      false,
+		fmt.Sprintf("%s\t%s\t%s", b1, `"location"@[]`, `/city<New York>`):                                    false,
+		fmt.Sprintf("%s\t%s\t%s", b1, `"outcome"@[]`, `"good"^^type:text`):                                   false,
+		fmt.Sprintf("%s\t%s\t%s", b2, `"_subject"@[2016-04-10T04:25:00Z]`, `/person<B>`):                     false,
+		fmt.Sprintf("%s\t%s\t%s", b2, `"_predicate"@[2016-04-10T04:25:00Z]`, `"met"@[2016-04-10T04:25:00Z]`): false,
+		fmt.Sprintf("%s\t%s\t%s", b2, `"_object"@[2016-04-10T04:25:00Z]`, `/person<C>`):                      false,
+		fmt.Sprintf("%s\t%s\t%s", b2, `"location"@[]`, `/city<New York>`):                                    false,
+		fmt.Sprintf("%s\t%s\t%s", b2, `"outcome"@[]`, `"good"^^type:text`):                                   false,
+	}
+	n := 0
+	for elem := range ts {
+		n++
+		if _, ok := dtm[elem.String()]; !ok {
+			t.Errorf("unexpected triple: %v added to graph", elem)
+		}
+		dtm[elem.String()] = true
+	}
+	if n != len(dtm) {
+		t.Errorf("g.Triples should have returned %v triples, returned %v instead", len(dtm), n)
+	}
+	for k, v := range dtm {
+		if !v {
+			t.Errorf("g.Triples did not return triple: %v", k)
+		}
+	}
+}
+
+func TestPlannerConstructDeterministicBlankNodes(t *testing.T) {
+	bql := `construct {?s "met"@[?t] ?o; "location"@[] /city<New York>}
+	        into ?dest
+	        from ?src
+	        where {?s "met"@[] ?o.
+	               ?s "met_at"@[?t] ?o};`
+	ctx := context.Background()
+	construct := func(s storage.Store) []string {
+		st := &semantic.Statement{}
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+			t.Fatalf("Parser.consume: failed to parse query %q with error %v", bql, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{DeterministicBlankNodes: true})
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+		}
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", bql, err)
+		}
+		g, err := s.Graph(ctx, "?dest")
+		if err != nil {
+			t.Fatalf("s.Graph(%q) failed with error %v", "?dest", err)
+		}
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+		var got []string
+		for trpl := range ts {
+			got = append(got, trpl.String())
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	s1, s2 := memory.NewStore(), memory.NewStore()
+	for _, s := range []storage.Store{s1, s2} {
+		populateStoreWithTriples(ctx, s, "?src", constructTestSrcTriples, t)
+		populateStoreWithTriples(ctx, s, "?dest", "", t)
+	}
+	first := construct(s1)
+	// 2 matching rows * 4 new triples due to reification.
+	if got, want := len(first), 2*4; got != want {
+		t.Fatalf("construct added %d triples; want %d", got, want)
+	}
+	// Running it again reuses the same blank nodes instead of adding new ones.
+	if got := construct(s1); !reflect.DeepEqual(got, first) {
+		t.Errorf("construct run twice returned %v; want %v", got, first)
+	}
+	// Other stores with the same data get the same blank nodes.
+	if got := construct(s2); !reflect.DeepEqual(got, first) {
+		t.Errorf("construct on another store returned %v; want %v", got, first)
+	}
+}
+
+func TestPlannerDeconstructRemovesCorrectTriples(t *testing.T) {
+	testTable := []struct {
+		s    string
+		trps []string
+	}{
+		{
+			s: `deconstruct {?p1 "met"@[] ?p2}
+			    in ?dest
+			    from ?src
+			    where {?p1 "lives_in"@[] /city<A>.
+				   ?p2 "lives_in"@[] /city<B>};`,
+			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<D>`, `"met"@[]`, `/person<A>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+		},
+		{
+			s: `deconstruct {?p1 "met"@[] ?p2.
+		                         ?p2 "met"@[] ?p1}
+			    in ?dest
+			    from ?src
+			    where {?p1 "lives_in"@[] /city<A>.
+				   ?p2 "lives_in"@[] /city<B>};`,
+			trps: []string{fmt.Sprintf("%s\t%s\t%s", `/person<A>`, `"met"@[]`, `/person<C>`),
+				fmt.Sprintf("%s\t%s\t%s", `/person<B>`, `"met"@[]`, `/person<D>`)},
+		},
+	}
+
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Errorf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+
+		s, ctx := memory.NewStore(), context.Background()
+		populateStoreWithTriples(ctx, s, "?src", deconstructTestSrcTriples, t)
+		populateStoreWithTriples(ctx, s, "?dest", deconstructTestDestTriples, t)
+
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.s, 1), st); err != nil {
+			t.Errorf("Parser.consume: failed to parse query %q with error %v", entry.s, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if err != nil {
+			t.Errorf("planner.Execute failed for query %q with error %v", entry.s, err)
+			continue
+		}
+
+		g, err := s.Graph(ctx, "?dest")
+		if err != nil {
+			t.Errorf("memory.DefaultStore.Graph(%q) should have not fail with error %v", "?test", err)
+		}
+
+		ts := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		dt := make(map[string]bool)
+		for _, trp := range entry.trps {
+			dt[trp] = false
+		}
+
+		i := 0
+		for trp := range ts {
+			if val, ok := dt[trp.String()]; ok {
+				if !val {
+					i++
+				}
+				dt[trp.String()] = true
+			} else {
+				t.Errorf("unexpected triple: %v added to graph", trp)
+			}
+		}
+		if i != len(entry.trps) {
+			t.Errorf("g.Triples did not return some of the triples.")
+		}
+	}
+
+}
+
+func TestTreeTraversalToRoot(t *testing.T) {
+	// Graph traversal data.
+	traversalTriples := `/person<Gavin Belson>  "born in"@[]    /city<Springfield>
+		/person<Gavin Belson>  "parent of"@[]  /person<Peter Belson>
+		/person<Gavin Belson>  "parent of"@[]  /person<Mary Belson>
+		/person<Mary Belson>   "parent of"@[]  /person<Amy Schumer>
+		/person<Mary Belson>   "parent of"@[]  /person<Joe Schumer>`
+
+	traversalQuery := `SELECT ?grandparent
+		                 FROM ?test
+										 WHERE {
+										   ?s "parent of"@[] /person<Amy Schumer> .
+											 ?grandparent "parent of"@[] ?s
+										 };`
+
+	// Load traversing data
+	s, ctx := memory.NewStore(), context.Background()
+	g, gErr := s.NewGraph(ctx, "?test")
+	if gErr != nil {
+		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	}
+	b := bytes.NewBufferString(traversalTriples)
+	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
+		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+	}
+	p, pErr := grammar.NewParser(grammar.SemanticBQL())
+	if pErr != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
+		t.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+	}
+	if got, want := len(tbl.Bindings()), 1; got != want {
+		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+	}
+	if got, want := tbl.NumRows(), 1; got != want {
+		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+	}
+}
+
+func TestChaining(t *testing.T) {
+	// Graph traversal data.
+	traversalTriples := `/u<joe> "parent_of"@[] /u<mary>
+		/u<joe> "parent_of"@[] /u<peter>
+		/u<peter> "parent_of"@[] /u<john>
+		/u<peter> "parent_of"@[] /u<eve>`
+
+	traversalQuery := `SELECT ?o FROM ?test
+	                   WHERE {
+	                       /u<joe> "parent_of"@[] ?o .
+		                   ?o "parent_of"@[] /u<john>
+	                   };`
+
+	// Load traversing data
+	s, ctx := memory.NewStore(), context.Background()
+	g, gErr := s.NewGraph(ctx, "?test")
+	if gErr != nil {
+		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	}
+	b := bytes.NewBufferString(traversalTriples)
+	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
+		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+	}
+	p, pErr := grammar.NewParser(grammar.SemanticBQL())
+	if pErr != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
+		t.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+	}
+	if got, want := len(tbl.Bindings()), 1; got != want {
+		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+	}
+	if got, want := tbl.NumRows(), 1; got != want {
+		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+	}
+}
+
+func BenchmarkChaining(b *testing.B) {
+	// Graph traversal data.
+	traversalTriples := `/u<joe> "parent_of"@[] /u<mary>
+		/u<joe> "parent_of"@[] /u<peter>
+		/u<peter> "parent_of"@[] /u<john>
+		/u<peter> "parent_of"@[] /u<eve>`
+
+	traversalQuery := `SELECT ?o FROM ?test
+	                   WHERE {
+	                       /u<joe> "parent_of"@[] ?o .
+		                   ?o "parent_of"@[] /u<john>
+	                   };`
+
+	// Load traversing data
+	s, ctx := memory.NewStore(), context.Background()
+	g, gErr := s.NewGraph(ctx, "?test")
+	if gErr != nil {
+		b.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	}
+	buf := bytes.NewBufferString(traversalTriples)
+	if _, err := io.ReadIntoGraph(ctx, g, buf, literal.DefaultBuilder()); err != nil {
+		b.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+	}
+	for n := 0; n < b.N; n++ {
+		p, pErr := grammar.NewParser(grammar.SemanticBQL())
+		if pErr != nil {
+			b.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
+			b.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			b.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			b.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+		}
+		if got, want := len(tbl.Bindings()), 1; got != want {
+			b.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+		}
+		if got, want := tbl.NumRows(), 1; got != want {
+			b.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+		}
+	}
+}
+
+// Test to validate https://github.com/google/badwolf/issues/70
+func TestReificationResolutionIssue70(t *testing.T) {
+	// Graph traversal data.
+	issue70Triples := `/_<c175b457-e6d6-4ce3-8312-674353815720>	"_predicate"@[]	"/some/immutable/id"@[]
+		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_owner"@[2017-05-23T16:41:12.187373-07:00]	/gid<0x9>
+		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_subject"@[]	/aid</some/subject/id>
+		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_object"@[]	/aid</some/object/id>
+		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_object"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>
+		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_owner"@[2017-05-23T16:41:12.187373-07:00]	/gid<0x6>
+		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_predicate"@[2017-05-23T16:41:12.187373-07:00]	"/some/temporal/id"@[2017-05-23T16:41:12.187373-07:00]
+		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_subject"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/subject/id>
+		/aid</some/subject/id>	"/some/temporal/id"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>
+		/aid</some/subject/id>	"/some/immutable/id"@[]	/aid</some/object/id>
+		/aid</some/subject/id>	"/some/ownerless_temporal/id"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>`
+
+	query := `
+		SELECT ?bn, ?p
+		FROM ?test
+		WHERE {
+			?bn "_subject"@[,]   /aid</some/subject/id>.
+			?bn "_predicate"@[,] ?p .
+			?bn "_object"@[,]    /aid</some/object/id>
+		};`
+
+	// Load traversing data
+	s, ctx := memory.NewStore(), context.Background()
+	g, gErr := s.NewGraph(ctx, "?test")
+	if gErr != nil {
+		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	}
+	b := bytes.NewBufferString(issue70Triples)
+	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
+		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+	}
+	p, pErr := grammar.NewParser(grammar.SemanticBQL())
+	if pErr != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(query, 1), st); err != nil {
+		t.Errorf("Parser.consume: failed to parse query %q with error %v", query, err)
+	}
+	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if err != nil {
+		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for query %q with error %v", query, err)
+	}
+	if got, want := len(tbl.Bindings()), 2; got != want {
+		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", query, got, want)
+	}
+	if got, want := tbl.NumRows(), 1; got != want {
+		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", query, got, want, tbl)
+	}
+}
+
+// benchmarkQuery is a helper function that runs a specified query on the testing data set for benchmarking purposes.
+func benchmarkQuery(query string, b *testing.B) {
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			b.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(query, 1), st); err != nil {
+			b.Errorf("Parser.consume: failed to parse query %q with error %v", query, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			b.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		}
+		_, err = plnr.Execute(ctx)
+		if err != nil {
+			b.Errorf("planner.Execute failed for query %q with error %v", query, err)
+		}
+	}
+}
+
+// These benchmark tests are used to observe the difference in speed between queries using the "as" keyword as opposed
+// to queries that do not.
+func BenchmarkReg1(b *testing.B) {
+	benchmarkQuery(`select ?p, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`, b)
+}
+
+func BenchmarkAs1(b *testing.B) {
+	benchmarkQuery(`select ?p as ?p1, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`, b)
+}
+
+func BenchmarkReg2(b *testing.B) {
+	benchmarkQuery(`select ?s, ?p, ?o from ?test where {?s ?p ?o};`, b)
+}
+
+func BenchmarkAs2(b *testing.B) {
+	benchmarkQuery(`select ?s as ?s1, ?p as ?p1, ?o as ?o1 from ?test where {?s ?p ?o};`, b)
+}
+
+// chainedGraph returns a store with a graph containing n people, each of them
+// knowing the next one and liking a thing.
+func chainedGraph(n int, tb testing.TB) (storage.Store, context.Context) {
+	s, ctx := memory.NewStore(), context.Background()
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		tb.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", err)
+	}
+	var ts []*triple.Triple
+	for i := 0; i < n; i++ {
+		for _, l := range []string{
+			fmt.Sprintf("/u<p%d>\t\"knows\"@[]\t/u<p%d>", i, (i+1)%n),
+			fmt.Sprintf("/u<p%d>\t\"likes\"@[]\t/t<t%d>", i, i%10),
+		} {
+			t, err := triple.Parse(l, literal.DefaultBuilder())
+			if err != nil {
+				tb.Fatalf("triple.Parse failed to parse %q with error %v", l, err)
+			}
+			ts = append(ts, t)
+		}
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		tb.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	return s, ctx
+}
+
+const chainedQuery = `SELECT ?s, ?o, ?t FROM ?test WHERE {?s "knows"@[] ?o . ?o "likes"@[] ?t};`
+
+func executeChainedQuery(ctx context.Context, s storage.Store, parallelism int, tb testing.TB) *table.Table {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		tb.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(chainedQuery, 1), st); err != nil {
+		tb.Fatalf("parser.Parse failed for query %q with error %v", chainedQuery, err)
+	}
+	plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{Parallelism: parallelism})
+	if err != nil {
+		tb.Fatalf("planner.NewWithOptions failed to create a valid query plan with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		tb.Fatalf("planner.Execute failed for query %q with error %v", chainedQuery, err)
+	}
+	return tbl
+}
+
+func TestPlannerParallelism(t *testing.T) {
+	const n = 100
+	s, ctx := chainedGraph(n, t)
+	for _, parallelism := range []int{0, 1, 3, n * 2} {
+		if got, want := executeChainedQuery(ctx, s, parallelism, t).NumRows(), n; got != want {
+			t.Errorf("planner.Execute with parallelism %d returned %d rows; want %d", parallelism, got, want)
+		}
+	}
+}
+
+func TestForEachRowStopsOnError(t *testing.T) {
+	for _, parallelism := range []int{1, 4} {
+		p := &queryPlan{parallelism: parallelism}
+		var rws []table.Row
+		for i := 0; i < 100; i++ {
+			rws = append(rws, table.Row{})
+		}
+		var (
+			mu  sync.Mutex
+			cnt int
+		)
+		err := p.forEachRow(context.Background(), rws, func(ctx context.Context, r table.Row) error {
+			mu.Lock()
+			defer mu.Unlock()
+			cnt++
+			if cnt == 10 {
+				return errors.New("row error")
+			}
+			return nil
+		})
+		if err == nil {
+			t.Errorf("forEachRow with parallelism %d should have returned the row error", parallelism)
+		}
+		if cnt >= len(rws) {
+			t.Errorf("forEachRow with parallelism %d processed all %d rows after an error", parallelism, cnt)
+		}
+	}
+}
+
+// BenchmarkSpecifyClauseWithTable compares processing a large intermediate
+// table with bounded worker pools against one worker per row.
+func BenchmarkSpecifyClauseWithTable(b *testing.B) {
+	const n = 10000
+	s, ctx := chainedGraph(n, b)
+	for _, bc := range []struct {
+		name        string
+		parallelism int
+	}{
+		{"sequential", 1},
+		{"gomaxprocs", runtime.GOMAXPROCS(0)},
+		{"one_per_row", n},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				executeChainedQuery(ctx, s, bc.parallelism, b)
+			}
+		})
+	}
+}
+
+func TestPlannerClearStore(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	for _, g := range []string{"?a", "?b"} {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("memory.NewGraph(%q) failed with error %v", g, err)
+		}
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk("CLEAR STORE;", 1), st); err != nil {
+		t.Fatalf("parser.Parse failed with error: %v", err)
+	}
+	if _, err := New(ctx, s, st, 0, 10, nil); err == nil {
+		t.Errorf("planner.New should have rejected CLEAR STORE without allowing administrative statements")
+	}
+	pln, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{AllowAdmin: true})
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed to create a valid plan with error: %v", err)
+	}
+	if got, want := pln.Type(), "CLEAR"; got != want {
+		t.Errorf("planner.NewWithOptions returned a plan of type %q; want %q", got, want)
+	}
+	if _, err := pln.Execute(ctx); err != nil {
+		t.Fatalf("planner.Execute failed with error: %v", err)
+	}
+	names := make(chan string, 2)
+	if err := s.GraphNames(ctx, names); err != nil {
+		t.Fatalf("memory.GraphNames failed with error %v", err)
+	}
+	for n := range names {
+		t.Errorf("graph %q should have been removed by CLEAR STORE", n)
+	}
+}
+
+func TestPlannerMaterializedViews(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?a"); err != nil {
+		t.Fatalf("memory.NewGraph(%q) failed with error %v", "?a", err)
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	run := func(bql string) (*table.Table, error) {
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+			t.Fatalf("parser.Parse(%q) failed with error: %v", bql, err)
+		}
+		pln, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New(%q) failed to create a valid plan with error: %v", bql, err)
+		}
+		return pln.Execute(ctx)
+	}
+	rows := func(bql string) int {
+		tbl, err := run(bql)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error: %v", bql, err)
+		}
+		return tbl.NumRows()
+	}
+	rows(`insert data into ?a {/u<joe> "knows"@[] /u<mary>};`)
+	rows(`create view ?knows as select ?s, ?o from ?a where {?s "knows"@[] ?o};`)
+	if got, want := rows(`show view ?knows;`), 1; got != want {
+		t.Errorf("SHOW VIEW returned %d rows; want %d", got, want)
+	}
+	if _, err := run(`create view ?knows as select ?s from ?a where {?s "knows"@[] ?o};`); err == nil {
+		t.Errorf("planner.Execute should have failed to create an already existing view")
+	}
+	rows(`insert data into ?a {/u<mary> "knows"@[] /u<peter>};`)
+	if got, want := rows(`show view ?knows;`), 1; got != want {
+		t.Errorf("SHOW VIEW returned %d rows before refreshing; want %d", got, want)
+	}
+	rows(`refresh view ?knows;`)
+	if got, want := rows(`show view ?knows;`), 2; got != want {
+		t.Errorf("SHOW VIEW returned %d rows after refreshing; want %d", got, want)
+	}
+	rows(`drop view ?knows;`)
+	for _, bql := range []string{`show view ?knows;`, `refresh view ?knows;`, `drop view ?knows;`} {
+		if _, err := run(bql); err == nil {
+			t.Errorf("planner.Execute(%q) should have failed for a dropped view", bql)
+		}
+	}
+}
+
+func TestPlannerStreamInsertData(t *testing.T) {
+	const bql = `insert data into ?a, ?b {/u<joe> "knows"@[] /u<mary> .
+	                                     /u<joe> "knows"@[] /u<peter> .
+	                                     /u<mary> "knows"@[] /u<peter> .
+	                                     /u<peter> "knows"@[] /u<john> .
+	                                     /u<john> "knows"@[] /u<mary>};`
+	ctx := context.Background()
+	count := func(s storage.Store, gID string) int {
+		g, err := s.Graph(ctx, gID)
+		if err != nil {
+			t.Fatalf("store.Graph(%q) failed with error %v", gID, err)
+		}
+		ts := make(chan *triple.Triple, 10)
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for range ts {
+			n++
+		}
+		return n
+	}
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+	}
+	for _, entry := range []struct {
+		bulkSize     int
+		wantStreamed int
+		wantData     int
+	}{
+		{bulkSize: 2, wantStreamed: 4, wantData: 1},
+		{bulkSize: 5, wantStreamed: 5, wantData: 0},
+		{bulkSize: 10, wantStreamed: 0, wantData: 5},
+	} {
+		s := memory.NewStore()
+		for _, g := range []string{"?a", "?b"} {
+			if _, err := s.NewGraph(ctx, g); err != nil {
+				t.Fatalf("memory.NewGraph(%q) failed with error %v", g, err)
+			}
+		}
+		st := &semantic.Statement{}
+		if err := StreamInsertData(ctx, s, st, entry.bulkSize, nil); err != nil {
+			t.Fatalf("planner.StreamInsertData failed with error: %v", err)
+		}
+		if err := p.Parse(grammar.NewLLk(bql, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed with error: %v", err)
+		}
+		if got, want := st.StreamedData(), entry.wantStreamed; got != want {
+			t.Errorf("statement.StreamedData() for bulk size %d returned %d; want %d", entry.bulkSize, got, want)
+		}
+		if got, want := len(st.Data()), entry.wantData; got != want {
+			t.Errorf("statement.Data() for bulk size %d returned %d triples; want %d", entry.bulkSize, got, want)
+		}
+		for _, g := range []string{"?a", "?b"} {
+			if got, want := count(s, g), entry.wantStreamed; got != want {
+				t.Errorf("graph %q for bulk size %d contains %d triples after parsing; want %d", g, entry.bulkSize, got, want)
+			}
+		}
+		pln, err := New(ctx, s, st, 0, entry.bulkSize, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid plan with error: %v", err)
+		}
+		if _, err := pln.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute failed with error: %v", err)
+		}
+		for _, g := range []string{"?a", "?b"} {
+			if got, want := count(s, g), 5; got != want {
+				t.Errorf("graph %q for bulk size %d contains %d triples after execution; want %d", g, entry.bulkSize, got, want)
+			}
+		}
+	}
+
+	// Streamed batches of statements inserting if absent count the triples added.
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?a", "/u<joe>\t\"knows\"@[]\t/u<mary>\n", t)
+	if _, err := s.NewGraph(ctx, "?b"); err != nil {
+		t.Fatalf("memory.NewGraph(%q) failed with error %v", "?b", err)
+	}
+	absent := strings.Replace(bql, "into ?a, ?b {", "into ?a, ?b if absent {", 1)
+	st := &semantic.Statement{}
+	if err := StreamInsertData(ctx, s, st, 2, nil); err != nil {
+		t.Fatalf("planner.StreamInsertData failed with error: %v", err)
+	}
+	if err := p.Parse(grammar.NewLLk(absent, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed with error: %v", err)
+	}
+	pln, err := New(ctx, s, st, 0, 2, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid plan with error: %v", err)
+	}
+	tbl, err := pln.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed with error: %v", err)
+	}
+	r, ok := tbl.Row(0)
+	if !ok {
+		t.Fatalf("planner.Execute(%q) returned no affected count", absent)
+	}
+	if got, err := r[AffectedBinding].L.Int64(); err != nil || got != 9 {
+		t.Errorf("planner.Execute(%q) affected %d triples, %v; want 9", absent, got, err)
+	}
+
+	// Streaming fails the parsing if the output graphs do not exist.
+	st = &semantic.Statement{}
+	if err := StreamInsertData(ctx, memory.NewStore(), st, 2, nil); err != nil {
+		t.Fatalf("planner.StreamInsertData failed with error: %v", err)
+	}
+	if err := p.Parse(grammar.NewLLk(bql, 1), st); err == nil {
+		t.Errorf("parser.Parse should have failed to stream data into missing graphs")
+	}
+
+	// Batches flushed before a parsing error stay inserted.
+	s = memory.NewStore()
+	if _, err := s.NewGraph(ctx, "?a"); err != nil {
+		t.Fatalf("memory.NewGraph(%q) failed with error %v", "?a", err)
+	}
+	broken := `insert data into ?a {/u<joe> "knows"@[] /u<mary> .
+	                                /u<joe> "knows"@[] /u<peter> .
+	                                /u<mary> "knows"@[] /u<peter> .
+	                                /u<peter> "knows"@[] };`
+	st = &semantic.Statement{}
+	if err := StreamInsertData(ctx, s, st, 2, nil); err != nil {
+		t.Fatalf("planner.StreamInsertData failed with error: %v", err)
+	}
+	if err := p.Parse(grammar.NewLLk(broken, 1), st); err == nil {
+		t.Fatalf("parser.Parse should have failed to parse %q", broken)
+	}
+	if got, want := st.StreamedData(), 2; got != want {
+		t.Errorf("statement.StreamedData() after a parsing error returned %d; want %d", got, want)
+	}
+	if got, want := count(s, "?a"), 2; got != want {
+		t.Errorf("graph %q contains %d triples after a parsing error; want %d", "?a", got, want)
+	}
+
+	// Explained statements cannot be streamed.
+	st = &semantic.Statement{}
+	st.SetExplain()
+	if err := StreamInsertData(ctx, s, st, 2, nil); err == nil {
+		t.Errorf("planner.StreamInsertData should have rejected an explained statement")
+	}
+}
+
+func TestPlannerDumpRoundTrip(t *testing.T) {
+	const dumpTriples = `/u<joe>	"is"@[]	"true"^^type:bool
+/u<joe>	"age"@[]	"42"^^type:int64
+/u<joe>	"height_m"@[]	"1.8543"^^type:float64
+/u<joe>	"name"@[]	"Joe Doe"^^type:text
+/u<joe>	"avatar"@[]	"[1 2 3]"^^type:blob
+/u<joe>	"tags"@[]	"["a"^^type:text, "1"^^type:int64]"^^type:list
+/u<joe>	"met"@[2016-01-01T00:00:00.123456789-08:00]	/u<mary>
+/_<bn>	"_predicate"@[]	"met"@[2016-01-01T00:00:00.123456789-08:00]
+`
+	ctx := context.Background()
+	src := memory.NewStore()
+	populateStoreWithTriples(ctx, src, "?test", testTriples+dumpTriples, t)
+
+	parse := func(q string) *semantic.Statement {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for statement %q with error: %v", q, err)
+		}
+		return st
+	}
+	srcG, err := src.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Text values that cannot be written as triple files are added directly.
+	texts := []string{"line 1\nline 2", `C:\dir\file`, `he said \"hi\"`}
+	for _, text := range texts {
+		if err := srcG.AddTriples(ctx, []*triple.Triple{textTriple(text, t)}); err != nil {
+			t.Fatalf("g.AddTriples failed with error: %v", err)
+		}
+	}
+
+	const bulkSize = 4
+	plnr, err := New(ctx, src, parse(`DUMP ?test;`), 0, bulkSize, nil)
+	if err != nil {
+		t.Fatalf("planner.New failed to create a valid dump plan with error: %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for the dump plan with error: %v", err)
+	}
+
+	want := graphTriples(ctx, srcG, t)
+	if got, want := tbl.NumRows(), (len(want)+bulkSize-1)/bulkSize; got != want {
+		t.Errorf("planner.Execute returned %d insert statements; want %d", got, want)
+	}
+
+	dst := memory.NewStore()
+	dstG, err := dst.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range tbl.Rows() {
+		plnr, err := New(ctx, dst, parse(*r["?statement"].S), 0, bulkSize, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid insert plan with error: %v", err)
+		}
+		if _, err := plnr.Execute(ctx); err != nil {
+			t.Fatalf("planner.Execute failed for %q with error: %v", *r["?statement"].S, err)
+		}
+	}
+	got := graphTriples(ctx, dstG, t)
+	if len(got) != len(want) {
+		t.Fatalf("replaying the dump recreated %d triples; want %d", len(got), len(want))
+	}
+	for k := range want {
+		if !got[k] {
+			t.Errorf("replaying the dump failed to recreate triple %s", k)
+		}
+	}
+}
+
+func TestPlannerDumpRejectsUnrepresentableLiterals(t *testing.T) {
+	ctx := context.Background()
+	for _, text := range []string{`he said "hi"`, "a\"\nb", `ends with \`} {
+		s := memory.NewStore()
+		g, err := s.NewGraph(ctx, "?test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, []*triple.Triple{textTriple(text, t)}); err != nil {
+			t.Fatalf("g.AddTriples failed with error: %v", err)
+		}
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(`DUMP ?test;`, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed with error: %v", err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid dump plan with error: %v", err)
+		}
+		if _, err := plnr.Execute(ctx); err == nil {
+			t.Errorf("planner.Execute should have failed to dump text literal %q", text)
+		}
+	}
+}
+
+// textTriple returns a triple with the provided text literal as its object.
+func textTriple(text string, t *testing.T) *triple.Triple {
+	n, err := node.Parse("/u<joe>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("says")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trpl, err := triple.New(n, p, triple.NewLiteralObject(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trpl
+}
+
+func TestPlannerMerge(t *testing.T) {
+	const (
+		srcTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<joe>	"age"@[]	"42"^^type:int64
+/u<mary>	"age"@[]	"27"^^type:int64
+/u<peter>	"age"@[]	"33"^^type:int64
+/u<ann>	"knows"@[]	/u<joe>
+/u<ann>	"knows"@[]	/u<mary>
+`
+		dstTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<mary>	"age"@[]	"28"^^type:int64
+/u<mary>	"age"@[]	"29"^^type:int64
+`
+	)
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?src", srcTriples, t)
+	populateStoreWithTriples(ctx, s, "?dest", dstTriples, t)
+
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 	}
 	st := &semantic.Statement{}
-	if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
-		t.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+	q := `MERGE ?src INTO ?dest REPORTING CONFLICTS;`
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for statement %q with error: %v", q, err)
 	}
-	plnr, err := New(ctx, s, st, 0, 10, nil)
+	plnr, err := New(ctx, s, st, 0, 2, nil)
 	if err != nil {
-		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		t.Fatalf("planner.New failed to create a valid merge plan with error: %v", err)
+	}
+	if got, want := plnr.Type(), "MERGE"; got != want {
+		t.Errorf("planner.New returned a plan of type %q; want %q", got, want)
 	}
 	tbl, err := plnr.Execute(ctx)
 	if err != nil {
-		t.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+		t.Fatalf("planner.Execute failed for the merge plan with error: %v", err)
 	}
-	if got, want := len(tbl.Bindings()), 1; got != want {
-		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+	if got, want := tbl.Bindings(), []string{"?s", "?p", "?src_o", "?dest_o"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planner.Execute returned bindings %v; want %v", got, want)
 	}
-	if got, want := len(tbl.Rows()), 1; got != want {
-		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+	var conflicts []string
+	for _, r := range tbl.Rows() {
+		conflicts = append(conflicts, fmt.Sprintf("%s %s %s %s", r["?s"], r["?p"], r["?src_o"], r["?dest_o"]))
+	}
+	sort.Strings(conflicts)
+	wantConflicts := []string{
+		`/u<joe> "knows"@[] /u<peter> /u<mary>`,
+		`/u<mary> "age"@[] "27"^^type:int64 "28"^^type:int64`,
+		`/u<mary> "age"@[] "27"^^type:int64 "29"^^type:int64`,
+	}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("planner.Execute reported conflicts %v; want %v", conflicts, wantConflicts)
 	}
-}
 
-func TestChaining(t *testing.T) {
-	// Graph traversal data.
-	traversalTriples := `/u<joe> "parent_of"@[] /u<mary>
-		/u<joe> "parent_of"@[] /u<peter>
-		/u<peter> "parent_of"@[] /u<john>
-		/u<peter> "parent_of"@[] /u<eve>`
+	dst, err := s.Graph(ctx, "?dest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := graphTriples(ctx, dst, t)
+	want := []string{
+		`/u<joe>	"knows"@[]	/u<mary>`,
+		`/u<joe>	"age"@[]	"42"^^type:int64`,
+		`/u<mary>	"age"@[]	"28"^^type:int64`,
+		`/u<mary>	"age"@[]	"29"^^type:int64`,
+		`/u<peter>	"age"@[]	"33"^^type:int64`,
+		`/u<ann>	"knows"@[]	/u<joe>`,
+		`/u<ann>	"knows"@[]	/u<mary>`,
+	}
+	if len(got) != len(want) {
+		t.Errorf("merging left %d triples in the destination graph; want %d", len(got), len(want))
+	}
+	for _, trpl := range want {
+		if !got[trpl] {
+			t.Errorf("merging failed to leave triple %s in the destination graph", trpl)
+		}
+	}
+}
 
-	traversalQuery := `SELECT ?o FROM ?test
-	                   WHERE {
-	                       /u<joe> "parent_of"@[] ?o .
-		                   ?o "parent_of"@[] /u<john>
-	                   };`
+func TestPlannerMergeReplacingConflicts(t *testing.T) {
+	const (
+		srcTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<mary>	"height_cm"@[]	"170"^^type:int64
+/u<ann>	"height_cm"@[]	"160"^^type:int64
+`
+		dstTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<zoe>
+/u<mary>	"height_cm"@[]	"168"^^type:int64
+/u<mary>	"height_cm"@[2016-04-10T04:21:00Z]	"165"^^type:int64
+/u<mary>	"weight_kg"@[]	"60"^^type:int64
+`
+	)
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?src", srcTriples, t)
+	populateStoreWithTriples(ctx, s, "?dest", dstTriples, t)
 
-	// Load traversing data
-	s, ctx := memory.NewStore(), context.Background()
-	g, gErr := s.NewGraph(ctx, "?test")
-	if gErr != nil {
-		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	plnr, err := newPlanWithOptions(ctx, s, `MERGE ?src INTO ?dest REPLACING CONFLICTS;`, nil, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed to create a valid merge plan with error: %v", err)
 	}
-	b := bytes.NewBufferString(traversalTriples)
-	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
-		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed for the merge plan with error: %v", err)
 	}
-	p, pErr := grammar.NewParser(grammar.SemanticBQL())
-	if pErr != nil {
-		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	var replaced []string
+	for _, r := range tbl.Rows() {
+		replaced = append(replaced, fmt.Sprintf("%s %s %s %s", r["?s"], r["?p"], r["?src_o"], r["?dest_o"]))
 	}
-	st := &semantic.Statement{}
-	if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
-		t.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+	sort.Strings(replaced)
+	wantReplaced := []string{
+		`/u<joe> "knows"@[] /u<mary> /u<zoe>`,
+		`/u<mary> "height_cm"@[2016-04-10T04:21:00Z] "170"^^type:int64 "165"^^type:int64`,
+		`/u<mary> "height_cm"@[] "170"^^type:int64 "168"^^type:int64`,
 	}
-	plnr, err := New(ctx, s, st, 0, 10, nil)
+	if !reflect.DeepEqual(replaced, wantReplaced) {
+		t.Errorf("planner.Execute reported replaced triples %v; want %v", replaced, wantReplaced)
+	}
+
+	dst, err := s.Graph(ctx, "?dest")
 	if err != nil {
-		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		t.Fatal(err)
 	}
-	tbl, err := plnr.Execute(ctx)
+	got := graphTriples(ctx, dst, t)
+	want := []string{
+		`/u<joe>	"knows"@[]	/u<mary>`,
+		`/u<joe>	"knows"@[]	/u<peter>`,
+		`/u<mary>	"height_cm"@[]	"170"^^type:int64`,
+		`/u<mary>	"weight_kg"@[]	"60"^^type:int64`,
+		`/u<ann>	"height_cm"@[]	"160"^^type:int64`,
+	}
+	if len(got) != len(want) {
+		t.Errorf("merging left %d triples in the destination graph; want %d", len(got), len(want))
+	}
+	for _, trpl := range want {
+		if !got[trpl] {
+			t.Errorf("merging failed to leave triple %s in the destination graph", trpl)
+		}
+	}
+}
+
+// graphWrappingStore returns the graphs of the wrapped store wrapped by wrap.
+type graphWrappingStore struct {
+	storage.Store
+	wrap func(storage.Graph) storage.Graph
+}
+
+func (s *graphWrappingStore) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	g, err := s.Store.Graph(ctx, id)
 	if err != nil {
-		t.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+		return nil, err
 	}
-	if got, want := len(tbl.Bindings()), 1; got != want {
-		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+	return s.wrap(g), nil
+}
+
+// plainGraph hides the optional interfaces implemented by the wrapped graph.
+type plainGraph struct {
+	storage.Graph
+}
+
+// batchCountingGraph counts the batched subject lookups on the wrapped graph.
+type batchCountingGraph struct {
+	storage.Graph
+	n *int32
+}
+
+func (g *batchCountingGraph) TriplesForSubjects(ctx context.Context, subjects []*node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	atomic.AddInt32(g.n, 1)
+	return storage.TriplesForSubjects(ctx, g.Graph, subjects, lo, trpls)
+}
+
+func TestPlannerBatchesSubjectLookups(t *testing.T) {
+	const familyTriples = `/u<joe>	"parent_of"@[]	/u<mary>
+/u<joe>	"parent_of"@[]	/u<peter>
+/u<mary>	"parent_of"@[]	/u<ann>
+/u<mary>	"name"@[]	"Mary"^^type:text
+/u<mary>	"age"@[]	"30"^^type:int64
+/u<ann>	"name"@[]	"Ann"^^type:text
+`
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?test", familyTriples, t)
+	var n int32
+	batched := &graphWrappingStore{Store: s, wrap: func(g storage.Graph) storage.Graph {
+		return &batchCountingGraph{Graph: g, n: &n}
+	}}
+	plain := &graphWrappingStore{Store: s, wrap: func(g storage.Graph) storage.Graph {
+		return &plainGraph{g}
+	}}
+	run := func(s storage.Store, q string) []string {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+		}
+		var rs []string
+		for _, r := range tbl.Rows() {
+			var b bytes.Buffer
+			if err := r.ToTextLine(&b, tbl.Bindings(), " "); err != nil {
+				t.Fatal(err)
+			}
+			rs = append(rs, b.String())
+		}
+		sort.Strings(rs)
+		return rs
 	}
-	if got, want := len(tbl.Rows()), 1; got != want {
-		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+	for _, q := range []string{
+		`SELECT ?c, ?p, ?o FROM ?test WHERE {/u<joe> "parent_of"@[] ?c . ?c ?p ?o};`,
+		`SELECT ?c, ?p, ?o FROM ?test WHERE {/u<joe> "parent_of"@[] ?c . OPTIONAL {?c ?p ?o}};`,
+		`SELECT ?g, ?p, ?o FROM ?test WHERE {/u<joe> "parent_of"@[] ?c . ?c "parent_of"@[] ?g . ?g ?p ?o};`,
+	} {
+		atomic.StoreInt32(&n, 0)
+		got := run(batched, q)
+		if atomic.LoadInt32(&n) == 0 {
+			t.Errorf("planner.Execute(%s) did not batch the subject lookups", q)
+		}
+		if want := run(plain, q); !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute(%s) returned %v when batching; want %v", q, got, want)
+		}
 	}
 }
 
-func BenchmarkChaining(b *testing.B) {
-	// Graph traversal data.
-	traversalTriples := `/u<joe> "parent_of"@[] /u<mary>
-		/u<joe> "parent_of"@[] /u<peter>
-		/u<peter> "parent_of"@[] /u<john>
-		/u<peter> "parent_of"@[] /u<eve>`
+// graphTriples returns the set of triples in the provided graph.
+func graphTriples(ctx context.Context, g storage.Graph, t *testing.T) map[string]bool {
+	trpls := make(chan *triple.Triple)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.Triples(ctx, storage.DefaultLookup, trpls)
+	}()
+	ts := make(map[string]bool)
+	for trpl := range trpls {
+		ts[trpl.String()] = true
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
 
-	traversalQuery := `SELECT ?o FROM ?test
-	                   WHERE {
-	                       /u<joe> "parent_of"@[] ?o .
-		                   ?o "parent_of"@[] /u<john>
-	                   };`
+func TestPlannerAnonymousBinding(t *testing.T) {
+	const anonymousTriples = `/u<a>	"knows"@[]	/u<b>
+/u<a>	"knows"@[]	/u<c>
+/u<a>	"knows"@[]	/u<d>
+/u<b>	"knows"@[]	/u<c>
+/u<c>	"likes"@[]	/u<c>
+/u<b>	"likes"@[]	/u<a>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			// Subjects are returned once, no matter how many objects they have.
+			q:    `SELECT ?s FROM ?test WHERE {?s "knows"@[] ?_} ORDER BY ?s;`,
+			want: []string{"/u<a>", "/u<b>"},
+		},
+		{
+			// The existence check only keeps the subjects that have the predicate.
+			q:    `SELECT ?s, ?o FROM ?test WHERE {?s "likes"@[] ?o . ?s "knows"@[] ?_} ORDER BY ?s;`,
+			want: []string{"/u<b> /u<a>"},
+		},
+		{
+			q:    `SELECT ?p FROM ?test WHERE {/u<a> ?p ?_};`,
+			want: []string{`"knows"@[]`},
+		},
+		{
+			// Multiple anonymous bindings in the same clause are independent.
+			q:    `SELECT ?p FROM ?test WHERE {?_ ?p ?_} ORDER BY ?p;`,
+			want: []string{`"knows"@[]`, `"likes"@[]`},
+		},
+		{
+			// Anonymous bindings are not shared across clauses either.
+			q:    `SELECT ?s FROM ?test WHERE {?s "likes"@[] ?_ . ?_ "knows"@[] ?s} ORDER BY ?s;`,
+			want: []string{"/u<b>", "/u<c>"},
+		},
+	}
 
-	// Load traversing data
 	s, ctx := memory.NewStore(), context.Background()
-	g, gErr := s.NewGraph(ctx, "?test")
-	if gErr != nil {
-		b.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
+	populateStoreWithTriples(ctx, s, "?test", anonymousTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if tbl.HasBinding(semantic.AnonymousBinding) {
+			t.Errorf("planner.Execute(%s) returned a table with binding %q; it should be discarded", entry.q, semantic.AnonymousBinding)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
 	}
-	buf := bytes.NewBufferString(traversalTriples)
-	if _, err := io.ReadIntoGraph(ctx, g, buf, literal.DefaultBuilder()); err != nil {
-		b.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
+}
+
+func TestPlannerHavingNumericComparison(t *testing.T) {
+	const heightTriples = `/u<alice>	"height_cm"@[]	"174"^^type:int64
+/u<bob>	"height_cm"@[]	"174.0"^^type:float64
+/u<carol>	"height_cm"@[]	"180"^^type:int64
+`
+	const q = `SELECT ?s, ?h FROM ?test WHERE {?s "height_cm"@[] ?h} ORDER BY ?s HAVING ?h = "174"^^type:int64;`
+	testTable := []struct {
+		m    semantic.NumericComparison
+		want []string
+	}{
+		{semantic.StrictNumericComparison, []string{"/u<alice>"}},
+		{semantic.PromotedNumericComparison, []string{"/u<alice>", "/u<bob>"}},
 	}
-	for n := 0; n < b.N; n++ {
-		p, pErr := grammar.NewParser(grammar.SemanticBQL())
-		if pErr != nil {
-			b.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", heightTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", q, err)
+		}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, nil, &Options{NumericComparison: entry.m})
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?s"].String())
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) with mode %d returned %v; want %v", q, entry.m, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGraphProjection(t *testing.T) {
+	const (
+		aTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<mary>	"lives_in"@[]	/city<paris>
+`
+		bTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<peter>	"lives_in"@[]	/city<rome>
+`
+	)
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			// Triples in several graphs produce one row per graph.
+			q:    `SELECT ?o, GRAPH() AS ?g FROM ?a, ?b WHERE {/u<joe> "knows"@[] ?o} ORDER BY ?o, ?g;`,
+			want: []string{`/u<mary> "?a"^^type:text`, `/u<mary> "?b"^^type:text`, `/u<peter> "?b"^^type:text`},
+		},
+		{
+			q:    `SELECT GRAPH() AS ?g, ?o FROM ?a, ?b WHERE {/u<joe> "knows"@[] ?o} ORDER BY ?g, ?o;`,
+			want: []string{`"?a"^^type:text /u<mary>`, `"?b"^^type:text /u<mary>`, `"?b"^^type:text /u<peter>`},
+		},
+		{
+			// All the clauses of the pattern match in the same graph.
+			q:    `SELECT ?o, ?c, GRAPH() AS ?g FROM ?a, ?b WHERE {/u<joe> "knows"@[] ?o . ?o "lives_in"@[] ?c} ORDER BY ?o;`,
+			want: []string{`/u<mary> /city<paris> "?a"^^type:text`, `/u<peter> /city<rome> "?b"^^type:text`},
+		},
+		{
+			// Without GRAPH() patterns can span graphs.
+			q:    `SELECT ?o, ?c FROM ?a, ?b WHERE {/u<joe> "knows"@[] ?o . ?o "lives_in"@[] ?c} ORDER BY ?o;`,
+			want: []string{`/u<mary> /city<paris>`, `/u<mary> /city<paris>`, `/u<peter> /city<rome>`},
+		},
+		{
+			q:    `SELECT GRAPH() AS ?g FROM ?a, ?b WHERE {/u<peter> "lives_in"@[] /city<rome>};`,
+			want: []string{`"?b"^^type:text`},
+		},
+		{
+			q:    `SELECT ?o, GRAPH() AS ?g FROM ?a, ?b WHERE {/u<peter> "lives_in"@[] /city<rome> . /u<joe> "knows"@[] ?o} ORDER BY ?o;`,
+			want: []string{`/u<mary> "?b"^^type:text`, `/u<peter> "?b"^^type:text`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?a", aTriples, t)
+	populateStoreWithTriples(ctx, s, "?b", bTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGraphCount(t *testing.T) {
+	const (
+		aTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<mary>	"lives_in"@[]	/city<paris>
+`
+		bTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"knows"@[]	/u<peter>
+/u<peter>	"lives_in"@[]	/city<rome>
+`
+		cTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<mary>	"lives_in"@[]	/city<paris>
+`
+	)
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s, ?p, ?o, GRAPH_COUNT() AS ?gc FROM ?a, ?b, ?c WHERE {?s ?p ?o} GROUP BY ?s, ?p, ?o ORDER BY ?s, ?o;`,
+			want: []string{`/u<joe> "knows"@[] /u<mary> "3"^^type:int64`, `/u<joe> "knows"@[] /u<peter> "2"^^type:int64`, `/u<mary> "lives_in"@[] /city<paris> "2"^^type:int64`, `/u<peter> "lives_in"@[] /city<rome> "1"^^type:int64`},
+		},
+		{
+			// The triples present in all the input graphs.
+			q:    `SELECT ?s, ?p, ?o, GRAPH_COUNT() AS ?gc FROM ?a, ?b, ?c WHERE {?s ?p ?o} GROUP BY ?s, ?p, ?o HAVING ?gc = "3"^^type:int64;`,
+			want: []string{`/u<joe> "knows"@[] /u<mary> "3"^^type:int64`},
+		},
+		{
+			q:    `SELECT ?o, GRAPH_COUNT() AS ?gc FROM ?a, ?b, ?c WHERE {/u<joe> "knows"@[] ?o} GROUP BY ?o ORDER BY ?o;`,
+			want: []string{`/u<mary> "3"^^type:int64`, `/u<peter> "2"^^type:int64`},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?a", aTriples, t)
+	populateStoreWithTriples(ctx, s, "?b", bTriples, t)
+	populateStoreWithTriples(ctx, s, "?c", cTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		plnr, err := New(ctx, s, st, 0, 10, nil)
+		if err != nil {
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerTripleProjection(t *testing.T) {
+	const (
+		aTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<joe>	"says"@[]	"hi"^^type:text
+`
+		bTriples = `/u<joe>	"knows"@[]	/u<mary>
+/u<mary>	"knows"@[]	/u<peter>
+`
+	)
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT DISTINCT TRIPLE(?s, ?p, ?o) AS ?t FROM ?a, ?b WHERE {?s ?p ?o} ORDER BY ?t;`,
+			want: []string{"/u<joe>\t\"knows\"@[]\t/u<mary>", "/u<joe>\t\"says\"@[]\t\"hi\"^^type:text", "/u<mary>\t\"knows\"@[]\t/u<peter>"},
+		},
+		{
+			// Triple cells can be grouped by.
+			q:    `SELECT TRIPLE(?s, ?p, ?o) AS ?t, GRAPH_COUNT() AS ?n FROM ?a, ?b WHERE {?s ?p ?o} GROUP BY ?t ORDER BY ?t;`,
+			want: []string{"/u<joe>\t\"knows\"@[]\t/u<mary> \"2\"^^type:int64", "/u<joe>\t\"says\"@[]\t\"hi\"^^type:text \"1\"^^type:int64", "/u<mary>\t\"knows\"@[]\t/u<peter> \"1\"^^type:int64"},
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?a", aTriples, t)
+	populateStoreWithTriples(ctx, s, "?b", bTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(traversalQuery, 1), st); err != nil {
-			b.Errorf("Parser.consume: failed to parse query %q with error %v", traversalQuery, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
 		}
 		plnr, err := New(ctx, s, st, 0, 10, nil)
 		if err != nil {
-			b.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 		}
 		tbl, err := plnr.Execute(ctx)
 		if err != nil {
-			b.Errorf("planner.Execute failed for query %q with error %v", traversalQuery, err)
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
 		}
-		if got, want := len(tbl.Bindings()), 1; got != want {
-			b.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", traversalQuery, got, want)
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
 		}
-		if got, want := len(tbl.Rows()), 1; got != want {
-			b.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", traversalQuery, got, want, tbl)
+		if strings.Join(got, ",") != strings.Join(entry.want, ",") {
+			t.Errorf("planner.Execute(%s) returned %q; want %q", entry.q, got, entry.want)
 		}
 	}
 }
 
-// Test to validate https://github.com/google/badwolf/issues/70
-func TestReificationResolutionIssue70(t *testing.T) {
-	// Graph traversal data.
-	issue70Triples := `/_<c175b457-e6d6-4ce3-8312-674353815720>	"_predicate"@[]	"/some/immutable/id"@[]
-		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_owner"@[2017-05-23T16:41:12.187373-07:00]	/gid<0x9>
-		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_subject"@[]	/aid</some/subject/id>
-		/_<c175b457-e6d6-4ce3-8312-674353815720>	"_object"@[]	/aid</some/object/id>
-		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_object"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>
-		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_owner"@[2017-05-23T16:41:12.187373-07:00]	/gid<0x6>
-		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_predicate"@[2017-05-23T16:41:12.187373-07:00]	"/some/temporal/id"@[2017-05-23T16:41:12.187373-07:00]
-		/_<cd8bae87-be96-41af-b1a8-27df990c9825>	"_subject"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/subject/id>
-		/aid</some/subject/id>	"/some/temporal/id"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>
-		/aid</some/subject/id>	"/some/immutable/id"@[]	/aid</some/object/id>
-		/aid</some/subject/id>	"/some/ownerless_temporal/id"@[2017-05-23T16:41:12.187373-07:00]	/aid</some/object/id>`
-
-	query := `
-		SELECT ?bn, ?p
-		FROM ?test
-		WHERE {
-			?bn "_subject"@[,]   /aid</some/subject/id>.
-			?bn "_predicate"@[,] ?p .
-			?bn "_object"@[,]    /aid</some/object/id>
-		};`
+func TestPlannerCountDistinctTimeAnchors(t *testing.T) {
+	const boughtTriples = `/u<peter>	"bought"@[2016-01-01T00:00:00-08:00]	/c<mini>
+/u<peter>	"bought"@[2016-01-01T08:00:00Z]	/c<model s>
+/u<peter>	"bought"@[2016-01-01T09:00:00+01:00]	/c<model x>
+/u<peter>	"bought"@[2016-01-02T08:00:00Z]	/c<model y>
+`
+	const q = `SELECT ?s, COUNT(DISTINCT ?t) AS ?n FROM ?test WHERE {?s "bought"@[?t] ?o} GROUP BY ?s;`
 
-	// Load traversing data
 	s, ctx := memory.NewStore(), context.Background()
-	g, gErr := s.NewGraph(ctx, "?test")
-	if gErr != nil {
-		t.Fatalf("memory.NewGraph failed to create \"?test\" with error %v", gErr)
-	}
-	b := bytes.NewBufferString(issue70Triples)
-	if _, err := io.ReadIntoGraph(ctx, g, b, literal.DefaultBuilder()); err != nil {
-		t.Fatalf("io.ReadIntoGraph failed to read test graph with error %v", err)
-	}
-	p, pErr := grammar.NewParser(grammar.SemanticBQL())
-	if pErr != nil {
-		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", pErr)
+	populateStoreWithTriples(ctx, s, "?test", boughtTriples, t)
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 	}
 	st := &semantic.Statement{}
-	if err := p.Parse(grammar.NewLLk(query, 1), st); err != nil {
-		t.Errorf("Parser.consume: failed to parse query %q with error %v", query, err)
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", q, err)
 	}
 	plnr, err := New(ctx, s, st, 0, 10, nil)
 	if err != nil {
-		t.Errorf("planner.New failed to create a valid query plan with error %v", err)
+		t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 	}
 	tbl, err := plnr.Execute(ctx)
 	if err != nil {
-		t.Fatalf("planner.Execute failed for query %q with error %v", query, err)
+		t.Fatalf("planner.Execute(%s) failed with error: %v", q, err)
 	}
-	if got, want := len(tbl.Bindings()), 2; got != want {
-		t.Errorf("tbl.Bindings returned the wrong number of bindings for %q; got %d, want %d", query, got, want)
+	rws := tbl.Rows()
+	if len(rws) != 1 {
+		t.Fatalf("planner.Execute(%s) returned %d rows; want 1", q, len(rws))
 	}
-	if got, want := len(tbl.Rows()), 1; got != want {
-		t.Errorf("planner.Execute failed to return the expected number of rows for query %q; got %d want %d\nGot:\n%v\n", query, got, want, tbl)
+	if got, want := rws[0]["?n"].String(), `"2"^^type:int64`; got != want {
+		t.Errorf("planner.Execute(%s) counted %s distinct anchors; want %s", q, got, want)
 	}
 }
 
-// benchmarkQuery is a helper function that runs a specified query on the testing data set for benchmarking purposes.
-func benchmarkQuery(query string, b *testing.B) {
+func TestPlannerGroupByMultipleAccumulators(t *testing.T) {
+	testTable := []struct {
+		q    string
+		want map[string]string
+	}{
+		{
+			q: `SELECT ?s, COUNT(?o) AS ?n, COUNT(DISTINCT ?p) AS ?m FROM ?test WHERE {?s ?p ?o} GROUP BY ?s;`,
+			want: map[string]string{
+				"/l<barcelona>": `"5"^^type:int64 "1"^^type:int64`,
+				"/u<alice>":     `"2"^^type:int64 "2"^^type:int64`,
+				"/u<joe>":       `"2"^^type:int64 "1"^^type:int64`,
+			},
+		},
+		{
+			q: `SELECT ?s, COUNT(?o) AS ?n, COUNT(DISTINCT ?o) AS ?m FROM ?test WHERE {?s ?p ?o} GROUP BY ?s;`,
+			want: map[string]string{
+				"/l<barcelona>": `"5"^^type:int64 "5"^^type:int64`,
+				"/u<alice>":     `"2"^^type:int64 "2"^^type:int64`,
+				"/u<joe>":       `"2"^^type:int64 "2"^^type:int64`,
+			},
+		},
+		{
+			q:    `SELECT ?s, COUNT(?o) AS ?n, SUM(?o) AS ?m FROM ?test WHERE {?s ?p ?o . ?s "unknown"@[] ?o} GROUP BY ?s;`,
+			want: map[string]string{},
+		},
+	}
+
 	s, ctx := memory.NewStore(), context.Background()
-	populateStoreWithTriples(ctx, s, "?test", testTriples, b)
-	b.ResetTimer()
-	for n := 0; n < b.N; n++ {
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
 		p, err := grammar.NewParser(grammar.SemanticBQL())
 		if err != nil {
-			b.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
 		}
-
 		st := &semantic.Statement{}
-		if err := p.Parse(grammar.NewLLk(query, 1), st); err != nil {
-			b.Errorf("Parser.consume: failed to parse query %q with error %v", query, err)
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
 		}
 		plnr, err := New(ctx, s, st, 0, 10, nil)
 		if err != nil {
-			b.Errorf("planner.New failed to create a valid query plan with error %v", err)
+			t.Fatalf("planner.New failed to create a valid query plan with error: %v", err)
 		}
-		_, err = plnr.Execute(ctx)
+		tbl, err := plnr.Execute(ctx)
 		if err != nil {
-			b.Errorf("planner.Execute failed for query %q with error %v", query, err)
+			t.Fatalf("planner.Execute(%s) failed with error: %v", entry.q, err)
+		}
+		if got, want := tbl.Bindings(), []string{"?s", "?n", "?m"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("planner.Execute(%s) returned bindings %v; want %v", entry.q, got, want)
+		}
+		if len(entry.want) == 0 && tbl.NumRows() != 0 {
+			t.Errorf("planner.Execute(%s) returned %d rows; want none", entry.q, tbl.NumRows())
+		}
+		for _, r := range tbl.Rows() {
+			want, ok := entry.want[r["?s"].String()]
+			if !ok {
+				continue
+			}
+			if got := r["?n"].String() + " " + r["?m"].String(); got != want {
+				t.Errorf("planner.Execute(%s) returned %s for %s; want %s", entry.q, got, r["?s"], want)
+			}
 		}
 	}
 }
 
-// These benchmark tests are used to observe the difference in speed between queries using the "as" keyword as opposed
-// to queries that do not.
-func BenchmarkReg1(b *testing.B) {
-	benchmarkQuery(`select ?p, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`, b)
+// syncBuffer is a bytes.Buffer safe to write concurrently with reading it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
 }
 
-func BenchmarkAs1(b *testing.B) {
-	benchmarkQuery(`select ?p as ?p1, ?o as ?o1 from ?test where {/u<joe> ?p ?o};`, b)
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
 }
 
-func BenchmarkReg2(b *testing.B) {
-	benchmarkQuery(`select ?s, ?p, ?o from ?test where {?s ?p ?o};`, b)
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
 }
 
-func BenchmarkAs2(b *testing.B) {
-	benchmarkQuery(`select ?s as ?s1, ?p as ?p1, ?o as ?o1 from ?test where {?s ?p ?o};`, b)
+func TestPlannerCrossProductPolicy(t *testing.T) {
+	const (
+		q       = `SELECT ?s, ?k FROM ?test WHERE {?s "parent_of"@[] ?o . ?k "parent_of"@[] ?m};`
+		warning = "planner.New: warning: the graph pattern has 2 groups of clauses sharing no bindings"
+	)
+	testTable := []struct {
+		policy   CrossProductPolicy
+		wantErr  bool
+		wantWarn bool
+	}{
+		{policy: AllowCrossProducts},
+		{policy: WarnCrossProducts, wantWarn: true},
+		{policy: ErrorCrossProducts, wantErr: true},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", q, err)
+		}
+		w := &syncBuffer{}
+		plnr, err := NewWithOptions(ctx, s, st, 0, 10, w, &Options{CrossProducts: entry.policy})
+		if entry.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "cartesian product") {
+				t.Errorf("planner.NewWithOptions(%s) with policy %v returned error %v; want a cartesian product error", q, entry.policy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions(%s) with policy %v failed with error: %v", q, entry.policy, err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%s) with policy %v failed with error: %v", q, entry.policy, err)
+		}
+		if got, want := tbl.NumRows(), 16; got != want {
+			t.Errorf("planner.Execute(%s) with policy %v returned %d rows; want %d", q, entry.policy, got, want)
+		}
+		// Tracing is asynchronous, so wait a bit for the warning to show up.
+		got := w.String()
+		for i := 0; entry.wantWarn && i < 100 && !strings.Contains(got, warning); i++ {
+			time.Sleep(10 * time.Millisecond)
+			got = w.String()
+		}
+		if gotWarn := strings.Contains(got, warning); gotWarn != entry.wantWarn {
+			t.Errorf("planner.NewWithOptions(%s) with policy %v traced %q; want warning %v", q, entry.policy, got, entry.wantWarn)
+		}
+	}
+}
+
+func TestPlannerRejectCrossProducts(t *testing.T) {
+	testTable := []struct {
+		q       string
+		opts    *Options
+		wantErr string
+	}{
+		{
+			q:    `SELECT ?s, ?k FROM ?test WHERE {?s ?p ?o . ?k ?l ?m};`,
+			opts: nil,
+		},
+		{
+			q:    `SELECT ?s, ?k FROM ?test WHERE {?s ?p ?o . ?k ?l ?m};`,
+			opts: &Options{},
+		},
+		{
+			q:    `SELECT ?s, ?x FROM ?test WHERE {?s "parent_of"@[] ?o . ?o "parent_of"@[] ?x};`,
+			opts: &Options{CrossProducts: ErrorCrossProducts},
+		},
+		{
+			q:       `SELECT ?s, ?k FROM ?test WHERE {?s ?p ?o . ?k ?l ?m};`,
+			opts:    &Options{CrossProducts: ErrorCrossProducts},
+			wantErr: "2 groups of clauses sharing no bindings, which would produce a cartesian product: {clauses 1 with bindings ?o, ?p, ?s} and {clauses 2 with bindings ?k, ?l, ?m}",
+		},
+		{
+			q:       `CONSTRUCT {?s "knows"@[] ?k} INTO ?test FROM ?test WHERE {?s ?p ?o . ?k ?l ?m};`,
+			opts:    &Options{CrossProducts: ErrorCrossProducts},
+			wantErr: "cartesian product",
+		},
+	}
+
+	s, ctx := memory.NewStore(), context.Background()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	for _, entry := range testTable {
+		p, err := grammar.NewParser(grammar.SemanticBQL())
+		if err != nil {
+			t.Fatalf("grammar.NewParser: should have produced a valid BQL parser, %v", err)
+		}
+		st := &semantic.Statement{}
+		if err := p.Parse(grammar.NewLLk(entry.q, 1), st); err != nil {
+			t.Fatalf("parser.Parse failed for query \"%s\"\nwith error: %v", entry.q, err)
+		}
+		_, err = NewWithOptions(ctx, s, st, 0, 10, nil, entry.opts)
+		if entry.wantErr == "" {
+			if err != nil {
+				t.Errorf("planner.NewWithOptions(%s, %+v) failed with error: %v", entry.q, entry.opts, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), entry.wantErr) {
+			t.Errorf("planner.NewWithOptions(%s, %+v) returned error %v; want an error containing %q", entry.q, entry.opts, err, entry.wantErr)
+		}
+	}
+}
+
+func TestPlannerShowGraphsSorted(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	for _, g := range []string{"?zeta", "?alpha", "?mu", "?beta"} {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("memoryStore.NewGraph(%q) failed with error %v", g, err)
+		}
+	}
+	plnr, err := newPlanWithOptions(ctx, s, `SHOW GRAPHS;`, nil, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed with error %v", err)
+	}
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?graph_id"].String())
+	}
+	if want := []string{"?alpha", "?beta", "?mu", "?zeta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SHOW GRAPHS returned %v; want %v", got, want)
+	}
+}
+
+func TestPlannerPredicateAndObjectAnchors(t *testing.T) {
+	const anchorTriples = `/u<a>	"said"@[2016-01-01T00:00:00Z]	"turned"@[2016-02-01T00:00:00Z]
+/u<a>	"said"@[2016-03-01T00:00:00Z]	"turned"@[2016-04-01T00:00:00Z]
+/u<b>	"said"@[2016-05-01T00:00:00Z]	"born"@[]
+/u<c>	"said"@[2016-06-01T00:00:00Z]	"turned"@[2016-06-01T00:00:00Z]
+/u<a>	"knows"@[]	/u<b>
+/u<a>	"knows"@[]	/u<c>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q:    `SELECT ?s, ?pt, ?ot FROM ?test WHERE {?s ?p AT ?pt ?o AT ?ot} ORDER BY ?s, ?pt;`,
+			want: []string{"/u<a> 2016-01-01T00:00:00Z 2016-02-01T00:00:00Z", "/u<a> 2016-03-01T00:00:00Z 2016-04-01T00:00:00Z", "/u<c> 2016-06-01T00:00:00Z 2016-06-01T00:00:00Z"},
+		},
+		{
+			q:    `SELECT ?pt, ?ot FROM ?test WHERE {/u<a> "said"@[?pt] ?o AT ?ot} ORDER BY ?pt;`,
+			want: []string{"2016-01-01T00:00:00Z 2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z 2016-04-01T00:00:00Z"},
+		},
+		{
+			// Both anchors are bound when the clause is specified with the rows of the table.
+			q:    `SELECT ?s, ?pt, ?ot FROM ?test WHERE {/u<a> "knows"@[] ?s . ?s ?p AT ?pt ?o AT ?ot} ORDER BY ?s;`,
+			want: []string{"/u<c> 2016-06-01T00:00:00Z 2016-06-01T00:00:00Z"},
+		},
+		{
+			// Both anchors are bound when the clause is fully specified by the table.
+			q:    `SELECT ?pt, ?ot FROM ?test WHERE {?s ?p ?o . ?s ?p AT ?pt ?o AT ?ot} ORDER BY ?pt;`,
+			want: []string{"2016-01-01T00:00:00Z 2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z 2016-04-01T00:00:00Z", "2016-06-01T00:00:00Z 2016-06-01T00:00:00Z"},
+		},
+		{
+			// Optional clauses leave the anchor of immutable objects unbound.
+			q:    `SELECT ?s, ?pt, ?ot FROM ?test WHERE {/u<a> "knows"@[] ?s . OPTIONAL {?s ?p AT ?pt ?o AT ?ot}} ORDER BY ?s;`,
+			want: []string{"/u<b> 2016-05-01T00:00:00Z <NULL>", "/u<c> 2016-06-01T00:00:00Z 2016-06-01T00:00:00Z"},
+		},
+		{
+			// Using the same binding for both anchors requires them to be equal.
+			q:    `SELECT ?s, ?t FROM ?test WHERE {?s ?p AT ?t ?o AT ?t};`,
+			want: []string{"/u<c> 2016-06-01T00:00:00Z"},
+		},
+	}
+	for _, entry := range testTable {
+		ctx := context.Background()
+		s := memory.NewStore()
+		populateStoreWithTriples(ctx, s, "?test", anchorTriples, t)
+		plnr, err := newPlanWithOptions(ctx, s, entry.q, nil, t)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions(%q) failed with error %v", entry.q, err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%q) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerGroupConcat(t *testing.T) {
+	const familyTriples = `/u<joe>	"parent_of"@[]	/u<mary>
+/u<joe>	"parent_of"@[]	/u<peter>
+/u<peter>	"parent_of"@[]	/u<john>
+/u<peter>	"parent_of"@[]	/u<eve>
+/u<mary>	"parent_of"@[]	/u<ann>
+/u<ann>	"parent_of"@[]	/u<zoe>
+`
+	testTable := []struct {
+		q    string
+		want []string
+	}{
+		{
+			q: `SELECT ?grandparent, GROUP_CONCAT(?name, ", ") AS ?kids
+			    FROM ?test
+			    WHERE {
+			      ?gp ID ?grandparent "parent_of"@[] ?parent .
+			      ?parent "parent_of"@[] ?gc ID ?name
+			    }
+			    GROUP BY ?grandparent
+			    ORDER BY ?grandparent;`,
+			want: []string{"joe ann, eve, john", "mary zoe"},
+		},
+		{
+			// The default separator is a single space, and GROUP_CONCAT can be
+			// combined with other aggregations.
+			q: `SELECT ?grandparent, GROUP_CONCAT(?name) AS ?kids, COUNT(?name) AS ?n
+			    FROM ?test
+			    WHERE {
+			      ?gp ID ?grandparent "parent_of"@[] ?parent .
+			      ?parent "parent_of"@[] ?gc ID ?name
+			    }
+			    GROUP BY ?grandparent
+			    ORDER BY ?grandparent;`,
+			want: []string{`joe ann eve john "3"^^type:int64`, `mary zoe "1"^^type:int64`},
+		},
+	}
+	for _, entry := range testTable {
+		ctx := context.Background()
+		s := memory.NewStore()
+		populateStoreWithTriples(ctx, s, "?test", familyTriples, t)
+		plnr, err := newPlanWithOptions(ctx, s, entry.q, nil, t)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions(%q) failed with error %v", entry.q, err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute(%q) failed with error %v", entry.q, err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, b := range tbl.Bindings() {
+				vs = append(vs, r[b].String())
+			}
+			got = append(got, strings.Join(vs, " "))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("planner.Execute(%q) returned %v; want %v", entry.q, got, entry.want)
+		}
+	}
+}
+
+func TestPlannerProvenance(t *testing.T) {
+	const provenanceTriples = `/u<a>	"knows"@[]	/u<b>
+/u<a>	"knows"@[]	/u<c>
+/u<b>	"name"@[]	"bee"^^type:text
+/u<c>	"name"@[]	"cee"^^type:text
+/u<b>	"age"@[]	"3"^^type:int64
+`
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?test", provenanceTriples, t)
+	prov := table.NewProvenance()
+	q := `SELECT ?s, ?f, ?n, ?age FROM ?test WHERE {?s "knows"@[] ?f . ?f "name"@[] ?n . OPTIONAL {?f "age"@[] ?age}};`
+	plnr, err := newPlanWithOptions(ctx, s, q, &Options{Provenance: prov}, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("planner.Execute failed with error %v", err)
+	}
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Fatalf("planner.Execute returned %d rows; want %d", got, want)
+	}
+	want := map[string]int{"?s": 0, "?f": 0, "?n": 1, "?age": 2}
+	for _, r := range tbl.Rows() {
+		for b, wc := range want {
+			c, ok := prov.Clause(r[b])
+			if !ok || c != wc {
+				t.Errorf("provenance of %s in row %v is clause %d (known: %v); want clause %d", b, r, c, ok, wc)
+			}
+		}
+	}
+}
+
+func TestPlannerClauseLimit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&b, "/u<a>\t\"knows\"@[]\t/u<f%d>\n", i)
+		for j := 0; j < 3; j++ {
+			fmt.Fprintf(&b, "/u<f%d>\t\"tag\"@[]\t\"t%d\"^^type:text\n", i, j)
+		}
+	}
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?test", b.String(), t)
+	testTable := []struct {
+		q    string
+		want int
+	}{
+		{
+			q:    `SELECT ?f, ?t FROM ?test WHERE {/u<a> "knows"@[] ?f . ?f "tag"@[] ?t};`,
+			want: 15,
+		},
+		{
+			// The limit caps the rows of the first clause.
+			q:    `SELECT ?f, ?t FROM ?test WHERE {/u<a> "knows"@[] ?f LIMIT "2"^^type:int64 . ?f "tag"@[] ?t};`,
+			want: 6,
+		},
+		{
+			// The limit caps the lookup made for each row bound by the first clause.
+			q:    `SELECT ?f, ?t FROM ?test WHERE {/u<a> "knows"@[] ?f . ?f "tag"@[] ?t LIMIT "1"^^type:int64};`,
+			want: 5,
+		},
+		{
+			q:    `SELECT ?f, ?t FROM ?test WHERE {/u<a> "knows"@[] ?f LIMIT "2"^^type:int64 . ?f "tag"@[] ?t LIMIT "1"^^type:int64};`,
+			want: 2,
+		},
+	}
+	for _, entry := range testTable {
+		plnr, err := newPlanWithOptions(ctx, s, entry.q, nil, t)
+		if err != nil {
+			t.Fatalf("planner.NewWithOptions failed to create plan for query %q with error %v", entry.q, err)
+		}
+		tbl, err := plnr.Execute(ctx)
+		if err != nil {
+			t.Fatalf("planner.Execute failed for query %q with error %v", entry.q, err)
+		}
+		if got := tbl.NumRows(); got != entry.want {
+			t.Errorf("planner.Execute(%q) returned %d rows; want %d", entry.q, got, entry.want)
+		}
+	}
 }
@@ -0,0 +1,141 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/bql/table"
+)
+
+// ErrQueryKilled is returned by plans whose execution was cancelled by a KILL
+// statement.
+var ErrQueryKilled = errors.New("planner: query killed")
+
+// RunningQuery describes a plan being executed and tracked by a registry.
+type RunningQuery struct {
+	// ID identifies the query on KILL statements.
+	ID string
+
+	// Type is the type of the plan being executed.
+	Type string
+
+	// Started is the time the plan started executing.
+	Started time.Time
+}
+
+// Registry tracks the plans being executed, so they can be listed and
+// cancelled by ID.
+type Registry struct {
+	mu      sync.Mutex
+	next    uint64
+	running map[string]*registryEntry
+}
+
+// registryEntry holds the state of a running query.
+type registryEntry struct {
+	query  RunningQuery
+	seq    uint64
+	cancel context.CancelFunc
+	killed bool
+}
+
+// NewRegistry returns a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		running: make(map[string]*registryEntry),
+	}
+}
+
+// Running returns the queries being executed, in the order they started.
+func (r *Registry) Running() []RunningQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	es := make([]*registryEntry, 0, len(r.running))
+	for _, e := range r.running {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].seq < es[j].seq
+	})
+	res := make([]RunningQuery, 0, len(es))
+	for _, e := range es {
+		res = append(res, e.query)
+	}
+	return res
+}
+
+// Kill cancels the context of the running query with the provided ID. Its
+// plan returns ErrQueryKilled once it notices the cancellation.
+func (r *Registry) Kill(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.running[id]
+	if !ok {
+		return fmt.Errorf("planner.Kill: no running query with ID %q", id)
+	}
+	e.killed = true
+	e.cancel()
+	return nil
+}
+
+// start registers a new running query of the provided type. It returns the
+// context the query should use and a function to call once it finishes,
+// which reports whether the query was killed.
+func (r *Registry) start(ctx context.Context, typ string) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.next++
+	e := &registryEntry{
+		query: RunningQuery{
+			ID:      fmt.Sprintf("q%d", r.next),
+			Type:    typ,
+			Started: time.Now(),
+		},
+		seq:    r.next,
+		cancel: cancel,
+	}
+	r.running[e.query.ID] = e
+	r.mu.Unlock()
+
+	return ctx, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.running, e.query.ID)
+		cancel()
+		return e.killed
+	}
+}
+
+// registeredPlan wraps a plan so its executions are tracked by a registry.
+type registeredPlan struct {
+	Executor
+	registry *Registry
+}
+
+// Execute runs the wrapped plan while registered as a running query.
+func (p *registeredPlan) Execute(ctx context.Context) (*table.Table, error) {
+	ctx, finish := p.registry.start(ctx, p.Type())
+	tbl, err := p.Executor.Execute(ctx)
+	if finish() {
+		return nil, ErrQueryKilled
+	}
+	return tbl, err
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+)
+
+// blockingGraph blocks all its triple lookups until their context is done.
+type blockingGraph struct {
+	storage.Graph
+	started chan struct{}
+}
+
+func (g *blockingGraph) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	defer close(trpls)
+	g.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newPlanWithOptions(ctx context.Context, s storage.Store, q string, opts *Options, t *testing.T) (Executor, error) {
+	t.Helper()
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		t.Fatalf("grammar.NewParser should have produced a valid BQL parser but got error: %v", err)
+	}
+	st := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(q, 1), st); err != nil {
+		t.Fatalf("parser.Parse failed for query %q with error: %v", q, err)
+	}
+	return NewWithOptions(ctx, s, st, 0, 10, nil, opts)
+}
+
+func TestRegistryKillQuery(t *testing.T) {
+	ctx := context.Background()
+	ms := memory.NewStore()
+	populateStoreWithTriples(ctx, ms, "?test", testTriples, t)
+	started := make(chan struct{}, 1)
+	s := &graphWrappingStore{Store: ms, wrap: func(g storage.Graph) storage.Graph {
+		return &blockingGraph{Graph: g, started: started}
+	}}
+	r := NewRegistry()
+	opts := &Options{Registry: r}
+
+	plnr, err := newPlanWithOptions(ctx, s, `select ?s from ?test where {?s ?p ?o};`, opts, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed with error %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := plnr.Execute(ctx)
+		errc <- err
+	}()
+	<-started
+
+	qs := r.Running()
+	if len(qs) != 1 || qs[0].Type != "SELECT" {
+		t.Fatalf("registry.Running() returned %v; want a single running SELECT", qs)
+	}
+	kill, err := newPlanWithOptions(ctx, s, fmt.Sprintf(`kill query "%s"^^type:text;`, qs[0].ID), opts, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed with error %v", err)
+	}
+	if _, err := kill.Execute(ctx); err != nil {
+		t.Fatalf("kill.Execute failed with error %v", err)
+	}
+	if err := <-errc; err != ErrQueryKilled {
+		t.Errorf("killed query returned error %v; want %v", err, ErrQueryKilled)
+	}
+	if qs := r.Running(); len(qs) != 0 {
+		t.Errorf("registry.Running() returned %v after the query was killed; want none", qs)
+	}
+
+	// Killing a query that is no longer running fails.
+	if _, err := kill.Execute(ctx); err == nil {
+		t.Errorf("kill.Execute should have failed for a query no longer running")
+	}
+}
+
+func TestRegistryTracksCompletedQueries(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStore()
+	populateStoreWithTriples(ctx, s, "?test", testTriples, t)
+	r := NewRegistry()
+
+	plnr, err := newPlanWithOptions(ctx, s, `select ?s from ?test where {?s ?p ?o};`, &Options{Registry: r}, t)
+	if err != nil {
+		t.Fatalf("planner.NewWithOptions failed with error %v", err)
+	}
+	tbl, err := plnr.Execute(ctx)
+	if err != nil {
+		t.Fatalf("plnr.Execute failed with error %v", err)
+	}
+	if tbl.NumRows() == 0 {
+		t.Errorf("plnr.Execute returned no rows; want some")
+	}
+	if qs := r.Running(); len(qs) != 0 {
+		t.Errorf("registry.Running() returned %v after the query finished; want none", qs)
+	}
+}
+
+func TestKillRequiresRegistry(t *testing.T) {
+	ctx := context.Background()
+	if _, err := newPlanWithOptions(ctx, memory.NewStore(), `kill query "q1"^^type:text;`, nil, t); err == nil {
+		t.Errorf("planner.NewWithOptions should have rejected a KILL statement without a registry")
+	}
+}
@@ -92,9 +92,30 @@ func V(verbosity int) MessageTracer {
 	return MessageTracer{verbosity}
 }
 
-// isTraceable returns true if the current tracer is verbose enough to let the given MessageTracer
-// indeed trace its correspondent message.
-func (t MessageTracer) isTraceable() bool {
+// verbosityWriter wraps a writer with the verbosity level to use for the
+// messages traced to it, overriding the global verbosity.
+type verbosityWriter struct {
+	io.Writer
+	verbosity int
+}
+
+// WithVerbosity returns a writer that receives the messages traced to w whose
+// level is not above the provided verbosity, regardless of the global
+// verbosity of the tracer. This allows tracing a single execution at a given
+// level. The verbosity is truncated to the range [1, 3] supported.
+func WithVerbosity(w io.Writer, verbosity int) io.Writer {
+	if vw, ok := w.(*verbosityWriter); ok {
+		w = vw.Writer
+	}
+	return &verbosityWriter{w, V(verbosity).verbosity}
+}
+
+// isTraceable returns true if the tracer verbosity for the given writer is high enough
+// to let the given MessageTracer indeed trace its correspondent message.
+func (t MessageTracer) isTraceable(w io.Writer) bool {
+	if vw, ok := w.(*verbosityWriter); ok {
+		return t.verbosity <= vw.verbosity
+	}
 	return t.verbosity <= tracerVerbosity
 }
 
@@ -102,7 +123,7 @@ func (t MessageTracer) isTraceable() bool {
 // of the MessageTracer is coherent with the global tracer verbosity. The tracer is lazy
 // on the arguments generation to avoid adding too much overhead when tracing is not on.
 func (t MessageTracer) Trace(w io.Writer, tracerArgs func() *Arguments) {
-	if w == nil || !t.isTraceable() {
+	if w == nil || !t.isTraceable(w) {
 		return
 	}
 	events <- &event{w, time.Now(), tracerArgs}
@@ -0,0 +1,230 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semantic
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/storage"
+)
+
+// CanonicalString returns a normalized form of the statement. It is rebuilt
+// from the parsed clauses and projections instead of the original input, so
+// statements that only differ on whitespace or on the case of their keywords
+// share the same canonical string. It is meant for logging and as the basis of
+// cache keys, but it is not meant to be parsed back. It only describes the
+// statement: results also depend on the store queried and on the planner
+// options, which cache keys need to include as well.
+func (s *Statement) CanonicalString() string {
+	b := bytes.NewBufferString(s.sType.String())
+
+	// Flags.
+	if s.ifNotExists {
+		b.WriteString(" IF NOT EXISTS")
+	}
+	if s.ifExists {
+		b.WriteString(" IF EXISTS")
+	}
+	if s.ifAbsent {
+		b.WriteString(" IF ABSENT")
+	}
+	if s.replaceConflicts {
+		b.WriteString(" REPLACING CONFLICTS")
+	}
+	if s.viewName != "" {
+		b.WriteString(" ")
+		b.WriteString(s.viewName)
+	}
+	if s.killQueryID != "" {
+		b.WriteString(" ")
+		b.WriteString(strconv.Quote(s.killQueryID))
+	}
+
+	// Projections.
+	if len(s.projection) > 0 {
+		b.WriteString(" SELECT ")
+		if s.distinct {
+			b.WriteString("DISTINCT ")
+		}
+		for i, p := range s.projection {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(p.String())
+		}
+	}
+
+	// Graphs.
+	writeCanonicalNames(b, " GRAPHS ", s.graphNames)
+	writeCanonicalNames(b, " FROM ", s.inputGraphNames)
+	writeCanonicalNames(b, " INTO ", s.outputGraphNames)
+
+	// Data.
+	if len(s.data) > 0 {
+		b.WriteString(" DATA {")
+		for _, t := range s.data {
+			b.WriteString(" ")
+			b.WriteString(t.String())
+			b.WriteString(" .")
+		}
+		b.WriteString(" }")
+	}
+	if len(s.constructClauses) > 0 {
+		b.WriteString(" CONSTRUCT {")
+		for _, c := range s.constructClauses {
+			b.WriteString(" ")
+			b.WriteString(c.String())
+		}
+		b.WriteString(" }")
+	}
+
+	// Graph pattern.
+	if len(s.pattern) > 0 || len(s.negated) > 0 || len(s.filters) > 0 {
+		b.WriteString(" WHERE {")
+		group := 0
+		for _, c := range s.pattern {
+			if c == nil || c.IsEmpty() {
+				continue
+			}
+			if c.OptionalGroup != group {
+				if group != 0 {
+					b.WriteString(" }")
+				}
+				if c.OptionalGroup != 0 {
+					b.WriteString(" OPTIONAL {")
+				}
+				group = c.OptionalGroup
+			}
+			b.WriteString(" ")
+			b.WriteString(c.String())
+		}
+		if group != 0 {
+			b.WriteString(" }")
+		}
+		for _, blk := range s.negated {
+			b.WriteString(" NOT {")
+			for _, c := range blk {
+				b.WriteString(" ")
+				b.WriteString(c.String())
+			}
+			b.WriteString(" }")
+		}
+		for _, f := range s.filters {
+			b.WriteString(" FILTER ")
+			b.WriteString(f.String())
+		}
+		b.WriteString(" }")
+	}
+	if s.anchorsSubject != nil || s.anchorsBinding != "" {
+		b.WriteString(" ANCHORS ")
+		if s.anchorsSubject != nil {
+			b.WriteString(s.anchorsSubject.String())
+		} else {
+			b.WriteString(s.anchorsBinding)
+		}
+		if s.anchorsPredicateID != "" {
+			b.WriteString(" ")
+			b.WriteString(strconv.Quote(s.anchorsPredicateID))
+		}
+	}
+
+	if len(s.bindingTypes) > 0 {
+		bs := make([]string, 0, len(s.bindingTypes))
+		for bn := range s.bindingTypes {
+			bs = append(bs, bn)
+		}
+		sort.Strings(bs)
+		b.WriteString(" TYPES")
+		if s.strictBindingTypes {
+			b.WriteString(" STRICT")
+		}
+		for _, bn := range bs {
+			b.WriteString(" ")
+			b.WriteString(bn)
+			b.WriteString(":")
+			b.WriteString(s.bindingTypes[bn].String())
+		}
+	}
+
+	// Result modifiers.
+	for _, u := range s.unwind {
+		b.WriteString(" UNWIND ")
+		b.WriteString(u.String())
+	}
+	if len(s.groupBy) > 0 {
+		b.WriteString(" GROUP BY ")
+		for i, g := range s.groupBy {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(g)
+			if bkt, ok := s.groupByBuckets[g]; ok {
+				b.WriteString(" BUCKET ")
+				b.WriteString(bkt.String())
+			}
+		}
+	}
+	if len(s.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(s.orderBy.String())
+	}
+	if len(s.havingExpression) > 0 {
+		b.WriteString(" HAVING")
+		for _, ce := range s.havingExpression {
+			b.WriteString(" ")
+			b.WriteString(canonicalTokenText(ce.Token()))
+		}
+	}
+	if !reflect.DeepEqual(s.lookupOptions, storage.LookupOptions{}) {
+		b.WriteString(" WITH ")
+		b.WriteString(s.lookupOptions.String())
+	}
+	if s.anchorCutoff != nil {
+		b.WriteString(" AT ")
+		b.WriteString(s.anchorCutoff.Format(time.RFC3339Nano))
+	}
+	if s.limitSet {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.FormatInt(s.limit, 10))
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// writeCanonicalNames writes the provided graph names after the keyword, if
+// there are any.
+func writeCanonicalNames(b *bytes.Buffer, kw string, ns []string) {
+	if len(ns) == 0 {
+		return
+	}
+	b.WriteString(kw)
+	b.WriteString(strings.Join(ns, ", "))
+}
+
+// canonicalTokenText returns the text of the token, with the keyword casing
+// normalized.
+func canonicalTokenText(tkn *lexer.Token) string {
+	switch tkn.Type {
+	case lexer.ItemNot, lexer.ItemAnd, lexer.ItemOr:
+		return strings.ToUpper(tkn.Text)
+	}
+	return tkn.Text
+}
@@ -19,10 +19,13 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 // Evaluator interface computes the evaluation of a boolean expression.
@@ -59,6 +62,8 @@ const (
 	AND
 	// OR represents 'or'.
 	OR
+	// UNDER represents 'under'.
+	UNDER
 )
 
 // String returns a readable string of the operation.
@@ -76,6 +81,8 @@ func (o OP) String() string {
 		return "and"
 	case OR:
 		return "or"
+	case UNDER:
+		return "under"
 	default:
 		return "@UNKNOWN@"
 	}
@@ -105,9 +112,82 @@ func formatCell(c *table.Cell) (string, error) {
 	return strings.TrimSpace(c.String()), nil
 }
 
+// compareTimes compares two time anchors as instants, regardless of the time
+// zone offsets they were expressed with.
+func compareTimes(op OP, l, r time.Time) (bool, error) {
+	switch op {
+	case EQ:
+		return l.Equal(r), nil
+	case LT:
+		return l.Before(r), nil
+	case GT:
+		return l.After(r), nil
+	default:
+		return false, fmt.Errorf("boolean evaluation requires a boolean operation; found %q instead", op)
+	}
+}
+
+// isNumber returns true if the literal holds an int64 or a float64.
+func isNumber(l *literal.Literal) bool {
+	return l != nil && (l.Type() == literal.Int64 || l.Type() == literal.Float64)
+}
+
+// NumericComparison selects how comparisons between numeric literals of
+// different types are evaluated.
+type NumericComparison int8
+
+const (
+	// StrictNumericComparison only compares numbers of the same type by value.
+	// An int64 and a float64 never compare equal.
+	StrictNumericComparison NumericComparison = iota
+	// PromotedNumericComparison promotes int64 values to float64 when they are
+	// compared with float64 values, so "174"^^type:int64 equals
+	// "174.0"^^type:float64.
+	PromotedNumericComparison
+)
+
+// comparableNumbers returns true if both literals are numbers that can be
+// compared by value under the provided comparison mode.
+func comparableNumbers(m NumericComparison, l, r *literal.Literal) bool {
+	if !isNumber(l) || !isNumber(r) {
+		return false
+	}
+	return l.Type() == r.Type() || m == PromotedNumericComparison
+}
+
+// compareNumbers compares two numeric literals by value, so values like
+// "1.5e10"^^type:float64 are not affected by how they are formatted. Literals
+// of different types are both promoted to float64 first.
+func compareNumbers(op OP, l, r *literal.Literal) (bool, error) {
+	if l.Type() != r.Type() {
+		var err error
+		if l, err = l.Cast(literal.Float64); err != nil {
+			return false, err
+		}
+		if r, err = r.Cast(literal.Float64); err != nil {
+			return false, err
+		}
+	}
+	c, err := l.Compare(r)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case EQ:
+		return c == 0, nil
+	case LT:
+		return c < 0, nil
+	case GT:
+		return c > 0, nil
+	default:
+		return false, fmt.Errorf("boolean evaluation requires a boolean operation; found %q instead", op)
+	}
+}
+
 // evaluationNode represents the internal representation of one expression.
 type evaluationNode struct {
 	operation OP
+	numbers   NumericComparison
 
 	leftBinding  string
 	rightBinding string
@@ -133,6 +213,19 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 		return false, err
 	}
 
+	// Time anchors are compared as instants instead of as strings.
+	if leftBinding.T != nil && rightBinding.T != nil {
+		return compareTimes(e.operation, *leftBinding.T, *rightBinding.T)
+	}
+	if leftBinding.T != nil || rightBinding.T != nil {
+		return false, fmt.Errorf("evaluationNode.Evaluate failed, cannot compare a time anchor with a non time value; got %s and %s", leftBinding, rightBinding)
+	}
+
+	// Numbers are compared by value.
+	if comparableNumbers(e.numbers, leftBinding.L, rightBinding.L) {
+		return compareNumbers(e.operation, leftBinding.L, rightBinding.L)
+	}
+
 	// comparable string expressions for left and right tokens.
 	var csEL, csER string
 	csEL, err = formatCell(leftBinding)
@@ -159,6 +252,7 @@ func (e *evaluationNode) Evaluate(r table.Row) (bool, error) {
 // comparisonForLiteral represents the internal representation of an expression of comparison between a binding and a literal.
 type comparisonForLiteral struct {
 	operation OP
+	numbers   NumericComparison
 
 	leftBinding  string
 	rightLiteral string
@@ -177,23 +271,40 @@ func (e *comparisonForLiteral) Evaluate(r table.Row) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("comparisonForLiteral.Evaluate failed, could not parse literal from the string %q, got error: %v", e.rightLiteral, err)
 	}
+	return compareCellWithLiteral(e.operation, e.numbers, leftBinding, rightLiteral)
+}
+
+// compareCellWithLiteral compares the provided non empty cell against the
+// literal using the provided operation.
+func compareCellWithLiteral(op OP, numbers NumericComparison, leftBinding *table.Cell, rightLiteral *literal.Literal) (bool, error) {
 	if leftBinding.S != nil && rightLiteral.Type() != literal.Text {
 		return false, fmt.Errorf("a string binding can only be compared with a literal of type text, got literal %q instead", rightLiteral)
 	}
 
+	if comparableNumbers(numbers, leftBinding.L, rightLiteral) {
+		return compareNumbers(op, leftBinding.L, rightLiteral)
+	}
 	if leftBinding.L != nil && leftBinding.L.Type() != rightLiteral.Type() {
 		return false, nil
 	}
+	// The language tag of text values is only considered when the literal
+	// compared against carries one.
+	if leftBinding.L != nil && leftBinding.L.Lang() != "" && rightLiteral.Lang() == "" {
+		l, err := leftBinding.L.WithLang("")
+		if err != nil {
+			return false, err
+		}
+		leftBinding = table.NewLiteralCell(l)
+	}
 
 	// comparable string expressions for left and right tokens.
-	var csEL, csER string
-	csEL, err = formatCell(leftBinding)
+	csEL, err := formatCell(leftBinding)
 	if err != nil {
 		return false, fmt.Errorf("comparisonForLiteral.Evaluate failed, the call for formatCell(%s) returned error: %v", leftBinding, err)
 	}
-	csER = rightLiteral.ToComparableString()
+	csER := rightLiteral.ToComparableString()
 
-	switch e.operation {
+	switch op {
 	case EQ:
 		return csEL == csER, nil
 	case LT:
@@ -201,8 +312,36 @@ func (e *comparisonForLiteral) Evaluate(r table.Row) (bool, error) {
 	case GT:
 		return csEL > csER, nil
 	default:
-		return false, fmt.Errorf("boolean evaluation requires a boolean operation; found %q instead", e.operation)
+		return false, fmt.Errorf("boolean evaluation requires a boolean operation; found %q instead", op)
+	}
+}
+
+// ClauseGuard is an inline comparison attached to a graph clause, as in
+// ?s "height_cm"@[] ?h WHERE ?h > "160"^^type:int64. The rows produced by the
+// clause are discarded while fetching unless the value of the binding
+// satisfies the comparison.
+type ClauseGuard struct {
+	Binding string
+	Op      OP
+	Value   *literal.Literal
+}
+
+// Evaluate returns true if the row satisfies the guard. Rows whose binding
+// does not hold a literal or a string never satisfy it.
+func (g *ClauseGuard) Evaluate(r table.Row) (bool, error) {
+	c, err := cellFromRow(g.Binding, r)
+	if err != nil {
+		return false, err
 	}
+	if c.L == nil && c.S == nil {
+		return false, nil
+	}
+	return compareCellWithLiteral(g.Op, StrictNumericComparison, c, g.Value)
+}
+
+// String returns a readable representation of the guard.
+func (g *ClauseGuard) String() string {
+	return fmt.Sprintf("WHERE %s %s %s", g.Binding, g.Op, g.Value)
 }
 
 // comparisonForNodeLiteral represents the internal representation of an expression of comparison between a binding and a node literal.
@@ -241,6 +380,26 @@ func (e *comparisonForNodeLiteral) Evaluate(r table.Row) (bool, error) {
 	}
 }
 
+// comparisonForNodeType represents the internal representation of an expression checking if the type of the node bound to a binding is under a type path.
+type comparisonForNodeType struct {
+	leftBinding string
+	rightType   *node.Type
+}
+
+// Evaluate returns true if the type of the node is the type path itself or
+// any of its descendants, so /item/book is under /item but /items is not.
+// Bindings not holding a node are never under any type.
+func (e *comparisonForNodeType) Evaluate(r table.Row) (bool, error) {
+	leftBinding, err := cellFromRow(e.leftBinding, r)
+	if err != nil {
+		return false, fmt.Errorf("comparisonForNodeType.Evaluate failed, the call for cellFromRow(%v, %v) returned error: %v", e.leftBinding, r, err)
+	}
+	if leftBinding.N == nil {
+		return false, nil
+	}
+	return leftBinding.N.Type().Covariant(e.rightType), nil
+}
+
 // comparisonForTimeLiteral is the internal representation of an expression of comparison between a binding and a time literal.
 type comparisonForTimeLiteral struct {
 	operation OP
@@ -262,21 +421,12 @@ func (e *comparisonForTimeLiteral) Evaluate(r table.Row) (bool, error) {
 	}
 
 	timeBinding := leftBinding.T
-	timeLiteral, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(e.rightTimeLiteral))
+	timeLiteral, err := predicate.ParseTimeAnchor(strings.TrimSpace(e.rightTimeLiteral), nil)
 	if err != nil {
 		return false, fmt.Errorf("comparisonForTimeLiteral.Evaluate failed, could not parse time from the string %q, got error: %v", strings.TrimSpace(e.rightTimeLiteral), err)
 	}
 
-	switch e.operation {
-	case EQ:
-		return timeBinding.Equal(timeLiteral), nil
-	case LT:
-		return timeBinding.Before(timeLiteral), nil
-	case GT:
-		return timeBinding.After(timeLiteral), nil
-	default:
-		return false, fmt.Errorf("boolean evaluation requires a boolean operation; found %q instead", e.operation)
-	}
+	return compareTimes(e.operation, *timeBinding, timeLiteral)
 }
 
 // comparisonForPredicateLiteral represents the internal representation of an expression of comparison between a binding and a predicate literal.
@@ -369,6 +519,25 @@ func NewEvaluationExpressionForNodeLiteral(op OP, lB, rNL string) (Evaluator, er
 	}
 }
 
+// NewEvaluationExpressionForNodeType creates a new evaluator for binding and node type path.
+func NewEvaluationExpressionForNodeType(op OP, lB, rT string) (Evaluator, error) {
+	l, r := strings.TrimSpace(lB), strings.TrimSpace(rT)
+	if l == "" || r == "" {
+		return nil, fmt.Errorf("operands cannot be empty; got %q, %q", l, r)
+	}
+	if op != UNDER {
+		return nil, fmt.Errorf("node type paths can only be used with the 'under' operation; got %q instead", op)
+	}
+	t, err := node.NewType(r)
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonForNodeType{
+		leftBinding: l,
+		rightType:   t,
+	}, nil
+}
+
 // NewEvaluationExpressionForTimeLiteral creates a new evaluator for binding and time literal.
 func NewEvaluationExpressionForTimeLiteral(op OP, lB, rTL string) (Evaluator, error) {
 	l, r := strings.TrimSpace(lB), strings.TrimSpace(rTL)
@@ -486,6 +655,258 @@ func NewUnaryBooleanExpression(op OP, lE Evaluator) (Evaluator, error) {
 	}
 }
 
+// CastType returns the literal type named by a literal type token text of the
+// form type:<name>. Only bool, int64, float64, and text are valid cast targets.
+func CastType(s string) (literal.Type, error) {
+	if idx := strings.Index(s, ":"); idx >= 0 {
+		s = s[idx+1:]
+	}
+	t, err := literal.ParseType(strings.ToLower(s))
+	if err != nil {
+		return t, err
+	}
+	switch t {
+	case literal.Bool, literal.Int64, literal.Float64, literal.Text:
+		return t, nil
+	default:
+		return t, fmt.Errorf("cannot cast to literal type %s; only bool, int64, float64, and text are supported", t)
+	}
+}
+
+// CastCell returns a new cell containing the value of the provided cell cast
+// to the requested literal type. Only literal and text cells can be cast; a
+// value that cannot be converted returns an error.
+func CastCell(c *table.Cell, t literal.Type) (*table.Cell, error) {
+	var l *literal.Literal
+	switch {
+	case c.L != nil:
+		l = c.L
+	case c.S != nil:
+		tl, err := literal.DefaultBuilder().Build(literal.Text, *c.S)
+		if err != nil {
+			return nil, err
+		}
+		l = tl
+	default:
+		return nil, fmt.Errorf("cannot cast %s to type %s; only literals and text can be cast", c, t)
+	}
+	cl, err := l.Cast(t)
+	if err != nil {
+		return nil, err
+	}
+	return table.NewLiteralCell(cl), nil
+}
+
+// LangCell returns a new cell containing a text literal with the language tag
+// of the provided cell. Literals and text without a language tag return an
+// empty text literal; any other kind of value returns an error.
+func LangCell(c *table.Cell) (*table.Cell, error) {
+	var tag string
+	switch {
+	case c.L != nil:
+		tag = c.L.Lang()
+	case c.S != nil:
+	default:
+		return nil, fmt.Errorf("cannot extract the language tag of %s; only literals and text have language tags", c)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, tag)
+	if err != nil {
+		return nil, err
+	}
+	return table.NewLiteralCell(l), nil
+}
+
+// KindCell returns a new cell containing a text literal with the kind of the
+// predicate in the provided cell, either "immutable" or "temporal". Any other
+// kind of value returns an error.
+func KindCell(c *table.Cell) (*table.Cell, error) {
+	if c.P == nil {
+		return nil, fmt.Errorf("cannot extract the kind of %s; only predicates have a kind", c)
+	}
+	kind := "immutable"
+	if c.P.Type() == predicate.Temporal {
+		kind = "temporal"
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, kind)
+	if err != nil {
+		return nil, err
+	}
+	return table.NewLiteralCell(l), nil
+}
+
+// DatatypeCell returns a new cell containing a text literal with the type of
+// the literal in the provided cell, such as "int64" or "text". Custom literals
+// return the name their type was registered with. Any other kind of value
+// returns an error.
+func DatatypeCell(c *table.Cell) (*table.Cell, error) {
+	if c.L == nil {
+		return nil, fmt.Errorf("cannot extract the datatype of %s; only literals have a datatype", c)
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, c.L.TypeName())
+	if err != nil {
+		return nil, err
+	}
+	return table.NewLiteralCell(l), nil
+}
+
+// cellText returns the text held by the provided cell and its language tag.
+// Only text literals and text cells hold text.
+func cellText(c *table.Cell) (string, string, error) {
+	switch {
+	case c.L != nil && c.L.Type() == literal.Text:
+		s, err := c.L.Text()
+		return s, c.L.Lang(), err
+	case c.S != nil:
+		return *c.S, "", nil
+	default:
+		return "", "", fmt.Errorf("%s is not a text literal", c)
+	}
+}
+
+// StrlenCell returns a new cell containing an int64 literal with the number of
+// characters, not bytes, of the text in the provided cell. Values other than
+// text return an error.
+func StrlenCell(c *table.Cell) (*table.Cell, error) {
+	s, _, err := cellText(c)
+	if err != nil {
+		return nil, err
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Int64, int64(utf8.RuneCountInString(s)))
+	if err != nil {
+		return nil, err
+	}
+	return table.NewLiteralCell(l), nil
+}
+
+// SubstrCell returns a new cell containing a text literal with at most length
+// characters of the text in the provided cell, starting at the 0 based start
+// character. Out of range values are clamped to the text, so they may return
+// an empty text. The language tag of the text is kept. Values other than text
+// return an error.
+func SubstrCell(c *table.Cell, start, length int64) (*table.Cell, error) {
+	s, tag, err := cellText(c)
+	if err != nil {
+		return nil, err
+	}
+	rs := []rune(s)
+	n := int64(len(rs))
+	if start < 0 {
+		start = 0
+	}
+	if start > n {
+		start = n
+	}
+	end := n
+	if length < n-start {
+		end = start + length
+	}
+	if end < start {
+		end = start
+	}
+	l, err := literal.DefaultBuilder().Build(literal.Text, string(rs[start:end]))
+	if err != nil {
+		return nil, err
+	}
+	if tag != "" {
+		if l, err = l.WithLang(tag); err != nil {
+			return nil, err
+		}
+	}
+	return table.NewLiteralCell(l), nil
+}
+
+// castOperand represents a CAST(?binding, type:<name>) operand in an
+// expression. Its value is stored in the row under the synthetic binding name
+// before the wrapped evaluator runs.
+type castOperand struct {
+	binding string
+	t       literal.Type
+	name    string
+}
+
+// castNode evaluates a cast operand of an expression before delegating to the
+// wrapped evaluator.
+type castNode struct {
+	cast *castOperand
+	e    Evaluator
+}
+
+// Evaluate the expression.
+func (c *castNode) Evaluate(r table.Row) (bool, error) {
+	cell, err := cellFromRow(c.cast.binding, r)
+	if err != nil {
+		return false, err
+	}
+	cc, err := CastCell(cell, c.cast.t)
+	if err != nil {
+		return false, fmt.Errorf("%s failed: %v", c.cast.name, err)
+	}
+	nr := make(table.Row, len(r)+1)
+	for k, v := range r {
+		nr[k] = v
+	}
+	nr[c.cast.name] = cc
+	return c.e.Evaluate(nr)
+}
+
+// parseCast parses a CAST(?binding, type:<name>) sequence at the beginning of
+// the provided consumed elements. It returns the cast operand, a synthetic
+// binding element standing for the cast value, and the left over elements.
+func parseCast(ce []ConsumedElement) (*castOperand, ConsumedElement, []ConsumedElement, error) {
+	want := []lexer.TokenType{lexer.ItemCast, lexer.ItemLPar, lexer.ItemBinding, lexer.ItemComma, lexer.ItemLiteralType, lexer.ItemRPar}
+	if len(ce) < len(want) {
+		return nil, ConsumedElement{}, nil, fmt.Errorf("incomplete cast expression %v", ce)
+	}
+	for i, tt := range want {
+		if ce[i].IsSymbol() || ce[i].Token().Type != tt {
+			return nil, ConsumedElement{}, nil, fmt.Errorf("invalid cast expression; expected %s, found %v", tt, ce[i])
+		}
+	}
+	bnd, typ := ce[2].Token().Text, ce[4].Token().Text
+	t, err := CastType(typ)
+	if err != nil {
+		return nil, ConsumedElement{}, nil, err
+	}
+	co := &castOperand{
+		binding: bnd,
+		t:       t,
+		name:    fmt.Sprintf("CAST(%s, %s)", bnd, typ),
+	}
+	syn := NewConsumedToken(&lexer.Token{Type: lexer.ItemBinding, Text: co.name})
+	return co, syn, ce[len(want):], nil
+}
+
+// WithNumericComparison returns a copy of the provided evaluator that compares
+// numbers using the provided mode. Evaluators are built using
+// StrictNumericComparison.
+func WithNumericComparison(e Evaluator, m NumericComparison) Evaluator {
+	switch n := e.(type) {
+	case *evaluationNode:
+		c := *n
+		c.numbers = m
+		return &c
+	case *comparisonForLiteral:
+		c := *n
+		c.numbers = m
+		return &c
+	case *booleanNode:
+		c := *n
+		if c.lE != nil {
+			c.lE = WithNumericComparison(c.lE, m)
+		}
+		if c.rE != nil {
+			c.rE = WithNumericComparison(c.rE, m)
+		}
+		return &c
+	case *castNode:
+		c := *n
+		c.e = WithNumericComparison(c.e, m)
+		return &c
+	default:
+		return e
+	}
+}
+
 // NewEvaluator construct an evaluator given a sequence of tokens. It will
 // return a descriptive error if it could build it properly.
 func NewEvaluator(ce []ConsumedElement) (Evaluator, error) {
@@ -520,12 +941,37 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 		return e, tailCEs, nil
 	}
 
+	// Cast token.
+	if tkn.Type == lexer.ItemCast {
+		co, syn, rest, err := parseCast(ce)
+		if err != nil {
+			return nil, nil, err
+		}
+		e, tailCEs, err := internalNewEvaluator(append([]ConsumedElement{syn}, rest...))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &castNode{cast: co, e: e}, tailCEs, nil
+	}
+
 	// Binding token.
 	if tkn.Type == lexer.ItemBinding {
 		if len(tail) < 2 {
 			return nil, nil, fmt.Errorf("cannot create a binary evaluation operand for %v", ce)
 		}
 		opTkn, bndTkn := tail[0].Token(), tail[1].Token()
+
+		if bndTkn.Type == lexer.ItemCast {
+			co, syn, rest, err := parseCast(tail[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			e, tailCEs, err := internalNewEvaluator(append([]ConsumedElement{head, tail[0], syn}, rest...))
+			if err != nil {
+				return nil, nil, err
+			}
+			return &castNode{cast: co, e: e}, tailCEs, nil
+		}
 		var op OP
 		switch opTkn.Type {
 		case lexer.ItemEQ:
@@ -534,6 +980,8 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 			op = LT
 		case lexer.ItemGT:
 			op = GT
+		case lexer.ItemUnder:
+			op = UNDER
 		default:
 			return nil, nil, fmt.Errorf("cannot create a binary evaluation operand for %v", opTkn)
 		}
@@ -574,6 +1022,18 @@ func internalNewEvaluator(ce []ConsumedElement) (Evaluator, []ConsumedElement, e
 			return e, res, nil
 		}
 
+		if bndTkn.Type == lexer.ItemNodeType {
+			e, err := NewEvaluationExpressionForNodeType(op, tkn.Text, bndTkn.Text)
+			if err != nil {
+				return nil, nil, err
+			}
+			var res []ConsumedElement
+			if len(tail) > 2 {
+				res = tail[2:]
+			}
+			return e, res, nil
+		}
+
 		if bndTkn.Type == lexer.ItemTime {
 			e, err := NewEvaluationExpressionForTimeLiteral(op, tkn.Text, bndTkn.Text)
 			if err != nil {
@@ -30,7 +30,7 @@ func TestEvaluationNode(t *testing.T) {
 		err  bool
 	}{
 		{
-			eval: &evaluationNode{EQ, "?foo", "?wrong_binding"},
+			eval: &evaluationNode{operation: EQ, leftBinding: "?foo", rightBinding: "?wrong_binding"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("foo")},
@@ -39,7 +39,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  true,
 		},
 		{
-			eval: &evaluationNode{EQ, "?foo", "?bar"},
+			eval: &evaluationNode{operation: EQ, leftBinding: "?foo", rightBinding: "?bar"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("bar")},
@@ -48,7 +48,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  false,
 		},
 		{
-			eval: &evaluationNode{EQ, "", "?bar"},
+			eval: &evaluationNode{operation: EQ, leftBinding: "", rightBinding: "?bar"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("bar")},
@@ -57,7 +57,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  true,
 		},
 		{
-			eval: &evaluationNode{EQ, "?foo", ""},
+			eval: &evaluationNode{operation: EQ, leftBinding: "?foo", rightBinding: ""},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("bar")},
@@ -66,7 +66,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  true,
 		},
 		{
-			eval: &evaluationNode{EQ, "?foo", "?bar"},
+			eval: &evaluationNode{operation: EQ, leftBinding: "?foo", rightBinding: "?bar"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("foo")},
@@ -75,7 +75,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  false,
 		},
 		{
-			eval: &evaluationNode{LT, "?foo", "?bar"},
+			eval: &evaluationNode{operation: LT, leftBinding: "?foo", rightBinding: "?bar"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("bar")},
@@ -84,7 +84,7 @@ func TestEvaluationNode(t *testing.T) {
 			err:  false,
 		},
 		{
-			eval: &evaluationNode{GT, "?foo", "?bar"},
+			eval: &evaluationNode{operation: GT, leftBinding: "?foo", rightBinding: "?bar"},
 			r: table.Row{
 				"?foo": &table.Cell{S: table.CellString("foo")},
 				"?bar": &table.Cell{S: table.CellString("bar")},
@@ -403,6 +403,94 @@ func TestEvaluatorEvaluate(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			id: `?foo = "abc"^^type:text ignores the language tag of ?foo`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"abc"^^type:text`,
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{
+					L: testutil.MustBuildLiteral(t, `"abc"^^type:text@es`),
+				},
+			},
+			want: true,
+		},
+		{
+			id: `?foo = "abc"^^type:text@es`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"abc"^^type:text@ES`,
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{
+					L: testutil.MustBuildLiteral(t, `"abc"^^type:text@es`),
+				},
+			},
+			want: true,
+		},
+		{
+			id: `?foo = "abc"^^type:text@en`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"abc"^^type:text@en`,
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{
+					L: testutil.MustBuildLiteral(t, `"abc"^^type:text@es`),
+				},
+			},
+			want: false,
+		},
+		{
+			id: `?foo = "abc"^^type:text@es`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"abc"^^type:text@es`,
+				}),
+			},
+			r: table.Row{
+				"?foo": &table.Cell{
+					L: testutil.MustBuildLiteral(t, `"abc"^^type:text`),
+				},
+			},
+			want: false,
+		},
 		{
 			id: `?s ID ?id = "abc"^^type:text`,
 			in: []ConsumedElement{
@@ -683,6 +771,26 @@ func TestEvaluatorEvaluate(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			id: `?time = 2012-03-10T0:00:00-08:00`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?time",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemTime,
+					Text: `2012-03-10T0:00:00-08:00`,
+				}),
+			},
+			r: table.Row{
+				"?time": &table.Cell{T: testutil.MustBuildTime(t, `2012-03-10T00:00:00.000000000-08:00`)},
+			},
+			want: true,
+		},
 		{
 			id: `?time < 2012-01-10T00:00:00-08:00`,
 			in: []ConsumedElement{
@@ -943,6 +1051,90 @@ func TestEvaluatorEvaluate(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			id: `?t1 = ?t2 for the same instant in different offsets`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t1",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t2",
+				}),
+			},
+			r: table.Row{
+				"?t1": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T08:00:00Z`)},
+				"?t2": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T00:00:00-08:00`)},
+			},
+			want: true,
+		},
+		{
+			id: `?t1 < ?t2 with an earlier instant in a later local time`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t1",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLT,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t2",
+				}),
+			},
+			r: table.Row{
+				"?t1": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T01:00:00+02:00`)},
+				"?t2": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T00:00:00Z`)},
+			},
+			want: true,
+		},
+		{
+			id: `?t1 > ?t2 with an earlier instant in a later local time`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t1",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemGT,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t2",
+				}),
+			},
+			r: table.Row{
+				"?t1": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T01:00:00+02:00`)},
+				"?t2": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T00:00:00Z`)},
+			},
+			want: false,
+		},
+		{
+			id: `?t1 < ?t2 for the same instant in different offsets`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t1",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLT,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t2",
+				}),
+			},
+			r: table.Row{
+				"?t1": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T08:00:00Z`)},
+				"?t2": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T00:00:00-08:00`)},
+			},
+			want: false,
+		},
 	}
 
 	for _, entry := range testTable {
@@ -1081,6 +1273,26 @@ func TestEvaluatorEvaluateError(t *testing.T) {
 				"?s": &table.Cell{N: testutil.MustBuildNodeFromStrings(t, "/u", "alice")},
 			},
 		},
+		{
+			id: `?t1 = ?s`,
+			in: []ConsumedElement{
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?t1",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemEQ,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?s",
+				}),
+			},
+			r: table.Row{
+				"?t1": &table.Cell{T: testutil.MustBuildTime(t, `2016-03-01T08:00:00Z`)},
+				"?s":  &table.Cell{S: table.CellString("2016-03-01T08:00:00Z")},
+			},
+		},
 	}
 
 	for _, entry := range testTable {
@@ -1098,3 +1310,270 @@ func TestEvaluatorEvaluateError(t *testing.T) {
 		}
 	}
 }
+
+func TestCastEvaluator(t *testing.T) {
+	tokens := func(s string) []ConsumedElement {
+		var ce []ConsumedElement
+		for tkn := range lexer.New(s, 0) {
+			if tkn.Type == lexer.ItemEOF {
+				break
+			}
+			tknCopy := tkn
+			ce = append(ce, NewConsumedToken(&tknCopy))
+		}
+		return ce
+	}
+	testTable := []struct {
+		expr    string
+		r       table.Row
+		want    bool
+		wantErr bool
+	}{
+		{
+			expr: `CAST(?id, type:int64) > "37"^^type:int64`,
+			r: table.Row{
+				"?id": &table.Cell{S: table.CellString("42")},
+			},
+			want: true,
+		},
+		{
+			expr: `CAST(?id, type:int64) > "37"^^type:int64`,
+			r: table.Row{
+				"?id": &table.Cell{S: table.CellString("7")},
+			},
+			want: false,
+		},
+		{
+			expr: `?name = CAST(?height, type:text)`,
+			r: table.Row{
+				"?name":   &table.Cell{S: table.CellString("174")},
+				"?height": &table.Cell{L: testutil.MustBuildLiteral(t, `"174"^^type:int64`)},
+			},
+			want: true,
+		},
+		{
+			expr: `(CAST(?id, type:float64) < "2.5"^^type:float64) and (?id = ?id)`,
+			r: table.Row{
+				"?id": &table.Cell{L: testutil.MustBuildLiteral(t, `"2"^^type:int64`)},
+			},
+			want: true,
+		},
+		{
+			expr: `CAST(?id, type:int64) > "37"^^type:int64`,
+			r: table.Row{
+				"?id": &table.Cell{S: table.CellString("peter")},
+			},
+			wantErr: true,
+		},
+		{
+			expr: `CAST(?id, type:int64) > "37"^^type:int64`,
+			r: table.Row{
+				"?id": &table.Cell{N: testutil.MustBuildNodeFromStrings(t, "/u", "peter")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, entry := range testTable {
+		eval, err := NewEvaluator(tokens(entry.expr))
+		if err != nil {
+			t.Fatalf("NewEvaluator(%q) failed with error %v", entry.expr, err)
+		}
+		got, err := eval.Evaluate(entry.r)
+		if entry.wantErr {
+			if err == nil {
+				t.Errorf("%q.Evaluate(%v) should have failed; got %v", entry.expr, entry.r, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q.Evaluate(%v) failed with error %v", entry.expr, entry.r, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("%q.Evaluate(%v) = %v; want %v", entry.expr, entry.r, got, entry.want)
+		}
+	}
+}
+
+func TestUnderEvaluator(t *testing.T) {
+	tokens := func(s string) []ConsumedElement {
+		var ce []ConsumedElement
+		for tkn := range lexer.New(s, 0) {
+			if tkn.Type == lexer.ItemEOF {
+				break
+			}
+			tknCopy := tkn
+			ce = append(ce, NewConsumedToken(&tknCopy))
+		}
+		return ce
+	}
+	book := table.Row{
+		"?s": &table.Cell{N: testutil.MustBuildNodeFromStrings(t, "/item/book", "000")},
+		"?o": &table.Cell{L: testutil.MustBuildLiteral(t, `"174"^^type:int64`)},
+	}
+	testTable := []struct {
+		expr string
+		want bool
+	}{
+		{`?s under /item`, true},
+		{`?s under /item/book`, true},
+		{`?s under /room`, false},
+		{`?s under /it`, false},
+		{`?s under /item/book/novel`, false},
+		{`?o under /item`, false},
+		{`(?s under /item) and (?s under /room)`, false},
+		{`not(?s under /room)`, true},
+	}
+	for _, entry := range testTable {
+		eval, err := NewEvaluator(tokens(entry.expr))
+		if err != nil {
+			t.Fatalf("NewEvaluator(%q) failed with error %v", entry.expr, err)
+		}
+		got, err := eval.Evaluate(book)
+		if err != nil {
+			t.Errorf("%q.Evaluate(%v) failed with error %v", entry.expr, book, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("%q.Evaluate(%v) = %v; want %v", entry.expr, book, got, entry.want)
+		}
+	}
+
+	for _, expr := range []string{`?s under /item/`, `?s under item`} {
+		if _, err := NewEvaluator(tokens(expr)); err == nil {
+			t.Errorf("NewEvaluator(%q) should have failed for an invalid type path", expr)
+		}
+	}
+}
+
+func TestNumericComparison(t *testing.T) {
+	tokens := func(s string) []ConsumedElement {
+		var ce []ConsumedElement
+		for tkn := range lexer.New(s, 0) {
+			if tkn.Type == lexer.ItemEOF {
+				break
+			}
+			tknCopy := tkn
+			ce = append(ce, NewConsumedToken(&tknCopy))
+		}
+		return ce
+	}
+	r := table.Row{
+		"?i": &table.Cell{L: testutil.MustBuildLiteral(t, `"174"^^type:int64`)},
+		"?f": &table.Cell{L: testutil.MustBuildLiteral(t, `"174.0"^^type:float64`)},
+		"?g": &table.Cell{L: testutil.MustBuildLiteral(t, `"174.5"^^type:float64`)},
+	}
+	testTable := []struct {
+		expr         string
+		strict, prom bool
+	}{
+		{expr: `?i = ?f`, strict: false, prom: true},
+		{expr: `(?i < ?g) and (?i = ?f)`, strict: false, prom: true},
+		{expr: `(?g > ?i) and (?f = ?i)`, strict: false, prom: true},
+		{expr: `?i = "174.0"^^type:float64`, strict: false, prom: true},
+		{expr: `?f = "174"^^type:int64`, strict: false, prom: true},
+		{expr: `?i = "174"^^type:int64`, strict: true, prom: true},
+		{expr: `?i = "0174"^^type:int64`, strict: true, prom: true},
+		{expr: `(?i = ?f) and not(?i = ?g)`, strict: false, prom: true},
+		{expr: `CAST(?f, type:int64) = ?f`, strict: false, prom: true},
+	}
+	for _, entry := range testTable {
+		eval, err := NewEvaluator(tokens(entry.expr))
+		if err != nil {
+			t.Fatalf("NewEvaluator(%q) failed with error %v", entry.expr, err)
+		}
+		for _, mode := range []struct {
+			m    NumericComparison
+			want bool
+		}{
+			{StrictNumericComparison, entry.strict},
+			{PromotedNumericComparison, entry.prom},
+		} {
+			got, err := WithNumericComparison(eval, mode.m).Evaluate(r)
+			if err != nil {
+				t.Errorf("%q.Evaluate(%v) with mode %d failed with error %v", entry.expr, r, mode.m, err)
+				continue
+			}
+			if got != mode.want {
+				t.Errorf("%q.Evaluate(%v) with mode %d = %v; want %v", entry.expr, r, mode.m, got, mode.want)
+			}
+		}
+	}
+}
+
+func TestStringCells(t *testing.T) {
+	text := "España 日本"
+	testTable := []struct {
+		in            *table.Cell
+		start, length int64
+		strlen        string
+		substr        string
+	}{
+		{
+			in:     &table.Cell{L: testutil.MustBuildLiteral(t, `"España 日本"^^type:text`)},
+			start:  4,
+			length: 5,
+			strlen: `"9"^^type:int64`,
+			substr: `"ña 日本"^^type:text`,
+		},
+		{
+			in:     &table.Cell{L: testutil.MustBuildLiteral(t, `"日本語"^^type:text@ja`)},
+			start:  1,
+			length: 1,
+			strlen: `"3"^^type:int64`,
+			substr: `"本"^^type:text@ja`,
+		},
+		{
+			in:     &table.Cell{S: &text},
+			start:  -4,
+			length: 2,
+			strlen: `"9"^^type:int64`,
+			substr: `"Es"^^type:text`,
+		},
+		{
+			in:     &table.Cell{S: &text},
+			start:  7,
+			length: 100,
+			strlen: `"9"^^type:int64`,
+			substr: `"日本"^^type:text`,
+		},
+		{
+			in:     &table.Cell{S: &text},
+			start:  20,
+			length: 3,
+			strlen: `"9"^^type:int64`,
+			substr: `""^^type:text`,
+		},
+		{
+			in:     &table.Cell{S: &text},
+			start:  2,
+			length: -1,
+			strlen: `"9"^^type:int64`,
+			substr: `""^^type:text`,
+		},
+	}
+	for _, entry := range testTable {
+		l, err := StrlenCell(entry.in)
+		if err != nil {
+			t.Fatalf("StrlenCell(%s) failed with error %v", entry.in, err)
+		}
+		if got, want := l.String(), entry.strlen; got != want {
+			t.Errorf("StrlenCell(%s) = %s; want %s", entry.in, got, want)
+		}
+		s, err := SubstrCell(entry.in, entry.start, entry.length)
+		if err != nil {
+			t.Fatalf("SubstrCell(%s, %d, %d) failed with error %v", entry.in, entry.start, entry.length, err)
+		}
+		if got, want := s.String(), entry.substr; got != want {
+			t.Errorf("SubstrCell(%s, %d, %d) = %s; want %s", entry.in, entry.start, entry.length, got, want)
+		}
+	}
+
+	n := &table.Cell{L: testutil.MustBuildLiteral(t, `"1"^^type:int64`)}
+	if _, err := StrlenCell(n); err == nil {
+		t.Errorf("StrlenCell(%s) should have failed for a non text literal", n)
+	}
+	if _, err := SubstrCell(n, 0, 1); err == nil {
+		t.Errorf("SubstrCell(%s, 0, 1) should have failed for a non text literal", n)
+	}
+}
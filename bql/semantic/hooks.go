@@ -17,12 +17,14 @@ package semantic
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/badwolf/bql/lexer"
 	"github.com/google/badwolf/bql/planner/filter"
 	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
@@ -43,6 +45,12 @@ var (
 
 	// boundRegexp contains the regular expression for not fully defined predicate bounds.
 	boundRegexp = regexp.MustCompile(`^"(.+)"@\["?([^\]"]*)"?,"?([^\]"]*)"?\]$`)
+	// rangesRegexp contains the regular expression for predicates bound to
+	// several time ranges separated by ;.
+	rangesRegexp = regexp.MustCompile(`^"(.+)"@\[([^\]]*;[^\]]*)\]$`)
+	// comparisonRegexp contains the regular expression for predicates bound by
+	// an inline anchor comparison, as in "p"@[> t].
+	comparisonRegexp = regexp.MustCompile(`^"(.+)"@\[\s*([<>]=?)\s*"?([^\]"]*)"?\]$`)
 )
 
 // DataAccumulatorHook returns the singleton for data accumulation.
@@ -75,6 +83,12 @@ func WhereNextWorkingClauseHook() ClauseHook {
 	return whereNextWorkingClause()
 }
 
+// WhereNextPredicateObjectPairClauseHook returns the singleton for closing
+// the current graph clause and starting a new one that shares its subject.
+func WhereNextPredicateObjectPairClauseHook() ClauseHook {
+	return whereNextPredicateObjectPair()
+}
+
 // WhereSubjectClauseHook returns the singleton for working clause hooks that
 // populates the subject.
 func WhereSubjectClauseHook() ElementHook {
@@ -93,6 +107,18 @@ func WhereObjectClauseHook() ElementHook {
 	return whereObjectClause()
 }
 
+// ClauseLimitHook returns the singleton for working clause hooks that
+// populates the limit of the clause lookups.
+func ClauseLimitHook() ElementHook {
+	return clauseLimit()
+}
+
+// WhereGuardClauseHook returns the singleton for working clause hooks that
+// populates the inline guard.
+func WhereGuardClauseHook() ElementHook {
+	return whereGuardClause()
+}
+
 // WhereFilterClauseHook returns the singleton for the working filter clause hook that
 // populates the filters list.
 func WhereFilterClauseHook() ElementHook {
@@ -111,6 +137,12 @@ func VarBindingsGraphChecker() ClauseHook {
 	return bindingsGraphChecker()
 }
 
+// DefaultGraphPattern returns the singleton that sets the graph pattern of
+// queries without a where clause to all the triples in the graphs.
+func DefaultGraphPattern() ElementHook {
+	return defaultGraphPattern()
+}
+
 // GroupByBindings returns the singleton for collecting all the group by
 // bindings.
 func GroupByBindings() ElementHook {
@@ -147,6 +179,60 @@ func HavingExpressionBuilder() ClauseHook {
 	return havingExpressionBuilder()
 }
 
+// UnwindCollection returns the singleton for collecting the bindings listed
+// in the unwind clause.
+func UnwindCollection() ElementHook {
+	return unwindCollection()
+}
+
+// UnwindBindingsChecker returns the singleton to check that the unwind
+// bindings are valid.
+func UnwindBindingsChecker() ClauseHook {
+	return unwindBindingsChecker()
+}
+
+// BindingTypeDeclarations returns the hook that collects the binding types
+// declared in the declare clause.
+func BindingTypeDeclarations() ElementHook {
+	return bindingTypeDeclarations()
+}
+
+// AnchorsLookupHook returns the hook that collects the subject and predicate
+// of the ANCHORS statement.
+func AnchorsLookupHook() ElementHook {
+	return anchorsLookup()
+}
+
+// KillQueryIDCollection returns the hook that collects the ID of the query
+// cancelled by a KILL statement.
+func KillQueryIDCollection() ElementHook {
+	return killQueryIDCollection()
+}
+
+// ViewNameCollection returns the hook that collects the name of the
+// materialized view a VIEW statement operates on.
+func ViewNameCollection() ElementHook {
+	return viewNameCollection()
+}
+
+// MergeConflictsCollection returns the hook that collects how MERGE statements
+// handle conflicting objects.
+func MergeConflictsCollection() ElementHook {
+	return mergeConflictsCollection()
+}
+
+// TraceLevelCollection returns the hook that collects the tracer verbosity
+// level of statements wrapped in a TRACE clause.
+func TraceLevelCollection() ElementHook {
+	return traceLevelCollection()
+}
+
+// AnchorCutoffCollection returns the hook that collects the anchor cutoff of
+// DELETE statements purging temporal triples.
+func AnchorCutoffCollection() ElementHook {
+	return anchorCutoffCollection()
+}
+
 // LimitCollection returns the limit collection hook.
 func LimitCollection() ElementHook {
 	return limitCollection()
@@ -202,16 +288,97 @@ func TypeBindingClauseHook(t StatementType) ClauseHook {
 	return hook
 }
 
+// IfNotExistsClauseHook returns a ClauseHook that flags the statement to ignore
+// the graphs that already exist.
+func IfNotExistsClauseHook() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.SetIfNotExists()
+		return hook, nil
+	}
+	return hook
+}
+
+// IfAbsentClauseHook returns a ClauseHook that flags the statement to only
+// insert the triples not already present in the output graphs.
+func IfAbsentClauseHook() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.SetIfAbsent()
+		return hook, nil
+	}
+	return hook
+}
+
+// DistinctClauseHook returns a ClauseHook that flags the statement to drop
+// the repeated rows of its results.
+func DistinctClauseHook() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.SetDistinct()
+		return hook, nil
+	}
+	return hook
+}
+
+// IfExistsClauseHook returns a ClauseHook that flags the statement to ignore
+// the graphs that do not exist.
+func IfExistsClauseHook() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		s.SetIfExists()
+		return hook, nil
+	}
+	return hook
+}
+
+// nowAnchor is the time anchor of the predicates of the DATA clause replaced
+// by the time the statement is executed.
+const nowAnchor = "NOW()"
+
+// replaceNowAnchor returns the provided predicate with its NOW() time anchor,
+// if any, replaced by a placeholder time so it can be parsed, and whether it
+// was replaced.
+func replaceNowAnchor(text string) (string, bool) {
+	raw := strings.TrimSpace(text)
+	idx := strings.LastIndex(raw, "\"@[")
+	if idx < 0 || !strings.HasSuffix(raw, "]") {
+		return text, false
+	}
+	if !strings.EqualFold(strings.TrimSpace(raw[idx+3:len(raw)-1]), nowAnchor) {
+		return text, false
+	}
+	return raw[:idx+3] + time.Time{}.Format(time.RFC3339Nano) + "]", true
+}
+
 // dataAccumulator creates a element hook that tracks fully formed triples and
-// adds them to the Statement when fully formed.
+// adds them to the Statement when fully formed. If the statement accepts
+// lenient data, the triples that fail to parse are skipped and recorded
+// instead. The triples with NOW() time anchors are tracked so they can be
+// resolved when the statement is executed.
 func dataAccumulator(b literal.Builder) ElementHook {
 	var (
 		hook ElementHook
 		s    *node.Node
 		p    *predicate.Predicate
 		o    *triple.Object
+		na   nowAnchors
+		skip int
 	)
 
+	// fail either rejects the statement or skips the remaining elements of the
+	// current triple. The partial triple is dropped either way, since the hook
+	// is reused by later statements.
+	fail := func(st *Statement, tkn *lexer.Token, remaining int, err error) (ElementHook, error) {
+		s, p, o, na = nil, nil, nil, nowAnchors{}
+		if !st.lenientData {
+			return nil, err
+		}
+		st.AddSkippedData(fmt.Errorf("skipped triple with %s: %w", tkn.Text, err))
+		skip = remaining
+		return hook, nil
+	}
+
 	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
 		if ce.IsSymbol() {
 			return hook, nil
@@ -220,13 +387,17 @@ func dataAccumulator(b literal.Builder) ElementHook {
 		if tkn.Type != lexer.ItemNode && tkn.Type != lexer.ItemPredicate && tkn.Type != lexer.ItemLiteral {
 			return hook, nil
 		}
+		if skip > 0 {
+			skip--
+			return hook, nil
+		}
 		if s == nil {
 			if tkn.Type != lexer.ItemNode {
 				return nil, fmt.Errorf("hook.DataAccumulator requires a node to create a subject, got %v instead", tkn)
 			}
 			tmp, err := node.Parse(tkn.Text)
 			if err != nil {
-				return nil, err
+				return fail(st, tkn, 2, err)
 			}
 			s = tmp
 			return hook, nil
@@ -235,25 +406,36 @@ func dataAccumulator(b literal.Builder) ElementHook {
 			if tkn.Type != lexer.ItemPredicate {
 				return nil, fmt.Errorf("hook.DataAccumulator requires a predicate to create a predicate, got %v instead", tkn)
 			}
-			tmp, err := predicate.Parse(tkn.Text)
+			text, now := replaceNowAnchor(tkn.Text)
+			tmp, err := predicate.ParseInLocation(text, st.defaultTimeZone)
 			if err != nil {
-				return nil, err
+				return fail(st, tkn, 1, err)
 			}
-			p = tmp
+			p, na.predicate = tmp, now
 			return hook, nil
 		}
 		if o == nil {
-			tmp, err := triple.ParseObject(tkn.Text, b)
+			text, now := tkn.Text, false
+			if tkn.Type == lexer.ItemPredicate {
+				text, now = replaceNowAnchor(text)
+			}
+			tmp, err := triple.ParseObject(text, b)
 			if err != nil {
-				return nil, err
+				return fail(st, tkn, 0, err)
 			}
-			o = tmp
+			o, na.object = tmp, now
 			trpl, err := triple.New(s, p, o)
 			if err != nil {
-				return nil, err
+				return fail(st, tkn, 0, err)
 			}
 			st.AddData(trpl)
-			s, p, o = nil, nil, nil
+			if na.predicate || na.object {
+				st.addNowData(trpl, na)
+			}
+			s, p, o, na = nil, nil, nil, nowAnchors{}
+			if err := st.flushData(); err != nil {
+				return nil, err
+			}
 			return hook, nil
 		}
 		return nil, fmt.Errorf("hook.DataAccumulator has failed to flush the triple %s, %s, %s", s, p, o)
@@ -338,6 +520,23 @@ func whereNextWorkingClause() ClauseHook {
 	return hook
 }
 
+// whereNextPredicateObjectPair returns a clause hook that closes the current
+// graph clause and starts a new working one with the same subject. Only the
+// subject itself is carried over, so the resulting clause is the same as if
+// the subject had been repeated.
+func whereNextPredicateObjectPair() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		wc := s.WorkingClause()
+		sub, sBinding := wc.S, wc.SBinding
+		s.AddWorkingGraphClause()
+		wc = s.WorkingClause()
+		wc.S, wc.SBinding = sub, sBinding
+		return hook, nil
+	}
+	return hook
+}
+
 // whereInitWorkingClause initialize a new working graph clause.
 func whereInitWorkingClause() ClauseHook {
 	var hook ClauseHook
@@ -367,13 +566,27 @@ func whereSubjectClause() ElementHook {
 			lastNopToken = nil
 			return hook, nil
 		case lexer.ItemRBracket:
+			// Closing an OPTIONAL or NOT block.
+			st.workingOptionalGroup, st.workingNegatedGroup = 0, 0
 			lastNopToken = nil
 			return hook, nil
 		case lexer.ItemOptional:
-			c.Optional = true
+			st.optionalGroups++
+			st.workingOptionalGroup = st.optionalGroups
+			c.Optional, c.OptionalGroup = true, st.workingOptionalGroup
+			lastNopToken = nil
+			return hook, nil
+		case lexer.ItemNot:
+			st.negated = append(st.negated, nil)
+			st.workingNegatedGroup = len(st.negated)
+			c.NegatedGroup = st.workingNegatedGroup
 			lastNopToken = nil
 			return hook, nil
 		case lexer.ItemNode:
+			if st.workingOptionalGroup != 0 {
+				c.Optional, c.OptionalGroup = true, st.workingOptionalGroup
+			}
+			c.NegatedGroup = st.workingNegatedGroup
 			if c.S != nil {
 				return nil, fmt.Errorf("invalid node in where clause that already has a subject; current %v, got %v", c.S, tkn.Type)
 			}
@@ -389,6 +602,10 @@ func whereSubjectClause() ElementHook {
 				if c.SBinding != "" {
 					return nil, fmt.Errorf("subject binding %q is already set to %q", tkn.Text, c.SBinding)
 				}
+				if st.workingOptionalGroup != 0 {
+					c.Optional, c.OptionalGroup = true, st.workingOptionalGroup
+				}
+				c.NegatedGroup = st.workingNegatedGroup
 				c.SBinding = tkn.Text
 				lastNopToken = nil
 				return hook, nil
@@ -425,7 +642,7 @@ func whereSubjectClause() ElementHook {
 }
 
 // processPredicate parses a consumed element and returns a predicate and its attributes if possible.
-func processPredicate(ce ConsumedElement) (*predicate.Predicate, string, string, bool, error) {
+func processPredicate(ce ConsumedElement, loc *time.Location) (*predicate.Predicate, string, string, bool, error) {
 	var (
 		nP             *predicate.Predicate
 		pID            string
@@ -433,7 +650,7 @@ func processPredicate(ce ConsumedElement) (*predicate.Predicate, string, string,
 		temporal       bool
 	)
 	raw := ce.Token().Text
-	p, err := predicate.Parse(raw)
+	p, err := predicate.ParseInLocation(raw, loc)
 	if err == nil {
 		// A fully specified predicate was provided.
 		nP = p
@@ -454,7 +671,7 @@ func processPredicate(ce ConsumedElement) (*predicate.Predicate, string, string,
 }
 
 // processPredicate parses a consumed element and returns a bound predicate and its attributes if possible.
-func processPredicateBound(ce ConsumedElement) (string, string, string, *time.Time, *time.Time, bool, error) {
+func processPredicateBound(ce ConsumedElement, loc *time.Location) (string, string, string, *time.Time, *time.Time, bool, error) {
 	var (
 		pID              string
 		pLowerBoundAlias string
@@ -475,7 +692,7 @@ func processPredicateBound(ce ConsumedElement) (string, string, string, *time.Ti
 	} else {
 		stl := strings.TrimSpace(tl)
 		if stl != "" {
-			ptl, err := time.Parse(time.RFC3339Nano, stl)
+			ptl, err := predicate.ParseTimeAnchor(stl, loc)
 			if err != nil {
 				return "", "", "", nil, nil, false, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", tl, raw, err)
 			}
@@ -488,7 +705,7 @@ func processPredicateBound(ce ConsumedElement) (string, string, string, *time.Ti
 	} else {
 		stu := strings.TrimSpace(tu)
 		if stu != "" {
-			ptu, err := time.Parse(time.RFC3339Nano, stu)
+			ptu, err := predicate.ParseTimeAnchor(stu, loc)
 			if err != nil {
 				return "", "", "", nil, nil, false, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", tu, raw, err)
 			}
@@ -504,6 +721,87 @@ func processPredicateBound(ce ConsumedElement) (string, string, string, *time.Ti
 	return pID, pLowerBoundAlias, pUpperBoundAlias, pLowerBound, pUpperBound, true, nil
 }
 
+// processPredicateComparison parses a consumed element containing a predicate
+// bound by an inline anchor comparison, as in "p"@[> t]. It returns the
+// predicate ID, the lower or upper bound set by the comparison, and whether
+// the bound is excluded. The compared anchor cannot be a binding.
+func processPredicateComparison(ce ConsumedElement, loc *time.Location) (string, *time.Time, *time.Time, bool, error) {
+	raw := ce.Token().Text
+	cmps := comparisonRegexp.FindStringSubmatch(raw)
+	if len(cmps) != 4 {
+		return "", nil, nil, false, fmt.Errorf("failed to extract the anchor comparison of predicate bound %q", raw)
+	}
+	id, op, ta := cmps[1], cmps[2], strings.TrimSpace(cmps[3])
+	if strings.Index(ta, "?") != -1 {
+		return "", nil, nil, false, fmt.Errorf("invalid anchor comparison %s in %s; bindings can only be used as , separated bounds", ta, raw)
+	}
+	t, err := predicate.ParseTimeAnchor(ta, loc)
+	if err != nil {
+		return "", nil, nil, false, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", ta, raw, err)
+	}
+	open := len(op) == 1
+	if op[0] == '>' {
+		return id, &t, nil, open, nil
+	}
+	return id, nil, &t, open, nil
+}
+
+// processPredicateRanges parses a consumed element containing a predicate bound
+// to several time ranges, as in "p"@[a,b; c,d]. It returns the predicate ID,
+// the ranges, and the lower and upper bounds enclosing all of them. Range
+// bounds cannot be bindings.
+func processPredicateRanges(ce ConsumedElement, loc *time.Location) (string, []storage.TimeRange, *time.Time, *time.Time, error) {
+	raw := ce.Token().Text
+	cmps := rangesRegexp.FindStringSubmatch(raw)
+	if len(cmps) != 3 {
+		return "", nil, nil, nil, fmt.Errorf("failed to extract the time ranges of predicate bound %q", raw)
+	}
+	parse := func(s string) (*time.Time, error) {
+		s = strings.Trim(strings.TrimSpace(s), `"`)
+		if s == "" {
+			return nil, nil
+		}
+		if strings.Index(s, "?") != -1 {
+			return nil, fmt.Errorf("invalid time range bound %s in %s; bindings can only be used in predicates bound to a single range", s, raw)
+		}
+		t, err := predicate.ParseTimeAnchor(s, loc)
+		if err != nil {
+			return nil, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", s, raw, err)
+		}
+		return &t, nil
+	}
+	var (
+		rngs         []storage.TimeRange
+		lower, upper *time.Time
+	)
+	for i, rs := range strings.Split(cmps[2], ";") {
+		bs := strings.Split(rs, ",")
+		if len(bs) != 2 {
+			return "", nil, nil, nil, fmt.Errorf("invalid time range %q in %s; ranges require a lower and an upper bound separated by ,", strings.TrimSpace(rs), raw)
+		}
+		l, err := parse(bs[0])
+		if err != nil {
+			return "", nil, nil, nil, err
+		}
+		u, err := parse(bs[1])
+		if err != nil {
+			return "", nil, nil, nil, err
+		}
+		if l != nil && u != nil && l.After(*u) {
+			lb, ub := l.Format(time.RFC3339Nano), u.Format(time.RFC3339Nano)
+			return "", nil, nil, nil, fmt.Errorf("invalid time bound; lower bound %s after upper bound %s", lb, ub)
+		}
+		rngs = append(rngs, storage.TimeRange{Lower: l, Upper: u})
+		if i == 0 || (lower != nil && (l == nil || l.Before(*lower))) {
+			lower = l
+		}
+		if i == 0 || (upper != nil && (u == nil || u.After(*upper))) {
+			upper = u
+		}
+	}
+	return cmps[1], rngs, lower, upper, nil
+}
+
 // wherePredicateClause returns an element hook that updates the predicate
 // modifiers on the working graph clause.
 func wherePredicateClause() ElementHook {
@@ -523,7 +821,7 @@ func wherePredicateClause() ElementHook {
 			if c.P != nil {
 				return nil, fmt.Errorf("invalid predicate %s on graph clause since already set to %s", tkn.Text, c.P)
 			}
-			p, pID, pAnchorBinding, pTemporal, err := processPredicate(ce)
+			p, pID, pAnchorBinding, pTemporal, err := processPredicate(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
@@ -531,10 +829,27 @@ func wherePredicateClause() ElementHook {
 			return hook, nil
 		case lexer.ItemPredicateBound:
 			lastNopToken = nil
-			if c.PLowerBound != nil || c.PUpperBound != nil || c.PLowerBoundAlias != "" || c.PUpperBoundAlias != "" {
+			if c.PLowerBound != nil || c.PUpperBound != nil || c.PLowerBoundAlias != "" || c.PUpperBoundAlias != "" || len(c.PAnchorRanges) > 0 {
 				return nil, fmt.Errorf("invalid predicate bound %s on graph clause since already set to %s", tkn.Text, c.P)
 			}
-			pID, pLowerBoundAlias, pUpperBoundAlias, pLowerBound, pUpperBound, pTemp, err := processPredicateBound(ce)
+			if rangesRegexp.MatchString(tkn.Text) {
+				pID, rngs, pLowerBound, pUpperBound, err := processPredicateRanges(ce, st.defaultTimeZone)
+				if err != nil {
+					return nil, err
+				}
+				c.PID, c.PAnchorRanges, c.PLowerBound, c.PUpperBound, c.PTemporal = pID, rngs, pLowerBound, pUpperBound, true
+				return hook, nil
+			}
+			if comparisonRegexp.MatchString(tkn.Text) {
+				pID, pLowerBound, pUpperBound, open, err := processPredicateComparison(ce, st.defaultTimeZone)
+				if err != nil {
+					return nil, err
+				}
+				c.PID, c.PLowerBound, c.PUpperBound, c.PTemporal = pID, pLowerBound, pUpperBound, true
+				c.PLowerBoundOpen, c.PUpperBoundOpen = open && pLowerBound != nil, open && pUpperBound != nil
+				return hook, nil
+			}
+			pID, pLowerBoundAlias, pUpperBoundAlias, pLowerBound, pUpperBound, pTemp, err := processPredicateBound(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
@@ -569,6 +884,20 @@ func wherePredicateClause() ElementHook {
 			}
 			lastNopToken = nil
 			return hook, nil
+		case lexer.ItemQuotedString:
+			if c.PBinding == "" {
+				return nil, fmt.Errorf("predicate ID %s can only restrict predicate bindings", tkn.Text)
+			}
+			id, err := strconv.Unquote(tkn.Text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid predicate ID %s: %v", tkn.Text, err)
+			}
+			if id == "" {
+				return nil, fmt.Errorf("predicate ID %s cannot be empty", tkn.Text)
+			}
+			c.PIDConstant = id
+			lastNopToken = nil
+			return hook, nil
 		}
 		lastNopToken = tkn
 		return hook, nil
@@ -610,7 +939,7 @@ func whereObjectClause() ElementHook {
 				pred *predicate.Predicate
 				err  error
 			)
-			pred, c.OID, c.OAnchorBinding, c.OTemporal, err = processPredicate(ce)
+			pred, c.OID, c.OAnchorBinding, c.OTemporal, err = processPredicate(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
@@ -620,10 +949,18 @@ func whereObjectClause() ElementHook {
 			return hook, nil
 		case lexer.ItemPredicateBound:
 			lastNopToken = nil
-			if c.OLowerBound != nil || c.OUpperBound != nil || c.OLowerBoundAlias != "" || c.OUpperBoundAlias != "" {
+			if c.OLowerBound != nil || c.OUpperBound != nil || c.OLowerBoundAlias != "" || c.OUpperBoundAlias != "" || len(c.OAnchorRanges) > 0 {
 				return nil, fmt.Errorf("invalid predicate bound %s on graph clause since already set to %s", tkn.Text, c.O)
 			}
-			oID, oLowerBoundAlias, oUpperBoundAlias, oLowerBound, oUpperBound, oTemp, err := processPredicateBound(ce)
+			if rangesRegexp.MatchString(tkn.Text) {
+				oID, rngs, oLowerBound, oUpperBound, err := processPredicateRanges(ce, st.defaultTimeZone)
+				if err != nil {
+					return nil, err
+				}
+				c.OID, c.OAnchorRanges, c.OLowerBound, c.OUpperBound, c.OTemporal = oID, rngs, oLowerBound, oUpperBound, true
+				return hook, nil
+			}
+			oID, oLowerBoundAlias, oUpperBoundAlias, oLowerBound, oUpperBound, oTemp, err := processPredicateBound(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
@@ -665,6 +1002,17 @@ func whereObjectClause() ElementHook {
 				return nil, fmt.Errorf("binding %q found after invalid token %s", tkn.Text, lastNopToken)
 			}
 			return hook, nil
+		case lexer.ItemNodeType:
+			lastNopToken = nil
+			if c.OBinding == "" {
+				return nil, fmt.Errorf("node type %s can only restrict object bindings", tkn.Text)
+			}
+			t, err := node.NewType(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			c.OTypeConstant = t
+			return hook, nil
 		}
 		lastNopToken = tkn
 		return hook, nil
@@ -737,6 +1085,84 @@ func validateFilterClause(f *FilterClause) error {
 	return nil
 }
 
+// whereGuardClause returns an element hook that sets the inline guard of the
+// working graph clause. The guard can only refer to bindings produced by the
+// clause itself.
+func whereGuardClause() ElementHook {
+	var (
+		hook  ElementHook
+		guard *ClauseGuard
+	)
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		c := st.WorkingClause()
+		switch tkn.Type {
+		case lexer.ItemWhere:
+			guard = &ClauseGuard{}
+		case lexer.ItemBinding:
+			b := strings.TrimSpace(tkn.Text)
+			if b == AnonymousBinding {
+				return nil, fmt.Errorf("the anonymous binding %s cannot be used on a clause guard", b)
+			}
+			if _, ok := c.BindingsMap()[b]; !ok {
+				return nil, fmt.Errorf("binding %s used on the guard is not produced by clause %v", b, c)
+			}
+			guard.Binding = b
+		case lexer.ItemLT:
+			guard.Op = LT
+		case lexer.ItemGT:
+			guard.Op = GT
+		case lexer.ItemEQ:
+			guard.Op = EQ
+		case lexer.ItemLiteral:
+			l, err := literal.DefaultBuilder().Parse(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			guard.Value = l
+			c.Guard = guard
+		default:
+			return nil, fmt.Errorf("unexpected token %q on clause guard", tkn.Text)
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// clauseLimit returns an element hook that sets the limit of the lookups of
+// the working graph clause. The limit must be a positive int64 literal.
+func clauseLimit() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type == lexer.ItemLimit {
+			return hook, nil
+		}
+		if ce.token.Type != lexer.ItemLiteral {
+			return nil, fmt.Errorf("clause limit required an int64 literal; found %v instead", ce.token)
+		}
+		l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse clause limit literal %q with error %v", ce.token.Text, err)
+		}
+		if l.Type() != literal.Int64 {
+			return nil, fmt.Errorf("clause limit required an int64 value; found %s instead", l)
+		}
+		lv, err := l.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the int64 value for literal %v with error %v", l, err)
+		}
+		if lv <= 0 {
+			return nil, fmt.Errorf("clause limit must be positive; found %d instead", lv)
+		}
+		st.WorkingClause().Limit = lv
+		return hook, nil
+	}
+	return hook
+}
+
 // whereFilterClause returns an element hook that updates the working filter clause and,
 // if the filter clause is complete, populates the filters list of the statement.
 func whereFilterClause() ElementHook {
@@ -761,7 +1187,7 @@ func whereFilterClause() ElementHook {
 				return nil, err
 			}
 			return hook, nil
-		case lexer.ItemLiteral:
+		case lexer.ItemLiteral, lexer.ItemNode:
 			err := addValueToWorkingFilter(tkn.Text, st.WorkingFilter())
 			if err != nil {
 				return nil, err
@@ -786,6 +1212,8 @@ func varAccumulator() ElementHook {
 	var (
 		hook         ElementHook
 		lastNopToken *lexer.Token
+		inArgs       bool
+		substrArgs   int
 	)
 	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
 		if ce.IsSymbol() {
@@ -795,6 +1223,13 @@ func varAccumulator() ElementHook {
 		p := st.WorkingProjection()
 		switch tkn.Type {
 		case lexer.ItemBinding:
+			if p.Func == lexer.ItemTriple && inArgs {
+				p.Args = append(p.Args, tkn.Text)
+				if len(p.Args) == 3 {
+					p.Binding = TripleBinding(p.Args[0], p.Args[1], p.Args[2])
+				}
+				break
+			}
 			if p.Binding == "" {
 				p.Binding = tkn.Text
 			} else {
@@ -808,12 +1243,80 @@ func varAccumulator() ElementHook {
 			}
 		case lexer.ItemAs:
 			lastNopToken = tkn
+		case lexer.ItemStar:
+			st.projectAll = true
 		case lexer.ItemSum, lexer.ItemCount:
 			p.OP = tkn.Type
+		case lexer.ItemGroupConcat:
+			p.OP, p.Separator, inArgs = tkn.Type, DefaultConcatSeparator, true
+		case lexer.ItemHistogram:
+			p.OP, inArgs = tkn.Type, true
+		case lexer.ItemQuotedString:
+			if p.OP == lexer.ItemHistogram {
+				i, err := histogramInterval(tkn)
+				if err != nil {
+					return nil, err
+				}
+				p.Interval = i
+				break
+			}
+			sep, err := strconv.Unquote(tkn.Text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GROUP_CONCAT separator %s: %v", tkn.Text, err)
+			}
+			p.Separator = sep
 		case lexer.ItemDistinct:
 			p.Modifier = tkn.Type
+		case lexer.ItemCast, lexer.ItemTriple:
+			p.Func, inArgs = tkn.Type, true
+		case lexer.ItemLang, lexer.ItemKind, lexer.ItemDatatype, lexer.ItemStrlen:
+			p.Func = tkn.Type
+		case lexer.ItemGraph:
+			p.Binding = GraphBinding
+		case lexer.ItemGraphCount:
+			p.Binding, p.OP = GraphBinding, tkn.Type
+		case lexer.ItemGroupIndex:
+			p.Binding, p.OP = GroupIndexBinding, tkn.Type
+		case lexer.ItemLiteralType:
+			t, err := CastType(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			p.CastType = t
+		case lexer.ItemSubstr:
+			p.Func, inArgs, substrArgs = tkn.Type, true, 0
+		case lexer.ItemLiteral:
+			if p.OP == lexer.ItemHistogram {
+				i, err := histogramInterval(tkn)
+				if err != nil {
+					return nil, err
+				}
+				p.Interval = i
+				break
+			}
+			if p.Func != lexer.ItemSubstr {
+				return nil, fmt.Errorf("unexpected literal %s in projection %s", tkn.Text, p)
+			}
+			v, err := substrArgument(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			if substrArgs == 0 {
+				p.SubstrStart = v
+			} else {
+				p.SubstrLen = v
+			}
+			substrArgs++
+		case lexer.ItemRPar:
+			inArgs, lastNopToken = false, nil
+			if p.OP == lexer.ItemHistogram {
+				// HISTOGRAM projects fixed bindings, so it takes no alias.
+				st.AddWorkingProjection()
+			}
 		case lexer.ItemComma:
-			st.AddWorkingProjection()
+			if !inArgs {
+				st.AddWorkingProjection()
+			}
 		default:
 			lastNopToken = nil
 		}
@@ -822,6 +1325,45 @@ func varAccumulator() ElementHook {
 	return hook
 }
 
+// histogramInterval returns the interval provided to HISTOGRAM, either as a
+// quoted string or as a text literal.
+func histogramInterval(tkn *lexer.Token) (*table.Interval, error) {
+	var s string
+	if tkn.Type == lexer.ItemQuotedString {
+		us, err := strconv.Unquote(tkn.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HISTOGRAM interval %s: %v", tkn.Text, err)
+		}
+		s = us
+	} else {
+		l, err := literal.DefaultBuilder().Parse(tkn.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HISTOGRAM interval %s: %v", tkn.Text, err)
+		}
+		if s, err = l.Text(); err != nil {
+			return nil, fmt.Errorf("HISTOGRAM interval must be a text literal; found %s instead", l)
+		}
+	}
+	i, err := table.ParseInterval(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HISTOGRAM interval: %v", err)
+	}
+	return i, nil
+}
+
+// substrArgument returns the value of the provided SUBSTR start or length
+// argument, which must be an int64 literal.
+func substrArgument(s string) (int64, error) {
+	l, err := literal.DefaultBuilder().Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	if l.Type() != literal.Int64 {
+		return 0, fmt.Errorf("SUBSTR start and length must be int64 literals; found %s instead", s)
+	}
+	return l.Int64()
+}
+
 // bindingsGraphChecker validate that all input bindings are provided by the
 // graph pattern.
 func bindingsGraphChecker() ClauseHook {
@@ -829,12 +1371,127 @@ func bindingsGraphChecker() ClauseHook {
 	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
 		// Force working projection flush.
 		s.AddWorkingProjection()
+		if s.projectAll {
+			s.projectAllBindings()
+		}
 		bs := s.BindingsMap()
 		for _, b := range s.InputBindings() {
 			if _, ok := bs[b]; !ok {
 				return nil, fmt.Errorf("specified binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
 			}
 		}
+		for b := range s.BindingTypes() {
+			if _, ok := bs[b]; !ok {
+				return nil, fmt.Errorf("declared binding %s not found in where clause, only %v bindings are available", b, s.Bindings())
+			}
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// defaultGraphPattern returns an element hook that, once the where clause of
+// a query has been consumed, sets the graph pattern to ?s ?p ?o if the query
+// omitted it.
+func defaultGraphPattern() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if !ce.IsSymbol() || ce.Symbol() != "QUERY_WHERE" || len(st.pattern) > 0 {
+			return hook, nil
+		}
+		st.pattern = append(st.pattern, &GraphClause{
+			SBinding: "?s",
+			PBinding: "?p",
+			OBinding: "?o",
+		})
+		if _, err := bindingsGraphChecker()(st, ce.Symbol()); err != nil {
+			return nil, err
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// bindingTypeDeclarations collects the binding types listed in the declare
+// clause.
+func bindingTypeDeclarations() ElementHook {
+	var (
+		hook    ElementHook
+		binding string
+	)
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemDeclare:
+			binding = ""
+		case lexer.ItemStrict:
+			st.SetStrictBindingTypes(true)
+		case lexer.ItemBinding:
+			if tkn.Text == AnonymousBinding {
+				return nil, fmt.Errorf("the anonymous binding %s cannot be declared", AnonymousBinding)
+			}
+			binding = tkn.Text
+		case lexer.ItemLiteralType:
+			t, err := literal.ParseType(strings.TrimPrefix(tkn.Text, "type:"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid type declared for binding %s: %v", binding, err)
+			}
+			if err := st.DeclareBindingType(binding, t); err != nil {
+				return nil, err
+			}
+			binding = ""
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// unwindCollection collects the bindings listed in the unwind clause.
+func unwindCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemUnwind, lexer.ItemComma:
+			st.unwind = append(st.unwind, &UnwindClause{})
+		case lexer.ItemBinding:
+			if len(st.unwind) == 0 {
+				return nil, fmt.Errorf("unwind binding %s found outside an unwind clause", tkn.Text)
+			}
+			u := st.unwind[len(st.unwind)-1]
+			if u.Binding == "" {
+				u.Binding = tkn.Text
+			} else {
+				u.Alias = tkn.Text
+			}
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// unwindBindingsChecker checks that the unwind clauses expand bindings
+// available on the graph pattern into new bindings.
+func unwindBindingsChecker() ClauseHook {
+	var hook ClauseHook
+	hook = func(s *Statement, _ Symbol) (ClauseHook, error) {
+		bs := s.patternBindingsMap()
+		seen := make(map[string]bool)
+		for _, u := range s.unwind {
+			if _, ok := bs[u.Binding]; !ok {
+				return nil, fmt.Errorf("unwind binding %s not found in where clause, only %v bindings are available", u.Binding, s.Bindings())
+			}
+			if _, ok := bs[u.Alias]; ok || seen[u.Alias] {
+				return nil, fmt.Errorf("unwind alias %s is already in use", u.Alias)
+			}
+			seen[u.Alias] = true
+		}
 		return hook, nil
 	}
 	return hook
@@ -848,8 +1505,38 @@ func groupByBindings() ElementHook {
 			return hook, nil
 		}
 		tkn := ce.Token()
-		if tkn.Type == lexer.ItemBinding {
+		switch tkn.Type {
+		case lexer.ItemBinding:
 			st.groupBy = append(st.groupBy, tkn.Text)
+		case lexer.ItemQuotedString, lexer.ItemLiteral:
+			if len(st.groupBy) == 0 {
+				return nil, fmt.Errorf("BUCKET interval %s requires a binding", tkn.Text)
+			}
+			b := st.groupBy[len(st.groupBy)-1]
+			var s string
+			if tkn.Type == lexer.ItemQuotedString {
+				us, err := strconv.Unquote(tkn.Text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BUCKET interval %s for binding %q: %v", tkn.Text, b, err)
+				}
+				s = us
+			} else {
+				l, err := literal.DefaultBuilder().Parse(tkn.Text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid BUCKET interval %s for binding %q: %v", tkn.Text, b, err)
+				}
+				if s, err = l.Text(); err != nil {
+					return nil, fmt.Errorf("BUCKET interval for binding %q must be a text literal; found %s instead", b, l)
+				}
+			}
+			i, err := table.ParseInterval(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BUCKET interval for binding %q: %v", b, err)
+			}
+			if st.groupByBuckets == nil {
+				st.groupByBuckets = make(map[string]*table.Interval)
+			}
+			st.groupByBuckets[b] = i
 		}
 		return hook, nil
 	}
@@ -879,11 +1566,44 @@ func groupByBindingsChecker() ClauseHook {
 				return nil, fmt.Errorf("invalid GROUP BY binging %s; available bindings %v", gb, s.OutputBindings())
 			}
 		}
+		grpIdx, hist := false, 0
+		for _, prj := range s.projection {
+			switch prj.OP {
+			case lexer.ItemGroupIndex:
+				grpIdx = true
+			case lexer.ItemHistogram:
+				hist++
+			case lexer.ItemGraphCount:
+				for idx := range idxs {
+					if s.projection[idx].Binding == GraphBinding {
+						return nil, fmt.Errorf("GRAPH_COUNT() cannot be combined with GROUP BY %s", s.projection[idx].Alias)
+					}
+				}
+			}
+		}
+		if hist > 1 {
+			return nil, fmt.Errorf("only one HISTOGRAM can be projected; found %d", hist)
+		}
 		for idx, prj := range s.projection {
 			if idxs[idx] {
+				if hist > 0 && (prj.Alias == HistogramStartBinding || prj.Alias == HistogramCountBinding || (prj.Alias == "" && (prj.Binding == HistogramStartBinding || prj.Binding == HistogramCountBinding))) {
+					return nil, fmt.Errorf("GROUP BY binding %s clashes with the bindings projected by HISTOGRAM", prj)
+				}
 				continue
 			}
-			if len(s.groupBy) > 0 && prj.OP == lexer.ItemError {
+			if grpIdx && prj.OP != lexer.ItemError && prj.OP != lexer.ItemGroupIndex {
+				return nil, fmt.Errorf("GROUP_INDEX() cannot be combined with the %s aggregation function", prj.OP)
+			}
+			if hist > 0 && prj.OP != lexer.ItemHistogram {
+				if prj.OP != lexer.ItemError {
+					return nil, fmt.Errorf("HISTOGRAM cannot be combined with the %s aggregation function", prj.OP)
+				}
+				return nil, fmt.Errorf("Binding %q not listed on GROUP BY cannot be projected along HISTOGRAM", prj.Binding)
+			}
+			if prj.OP == lexer.ItemHistogram {
+				continue
+			}
+			if len(s.groupBy) > 0 && prj.OP == lexer.ItemError && !grpIdx {
 				return nil, fmt.Errorf("Binding %q not listed on GROUP BY requires an aggregation function", prj.Binding)
 			}
 			if len(s.groupBy) == 0 && prj.OP != lexer.ItemError {
@@ -899,21 +1619,107 @@ func groupByBindingsChecker() ClauseHook {
 	return hook
 }
 
-// orderByBindings collects the bindings listed in the order by clause.
+// orderByKey contains the function and arguments of a computed order by key.
+type orderByKey struct {
+	fn                     lexer.TokenType
+	binding                string
+	castType               literal.Type
+	substrStart, substrLen int64
+	substrArgs             int
+}
+
+// name returns the name of the transient column holding the key values.
+func (k *orderByKey) name() string {
+	switch k.fn {
+	case lexer.ItemCast:
+		return fmt.Sprintf("CAST(%s, %s)", k.binding, k.castType)
+	case lexer.ItemSubstr:
+		return fmt.Sprintf("SUBSTR(%s, %d, %d)", k.binding, k.substrStart, k.substrLen)
+	default:
+		return fmt.Sprintf("%s(%s)", k.fn, k.binding)
+	}
+}
+
+// value computes the key for the provided row.
+func (k *orderByKey) value(r table.Row) (*table.Cell, error) {
+	c, ok := r[k.binding]
+	if !ok {
+		return nil, fmt.Errorf("missing binding %q", k.binding)
+	}
+	switch k.fn {
+	case lexer.ItemCast:
+		return CastCell(c, k.castType)
+	case lexer.ItemLang:
+		return LangCell(c)
+	case lexer.ItemKind:
+		return KindCell(c)
+	case lexer.ItemDatatype:
+		return DatatypeCell(c)
+	case lexer.ItemStrlen:
+		return StrlenCell(c)
+	case lexer.ItemSubstr:
+		return SubstrCell(c, k.substrStart, k.substrLen)
+	default:
+		return nil, fmt.Errorf("unsupported order by function %s", k.fn)
+	}
+}
+
+// orderByBindings collects the bindings and the computed keys listed in the
+// order by clause.
 func orderByBindings() ElementHook {
-	var hook func(st *Statement, ce ConsumedElement) (ElementHook, error)
+	var (
+		hook func(st *Statement, ce ConsumedElement) (ElementHook, error)
+		key  *orderByKey
+	)
 	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
 		if ce.IsSymbol() {
 			return hook, nil
 		}
 		tkn := ce.Token()
 		switch tkn.Type {
+		case lexer.ItemOrder:
+			key = nil
+		case lexer.ItemCast, lexer.ItemLang, lexer.ItemKind, lexer.ItemDatatype, lexer.ItemStrlen, lexer.ItemSubstr:
+			key = &orderByKey{fn: tkn.Type}
+		case lexer.ItemLiteralType:
+			t, err := CastType(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			key.castType = t
+		case lexer.ItemLiteral:
+			v, err := substrArgument(tkn.Text)
+			if err != nil {
+				return nil, err
+			}
+			if key.substrArgs == 0 {
+				key.substrStart = v
+			} else {
+				key.substrLen = v
+			}
+			key.substrArgs++
+		case lexer.ItemRPar:
+			k := key
+			if st.orderByArgs == nil {
+				st.orderByArgs = make(map[string]string)
+			}
+			st.orderByArgs[k.name()] = k.binding
+			st.orderBy = append(st.orderBy, table.SortConfig{{Binding: k.name(), Key: k.value}}...)
+			key = nil
 		case lexer.ItemBinding:
+			if key != nil {
+				key.binding = tkn.Text
+				break
+			}
 			st.orderBy = append(st.orderBy, table.SortConfig{{Binding: tkn.Text}}...)
 		case lexer.ItemAsc:
 			st.orderBy[len(st.orderBy)-1].Desc = false
 		case lexer.ItemDesc:
 			st.orderBy[len(st.orderBy)-1].Desc = true
+		case lexer.ItemFirst:
+			st.orderBy[len(st.orderBy)-1].Nulls = table.NullsFirst
+		case lexer.ItemLast:
+			st.orderBy[len(st.orderBy)-1].Nulls = table.NullsLast
 		}
 		return hook, nil
 	}
@@ -941,17 +1747,27 @@ func orderByBindingsChecker() ClauseHook {
 			} else {
 				seen[cfg.Binding] = cfg.Desc
 			}
-			// Check that the binding exist.
-			if _, ok := outs[cfg.Binding]; !ok {
-				return nil, fmt.Errorf("order by binding %q unknown; available bindings are %v", cfg.Binding, s.OutputBindings())
+			// Check that the binding exist, or the binding computed keys use.
+			b := cfg.Binding
+			if arg, ok := s.orderByArgs[b]; ok {
+				b = arg
+			}
+			if _, ok := outs[b]; !ok {
+				return nil, fmt.Errorf("order by binding %q unknown; available bindings are %v", b, s.OutputBindings())
 			}
 		}
-		// If dups exist rewrite the order by SortConfig.
+		// If dups exist rewrite the order by SortConfig keeping the first
+		// occurrence of each binding.
 		if dups {
-			s.orderBy = table.SortConfig{}
-			for b, d := range seen {
-				s.orderBy = append(s.orderBy, table.SortConfig{{Binding: b, Desc: d}}...)
+			ob := table.SortConfig{}
+			kept := make(map[string]bool)
+			for _, cfg := range s.orderBy {
+				if !kept[cfg.Binding] {
+					kept[cfg.Binding] = true
+					ob = append(ob, cfg)
+				}
 			}
+			s.orderBy = ob
 		}
 		return hook, nil
 	}
@@ -1020,6 +1836,166 @@ func limitCollection() ElementHook {
 	return hook
 }
 
+// anchorsLookup collects the subject and the predicate ID of the ANCHORS
+// statement. The predicate must bind its anchor, as in "bought"@[?t], and
+// the binding names the anchors returned.
+func anchorsLookup() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		tkn := ce.Token()
+		switch tkn.Type {
+		case lexer.ItemNode:
+			n, err := ToNode(ce)
+			if err != nil {
+				return nil, err
+			}
+			st.anchorsSubject = n
+		case lexer.ItemPredicate:
+			p, pID, pAnchorBinding, _, err := processPredicate(ce, st.defaultTimeZone)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil || pAnchorBinding == "" || !strings.HasPrefix(pAnchorBinding, "?") {
+				return nil, fmt.Errorf("ANCHORS requires a predicate with an anchor binding, as in \"bought\"@[?t]; found %s instead", tkn.Text)
+			}
+			if pAnchorBinding == AnonymousBinding {
+				return nil, fmt.Errorf("ANCHORS cannot bind the anchors to the anonymous binding %s", AnonymousBinding)
+			}
+			st.anchorsPredicateID, st.anchorsBinding = pID, pAnchorBinding
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// traceLevelCollection collects the tracer verbosity level of the TRACE
+// clause.
+func traceLevelCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		if ce.token.Type == lexer.ItemTrace {
+			if st.TraceLevel() != 0 {
+				return nil, fmt.Errorf("a statement can only be wrapped in a single TRACE clause")
+			}
+			return hook, nil
+		}
+		if ce.token.Type != lexer.ItemLiteral {
+			return nil, fmt.Errorf("trace clause requires an int64 literal; found %v instead", ce.token)
+		}
+		l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace literal %q with error %v", ce.token.Text, err)
+		}
+		if l.Type() != literal.Int64 {
+			return nil, fmt.Errorf("trace requires an int64 value; found %s instead", l)
+		}
+		lv, err := l.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the int64 value for %q with error %v", l, err)
+		}
+		if lv < 1 || lv > 3 {
+			return nil, fmt.Errorf("trace level must be between 1 and 3; found %d instead", lv)
+		}
+		st.SetTraceLevel(int(lv))
+		return hook, nil
+	}
+	return hook
+}
+
+// killQueryIDCollection collects the ID of the query cancelled by a KILL
+// statement, provided as a quoted string or as a text literal.
+func killQueryIDCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() {
+			return hook, nil
+		}
+		var id string
+		switch ce.token.Type {
+		case lexer.ItemQuotedString:
+			us, err := strconv.Unquote(ce.token.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse query ID %s with error %v", ce.token.Text, err)
+			}
+			id = us
+		case lexer.ItemLiteral:
+			l, err := literal.DefaultBuilder().Parse(ce.token.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse query ID literal %q with error %v", ce.token.Text, err)
+			}
+			if l.Type() != literal.Text {
+				return nil, fmt.Errorf("KILL QUERY requires a text literal ID; found %s instead", l)
+			}
+			if id, err = l.Text(); err != nil {
+				return nil, fmt.Errorf("failed to retrieve the text value for %q with error %v", l, err)
+			}
+		default:
+			return hook, nil
+		}
+		if id == "" {
+			return nil, fmt.Errorf("KILL QUERY requires a non empty query ID")
+		}
+		st.SetKillQueryID(id)
+		return hook, nil
+	}
+	return hook
+}
+
+// viewNameCollection collects the binding naming the materialized view of a
+// VIEW statement.
+func viewNameCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type != lexer.ItemBinding {
+			return hook, nil
+		}
+		if st.viewName != "" {
+			return nil, fmt.Errorf("view name %s already set to %s", ce.token.Text, st.viewName)
+		}
+		st.SetViewName(ce.token.Text)
+		return hook, nil
+	}
+	return hook
+}
+
+// mergeConflictsCollection flags the MERGE statements that replace the
+// conflicting objects instead of reporting them.
+func mergeConflictsCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if !ce.IsSymbol() && ce.token.Type == lexer.ItemReplacing {
+			st.SetReplaceConflicts()
+		}
+		return hook, nil
+	}
+	return hook
+}
+
+// anchorCutoffCollection collects the time anchor cutoff of a DELETE statement
+// purging all the temporal triples anchored at or before it.
+func anchorCutoffCollection() ElementHook {
+	var hook ElementHook
+	hook = func(st *Statement, ce ConsumedElement) (ElementHook, error) {
+		if ce.IsSymbol() || ce.token.Type != lexer.ItemTime {
+			return hook, nil
+		}
+		ta, err := predicate.ParseTimeAnchor(strings.TrimSpace(ce.token.Text), st.defaultTimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the anchor cutoff %q with error %v", ce.token.Text, err)
+		}
+		st.BindType(Delete)
+		st.SetAnchorCutoff(ta)
+		return hook, nil
+	}
+	return hook
+}
+
 // collectGlobalBounds collects the global time bounds that should be applied
 // to all temporal predicates.
 func collectGlobalBounds() ElementHook {
@@ -1048,7 +2024,7 @@ func collectGlobalBounds() ElementHook {
 			if lastToken == nil {
 				return nil, fmt.Errorf("invalid token %v without a global time modifier", tkn)
 			}
-			ta, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(tkn.Text))
+			ta, err := predicate.ParseTimeAnchor(strings.TrimSpace(tkn.Text), st.defaultTimeZone)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse global time bound in %s with error: %s", tkn.Text, err)
 			}
@@ -1066,11 +2042,11 @@ func collectGlobalBounds() ElementHook {
 			if len(bounds) != 2 {
 				return nil, fmt.Errorf("wrong number of bounds in predicate %s; want 2 got %d", tkn.Text, len(bounds))
 			}
-			lowBound, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(bounds[0]))
+			lowBound, err := predicate.ParseTimeAnchor(strings.TrimSpace(bounds[0]), st.defaultTimeZone)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse lower time bound in %s with error: %s", tkn.Text, err)
 			}
-			upBound, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(bounds[1]))
+			upBound, err := predicate.ParseTimeAnchor(strings.TrimSpace(bounds[1]), st.defaultTimeZone)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse upper time bound in %s with error: %s", tkn.Text, err)
 			}
@@ -1158,7 +2134,7 @@ func constructPredicate() ElementHook {
 		}
 		switch tkn.Type {
 		case lexer.ItemPredicate:
-			pred, pID, pAnchorBinding, pTemporal, err := processPredicate(ce)
+			pred, pID, pAnchorBinding, pTemporal, err := processPredicate(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
@@ -1202,7 +2178,7 @@ func constructObject() ElementHook {
 				pred *predicate.Predicate
 				err  error
 			)
-			pred, p.OID, p.OAnchorBinding, p.OTemporal, err = processPredicate(ce)
+			pred, p.OID, p.OAnchorBinding, p.OTemporal, err = processPredicate(ce, st.defaultTimeZone)
 			if err != nil {
 				return nil, err
 			}
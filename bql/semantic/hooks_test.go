@@ -92,6 +92,99 @@ func TestDataAccumulatorHook(t *testing.T) {
 	}
 }
 
+func TestDataAccumulatorHookNowAnchors(t *testing.T) {
+	tkns := []*lexer.Token{
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[NOW()]`},
+		{Type: lexer.ItemNode, Text: "/_<o>"},
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[]`},
+		{Type: lexer.ItemPredicate, Text: `"q"@[now()]`},
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[]`},
+		{Type: lexer.ItemNode, Text: "/_<o>"},
+	}
+	st := &Statement{}
+	hook := dataAccumulator(literal.DefaultBuilder())
+	for _, tkn := range tkns {
+		var err error
+		hook, err = hook(st, NewConsumedToken(tkn))
+		if err != nil {
+			t.Fatalf("semantic.DataAccumulator hook should have never failed for %v, got %v", tkn, err)
+		}
+	}
+	if !st.HasNowData() {
+		t.Fatalf("semantic.DataAccumulator hook should have tracked the NOW() time anchors")
+	}
+	now := time.Date(2020, time.May, 4, 3, 2, 1, 0, time.UTC)
+	ts, err := st.ResolveNow(st.Data(), now)
+	if err != nil {
+		t.Fatalf("st.ResolveNow failed with error %v", err)
+	}
+	var got []string
+	for _, trpl := range ts {
+		got = append(got, trpl.String())
+	}
+	want := []string{
+		`/_<s>	"p"@[2020-05-04T03:02:01Z]	/_<o>`,
+		`/_<s>	"p"@[]	"q"@[2020-05-04T03:02:01Z]`,
+		`/_<s>	"p"@[]	/_<o>`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("st.ResolveNow returned %v; want %v", got, want)
+	}
+	// Later calls resolve to the same instant.
+	ts, err = st.ResolveNow(st.Data(), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("st.ResolveNow failed with error %v", err)
+	}
+	if got, want := ts[0].String(), `/_<s>	"p"@[2020-05-04T03:02:01Z]	/_<o>`; got != want {
+		t.Errorf("st.ResolveNow returned %v on a second call; want %v", got, want)
+	}
+}
+
+func TestDataAccumulatorHookLenient(t *testing.T) {
+	tkns := []*lexer.Token{
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[not a time]`},
+		{Type: lexer.ItemNode, Text: "/_<o>"},
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[]`},
+		{Type: lexer.ItemLiteral, Text: `"o"^^type:int64`},
+		{Type: lexer.ItemNode, Text: "/_<s>"},
+		{Type: lexer.ItemPredicate, Text: `"p"@[]`},
+		{Type: lexer.ItemNode, Text: "/_<o>"},
+	}
+	st := &Statement{}
+	st.SetLenientData()
+	hook := dataAccumulator(literal.DefaultBuilder())
+	for _, tkn := range tkns {
+		var err error
+		hook, err = hook(st, NewConsumedToken(tkn))
+		if err != nil {
+			t.Fatalf("semantic.DataAccumulator hook should have never failed for %v with lenient data; got error %v", tkn, err)
+		}
+	}
+	if got, want := len(st.Data()), 1; got != want {
+		t.Errorf("semantic.DataAccumulator hook produced %d triples; want %d", got, want)
+	}
+	if got, want := len(st.SkippedData()), 2; got != want {
+		t.Errorf("semantic.DataAccumulator hook skipped %d triples (%v); want %d", got, st.SkippedData(), want)
+	}
+
+	// Without lenient data, the first malformed triple is rejected.
+	st, hook = &Statement{}, dataAccumulator(literal.DefaultBuilder())
+	var err error
+	for _, tkn := range tkns {
+		if hook, err = hook(st, NewConsumedToken(tkn)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Errorf("semantic.DataAccumulator hook should have rejected the malformed triple")
+	}
+}
+
 func TestGraphAccumulatorElementHooks(t *testing.T) {
 	st := &Statement{}
 	ces := []ConsumedElement{
@@ -1453,6 +1546,98 @@ func TestVarAccumulatorHook(t *testing.T) {
 				Modifier: lexer.ItemDistinct,
 			},
 		},
+		{
+			valid: true,
+			id:    "group concat var with separator and alias",
+			ces: []ConsumedElement{
+				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemGroupConcat,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLPar,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemComma,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemQuotedString,
+					Text: `"; "`,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemRPar,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemAs,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?bar",
+				}),
+				NewConsumedSymbol("FOO"),
+			},
+			want: &Projection{
+				Binding:   "?foo",
+				Alias:     "?bar",
+				OP:        lexer.ItemGroupConcat,
+				Separator: "; ",
+			},
+		},
+		{
+			valid: true,
+			id:    "substr var with alias",
+			ces: []ConsumedElement{
+				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemSubstr,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLPar,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?foo",
+				}),
+				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemComma,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"1"^^type:int64`,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemComma,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLiteral,
+					Text: `"3"^^type:int64`,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemRPar,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemAs,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?bar",
+				}),
+				NewConsumedSymbol("FOO"),
+			},
+			want: &Projection{
+				Binding:     "?foo",
+				Alias:       "?bar",
+				Func:        lexer.ItemSubstr,
+				SubstrStart: 1,
+				SubstrLen:   3,
+			},
+		},
 	})
 }
 
@@ -1599,6 +1784,18 @@ func TestGroupByBindingsChecker(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			id: "several aggregations over different bindings",
+			s: &Statement{
+				projection: []*Projection{
+					{Binding: "?foo"},
+					{Binding: "?bar", Alias: "?bars", OP: lexer.ItemCount},
+					{Binding: "?baz", Alias: "?bazs", OP: lexer.ItemCount, Modifier: lexer.ItemDistinct},
+				},
+				groupBy: []string{"?foo"},
+			},
+			want: true,
+		},
 		{
 			id: "two binding missing aggregation function",
 			s: &Statement{
@@ -1666,12 +1863,39 @@ func TestOrderByBindings(t *testing.T) {
 					Type: lexer.ItemDesc,
 				}),
 				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?first",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemNulls,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemFirst,
+				}),
+				NewConsumedSymbol("FOO"),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemBinding,
+					Text: "?last",
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemDesc,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemNulls,
+				}),
+				NewConsumedToken(&lexer.Token{
+					Type: lexer.ItemLast,
+				}),
+				NewConsumedSymbol("FOO"),
 			},
 			want: table.SortConfig{
 				{Binding: "?foo", Desc: false},
 				{Binding: "?bar", Desc: false},
 				{Binding: "?asc", Desc: false},
 				{Binding: "?desc", Desc: true},
+				{Binding: "?first", Nulls: table.NullsFirst},
+				{Binding: "?last", Desc: true, Nulls: table.NullsLast},
 			},
 		},
 	}
@@ -2207,6 +2431,32 @@ func TestCollectGlobalBounds(t *testing.T) {
 	}
 }
 
+func TestCollectGlobalBoundsRelaxedTimes(t *testing.T) {
+	f := collectGlobalBounds()
+	st := &Statement{}
+	for _, ce := range []ConsumedElement{
+		NewConsumedSymbol("FOO"),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemBetween,
+		}),
+		NewConsumedSymbol("FOO"),
+		NewConsumedToken(&lexer.Token{
+			Type: lexer.ItemPredicateBound,
+			// Single digit hour, with and without fractional seconds.
+			Text: "2015-07-19T6:12:04-07:00, 2015-07-20T6:12:04.5-07:00",
+		}),
+		NewConsumedSymbol("FOO"),
+	} {
+		if _, err := f(st, ce); err != nil {
+			t.Fatalf("semantic.CollectGlobalBounds failed with error %v", err)
+		}
+	}
+	lower, upper := time.Date(2015, 7, 19, 13, 12, 4, 0, time.UTC), time.Date(2015, 7, 20, 13, 12, 4, 500000000, time.UTC)
+	if got := st.lookupOptions; got.LowerAnchor == nil || !got.LowerAnchor.Equal(lower) || got.UpperAnchor == nil || !got.UpperAnchor.Equal(upper) {
+		t.Errorf("semantic.CollectGlobalBounds collected %v; want bounds %v and %v", got, lower, upper)
+	}
+}
+
 func TestInitWorkingConstructClauseHook(t *testing.T) {
 	f := InitWorkingConstructClause()
 	st := &Statement{}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/google/badwolf/bql/lexer"
@@ -31,10 +32,44 @@ import (
 	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
 )
 
+// AnonymousBinding is the discard binding. Clauses may use it to require that
+// a value exists without keeping it around. It is never added to the bindings
+// of a clause or a statement, so it cannot be projected or joined on, and each
+// of its occurrences is independent of the others.
+const AnonymousBinding = "?_"
+
+// GraphBinding is the reserved binding holding the name of the graph a row was
+// matched in. It can only be projected via GRAPH() AS ?alias, or counted per
+// group via GRAPH_COUNT() AS ?alias. When projected,
+// all the clauses of the graph pattern are bound to it, so rows are only built
+// out of triples from the same graph.
+const GraphBinding = "GRAPH()"
+
+// GroupIndexBinding is the reserved binding holding the position of a row
+// within its GROUP BY group, following the ORDER BY order. It can only be
+// projected via GROUP_INDEX() AS ?alias, and it is not fed by the graph
+// pattern.
+const GroupIndexBinding = "GROUP_INDEX()"
+
+// TripleBinding returns the reserved binding holding the triples TRIPLE packs
+// out of the provided subject, predicate and object bindings.
+func TripleBinding(s, p, o string) string {
+	return fmt.Sprintf("TRIPLE(%s, %s, %s)", s, p, o)
+}
+
+// HistogramStartBinding and HistogramCountBinding are the bindings projected
+// by HISTOGRAM(?time, interval), holding the start of each bucket and the
+// number of rows in it.
+const (
+	HistogramStartBinding = "?bucket_start"
+	HistogramCountBinding = "?count"
+)
+
 // StatementType describes the type of statement being represented.
 type StatementType int8
 
@@ -55,6 +90,26 @@ const (
 	Deconstruct
 	// Show statement.
 	Show
+	// Dump statement.
+	Dump
+	// Clear statement.
+	Clear
+	// Truncate statement.
+	Truncate
+	// Merge statement.
+	Merge
+	// Anchors statement.
+	Anchors
+	// Kill statement.
+	Kill
+	// CreateView statement.
+	CreateView
+	// RefreshView statement.
+	RefreshView
+	// DropView statement.
+	DropView
+	// ShowView statement.
+	ShowView
 )
 
 // String provides a readable version of the StatementType.
@@ -76,6 +131,26 @@ func (t StatementType) String() string {
 		return "DECONSTRUCT"
 	case Show:
 		return "SHOW"
+	case Dump:
+		return "DUMP"
+	case Clear:
+		return "CLEAR"
+	case Truncate:
+		return "TRUNCATE"
+	case Merge:
+		return "MERGE"
+	case Anchors:
+		return "ANCHORS"
+	case Kill:
+		return "KILL"
+	case CreateView:
+		return "CREATE VIEW"
+	case RefreshView:
+		return "REFRESH VIEW"
+	case DropView:
+		return "DROP VIEW"
+	case ShowView:
+		return "SHOW VIEW"
 	default:
 		return "UNKNOWN"
 	}
@@ -91,14 +166,22 @@ type Statement struct {
 	outputGraphNames          []string
 	outputGraphs              []storage.Graph
 	data                      []*triple.Triple
+	dataSink                  DataSink
+	dataSinkSize              int
+	streamedData              int
+	nowData                   map[*triple.Triple]nowAnchors
+	now                       time.Time
 	pattern                   []*GraphClause
 	workingClause             *GraphClause
 	constructClauses          []*ConstructClause
 	workingConstructClause    *ConstructClause
 	projection                []*Projection
 	workingProjection         *Projection
+	projectAll                bool
 	groupBy                   []string
+	groupByBuckets            map[string]*table.Interval
 	orderBy                   table.SortConfig
+	orderByArgs               map[string]string
 	havingExpression          []ConsumedElement
 	havingExpressionEvaluator Evaluator
 	limitSet                  bool
@@ -106,11 +189,37 @@ type Statement struct {
 	lookupOptions             storage.LookupOptions
 	filters                   []*FilterClause
 	workingFilter             *FilterClause
+	unwind                    []*UnwindClause
+	ifNotExists               bool
+	ifAbsent                  bool
+	distinct                  bool
+	streamedAdded             int
+	ifExists                  bool
+	optionalGroups            int
+	workingOptionalGroup      int
+	negated                   [][]*GraphClause
+	workingNegatedGroup       int
+	defaultTimeZone           *time.Location
+	bindingTypes              map[string]literal.Type
+	strictBindingTypes        bool
+	traceLevel                int
+	anchorsSubject            *node.Node
+	anchorsPredicateID        string
+	anchorsBinding            string
+	anchorCutoff              *time.Time
+	killQueryID               string
+	replaceConflicts          bool
+	explain                   bool
+	lenientData               bool
+	skippedData               []error
+	viewName                  string
 }
 
 // GraphClause represents a clause of a graph pattern in a where clause.
 type GraphClause struct {
-	Optional bool // This will be set to true if the clause is optional.
+	Optional      bool // This will be set to true if the clause is optional.
+	OptionalGroup int  // Identifies the OPTIONAL block the clause belongs to; 0 if not optional.
+	NegatedGroup  int  // Identifies the NOT block the clause belongs to; 0 if not negated.
 
 	S          *node.Node
 	SBinding   string
@@ -123,12 +232,16 @@ type GraphClause struct {
 	PBinding         string
 	PAlias           string
 	PIDAlias         string
+	PIDConstant      string // Set if the predicate binding is restricted to an ID, as in ?p ID "bought".
 	PAnchorBinding   string
 	PAnchorAlias     string
 	PLowerBound      *time.Time
 	PUpperBound      *time.Time
+	PLowerBoundOpen  bool // Set if the lower bound is excluded, as in "p"@[> t].
+	PUpperBoundOpen  bool // Set if the upper bound is excluded, as in "p"@[< t].
 	PLowerBoundAlias string
 	PUpperBoundAlias string
+	PAnchorRanges    []storage.TimeRange // Set if the predicate is bound to several time ranges.
 	PTemporal        bool
 
 	O                *triple.Object
@@ -136,6 +249,7 @@ type GraphClause struct {
 	OAlias           string
 	OID              string
 	OTypeAlias       string
+	OTypeConstant    *node.Type // Set if the object binding is restricted to nodes of a type, as in ?o TYPE /u.
 	OIDAlias         string
 	OAnchorBinding   string
 	OAnchorAlias     string
@@ -143,7 +257,19 @@ type GraphClause struct {
 	OUpperBound      *time.Time
 	OLowerBoundAlias string
 	OUpperBoundAlias string
+	OAnchorRanges    []storage.TimeRange // Set if the predicate is bound to several time ranges.
 	OTemporal        bool
+
+	GBinding string // Set to GraphBinding if the graph name of the matched triples is needed.
+
+	Guard *ClauseGuard // Set if the clause has an inline WHERE guard.
+	Limit int64        // Caps the triples of each lookup of the clause if positive.
+
+	// BindingTypes contains the literal types declared for the bindings of the
+	// statement. Matches binding other values are skipped, or rejected if
+	// StrictBindingTypes is set.
+	BindingTypes       map[string]literal.Type
+	StrictBindingTypes bool
 }
 
 // FilterClause represents a FILTER clause inside WHERE.
@@ -188,6 +314,16 @@ func (c *GraphClause) HasAlias() bool {
 		c.OTypeAlias != "" || c.OLowerBoundAlias != "" || c.OUpperBoundAlias != ""
 }
 
+// writeTimeRanges writes the time ranges of a predicate bound separated by ;.
+func writeTimeRanges(b *bytes.Buffer, rngs []storage.TimeRange) {
+	for i, r := range rngs {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(r.String())
+	}
+}
+
 // String returns a readable representation of a graph clause.
 func (c *GraphClause) String() string {
 	b := bytes.NewBufferString("{ ")
@@ -243,6 +379,14 @@ func (c *GraphClause) String() string {
 					b.WriteString(" at ")
 					b.WriteString(c.PAnchorAlias)
 				}
+			} else if len(c.PAnchorRanges) > 0 {
+				writeTimeRanges(b, c.PAnchorRanges)
+			} else if c.PLowerBoundOpen {
+				b.WriteString("> ")
+				b.WriteString(c.PLowerBound.Format(time.RFC3339Nano))
+			} else if c.PUpperBoundOpen {
+				b.WriteString("< ")
+				b.WriteString(c.PUpperBound.Format(time.RFC3339Nano))
 			} else {
 				if c.PLowerBound != nil {
 					b.WriteString(c.PLowerBound.Format(time.RFC3339Nano))
@@ -272,6 +416,10 @@ func (c *GraphClause) String() string {
 		b.WriteString(" ID ")
 		b.WriteString(c.PIDAlias)
 	}
+	if c.PIDConstant != "" {
+		b.WriteString(" ID ")
+		b.WriteString(strconv.Quote(c.PIDConstant))
+	}
 	if c.PAnchorAlias != "" {
 		b.WriteString(" AT ")
 		b.WriteString(c.PAnchorAlias)
@@ -306,6 +454,8 @@ func (c *GraphClause) String() string {
 					b.WriteString(" at ")
 					b.WriteString(c.OAnchorAlias)
 				}
+			} else if len(c.OAnchorRanges) > 0 {
+				writeTimeRanges(b, c.OAnchorRanges)
 			} else {
 				if c.OLowerBound != nil {
 					b.WriteString(c.OLowerBound.Format(time.RFC3339Nano))
@@ -334,6 +484,10 @@ func (c *GraphClause) String() string {
 		b.WriteString(" TYPE ")
 		b.WriteString(c.OTypeAlias)
 	}
+	if c.OTypeConstant != nil {
+		b.WriteString(" TYPE ")
+		b.WriteString(c.OTypeConstant.String())
+	}
 	if c.OIDAlias != "" {
 		b.WriteString(" ID ")
 		b.WriteString(c.OIDAlias)
@@ -351,6 +505,18 @@ func (c *GraphClause) String() string {
 		b.WriteString(" ID ")
 		b.WriteString(c.OIDAlias)
 	}
+	if c.GBinding != "" {
+		b.WriteString(" IN ")
+		b.WriteString(c.GBinding)
+	}
+	if c.Guard != nil {
+		b.WriteString(" ")
+		b.WriteString(c.Guard.String())
+	}
+	if c.Limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.FormatInt(c.Limit, 10))
+	}
 
 	b.WriteString(" }")
 	return b.String()
@@ -394,10 +560,30 @@ func (c *GraphClause) BindingsMap() map[string]int {
 	addToBindings(bm, c.OAnchorBinding)
 	addToBindings(bm, c.OLowerBoundAlias)
 	addToBindings(bm, c.OUpperBoundAlias)
+	addToBindings(bm, c.GBinding)
 
 	return bm
 }
 
+// orderedBindings returns the unique bindings listed in the graph clause in the
+// order they appear in it.
+func (c *GraphClause) orderedBindings() []string {
+	var bs []string
+	seen := make(map[string]bool)
+	for _, b := range []string{
+		c.SBinding, c.SAlias, c.STypeAlias, c.SIDAlias,
+		c.PBinding, c.PAlias, c.PIDAlias, c.PAnchorBinding, c.PAnchorAlias, c.PLowerBoundAlias, c.PUpperBoundAlias,
+		c.OBinding, c.OAlias, c.OTypeAlias, c.OIDAlias, c.OAnchorBinding, c.OAnchorAlias, c.OLowerBoundAlias, c.OUpperBoundAlias,
+		c.GBinding,
+	} {
+		if b != "" && b != AnonymousBinding && !seen[b] {
+			seen[b] = true
+			bs = append(bs, b)
+		}
+	}
+	return bs
+}
+
 // Bindings returns the list of unique bindings listed in the graph clause.
 func (c *GraphClause) Bindings() []string {
 	bindingsMap := c.BindingsMap()
@@ -408,6 +594,21 @@ func (c *GraphClause) Bindings() []string {
 	return bs
 }
 
+// HasAnonymousBinding returns true if any of the bindings of the clause is the
+// anonymous binding.
+func (c *GraphClause) HasAnonymousBinding() bool {
+	for _, b := range []string{
+		c.SBinding, c.SAlias, c.STypeAlias, c.SIDAlias,
+		c.PAlias, c.PAnchorBinding, c.PBinding, c.PLowerBoundAlias, c.PUpperBoundAlias, c.PIDAlias, c.PAnchorAlias,
+		c.OBinding, c.OAlias, c.OTypeAlias, c.OIDAlias, c.OAnchorAlias, c.OAnchorBinding, c.OLowerBoundAlias, c.OUpperBoundAlias,
+	} {
+		if b == AnonymousBinding {
+			return true
+		}
+	}
+	return false
+}
+
 // IsEmpty will return true if the are no set values in the clause.
 func (c *GraphClause) IsEmpty() bool {
 	return reflect.DeepEqual(c, &GraphClause{})
@@ -522,6 +723,100 @@ func (s *Statement) Type() StatementType {
 	return s.sType
 }
 
+// SetIfAbsent flags the statement to only insert the triples not already
+// present in the output graphs.
+func (s *Statement) SetIfAbsent() {
+	s.ifAbsent = true
+}
+
+// IfAbsent returns true if the statement only inserts the triples not already
+// present in the output graphs.
+func (s *Statement) IfAbsent() bool {
+	return s.ifAbsent
+}
+
+// SetDistinct flags the statement to drop the repeated rows of its results.
+func (s *Statement) SetDistinct() {
+	s.distinct = true
+}
+
+// Distinct returns true if the statement drops the repeated rows of its
+// results.
+func (s *Statement) Distinct() bool {
+	return s.distinct
+}
+
+// SetExplain flags the statement as only being planned to describe its
+// execution plan. It needs to be set before parsing. The data of explained
+// statements is never flushed to a data sink.
+func (s *Statement) SetExplain() {
+	s.explain = true
+}
+
+// Explain returns true if the statement is only planned to describe its
+// execution plan, and will not be executed.
+func (s *Statement) Explain() bool {
+	return s.explain
+}
+
+// SetLenientData makes the statement skip the triples of its DATA clause that
+// fail to parse, recording the reason, instead of rejecting the whole
+// statement. It needs to be set before parsing.
+func (s *Statement) SetLenientData() {
+	s.lenientData = true
+}
+
+// LenientData returns true if the triples of the DATA clause that fail to
+// parse are skipped instead of rejecting the statement.
+func (s *Statement) LenientData() bool {
+	return s.lenientData
+}
+
+// AddSkippedData records the reason why a triple of the DATA clause was
+// skipped.
+func (s *Statement) AddSkippedData(err error) {
+	s.skippedData = append(s.skippedData, err)
+}
+
+// SkippedData returns the reasons why triples of the DATA clause were skipped,
+// in the order they were found.
+func (s *Statement) SkippedData() []error {
+	return s.skippedData
+}
+
+// AddStreamedAdded records the number of triples actually added to the output
+// graphs by a batch flushed to the data sink.
+func (s *Statement) AddStreamedAdded(n int) {
+	s.streamedAdded += n
+}
+
+// StreamedAdded returns the number of triples actually added to the output
+// graphs by the batches already flushed to the data sink.
+func (s *Statement) StreamedAdded() int {
+	return s.streamedAdded
+}
+
+// SetIfNotExists flags the statement to ignore the graphs that already exist.
+func (s *Statement) SetIfNotExists() {
+	s.ifNotExists = true
+}
+
+// IfNotExists returns true if creating graphs that already exist should not
+// fail.
+func (s *Statement) IfNotExists() bool {
+	return s.ifNotExists
+}
+
+// SetIfExists flags the statement to ignore the graphs that do not exist.
+func (s *Statement) SetIfExists() {
+	s.ifExists = true
+}
+
+// IfExists returns true if dropping graphs that do not exist should not fail.
+func (s *Statement) IfExists() bool {
+	return s.ifExists
+}
+
 // AddGraph adds a graph to a given statement.
 func (s *Statement) AddGraph(g string) {
 	s.graphNames = append(s.graphNames, g)
@@ -562,8 +857,10 @@ func (s *Statement) OutputGraphs() []storage.Graph {
 	return s.outputGraphs
 }
 
-// Init initializes all graphs given the graph names.
+// Init initializes all graphs given the graph names. Calling it again resolves
+// the graphs anew, so the same statement can be executed several times.
 func (s *Statement) Init(ctx context.Context, st storage.Store) error {
+	s.graphs, s.inputGraphs, s.outputGraphs = nil, nil, nil
 	for _, gn := range s.graphNames {
 		g, err := st.Graph(ctx, gn)
 		if err != nil {
@@ -598,16 +895,176 @@ func (s *Statement) AddData(d *triple.Triple) {
 	s.data = append(s.data, d)
 }
 
-// Data returns the data available for the given statement.
+// nowAnchors records which time anchors of a triple of the DATA clause are
+// NOW().
+type nowAnchors struct {
+	predicate bool
+	object    bool
+}
+
+// addNowData tracks a triple of the DATA clause with NOW() time anchors.
+func (s *Statement) addNowData(t *triple.Triple, na nowAnchors) {
+	if s.nowData == nil {
+		s.nowData = make(map[*triple.Triple]nowAnchors)
+	}
+	s.nowData[t] = na
+}
+
+// HasNowData returns true if any triple of the DATA clause has a NOW() time
+// anchor.
+func (s *Statement) HasNowData() bool {
+	return len(s.nowData) > 0
+}
+
+// ResolveNow returns the provided triples of the DATA clause with their NOW()
+// time anchors replaced by the provided time. Only the time provided to the
+// first call is used, so all the NOW() time anchors of the statement resolve
+// to the same instant even if its data is flushed in several batches. The
+// provided slice is not modified.
+func (s *Statement) ResolveNow(ts []*triple.Triple, now time.Time) ([]*triple.Triple, error) {
+	if len(s.nowData) == 0 {
+		return ts, nil
+	}
+	if s.now.IsZero() {
+		s.now = now
+	}
+	res := make([]*triple.Triple, 0, len(ts))
+	for _, t := range ts {
+		na, ok := s.nowData[t]
+		if !ok {
+			res = append(res, t)
+			continue
+		}
+		p, o := t.Predicate(), t.Object()
+		if na.predicate {
+			np, err := predicate.NewTemporal(string(p.ID()), s.now)
+			if err != nil {
+				return nil, err
+			}
+			p = np
+		}
+		if na.object {
+			op, err := o.Predicate()
+			if err != nil {
+				return nil, err
+			}
+			np, err := predicate.NewTemporal(string(op.ID()), s.now)
+			if err != nil {
+				return nil, err
+			}
+			o = triple.NewPredicateObject(np)
+		}
+		nt, err := triple.New(t.Subject(), p, o)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, nt)
+	}
+	return res, nil
+}
+
+// Data returns the data available for the given statement. If the data was
+// streamed, it only contains the triples not yet flushed to the data sink.
 func (s *Statement) Data() []*triple.Triple {
 	return s.data
 }
 
+// DataSink receives the batches of triples flushed while the data of an
+// INSERT statement is being parsed.
+type DataSink func([]*triple.Triple) error
+
+// StreamData sets the sink that receives the data of an INSERT statement in
+// batches of the provided size as it gets parsed, instead of holding all of
+// it in the statement. Any remaining triples that do not fill a whole batch
+// are still available via Data. It needs to be set before parsing.
+func (s *Statement) StreamData(size int, sink DataSink) {
+	s.dataSink, s.dataSinkSize = sink, size
+}
+
+// StreamedData returns the number of triples already flushed to the data
+// sink.
+func (s *Statement) StreamedData() int {
+	return s.streamedData
+}
+
+// flushData sends the accumulated data to the data sink once a full batch
+// is available. Only INSERT statements, the only ones accumulating data
+// into output graphs, are streamed, and never when explained.
+func (s *Statement) flushData() error {
+	if s.dataSink == nil || s.explain || s.dataSinkSize <= 0 || len(s.outputGraphNames) == 0 || len(s.data) < s.dataSinkSize {
+		return nil
+	}
+	if err := s.dataSink(s.data); err != nil {
+		return err
+	}
+	s.streamedData += len(s.data)
+	s.data = nil
+	return nil
+}
+
 // GraphPatternClauses returns the list of graph pattern clauses
 func (s *Statement) GraphPatternClauses() []*GraphClause {
 	return s.pattern
 }
 
+// NegatedClauses returns the clauses of each NOT block of the graph pattern.
+// They are not part of GraphPatternClauses, and their bindings are local to
+// the block.
+func (s *Statement) NegatedClauses() [][]*GraphClause {
+	return s.negated
+}
+
+// DisconnectedClauseGroups returns the groups of graph pattern clauses that
+// share no bindings with each other, as indexes into GraphPatternClauses.
+// Clauses without bindings only check the existence of a triple and belong to
+// no group, and GRAPH() is not considered a shared binding. A pattern with more
+// than one group computes the cartesian product of the results of its groups.
+func (s *Statement) DisconnectedClauseGroups() [][]int {
+	parent := make([]int, len(s.pattern))
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	owner := make(map[string]int)
+	hasBindings := make([]bool, len(s.pattern))
+	for i, cls := range s.pattern {
+		parent[i] = i
+		if cls == nil {
+			continue
+		}
+		for b := range cls.BindingsMap() {
+			if b == GraphBinding {
+				continue
+			}
+			hasBindings[i] = true
+			if j, ok := owner[b]; ok {
+				parent[find(i)] = find(j)
+				continue
+			}
+			owner[b] = i
+		}
+	}
+	var groups [][]int
+	idx := make(map[int]int)
+	for i := range s.pattern {
+		if !hasBindings[i] {
+			continue
+		}
+		r := find(i)
+		g, ok := idx[r]
+		if !ok {
+			g = len(groups)
+			idx[r] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
 // FilterClauses returns the list of FILTER clauses.
 func (s *Statement) FilterClauses() []*FilterClause {
 	return s.filters
@@ -637,7 +1094,21 @@ func (s *Statement) WorkingFilter() *FilterClause {
 // clauses that form the graph pattern.
 func (s *Statement) AddWorkingGraphClause() {
 	if s.workingClause != nil && !s.workingClause.IsEmpty() {
-		s.pattern = append(s.pattern, s.workingClause)
+		if s.projectsGraph() {
+			s.workingClause.GBinding = GraphBinding
+		}
+		if len(s.bindingTypes) > 0 {
+			s.workingClause.BindingTypes = s.bindingTypes
+			s.workingClause.StrictBindingTypes = s.strictBindingTypes
+		}
+		if g := s.workingClause.NegatedGroup; g != 0 {
+			for len(s.negated) < g {
+				s.negated = append(s.negated, nil)
+			}
+			s.negated[g-1] = append(s.negated[g-1], s.workingClause)
+		} else {
+			s.pattern = append(s.pattern, s.workingClause)
+		}
 	}
 	s.ResetWorkingGraphClause()
 }
@@ -648,6 +1119,19 @@ func (s *Statement) AddWorkingFilterClause() {
 	s.ResetWorkingFilterClause()
 }
 
+// projectsGraph returns true if the statement projects GRAPH().
+func (s *Statement) projectsGraph() bool {
+	if s.workingProjection != nil && s.workingProjection.Binding == GraphBinding {
+		return true
+	}
+	for _, p := range s.projection {
+		if p.Binding == GraphBinding {
+			return true
+		}
+	}
+	return false
+}
+
 // Projection returns the available projections in the statement.
 func (s *Statement) Projection() []*Projection {
 	return s.projection
@@ -673,16 +1157,26 @@ func (s *Statement) HasLimit() bool {
 	return s.limitSet
 }
 
-// addToBindings adds the binding if not empty.
+// addToBindings adds the binding if not empty nor the anonymous binding.
 func addToBindings(bs map[string]int, b string) {
-	if b != "" {
+	if b != "" && b != AnonymousBinding {
 		bs[b]++
 	}
 }
 
 // BindingsMap returns the set of bindings available on the graph clauses for the
-// statement.
+// statement, including the ones introduced by unwind clauses.
 func (s *Statement) BindingsMap() map[string]int {
+	bm := s.patternBindingsMap()
+	for _, u := range s.unwind {
+		addToBindings(bm, u.Alias)
+	}
+	return bm
+}
+
+// patternBindingsMap returns the set of bindings available on the graph
+// clauses for the statement.
+func (s *Statement) patternBindingsMap() map[string]int {
 	bm := make(map[string]int)
 
 	for _, cls := range s.pattern {
@@ -706,6 +1200,7 @@ func (s *Statement) BindingsMap() map[string]int {
 			addToBindings(bm, cls.OAnchorBinding)
 			addToBindings(bm, cls.OLowerBoundAlias)
 			addToBindings(bm, cls.OUpperBoundAlias)
+			addToBindings(bm, cls.GBinding)
 		}
 	}
 	return bm
@@ -753,6 +1248,23 @@ func (s *Statement) SortedGraphPatternClauses() []*GraphClause {
 	return ptrns
 }
 
+// UnwindClause contains the information required to expand a binding holding
+// a list literal into one row per element of the list.
+type UnwindClause struct {
+	Binding string
+	Alias   string
+}
+
+// String returns a readable form of the unwind clause.
+func (u *UnwindClause) String() string {
+	return u.Binding + " as " + u.Alias
+}
+
+// UnwindClauses returns the list of unwind clauses in the statement.
+func (s *Statement) UnwindClauses() []*UnwindClause {
+	return s.unwind
+}
+
 // Projection contains the information required to project the outcome of
 // querying with GraphClauses. It also contains the information of what
 // aggregation function should be used.
@@ -761,13 +1273,38 @@ type Projection struct {
 	Alias    string
 	OP       lexer.TokenType // The information about what function to use.
 	Modifier lexer.TokenType // The modifier for the selected op.
-}
+	// Func is the function applied to the binding value before projecting it,
+	// one of ItemCast, ItemLang, ItemKind, ItemDatatype, ItemStrlen, ItemSubstr
+	// and ItemTriple, or ItemError if none.
+	Func lexer.TokenType
+	// Args are the subject, predicate and object bindings TRIPLE packs into a
+	// single cell. The projection binding is then the name of the cell holding
+	// the packed triple.
+	Args []string
+	// CastType is the literal type CAST converts the binding value to.
+	CastType literal.Type
+	// SubstrStart and SubstrLen are the index of the first character and the
+	// maximum number of characters of the substring projected by SUBSTR.
+	SubstrStart, SubstrLen int64
+	// Separator is the string placed between the values joined by GROUP_CONCAT.
+	Separator string
+	// Interval is the width of the buckets HISTOGRAM counts rows in.
+	Interval *table.Interval
+}
+
+// DefaultConcatSeparator is the separator GROUP_CONCAT uses when none is
+// provided.
+const DefaultConcatSeparator = " "
 
 // String returns a readable form of the projection.
 func (p *Projection) String() string {
 	b := bytes.NewBufferString(p.Binding)
 	b.WriteString(" as ")
-	b.WriteString(p.Binding)
+	if p.Alias != "" {
+		b.WriteString(p.Alias)
+	} else {
+		b.WriteString(p.Binding)
+	}
 	if p.OP != lexer.ItemError {
 		b.WriteString(" via ")
 		b.WriteString(p.OP.String())
@@ -775,6 +1312,29 @@ func (p *Projection) String() string {
 			b.WriteString(" ")
 			b.WriteString(p.Modifier.String())
 		}
+		if p.OP == lexer.ItemGroupConcat {
+			b.WriteString(" separated by ")
+			b.WriteString(strconv.Quote(p.Separator))
+		}
+		if p.OP == lexer.ItemHistogram && p.Interval != nil {
+			b.WriteString(" every ")
+			b.WriteString(p.Interval.String())
+		}
+	}
+	switch p.Func {
+	case lexer.ItemCast:
+		b.WriteString(" cast to ")
+		b.WriteString(p.CastType.String())
+	case lexer.ItemLang:
+		b.WriteString(" lang")
+	case lexer.ItemKind:
+		b.WriteString(" kind")
+	case lexer.ItemDatatype:
+		b.WriteString(" datatype")
+	case lexer.ItemStrlen:
+		b.WriteString(" strlen")
+	case lexer.ItemSubstr:
+		fmt.Fprintf(b, " substr(%d, %d)", p.SubstrStart, p.SubstrLen)
 	}
 	return b.String()
 }
@@ -811,11 +1371,35 @@ func (s *Statement) Projections() []*Projection {
 	return s.projection
 }
 
+// projectAllBindings projects every binding available to the statement, in the
+// order they first appear in the graph pattern and the unwind clauses.
+func (s *Statement) projectAllBindings() {
+	seen := make(map[string]bool)
+	add := func(b string) {
+		if !seen[b] {
+			seen[b] = true
+			s.projection = append(s.projection, &Projection{Binding: b})
+		}
+	}
+	for _, cls := range s.pattern {
+		for _, b := range cls.orderedBindings() {
+			add(b)
+		}
+	}
+	for _, u := range s.unwind {
+		add(u.Alias)
+	}
+}
+
 // InputBindings returns the list of incoming bindings feed from a where clause.
 func (s *Statement) InputBindings() []string {
 	var res []string
 	for _, p := range s.projection {
-		if p.Binding != "" {
+		if p.Func == lexer.ItemTriple {
+			res = append(res, p.Args...)
+			continue
+		}
+		if p.Binding != "" && p.Binding != GroupIndexBinding {
 			res = append(res, p.Binding)
 		}
 	}
@@ -845,6 +1429,10 @@ func (s *Statement) InputBindings() []string {
 func (s *Statement) OutputBindings() []string {
 	var res []string
 	for _, p := range s.projection {
+		if p.OP == lexer.ItemHistogram {
+			res = append(res, HistogramStartBinding, HistogramCountBinding)
+			continue
+		}
 		if p.Alias != "" {
 			res = append(res, p.Alias)
 			continue
@@ -887,6 +1475,12 @@ func (s *Statement) GroupByBindings() []string {
 	return s.groupBy
 }
 
+// GroupByBucket returns the interval used to bucket the provided group by
+// binding, or nil if the binding values are grouped as they are.
+func (s *Statement) GroupByBucket(b string) *table.Interval {
+	return s.groupByBuckets[b]
+}
+
 // OrderByConfig returns the sort configuration specified by the order by
 // statement.
 func (s *Statement) OrderByConfig() table.SortConfig {
@@ -898,6 +1492,30 @@ func (s *Statement) HasHavingClause() bool {
 	return len(s.havingExpression) > 0
 }
 
+// CheckHavingScope checks that the HAVING clause of a grouped query only
+// references bindings available after grouping. HAVING is evaluated after
+// grouping and projecting, so the aliases of aggregations are in scope, while
+// the bindings reduced away by the aggregation are not.
+func (s *Statement) CheckHavingScope() error {
+	if len(s.GroupByBindings()) == 0 || !s.HasHavingClause() {
+		return nil
+	}
+	obs := s.OutputBindings()
+	inScope := make(map[string]bool, len(obs))
+	for _, b := range obs {
+		inScope[b] = true
+	}
+	for _, ce := range s.HavingExpression() {
+		if ce.IsSymbol() {
+			continue
+		}
+		if tkn := ce.Token(); tkn.Type == lexer.ItemBinding && !inScope[tkn.Text] {
+			return fmt.Errorf("binding %q referenced by the HAVING clause is not available after grouping; only the projected bindings %v can be used", tkn.Text, obs)
+		}
+	}
+	return nil
+}
+
 // HavingEvaluator returns the evaluator constructed for the provided having
 // clause.
 func (s *Statement) HavingEvaluator() Evaluator {
@@ -914,6 +1532,117 @@ func (s *Statement) Limit() int64 {
 	return s.limit
 }
 
+// SetDefaultTimeZone sets the location used to interpret the time anchors and
+// global time bounds lacking a time zone offset, as in @[2016-01-01T00:00:00].
+// It needs to be set before parsing the statement. If no location is set, such
+// time anchors are rejected.
+func (s *Statement) SetDefaultTimeZone(loc *time.Location) {
+	s.defaultTimeZone = loc
+}
+
+// DefaultTimeZone returns the location used to interpret the time anchors
+// lacking a time zone offset, or nil if none is set.
+func (s *Statement) DefaultTimeZone() *time.Location {
+	return s.defaultTimeZone
+}
+
+// DeclareBindingType declares the literal type of the values of the provided
+// binding. Declaring a different type for the same binding returns an error.
+func (s *Statement) DeclareBindingType(b string, t literal.Type) error {
+	if ot, ok := s.bindingTypes[b]; ok && ot != t {
+		return fmt.Errorf("binding %s cannot be declared as both type:%s and type:%s", b, ot, t)
+	}
+	if s.bindingTypes == nil {
+		s.bindingTypes = make(map[string]literal.Type)
+	}
+	s.bindingTypes[b] = t
+	return nil
+}
+
+// BindingTypes returns the literal types declared for the bindings of the
+// statement, if any.
+func (s *Statement) BindingTypes() map[string]literal.Type {
+	return s.bindingTypes
+}
+
+// SetStrictBindingTypes sets whether matches binding values that do not have
+// the declared type are rejected with an error instead of being skipped.
+func (s *Statement) SetStrictBindingTypes(b bool) {
+	s.strictBindingTypes = b
+}
+
+// StrictBindingTypes returns true if matches binding values that do not have
+// the declared type are rejected with an error instead of being skipped.
+func (s *Statement) StrictBindingTypes() bool {
+	return s.strictBindingTypes
+}
+
+// AnchorsLookup returns the subject, the predicate ID, and the binding of the
+// anchors listed by an ANCHORS statement.
+func (s *Statement) AnchorsLookup() (*node.Node, string, string) {
+	return s.anchorsSubject, s.anchorsPredicateID, s.anchorsBinding
+}
+
+// SetKillQueryID sets the ID of the running query to cancel by a KILL
+// statement.
+func (s *Statement) SetKillQueryID(id string) {
+	s.killQueryID = id
+}
+
+// SetViewName sets the name of the materialized view a VIEW statement
+// operates on.
+func (s *Statement) SetViewName(n string) {
+	s.viewName = n
+}
+
+// ViewName returns the name of the materialized view a VIEW statement
+// operates on.
+func (s *Statement) ViewName() string {
+	return s.viewName
+}
+
+// SetReplaceConflicts flags a MERGE statement to replace the conflicting
+// destination objects instead of reporting them.
+func (s *Statement) SetReplaceConflicts() {
+	s.replaceConflicts = true
+}
+
+// ReplaceConflicts returns true if a MERGE statement replaces the conflicting
+// destination objects instead of reporting them.
+func (s *Statement) ReplaceConflicts() bool {
+	return s.replaceConflicts
+}
+
+// KillQueryID returns the ID of the running query to cancel by a KILL
+// statement.
+func (s *Statement) KillQueryID() string {
+	return s.killQueryID
+}
+
+// SetAnchorCutoff sets the cutoff of a DELETE statement that purges all the
+// temporal triples anchored at or before it.
+func (s *Statement) SetAnchorCutoff(t time.Time) {
+	s.anchorCutoff = &t
+}
+
+// AnchorCutoff returns the cutoff of a DELETE statement purging temporal
+// triples by their anchor, or nil if the statement deletes explicit data.
+func (s *Statement) AnchorCutoff() *time.Time {
+	return s.anchorCutoff
+}
+
+// SetTraceLevel sets the tracer verbosity level requested for the execution of
+// the statement.
+func (s *Statement) SetTraceLevel(l int) {
+	s.traceLevel = l
+}
+
+// TraceLevel returns the tracer verbosity level requested for the execution of
+// the statement, or 0 if the statement was not wrapped in a TRACE clause.
+func (s *Statement) TraceLevel() int {
+	return s.traceLevel
+}
+
 // GlobalLookupOptions returns the global lookup options available in the
 // statement.
 func (s *Statement) GlobalLookupOptions() *storage.LookupOptions {
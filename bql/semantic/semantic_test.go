@@ -41,6 +41,10 @@ func TestStamentTypeString(t *testing.T) {
 		{Construct, "CONSTRUCT"},
 		{Deconstruct, "DECONSTRUCT"},
 		{Show, "SHOW"},
+		{Dump, "DUMP"},
+		{Clear, "CLEAR"},
+		{Truncate, "TRUNCATE"},
+		{Merge, "MERGE"},
 		{StatementType(-1), "UNKNOWN"},
 	}
 
@@ -99,6 +103,72 @@ func TestStatementAddData(t *testing.T) {
 	}
 }
 
+func TestStatementStreamData(t *testing.T) {
+	tr, err := triple.Parse(`/_<foo> "foo"@[] /_<bar>`, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("triple.Parse failed to parse valid triple with error %v", err)
+	}
+	var batches [][]*triple.Triple
+	st := &Statement{}
+	st.StreamData(2, func(d []*triple.Triple) error {
+		batches = append(batches, d)
+		return nil
+	})
+	// Nothing gets streamed without output graphs.
+	for i := 0; i < 3; i++ {
+		st.AddData(tr)
+		if err := st.flushData(); err != nil {
+			t.Fatalf("semantic.flushData failed with error %v", err)
+		}
+	}
+	if len(batches) != 0 || len(st.Data()) != 3 {
+		t.Fatalf("semantic.flushData should not stream data without output graphs; got %d batches and %d triples", len(batches), len(st.Data()))
+	}
+	st = &Statement{}
+	st.StreamData(2, func(d []*triple.Triple) error {
+		batches = append(batches, d)
+		return nil
+	})
+	st.AddOutputGraph("?a")
+	for i := 0; i < 3; i++ {
+		st.AddData(tr)
+		if err := st.flushData(); err != nil {
+			t.Fatalf("semantic.flushData failed with error %v", err)
+		}
+	}
+	if got, want := len(batches), 1; got != want {
+		t.Fatalf("semantic.flushData streamed %d batches; want %d", got, want)
+	}
+	if got, want := batches[0], []*triple.Triple{tr, tr}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.flushData streamed the wrong batch; got %v, want %v", got, want)
+	}
+	if got, want := st.StreamedData(), 2; got != want {
+		t.Errorf("semantic.StreamedData returned %d; want %d", got, want)
+	}
+	if got, want := st.Data(), []*triple.Triple{tr}; !reflect.DeepEqual(got, want) {
+		t.Errorf("semantic.Data returned the wrong remaining data; got %v, want %v", got, want)
+	}
+
+	// Explained statements are never streamed.
+	batches = nil
+	st = &Statement{}
+	st.SetExplain()
+	st.StreamData(2, func(d []*triple.Triple) error {
+		batches = append(batches, d)
+		return nil
+	})
+	st.AddOutputGraph("?a")
+	for i := 0; i < 3; i++ {
+		st.AddData(tr)
+		if err := st.flushData(); err != nil {
+			t.Fatalf("semantic.flushData failed with error %v", err)
+		}
+	}
+	if len(batches) != 0 || len(st.Data()) != 3 {
+		t.Errorf("semantic.flushData should not stream explained statements; got %d batches and %d triples", len(batches), len(st.Data()))
+	}
+}
+
 func TestGraphClauseString(t *testing.T) {
 	timeObj1 := time.Date(2019, 11, 20, 2, 30, 10, 5, time.UTC)
 	timeObj2 := time.Date(2019, 12, 3, 5, 40, 20, 7, time.UTC)
@@ -329,6 +399,20 @@ func TestBindingListing(t *testing.T) {
 	}
 }
 
+func TestAnonymousBinding(t *testing.T) {
+	cls := &GraphClause{SBinding: "?s", PBinding: AnonymousBinding, OBinding: AnonymousBinding}
+	if !cls.HasAnonymousBinding() {
+		t.Errorf("GraphClause.HasAnonymousBinding(%v) = false; want true", cls)
+	}
+	if got, want := cls.BindingsMap(), map[string]int{"?s": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GraphClause.BindingsMap(%v) = %v; want %v", cls, got, want)
+	}
+	cls = &GraphClause{SBinding: "?s", PBinding: "?p", OBinding: "?o"}
+	if cls.HasAnonymousBinding() {
+		t.Errorf("GraphClause.HasAnonymousBinding(%v) = true; want false", cls)
+	}
+}
+
 func TestIsEmptyClause(t *testing.T) {
 	testTable := []struct {
 		in  *GraphClause
@@ -0,0 +1,433 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// sortSpillThreshold is the number of rows above which Sort spills sorted runs
+// of rows to temporary files and merges them. Zero disables spilling.
+var sortSpillThreshold int64
+
+// SetSortSpillThreshold sets the number of rows above which Sort switches to
+// an external merge sort. The rows are sorted in runs of at most that many
+// rows, which are written to temporary files and then merged back into the
+// table. Zero or negative values, the default, sort all the rows in memory. It
+// returns the previous threshold.
+//
+// Tables holding custom literals are always sorted in memory, since their
+// values can only be rebuilt by the builder that knows their type, and so are
+// tables holding triples. Tables whose runs cannot be written or read back are
+// also sorted in memory, since their rows are only replaced once the merge
+// succeeds.
+func SetSortSpillThreshold(rows int) int {
+	if rows < 0 {
+		rows = 0
+	}
+	return int(atomic.SwapInt64(&sortSpillThreshold, int64(rows)))
+}
+
+// errNotSpillable is returned when the rows hold values that cannot be spilled.
+var errNotSpillable = errors.New("table: custom literals and triples cannot be spilled")
+
+// spilledRow is the encoding of a row written to a run file.
+type spilledRow map[string]spilledCell
+
+// spilledCell is the encoding of a cell written to a run file. Nil is set for
+// nil cells, which are different from empty ones. Since gob does not transmit
+// zero values, HasS and HasT tell apart the empty strings and zero times from
+// the missing ones.
+type spilledCell struct {
+	Nil  bool
+	S    string
+	HasS bool
+	N    *spilledNode
+	P    *spilledPredicate
+	L    *spilledLiteral
+	T    time.Time
+	HasT bool
+}
+
+// spilledNode is the encoding of a node written to a run file.
+type spilledNode struct {
+	Type, ID string
+}
+
+// spilledPredicate is the encoding of a predicate written to a run file.
+type spilledPredicate struct {
+	ID       string
+	Temporal bool
+	Anchor   time.Time
+}
+
+// spilledLiteral is the encoding of a literal written to a run file. Only the
+// field matching its type is set.
+type spilledLiteral struct {
+	Type    literal.Type
+	Lang    string
+	Bool    bool
+	Int64   int64
+	Float64 float64
+	Text    string
+	Blob    []byte
+	List    []spilledLiteral
+}
+
+// spillable returns true if all the cells of the rows can be spilled.
+func spillable(rows []Row) bool {
+	for _, r := range rows {
+		for _, c := range r {
+			if c == nil {
+				continue
+			}
+			if c.Tr != nil || (c.L != nil && c.L.Type() == literal.Custom) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// encodeRow returns the encoding of the provided row.
+func encodeRow(r Row) (spilledRow, error) {
+	sr := make(spilledRow, len(r))
+	for k, c := range r {
+		if c == nil {
+			sr[k] = spilledCell{Nil: true}
+			continue
+		}
+		var sc spilledCell
+		if c.S != nil {
+			sc.S, sc.HasS = *c.S, true
+		}
+		if c.T != nil {
+			sc.T, sc.HasT = *c.T, true
+		}
+		if c.N != nil {
+			sc.N = &spilledNode{Type: c.N.Type().String(), ID: c.N.ID().String()}
+		}
+		if c.P != nil {
+			sp := &spilledPredicate{ID: string(c.P.ID())}
+			if ta, err := c.P.TimeAnchor(); err == nil {
+				sp.Temporal, sp.Anchor = true, *ta
+			}
+			sc.P = sp
+		}
+		if c.L != nil {
+			sl, err := encodeLiteral(c.L)
+			if err != nil {
+				return nil, err
+			}
+			sc.L = &sl
+		}
+		sr[k] = sc
+	}
+	return sr, nil
+}
+
+// encodeLiteral returns the encoding of the provided literal.
+func encodeLiteral(l *literal.Literal) (spilledLiteral, error) {
+	sl := spilledLiteral{Type: l.Type(), Lang: l.Lang()}
+	var err error
+	switch l.Type() {
+	case literal.Bool:
+		sl.Bool, err = l.Bool()
+	case literal.Int64:
+		sl.Int64, err = l.Int64()
+	case literal.Float64:
+		sl.Float64, err = l.Float64()
+	case literal.Text:
+		sl.Text, err = l.Text()
+	case literal.Blob:
+		sl.Blob, err = l.Blob()
+	case literal.List:
+		var ls []*literal.Literal
+		ls, err = l.List()
+		for _, e := range ls {
+			se, eErr := encodeLiteral(e)
+			if eErr != nil {
+				return sl, eErr
+			}
+			sl.List = append(sl.List, se)
+		}
+	default:
+		return sl, errNotSpillable
+	}
+	return sl, err
+}
+
+// decodeRow rebuilds the row out of its encoding.
+func decodeRow(sr spilledRow) (Row, error) {
+	r := make(Row, len(sr))
+	for k, sc := range sr {
+		if sc.Nil {
+			r[k] = nil
+			continue
+		}
+		c := &Cell{}
+		if sc.HasS {
+			c.S = CellString(sc.S)
+		}
+		if sc.HasT {
+			t := sc.T
+			c.T = &t
+		}
+		if sc.N != nil {
+			n, err := node.NewNodeFromStrings(sc.N.Type, sc.N.ID)
+			if err != nil {
+				return nil, err
+			}
+			c.N = n
+		}
+		if sc.P != nil {
+			var (
+				p   *predicate.Predicate
+				err error
+			)
+			if sc.P.Temporal {
+				p, err = predicate.NewTemporal(sc.P.ID, sc.P.Anchor)
+			} else {
+				p, err = predicate.NewImmutable(sc.P.ID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.P = p
+		}
+		if sc.L != nil {
+			l, err := decodeLiteral(*sc.L)
+			if err != nil {
+				return nil, err
+			}
+			c.L = l
+		}
+		r[k] = c
+	}
+	return r, nil
+}
+
+// decodeLiteral rebuilds the literal out of its encoding.
+func decodeLiteral(sl spilledLiteral) (*literal.Literal, error) {
+	b := literal.DefaultBuilder()
+	var v interface{}
+	switch sl.Type {
+	case literal.Bool:
+		v = sl.Bool
+	case literal.Int64:
+		v = sl.Int64
+	case literal.Float64:
+		v = sl.Float64
+	case literal.Text:
+		v = sl.Text
+	case literal.Blob:
+		bs := sl.Blob
+		if bs == nil {
+			bs = []byte{}
+		}
+		v = bs
+	case literal.List:
+		ls := []*literal.Literal{}
+		for _, se := range sl.List {
+			e, err := decodeLiteral(se)
+			if err != nil {
+				return nil, err
+			}
+			ls = append(ls, e)
+		}
+		v = ls
+	default:
+		return nil, errNotSpillable
+	}
+	l, err := b.Build(sl.Type, v)
+	if err != nil {
+		return nil, err
+	}
+	if sl.Lang != "" {
+		return l.WithLang(sl.Lang)
+	}
+	return l, nil
+}
+
+// run is a temporary file holding a sorted run of spilled rows.
+type run struct {
+	f   *os.File
+	n   int
+	dec *gob.Decoder
+	cur Row
+	idx int
+}
+
+// next reads the next row of the run into cur. It returns io.EOF once all the
+// rows of the run were read.
+func (r *run) next() error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	var sr spilledRow
+	if err := r.dec.Decode(&sr); err != nil {
+		return err
+	}
+	row, err := decodeRow(sr)
+	if err != nil {
+		return err
+	}
+	r.cur, r.n = row, r.n-1
+	return nil
+}
+
+// rewind prepares the run to be read from the beginning.
+func (r *run) rewind() error {
+	if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.dec = gob.NewDecoder(bufio.NewReader(r.f))
+	return nil
+}
+
+// writeRun sorts the provided rows and writes them to a new temporary file.
+func writeRun(rows []Row, cfg SortConfig, idx int) (*run, error) {
+	sort.Sort(bySortConfig{rows, cfg})
+	f, err := ioutil.TempFile("", "badwolf-sort-")
+	if err != nil {
+		return nil, err
+	}
+	rn := &run{f: f, n: len(rows), idx: idx}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	for _, r := range rows {
+		sr, err := encodeRow(r)
+		if err == nil {
+			err = enc.Encode(sr)
+		}
+		if err != nil {
+			rn.close()
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		rn.close()
+		return nil, err
+	}
+	return rn, nil
+}
+
+// close closes and removes the file of the run.
+func (r *run) close() {
+	r.f.Close()
+	os.Remove(r.f.Name())
+}
+
+// runHeap orders the runs being merged by their current row. Ties are broken
+// by the order of the runs.
+type runHeap struct {
+	runs []*run
+	cfg  SortConfig
+}
+
+func (h *runHeap) Len() int {
+	return len(h.runs)
+}
+
+func (h *runHeap) Less(i, j int) bool {
+	ri, rj := h.runs[i], h.runs[j]
+	if rowLess(ri.cur, rj.cur, h.cfg) {
+		return true
+	}
+	if rowLess(rj.cur, ri.cur, h.cfg) {
+		return false
+	}
+	return ri.idx < rj.idx
+}
+
+func (h *runHeap) Swap(i, j int) {
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	h.runs = append(h.runs, x.(*run))
+}
+
+func (h *runHeap) Pop() interface{} {
+	r := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return r
+}
+
+// externalSort sorts the provided rows in place, spilling runs of the
+// provided size to temporary files and merging them. The rows are only
+// replaced once all the runs were merged. If a run cannot be written or read
+// back, the error is returned and the rows are left complete but not sorted.
+func externalSort(rows []Row, cfg SortConfig, size int) error {
+	if !spillable(rows) {
+		return errNotSpillable
+	}
+	var runs []*run
+	defer func() {
+		for _, r := range runs {
+			r.close()
+		}
+	}()
+	for i := 0; i < len(rows); i += size {
+		j := i + size
+		if j > len(rows) {
+			j = len(rows)
+		}
+		r, err := writeRun(rows[i:j], cfg, len(runs))
+		if err != nil {
+			return err
+		}
+		runs = append(runs, r)
+	}
+	h := &runHeap{cfg: cfg}
+	for _, r := range runs {
+		if err := r.rewind(); err != nil {
+			return err
+		}
+		if err := r.next(); err != nil {
+			return err
+		}
+		h.runs = append(h.runs, r)
+	}
+	heap.Init(h)
+	merged := make([]Row, 0, len(rows))
+	for h.Len() > 0 {
+		r := h.runs[0]
+		merged = append(merged, r.cur)
+		switch err := r.next(); err {
+		case nil:
+			heap.Fix(h, 0)
+		case io.EOF:
+			heap.Pop(h)
+		default:
+			return err
+		}
+	}
+	copy(rows, merged)
+	return nil
+}
@@ -17,19 +17,25 @@ package table
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
+	"github.com/pborman/uuid"
 )
 
 // Table contains the results of a BQL query. This table implementation is not
@@ -42,6 +48,8 @@ type Table struct {
 	Data []Row `json:"rows,omitempty"`
 	// mbs is an internal map for bindings existence.
 	mbs map[string]bool
+	// ordered is true if the rows were explicitly sorted via Sort.
+	ordered bool
 	// mu provides a RW mutex for safe table manipulation operations.
 	mu sync.RWMutex
 }
@@ -69,6 +77,112 @@ type Cell struct {
 	P *predicate.Predicate `json:"pred,omitempty"`
 	L *literal.Literal     `json:"lit,omitempty"`
 	T *time.Time           `json:"time,omitempty"`
+	// Tr holds a whole triple, as packed by TRIPLE(). Triple cells are equal if
+	// their triples have the same UUID.
+	Tr *triple.Triple `json:"triple,omitempty"`
+}
+
+// NewNodeCell returns a new cell holding the provided node.
+func NewNodeCell(n *node.Node) *Cell {
+	return &Cell{N: n}
+}
+
+// NewPredicateCell returns a new cell holding the provided predicate.
+func NewPredicateCell(p *predicate.Predicate) *Cell {
+	return &Cell{P: p}
+}
+
+// NewLiteralCell returns a new cell holding the provided literal.
+func NewLiteralCell(l *literal.Literal) *Cell {
+	return &Cell{L: l}
+}
+
+// NewTimeCell returns a new cell holding the provided time.
+func NewTimeCell(t time.Time) *Cell {
+	return &Cell{T: &t}
+}
+
+// NewTripleCell returns a new cell holding the provided triple.
+func NewTripleCell(t *triple.Triple) *Cell {
+	return &Cell{Tr: t}
+}
+
+// NewStringCell returns a new cell holding the provided string.
+func NewStringCell(s string) *Cell {
+	return &Cell{S: &s}
+}
+
+// Node returns the node held by the cell, if any.
+func (c *Cell) Node() (*node.Node, bool) {
+	if c == nil || c.N == nil {
+		return nil, false
+	}
+	return c.N, true
+}
+
+// Predicate returns the predicate held by the cell, if any.
+func (c *Cell) Predicate() (*predicate.Predicate, bool) {
+	if c == nil || c.P == nil {
+		return nil, false
+	}
+	return c.P, true
+}
+
+// Literal returns the literal held by the cell, if any.
+func (c *Cell) Literal() (*literal.Literal, bool) {
+	if c == nil || c.L == nil {
+		return nil, false
+	}
+	return c.L, true
+}
+
+// Time returns the time held by the cell, if any.
+func (c *Cell) Time() (time.Time, bool) {
+	if c == nil || c.T == nil {
+		return time.Time{}, false
+	}
+	return *c.T, true
+}
+
+// StringValue returns the string held by the cell, if any.
+func (c *Cell) StringValue() (string, bool) {
+	if c == nil || c.S == nil {
+		return "", false
+	}
+	return *c.S, true
+}
+
+// Triple returns the triple held by the cell, if any.
+func (c *Cell) Triple() (*triple.Triple, bool) {
+	if c == nil || c.Tr == nil {
+		return nil, false
+	}
+	return c.Tr, true
+}
+
+// Equal returns true if both cells hold the same value. Triple cells are
+// compared by the UUIDs of their triples.
+func (c *Cell) Equal(o *Cell) bool {
+	if c != nil && o != nil && c.Tr != nil && o.Tr != nil {
+		return uuid.Equal(c.Tr.UUID(), o.Tr.UUID())
+	}
+	return reflect.DeepEqual(c, o)
+}
+
+// key returns the string identifying the value of the cell when grouping and
+// dropping repeated values. Triple cells are identified by the UUIDs of their
+// triples.
+func (c *Cell) key() string {
+	if c.Tr != nil {
+		return "triple:" + c.Tr.UUID().String()
+	}
+	return c.String()
+}
+
+// IsEmpty returns true if the cell does not hold any value. A nil cell is
+// empty.
+func (c *Cell) IsEmpty() bool {
+	return c == nil || *c == Cell{}
 }
 
 // String returns a readable representation of a cell.
@@ -88,6 +202,9 @@ func (c *Cell) String() string {
 	if c.T != nil {
 		return c.T.Format(time.RFC3339Nano)
 	}
+	if c.Tr != nil {
+		return c.Tr.String()
+	}
 	return "<NULL>"
 }
 
@@ -156,6 +273,22 @@ func (t *Table) Rows() []Row {
 	return t.Data
 }
 
+// EachRow calls fn for each row of the table, in order, and stops at the
+// first error returned by fn, which is passed through. The rows iterated are
+// the ones available when the call started; fn may modify the table, but the
+// changes are not visited.
+func (t *Table) EachRow(fn func(Row) error) error {
+	t.mu.RLock()
+	data := t.Data
+	t.mu.RUnlock()
+	for _, r := range data {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // unsafeAddBindings add the new bindings provided to the table bypassing the lock.
 func (t *Table) unsafeAddBindings(bs []string) {
 	for _, b := range bs {
@@ -195,6 +328,35 @@ func (t *Table) ProjectBindings(bs []string) error {
 	return nil
 }
 
+// ReorderBindings rearranges the bindings of the table so the provided ones
+// come first in the given order, followed by the remaining ones in their
+// current order. It is a presentation change that does not modify the rows. If
+// any of the provided bindings is unknown or repeated, the table is left
+// unmodified and an error is returned.
+func (t *Table) ReorderBindings(bs []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seen := make(map[string]bool, len(bs))
+	for _, b := range bs {
+		if !t.mbs[b] {
+			return fmt.Errorf("cannot reorder unknown binding %s; known bindinds are %v", b, t.AvailableBindings)
+		}
+		if seen[b] {
+			return fmt.Errorf("cannot reorder binding %s more than once", b)
+		}
+		seen[b] = true
+	}
+	nbs := make([]string, 0, len(t.AvailableBindings))
+	nbs = append(nbs, bs...)
+	for _, b := range t.AvailableBindings {
+		if !seen[b] {
+			nbs = append(nbs, b)
+		}
+	}
+	t.AvailableBindings = nbs
+	return nil
+}
+
 // HasBinding returns true if the binding currently exist on the table.
 func (t *Table) HasBinding(b string) bool {
 	t.mu.RLock()
@@ -251,7 +413,8 @@ func disjointBindings(b1, b2 map[string]bool) bool {
 	return true
 }
 
-// MergeRows takes a list of rows and returns a new map containing both.
+// MergeRows takes a list of rows and returns a new map containing both. If
+// several rows hold the same binding, the value of the first one is kept.
 func MergeRows(ms []Row) Row {
 	res := make(map[string]*Cell)
 	for _, om := range ms {
@@ -264,6 +427,62 @@ func MergeRows(ms []Row) Row {
 	return res
 }
 
+// Provenance records the index of the clause that produced each cell of the
+// rows of a table. It is kept apart from the rows, so tables not tracking
+// provenance pay no cost for it. Cells are tracked by identity, hence copies
+// of a cell have no provenance. It is safe for concurrent use.
+type Provenance struct {
+	mu      sync.RWMutex
+	clauses map[*Cell]int
+}
+
+// NewProvenance returns a new empty provenance record.
+func NewProvenance() *Provenance {
+	return &Provenance{
+		clauses: make(map[*Cell]int),
+	}
+}
+
+// Record sets the provided clause as the origin of the cells of the row that
+// have no provenance yet.
+func (p *Provenance) Record(r Row, clause int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range r {
+		if _, ok := p.clauses[c]; !ok {
+			p.clauses[c] = clause
+		}
+	}
+}
+
+// Clause returns the index of the clause that produced the provided cell, and
+// whether it is known.
+func (p *Provenance) Clause(c *Cell) (int, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	i, ok := p.clauses[c]
+	return i, ok
+}
+
+// Describe returns a readable description of the clauses that produced the
+// cells of the provided row, sorted by binding.
+func (p *Provenance) Describe(r Row) string {
+	var bs []string
+	for k := range r {
+		bs = append(bs, k)
+	}
+	sort.Strings(bs)
+	var res []string
+	for _, k := range bs {
+		if i, ok := p.Clause(r[k]); ok {
+			res = append(res, fmt.Sprintf("%s from clause %d", k, i))
+		} else {
+			res = append(res, fmt.Sprintf("%s from unknown clause", k))
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
 // DotProduct does the dot product with the provided table
 func (t *Table) DotProduct(t2 *Table) error {
 	t.mu.Lock()
@@ -297,9 +516,87 @@ func (t *Table) DotProduct(t2 *Table) error {
 	return nil
 }
 
-// LeftOptionalJoin does a left join using the provided right table.
+// AntiJoin removes the rows that agree on all the bindings shared with any row
+// of the provided table. Rows holding an empty value for any shared binding
+// never agree, so they are kept. If the tables share no bindings, any row of
+// the provided table agrees with every row, so all the rows are removed unless
+// the provided table is empty.
+func (t *Table) AntiJoin(t2 *Table) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t2.mu.RLock()
+	defer t2.mu.RUnlock()
+	if len(t2.Data) == 0 {
+		return
+	}
+	var bs []string
+	for k := range intersectBindings(t.mbs, t2.mbs) {
+		bs = append(bs, k)
+	}
+	if len(bs) == 0 {
+		t.Data = nil
+		return
+	}
+	sort.Strings(bs)
+	var buf bytes.Buffer
+	key := func(r Row) (string, bool) {
+		buf.Reset()
+		for _, k := range bs {
+			c := r[k]
+			if c.IsEmpty() {
+				return "", false
+			}
+			s := c.String()
+			buf.WriteString(strconv.Itoa(len(s)))
+			buf.WriteString(":")
+			buf.WriteString(s)
+		}
+		return buf.String(), true
+	}
+	matches := make(map[string]bool)
+	for _, r := range t2.Data {
+		if k, ok := key(r); ok {
+			matches[k] = true
+		}
+	}
+	var data []Row
+	for _, r := range t.Data {
+		if k, ok := key(r); ok && matches[k] {
+			continue
+		}
+		data = append(data, r)
+	}
+	t.Data = data
+}
+
+// MergePolicy decides what a join does when a left and a right row collide,
+// this is, when they agree on some of the shared bindings but hold different
+// non-empty values for others.
+type MergePolicy int8
+
+const (
+	// KeepLeft keeps the left row as is, without joining the colliding right
+	// row to it.
+	KeepLeft MergePolicy = iota
+	// KeepRight joins the colliding rows, keeping the values of the right row
+	// for the bindings they disagree on.
+	KeepRight
+	// ErrorOnConflict makes the join fail when two rows collide.
+	ErrorOnConflict
+)
+
+// LeftOptionalJoin does a left join using the provided right table. Rows are
+// joined when their shared bindings agree; an empty cell agrees with any value
+// and it is filled with the value of the other row. Colliding rows are not
+// joined. It is equivalent to LeftOptionalJoinWithPolicy using KeepLeft.
 func (t *Table) LeftOptionalJoin(t2 *Table) error {
-	if equalBindings(t.mbs, t2.mbs) || len(t2.mbs) == 0 {
+	return t.LeftOptionalJoinWithPolicy(t2, KeepLeft)
+}
+
+// LeftOptionalJoinWithPolicy does a left join using the provided right table,
+// resolving colliding rows using the provided policy.
+func (t *Table) LeftOptionalJoinWithPolicy(t2 *Table, p MergePolicy) error {
+	if len(t2.mbs) == 0 || equalBindings(t.mbs, t2.mbs) && p == KeepLeft {
 		// Both tables have the same bindings. Hence, the optinal results of
 		// the second table can be ignored and keep the left originol table
 		// untouched.
@@ -310,10 +607,119 @@ func (t *Table) LeftOptionalJoin(t2 *Table) error {
 		// as a regular cross product.
 		return t.DotProduct(t2)
 	}
-	// There are some overlapping bindings. That requires to sort both tables
-	// by the overlapping bindings and and then create the new rows merging
-	// both row ranges.
-	joinWithRange(t, t2)
+	// There are some overlapping bindings. If rows can only fully agree or
+	// disagree, both tables can be sorted by the overlapping bindings to then
+	// create the new rows merging both row ranges.
+	ibs := intersectBindings(t.mbs, t2.mbs)
+	if p == KeepLeft && !t.hasEmptyCells(ibs) && !t2.hasEmptyCells(ibs) {
+		joinWithRange(t, t2)
+		return nil
+	}
+	return joinWithPolicy(t, t2, p)
+}
+
+// hasEmptyCells returns true if any row has an empty cell for one of the
+// provided bindings.
+func (t *Table) hasEmptyCells(bs map[string]bool) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, r := range t.Data {
+		for k := range bs {
+			if r[k].IsEmpty() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compareShared compares the values of the provided bindings for both rows.
+// It returns whether they agree on a non-empty value for any binding, and the
+// first binding they hold different non-empty values for, if any.
+func compareShared(r1, r2 Row, bs []string) (bool, string) {
+	agree, conflict := false, ""
+	for _, k := range bs {
+		c1, c2 := r1[k], r2[k]
+		if c1.IsEmpty() || c2.IsEmpty() {
+			continue
+		}
+		if c1.Equal(c2) {
+			agree = true
+		} else if conflict == "" {
+			conflict = k
+		}
+	}
+	return agree, conflict
+}
+
+// mergeRowPair merges the two rows into a new row containing all the provided
+// bindings. Empty cells are filled with the value of the other row. If both
+// rows hold different non-empty values, the right one is only kept when using
+// KeepRight.
+func mergeRowPair(r1, r2 Row, bs map[string]bool, p MergePolicy) Row {
+	nr := make(Row, len(bs))
+	for k, v := range r1 {
+		nr[k] = v
+	}
+	for k, v := range r2 {
+		c, ok := nr[k]
+		if !ok || c.IsEmpty() || p == KeepRight && !v.IsEmpty() {
+			nr[k] = v
+		}
+	}
+	for k := range bs {
+		if _, ok := nr[k]; !ok {
+			nr[k] = &Cell{}
+		}
+	}
+	return nr
+}
+
+// joinWithPolicy joins the two tables with overlapping bindings comparing all
+// the pairs of rows, which allows empty cells to act as wildcards and
+// colliding rows to be resolved using the provided policy.
+func joinWithPolicy(t, t2 *Table, p MergePolicy) error {
+	ibs := intersectBindings(t.mbs, t2.mbs)
+	ubs := unionBindings(t.mbs, t2.mbs)
+	sbs := make([]string, 0, len(ibs))
+	for k := range ibs {
+		sbs = append(sbs, k)
+	}
+	sort.Strings(sbs)
+	sortTablesData(t, t2, sbs)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t2.mu.Lock()
+	defer t2.mu.Unlock()
+	var res []Row
+	for _, t1r := range t.Data {
+		extended := false
+		for _, t2r := range t2.Data {
+			agree, conflict := compareShared(t1r, t2r, sbs)
+			if conflict != "" {
+				if !agree || p == KeepLeft {
+					continue
+				}
+				if p == ErrorOnConflict {
+					return fmt.Errorf("LeftOptionalJoin found conflicting values %s and %s for binding %s", t1r[conflict], t2r[conflict], conflict)
+				}
+			}
+			res = append(res, mergeRowPair(t1r, t2r, ubs, p))
+			extended = true
+		}
+		if !extended {
+			res = append(res, extendRow(t1r, ubs))
+		}
+	}
+
+	// Update the table.
+	t.mbs = ubs
+	t.AvailableBindings = nil
+	for k := range ubs {
+		t.AvailableBindings = append(t.AvailableBindings, k)
+	}
+	t.Data = res
 	return nil
 }
 
@@ -451,7 +857,7 @@ func sortTablesData(t, t2 *Table, bs []string) {
 // for both provided rows.
 func joinable(r1, r2 Row, bs map[string]bool) bool {
 	for k := range bs {
-		if !reflect.DeepEqual(r1[k], r2[k]) {
+		if !r1[k].Equal(r2[k]) {
 			return false
 		}
 	}
@@ -492,12 +898,61 @@ func (t *Table) Limit(i int64) {
 	}
 }
 
+// Distinct removes the repeated rows of the table, keeping the first
+// occurrence of each one in its original position.
+func (t *Table) Distinct() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seen := make(map[string]bool, len(t.Data))
+	td := t.Data[:0]
+	for _, r := range t.Data {
+		var b bytes.Buffer
+		for _, k := range t.AvailableBindings {
+			v := ""
+			if c, ok := r[k]; ok && c != nil {
+				v = c.key()
+			}
+			// Each value is length prefixed to avoid ambiguous concatenations.
+			fmt.Fprintf(&b, "%d:%s", len(v), v)
+		}
+		if k := b.String(); !seen[k] {
+			seen[k] = true
+			td = append(td, r)
+		}
+	}
+	for i := len(td); i < len(t.Data); i++ {
+		t.Data[i] = nil
+	}
+	t.Data = td
+}
+
+// NullsOrder indicates where empty cells are placed when sorting.
+type NullsOrder int8
+
+const (
+	// NullsUnspecified is the default behavior. Empty cells compare equal to
+	// any other value, so their position in the sorted table is unspecified.
+	NullsUnspecified NullsOrder = iota
+	// NullsFirst places empty cells before any other value.
+	NullsFirst
+	// NullsLast places empty cells after any other value.
+	NullsLast
+)
+
 // SortConfig contains the sorting information. Contains the binding order
 // to use while sorting as well as the direction for each of them to use.
 type SortConfig []sortConfig
 type sortConfig struct {
 	Binding string
 	Desc    bool
+	// Nulls indicates where empty cells are placed regardless of the
+	// direction used to sort the rest of the values.
+	Nulls NullsOrder
+	// Key, if not nil, computes the value to sort by from each row. It is
+	// evaluated once per row into a transient column named after Binding,
+	// which is removed once sorted. Rows whose key cannot be computed hold
+	// an empty cell.
+	Key func(Row) (*Cell, error)
 }
 
 func (s SortConfig) String() string {
@@ -510,6 +965,12 @@ func (s SortConfig) String() string {
 		} else {
 			b.WriteString("ASC ")
 		}
+		switch sc.Nulls {
+		case NullsFirst:
+			b.WriteString("NULLS FIRST ")
+		case NullsLast:
+			b.WriteString("NULLS LAST ")
+		}
 	}
 	b.WriteString("]")
 	return b.String()
@@ -562,6 +1023,18 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 	if !ok {
 		log.Fatalf("Could not retrieve binding %q! %v %v", cfg.Binding, ri, rj)
 	}
+	if cfg.Nulls != NullsUnspecified {
+		ni, nj := ci.IsEmpty(), cj.IsEmpty()
+		if ni != nj {
+			return ni == (cfg.Nulls == NullsFirst)
+		}
+		if ni {
+			if last {
+				return false
+			}
+			return rowLess(ri, rj, c[1:])
+		}
+	}
 	si, sj := "", ""
 	// Check if it has a string.
 	if ci.S != nil && cj.S != nil {
@@ -583,7 +1056,21 @@ func rowLess(ri, rj Row, c SortConfig) bool {
 	if ci.T != nil && cj.T != nil {
 		si, sj = ci.T.Format(time.RFC3339Nano), cj.T.Format(time.RFC3339Nano)
 	}
+	// Check if it has a triple.
+	if ci.Tr != nil && cj.Tr != nil {
+		si, sj = ci.Tr.String(), cj.Tr.String()
+	}
 	l := stringLess(si, sj, cfg.Desc)
+	// Numbers are ordered by value, and custom literals using the comparison
+	// of their type, if any.
+	if ci.L != nil && cj.L != nil && (ci.L.Type() == literal.Int64 || ci.L.Type() == literal.Float64 || ci.L.Type() == literal.Custom) {
+		if cl, err := ci.L.Compare(cj.L); err == nil {
+			l = cl
+			if cfg.Desc {
+				l *= -1
+			}
+		}
+	}
 	if l < 0 {
 		return true
 	}
@@ -604,16 +1091,97 @@ func (t *Table) unsafeSort(cfg SortConfig) {
 	if cfg == nil {
 		return
 	}
-	sort.Sort(bySortConfig{t.Data, cfg})
+	var keys []string
+	for _, c := range cfg {
+		if c.Key == nil {
+			continue
+		}
+		keys = append(keys, c.Binding)
+		for _, r := range t.Data {
+			v, err := c.Key(r)
+			if err != nil || v == nil {
+				v = &Cell{}
+			}
+			r[c.Binding] = v
+		}
+	}
+	if th := int(atomic.LoadInt64(&sortSpillThreshold)); th <= 0 || len(t.Data) <= th || externalSort(t.Data, cfg, th) != nil {
+		sort.Sort(bySortConfig{t.Data, cfg})
+	}
+	for _, k := range keys {
+		for _, r := range t.Data {
+			delete(r, k)
+		}
+	}
 }
 
 // Sort sorts the table given a sort configuration.
 func (t *Table) Sort(cfg SortConfig) {
 	t.mu.Lock()
 	t.unsafeSort(cfg)
+	if cfg != nil {
+		t.ordered = true
+	}
 	t.mu.Unlock()
 }
 
+// Fingerprint returns a stable hash of the table bindings and row contents
+// that can be used to detect result changes without diffing full tables. If
+// the table was sorted via Sort (for instance, by an ORDER BY clause) the
+// fingerprint is sensitive to row and binding order. Otherwise, both the rows
+// and the bindings are treated as unordered sets, so paginated or reshuffled
+// results remain comparable.
+func (t *Table) Fingerprint() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.unsafeFingerprint(t.ordered)
+}
+
+// FingerprintWithOrder returns the same hash as Fingerprint, but lets the
+// caller decide whether row and binding order should be taken into account.
+func (t *Table) FingerprintWithOrder(ordered bool) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.unsafeFingerprint(ordered)
+}
+
+// unsafeFingerprint computes the table fingerprint bypassing the lock.
+func (t *Table) unsafeFingerprint(ordered bool) string {
+	bs := append([]string{}, t.AvailableBindings...)
+	if !ordered {
+		sort.Strings(bs)
+	}
+	// Each value is length prefixed to avoid ambiguous concatenations.
+	write := func(b *bytes.Buffer, v string) {
+		fmt.Fprintf(b, "%d:%s", len(v), v)
+	}
+	var rows []string
+	for _, r := range t.Data {
+		var b bytes.Buffer
+		for _, k := range bs {
+			v := ""
+			if c, ok := r[k]; ok && c != nil {
+				v = c.String()
+			}
+			write(&b, v)
+		}
+		rows = append(rows, b.String())
+	}
+	if !ordered {
+		sort.Strings(rows)
+	}
+	h := sha256.New()
+	var hdr bytes.Buffer
+	for _, k := range bs {
+		write(&hdr, k)
+	}
+	fmt.Fprintf(h, "%d|%s|%d|", len(bs), hdr.String(), len(rows))
+	for _, r := range rows {
+		fmt.Fprintf(h, "%d:%s", len(r), r)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // Accumulator type represents a generic accumulator for independent values
 // expressed as the element of the array slice. Returns the values after being
 // accumulated. If the wrong type is passed in, it will crash casting the
@@ -626,6 +1194,16 @@ type Accumulator interface {
 	Reset()
 }
 
+// CloneableAccumulator is an accumulator that can create new accumulators of
+// the same kind in their initial state. It allows reducing groups
+// independently from each other without sorting them first.
+type CloneableAccumulator interface {
+	Accumulator
+
+	// Clone returns a new accumulator of the same kind in its initial state.
+	Clone() Accumulator
+}
+
 // sumInt64 implements an accumulator that sum int64 values.
 type sumInt64 struct {
 	initialState int64
@@ -652,6 +1230,11 @@ func (s *sumInt64) Reset() {
 	s.state = s.initialState
 }
 
+// Clone returns a new accumulator with the same initial state.
+func (s *sumInt64) Clone() Accumulator {
+	return &sumInt64{s.initialState, s.initialState}
+}
+
 // NewSumInt64LiteralAccumulator accumulates the int64 types of a literal.
 func NewSumInt64LiteralAccumulator(s int64) Accumulator {
 	return &sumInt64{s, s}
@@ -683,6 +1266,11 @@ func (s *sumFloat64) Reset() {
 	s.state = s.initialState
 }
 
+// Clone returns a new accumulator with the same initial state.
+func (s *sumFloat64) Clone() Accumulator {
+	return &sumFloat64{s.initialState, s.initialState}
+}
+
 // NewSumFloat64LiteralAccumulator accumulates the int64 types of a literal.
 func NewSumFloat64LiteralAccumulator(s float64) Accumulator {
 	return &sumFloat64{s, s}
@@ -704,6 +1292,11 @@ func (c *countAcc) Reset() {
 	c.state = 0
 }
 
+// Clone returns a new accumulator with no occurrences counted.
+func (c *countAcc) Clone() Accumulator {
+	return &countAcc{0}
+}
+
 // NewCountAccumulator accumulates the int64 types of a literal.
 func NewCountAccumulator() Accumulator {
 	return &countAcc{0}
@@ -715,7 +1308,16 @@ type countDistinctAcc struct {
 }
 
 // Accumulate takes the given value and accumulates it to the current state.
+// Cells holding time anchors are compared as instants, so the same instant
+// expressed with different time zone offsets is only counted once.
 func (c *countDistinctAcc) Accumulate(v interface{}) (interface{}, error) {
+	if cell, ok := v.(*Cell); ok && cell != nil && cell.T != nil {
+		utc := cell.T.UTC()
+		v = NewTimeCell(utc)
+	}
+	if cell, ok := v.(*Cell); ok && cell != nil && cell.Tr != nil {
+		v = cell.key()
+	}
 	vs := fmt.Sprintf("%v", v)
 	c.state[vs]++
 	return int64(len(c.state)), nil
@@ -726,12 +1328,56 @@ func (c *countDistinctAcc) Reset() {
 	c.state = make(map[string]int64)
 }
 
+// Clone returns a new accumulator with no values seen.
+func (c *countDistinctAcc) Clone() Accumulator {
+	return &countDistinctAcc{make(map[string]int64)}
+}
+
 // NewCountDistinctAccumulator counts calls by incrementing the internal state
 // only if the value has not been seen before.
 func NewCountDistinctAccumulator() Accumulator {
 	return &countDistinctAcc{make(map[string]int64)}
 }
 
+// concatAcc implements an accumulator that joins the string form of the
+// accumulated cells.
+type concatAcc struct {
+	sep   string
+	n     int
+	state strings.Builder
+}
+
+// Accumulate takes the given value and accumulates it to the current state.
+// Values are joined in the order they are accumulated; unbound values are
+// skipped.
+func (c *concatAcc) Accumulate(v interface{}) (interface{}, error) {
+	if cell, ok := v.(*Cell); ok && !cell.IsEmpty() {
+		if c.n > 0 {
+			c.state.WriteString(c.sep)
+		}
+		c.state.WriteString(cell.String())
+		c.n++
+	}
+	return c.state.String(), nil
+}
+
+// Resets the current state back to the original one.
+func (c *concatAcc) Reset() {
+	c.n = 0
+	c.state = strings.Builder{}
+}
+
+// Clone returns a new accumulator with the same separator and no values.
+func (c *concatAcc) Clone() Accumulator {
+	return &concatAcc{sep: c.sep}
+}
+
+// NewConcatAccumulator joins the string form of the accumulated cells using
+// the provided separator. Groups without values accumulate the empty string.
+func NewConcatAccumulator(sep string) Accumulator {
+	return &concatAcc{sep: sep}
+}
+
 // groupRangeReduce takes a sorted range and generates a new row containing
 // the aggregated columns and the non aggregated ones.
 func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[string]Accumulator) (Row, error) {
@@ -773,20 +1419,22 @@ func (t *Table) groupRangeReduce(i, j int, alias map[string]string, acc map[stri
 			if !ok {
 				return nil, fmt.Errorf("aggregated bindings require and alias; binding %s missing alias", b)
 			}
-			// Accumulators currently only can return numeric literals.
+			// Accumulators currently only can return numeric literals and strings.
 			switch acc.(type) {
+			case string:
+				newRow[a] = NewStringCell(acc.(string))
 			case int64:
 				l, err := literal.DefaultBuilder().Build(literal.Int64, acc)
 				if err != nil {
 					return nil, err
 				}
-				newRow[a] = &Cell{L: l}
+				newRow[a] = NewLiteralCell(l)
 			case float64:
 				l, err := literal.DefaultBuilder().Build(literal.Float64, acc)
 				if err != nil {
 					return nil, err
 				}
-				newRow[a] = &Cell{L: l}
+				newRow[a] = NewLiteralCell(l)
 			default:
 				return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
 			}
@@ -837,28 +1485,35 @@ func (t *Table) unsafeFullGroupRangeReduce(i, j int, acc map[string]map[string]A
 			}
 		}
 	}
-	// Create a new row based on the resulting aggregations with the proper
-	// binding aliasing and the non aggregated values.
+	return reducedRow(rng[0], acc, vaccs)
+}
+
+// reducedRow creates the row resulting of reducing a group given its first row
+// and the values of its accumulators, with the proper binding aliasing and the
+// non aggregated values.
+func reducedRow(first Row, acc map[string]map[string]AliasAccPair, vaccs map[string]map[string]interface{}) (Row, error) {
 	newRow := Row{}
-	for b, v := range rng[0] {
+	for b, v := range first {
 		for _, app := range acc[b] { //macc {
 			if app.Acc == nil {
 				newRow[app.OutAlias] = v
 			} else {
-				// Accumulators currently only can return numeric literals.
-				switch vaccs[app.InAlias][app.OutAlias].(type) {
+				// Accumulators currently only can return numeric literals and strings.
+				switch v := vaccs[app.InAlias][app.OutAlias].(type) {
+				case string:
+					newRow[app.OutAlias] = NewStringCell(v)
 				case int64:
 					l, err := literal.DefaultBuilder().Build(literal.Int64, vaccs[app.InAlias][app.OutAlias])
 					if err != nil {
 						return nil, err
 					}
-					newRow[app.OutAlias] = &Cell{L: l}
+					newRow[app.OutAlias] = NewLiteralCell(l)
 				case float64:
 					l, err := literal.DefaultBuilder().Build(literal.Float64, vaccs[app.InAlias][app.OutAlias])
 					if err != nil {
 						return nil, err
 					}
-					newRow[app.OutAlias] = &Cell{L: l}
+					newRow[app.OutAlias] = NewLiteralCell(l)
 				default:
 					return nil, fmt.Errorf("aggregation of binding %s returned unknown value %v or type", b, acc)
 				}
@@ -894,7 +1549,15 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 	maaps := toMap(aaps)
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	// Input validation tests.
+	if err := t.unsafeCheckReduce(cfg, aaps, maaps); err != nil {
+		return err
+	}
+	return t.unsafeReduce(cfg, aaps, maaps)
+}
+
+// unsafeCheckReduce validates the reduce configuration against the table
+// bindings. This call bypasses the lock.
+func (t *Table) unsafeCheckReduce(cfg SortConfig, aaps []AliasAccPair, maaps map[string]map[string]AliasAccPair) error {
 	if len(t.AvailableBindings) != len(maaps) {
 		return fmt.Errorf("table.Reduce cannot project bindings; current %v, requested %v", t.AvailableBindings, aaps)
 	}
@@ -913,17 +1576,24 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 	if cnt != len(t.AvailableBindings) {
 		return fmt.Errorf("table.Reduce invalid reduce configuration in cfg=%v, aap=%v for table with binding %v", cfg, aaps, t.AvailableBindings)
 	}
-	// Valid reduce configuration. Reduce sorts the table and then reduces
-	// contiguous groups row groups.
+	return nil
+}
+
+// unsafeReduce sorts the table and then reduces contiguous groups of rows. This
+// call bypasses the lock.
+func (t *Table) unsafeReduce(cfg SortConfig, aaps []AliasAccPair, maaps map[string]map[string]AliasAccPair) error {
 	if len(t.Data) == 0 {
 		return nil
 	}
-	t.unsafeSort(cfg)
+	// Keep the input order of the rows of each group for the accumulators.
+	if cfg != nil {
+		sort.Stable(bySortConfig{t.Data, cfg})
+	}
 	last, lastIdx, current, newData := "", 0, "", []Row{}
 	id := func(r Row) string {
 		res := bytes.NewBufferString("")
 		for _, c := range cfg {
-			res.WriteString(r[c.Binding].String())
+			res.WriteString(r[c.Binding].key())
 			res.WriteString(";")
 		}
 		return res.String()
@@ -952,7 +1622,13 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 		return err
 	}
 	newData = append(newData, nr)
-	// Update the table.
+	t.unsafeSetReduced(aaps, newData)
+	return nil
+}
+
+// unsafeSetReduced updates the table metadata and data to reflect the reduce
+// operation. This call bypasses the lock.
+func (t *Table) unsafeSetReduced(aaps []AliasAccPair, data []Row) {
 	t.AvailableBindings, t.mbs = []string{}, make(map[string]bool)
 	for _, aap := range aaps {
 		if !t.mbs[aap.OutAlias] {
@@ -960,7 +1636,90 @@ func (t *Table) Reduce(cfg SortConfig, aaps []AliasAccPair) error {
 		}
 		t.mbs[aap.OutAlias] = true
 	}
-	t.Data = newData
+	t.Data = data
+}
+
+// reduceGroup holds the incremental state of a group being hash reduced. The
+// accumulators and their values follow the order of the aggregated pairs.
+type reduceGroup struct {
+	first Row
+	accs  []Accumulator
+	vals  []interface{}
+}
+
+// HashReduce works like Reduce, but groups the rows in a hash table keeping
+// the accumulators of each group up to date as the rows are visited, instead of
+// sorting the whole table first. Only the reduced rows get sorted, which is
+// much cheaper for tables with many rows per group. It requires all the
+// accumulators to be cloneable, and falls back to Reduce otherwise.
+func (t *Table) HashReduce(cfg SortConfig, aaps []AliasAccPair) error {
+	maaps := toMap(aaps)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.unsafeCheckReduce(cfg, aaps, maaps); err != nil {
+		return err
+	}
+	var aggs []AliasAccPair
+	for _, aap := range aaps {
+		if aap.Acc == nil {
+			continue
+		}
+		if _, ok := aap.Acc.(CloneableAccumulator); !ok {
+			return t.unsafeReduce(cfg, aaps, maaps)
+		}
+		aggs = append(aggs, aap)
+	}
+	if len(t.Data) == 0 {
+		return nil
+	}
+	groups, order := make(map[string]*reduceGroup), []*reduceGroup{}
+	var key bytes.Buffer
+	for _, r := range t.Data {
+		key.Reset()
+		for _, c := range cfg {
+			key.WriteString(r[c.Binding].key())
+			key.WriteString(";")
+		}
+		g, ok := groups[key.String()]
+		if !ok {
+			g = &reduceGroup{
+				first: r,
+				accs:  make([]Accumulator, len(aggs)),
+				vals:  make([]interface{}, len(aggs)),
+			}
+			for i, a := range aggs {
+				g.accs[i] = a.Acc.(CloneableAccumulator).Clone()
+			}
+			groups[key.String()] = g
+			order = append(order, g)
+		}
+		for i, a := range g.accs {
+			av, err := a.Accumulate(r[aggs[i].InAlias])
+			if err != nil {
+				return err
+			}
+			g.vals[i] = av
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return rowLess(order[i].first, order[j].first, cfg)
+	})
+	newData := make([]Row, 0, len(order))
+	for _, g := range order {
+		vaccs := make(map[string]map[string]interface{})
+		for i, a := range aggs {
+			if _, ok := vaccs[a.InAlias]; !ok {
+				vaccs[a.InAlias] = make(map[string]interface{})
+			}
+			vaccs[a.InAlias][a.OutAlias] = g.vals[i]
+		}
+		nr, err := reducedRow(g.first, maaps, vaccs)
+		if err != nil {
+			return err
+		}
+		newData = append(newData, nr)
+	}
+	t.unsafeSetReduced(aaps, newData)
 	return nil
 }
 
@@ -980,34 +1739,252 @@ func (t *Table) Filter(f func(Row) bool) int {
 	return nRowsRemoved
 }
 
-// ToText convert the table into a readable text versions. It requires the
-// separator to be used between cells.
-func (t *Table) ToText(sep string) (*bytes.Buffer, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-	res, row := &bytes.Buffer{}, &bytes.Buffer{}
-	res.WriteString(strings.Join(t.AvailableBindings, sep))
-	res.WriteString("\n")
+// Unwind expands each row into one row per element of the list literal bound
+// to the provided binding, binding each element to the provided alias. Rows
+// bound to an empty list are dropped. Unwind fails and leaves the table
+// unmodified if any row is not bound to a list literal.
+func (t *Table) Unwind(binding, alias string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.mbs[binding] {
+		return fmt.Errorf("table.Unwind: unknown binding %q; known bindings are %v", binding, t.AvailableBindings)
+	}
+	if t.mbs[alias] {
+		return fmt.Errorf("table.Unwind: alias %q is already in use", alias)
+	}
+	var newData []Row
 	for _, r := range t.Data {
-		err := r.ToTextLine(row, t.AvailableBindings, sep)
+		c := r[binding]
+		if c == nil || c.L == nil || c.L.Type() != literal.List {
+			return fmt.Errorf("table.Unwind: binding %q requires a list literal, got %v instead", binding, c)
+		}
+		ls, err := c.L.List()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if _, err := res.Write(row.Bytes()); err != nil {
-			return nil, err
+		for _, l := range ls {
+			nr := make(Row, len(r)+1)
+			for k, v := range r {
+				nr[k] = v
+			}
+			nr[alias] = NewLiteralCell(l)
+			newData = append(newData, nr)
 		}
-		if _, err := res.WriteString("\n"); err != nil {
-			return nil, err
+	}
+	t.unsafeAddBindings([]string{alias})
+	t.Data = newData
+	return nil
+}
+
+// Interval represents a time bucketing interval. Intervals can be expressed
+// as Go durations (e.g. "15m", "1h") or as calendar units of days ("1d") or
+// months ("1mo"). Calendar units are always computed in UTC.
+type Interval struct {
+	s      string
+	d      time.Duration
+	days   int64
+	months int64
+}
+
+// calendarUnits returns the number of calendar units in the provided interval
+// text if it is expressed as an integer followed by the provided unit suffix.
+func calendarUnits(s, unit string) (int64, bool) {
+	if !strings.HasSuffix(s, unit) {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSuffix(s, unit), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// ParseInterval returns the interval for the provided textual representation.
+func ParseInterval(s string) (*Interval, error) {
+	ts := strings.TrimSpace(s)
+	i := &Interval{s: ts}
+	if n, ok := calendarUnits(ts, "mo"); ok {
+		i.months = n
+	} else if n, ok := calendarUnits(ts, "d"); ok {
+		i.days = n
+	} else {
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q; %v", s, err)
 		}
-		row.Reset()
+		i.d = d
+	}
+	if i.months < 0 || i.days < 0 || i.d < 0 || (i.months == 0 && i.days == 0 && i.d == 0) {
+		return nil, fmt.Errorf("invalid interval %q; intervals must be positive", s)
+	}
+	return i, nil
+}
+
+// String returns the textual representation of the interval.
+func (i *Interval) String() string {
+	return i.s
+}
+
+// floorMultiple returns the largest multiple of m lower or equal to n.
+func floorMultiple(n, m int64) int64 {
+	r := n % m
+	if r < 0 {
+		r += m
+	}
+	return n - r
+}
+
+// Truncate returns the start of the bucket of the interval that contains the
+// provided time. Buckets are aligned to the Unix epoch and the returned time
+// is always in UTC.
+func (i *Interval) Truncate(t time.Time) time.Time {
+	u := t.UTC()
+	switch {
+	case i.months > 0:
+		m := floorMultiple(int64(u.Year()-1970)*12+int64(u.Month())-1, i.months)
+		return time.Date(1970, time.Month(m+1), 1, 0, 0, 0, 0, time.UTC)
+	case i.days > 0:
+		const day = 24 * 60 * 60
+		d := floorMultiple(floorMultiple(u.Unix(), day)/day, i.days)
+		return time.Unix(d*day, 0).UTC()
+	default:
+		return u.Truncate(i.d)
+	}
+}
+
+// Next returns the start of the bucket that follows the bucket starting at the
+// provided time.
+func (i *Interval) Next(t time.Time) time.Time {
+	u := t.UTC()
+	switch {
+	case i.months > 0:
+		return u.AddDate(0, int(i.months), 0)
+	case i.days > 0:
+		return u.AddDate(0, 0, int(i.days))
+	default:
+		return u.Add(i.d)
+	}
+}
+
+// histogramGroup contains the number of rows in each bucket of a group.
+type histogramGroup struct {
+	first  Row
+	counts map[int64]int64
+	starts []time.Time
+}
+
+// Histogram replaces the rows of the table with the number of rows whose time
+// anchor, bound to the provided binding, falls in each bucket of the interval,
+// for each group of rows sharing the values of the group bindings. The
+// resulting table contains the group bindings, the start of each bucket bound
+// to startAlias, and its number of rows bound to countAlias. Groups keep the
+// order of their first row and buckets are sorted by start time. Rows with an
+// empty value are skipped. Buckets without rows between the first and last
+// bucket of a group are only emitted, with a zero count, if zeros is true.
+func (t *Table) Histogram(groups []string, binding string, iv *Interval, startAlias, countAlias string, zeros bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range append([]string{binding}, groups...) {
+		if !t.mbs[b] {
+			return fmt.Errorf("table.Histogram: unknown binding %q; known bindings are %v", b, t.AvailableBindings)
+		}
+	}
+	hgs, order := make(map[string]*histogramGroup), []*histogramGroup{}
+	var key bytes.Buffer
+	for _, r := range t.Data {
+		c := r[binding]
+		if c.IsEmpty() {
+			continue
+		}
+		if c.T == nil {
+			return fmt.Errorf("table.Histogram: can only count time anchors; found %v for binding %q instead", c, binding)
+		}
+		key.Reset()
+		for _, g := range groups {
+			if gc := r[g]; gc != nil {
+				key.WriteString(gc.key())
+			}
+			key.WriteString(";")
+		}
+		hg, ok := hgs[key.String()]
+		if !ok {
+			hg = &histogramGroup{
+				first:  r,
+				counts: make(map[int64]int64),
+			}
+			hgs[key.String()] = hg
+			order = append(order, hg)
+		}
+		s := iv.Truncate(*c.T)
+		if _, ok := hg.counts[s.UnixNano()]; !ok {
+			hg.starts = append(hg.starts, s)
+		}
+		hg.counts[s.UnixNano()]++
+	}
+	var data []Row
+	for _, hg := range order {
+		sort.Slice(hg.starts, func(i, j int) bool {
+			return hg.starts[i].Before(hg.starts[j])
+		})
+		starts := hg.starts
+		if zeros {
+			starts = nil
+			last := hg.starts[len(hg.starts)-1]
+			for s := hg.starts[0]; !s.After(last); s = iv.Next(s) {
+				starts = append(starts, s)
+			}
+		}
+		for _, s := range starts {
+			r := make(Row, len(groups)+2)
+			for _, g := range groups {
+				r[g] = hg.first[g]
+			}
+			l, err := literal.DefaultBuilder().Build(literal.Int64, hg.counts[s.UnixNano()])
+			if err != nil {
+				return err
+			}
+			r[startAlias], r[countAlias] = NewTimeCell(s), NewLiteralCell(l)
+			data = append(data, r)
+		}
+	}
+	t.AvailableBindings, t.mbs = nil, make(map[string]bool)
+	t.unsafeAddBindings(append(append([]string{}, groups...), startAlias, countAlias))
+	t.Data = data
+	return nil
+}
+
+// ToText convert the table into a readable text versions. It requires the
+// separator to be used between cells.
+func (t *Table) ToText(sep string) (*bytes.Buffer, error) {
+	res := &bytes.Buffer{}
+	if err := t.WriteText(res, sep); err != nil {
+		return nil, err
 	}
 	return res, nil
 }
 
+// WriteText writes the same readable text version of the table returned by
+// ToText into the writer, one row at a time, instead of building it in
+// memory first.
+func (t *Table) WriteText(w io.Writer, sep string) error {
+	bs := t.Bindings()
+	if _, err := io.WriteString(w, strings.Join(bs, sep)+"\n"); err != nil {
+		return err
+	}
+	row := &bytes.Buffer{}
+	return t.EachRow(func(r Row) error {
+		row.Reset()
+		if err := r.ToTextLine(row, bs, sep); err != nil {
+			return err
+		}
+		row.WriteString("\n")
+		_, err := w.Write(row.Bytes())
+		return err
+	})
+}
+
 // String attempts to force serialize the table into a string.
 func (t *Table) String() string {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	b, err := t.ToText("\t")
 	if err != nil {
 		return fmt.Sprintf("Failed to serialize to text! Error: %s", err)
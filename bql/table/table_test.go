@@ -18,11 +18,16 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
 	"github.com/google/badwolf/triple/node"
 	"github.com/google/badwolf/triple/predicate"
@@ -74,6 +79,134 @@ func TestCellString(t *testing.T) {
 	}
 }
 
+func TestTripleCells(t *testing.T) {
+	parse := func(s string) *triple.Triple {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", s, err)
+		}
+		return trpl
+	}
+	utc := parse(`/u<joe>	"bought"@[2016-01-01T08:00:00Z]	/c<mini>`)
+	pst := parse(`/u<joe>	"bought"@[2016-01-01T00:00:00-08:00]	/c<mini>`)
+	other := parse(`/u<joe>	"bought"@[2016-01-01T08:00:00Z]	/c<model s>`)
+
+	c := NewTripleCell(utc)
+	if got, ok := c.Triple(); !ok || got != utc {
+		t.Errorf("Cell.Triple returned (%v, %v); want (%v, true)", got, ok, utc)
+	}
+	if _, ok := NewNodeCell(node.NewBlankNode()).Triple(); ok {
+		t.Error("Cell.Triple should not return a triple for a node cell")
+	}
+	if got, want := c.String(), "/u<joe>\t\"bought\"@[2016-01-01T08:00:00Z]\t/c<mini>"; got != want {
+		t.Errorf("Cell.String returned %q; want %q", got, want)
+	}
+	if c.IsEmpty() {
+		t.Error("Cell.IsEmpty should be false for a triple cell")
+	}
+
+	// Triples with the same UUID are equal, regardless of how their anchors
+	// are written.
+	if !c.Equal(NewTripleCell(pst)) {
+		t.Errorf("Cell.Equal(%v, %v) should be true", c, pst)
+	}
+	if c.Equal(NewTripleCell(other)) {
+		t.Errorf("Cell.Equal(%v, %v) should be false", c, other)
+	}
+	if c.Equal(NewStringCell(c.String())) {
+		t.Error("Cell.Equal should be false for a triple and a string cell")
+	}
+
+	tbl, err := New([]string{"?t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trpl := range []*triple.Triple{utc, other, pst} {
+		tbl.AddRow(Row{"?t": NewTripleCell(trpl)})
+	}
+	tbl.Distinct()
+	if got, want := tbl.NumRows(), 2; got != want {
+		t.Errorf("Distinct kept %d rows out of triple cells; want %d", got, want)
+	}
+	if !joinable(Row{"?t": NewTripleCell(utc)}, Row{"?t": NewTripleCell(pst)}, map[string]bool{"?t": true}) {
+		t.Error("joinable should be true for rows holding triples with the same UUID")
+	}
+
+	acc := NewCountDistinctAccumulator()
+	var n interface{}
+	for _, trpl := range []*triple.Triple{utc, pst, other} {
+		if n, err = acc.Accumulate(NewTripleCell(trpl)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := n.(int64), int64(2); got != want {
+		t.Errorf("countDistinctAcc counted %d distinct triples; want %d", got, want)
+	}
+}
+
+func TestCellConstructorsAndAccessors(t *testing.T) {
+	now := time.Now()
+	n := node.NewBlankNode()
+	p, err := predicate.NewImmutable("foo")
+	if err != nil {
+		t.Fatalf("failed to create predicate with error %v", err)
+	}
+	l, err := literal.DefaultBuilder().Parse(`"true"^^type:bool`)
+	if err != nil {
+		t.Fatalf("failed to create literal with error %v", err)
+	}
+
+	if got, ok := NewNodeCell(n).Node(); !ok || got != n {
+		t.Errorf("NewNodeCell(%v).Node() = %v, %v; want %v, true", n, got, ok, n)
+	}
+	if got, ok := NewPredicateCell(p).Predicate(); !ok || got != p {
+		t.Errorf("NewPredicateCell(%v).Predicate() = %v, %v; want %v, true", p, got, ok, p)
+	}
+	if got, ok := NewLiteralCell(l).Literal(); !ok || got != l {
+		t.Errorf("NewLiteralCell(%v).Literal() = %v, %v; want %v, true", l, got, ok, l)
+	}
+	if got, ok := NewTimeCell(now).Time(); !ok || !got.Equal(now) {
+		t.Errorf("NewTimeCell(%v).Time() = %v, %v; want %v, true", now, got, ok, now)
+	}
+	if got, ok := NewStringCell("foo").StringValue(); !ok || got != "foo" {
+		t.Errorf("NewStringCell(%q).StringValue() = %q, %v; want %q, true", "foo", got, ok, "foo")
+	}
+
+	// Accessors for values the cell does not hold report them as missing.
+	c := NewNodeCell(n)
+	if _, ok := c.Predicate(); ok {
+		t.Errorf("%v.Predicate() should not return a predicate", c)
+	}
+	if _, ok := c.Literal(); ok {
+		t.Errorf("%v.Literal() should not return a literal", c)
+	}
+	if _, ok := c.Time(); ok {
+		t.Errorf("%v.Time() should not return a time", c)
+	}
+	if _, ok := c.StringValue(); ok {
+		t.Errorf("%v.StringValue() should not return a string", c)
+	}
+	var nc *Cell
+	if _, ok := nc.Node(); ok {
+		t.Errorf("nil cell Node() should not return a node")
+	}
+
+	testTable := []struct {
+		c    *Cell
+		want bool
+	}{
+		{c: nil, want: true},
+		{c: &Cell{}, want: true},
+		{c: NewNodeCell(n), want: false},
+		{c: NewStringCell(""), want: false},
+	}
+	for _, entry := range testTable {
+		if got := entry.c.IsEmpty(); got != entry.want {
+			t.Errorf("%v.IsEmpty() = %v; want %v", entry.c, got, entry.want)
+		}
+	}
+}
+
 func TestRowToTextLine(t *testing.T) {
 	r, b := make(Row), &bytes.Buffer{}
 	r["?foo"] = &Cell{S: CellString("foo")}
@@ -170,6 +303,64 @@ func TestTableToText(t *testing.T) {
 	}
 }
 
+func TestEachRow(t *testing.T) {
+	tbl, err := New([]string{"?i"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		tbl.AddRow(Row{"?i": NewStringCell(fmt.Sprintf("%d", i))})
+	}
+
+	var got []string
+	err = tbl.EachRow(func(r Row) error {
+		got = append(got, *r["?i"].S)
+		// Rows added during the iteration should not be visited.
+		tbl.AddRow(Row{"?i": NewStringCell("extra")})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tbl.EachRow failed with error %v", err)
+	}
+	if want := []string{"0", "1", "2", "3", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.EachRow visited %v; want %v", got, want)
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err = tbl.EachRow(func(r Row) error {
+		visited++
+		if visited == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || visited != 2 {
+		t.Errorf("tbl.EachRow should have stopped after 2 rows with error %v; got %d rows and error %v", stop, visited, err)
+	}
+}
+
+func TestTableWriteText(t *testing.T) {
+	tbl, err := New([]string{"?foo", "?bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		tbl.AddRow(Row{"?foo": NewStringCell("foo"), "?bar": NewStringCell("bar")})
+	}
+	var buf bytes.Buffer
+	if err := tbl.WriteText(&buf, ", "); err != nil {
+		t.Fatalf("tbl.WriteText failed with error %v", err)
+	}
+	want, err := tbl.ToText(", ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want.String() {
+		t.Errorf("tbl.WriteText returned\n%s\nwant\n%s", got, want)
+	}
+}
+
 func TestEqualBindings(t *testing.T) {
 	testTable := []struct {
 		b1   map[string]bool
@@ -280,12 +471,74 @@ func TestAppendTable(t *testing.T) {
 		if got, want := len(entry.t.Bindings()), len(entry.want.Bindings()); got != want {
 			t.Errorf("Append returned the wrong number of bindings; got %d, want %d", got, want)
 		}
-		if got, want := len(entry.t.Rows()), len(entry.want.Rows()); got != want {
+		if got, want := entry.t.NumRows(), entry.want.NumRows(); got != want {
 			t.Errorf("Append returned the wrong number of rows; got %d, want %d", got, want)
 		}
 	}
 }
 
+func TestReorderBindings(t *testing.T) {
+	newTable := func() *Table {
+		tbl, err := New([]string{"?foo", "?bar", "?baz"})
+		if err != nil {
+			t.Fatal(errors.New("tbl.New failed to create a new valid table"))
+		}
+		tbl.AddRow(Row{"?foo": NewStringCell("foo"), "?bar": NewStringCell("bar"), "?baz": NewStringCell("baz")})
+		return tbl
+	}
+	testTable := []struct {
+		bs      []string
+		success bool
+		want    []string
+	}{
+		{
+			bs:      []string{},
+			success: true,
+			want:    []string{"?foo", "?bar", "?baz"},
+		},
+		{
+			bs:      []string{"?baz", "?foo", "?bar"},
+			success: true,
+			want:    []string{"?baz", "?foo", "?bar"},
+		},
+		{
+			bs:      []string{"?baz"},
+			success: true,
+			want:    []string{"?baz", "?foo", "?bar"},
+		},
+		{
+			bs:      []string{"?bar", "?moo"},
+			success: false,
+			want:    []string{"?foo", "?bar", "?baz"},
+		},
+		{
+			bs:      []string{"?bar", "?bar"},
+			success: false,
+			want:    []string{"?foo", "?bar", "?baz"},
+		},
+	}
+	for _, entry := range testTable {
+		tbl := newTable()
+		if err := tbl.ReorderBindings(entry.bs); (err == nil) != entry.success {
+			t.Errorf("tbl.ReorderBindings(%v) returned error %v; want success %v", entry.bs, err, entry.success)
+		}
+		if got := tbl.Bindings(); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("tbl.ReorderBindings(%v) produced bindings %v; want %v", entry.bs, got, entry.want)
+		}
+		txt, err := tbl.ToText(",")
+		if err != nil {
+			t.Fatalf("tbl.ToText failed with error %v", err)
+		}
+		if got, want := strings.Split(txt.String(), "\n")[0], strings.Join(entry.want, ","); got != want {
+			t.Errorf("tbl.ToText rendered header %q after reordering; want %q", got, want)
+		}
+		r, _ := tbl.Row(0)
+		if got, _ := r["?baz"].StringValue(); got != "baz" {
+			t.Errorf("tbl.ReorderBindings(%v) modified the row data to %v", entry.bs, r)
+		}
+	}
+}
+
 func TestProjectBindings(t *testing.T) {
 	testTable := []struct {
 		t       *Table
@@ -661,7 +914,7 @@ func TestDotProduct(t *testing.T) {
 		if got, want := len(entry.t.Bindings()), len(entry.want.Bindings()); got != want {
 			t.Errorf("Append returned the wrong number of bindings; got %d, want %d", got, want)
 		}
-		if got, want := len(entry.t.Rows()), len(entry.want.Rows()); got != want {
+		if got, want := entry.t.NumRows(), entry.want.NumRows(); got != want {
 			t.Errorf("Append returned the wrong number of rows; got %d, want %d", got, want)
 		}
 	}
@@ -672,8 +925,8 @@ func TestDotProductContent(t *testing.T) {
 	if err := t1.DotProduct(t2); err != nil {
 		t.Errorf("Failed to dot product %s to %s with error %v", t2, t1, err)
 	}
-	if len(t1.Rows()) != 9 {
-		t.Errorf("DotProduct returned the wrong number of rows (%d)", len(t1.Rows()))
+	if t1.NumRows() != 9 {
+		t.Errorf("DotProduct returned the wrong number of rows (%d)", t1.NumRows())
 	}
 	if len(t1.Bindings()) != 2 {
 		t.Errorf("DotProduct returned the wrong number of bindings (%d)", len(t1.Bindings()))
@@ -691,6 +944,73 @@ func TestDotProductContent(t *testing.T) {
 	}
 }
 
+func TestAntiJoin(t *testing.T) {
+	newTable := func(bs []string, rows ...[]string) *Table {
+		tbl, err := New(bs)
+		if err != nil {
+			t.Fatalf("table.New(%v) failed with error %v", bs, err)
+		}
+		for _, vs := range rows {
+			r := make(Row)
+			for i, v := range vs {
+				if v == "" {
+					r[bs[i]] = &Cell{}
+					continue
+				}
+				r[bs[i]] = &Cell{S: CellString(v)}
+			}
+			tbl.AddRow(r)
+		}
+		return tbl
+	}
+	testTable := []struct {
+		t    *Table
+		t2   *Table
+		want []string
+	}{
+		{
+			// Rows agreeing on the shared binding are removed.
+			t:    newTable([]string{"?s", "?o"}, []string{"a", "1"}, []string{"b", "2"}, []string{"c", "3"}),
+			t2:   newTable([]string{"?s", "?t"}, []string{"b", "x"}, []string{"d", "y"}),
+			want: []string{"a", "c"},
+		},
+		{
+			// All the shared bindings need to agree.
+			t:    newTable([]string{"?s", "?o"}, []string{"a", "1"}, []string{"b", "2"}),
+			t2:   newTable([]string{"?s", "?o"}, []string{"a", "2"}, []string{"b", "2"}),
+			want: []string{"a"},
+		},
+		{
+			// Empty shared values never agree.
+			t:    newTable([]string{"?s", "?o"}, []string{"a", ""}, []string{"b", "2"}),
+			t2:   newTable([]string{"?o"}, []string{"1"}, []string{"2"}),
+			want: []string{"a"},
+		},
+		{
+			// An empty table removes nothing.
+			t:    newTable([]string{"?s"}, []string{"a"}, []string{"b"}),
+			t2:   newTable([]string{"?s"}),
+			want: []string{"a", "b"},
+		},
+		{
+			// Without shared bindings any row removes everything.
+			t:    newTable([]string{"?s"}, []string{"a"}, []string{"b"}),
+			t2:   newTable([]string{"?t"}, []string{"x"}),
+			want: nil,
+		},
+	}
+	for i, entry := range testTable {
+		entry.t.AntiJoin(entry.t2)
+		var got []string
+		for _, r := range entry.t.Rows() {
+			got = append(got, r["?s"].String())
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("AntiJoin case %d returned rows %v; want %v", i, got, entry.want)
+		}
+	}
+}
+
 func TestDeleteRow(t *testing.T) {
 	testTable := []struct {
 		t   *Table
@@ -727,8 +1047,8 @@ func TestDeleteRow(t *testing.T) {
 		if err := entry.t.DeleteRow(entry.idx); (err != nil) == entry.out {
 			t.Errorf("Failed to delete row %d with error %v", entry.idx, err)
 		}
-		if entry.out && len(entry.t.Rows()) != 2 {
-			t.Errorf("Failed successfully delete row %d ending with %d rows", entry.idx, len(entry.t.Rows()))
+		if entry.out && entry.t.NumRows() != 2 {
+			t.Errorf("Failed successfully delete row %d ending with %d rows", entry.idx, entry.t.NumRows())
 		}
 	}
 }
@@ -736,18 +1056,38 @@ func TestDeleteRow(t *testing.T) {
 func TestTruncate(t *testing.T) {
 	tbl := testDotTable(t, []string{"?foo"}, 3)
 
-	if got, want := len(tbl.Rows()), 3; got != want {
+	if got, want := tbl.NumRows(), 3; got != want {
 		t.Errorf("Failed to create a table with %d rows instead of %v", got, want)
 	}
 	tbl.Truncate()
-	if got, want := len(tbl.Rows()), 0; got != want {
+	if got, want := tbl.NumRows(), 0; got != want {
 		t.Errorf("Failed to truncate a table; got %d rows, want %v", got, want)
 	}
 }
 
+func TestDistinct(t *testing.T) {
+	tbl, err := New([]string{"?foo", "?bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range [][2]string{{"a", "b"}, {"a", "c"}, {"a", "b"}, {"ab", ""}, {"a", "b"}, {"ab", ""}} {
+		foo, bar := r[0], r[1]
+		tbl.AddRow(Row{"?foo": &Cell{S: CellString(foo)}, "?bar": &Cell{S: CellString(bar)}})
+	}
+	tbl.Distinct()
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, r["?foo"].String()+"|"+r["?bar"].String())
+	}
+	want := []string{"a|b", "a|c", "ab|"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.Distinct() kept rows %v; want %v", got, want)
+	}
+}
+
 func Limit(t *testing.T) {
 	tbl := testDotTable(t, []string{"?foo"}, 3)
-	if got, want := len(tbl.Rows()), 3; got != want {
+	if got, want := tbl.NumRows(), 3; got != want {
 		t.Errorf("Failed to create a table with %d rows instead of %v", got, want)
 	}
 
@@ -764,7 +1104,7 @@ func Limit(t *testing.T) {
 	}
 	for _, entry := range testTable {
 		tbl.Limit(entry.in)
-		if got, want := len(tbl.Rows()), entry.want; got != want {
+		if got, want := tbl.NumRows(), entry.want; got != want {
 			t.Errorf("Failed to limit a table correctly; want %d rows, got %v", got, want)
 		}
 	}
@@ -808,18 +1148,18 @@ func TestRowLess(t *testing.T) {
 		cfg  SortConfig
 		less bool
 	}{
-		{r1, r2, SortConfig{{"?s", false}}, true},
-		{r1, r2, SortConfig{{"?s", true}}, false},
-		{r1, r2, SortConfig{{"?t", false}}, false},
-		{r1, r2, SortConfig{{"?t", true}}, false},
-		{r1, r2, SortConfig{{"?s", false}, {"?t", false}}, true},
-		{r1, r2, SortConfig{{"?s", false}, {"?t", true}}, true},
-		{r1, r2, SortConfig{{"?s", true}, {"?t", false}}, false},
-		{r1, r2, SortConfig{{"?s", true}, {"?t", true}}, false},
-		{r1, r2, SortConfig{{"?t", false}, {"?s", false}}, true},
-		{r1, r2, SortConfig{{"?t", false}, {"?s", true}}, false},
-		{r1, r2, SortConfig{{"?t", true}, {"?s", false}}, true},
-		{r1, r2, SortConfig{{"?t", true}, {"?s", true}}, false},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: false}}, true},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: true}}, false},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: false}}, false},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: true}}, false},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: false}, {Binding: "?t", Desc: false}}, true},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: false}, {Binding: "?t", Desc: true}}, true},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: true}, {Binding: "?t", Desc: false}}, false},
+		{r1, r2, SortConfig{{Binding: "?s", Desc: true}, {Binding: "?t", Desc: true}}, false},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: false}, {Binding: "?s", Desc: false}}, true},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: false}, {Binding: "?s", Desc: true}}, false},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: true}, {Binding: "?s", Desc: false}}, true},
+		{r1, r2, SortConfig{{Binding: "?t", Desc: true}, {Binding: "?s", Desc: true}}, false},
 	}
 
 	for _, entry := range testTable {
@@ -854,12 +1194,12 @@ func TestSort(t *testing.T) {
 		cfg  SortConfig
 		desc bool
 	}{
-		{table(), SortConfig{{"?s", false}}, false},
-		{table(), SortConfig{{"?s", true}}, true},
-		{table(), SortConfig{{"?t", false}, {"?s", false}}, false},
-		{table(), SortConfig{{"?t", true}, {"?s", false}}, false},
-		{table(), SortConfig{{"?t", false}, {"?s", true}}, true},
-		{table(), SortConfig{{"?t", true}, {"?s", true}}, true},
+		{table(), SortConfig{{Binding: "?s", Desc: false}}, false},
+		{table(), SortConfig{{Binding: "?s", Desc: true}}, true},
+		{table(), SortConfig{{Binding: "?t", Desc: false}, {Binding: "?s", Desc: false}}, false},
+		{table(), SortConfig{{Binding: "?t", Desc: true}, {Binding: "?s", Desc: false}}, false},
+		{table(), SortConfig{{Binding: "?t", Desc: false}, {Binding: "?s", Desc: true}}, true},
+		{table(), SortConfig{{Binding: "?t", Desc: true}, {Binding: "?s", Desc: true}}, true},
 	}
 
 	for _, entry := range testTable {
@@ -872,6 +1212,318 @@ func TestSort(t *testing.T) {
 	}
 }
 
+func TestSortCustomLiterals(t *testing.T) {
+	// Versions are sorted numerically by their components instead of by their
+	// text encoding.
+	b, err := literal.NewBuilderWithTypes(&literal.TypeDefinition{
+		Name: "version",
+		Parse: func(s string) (interface{}, error) {
+			var major, minor int
+			if _, err := fmt.Sscanf(s, "%d.%d", &major, &minor); err != nil {
+				return nil, err
+			}
+			return [2]int{major, minor}, nil
+		},
+		Format: func(v interface{}) string {
+			vs := v.([2]int)
+			return fmt.Sprintf("%d.%d", vs[0], vs[1])
+		},
+		Compare: func(a, b interface{}) int {
+			va, vb := a.([2]int), b.([2]int)
+			if va[0] != vb[0] {
+				return va[0] - vb[0]
+			}
+			return va[1] - vb[1]
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := New([]string{"?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"1.10"^^type:version`, `"1.9"^^type:version`, `"10.0"^^type:version`, `"2.0"^^type:version`} {
+		l, err := b.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tbl.AddRow(Row{"?v": &Cell{L: l}})
+	}
+	for _, desc := range []bool{false, true} {
+		tbl.Sort(SortConfig{{Binding: "?v", Desc: desc}})
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?v"].String())
+		}
+		want := []string{`"1.9"^^type:version`, `"1.10"^^type:version`, `"2.0"^^type:version`, `"10.0"^^type:version`}
+		if desc {
+			want = []string{want[3], want[2], want[1], want[0]}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("table.Sort(DESC=%v) returned %v; want %v", desc, got, want)
+		}
+	}
+}
+
+func TestSortNulls(t *testing.T) {
+	tbl, err := New([]string{"?v", "?k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range []string{"b", "", "a", "", "c"} {
+		k := fmt.Sprint(i)
+		r := Row{"?v": &Cell{}, "?k": &Cell{S: &k}}
+		if v != "" {
+			vc := v
+			r["?v"] = &Cell{S: &vc}
+		}
+		tbl.AddRow(r)
+	}
+	table := []struct {
+		cfg  SortConfig
+		want []string
+	}{
+		{SortConfig{{Binding: "?v", Nulls: NullsFirst}, {Binding: "?k"}}, []string{"1", "3", "2", "0", "4"}},
+		{SortConfig{{Binding: "?v", Nulls: NullsLast}, {Binding: "?k"}}, []string{"2", "0", "4", "1", "3"}},
+		{SortConfig{{Binding: "?v", Desc: true, Nulls: NullsFirst}, {Binding: "?k", Desc: true}}, []string{"3", "1", "4", "0", "2"}},
+		{SortConfig{{Binding: "?v", Desc: true, Nulls: NullsLast}, {Binding: "?k"}}, []string{"4", "0", "2", "1", "3"}},
+	}
+	for _, entry := range table {
+		tbl.Sort(entry.cfg)
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, *r["?k"].S)
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("table.Sort(%v) returned keys %v; want %v", entry.cfg, got, entry.want)
+		}
+	}
+	if got, want := (SortConfig{{Binding: "?v", Desc: true, Nulls: NullsLast}}).String(), "[ ?v->DESC NULLS LAST ]"; got != want {
+		t.Errorf("SortConfig.String() = %q; want %q", got, want)
+	}
+}
+
+func TestSortComputedKeys(t *testing.T) {
+	tbl, err := New([]string{"?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"ccc", "a", "bb", "", "b"} {
+		vc := v
+		tbl.AddRow(Row{"?v": &Cell{S: &vc}})
+	}
+	length := func(r Row) (*Cell, error) {
+		if *r["?v"].S == "" {
+			return nil, fmt.Errorf("no length for empty values")
+		}
+		l, err := literal.DefaultBuilder().Build(literal.Int64, int64(len(*r["?v"].S)))
+		if err != nil {
+			return nil, err
+		}
+		return &Cell{L: l}, nil
+	}
+	tbl.Sort(SortConfig{{Binding: "LEN(?v)", Desc: true, Nulls: NullsLast, Key: length}, {Binding: "?v"}})
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, *r["?v"].S)
+		if _, ok := r["LEN(?v)"]; ok {
+			t.Errorf("table.Sort should have removed the transient sort column from row %v", r)
+		}
+	}
+	if want := []string{"ccc", "bb", "a", "b", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("table.Sort returned %v; want %v", got, want)
+	}
+}
+
+func TestSortSpill(t *testing.T) {
+	dir := t.TempDir()
+	oldTmp := os.Getenv("TMPDIR")
+	os.Setenv("TMPDIR", dir)
+	defer os.Setenv("TMPDIR", oldTmp)
+
+	b := literal.DefaultBuilder()
+	mustLiteral := func(l *literal.Literal, err error) *literal.Literal {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	text := mustLiteral(b.Build(literal.Text, "hello"))
+	list := mustLiteral(b.Build(literal.List, []*literal.Literal{
+		mustLiteral(b.Build(literal.Bool, true)),
+		mustLiteral(b.Build(literal.Blob, []byte{1, 2})),
+	}))
+	anchor := time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)
+	tmp, err := predicate.NewTemporal("in", anchor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imm, err := predicate.NewImmutable("knows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, err := node.NewNodeFromStrings("/item/book", "000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rows are built out of a permutation of distinct keys, so the sorted
+	// order is fully defined, and hold cells of every kind.
+	const n = 1000
+	build := func() *Table {
+		tbl, err := New([]string{"?k", "?f", "?v"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			k := int64((i * 7919) % n)
+			r := Row{
+				"?k": &Cell{L: mustLiteral(b.Build(literal.Int64, k))},
+				"?f": &Cell{L: mustLiteral(b.Build(literal.Float64, float64(k%13)/3))},
+			}
+			switch k % 8 {
+			case 0:
+				r["?v"] = &Cell{N: book}
+			case 1:
+				r["?v"] = &Cell{P: tmp}
+			case 2:
+				r["?v"] = &Cell{P: imm}
+			case 3:
+				r["?v"] = &Cell{L: mustLiteral(text.WithLang("en"))}
+			case 4:
+				r["?v"] = &Cell{L: list}
+			case 5:
+				r["?v"] = &Cell{T: &anchor}
+			case 6:
+				r["?v"] = &Cell{S: CellString("")}
+			default:
+				r["?v"] = &Cell{}
+			}
+			tbl.AddRow(r)
+		}
+		return tbl
+	}
+	rows := func(tbl *Table) []string {
+		var res []string
+		for _, r := range tbl.Rows() {
+			var b bytes.Buffer
+			if err := r.ToTextLine(&b, tbl.Bindings(), "|"); err != nil {
+				t.Fatal(err)
+			}
+			if c := r["?v"]; c.S != nil {
+				b.WriteString("|S")
+			}
+			res = append(res, b.String())
+		}
+		return res
+	}
+
+	for _, cfg := range []SortConfig{
+		{{Binding: "?k"}},
+		{{Binding: "?k", Desc: true}},
+		{{Binding: "?f"}, {Binding: "?k", Desc: true}},
+	} {
+		want := build()
+		want.Sort(cfg)
+		got := build()
+		old := SetSortSpillThreshold(7)
+		got.Sort(cfg)
+		SetSortSpillThreshold(old)
+		if g, w := rows(got), rows(want); !reflect.DeepEqual(g, w) {
+			t.Errorf("table.Sort(%v) with spilling returned %v; want %v", cfg, g, w)
+		}
+		// Check the rows are not sorted in memory instead.
+		got = build()
+		if err := externalSort(got.Data, cfg, 7); err != nil {
+			t.Fatalf("externalSort(%v) failed with error %v", cfg, err)
+		}
+		if g, w := rows(got), rows(want); !reflect.DeepEqual(g, w) {
+			t.Errorf("externalSort(%v) returned %v; want %v", cfg, g, w)
+		}
+		fs, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fs) != 0 {
+			t.Errorf("table.Sort(%v) left %d spilled runs behind", cfg, len(fs))
+		}
+	}
+}
+
+func TestSortSpillFailure(t *testing.T) {
+	oldTmp := os.Getenv("TMPDIR")
+	os.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+	defer os.Setenv("TMPDIR", oldTmp)
+
+	b := literal.DefaultBuilder()
+	build := func() *Table {
+		tbl, err := New([]string{"?k"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			l, err := b.Build(literal.Int64, int64((i*37)%100))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tbl.AddRow(Row{"?k": &Cell{L: l}})
+		}
+		return tbl
+	}
+	cfg := SortConfig{{Binding: "?k"}}
+	want := build()
+	want.Sort(cfg)
+
+	// The runs cannot be written, so the rows are kept and sorted in memory.
+	got := build()
+	if err := externalSort(got.Data, cfg, 7); err == nil {
+		t.Fatal("externalSort should have failed writing its runs")
+	}
+	if g, w := got.NumRows(), want.NumRows(); g != w {
+		t.Fatalf("externalSort left %d rows after failing; want %d", g, w)
+	}
+	for _, r := range got.Rows() {
+		if r == nil {
+			t.Fatal("externalSort released rows after failing")
+		}
+	}
+	old := SetSortSpillThreshold(7)
+	got.Sort(cfg)
+	SetSortSpillThreshold(old)
+	if !reflect.DeepEqual(got.Rows(), want.Rows()) {
+		t.Errorf("table.Sort returned %v after failing to spill; want %v", got.Rows(), want.Rows())
+	}
+}
+
+func TestSortNumericLiterals(t *testing.T) {
+	tbl, err := New([]string{"?v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"1.5e10"^^type:float64`, `"-1"^^type:float64`, `"2e-10"^^type:float64`, `"-2.5e3"^^type:float64`, `"1e-10"^^type:float64`} {
+		l, err := literal.DefaultBuilder().Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tbl.AddRow(Row{"?v": &Cell{L: l}})
+	}
+	for _, desc := range []bool{false, true} {
+		tbl.Sort(SortConfig{{Binding: "?v", Desc: desc}})
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?v"].String())
+		}
+		want := []string{`"-2500"^^type:float64`, `"-1"^^type:float64`, `"1e-10"^^type:float64`, `"2e-10"^^type:float64`, `"1.5e+10"^^type:float64`}
+		if desc {
+			want = []string{want[4], want[3], want[2], want[1], want[0]}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("table.Sort(DESC=%v) returned %v; want %v", desc, got, want)
+		}
+	}
+}
+
 func TestSumAccumulators(t *testing.T) {
 	// int64 sum accumulator.
 	var (
@@ -897,6 +1549,28 @@ func TestSumAccumulators(t *testing.T) {
 	if got, want := fv.(float64), float64(10); got != want {
 		t.Errorf("Int64 sum accumulator failed; got %f, want %f", got, want)
 	}
+	// Non finite values, only parsed when allowed, propagate through sums.
+	b := literal.NewBuilderWithNonFinitePolicy(literal.AllowNonFinite)
+	for _, entry := range []struct {
+		vs    []string
+		check func(float64) bool
+	}{
+		{[]string{"1", "NaN", "2"}, math.IsNaN},
+		{[]string{"1", "+Inf", "2"}, func(v float64) bool { return math.IsInf(v, 1) }},
+		{[]string{"+Inf", "-Inf"}, math.IsNaN},
+	} {
+		fa.Reset()
+		for _, v := range entry.vs {
+			l, err := b.Parse(`"` + v + `"^^type:float64`)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed with error %v", v, err)
+			}
+			fv, _ = fa.Accumulate(&Cell{L: l})
+		}
+		if !entry.check(fv.(float64)) {
+			t.Errorf("Float64 sum accumulator of %v returned %v", entry.vs, fv)
+		}
+	}
 }
 
 func TestCountAccumulators(t *testing.T) {
@@ -925,6 +1599,86 @@ func TestCountAccumulators(t *testing.T) {
 	}
 }
 
+func TestConcatAccumulator(t *testing.T) {
+	ca := NewConcatAccumulator(", ")
+	var cv interface{}
+	for _, c := range []*Cell{NewStringCell("b"), nil, {}, NewStringCell("a"), NewStringCell("b")} {
+		cv, _ = ca.Accumulate(c)
+	}
+	if got, want := cv.(string), "b, a, b"; got != want {
+		t.Errorf("Concat accumulator failed; got %q, want %q", got, want)
+	}
+	ca.Reset()
+	if cv, _ = ca.Accumulate(nil); cv.(string) != "" {
+		t.Errorf("Concat accumulator of no values returned %q; want an empty string", cv)
+	}
+	cc := ca.(CloneableAccumulator).Clone()
+	cc.Accumulate(NewStringCell("x"))
+	if cv, _ = cc.Accumulate(NewStringCell("y")); cv.(string) != "x, y" {
+		t.Errorf("Cloned concat accumulator returned %q; want %q", cv, "x, y")
+	}
+}
+
+func TestReduceConcatKeepsInputOrder(t *testing.T) {
+	for _, hash := range []bool{false, true} {
+		tbl, err := New([]string{"?g", "?v"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, gv := range [][2]string{{"g2", "c"}, {"g1", "b"}, {"g2", "a"}, {"g1", "d"}, {"g1", "a"}} {
+			tbl.AddRow(Row{"?g": NewStringCell(gv[0]), "?v": NewStringCell(gv[1])})
+		}
+		cfg := SortConfig{{Binding: "?g"}}
+		aaps := []AliasAccPair{
+			{InAlias: "?g", OutAlias: "?g"},
+			{InAlias: "?v", OutAlias: "?vs", Acc: NewConcatAccumulator("|")},
+		}
+		if hash {
+			err = tbl.HashReduce(cfg, aaps)
+		} else {
+			err = tbl.Reduce(cfg, aaps)
+		}
+		if err != nil {
+			t.Fatalf("reducing the table failed with error %v", err)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			got = append(got, r["?g"].String()+" "+r["?vs"].String())
+		}
+		if want := []string{"g1 b|d|a", "g2 c|a"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reducing with hash=%v returned %v; want %v", hash, got, want)
+		}
+	}
+}
+
+func TestCountDistinctTimeAnchors(t *testing.T) {
+	var instants []time.Time
+	for _, s := range []string{
+		"2016-01-01T00:00:00-08:00",
+		"2016-01-01T08:00:00Z",
+		"2016-01-01T09:00:00+01:00",
+		"2016-01-02T08:00:00Z",
+	} {
+		ta, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatalf("time.Parse(%q) failed with error %v", s, err)
+		}
+		instants = append(instants, ta)
+	}
+	da := NewCountDistinctAccumulator()
+	var dv interface{}
+	for i := range instants[:3] {
+		dv, _ = da.Accumulate(&Cell{T: &instants[i]})
+	}
+	if got, want := dv.(int64), int64(1); got != want {
+		t.Errorf("Count distinct accumulator counted %d distinct equal instants; want %d", got, want)
+	}
+	dv, _ = da.Accumulate(&Cell{T: &instants[3]})
+	if got, want := dv.(int64), int64(2); got != want {
+		t.Errorf("Count distinct accumulator counted %d distinct instants; want %d", got, want)
+	}
+}
+
 func TestGroupRangeReduce(t *testing.T) {
 	int64LiteralCell := func(i int64) *Cell {
 		l, _ := literal.DefaultBuilder().Build(literal.Int64, i)
@@ -1114,8 +1868,8 @@ func TestTableReduce(t *testing.T) {
 				},
 			},
 			cfg: SortConfig{
-				{"?foo", false},
-				{"?bar", false},
+				{Binding: "?foo", Desc: false},
+				{Binding: "?bar", Desc: false},
 			},
 			aap: []AliasAccPair{
 				{
@@ -1176,7 +1930,7 @@ func TestTableReduce(t *testing.T) {
 					},
 				},
 			},
-			cfg: SortConfig{{"?foo", false}},
+			cfg: SortConfig{{Binding: "?foo", Desc: false}},
 			aap: []AliasAccPair{
 				{
 					InAlias:  "?foo",
@@ -1244,7 +1998,7 @@ func TestTableReduce(t *testing.T) {
 					},
 				},
 			},
-			cfg: SortConfig{{"?foo", true}},
+			cfg: SortConfig{{Binding: "?foo", Desc: true}},
 			aap: []AliasAccPair{
 				{
 					InAlias:  "?foo",
@@ -1294,6 +2048,203 @@ func TestTableReduce(t *testing.T) {
 	}
 }
 
+// reduceTestTable returns a table with n rows spread over the provided number
+// of groups in no particular order.
+func reduceTestTable(n, groups int) *Table {
+	tbl, _ := New([]string{"?g", "?i", "?f", "?o"})
+	b := literal.DefaultBuilder()
+	for i := 0; i < n; i++ {
+		il, _ := b.Build(literal.Int64, int64(i))
+		fl, _ := b.Build(literal.Float64, float64(i)/2)
+		tbl.AddRow(Row{
+			"?g": NewStringCell(fmt.Sprintf("g%d", (i*7919)%groups)),
+			"?i": NewLiteralCell(il),
+			"?f": NewLiteralCell(fl),
+			"?o": NewStringCell(fmt.Sprintf("o%d", i%3)),
+		})
+	}
+	return tbl
+}
+
+// reduceTestPairs returns the aggregations used to reduce the rows of
+// reduceTestTable.
+func reduceTestPairs() []AliasAccPair {
+	return []AliasAccPair{
+		{InAlias: "?g", OutAlias: "?g"},
+		{InAlias: "?i", OutAlias: "?sum_i", Acc: NewSumInt64LiteralAccumulator(0)},
+		{InAlias: "?f", OutAlias: "?sum_f", Acc: NewSumFloat64LiteralAccumulator(0)},
+		{InAlias: "?o", OutAlias: "?count_o", Acc: NewCountAccumulator()},
+		{InAlias: "?o", OutAlias: "?distinct_o", Acc: NewCountDistinctAccumulator()},
+	}
+}
+
+// nonCloneableAcc hides the Clone method of the wrapped accumulator.
+type nonCloneableAcc struct {
+	Accumulator
+}
+
+func TestTableHashReduce(t *testing.T) {
+	for _, cfg := range []SortConfig{
+		{{Binding: "?g"}},
+		{{Binding: "?g", Desc: true}},
+	} {
+		for _, groups := range []int{1, 7, 100} {
+			want, got := reduceTestTable(100, groups), reduceTestTable(100, groups)
+			if err := want.Reduce(cfg, reduceTestPairs()); err != nil {
+				t.Fatalf("table.Reduce(%v, _) failed with error %v", cfg, err)
+			}
+			if err := got.HashReduce(cfg, reduceTestPairs()); err != nil {
+				t.Fatalf("table.HashReduce(%v, _) failed with error %v", cfg, err)
+			}
+			if got.NumRows() != groups {
+				t.Errorf("table.HashReduce(%v, _) returned %d rows; want %d", cfg, got.NumRows(), groups)
+			}
+			if !reflect.DeepEqual(got.Bindings(), want.Bindings()) || got.String() != want.String() {
+				t.Errorf("table.HashReduce(%v, _) returned\n%s\nwant\n%s", cfg, got, want)
+			}
+		}
+	}
+
+	// Accumulators that cannot be cloned fall back to sorting the table.
+	cfg := SortConfig{{Binding: "?g"}}
+	want, got := reduceTestTable(20, 3), reduceTestTable(20, 3)
+	if err := want.Reduce(cfg, reduceTestPairs()); err != nil {
+		t.Fatalf("table.Reduce(%v, _) failed with error %v", cfg, err)
+	}
+	aaps := reduceTestPairs()
+	aaps[3].Acc = &nonCloneableAcc{aaps[3].Acc}
+	if err := got.HashReduce(cfg, aaps); err != nil {
+		t.Fatalf("table.HashReduce(%v, _) failed with error %v", cfg, err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("table.HashReduce(%v, _) returned\n%s\nwant\n%s", cfg, got, want)
+	}
+
+	// Invalid configurations are rejected.
+	if err := reduceTestTable(5, 2).HashReduce(cfg, reduceTestPairs()[:2]); err == nil {
+		t.Errorf("table.HashReduce(%v, _) should have rejected a configuration missing bindings", cfg)
+	}
+}
+
+func benchmarkReduce(b *testing.B, hash bool, groups int) {
+	cfg := SortConfig{{Binding: "?g"}}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tbl := reduceTestTable(10000, groups)
+		b.StartTimer()
+		var err error
+		if hash {
+			err = tbl.HashReduce(cfg, reduceTestPairs())
+		} else {
+			err = tbl.Reduce(cfg, reduceTestPairs())
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRows(b *testing.B) {
+	tbl := reduceTestTable(100000, 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		for range tbl.Rows() {
+			n++
+		}
+	}
+}
+
+func BenchmarkEachRow(b *testing.B) {
+	tbl := reduceTestTable(100000, 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		tbl.EachRow(func(Row) error {
+			n++
+			return nil
+		})
+	}
+}
+
+func BenchmarkReduceFewGroups(b *testing.B) {
+	benchmarkReduce(b, false, 10)
+}
+
+func BenchmarkHashReduceFewGroups(b *testing.B) {
+	benchmarkReduce(b, true, 10)
+}
+
+func BenchmarkReduceManyGroups(b *testing.B) {
+	benchmarkReduce(b, false, 5000)
+}
+
+func BenchmarkHashReduceManyGroups(b *testing.B) {
+	benchmarkReduce(b, true, 5000)
+}
+
+func TestUnwind(t *testing.T) {
+	b := literal.DefaultBuilder()
+	lit := func(v interface{}) *literal.Literal {
+		var (
+			l   *literal.Literal
+			err error
+		)
+		switch v := v.(type) {
+		case string:
+			l, err = b.Build(literal.Text, v)
+		case []*literal.Literal:
+			l, err = b.Build(literal.List, v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	tbl, err := New([]string{"?s", "?l"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl.AddRow(Row{
+		"?s": &Cell{S: CellString("1s")},
+		"?l": &Cell{L: lit([]*literal.Literal{lit("a"), lit("b")})},
+	})
+	tbl.AddRow(Row{
+		"?s": &Cell{S: CellString("2s")},
+		"?l": &Cell{L: lit([]*literal.Literal{})},
+	})
+	tbl.AddRow(Row{
+		"?s": &Cell{S: CellString("3s")},
+		"?l": &Cell{L: lit([]*literal.Literal{lit("c")})},
+	})
+	if err := tbl.Unwind("?l", "?s"); err == nil {
+		t.Errorf("tbl.Unwind should have rejected an alias already in use")
+	}
+	if err := tbl.Unwind("?unknown", "?i"); err == nil {
+		t.Errorf("tbl.Unwind should have rejected an unknown binding")
+	}
+	if err := tbl.Unwind("?s", "?i"); err == nil {
+		t.Errorf("tbl.Unwind should have rejected non list cells")
+	}
+	if got, want := tbl.NumRows(), 3; got != want {
+		t.Fatalf("failed tbl.Unwind should have left the table unmodified; got %d rows, want %d", got, want)
+	}
+	if err := tbl.Unwind("?l", "?i"); err != nil {
+		t.Fatalf("tbl.Unwind failed with error %v", err)
+	}
+	if got, want := tbl.Bindings(), []string{"?s", "?l", "?i"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.Unwind returned bindings %v; want %v", got, want)
+	}
+	var got []string
+	for _, r := range tbl.Rows() {
+		got = append(got, *r["?s"].S+"="+r["?i"].L.String())
+	}
+	want := []string{`1s="a"^^type:text`, `1s="b"^^type:text`, `3s="c"^^type:text`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tbl.Unwind returned rows %v; want %v", got, want)
+	}
+}
+
 func TestFilter(t *testing.T) {
 	table := func() *Table {
 		return &Table{
@@ -1578,6 +2529,105 @@ func TestLeftOptionalJoin(t *testing.T) {
 	}
 }
 
+func TestLeftOptionalJoinWithPolicy(t *testing.T) {
+	cell := func(s string) *Cell {
+		if s == "" {
+			return &Cell{}
+		}
+		return &Cell{S: CellString(s)}
+	}
+	newTable := func(bs []string, rs ...[]string) *Table {
+		tbl, err := New(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rs {
+			row := Row{}
+			for i, b := range bs {
+				row[b] = cell(r[i])
+			}
+			tbl.AddRow(row)
+		}
+		return tbl
+	}
+	left := func() *Table {
+		return newTable([]string{"?s", "?t"},
+			[]string{"1s", "1t"},
+			[]string{"2s", ""},
+			[]string{"3s", "3t"},
+		)
+	}
+	right := func() *Table {
+		return newTable([]string{"?s", "?t", "?x"},
+			// Collides with the first left row.
+			[]string{"1s", "2t", "1x"},
+			// Fills the empty cell of the second left row.
+			[]string{"2s", "9t", "2x"},
+			// Fully agrees with the third left row.
+			[]string{"3s", "3t", "3x"},
+			// Not related to any left row.
+			[]string{"4s", "4t", "4x"},
+		)
+	}
+	bs := []string{"?s", "?t", "?x"}
+	entries := []struct {
+		p       MergePolicy
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			p: KeepLeft,
+			want: [][]string{
+				{"1s", "1t", ""},
+				{"2s", "9t", "2x"},
+				{"3s", "3t", "3x"},
+			},
+		},
+		{
+			p: KeepRight,
+			want: [][]string{
+				{"1s", "2t", "1x"},
+				{"2s", "9t", "2x"},
+				{"3s", "3t", "3x"},
+			},
+		},
+		{
+			p:       ErrorOnConflict,
+			wantErr: true,
+		},
+	}
+	for _, entry := range entries {
+		tbl := left()
+		err := tbl.LeftOptionalJoinWithPolicy(right(), entry.p)
+		if entry.wantErr {
+			if err == nil {
+				t.Errorf("LeftOptionalJoinWithPolicy(_, %d) should have failed; got\n%s", entry.p, tbl)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LeftOptionalJoinWithPolicy(_, %d) failed with error %v", entry.p, err)
+			continue
+		}
+		if got, want := tbl.Rows(), newTable(bs, entry.want...).Rows(); !reflect.DeepEqual(got, want) {
+			t.Errorf("LeftOptionalJoinWithPolicy(_, %d) returned\n%s\nwant\n%s", entry.p, tbl, newTable(bs, entry.want...))
+		}
+	}
+
+	// Without collisions nor empty cells all the policies behave the same.
+	for _, p := range []MergePolicy{KeepLeft, KeepRight, ErrorOnConflict} {
+		tbl := newTable([]string{"?s", "?t"}, []string{"3s", "3t"}, []string{"5s", "5t"})
+		if err := tbl.LeftOptionalJoinWithPolicy(right(), p); err != nil {
+			t.Errorf("LeftOptionalJoinWithPolicy(_, %d) failed with error %v", p, err)
+			continue
+		}
+		want := newTable(bs, []string{"3s", "3t", "3x"}, []string{"5s", "5t", ""})
+		if got := tbl.Rows(); !reflect.DeepEqual(got, want.Rows()) {
+			t.Errorf("LeftOptionalJoinWithPolicy(_, %d) returned\n%s\nwant\n%s", p, tbl, want)
+		}
+	}
+}
+
 func TestExtendRow(t *testing.T) {
 	r := Row{}
 	er := Row{"?foo": &Cell{}, "?bar": &Cell{}}
@@ -1603,3 +2653,192 @@ func TestExtendRowWith(t *testing.T) {
 		t.Errorf("failed to extend a fully binded row; got %v, want %v", got, want)
 	}
 }
+
+func TestIntervalTruncate(t *testing.T) {
+	ts, err := time.Parse(time.RFC3339, "2016-03-17T13:47:12-08:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		in   string
+		want string
+	}{
+		{"15m", "2016-03-17T21:45:00Z"},
+		{"1h", "2016-03-17T21:00:00Z"},
+		{" 2h ", "2016-03-17T20:00:00Z"},
+		{"1d", "2016-03-17T00:00:00Z"},
+		{"7d", "2016-03-17T00:00:00Z"},
+		{"1mo", "2016-03-01T00:00:00Z"},
+		{"2mo", "2016-03-01T00:00:00Z"},
+		{"12mo", "2016-01-01T00:00:00Z"},
+		{"5mo", "2015-11-01T00:00:00Z"},
+		{"7mo", "2016-02-01T00:00:00Z"},
+	}
+	for _, entry := range table {
+		i, err := ParseInterval(entry.in)
+		if err != nil {
+			t.Errorf("ParseInterval(%q) failed with error %v", entry.in, err)
+			continue
+		}
+		if got := i.Truncate(ts).Format(time.RFC3339); got != entry.want {
+			t.Errorf("ParseInterval(%q).Truncate(%v) = %s; want %s", entry.in, ts, got, entry.want)
+		}
+	}
+	for _, in := range []string{"", "1x", "mo", "0d", "-1mo", "0s", "-1h", "one day"} {
+		if i, err := ParseInterval(in); err == nil {
+			t.Errorf("ParseInterval(%q) should have failed; got %v", in, i)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	newTable := func() *Table {
+		tbl, err := New([]string{"?s", "?t"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range [][]string{
+			{"b", "2016-03-17T13:47:12Z"},
+			{"a", "2016-01-02T00:00:00Z"},
+			{"a", "2016-01-30T10:00:00Z"},
+			{"a", "2016-04-01T00:00:00Z"},
+			{"b", ""},
+		} {
+			s := v[0]
+			r := Row{"?s": &Cell{S: &s}, "?t": &Cell{}}
+			if v[1] != "" {
+				ts, err := time.Parse(time.RFC3339, v[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				r["?t"] = NewTimeCell(ts)
+			}
+			tbl.AddRow(r)
+		}
+		return tbl
+	}
+	iv, err := ParseInterval("1mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		groups []string
+		zeros  bool
+		want   []string
+	}{
+		{
+			groups: []string{"?s"},
+			want:   []string{"b 2016-03-01T00:00:00Z 1", "a 2016-01-01T00:00:00Z 2", "a 2016-04-01T00:00:00Z 1"},
+		},
+		{
+			groups: []string{"?s"},
+			zeros:  true,
+			want: []string{
+				"b 2016-03-01T00:00:00Z 1",
+				"a 2016-01-01T00:00:00Z 2", "a 2016-02-01T00:00:00Z 0", "a 2016-03-01T00:00:00Z 0", "a 2016-04-01T00:00:00Z 1",
+			},
+		},
+		{
+			want: []string{"2016-01-01T00:00:00Z 2", "2016-03-01T00:00:00Z 1", "2016-04-01T00:00:00Z 1"},
+		},
+	}
+	for _, entry := range table {
+		tbl := newTable()
+		if err := tbl.Histogram(entry.groups, "?t", iv, "?start", "?n", entry.zeros); err != nil {
+			t.Fatalf("table.Histogram(%v, zeros=%v) failed with error %v", entry.groups, entry.zeros, err)
+		}
+		if got, want := tbl.Bindings(), append(append([]string{}, entry.groups...), "?start", "?n"); !reflect.DeepEqual(got, want) {
+			t.Errorf("table.Histogram(%v, zeros=%v) returned bindings %v; want %v", entry.groups, entry.zeros, got, want)
+		}
+		var got []string
+		for _, r := range tbl.Rows() {
+			var vs []string
+			for _, g := range entry.groups {
+				vs = append(vs, r[g].String())
+			}
+			n, err := r["?n"].L.Int64()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, strings.Join(append(vs, r["?start"].T.Format(time.RFC3339), fmt.Sprint(n)), " "))
+		}
+		if !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("table.Histogram(%v, zeros=%v) returned %v; want %v", entry.groups, entry.zeros, got, entry.want)
+		}
+	}
+	if err := newTable().Histogram(nil, "?s", iv, "?start", "?n", false); err == nil {
+		t.Errorf("table.Histogram should have failed to count values that are not time anchors")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	newTable := func(bs []string, vs ...[]string) *Table {
+		tbl, err := New(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range vs {
+			r := Row{}
+			for i, b := range bs {
+				s := v[i]
+				r[b] = &Cell{S: &s}
+			}
+			tbl.AddRow(r)
+		}
+		return tbl
+	}
+	a := newTable([]string{"?a", "?b"}, []string{"1", "2"}, []string{"3", "4"})
+	b := newTable([]string{"?b", "?a"}, []string{"4", "3"}, []string{"2", "1"})
+	if got, want := a.Fingerprint(), b.Fingerprint(); got != want {
+		t.Errorf("unordered fingerprints should ignore row and binding order; got %q, want %q", got, want)
+	}
+	if a.FingerprintWithOrder(true) == b.FingerprintWithOrder(true) {
+		t.Errorf("ordered fingerprints should take row and binding order into account")
+	}
+	c := newTable([]string{"?a", "?b"}, []string{"1", "2"}, []string{"3", "5"})
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Errorf("fingerprints of tables with different contents should differ")
+	}
+	d := newTable([]string{"?a", "?b"}, []string{"12", ""}, []string{"3", "4"})
+	e := newTable([]string{"?a", "?b"}, []string{"1", "2"}, []string{"3", "4"})
+	if d.Fingerprint() == e.Fingerprint() {
+		t.Errorf("fingerprints should not be fooled by ambiguous concatenations")
+	}
+
+	// Sorting the table makes the fingerprint order sensitive.
+	s1 := newTable([]string{"?a", "?b"}, []string{"3", "4"}, []string{"1", "2"})
+	s2 := newTable([]string{"?a", "?b"}, []string{"1", "2"}, []string{"3", "4"})
+	s1.Sort(SortConfig{{Binding: "?a"}})
+	if got := s1.Fingerprint(); got != s1.FingerprintWithOrder(true) {
+		t.Errorf("sorted table fingerprint should be order sensitive")
+	}
+	if got, want := s1.Fingerprint(), s2.FingerprintWithOrder(true); got != want {
+		t.Errorf("sorted fingerprints should match the ordered fingerprint of equal rows; got %q, want %q", got, want)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	p := NewProvenance()
+	a, b, c := NewStringCell("a"), NewStringCell("b"), NewStringCell("c")
+	p.Record(Row{"?a": a, "?b": b}, 0)
+	p.Record(Row{"?a": a, "?b": b, "?c": c}, 1)
+	for _, entry := range []struct {
+		c    *Cell
+		want int
+	}{
+		{a, 0},
+		{b, 0},
+		{c, 1},
+	} {
+		if got, ok := p.Clause(entry.c); !ok || got != entry.want {
+			t.Errorf("Provenance.Clause(%v) = %d, %v; want %d, true", entry.c, got, ok, entry.want)
+		}
+	}
+	if _, ok := p.Clause(NewStringCell("a")); ok {
+		t.Errorf("Provenance.Clause should not know copies of recorded cells")
+	}
+	r := Row{"?c": c, "?a": a, "?d": NewStringCell("d")}
+	if got, want := p.Describe(r), "?a from clause 0, ?c from clause 1, ?d from unknown clause"; got != want {
+		t.Errorf("Provenance.Describe(%v) = %q; want %q", r, got, want)
+	}
+}
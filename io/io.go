@@ -17,6 +17,8 @@ package io
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -28,12 +30,35 @@ import (
 	"github.com/google/badwolf/triple/literal"
 )
 
+// gzipMagic are the first bytes of any gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader that transparently decompresses the provided
+// reader if it contains a gzip stream. Otherwise, the returned reader provides
+// the same data as the original one.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	hdr, err := br.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(hdr, gzipMagic) {
+		// Short or empty inputs cannot be compressed; let the callers deal
+		// with them as plain text.
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
 // ReadIntoGraph reads a graph out of the provided reader. The data on the
 // reader is interpret as text. Each line represents one triple using the
 // standard serialized format. ReadIntoGraph will stop if fails to Parse
 // a triple on the stream. The triples read till then would have also been
 // added to the graph. The int value returns the number of triples added.
+// Gzip compressed data, as the one produced by WriteGraphCompressed, is
+// detected and decompressed transparently.
 func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder) (int, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return 0, err
+	}
 	cnt, scanner := 0, bufio.NewScanner(r)
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
@@ -48,7 +73,61 @@ func ReadIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.
 		cnt++
 		g.AddTriples(ctx, []*triple.Triple{t})
 	}
-	return cnt, nil
+	return cnt, scanner.Err()
+}
+
+// LineError describes a line of the input that could not be parsed into a
+// triple.
+type LineError struct {
+	// Line is the 1-based number of the offending line.
+	Line int
+	// Text is the trimmed content of the offending line.
+	Text string
+	// Err is the error returned while parsing the line.
+	Err error
+}
+
+// Error returns the description of the line error.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the error returned while parsing the line.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ReadIntoGraphLenient works like ReadIntoGraph, but lines that fail to parse
+// do not stop the import. Instead, they are skipped and reported as line
+// errors, in the order they were found. The int value returns the number of
+// triples added. The error is only returned if the reader or the graph fail,
+// in which case the triples read till then would have been added. As for
+// ReadIntoGraph, gzip compressed data is decompressed transparently.
+func ReadIntoGraphLenient(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder) (int, []*LineError, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var lErrs []*LineError
+	cnt, line, scanner := 0, 0, bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		t, err := triple.Parse(text, b)
+		if err != nil {
+			lErrs = append(lErrs, &LineError{Line: line, Text: text, Err: err})
+			continue
+		}
+		if err := g.AddTriples(ctx, []*triple.Triple{t}); err != nil {
+			return cnt, lErrs, err
+		}
+		cnt++
+	}
+	return cnt, lErrs, scanner.Err()
 }
 
 // WriteGraph serializes the graph into the writer where each triple is
@@ -86,3 +165,18 @@ func WriteGraph(ctx context.Context, w io.Writer, g storage.Graph) (int, error)
 	}
 	return cnt, nil
 }
+
+// WriteGraphCompressed works like WriteGraph, but the serialization is gzip
+// compressed. The compressed stream is completed before returning, so it can
+// be read back by ReadIntoGraph.
+func WriteGraphCompressed(ctx context.Context, w io.Writer, g storage.Graph) (int, error) {
+	zw := gzip.NewWriter(w)
+	cnt, err := WriteGraph(ctx, zw, g)
+	if err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
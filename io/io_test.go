@@ -18,12 +18,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/storage/memory"
 	"github.com/google/badwolf/triple"
 	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
 )
 
 func getTestTriples(t *testing.T) []*triple.Triple {
@@ -55,6 +59,156 @@ func TestReadIntoGraph(t *testing.T) {
 	}
 }
 
+func TestReadIntoGraphWithCustomTypes(t *testing.T) {
+	b, err := literal.NewBuilderWithTypes(&literal.TypeDefinition{
+		Name: "upper",
+		Parse: func(s string) (interface{}, error) {
+			return strings.ToUpper(s), nil
+		},
+		Format: func(v interface{}) string {
+			return v.(string)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	g, err := memory.DefaultStore.NewGraph(ctx, "?custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memory.DefaultStore.DeleteGraph(ctx, "?custom")
+	src := "/u<john>\t\"name\"@[]\t\"john\"^^type:upper\n"
+	if cnt, err := ReadIntoGraph(ctx, g, strings.NewReader(src), b); err != nil || cnt != 1 {
+		t.Fatalf("io.ReadIntoGraph returned %d, %v; want 1 triple", cnt, err)
+	}
+	var buffer bytes.Buffer
+	if _, err := WriteGraph(ctx, &buffer, g); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buffer.String(), "/u<john>\t\"name\"@[]\t\"JOHN\"^^type:upper\n"; got != want {
+		t.Errorf("io.WriteGraph returned %q; want %q", got, want)
+	}
+	// Without registering the custom type the literal cannot be read.
+	if _, err := ReadIntoGraph(ctx, g, strings.NewReader(src), literal.DefaultBuilder()); err == nil {
+		t.Errorf("io.ReadIntoGraph should have failed to read an unknown literal type")
+	}
+}
+
+func TestReadIntoGraphScientificFloats(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "?floats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := "/u<a>\t\"value\"@[]\t\"1.5e10\"^^type:float64\n" +
+		"/u<b>\t\"value\"@[]\t\"-2.5E-3\"^^type:float64\n"
+	if cnt, err := ReadIntoGraph(ctx, g, strings.NewReader(src), literal.DefaultBuilder()); err != nil || cnt != 2 {
+		t.Fatalf("io.ReadIntoGraph returned %d, %v; want 2 triples", cnt, err)
+	}
+	var buffer bytes.Buffer
+	if _, err := WriteGraph(ctx, &buffer, g); err != nil {
+		t.Fatal(err)
+	}
+	// The written triples should be readable again and yield the same graph.
+	rg, err := memory.NewStore().NewGraph(ctx, "?floats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := ReadIntoGraph(ctx, rg, strings.NewReader(buffer.String()), literal.DefaultBuilder()); err != nil || cnt != 2 {
+		t.Fatalf("io.ReadIntoGraph(%q) returned %d, %v; want 2 triples", buffer.String(), cnt, err)
+	}
+	for _, s := range strings.Split(strings.TrimSpace(src), "\n") {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := rg.Exist(ctx, trpl); err != nil || !ok {
+			t.Errorf("round tripped graph should contain %s; got %v, %v", trpl, ok, err)
+		}
+	}
+}
+
+func TestLanguageTagsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "?lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := "/u<mary>\t\"greets\"@[]\t\"hola\"^^type:text@es\n" +
+		"/u<mary>\t\"greets\"@[]\t\"hola\"^^type:text\n" +
+		"/u<mary>\t\"greets\"@[]\t\"hola@es\"^^type:text\n"
+	if cnt, err := ReadIntoGraph(ctx, g, strings.NewReader(src), literal.DefaultBuilder()); err != nil || cnt != 3 {
+		t.Fatalf("io.ReadIntoGraph returned %d, %v; want 3 triples", cnt, err)
+	}
+	var buffer bytes.Buffer
+	if cnt, err := WriteGraph(ctx, &buffer, g); err != nil || cnt != 3 {
+		t.Fatalf("io.WriteGraph returned %d, %v; want 3 triples", cnt, err)
+	}
+	// The written triples should keep their language tags.
+	rg, err := memory.NewStore().NewGraph(ctx, "?lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := ReadIntoGraph(ctx, rg, strings.NewReader(buffer.String()), literal.DefaultBuilder()); err != nil || cnt != 3 {
+		t.Fatalf("io.ReadIntoGraph(%q) returned %d, %v; want 3 triples", buffer.String(), cnt, err)
+	}
+	for _, s := range strings.Split(strings.TrimSpace(src), "\n") {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := rg.Exist(ctx, trpl); err != nil || !ok {
+			t.Errorf("round tripped graph should contain %s; got %v, %v", trpl, ok, err)
+		}
+	}
+}
+
+func TestReadIntoGraphLenient(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "?dirty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := "/u<john>\t\"knows\"@[]\t/u<mary>\n" +
+		"/u<john>\t\"knows\"\t/u<peter>\n" +
+		"\n" +
+		"/u<mary>\t\"age\"@[]\t\"forty\"^^type:int64\n" +
+		"/u<mary>\t\"knows\"@[]\t/u<alice>\n" +
+		"not a triple\n"
+	cnt, lErrs, err := ReadIntoGraphLenient(ctx, g, strings.NewReader(src), literal.DefaultBuilder())
+	if err != nil || cnt != 2 {
+		t.Fatalf("io.ReadIntoGraphLenient returned %d, _, %v; want 2 triples", cnt, err)
+	}
+	var lines []int
+	for _, lErr := range lErrs {
+		if lErr.Err == nil || lErr.Text == "" {
+			t.Errorf("io.ReadIntoGraphLenient returned incomplete line error %#v", lErr)
+		}
+		lines = append(lines, lErr.Line)
+	}
+	if got, want := fmt.Sprint(lines), "[2 4 6]"; got != want {
+		t.Errorf("io.ReadIntoGraphLenient reported errors on lines %s; want %s", got, want)
+	}
+	for _, s := range []string{"/u<john>\t\"knows\"@[]\t/u<mary>", "/u<mary>\t\"knows\"@[]\t/u<alice>"} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+			t.Errorf("graph should contain %s; got %v, %v", trpl, ok, err)
+		}
+	}
+	// The strict version stops on the first invalid line.
+	sg, err := memory.NewStore().NewGraph(ctx, "?dirty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt, err := ReadIntoGraph(ctx, sg, strings.NewReader(src), literal.DefaultBuilder()); err == nil || cnt != 1 {
+		t.Errorf("io.ReadIntoGraph returned %d, %v; want 1 triple and an error", cnt, err)
+	}
+}
+
 func TestWriteIntoGraph(t *testing.T) {
 	var buffer bytes.Buffer
 	ts, ctx := getTestTriples(t), context.Background()
@@ -135,3 +289,132 @@ func TestSerializationContents(t *testing.T) {
 		t.Errorf("Failed to unmarshal marshaled the right number of triples, %d != %d != 6", gs, gos)
 	}
 }
+
+func TestEscapedNodeIDsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	p, err := predicate.NewImmutable("knows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ts []*triple.Triple
+	ids := []string{"Fire <Escape>", "a>b", `C:\dir\`, `quote> "knows"@[] /u<x`, `\<\>`}
+	for i, id := range ids {
+		s, err := node.NewNodeFromStrings("/u", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o, err := node.NewNodeFromStrings("/u", ids[(i+1)%len(ids)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		trpl, err := triple.New(s, p, triple.NewNodeObject(o))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, trpl)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	var buffer bytes.Buffer
+	if _, err := WriteGraph(ctx, &buffer, g); err != nil {
+		t.Fatalf("io.WriteGraph failed with error %v", err)
+	}
+	g2, err := memory.NewStore().NewGraph(ctx, "test2")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	cnt, err := ReadIntoGraph(ctx, g2, &buffer, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("io.ReadIntoGraph failed with error %v", err)
+	}
+	if cnt != len(ts) {
+		t.Errorf("io.ReadIntoGraph read %d triples; want %d", cnt, len(ts))
+	}
+	for _, trpl := range ts {
+		ok, err := g2.Exist(ctx, trpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("triple %s did not round trip through io.WriteGraph and io.ReadIntoGraph", trpl)
+		}
+	}
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	if err := g.AddTriples(ctx, getTestTriples(t)); err != nil {
+		t.Fatalf("g.AddTriples failed with error %v", err)
+	}
+	testTable := []struct {
+		name  string
+		write func(context.Context, io.Writer, storage.Graph) (int, error)
+		read  func(context.Context, storage.Graph, io.Reader, literal.Builder) (int, error)
+	}{
+		{
+			name:  "text",
+			write: WriteGraphCompressed,
+			read:  ReadIntoGraph,
+		},
+		{
+			name: "jsonl",
+			write: func(ctx context.Context, w io.Writer, g storage.Graph) (int, error) {
+				return WriteGraphAsJSONLCompressed(ctx, g, w)
+			},
+			read: ReadJSONLIntoGraph,
+		},
+	}
+	for _, entry := range testTable {
+		var buffer bytes.Buffer
+		cnt, err := entry.write(ctx, &buffer, g)
+		if err != nil {
+			t.Fatalf("%s: writing the compressed graph failed with error %v", entry.name, err)
+		}
+		if cnt != 6 {
+			t.Errorf("%s: wrote %d triples; want 6", entry.name, cnt)
+		}
+		if bs := buffer.Bytes(); len(bs) < 2 || bs[0] != 0x1f || bs[1] != 0x8b {
+			t.Errorf("%s: the written data is not gzip compressed", entry.name)
+		}
+		g2, err := memory.NewStore().NewGraph(ctx, "test2")
+		if err != nil {
+			t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+		}
+		cnt2, err := entry.read(ctx, g2, &buffer, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("%s: reading the compressed graph failed with error %v", entry.name, err)
+		}
+		if cnt2 != 6 {
+			t.Errorf("%s: read %d triples; want 6", entry.name, cnt2)
+		}
+		want, got := graphUUIDs(ctx, t, g), graphUUIDs(ctx, t, g2)
+		if len(got) != len(want) {
+			t.Errorf("%s: round tripped graph has %d triples; want %d", entry.name, len(got), len(want))
+		}
+		for id := range want {
+			if !got[id] {
+				t.Errorf("%s: round tripped graph is missing triple with UUID %s", entry.name, id)
+			}
+		}
+	}
+}
+
+func TestReadIntoGraphCorruptedCompression(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	if _, err := ReadIntoGraph(ctx, g, bytes.NewReader([]byte{0x1f, 0x8b, 0x00}), literal.DefaultBuilder()); err == nil {
+		t.Errorf("io.ReadIntoGraph should have failed to read a corrupted gzip stream")
+	}
+}
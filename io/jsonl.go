@@ -0,0 +1,244 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// jsonNode is the JSON encoding of a node.
+type jsonNode struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonPredicate is the JSON encoding of a predicate. Immutable predicates have
+// no anchor; temporal ones carry it formatted as RFC3339Nano.
+type jsonPredicate struct {
+	ID     string `json:"id"`
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// jsonObject is the JSON encoding of an object. Exactly one of its fields is
+// set. Literals are encoded using their text representation, which also
+// carries their type.
+type jsonObject struct {
+	Node      *jsonNode      `json:"node,omitempty"`
+	Predicate *jsonPredicate `json:"predicate,omitempty"`
+	Literal   *string        `json:"literal,omitempty"`
+}
+
+// jsonTriple is the JSON encoding of a triple.
+type jsonTriple struct {
+	Subject   jsonNode      `json:"subject"`
+	Predicate jsonPredicate `json:"predicate"`
+	Object    jsonObject    `json:"object"`
+}
+
+func nodeToJSON(n *node.Node) jsonNode {
+	return jsonNode{Type: n.Type().String(), ID: n.ID().String()}
+}
+
+func predicateToJSON(p *predicate.Predicate) (jsonPredicate, error) {
+	jp := jsonPredicate{ID: string(p.ID())}
+	if p.Type() == predicate.Temporal {
+		ta, err := p.TimeAnchor()
+		if err != nil {
+			return jp, err
+		}
+		jp.Anchor = ta.Format(time.RFC3339Nano)
+	}
+	return jp, nil
+}
+
+// tripleToJSON returns the JSON encoding of the provided triple.
+func tripleToJSON(t *triple.Triple) (*jsonTriple, error) {
+	jp, err := predicateToJSON(t.Predicate())
+	if err != nil {
+		return nil, err
+	}
+	jt := &jsonTriple{
+		Subject:   nodeToJSON(t.Subject()),
+		Predicate: jp,
+	}
+	o := t.Object()
+	if n, err := o.Node(); err == nil {
+		jn := nodeToJSON(n)
+		jt.Object.Node = &jn
+		return jt, nil
+	}
+	if p, err := o.Predicate(); err == nil {
+		jp, err := predicateToJSON(p)
+		if err != nil {
+			return nil, err
+		}
+		jt.Object.Predicate = &jp
+		return jt, nil
+	}
+	if l, err := o.Literal(); err == nil {
+		s := l.String()
+		jt.Object.Literal = &s
+		return jt, nil
+	}
+	return nil, fmt.Errorf("unknown object type in triple %s", t)
+}
+
+func nodeFromJSON(jn jsonNode) (*node.Node, error) {
+	return node.NewNodeFromStrings(jn.Type, jn.ID)
+}
+
+func predicateFromJSON(jp jsonPredicate) (*predicate.Predicate, error) {
+	if jp.Anchor == "" {
+		return predicate.NewImmutable(jp.ID)
+	}
+	ta, err := predicate.ParseTimeAnchor(jp.Anchor, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anchor %q for predicate %q: %v", jp.Anchor, jp.ID, err)
+	}
+	return predicate.NewTemporal(jp.ID, ta)
+}
+
+// toTriple returns the triple encoded by the JSON triple.
+func (jt *jsonTriple) toTriple(b literal.Builder) (*triple.Triple, error) {
+	s, err := nodeFromJSON(jt.Subject)
+	if err != nil {
+		return nil, err
+	}
+	p, err := predicateFromJSON(jt.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	var o *triple.Object
+	switch jo := jt.Object; {
+	case jo.Node != nil && jo.Predicate == nil && jo.Literal == nil:
+		n, err := nodeFromJSON(*jo.Node)
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewNodeObject(n)
+	case jo.Predicate != nil && jo.Node == nil && jo.Literal == nil:
+		op, err := predicateFromJSON(*jo.Predicate)
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewPredicateObject(op)
+	case jo.Literal != nil && jo.Node == nil && jo.Predicate == nil:
+		l, err := b.Parse(*jo.Literal)
+		if err != nil {
+			return nil, err
+		}
+		o = triple.NewLiteralObject(l)
+	default:
+		return nil, fmt.Errorf("objects must set exactly one of node, predicate or literal")
+	}
+	return triple.New(s, p, o)
+}
+
+// WriteGraphAsJSONL serializes the graph into the writer as JSON lines, one
+// JSON object per triple. The triples are streamed from the graph as they are
+// written. If there is an error writing, the serialization will stop. It
+// returns the number of triples serialized.
+func WriteGraphAsJSONL(ctx context.Context, g storage.Graph, w io.Writer) (int, error) {
+	var (
+		wg   sync.WaitGroup
+		tErr error
+		wErr error
+	)
+	cnt, ts, enc := 0, make(chan *triple.Triple), json.NewEncoder(w)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tErr = g.Triples(ctx, storage.DefaultLookup, ts)
+	}()
+	for t := range ts {
+		if wErr != nil {
+			continue
+		}
+		jt, err := tripleToJSON(t)
+		if err != nil {
+			wErr = err
+			continue
+		}
+		if err := enc.Encode(jt); err != nil {
+			wErr = err
+			continue
+		}
+		cnt++
+	}
+	wg.Wait()
+	if tErr != nil {
+		return 0, tErr
+	}
+	if wErr != nil {
+		return 0, wErr
+	}
+	return cnt, nil
+}
+
+// WriteGraphAsJSONLCompressed works like WriteGraphAsJSONL, but the JSON lines
+// are gzip compressed. The compressed stream is completed before returning.
+func WriteGraphAsJSONLCompressed(ctx context.Context, g storage.Graph, w io.Writer) (int, error) {
+	zw := gzip.NewWriter(w)
+	cnt, err := WriteGraphAsJSONL(ctx, g, zw)
+	if err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+// ReadJSONLIntoGraph reads a graph out of the provided reader containing the
+// JSON lines produced by WriteGraphAsJSONL. It will stop if it fails to decode
+// a triple on the stream. The triples read till then would have also been
+// added to the graph. The int value returns the number of triples added.
+// Gzip compressed data, as the one produced by WriteGraphAsJSONLCompressed,
+// is detected and decompressed transparently.
+func ReadJSONLIntoGraph(ctx context.Context, g storage.Graph, r io.Reader, b literal.Builder) (int, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return 0, err
+	}
+	cnt, dec := 0, json.NewDecoder(r)
+	for {
+		jt := &jsonTriple{}
+		if err := dec.Decode(jt); err == io.EOF {
+			return cnt, nil
+		} else if err != nil {
+			return cnt, fmt.Errorf("failed to decode triple %d: %v", cnt+1, err)
+		}
+		t, err := jt.toTriple(b)
+		if err != nil {
+			return cnt, fmt.Errorf("failed to decode triple %d: %v", cnt+1, err)
+		}
+		if err := g.AddTriples(ctx, []*triple.Triple{t}); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+)
+
+// plannerTriples mirrors the fixtures used by the planner tests.
+const plannerTriples = `/u<joe> "parent_of"@[] /u<mary>
+/u<joe> "parent_of"@[] /u<peter>
+/u<peter> "parent_of"@[] /u<john>
+/u<peter> "parent_of"@[] /u<eve>
+/u<peter> "bought"@[2016-01-01T00:00:00-08:00] /c<mini>
+/u<peter> "bought"@[2016-02-01T00:00:00-08:00] /c<model s>
+/u<peter> "bought"@[2016-03-01T00:00:00-08:00] /c<model x>
+/u<peter> "bought"@[2016-04-01T00:00:00-08:00] /c<model y>
+/u<paul> "bought"@[2016-01-01T00:00:00-08:00] /c<model n>
+/u<paul> "bought"@[2016-04-01T00:00:00-08:00] /c<model r>
+/c<mini> "is_a"@[] /t<car>
+/c<model s> "is_a"@[] /t<car>
+/c<model x> "is_a"@[] /t<car>
+/c<model y> "is_a"@[] /t<car>
+/l<barcelona> "predicate"@[] "turned"@[2016-01-01T00:00:00-08:00]
+/l<barcelona> "predicate"@[] "turned"@[2016-02-01T00:00:00-08:00]
+/l<barcelona> "predicate"@[] "turned"@[2016-03-01T00:00:00-08:00]
+/l<barcelona> "predicate"@[] "turned"@[2016-04-01T00:00:00-08:00]
+/l<barcelona> "predicate"@[] "immutable_predicate"@[]
+/l<paris> "predicate"@[] "turned"@[2016-04-01T00:00:00-08:00]
+/u<alice> "height_cm"@[] "174"^^type:int64
+/u<alice> "tag"@[] "abc"^^type:text
+/u<bob> "height_cm"@[] "151"^^type:int64
+/u<charlie> "height_cm"@[] "174"^^type:int64
+/u<delta> "height_cm"@[] "174"^^type:int64
+/room<Hallway> "connects_to"@[] /room<Kitchen>
+/room<Kitchen> "connects_to"@[] /room<Hallway>
+/room<Kitchen> "connects_to"@[] /room<Bathroom>
+/room<Kitchen> "connects_to"@[] /room<Bedroom>
+/room<Bathroom> "connects_to"@[] /room<Kitchen>
+/room<Bedroom> "connects_to"@[] /room<Kitchen>
+/room<Bedroom> "connects_to"@[] /room<Fire Escape>
+/room<Fire Escape> "connects_to"@[] /room<Kitchen>
+/item/book<000> "in"@[2016-04-10T4:21:00.000000000Z] /room<Hallway>
+/item/book<000> "in"@[2016-04-10T4:23:00.000000000Z] /room<Kitchen>
+/item/book<000> "in"@[2016-04-10T4:25:00.000000000Z] /room<Bedroom>
+/u<mary> "greets"@[] "hola"^^type:text@es
+/u<mary> "tags"@[] "["a"^^type:text, "1"^^type:int64]"^^type:list
+/u<mary> "mass"@[] "1.5e10"^^type:float64
+`
+
+func graphUUIDs(ctx context.Context, t *testing.T, g storage.Graph) map[string]bool {
+	m := make(map[string]bool)
+	ts := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Errorf("g.Triples failed to retrieve triples with error %v", err)
+		}
+	}()
+	for trpl := range ts {
+		m[trpl.UUID().String()] = true
+	}
+	return m
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	g, err := memory.NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	n, err := ReadIntoGraph(ctx, g, strings.NewReader(plannerTriples), literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("io.ReadIntoGraph failed with error %v", err)
+	}
+
+	var buffer bytes.Buffer
+	cnt, err := WriteGraphAsJSONL(ctx, g, &buffer)
+	if err != nil {
+		t.Fatalf("io.WriteGraphAsJSONL failed with error %v", err)
+	}
+	if cnt != n {
+		t.Errorf("io.WriteGraphAsJSONL wrote %d triples; want %d", cnt, n)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != n {
+		t.Errorf("io.WriteGraphAsJSONL wrote %d lines; want %d", len(lines), n)
+	}
+	for _, l := range lines {
+		if !json.Valid([]byte(l)) {
+			t.Errorf("io.WriteGraphAsJSONL wrote invalid JSON line %q", l)
+		}
+	}
+
+	g2, err := memory.NewStore().NewGraph(ctx, "test2")
+	if err != nil {
+		t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+	}
+	cnt2, err := ReadJSONLIntoGraph(ctx, g2, &buffer, literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("io.ReadJSONLIntoGraph failed with error %v", err)
+	}
+	if cnt2 != n {
+		t.Errorf("io.ReadJSONLIntoGraph read %d triples; want %d", cnt2, n)
+	}
+	want, got := graphUUIDs(ctx, t, g), graphUUIDs(ctx, t, g2)
+	if len(got) != len(want) {
+		t.Errorf("round tripped graph has %d triples; want %d", len(got), len(want))
+	}
+	for id := range want {
+		if !got[id] {
+			t.Errorf("round tripped graph is missing triple with UUID %s", id)
+		}
+	}
+}
+
+func TestJSONLEncoding(t *testing.T) {
+	table := []struct {
+		trpl string
+		want string
+	}{
+		{
+			trpl: `/u<joe> "parent_of"@[] /u<mary>`,
+			want: `{"subject":{"type":"/u","id":"joe"},"predicate":{"id":"parent_of"},"object":{"node":{"type":"/u","id":"mary"}}}`,
+		},
+		{
+			trpl: `/u<peter> "bought"@[2016-01-01T00:00:00.5-08:00] /c<mini>`,
+			want: `{"subject":{"type":"/u","id":"peter"},"predicate":{"id":"bought","anchor":"2016-01-01T00:00:00.5-08:00"},"object":{"node":{"type":"/c","id":"mini"}}}`,
+		},
+		{
+			trpl: `/l<paris> "predicate"@[] "turned"@[2016-04-01T00:00:00Z]`,
+			want: `{"subject":{"type":"/l","id":"paris"},"predicate":{"id":"predicate"},"object":{"predicate":{"id":"turned","anchor":"2016-04-01T00:00:00Z"}}}`,
+		},
+		{
+			trpl: `/u<alice> "height_cm"@[] "174"^^type:int64`,
+			want: `{"subject":{"type":"/u","id":"alice"},"predicate":{"id":"height_cm"},"object":{"literal":"\"174\"^^type:int64"}}`,
+		},
+	}
+	for _, entry := range table {
+		trpl, err := triple.Parse(entry.trpl, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", entry.trpl, err)
+		}
+		jt, err := tripleToJSON(trpl)
+		if err != nil {
+			t.Fatalf("tripleToJSON(%s) failed with error %v", trpl, err)
+		}
+		bs, err := json.Marshal(jt)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) failed with error %v", jt, err)
+		}
+		if got := string(bs); got != entry.want {
+			t.Errorf("tripleToJSON(%s) = %s; want %s", trpl, got, entry.want)
+		}
+	}
+}
+
+func TestReadJSONLIntoGraphErrors(t *testing.T) {
+	table := []string{
+		`{"subject":{"type":"/u","id":"joe"},"predicate":{"id":"p"},"object":{}}`,
+		`{"subject":{"type":"/u","id":"joe"},"predicate":{"id":"p"},"object":{"node":{"type":"/u","id":"a"},"literal":"\"1\"^^type:int64"}}`,
+		`{"subject":{"type":"/u","id":"joe"},"predicate":{"id":"p","anchor":"yesterday"},"object":{"node":{"type":"/u","id":"a"}}}`,
+		`{"subject":{"type":"u","id":"joe"},"predicate":{"id":"p"},"object":{"node":{"type":"/u","id":"a"}}}`,
+		`{"subject":{"type":"/u","id":"joe"},"predicate":{"id":"p"},"object":{"literal":"1"}}`,
+		`{"subject":`,
+	}
+	ctx := context.Background()
+	for _, entry := range table {
+		g, err := memory.NewStore().NewGraph(ctx, "test")
+		if err != nil {
+			t.Fatalf("memory.NewStore().NewGraph should have never failed to create a graph")
+		}
+		if _, err := ReadJSONLIntoGraph(ctx, g, strings.NewReader(entry), literal.DefaultBuilder()); err == nil {
+			t.Errorf("io.ReadJSONLIntoGraph(%q) should have failed", entry)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/triple"
@@ -92,11 +93,66 @@ func (s *storeMemoizer) DeleteGraph(ctx context.Context, id string) error {
 	return s.s.DeleteGraph(ctx, id)
 }
 
+// Clear deletes all the graphs available in the store, if the memoized store
+// supports it.
+func (s *storeMemoizer) Clear(ctx context.Context) error {
+	c, ok := s.s.(storage.Clearer)
+	if !ok {
+		return fmt.Errorf("store %q does not support clearing all its graphs", s.s.Name(ctx))
+	}
+	return c.Clear(ctx)
+}
+
+// metadata returns the metadata store being memoized, if it supports metadata.
+func (s *storeMemoizer) metadata(ctx context.Context) (storage.MetadataStore, error) {
+	m, ok := s.s.(storage.MetadataStore)
+	if !ok {
+		return nil, fmt.Errorf("store %q does not support metadata", s.s.Name(ctx))
+	}
+	return m, nil
+}
+
+// SetMetadata stores the value under the provided key, if the memoized store
+// supports metadata.
+func (s *storeMemoizer) SetMetadata(ctx context.Context, key string, v interface{}) error {
+	m, err := s.metadata(ctx)
+	if err != nil {
+		return err
+	}
+	return m.SetMetadata(ctx, key, v)
+}
+
+// Metadata returns the value stored under the provided key, if the memoized
+// store supports metadata.
+func (s *storeMemoizer) Metadata(ctx context.Context, key string) (interface{}, error) {
+	m, err := s.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Metadata(ctx, key)
+}
+
+// DeleteMetadata deletes the value stored under the provided key, if the
+// memoized store supports metadata.
+func (s *storeMemoizer) DeleteMetadata(ctx context.Context, key string) error {
+	m, err := s.metadata(ctx)
+	if err != nil {
+		return err
+	}
+	return m.DeleteMetadata(ctx, key)
+}
+
 // GraphNames returns the current available graph names in the store.
 func (s *storeMemoizer) GraphNames(ctx context.Context, names chan<- string) error {
 	return s.s.GraphNames(ctx, names)
 }
 
+// GraphNamesSorted returns the current available graph names in the store in
+// lexical order.
+func (s *storeMemoizer) GraphNamesSorted(ctx context.Context, names chan<- string) error {
+	return storage.GraphNamesSorted(ctx, s.s, names)
+}
+
 // graphMemoizer memoizers partial query results.
 type graphMemoizer struct {
 	g storage.Graph
@@ -129,6 +185,36 @@ func (g *graphMemoizer) AddTriples(ctx context.Context, ts []*triple.Triple) err
 	return g.g.AddTriples(ctx, ts)
 }
 
+// AddTriplesCount adds the triples to the storage and returns how many of them
+// were not already present in the wrapped graph.
+func (g *graphMemoizer) AddTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error) {
+	g.mu.Lock()
+	// Update operations reset the memoization.
+	g.memN = make(map[string][]*node.Node)
+	g.memP = make(map[string][]*predicate.Predicate)
+	g.memO = make(map[string][]*triple.Object)
+	g.memT = make(map[string][]*triple.Triple)
+	g.memE = make(map[string]bool)
+	g.mu.Unlock()
+
+	return storage.AddTriples(ctx, g.g, ts)
+}
+
+// AddTriplesIfAbsent adds the triples not already present in the wrapped graph
+// and returns how many of them were added.
+func (g *graphMemoizer) AddTriplesIfAbsent(ctx context.Context, ts []*triple.Triple) (int, error) {
+	g.mu.Lock()
+	// Update operations reset the memoization.
+	g.memN = make(map[string][]*node.Node)
+	g.memP = make(map[string][]*predicate.Predicate)
+	g.memO = make(map[string][]*triple.Object)
+	g.memT = make(map[string][]*triple.Triple)
+	g.memE = make(map[string]bool)
+	g.mu.Unlock()
+
+	return storage.AddTriplesIfAbsent(ctx, g.g, ts)
+}
+
 // RemoveTriples removes the triples from the storage. Removing triples that
 // are not present on the store should not fail.
 func (g *graphMemoizer) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
@@ -144,6 +230,51 @@ func (g *graphMemoizer) RemoveTriples(ctx context.Context, ts []*triple.Triple)
 	return g.g.RemoveTriples(ctx, ts)
 }
 
+// RemoveTriplesCount removes the triples from the storage and returns how many
+// of them were present in the wrapped graph.
+func (g *graphMemoizer) RemoveTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error) {
+	g.mu.Lock()
+	// Update operations reset the memoization.
+	g.memN = make(map[string][]*node.Node)
+	g.memP = make(map[string][]*predicate.Predicate)
+	g.memO = make(map[string][]*triple.Object)
+	g.memT = make(map[string][]*triple.Triple)
+	g.memE = make(map[string]bool)
+	g.mu.Unlock()
+
+	return storage.RemoveTriples(ctx, g.g, ts)
+}
+
+// ReplaceObject replaces the objects of the provided subject and predicate ID
+// in the wrapped graph and returns the triples removed.
+func (g *graphMemoizer) ReplaceObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) ([]*triple.Triple, error) {
+	g.mu.Lock()
+	// Update operations reset the memoization.
+	g.memN = make(map[string][]*node.Node)
+	g.memP = make(map[string][]*predicate.Predicate)
+	g.memO = make(map[string][]*triple.Object)
+	g.memT = make(map[string][]*triple.Triple)
+	g.memE = make(map[string]bool)
+	g.mu.Unlock()
+
+	return storage.ReplaceObject(ctx, g.g, s, p, o)
+}
+
+// RemoveAllTriples removes all the triples from the storage, leaving the
+// graph in place.
+func (g *graphMemoizer) RemoveAllTriples(ctx context.Context) error {
+	g.mu.Lock()
+	// Update operations reset the memoization.
+	g.memN = make(map[string][]*node.Node)
+	g.memP = make(map[string][]*predicate.Predicate)
+	g.memO = make(map[string][]*triple.Object)
+	g.memT = make(map[string][]*triple.Triple)
+	g.memE = make(map[string]bool)
+	g.mu.Unlock()
+
+	return g.g.RemoveAllTriples(ctx)
+}
+
 func combinedUUID(op string, lo *storage.LookupOptions, uuids ...uuid.UUID) string {
 	var ss []string
 	for _, id := range uuids {
@@ -782,6 +913,36 @@ func (g *graphMemoizer) Exist(ctx context.Context, t *triple.Triple) (bool, erro
 	return b, err
 }
 
+// TriplesForSubjectPredicateObject returns the stored triple for the provided
+// subject, predicate, and object, or nil if it does not exist.
+func (g *graphMemoizer) TriplesForSubjectPredicateObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) (*triple.Triple, error) {
+	k := combinedUUID("TriplesForSubjectPredicateObject", storage.DefaultLookup, s.UUID(), p.UUID(), o.UUID())
+	g.mu.RLock()
+	v, ok := g.memT[k]
+	g.mu.RUnlock()
+	if ok {
+		// Return the memoized results.
+		if len(v) == 0 {
+			return nil, nil
+		}
+		return v[0], nil
+	}
+
+	// Query and memoize the results.
+	t, err := g.g.TriplesForSubjectPredicateObject(ctx, s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	mts := []*triple.Triple{}
+	if t != nil {
+		mts = append(mts, t)
+	}
+	g.mu.Lock()
+	g.memT[k] = mts
+	g.mu.Unlock()
+	return t, nil
+}
+
 // Triples pushes to the provided channel all available triples in the graph.
 // The function does not return immediately but spawns a goroutine to satisfy
 // elements in the channel.
@@ -834,3 +995,27 @@ func (g *graphMemoizer) Triples(ctx context.Context, lo *storage.LookupOptions,
 	g.mu.Unlock()
 	return err
 }
+
+// Version returns the version of the wrapped graph. Memoization does not
+// alter the contents of the graph, hence it does not alter its version.
+func (g *graphMemoizer) Version(ctx context.Context) (uint64, error) {
+	return g.g.Version(ctx)
+}
+
+// Walk walks the wrapped graph. Walks are not memoized since they already
+// visit the whole graph.
+func (g *graphMemoizer) Walk(ctx context.Context, fn storage.WalkFunc) error {
+	return storage.Walk(ctx, g.g, fn)
+}
+
+// TriplesForSubjects retrieves the triples of the provided subjects from the
+// wrapped graph. Batched lookups are not memoized.
+func (g *graphMemoizer) TriplesForSubjects(ctx context.Context, subjects []*node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	return storage.TriplesForSubjects(ctx, g.g, subjects, lo, trpls)
+}
+
+// PredicateAnchors retrieves the time anchors of the provided subject and
+// predicate ID from the wrapped graph. Anchor lookups are not memoized.
+func (g *graphMemoizer) PredicateAnchors(ctx context.Context, s *node.Node, pid string, lo *storage.LookupOptions, anchors chan<- *time.Time) error {
+	return storage.PredicateAnchors(ctx, g.g, s, pid, lo, anchors)
+}
@@ -130,6 +130,22 @@ func TestStoreWrapper(t *testing.T) {
 	if want := []string(nil); !reflect.DeepEqual(got, want) {
 		t.Errorf("failed to retrieve the right graph IDs; got %q, want %q", got, want)
 	}
+
+	if _, err := sm.NewGraph(ctx, "?other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c = make(chan string)
+	got = nil
+	go sm.GraphNames(ctx, c)
+	for s := range c {
+		got = append(got, s)
+	}
+	if want := []string(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("failed to clear the graphs; got %q, want %q", got, want)
+	}
 }
 
 func TestTripleDelition(t *testing.T) {
@@ -145,6 +161,42 @@ func TestTripleDelition(t *testing.T) {
 	}
 }
 
+func TestRemoveAllTriples(t *testing.T) {
+	ctx, sm := buildtMemoizedStore(t)
+
+	g, err := sm.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	triples := func() []*triple.Triple {
+		trps := make(chan *triple.Triple)
+		go func() {
+			if err := g.Triples(ctx, storage.DefaultLookup, trps); err != nil {
+				t.Error(err)
+			}
+		}()
+		var ts []*triple.Triple
+		for t := range trps {
+			ts = append(ts, t)
+		}
+		return ts
+	}
+
+	// Populate the memoization before removing the triples.
+	if len(triples()) == 0 {
+		t.Fatal("the memoized graph should not be empty")
+	}
+	if err := g.RemoveAllTriples(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := triples(); len(got) != 0 {
+		t.Errorf("memoized graph should be empty after RemoveAllTriples; got %v", got)
+	}
+	if _, err := sm.Graph(ctx, "?test"); err != nil {
+		t.Errorf("graph should still exist after RemoveAllTriples; got error %v", err)
+	}
+}
+
 func TestObjects(t *testing.T) {
 	ctx, sm := buildtMemoizedStore(t)
 	ts := buildTriples(t)
@@ -490,6 +542,35 @@ func TestExist(t *testing.T) {
 	}
 }
 
+func TestTriplesForSubjectPredicateObject(t *testing.T) {
+	ctx, sm := buildtMemoizedStore(t)
+	ts := buildTriples(t)
+
+	g, err := sm.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Query the fist round.
+	lookup := func() *triple.Triple {
+		trpl, err := g.TriplesForSubjectPredicateObject(ctx, ts[0].Subject(), ts[0].Predicate(), ts[0].Object())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trpl
+	}
+
+	ot := lookup()
+	if ot == nil {
+		t.Fatalf("g.TriplesForSubjectPredicateObject failed to return triple %s", ts[0])
+	}
+	for i, max := 0, 100; i < max; i++ {
+		if got, want := lookup(), ot; !reflect.DeepEqual(got, want) {
+			t.Fatalf("failed to returned the right triple; got %v, want %v", got, want)
+		}
+	}
+}
+
 func TestTriples(t *testing.T) {
 	ctx, sm := buildtMemoizedStore(t)
 
@@ -520,3 +601,36 @@ func TestTriples(t *testing.T) {
 		}
 	}
 }
+
+func TestWalk(t *testing.T) {
+	ctx, sm := buildtMemoizedStore(t)
+
+	g, err := sm.Graph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(storage.Walker); !ok {
+		t.Fatalf("memoized graphs should implement storage.Walker")
+	}
+	n := 0
+	err = storage.Walk(ctx, g, func(s *node.Node, ts []*triple.Triple) error {
+		n += len(ts)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trps := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, trps); err != nil {
+			t.Error(err)
+		}
+	}()
+	want := 0
+	for range trps {
+		want++
+	}
+	if n != want {
+		t.Errorf("storage.Walk visited %d triples; want %d", n, want)
+	}
+}
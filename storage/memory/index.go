@@ -0,0 +1,181 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+)
+
+// IndexField identifies the keys a secondary index is built on.
+//
+// Secondary indexes use the same keys as the built-in predicate-object and
+// subject-predicate indexes, so they do not serve any lookup the built-in
+// indexes cannot. Indexes on other values, such as literal values or node
+// types, are not supported.
+type IndexField int
+
+// List of the fields secondary indexes can be built on.
+const (
+	// PredicateObject indexes the triples by predicate ID and object. It
+	// serves Subjects lookups.
+	PredicateObject IndexField = iota + 1
+	// SubjectPredicate indexes the triples by subject and predicate ID. It
+	// serves Objects lookups.
+	SubjectPredicate
+)
+
+// String returns a readable version of the index field.
+func (f IndexField) String() string {
+	switch f {
+	case PredicateObject:
+		return "predicate object"
+	case SubjectPredicate:
+		return "subject predicate"
+	default:
+		return fmt.Sprintf("unknown index field %d", int(f))
+	}
+}
+
+// Indexer is implemented by the stores returned by this package. It lets
+// callers that know their access patterns keep the results of their most
+// frequent lookups sorted.
+type Indexer interface {
+	// CreateIndex builds a secondary index on the provided field for the
+	// provided graph. The index is kept up to date as triples are added and
+	// removed. Creating an index that already exists does nothing.
+	CreateIndex(graph string, field IndexField) error
+}
+
+// CreateIndex builds a secondary index on the provided field for the provided
+// graph.
+//
+// The built-in indexes find the triples of a lookup in constant time, but
+// every lookup renders and sorts them before returning them. A secondary index
+// finds the same triples but keeps each of its entries sorted instead, so
+// lookups with many results stream them directly at the cost of a second copy
+// of the keys. Lookups using filters or LatestAnchor,
+// and graphs preserving the insertion order, do not use secondary indexes.
+func (s *memoryStore) CreateIndex(graph string, field IndexField) error {
+	if field != PredicateObject && field != SubjectPredicate {
+		return fmt.Errorf("memory.CreateIndex(%q, %v): unsupported index field", graph, field)
+	}
+	g, err := s.Graph(context.Background(), graph)
+	if err != nil {
+		return err
+	}
+	m := g.(*memory)
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	if _, ok := m.secondary[field]; ok {
+		return nil
+	}
+	idx := make(map[string][]indexEntry)
+	for _, t := range m.idx {
+		k := indexKey(field, t)
+		idx[k] = append(idx[k], indexEntry{s: t.String(), t: t})
+	}
+	for _, es := range idx {
+		sort.Slice(es, func(i, j int) bool {
+			return es[i].s < es[j].s
+		})
+	}
+	if m.secondary == nil {
+		m.secondary = make(map[IndexField]map[string][]indexEntry)
+	}
+	m.secondary[field] = idx
+	return nil
+}
+
+// indexEntry holds a triple of a secondary index along with its text
+// representation, which determines its position.
+type indexEntry struct {
+	s string
+	t *triple.Triple
+}
+
+// indexKey returns the key of the provided triple in the secondary index of the
+// provided field. Keys match the ones of the built-in indexes serving the same
+// lookups.
+func indexKey(f IndexField, t *triple.Triple) string {
+	pUUID := UUIDToByteString(t.Predicate().PartialUUID())
+	if f == PredicateObject {
+		return pUUID + UUIDToByteString(t.Object().UUID())
+	}
+	return UUIDToByteString(t.Subject().UUID()) + pUUID
+}
+
+// unsafeIndexTriple adds a triple not yet present in the graph to the
+// secondary indexes. This call bypasses the lock.
+func (m *memory) unsafeIndexTriple(t *triple.Triple) {
+	if len(m.secondary) == 0 {
+		return
+	}
+	e := indexEntry{s: t.String(), t: t}
+	for f, idx := range m.secondary {
+		k := indexKey(f, t)
+		es := idx[k]
+		i := sort.Search(len(es), func(i int) bool {
+			return es[i].s >= e.s
+		})
+		es = append(es, indexEntry{})
+		copy(es[i+1:], es[i:])
+		es[i] = e
+		idx[k] = es
+	}
+}
+
+// unsafeUnindexTriple removes a triple present in the graph from the
+// secondary indexes. This call bypasses the lock.
+func (m *memory) unsafeUnindexTriple(t *triple.Triple) {
+	if len(m.secondary) == 0 {
+		return
+	}
+	s := t.String()
+	for f, idx := range m.secondary {
+		k := indexKey(f, t)
+		es := idx[k]
+		i := sort.Search(len(es), func(i int) bool {
+			return es[i].s >= s
+		})
+		if i == len(es) || es[i].s != s {
+			continue
+		}
+		es = append(es[:i], es[i+1:]...)
+		if len(es) == 0 {
+			delete(idx, k)
+		} else {
+			idx[k] = es
+		}
+	}
+}
+
+// indexed returns the sorted triples of the provided key in the secondary
+// index of the provided field, and whether the lookup can be served from it.
+// The graph must be locked by the caller.
+func (m *memory) indexed(f IndexField, key string, lo *storage.LookupOptions) ([]indexEntry, bool) {
+	if m.seq != nil || lo.LatestAnchor || lo.FilterOptions != nil {
+		return nil, false
+	}
+	idx, ok := m.secondary[f]
+	if !ok {
+		return nil, false
+	}
+	return idx[key], true
+}
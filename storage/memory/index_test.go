@@ -0,0 +1,214 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/triple"
+	"github.com/google/badwolf/triple/literal"
+	"github.com/google/badwolf/triple/node"
+	"github.com/google/badwolf/triple/predicate"
+)
+
+// lookupStrings returns the text of the objects of s and p, followed by the
+// text of the subjects of p and o.
+func lookupStrings(ctx context.Context, t *testing.T, g storage.Graph, s *node.Node, p *predicate.Predicate, o *triple.Object, lo *storage.LookupOptions) []string {
+	t.Helper()
+	var res []string
+	objs := make(chan *triple.Object, 100)
+	if err := g.Objects(ctx, s, p, lo, objs); err != nil {
+		t.Fatalf("g.Objects failed with error %v", err)
+	}
+	for o := range objs {
+		res = append(res, o.String())
+	}
+	subjs := make(chan *node.Node, 100)
+	if err := g.Subjects(ctx, p, o, lo, subjs); err != nil {
+		t.Fatalf("g.Subjects failed with error %v", err)
+	}
+	for s := range subjs {
+		res = append(res, s.String())
+	}
+	return res
+}
+
+func TestCreateIndex(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<john>\t\"meet\"@[2012-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"meet\"@[2013-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"meet\"@[2014-04-10T04:21:00Z]\t/u<mary>",
+		"/u<john>\t\"meet\"@[2014-04-10T04:21:00Z]\t/u<bob>",
+		"/u<paul>\t\"meet\"@[2013-04-10T04:21:00Z]\t/u<mary>",
+		"/u<alice>\t\"meet\"@[2013-04-10T04:21:00Z]\t/u<mary>",
+	})
+	plain, indexed := NewStore(), NewStore()
+	gp, err := plain.NewGraph(ctx, "?g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi, err := indexed.NewGraph(ctx, "?g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Index some triples before creating the indexes and some after.
+	for _, g := range []storage.Graph{gp, gi} {
+		if err := g.AddTriples(ctx, ts[:3]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []IndexField{PredicateObject, SubjectPredicate, PredicateObject} {
+		if err := indexed.(Indexer).CreateIndex("?g", f); err != nil {
+			t.Fatalf("memoryStore.CreateIndex(%v) failed with error %v", f, err)
+		}
+	}
+	for _, g := range []storage.Graph{gp, gi} {
+		if err := g.AddTriples(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.RemoveTriples(ctx, ts[1:2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := node.Parse("/u<mary>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("meet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := predicate.NewTemporal("meet", time.Date(2013, 4, 10, 4, 21, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	testTable := []struct {
+		p  *predicate.Predicate
+		lo *storage.LookupOptions
+	}{
+		{p: p, lo: storage.DefaultLookup},
+		{p: pt, lo: storage.DefaultLookup},
+		{p: p, lo: &storage.LookupOptions{LowerAnchor: &lower}},
+		{p: p, lo: &storage.LookupOptions{MaxElements: 1}},
+		{p: p, lo: &storage.LookupOptions{MaxElements: 1, Offset: 1}},
+		{p: p, lo: &storage.LookupOptions{LatestAnchor: true}},
+	}
+	for _, entry := range testTable {
+		want := lookupStrings(ctx, t, gp, s, entry.p, triple.NewNodeObject(o), entry.lo)
+		got := lookupStrings(ctx, t, gi, s, entry.p, triple.NewNodeObject(o), entry.lo)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("indexed lookups for %v with options %v returned %v; want %v", entry.p, entry.lo, got, want)
+		}
+	}
+
+	for _, g := range []storage.Graph{gp, gi} {
+		if err := g.RemoveAllTriples(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddTriples(ctx, ts[4:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := lookupStrings(ctx, t, gp, s, p, triple.NewNodeObject(o), storage.DefaultLookup)
+	got := lookupStrings(ctx, t, gi, s, p, triple.NewNodeObject(o), storage.DefaultLookup)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("indexed lookups after removing all triples returned %v; want %v", got, want)
+	}
+}
+
+func TestCreateIndexErrors(t *testing.T) {
+	s := NewStore()
+	if _, err := s.NewGraph(context.Background(), "?g"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.(Indexer).CreateIndex("?unknown", PredicateObject); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("memoryStore.CreateIndex on a missing graph returned error %v; want %v", err, storage.ErrGraphNotFound)
+	}
+	if err := s.(Indexer).CreateIndex("?g", IndexField(0)); err == nil {
+		t.Errorf("memoryStore.CreateIndex should have rejected an unknown index field")
+	}
+}
+
+// benchmarkSubjectsByValue looks up the subjects of a literal value shared by
+// many of the triples of the graph.
+func benchmarkSubjectsByValue(b *testing.B, index bool) {
+	ctx := context.Background()
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "?g")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := predicate.NewImmutable("status")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var ts []*triple.Triple
+	for i := 0; i < 10000; i++ {
+		sn, err := node.NewNodeFromStrings("/u", fmt.Sprintf("user%d", i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		l, err := literal.DefaultBuilder().Parse(fmt.Sprintf(`"status%d"^^type:text`, i%2))
+		if err != nil {
+			b.Fatal(err)
+		}
+		t, err := triple.New(sn, p, triple.NewLiteralObject(l))
+		if err != nil {
+			b.Fatal(err)
+		}
+		ts = append(ts, t)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		b.Fatal(err)
+	}
+	if index {
+		if err := s.(Indexer).CreateIndex("?g", PredicateObject); err != nil {
+			b.Fatal(err)
+		}
+	}
+	o := ts[0].Object()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		subjs := make(chan *node.Node, 100)
+		go func() {
+			if err := g.Subjects(ctx, p, o, storage.DefaultLookup, subjs); err != nil {
+				b.Error(err)
+			}
+		}()
+		for range subjs {
+		}
+	}
+}
+
+func BenchmarkSubjectsByValue(b *testing.B) {
+	benchmarkSubjectsByValue(b, false)
+}
+
+func BenchmarkSubjectsByValueIndexed(b *testing.B) {
+	benchmarkSubjectsByValue(b, true)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/badwolf/bql/planner/filter"
@@ -39,16 +40,88 @@ func init() {
 	DefaultStore = NewStore()
 }
 
+// lastVersion holds the last version assigned to a graph. It is shared by all
+// graphs so a graph recreated with the same ID never reuses a version.
+var lastVersion uint64
+
+func nextVersion() uint64 {
+	return atomic.AddUint64(&lastVersion, 1)
+}
+
 type memoryStore struct {
 	graphs map[string]storage.Graph
+	meta   map[string]interface{}
 	rwmu   sync.RWMutex
+	opts   Options
+	limit  *capacity // Shared by all the graphs of the store; nil if unlimited.
+}
+
+// capacity tracks the number of triples held by all the graphs of a store
+// against its limit.
+type capacity struct {
+	mu    sync.Mutex
+	max   int
+	count int
+}
+
+// reserve accounts for n new triples, failing if they do not fit.
+func (c *capacity) reserve(n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.count+n > c.max {
+		return fmt.Errorf("adding %d triples to a store holding %d of at most %d: %w", n, c.count, c.max, storage.ErrCapacityExceeded)
+	}
+	c.count += n
+	return nil
+}
+
+// release accounts for n triples no longer held by the store.
+func (c *capacity) release(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count -= n
+}
+
+// Options contains the configuration of the graphs created by a memory store.
+type Options struct {
+	// PreserveInsertionOrder makes the graphs return triples in the order they
+	// were first added, instead of sorted by their text representation. Lookups
+	// still honor offsets and limits, so results are stable across runs and
+	// consistent with the order the data was loaded. Each graph keeps an extra
+	// index entry per triple (its UUID and a sequence number, about 40 bytes
+	// plus map overhead), and every lookup sorts its results by it.
+	PreserveInsertionOrder bool
+
+	// MaxTriples caps the total number of triples held by all the graphs of
+	// the store. Additions that would exceed it fail as a whole with an error
+	// wrapping storage.ErrCapacityExceeded. Zero or negative values mean no
+	// limit.
+	MaxTriples int
 }
 
 // NewStore creates a new memory store.
 func NewStore() storage.Store {
-	return &memoryStore{
+	return NewStoreWithOptions(Options{})
+}
+
+// NewStoreWithOptions creates a new memory store whose graphs are configured
+// using the provided options.
+func NewStoreWithOptions(opts Options) storage.Store {
+	s := &memoryStore{
 		graphs: make(map[string]storage.Graph),
+		meta:   make(map[string]interface{}),
+		opts:   opts,
 	}
+	if opts.MaxTriples > 0 {
+		s.limit = &capacity{max: opts.MaxTriples}
+	}
+	return s
+}
+
+// NewStoreWithLimits creates a new memory store holding at most maxTriples
+// triples across all its graphs.
+func NewStoreWithLimits(maxTriples int) storage.Store {
+	return NewStoreWithOptions(Options{MaxTriples: maxTriples})
 }
 
 // Name returns the ID of the backend being used.
@@ -63,21 +136,29 @@ func (s *memoryStore) Version(ctx context.Context) string {
 
 // NewGraph creates a new graph.
 func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, error) {
+	if id == "" {
+		return nil, fmt.Errorf("memory.NewGraph(%q): %w", id, storage.ErrGraphEmpty)
+	}
 	g := &memory{
-		id:    id,
-		idx:   make(map[string]*triple.Triple, initialAllocation),
-		idxS:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxP:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxO:  make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxSP: make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxPO: make(map[string]map[string]*triple.Triple, initialAllocation),
-		idxSO: make(map[string]map[string]*triple.Triple, initialAllocation),
+		id:      id,
+		version: nextVersion(),
+		limit:   s.limit,
+		idx:     make(map[string]*triple.Triple, initialAllocation),
+		idxS:    make(map[string]map[string]*triple.Triple, initialAllocation),
+		idxP:    make(map[string]map[string]*triple.Triple, initialAllocation),
+		idxO:    make(map[string]map[string]*triple.Triple, initialAllocation),
+		idxSP:   make(map[string]map[string]*triple.Triple, initialAllocation),
+		idxPO:   make(map[string]map[string]*triple.Triple, initialAllocation),
+		idxSO:   make(map[string]map[string]*triple.Triple, initialAllocation),
+	}
+	if s.opts.PreserveInsertionOrder {
+		g.seq = make(map[string]uint64, initialAllocation)
 	}
 
 	s.rwmu.Lock()
 	defer s.rwmu.Unlock()
 	if _, ok := s.graphs[id]; ok {
-		return nil, fmt.Errorf("memory.NewGraph(%q): graph already exists", id)
+		return nil, fmt.Errorf("memory.NewGraph(%q): %w", id, storage.ErrGraphExists)
 	}
 	s.graphs[id] = g
 	return g, nil
@@ -86,24 +167,91 @@ func (s *memoryStore) NewGraph(ctx context.Context, id string) (storage.Graph, e
 // Graph returns an existing graph if available. Getting a non existing
 // graph should return an error.
 func (s *memoryStore) Graph(ctx context.Context, id string) (storage.Graph, error) {
+	if id == "" {
+		return nil, fmt.Errorf("memory.Graph(%q): %w", id, storage.ErrGraphEmpty)
+	}
 	s.rwmu.RLock()
 	defer s.rwmu.RUnlock()
 	if g, ok := s.graphs[id]; ok {
 		return g, nil
 	}
-	return nil, fmt.Errorf("memory.Graph(%q): graph does not exist", id)
+	return nil, fmt.Errorf("memory.Graph(%q): %w", id, storage.ErrGraphNotFound)
 }
 
 // DeleteGraph deletes an existing graph. Deleting a non existing graph
 // should return an error.
 func (s *memoryStore) DeleteGraph(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("memory.DeleteGraph(%q): %w", id, storage.ErrGraphEmpty)
+	}
 	s.rwmu.Lock()
 	defer s.rwmu.Unlock()
-	if _, ok := s.graphs[id]; ok {
+	if g, ok := s.graphs[id]; ok {
 		delete(s.graphs, id)
+		detach(g)
 		return nil
 	}
-	return fmt.Errorf("memory.DeleteGraph(%q): graph does not exist", id)
+	return fmt.Errorf("memory.DeleteGraph(%q): %w", id, storage.ErrGraphNotFound)
+}
+
+// Clear atomically deletes all the graphs available in the store. Graphs
+// already retrieved by readers remain usable, but are no longer reachable
+// from the store.
+func (s *memoryStore) Clear(ctx context.Context) error {
+	s.rwmu.Lock()
+	for _, g := range s.graphs {
+		detach(g)
+	}
+	s.graphs = make(map[string]storage.Graph)
+	s.rwmu.Unlock()
+	return nil
+}
+
+// detach releases the triples of a graph no longer reachable from its store
+// from the store capacity. The graph remains usable, but no longer counts
+// against it.
+func detach(g storage.Graph) {
+	m, ok := g.(*memory)
+	if !ok {
+		return
+	}
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	if m.limit != nil {
+		m.limit.release(len(m.idx))
+		m.limit = nil
+	}
+}
+
+// SetMetadata stores the value under the provided key, replacing the value
+// previously stored under it, if any. Clearing the store keeps its metadata.
+func (s *memoryStore) SetMetadata(ctx context.Context, key string, v interface{}) error {
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	s.meta[key] = v
+	return nil
+}
+
+// Metadata returns the value stored under the provided key.
+func (s *memoryStore) Metadata(ctx context.Context, key string) (interface{}, error) {
+	s.rwmu.RLock()
+	defer s.rwmu.RUnlock()
+	v, ok := s.meta[key]
+	if !ok {
+		return nil, fmt.Errorf("memory.Metadata(%q): %w", key, storage.ErrMetadataNotFound)
+	}
+	return v, nil
+}
+
+// DeleteMetadata deletes the value stored under the provided key.
+func (s *memoryStore) DeleteMetadata(ctx context.Context, key string) error {
+	s.rwmu.Lock()
+	defer s.rwmu.Unlock()
+	if _, ok := s.meta[key]; !ok {
+		return fmt.Errorf("memory.DeleteMetadata(%q): %w", key, storage.ErrMetadataNotFound)
+	}
+	delete(s.meta, key)
+	return nil
 }
 
 // GraphNames returns the current available graph names in the store.
@@ -112,9 +260,33 @@ func (s *memoryStore) GraphNames(ctx context.Context, names chan<- string) error
 		return fmt.Errorf("cannot provide an empty channel")
 	}
 	s.rwmu.RLock()
-	defer s.rwmu.RUnlock()
+	ns := make([]string, 0, len(s.graphs))
 	for k := range s.graphs {
-		names <- k
+		ns = append(ns, k)
+	}
+	s.rwmu.RUnlock()
+	for _, n := range ns {
+		names <- n
+	}
+	close(names)
+	return nil
+}
+
+// GraphNamesSorted returns the current available graph names in the store in
+// lexical order.
+func (s *memoryStore) GraphNamesSorted(ctx context.Context, names chan<- string) error {
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	s.rwmu.RLock()
+	ns := make([]string, 0, len(s.graphs))
+	for k := range s.graphs {
+		ns = append(ns, k)
+	}
+	s.rwmu.RUnlock()
+	sort.Strings(ns)
+	for _, n := range ns {
+		names <- n
 	}
 	close(names)
 	return nil
@@ -122,15 +294,22 @@ func (s *memoryStore) GraphNames(ctx context.Context, names chan<- string) error
 
 // memory provides an memory-based volatile implementation of the graph API.
 type memory struct {
-	id    string
-	rwmu  sync.RWMutex
-	idx   map[string]*triple.Triple
-	idxS  map[string]map[string]*triple.Triple
-	idxP  map[string]map[string]*triple.Triple
-	idxO  map[string]map[string]*triple.Triple
-	idxSP map[string]map[string]*triple.Triple
-	idxPO map[string]map[string]*triple.Triple
-	idxSO map[string]map[string]*triple.Triple
+	id      string
+	version uint64
+	rwmu    sync.RWMutex
+	idx     map[string]*triple.Triple
+	idxS    map[string]map[string]*triple.Triple
+	idxP    map[string]map[string]*triple.Triple
+	idxO    map[string]map[string]*triple.Triple
+	idxSP   map[string]map[string]*triple.Triple
+	idxPO   map[string]map[string]*triple.Triple
+	idxSO   map[string]map[string]*triple.Triple
+	seq     map[string]uint64 // Insertion sequence of each triple; nil if the order is not preserved.
+	lastSeq uint64
+	limit   *capacity // Capacity of the store the graph belongs to; nil if unlimited.
+
+	// secondary holds the secondary indexes built by CreateIndex.
+	secondary map[IndexField]map[string][]indexEntry
 }
 
 // ID returns the id for this graph.
@@ -138,87 +317,262 @@ func (m *memory) ID(ctx context.Context) string {
 	return m.id
 }
 
+// Version returns the current version of the graph.
+func (m *memory) Version(ctx context.Context) (uint64, error) {
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return m.version, nil
+}
+
 // AddTriples adds the triples to the storage.
 func (m *memory) AddTriples(ctx context.Context, ts []*triple.Triple) error {
+	_, err := m.AddTriplesCount(ctx, ts)
+	return err
+}
+
+// AddTriplesCount adds the triples to the storage and returns how many of them
+// were not already present.
+func (m *memory) AddTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error) {
 	m.rwmu.Lock()
 	defer m.rwmu.Unlock()
+	if err := m.unsafeReserve(ts); err != nil {
+		return 0, err
+	}
+	m.version = nextVersion()
+	cnt := 0
 	for _, t := range ts {
-		tuuid := UUIDToByteString(t.UUID())
-		sUUID := UUIDToByteString(t.Subject().UUID())
-		pUUID := UUIDToByteString(t.Predicate().PartialUUID())
-		oUUID := UUIDToByteString(t.Object().UUID())
-		// Update master index
-		m.idx[tuuid] = t
-
-		if _, ok := m.idxS[sUUID]; !ok {
-			m.idxS[sUUID] = make(map[string]*triple.Triple)
+		if _, ok := m.idx[UUIDToByteString(t.UUID())]; !ok {
+			cnt++
 		}
-		m.idxS[sUUID][tuuid] = t
+		m.unsafeAddTriple(t)
+	}
+	return cnt, nil
+}
 
-		if _, ok := m.idxP[pUUID]; !ok {
-			m.idxP[pUUID] = make(map[string]*triple.Triple)
+// AddTriplesIfAbsent adds the triples not already present in the storage, and
+// returns how many of them were added. Existence is checked under the same
+// write lock used to add them, so the count is exact.
+func (m *memory) AddTriplesIfAbsent(ctx context.Context, ts []*triple.Triple) (int, error) {
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	if err := m.unsafeReserve(ts); err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for _, t := range ts {
+		if _, ok := m.idx[UUIDToByteString(t.UUID())]; ok {
+			continue
 		}
-		m.idxP[pUUID][tuuid] = t
+		m.unsafeAddTriple(t)
+		cnt++
+	}
+	if cnt > 0 {
+		m.version = nextVersion()
+	}
+	return cnt, nil
+}
 
-		if _, ok := m.idxO[oUUID]; !ok {
-			m.idxO[oUUID] = make(map[string]*triple.Triple)
+// unsafeReserve accounts for the triples not yet present in the graph against
+// the store capacity, failing if they do not fit. This call bypasses the lock.
+func (m *memory) unsafeReserve(ts []*triple.Triple) error {
+	if m.limit == nil {
+		return nil
+	}
+	added := make(map[string]bool)
+	for _, t := range ts {
+		tuuid := UUIDToByteString(t.UUID())
+		if _, ok := m.idx[tuuid]; !ok {
+			added[tuuid] = true
 		}
-		m.idxO[oUUID][tuuid] = t
+	}
+	return m.limit.reserve(len(added))
+}
 
-		key := sUUID + pUUID
-		if _, ok := m.idxSP[key]; !ok {
-			m.idxSP[key] = make(map[string]*triple.Triple)
+// unsafeAddTriple adds the triple to all the indices. This call bypasses the
+// lock.
+func (m *memory) unsafeAddTriple(t *triple.Triple) {
+	tuuid := UUIDToByteString(t.UUID())
+	sUUID := UUIDToByteString(t.Subject().UUID())
+	pUUID := UUIDToByteString(t.Predicate().PartialUUID())
+	oUUID := UUIDToByteString(t.Object().UUID())
+	// Update master index
+	if _, ok := m.idx[tuuid]; !ok {
+		if m.seq != nil {
+			m.lastSeq++
+			m.seq[tuuid] = m.lastSeq
 		}
-		m.idxSP[key][tuuid] = t
+		m.unsafeIndexTriple(t)
+	}
+	m.idx[tuuid] = t
 
-		key = pUUID + oUUID
-		if _, ok := m.idxPO[key]; !ok {
-			m.idxPO[key] = make(map[string]*triple.Triple)
-		}
-		m.idxPO[key][tuuid] = t
+	if _, ok := m.idxS[sUUID]; !ok {
+		m.idxS[sUUID] = make(map[string]*triple.Triple)
+	}
+	m.idxS[sUUID][tuuid] = t
 
-		key = sUUID + oUUID
-		if _, ok := m.idxSO[key]; !ok {
-			m.idxSO[key] = make(map[string]*triple.Triple)
-		}
-		m.idxSO[key][tuuid] = t
+	if _, ok := m.idxP[pUUID]; !ok {
+		m.idxP[pUUID] = make(map[string]*triple.Triple)
 	}
-	return nil
+	m.idxP[pUUID][tuuid] = t
+
+	if _, ok := m.idxO[oUUID]; !ok {
+		m.idxO[oUUID] = make(map[string]*triple.Triple)
+	}
+	m.idxO[oUUID][tuuid] = t
+
+	key := sUUID + pUUID
+	if _, ok := m.idxSP[key]; !ok {
+		m.idxSP[key] = make(map[string]*triple.Triple)
+	}
+	m.idxSP[key][tuuid] = t
+
+	key = pUUID + oUUID
+	if _, ok := m.idxPO[key]; !ok {
+		m.idxPO[key] = make(map[string]*triple.Triple)
+	}
+	m.idxPO[key][tuuid] = t
+
+	key = sUUID + oUUID
+	if _, ok := m.idxSO[key]; !ok {
+		m.idxSO[key] = make(map[string]*triple.Triple)
+	}
+	m.idxSO[key][tuuid] = t
 }
 
 // RemoveTriples removes the triples from the storage.
 func (m *memory) RemoveTriples(ctx context.Context, ts []*triple.Triple) error {
+	_, err := m.RemoveTriplesCount(ctx, ts)
+	return err
+}
+
+// RemoveTriplesCount removes the triples from the storage and returns how many
+// of them were present.
+func (m *memory) RemoveTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error) {
+	cnt := 0
 	for _, t := range ts {
-		suuid := UUIDToByteString(t.UUID())
-		sUUID := UUIDToByteString(t.Subject().UUID())
-		pUUID := UUIDToByteString(t.Predicate().PartialUUID())
-		oUUID := UUIDToByteString(t.Object().UUID())
-		// Update master index
 		m.rwmu.Lock()
-		delete(m.idx, suuid)
-		delete(m.idxS[sUUID], suuid)
-		delete(m.idxP[pUUID], suuid)
-		delete(m.idxO[oUUID], suuid)
-
-		key := sUUID + pUUID
-		delete(m.idxSP[key], suuid)
-		if len(m.idxSP[key]) == 0 {
-			delete(m.idxSP, key)
+		m.version = nextVersion()
+		if m.unsafeRemoveTriple(t) {
+			cnt++
+			if m.limit != nil {
+				m.limit.release(1)
+			}
 		}
+		m.rwmu.Unlock()
+	}
+	return cnt, nil
+}
 
-		key = pUUID + oUUID
-		delete(m.idxPO[key], suuid)
-		if len(m.idxPO[key]) == 0 {
-			delete(m.idxPO, key)
-		}
+// unsafeRemoveTriple removes the triple from all the indices, and returns
+// whether it was present. The capacity the triple held, if any, needs to be
+// released by the caller. This call bypasses the lock.
+func (m *memory) unsafeRemoveTriple(t *triple.Triple) bool {
+	suuid := UUIDToByteString(t.UUID())
+	sUUID := UUIDToByteString(t.Subject().UUID())
+	pUUID := UUIDToByteString(t.Predicate().PartialUUID())
+	oUUID := UUIDToByteString(t.Object().UUID())
+	// Update master index
+	it, present := m.idx[suuid]
+	if present {
+		m.unsafeUnindexTriple(it)
+	}
+	delete(m.idx, suuid)
+	delete(m.seq, suuid)
+	delete(m.idxS[sUUID], suuid)
+	delete(m.idxP[pUUID], suuid)
+	delete(m.idxO[oUUID], suuid)
+
+	key := sUUID + pUUID
+	delete(m.idxSP[key], suuid)
+	if len(m.idxSP[key]) == 0 {
+		delete(m.idxSP, key)
+	}
+
+	key = pUUID + oUUID
+	delete(m.idxPO[key], suuid)
+	if len(m.idxPO[key]) == 0 {
+		delete(m.idxPO, key)
+	}
+
+	key = sUUID + oUUID
+	delete(m.idxSO[key], suuid)
+	if len(m.idxSO[key]) == 0 {
+		delete(m.idxSO, key)
+	}
+	return present
+}
+
+// ReplaceObject removes all the triples with the provided subject and a
+// predicate with the same ID as the provided one, and adds the triple built
+// out of the provided subject, predicate, and object, under a single write
+// lock. The triples to remove are found using the subject and predicate ID
+// index. The capacity the change needs is reserved before any triple is
+// removed, so the replacement either fully happens or leaves the graph
+// untouched. It returns the triples removed, sorted by their text
+// representation.
+func (m *memory) ReplaceObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) ([]*triple.Triple, error) {
+	t, err := triple.New(s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	tuuid := UUIDToByteString(t.UUID())
+	key := UUIDToByteString(s.UUID()) + UUIDToByteString(p.PartialUUID())
 
-		key = sUUID + oUUID
-		delete(m.idxSO[key], suuid)
-		if len(m.idxSO[key]) == 0 {
-			delete(m.idxSO, key)
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	var removed []*triple.Triple
+	for id, et := range m.idxSP[key] {
+		if id != tuuid {
+			removed = append(removed, et)
+		}
+	}
+	if m.limit != nil {
+		// Only the net change is reserved, so the capacity held by the removed
+		// triples is never released for other graphs to take in between.
+		net := -len(removed)
+		if _, ok := m.idx[tuuid]; !ok {
+			net++
+		}
+		if net > 0 {
+			if err := m.limit.reserve(net); err != nil {
+				return nil, err
+			}
+		} else {
+			defer m.limit.release(-net)
 		}
+	}
+	m.version = nextVersion()
+	for _, rt := range removed {
+		m.unsafeRemoveTriple(rt)
+	}
+	m.unsafeAddTriple(t)
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].String() < removed[j].String()
+	})
+	return removed, nil
+}
 
-		m.rwmu.Unlock()
+// RemoveAllTriples removes all the triples from the storage by resetting its
+// indices. The graph ID is preserved.
+func (m *memory) RemoveAllTriples(ctx context.Context) error {
+	m.rwmu.Lock()
+	defer m.rwmu.Unlock()
+	m.version = nextVersion()
+	if m.limit != nil {
+		m.limit.release(len(m.idx))
+	}
+	m.idx = make(map[string]*triple.Triple, initialAllocation)
+	m.idxS = make(map[string]map[string]*triple.Triple, initialAllocation)
+	m.idxP = make(map[string]map[string]*triple.Triple, initialAllocation)
+	m.idxO = make(map[string]map[string]*triple.Triple, initialAllocation)
+	m.idxSP = make(map[string]map[string]*triple.Triple, initialAllocation)
+	m.idxPO = make(map[string]map[string]*triple.Triple, initialAllocation)
+	m.idxSO = make(map[string]map[string]*triple.Triple, initialAllocation)
+	if m.seq != nil {
+		m.seq = make(map[string]uint64, initialAllocation)
+	}
+	for f := range m.secondary {
+		m.secondary[f] = make(map[string][]indexEntry)
 	}
 	return nil
 }
@@ -269,6 +623,9 @@ func (c *checker) CheckGlobalTimeBounds(p *predicate.Predicate) bool {
 		if c.o.UpperAnchor != nil && t.After(*c.o.UpperAnchor) {
 			return false
 		}
+		if !storage.InTimeRanges(c.o.AnchorRanges, *t) {
+			return false
+		}
 	}
 
 	return true
@@ -440,9 +797,89 @@ func SortByString(selectedTrpls, st map[string]*triple.Triple, strObs *[]string)
 	return nil
 }
 
+// sortTriples sorts the provided triples by string like SortByString. If the
+// graph preserves the insertion order, the strings are then sorted in the
+// order the triples were first added. The graph must be locked by the caller.
+func (m *memory) sortTriples(selectedTrpls, st map[string]*triple.Triple, strTrpls *[]string) error {
+	if err := SortByString(selectedTrpls, st, strTrpls); err != nil {
+		return err
+	}
+	if m.seq == nil {
+		return nil
+	}
+	seqs := make(map[string]uint64, len(st))
+	for s, t := range st {
+		seqs[s] = m.seq[UUIDToByteString(t.UUID())]
+	}
+	ss := *strTrpls
+	sort.SliceStable(ss, func(i, j int) bool {
+		return seqs[ss[i]] < seqs[ss[j]]
+	})
+	return nil
+}
+
+// The lookups below collect their results into a snapshot while holding the
+// graph read lock, and only publish it to the provided channel once the lock
+// is released. Hence, readers that consume the channel slowly, or that modify
+// the graph while doing so, never block writers.
+
+// publishObjects sends the provided objects to the channel and closes it.
+func publishObjects(ch chan<- *triple.Object, res *[]*triple.Object) {
+	for _, o := range *res {
+		ch <- o
+	}
+	close(ch)
+}
+
+// publishNodes sends the provided nodes to the channel and closes it.
+func publishNodes(ch chan<- *node.Node, res *[]*node.Node) {
+	for _, n := range *res {
+		ch <- n
+	}
+	close(ch)
+}
+
+// publishPredicates sends the provided predicates to the channel and closes
+// it.
+func publishPredicates(ch chan<- *predicate.Predicate, res *[]*predicate.Predicate) {
+	for _, p := range *res {
+		ch <- p
+	}
+	close(ch)
+}
+
+// publishTriples sends the provided triples to the channel and closes it.
+func publishTriples(ch chan<- *triple.Triple, res *[]*triple.Triple) {
+	for _, t := range *res {
+		ch <- t
+	}
+	close(ch)
+}
+
+// publishAnchors sends the provided time anchors to the channel and closes it.
+func publishAnchors(ch chan<- *time.Time, res *[]*time.Time) {
+	for _, ta := range *res {
+		ch <- ta
+	}
+	close(ch)
+}
+
 // Objects published the objects for the give object and predicate to the
 // provided channel.
 func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	return m.objects(ctx, s, p, lo, false, objs)
+}
+
+// DistinctObjects publishes the objects for the given subject and predicate
+// to the provided channel like Objects does, but only once per object value.
+// The duplicates are skipped while scanning, before applying the limit.
+func (m *memory) DistinctObjects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, objs chan<- *triple.Object) error {
+	return m.objects(ctx, s, p, lo, true, objs)
+}
+
+// objects publishes the objects for the given subject and predicate to the
+// provided channel, skipping the repeated object values if distinct is set.
+func (m *memory) objects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *storage.LookupOptions, distinct bool, objs chan<- *triple.Object) error {
 	if objs == nil {
 		return fmt.Errorf("cannot provide an empty channel")
 	}
@@ -451,10 +888,31 @@ func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predica
 	pUUID := UUIDToByteString(p.PartialUUID())
 	spIdx := sUUID + pUUID
 	m.rwmu.RLock()
+	var res []*triple.Object
+	defer publishObjects(objs, &res)
 	defer m.rwmu.RUnlock()
-	defer close(objs)
 
+	seen := make(map[string]bool)
+	isNew := func(o *triple.Object) bool {
+		if !distinct {
+			return true
+		}
+		k := UUIDToByteString(o.UUID())
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+		return true
+	}
 	ckr := newChecker(lo, p)
+	if es, ok := m.indexed(SubjectPredicate, spIdx, lo); ok {
+		for _, e := range es {
+			if ckr.CheckGlobalTimeBounds(e.t.Predicate()) && isNew(e.t.Object()) && ckr.CheckLimitAndUpdate() {
+				res = append(res, e.t.Object())
+			}
+		}
+		return nil
+	}
 	selectedTrpls := applyGlobalTimeBounds(m.idxSP[spIdx], ckr)
 
 	var err error
@@ -480,13 +938,13 @@ func (m *memory) Objects(ctx context.Context, s *node.Node, p *predicate.Predica
 
 	st := make(map[string]*triple.Triple)
 	var strObs []string
-	if err := SortByString(selectedTrpls, st, &strObs); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strObs); err != nil {
 		return err
 	}
 
 	for _, t := range strObs {
-		if t != "" && ckr.CheckLimitAndUpdate() {
-			objs <- st[t].Object()
+		if t != "" && isNew(st[t].Object()) && ckr.CheckLimitAndUpdate() {
+			res = append(res, st[t].Object())
 		}
 	}
 
@@ -504,10 +962,19 @@ func (m *memory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple
 	oUUID := UUIDToByteString(o.UUID())
 	poIdx := pUUID + oUUID
 	m.rwmu.RLock()
+	var res []*node.Node
+	defer publishNodes(subjs, &res)
 	defer m.rwmu.RUnlock()
-	defer close(subjs)
 
 	ckr := newChecker(lo, p)
+	if es, ok := m.indexed(PredicateObject, poIdx, lo); ok {
+		for _, e := range es {
+			if ckr.CheckGlobalTimeBounds(e.t.Predicate()) && ckr.CheckLimitAndUpdate() {
+				res = append(res, e.t.Subject())
+			}
+		}
+		return nil
+	}
 	selectedTrpls := applyGlobalTimeBounds(m.idxPO[poIdx], ckr)
 
 	var err error
@@ -533,14 +1000,14 @@ func (m *memory) Subjects(ctx context.Context, p *predicate.Predicate, o *triple
 
 	st := make(map[string]*triple.Triple)
 	var strSubs []string
-	if err := SortByString(selectedTrpls, st, &strSubs); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strSubs); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strSubs {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			subjs <- st[t].Subject()
+			res = append(res, st[t].Subject())
 			cnt++
 		}
 	}
@@ -559,8 +1026,9 @@ func (m *memory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node
 	oUUID := UUIDToByteString(o.UUID())
 	soIdx := sUUID + oUUID
 	m.rwmu.RLock()
+	var res []*predicate.Predicate
+	defer publishPredicates(prds, &res)
 	defer m.rwmu.RUnlock()
-	defer close(prds)
 
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idxSO[soIdx], ckr)
@@ -588,14 +1056,14 @@ func (m *memory) PredicatesForSubjectAndObject(ctx context.Context, s *node.Node
 
 	st := make(map[string]*triple.Triple)
 	var strPrds []string
-	if err := SortByString(selectedTrpls, st, &strPrds); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strPrds); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strPrds {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			prds <- st[t].Predicate()
+			res = append(res, st[t].Predicate())
 			cnt++
 		}
 	}
@@ -612,8 +1080,9 @@ func (m *memory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *sto
 
 	sUUID := UUIDToByteString(s.UUID())
 	m.rwmu.RLock()
+	var res []*predicate.Predicate
+	defer publishPredicates(prds, &res)
 	defer m.rwmu.RUnlock()
-	defer close(prds)
 
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idxS[sUUID], ckr)
@@ -641,14 +1110,14 @@ func (m *memory) PredicatesForSubject(ctx context.Context, s *node.Node, lo *sto
 
 	st := make(map[string]*triple.Triple)
 	var strPrds []string
-	if err := SortByString(selectedTrpls, st, &strPrds); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strPrds); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strPrds {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			prds <- st[t].Predicate()
+			res = append(res, st[t].Predicate())
 			cnt++
 		}
 	}
@@ -665,8 +1134,9 @@ func (m *memory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *
 
 	oUUID := UUIDToByteString(o.UUID())
 	m.rwmu.RLock()
+	var res []*predicate.Predicate
+	defer publishPredicates(prds, &res)
 	defer m.rwmu.RUnlock()
-	defer close(prds)
 
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idxO[oUUID], ckr)
@@ -694,14 +1164,14 @@ func (m *memory) PredicatesForObject(ctx context.Context, o *triple.Object, lo *
 
 	st := make(map[string]*triple.Triple)
 	var strPrds []string
-	if err := SortByString(selectedTrpls, st, &strPrds); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strPrds); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strPrds {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			prds <- st[t].Predicate()
+			res = append(res, st[t].Predicate())
 			cnt++
 		}
 	}
@@ -716,11 +1186,41 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 		return fmt.Errorf("cannot provide an empty channel")
 	}
 
-	sUUID := UUIDToByteString(s.UUID())
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
+	defer m.rwmu.RUnlock()
+	return m.triplesForSubject(UUIDToByteString(s.UUID()), lo, &res)
+}
+
+// TriplesForSubjects publishes all triples available for any of the given
+// subjects to the provided channel, holding the graph lock only once.
+func (m *memory) TriplesForSubjects(ctx context.Context, subjects []*node.Node, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+
+	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
+	seen := make(map[string]bool, len(subjects))
+	for _, s := range subjects {
+		sUUID := UUIDToByteString(s.UUID())
+		if seen[sUUID] {
+			continue
+		}
+		seen[sUUID] = true
+		if err := m.triplesForSubject(sUUID, lo, &res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// triplesForSubject appends the triples of the subject with the provided UUID
+// to res. The caller must hold the graph read lock.
+func (m *memory) triplesForSubject(sUUID string, lo *storage.LookupOptions, res *[]*triple.Triple) error {
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idxS[sUUID], ckr)
 
@@ -747,14 +1247,14 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			*res = append(*res, st[t])
 			cnt++
 		}
 	}
@@ -762,6 +1262,61 @@ func (m *memory) TriplesForSubject(ctx context.Context, s *node.Node, lo *storag
 	return nil
 }
 
+// PredicateAnchors publishes the distinct time anchors of the temporal
+// predicates with the provided ID for the given subject to the provided
+// channel, in ascending order. The anchors are read from the subject and
+// predicate index without materializing the matching triples.
+func (m *memory) PredicateAnchors(ctx context.Context, s *node.Node, pid string, lo *storage.LookupOptions, anchors chan<- *time.Time) error {
+	if anchors == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	p, err := predicate.NewImmutable(pid)
+	if err != nil {
+		return err
+	}
+
+	m.rwmu.RLock()
+	var res []*time.Time
+	defer publishAnchors(anchors, &res)
+	defer m.rwmu.RUnlock()
+
+	if lo == nil {
+		lo = storage.DefaultLookup
+	}
+	ckr := newChecker(lo, nil)
+	key := UUIDToByteString(s.UUID()) + UUIDToByteString(p.PartialUUID())
+	var tas []*time.Time
+	seen := make(map[int64]bool)
+	for _, t := range m.idxSP[key] {
+		tp := t.Predicate()
+		if tp.Type() != predicate.Temporal || !ckr.CheckGlobalTimeBounds(tp) {
+			continue
+		}
+		ta, err := tp.TimeAnchor()
+		if err != nil {
+			return err
+		}
+		if seen[ta.UnixNano()] {
+			continue
+		}
+		seen[ta.UnixNano()] = true
+		tas = append(tas, ta)
+	}
+	sort.Slice(tas, func(i, j int) bool {
+		return tas[i].Before(*tas[j])
+	})
+	if lo.LatestAnchor && len(tas) > 0 {
+		tas = tas[len(tas)-1:]
+	}
+	for i, ta := range tas {
+		if lo.MaxElements > 0 && i >= lo.MaxElements {
+			break
+		}
+		res = append(res, ta)
+	}
+	return nil
+}
+
 // TriplesForPredicate publishes all triples available for the given predicate
 // to the provided channel.
 func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
@@ -771,8 +1326,9 @@ func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate
 
 	pUUID := UUIDToByteString(p.PartialUUID())
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
 
 	ckr := newChecker(lo, p)
 	selectedTrpls := applyGlobalTimeBounds(m.idxP[pUUID], ckr)
@@ -800,14 +1356,14 @@ func (m *memory) TriplesForPredicate(ctx context.Context, p *predicate.Predicate
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			res = append(res, st[t])
 			cnt++
 		}
 	}
@@ -824,8 +1380,9 @@ func (m *memory) TriplesForObject(ctx context.Context, o *triple.Object, lo *sto
 
 	oUUID := UUIDToByteString(o.UUID())
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
 
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idxO[oUUID], ckr)
@@ -853,14 +1410,14 @@ func (m *memory) TriplesForObject(ctx context.Context, o *triple.Object, lo *sto
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			res = append(res, st[t])
 			cnt++
 		}
 	}
@@ -879,8 +1436,9 @@ func (m *memory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node
 	pUUID := UUIDToByteString(p.PartialUUID())
 	spIdx := sUUID + pUUID
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
 
 	ckr := newChecker(lo, p)
 	selectedTrpls := applyGlobalTimeBounds(m.idxSP[spIdx], ckr)
@@ -908,14 +1466,14 @@ func (m *memory) TriplesForSubjectAndPredicate(ctx context.Context, s *node.Node
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			res = append(res, st[t])
 			cnt++
 		}
 	}
@@ -934,8 +1492,9 @@ func (m *memory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.
 	oUUID := UUIDToByteString(o.UUID())
 	poIdx := pUUID + oUUID
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
 
 	ckr := newChecker(lo, p)
 	selectedTrpls := applyGlobalTimeBounds(m.idxPO[poIdx], ckr)
@@ -963,14 +1522,14 @@ func (m *memory) TriplesForPredicateAndObject(ctx context.Context, p *predicate.
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			res = append(res, st[t])
 			cnt++
 		}
 	}
@@ -987,6 +1546,19 @@ func (m *memory) Exist(ctx context.Context, t *triple.Triple) (bool, error) {
 	return ok, nil
 }
 
+// TriplesForSubjectPredicateObject returns the stored triple for the provided
+// subject, predicate, and object, or nil if it does not exist.
+func (m *memory) TriplesForSubjectPredicateObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) (*triple.Triple, error) {
+	t, err := triple.New(s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	suuid := UUIDToByteString(t.UUID())
+	m.rwmu.RLock()
+	defer m.rwmu.RUnlock()
+	return m.idx[suuid], nil
+}
+
 // Triples allows to iterate over all available triples by pushing them to the
 // provided channel.
 func (m *memory) Triples(ctx context.Context, lo *storage.LookupOptions, trpls chan<- *triple.Triple) error {
@@ -995,8 +1567,9 @@ func (m *memory) Triples(ctx context.Context, lo *storage.LookupOptions, trpls c
 	}
 
 	m.rwmu.RLock()
+	var res []*triple.Triple
+	defer publishTriples(trpls, &res)
 	defer m.rwmu.RUnlock()
-	defer close(trpls)
 
 	ckr := newChecker(lo, nil)
 	selectedTrpls := applyGlobalTimeBounds(m.idx, ckr)
@@ -1024,17 +1597,61 @@ func (m *memory) Triples(ctx context.Context, lo *storage.LookupOptions, trpls c
 
 	st := make(map[string]*triple.Triple)
 	var strTrpls []string
-	if err := SortByString(selectedTrpls, st, &strTrpls); err != nil {
+	if err := m.sortTriples(selectedTrpls, st, &strTrpls); err != nil {
 		return err
 	}
 
 	cnt := 0
 	for _, t := range strTrpls {
 		if t != "" && ckr.CheckLimitAndUpdate() {
-			trpls <- st[t]
+			res = append(res, st[t])
 			cnt++
 		}
 	}
 
 	return nil
 }
+
+// Walk calls fn once for each subject in the graph with all the triples that
+// have it as subject, in subject order, using the subject index. The triples
+// of a subject follow the insertion order if the graph preserves it. The graph
+// is only locked while collecting the triples of each subject, so fn can
+// safely modify the graph.
+func (m *memory) Walk(ctx context.Context, fn storage.WalkFunc) error {
+	m.rwmu.RLock()
+	sbjs := make(map[string]string, len(m.idxS))
+	var strSbjs []string
+	for k, ts := range m.idxS {
+		for _, t := range ts {
+			s := t.Subject().String()
+			sbjs[s] = k
+			strSbjs = append(strSbjs, s)
+			break
+		}
+	}
+	m.rwmu.RUnlock()
+	sort.Strings(strSbjs)
+
+	for _, s := range strSbjs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m.rwmu.RLock()
+		st := make(map[string]*triple.Triple)
+		var strTrpls []string
+		err := m.sortTriples(m.idxS[sbjs[s]], st, &strTrpls)
+		m.rwmu.RUnlock()
+		if err != nil || len(strTrpls) == 0 {
+			// The subject was removed while walking the graph.
+			continue
+		}
+		ts := make([]*triple.Triple, 0, len(strTrpls))
+		for _, t := range strTrpls {
+			ts = append(ts, st[t])
+		}
+		if err := fn(ts[0].Subject(), ts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,8 +16,11 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -54,6 +57,117 @@ func TestMemoryStore(t *testing.T) {
 	}
 }
 
+func TestMemoryStoreErrors(t *testing.T) {
+	s, ctx := NewStore(), context.Background()
+	if _, err := s.NewGraph(ctx, "test"); err != nil {
+		t.Fatalf("memoryStore.NewGraph: should never fail to crate a graph; %s", err)
+	}
+	if _, err := s.NewGraph(ctx, "test"); !errors.Is(err, storage.ErrGraphExists) {
+		t.Errorf("memoryStore.NewGraph returned error %v for an existing graph; want %v", err, storage.ErrGraphExists)
+	}
+	if _, err := s.Graph(ctx, "missing"); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("memoryStore.Graph returned error %v for a non existing graph; want %v", err, storage.ErrGraphNotFound)
+	}
+	if err := s.DeleteGraph(ctx, "missing"); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("memoryStore.DeleteGraph returned error %v for a non existing graph; want %v", err, storage.ErrGraphNotFound)
+	}
+	if _, err := s.NewGraph(ctx, ""); !errors.Is(err, storage.ErrGraphEmpty) {
+		t.Errorf("memoryStore.NewGraph returned error %v for an empty ID; want %v", err, storage.ErrGraphEmpty)
+	}
+	if _, err := s.Graph(ctx, ""); !errors.Is(err, storage.ErrGraphEmpty) {
+		t.Errorf("memoryStore.Graph returned error %v for an empty ID; want %v", err, storage.ErrGraphEmpty)
+	}
+	if err := s.DeleteGraph(ctx, ""); !errors.Is(err, storage.ErrGraphEmpty) {
+		t.Errorf("memoryStore.DeleteGraph returned error %v for an empty ID; want %v", err, storage.ErrGraphEmpty)
+	}
+}
+
+func TestMemoryStoreClear(t *testing.T) {
+	gs, ctx := []string{"?foo", "?bar", "?test"}, context.Background()
+	s := NewStore()
+	for _, g := range gs {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("memoryStore.NewGraph: should never fail to crate a graph %s; %s", g, err)
+		}
+	}
+	c, ok := s.(storage.Clearer)
+	if !ok {
+		t.Fatalf("memoryStore should implement storage.Clearer")
+	}
+	if err := c.Clear(ctx); err != nil {
+		t.Fatalf("memoryStore.Clear failed with error %v", err)
+	}
+	gns := make(chan string, len(gs))
+	if err := s.GraphNames(ctx, gns); err != nil {
+		t.Errorf("memoryStore.GraphNames: failed with error %v", err)
+	}
+	for g := range gns {
+		t.Errorf("memoryStore.GraphNames returned graph %q after clearing the store", g)
+	}
+	if _, err := s.Graph(ctx, "?foo"); !errors.Is(err, storage.ErrGraphNotFound) {
+		t.Errorf("memoryStore.Graph returned error %v for a cleared graph; want %v", err, storage.ErrGraphNotFound)
+	}
+	if _, err := s.NewGraph(ctx, "?foo"); err != nil {
+		t.Errorf("memoryStore.NewGraph should be able to recreate a cleared graph; %v", err)
+	}
+}
+
+func TestMemoryStoreWithLimits(t *testing.T) {
+	ctx := context.Background()
+	ts := getTestTriples(t)
+	s := NewStoreWithLimits(len(ts))
+	g1, err := s.NewGraph(ctx, "?g1")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	g2, err := s.NewGraph(ctx, "?g2")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	// The limit is shared by all the graphs, and triples already present do
+	// not count twice.
+	if err := g1.AddTriples(ctx, ts[:4]); err != nil {
+		t.Fatalf("g1.AddTriples failed with error %v", err)
+	}
+	if err := g1.AddTriples(ctx, ts[:4]); err != nil {
+		t.Fatalf("g1.AddTriples failed to add already present triples with error %v", err)
+	}
+	if err := g2.AddTriples(ctx, ts[:2]); err != nil {
+		t.Fatalf("g2.AddTriples failed to add triples up to the limit with error %v", err)
+	}
+	// Additions beyond the limit fail as a whole.
+	if err := g1.AddTriples(ctx, ts[4:]); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Errorf("g1.AddTriples returned error %v beyond the limit; want %v", err, storage.ErrCapacityExceeded)
+	}
+	if _, err := storage.AddTriplesIfAbsent(ctx, g2, ts); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Errorf("storage.AddTriplesIfAbsent returned error %v beyond the limit; want %v", err, storage.ErrCapacityExceeded)
+	}
+	for _, trpl := range ts[4:] {
+		if ok, _ := g1.Exist(ctx, trpl); ok {
+			t.Errorf("g1.Exist(%s) returned true for a triple rejected by the limit", trpl)
+		}
+	}
+	// Removing triples, clearing graphs or deleting them frees capacity.
+	if err := g1.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g1.RemoveTriples failed with error %v", err)
+	}
+	if err := g1.AddTriples(ctx, ts[4:5]); err != nil {
+		t.Errorf("g1.AddTriples failed after removing a triple with error %v", err)
+	}
+	if err := g2.RemoveAllTriples(ctx); err != nil {
+		t.Fatalf("g2.RemoveAllTriples failed with error %v", err)
+	}
+	if err := g1.AddTriples(ctx, ts[5:]); err != nil {
+		t.Errorf("g1.AddTriples failed after clearing a graph with error %v", err)
+	}
+	if err := s.DeleteGraph(ctx, "?g1"); err != nil {
+		t.Fatalf("memoryStore.DeleteGraph failed with error %v", err)
+	}
+	if err := g2.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g2.AddTriples failed after deleting a graph with error %v", err)
+	}
+}
+
 func TestGraphNames(t *testing.T) {
 	gs, ctx := []string{"?foo", "?bar", "?test"}, context.Background()
 	s := NewStore()
@@ -88,6 +202,62 @@ func TestGraphNames(t *testing.T) {
 	}
 }
 
+func TestMemoryStoreMetadata(t *testing.T) {
+	s, ctx := NewStore(), context.Background()
+	m, ok := s.(storage.MetadataStore)
+	if !ok {
+		t.Fatalf("memoryStore should implement storage.MetadataStore")
+	}
+	if _, err := m.Metadata(ctx, "foo"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Errorf("memoryStore.Metadata returned error %v for a missing key; want %v", err, storage.ErrMetadataNotFound)
+	}
+	for _, v := range []string{"bar", "baz"} {
+		if err := m.SetMetadata(ctx, "foo", v); err != nil {
+			t.Fatalf("memoryStore.SetMetadata failed with error %v", err)
+		}
+		got, err := m.Metadata(ctx, "foo")
+		if err != nil {
+			t.Fatalf("memoryStore.Metadata failed with error %v", err)
+		}
+		if got != v {
+			t.Errorf("memoryStore.Metadata returned %v; want %v", got, v)
+		}
+	}
+	if err := m.DeleteMetadata(ctx, "foo"); err != nil {
+		t.Fatalf("memoryStore.DeleteMetadata failed with error %v", err)
+	}
+	if err := m.DeleteMetadata(ctx, "foo"); !errors.Is(err, storage.ErrMetadataNotFound) {
+		t.Errorf("memoryStore.DeleteMetadata returned error %v for a deleted key; want %v", err, storage.ErrMetadataNotFound)
+	}
+}
+
+func TestGraphNamesSorted(t *testing.T) {
+	gs, ctx := []string{"?foo", "?bar", "?test", "?baz"}, context.Background()
+	s := NewStore()
+	for _, g := range gs {
+		if _, err := s.NewGraph(ctx, g); err != nil {
+			t.Fatalf("memoryStore.NewGraph: should never fail to crate a graph %s; %s", g, err)
+		}
+	}
+	want := []string{"?bar", "?baz", "?foo", "?test"}
+	// The wrapper hides GraphNamesSorted, forcing storage.GraphNamesSorted to
+	// sort the names returned by GraphNames.
+	stores := []storage.Store{s, struct{ storage.Store }{s}}
+	for _, st := range stores {
+		gns := make(chan string, len(gs))
+		if err := storage.GraphNamesSorted(ctx, st, gns); err != nil {
+			t.Fatalf("storage.GraphNamesSorted: failed with error %v", err)
+		}
+		var got []string
+		for g := range gns {
+			got = append(got, g)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.GraphNamesSorted(%T) returned %v; want %v", st, got, want)
+		}
+	}
+}
+
 func TestDefaultLookupChecker(t *testing.T) {
 	dlu := storage.DefaultLookup
 	c := newChecker(dlu, nil)
@@ -159,6 +329,27 @@ func TestTemporalBoundedLookupChecker(t *testing.T) {
 	if cuu.CheckGlobalTimeBounds(upa) {
 		t.Errorf("Failed to reject invalid predicate %v by checker %v", mpa, cuu)
 	}
+	// Check several ranges, including overlapping ones.
+	lb, _ = lpa.TimeAnchor()
+	mb, _ := mpa.TimeAnchor()
+	ub, _ = upa.TimeAnchor()
+	bru := &storage.LookupOptions{AnchorRanges: []storage.TimeRange{
+		{Upper: lb},
+		{Lower: ub},
+		{Lower: ub, Upper: ub},
+	}}
+	cru := newChecker(bru, nil)
+	if !cru.CheckGlobalTimeBounds(lpa) || !cru.CheckGlobalTimeBounds(upa) {
+		t.Errorf("Failed to accept valid predicates %v and %v by checker %v", lpa, upa, cru)
+	}
+	if cru.CheckGlobalTimeBounds(mpa) {
+		t.Errorf("Failed to reject invalid predicate %v by checker %v", mpa, cru)
+	}
+	bru = &storage.LookupOptions{AnchorRanges: []storage.TimeRange{{Lower: lb, Upper: mb}}}
+	cru = newChecker(bru, nil)
+	if !cru.CheckGlobalTimeBounds(mpa) || cru.CheckGlobalTimeBounds(upa) {
+		t.Errorf("Failed to check predicates %v and %v against the ranges of checker %v", mpa, upa, cru)
+	}
 }
 
 func TestTemporalExactChecker(t *testing.T) {
@@ -267,6 +458,494 @@ func getTestTriplesFilter(t *testing.T) []*triple.Triple {
 	})
 }
 
+// plainGraph hides the optional interfaces implemented by the wrapped graph.
+type plainGraph struct {
+	storage.Graph
+}
+
+func TestWalk(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if _, ok := g.(storage.Walker); !ok {
+		t.Fatalf("memory graphs should implement storage.Walker")
+	}
+	for _, wg := range []storage.Graph{g, &plainGraph{g}} {
+		var got []string
+		err := storage.Walk(ctx, wg, func(s *node.Node, sts []*triple.Triple) error {
+			for _, st := range sts {
+				if st.Subject().String() != s.String() {
+					t.Errorf("storage.Walk returned triple %s for subject %s", st, s)
+				}
+			}
+			got = append(got, fmt.Sprintf("%s:%d", s, len(sts)))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("storage.Walk failed with error %v", err)
+		}
+		if want := []string{"/u<john>:3", "/u<mary>:3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.Walk visited %v; want %v", got, want)
+		}
+
+		// The first callback error stops the walk.
+		errStop, n := errors.New("stop"), 0
+		err = storage.Walk(ctx, wg, func(*node.Node, []*triple.Triple) error {
+			n++
+			return errStop
+		})
+		if err != errStop || n != 1 {
+			t.Errorf("storage.Walk returned error %v after %d callbacks; want %v after 1", err, n, errStop)
+		}
+
+		// Walks stop once the context is done.
+		cctx, cancel := context.WithCancel(ctx)
+		n = 0
+		err = storage.Walk(cctx, wg, func(*node.Node, []*triple.Triple) error {
+			n++
+			cancel()
+			return nil
+		})
+		if err != context.Canceled || n != 1 {
+			t.Errorf("storage.Walk returned error %v after %d callbacks; want %v after 1", err, n, context.Canceled)
+		}
+	}
+}
+
+func TestTriplesForSubjects(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if _, ok := g.(storage.SubjectsBatcher); !ok {
+		t.Fatalf("memory graphs should implement storage.SubjectsBatcher")
+	}
+	john, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := node.Parse("/u<missing>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sg := range []storage.Graph{g, &plainGraph{g}} {
+		// Repeated subjects are only looked up once, and missing ones are ignored.
+		trpls := make(chan *triple.Triple)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- storage.TriplesForSubjects(ctx, sg, []*node.Node{john, missing, john}, storage.DefaultLookup, trpls)
+		}()
+		n := 0
+		for trpl := range trpls {
+			if got, want := trpl.Subject().String(), john.String(); got != want {
+				t.Errorf("storage.TriplesForSubjects returned triple %s for subject %s; want subject %s", trpl, got, want)
+			}
+			n++
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("storage.TriplesForSubjects failed with error %v", err)
+		}
+		if n != 3 {
+			t.Errorf("storage.TriplesForSubjects returned %d triples; want 3", n)
+		}
+
+		// The lookup options apply to each subject.
+		trpls = make(chan *triple.Triple)
+		go func() {
+			errc <- storage.TriplesForSubjects(ctx, sg, []*node.Node{john, ts[3].Subject()}, &storage.LookupOptions{MaxElements: 1}, trpls)
+		}()
+		sbjs := make(map[string]int)
+		for trpl := range trpls {
+			sbjs[trpl.Subject().String()]++
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("storage.TriplesForSubjects failed with error %v", err)
+		}
+		if want := map[string]int{"/u<john>": 1, "/u<mary>": 1}; !reflect.DeepEqual(sbjs, want) {
+			t.Errorf("storage.TriplesForSubjects returned %v triples per subject; want %v", sbjs, want)
+		}
+	}
+}
+
+func TestPredicateAnchors(t *testing.T) {
+	ctx := context.Background()
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<peter>\t\"bought\"@[2016-03-01T00:00:00Z]\t/c<x>",
+		"/u<peter>\t\"bought\"@[2016-01-01T00:00:00Z]\t/c<y>",
+		"/u<peter>\t\"bought\"@[2016-01-01T00:00:00Z]\t/c<z>",
+		"/u<peter>\t\"bought\"@[2016-02-01T00:00:00Z]\t/c<x>",
+		"/u<peter>\t\"bought\"@[]\t/c<w>",
+		"/u<peter>\t\"sold\"@[2016-04-01T00:00:00Z]\t/c<x>",
+		"/u<mary>\t\"bought\"@[2016-05-01T00:00:00Z]\t/c<x>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if _, ok := g.(storage.PredicateAnchorer); !ok {
+		t.Fatalf("memory graphs should implement storage.PredicateAnchorer")
+	}
+	peter := ts[0].Subject()
+	lower := time.Date(2016, 1, 15, 0, 0, 0, 0, time.UTC)
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want []string
+	}{
+		{storage.DefaultLookup, []string{"2016-01-01T00:00:00Z", "2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z"}},
+		{nil, []string{"2016-01-01T00:00:00Z", "2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z"}},
+		{&storage.LookupOptions{MaxElements: 2}, []string{"2016-01-01T00:00:00Z", "2016-02-01T00:00:00Z"}},
+		{&storage.LookupOptions{LowerAnchor: &lower}, []string{"2016-02-01T00:00:00Z", "2016-03-01T00:00:00Z"}},
+		{&storage.LookupOptions{LatestAnchor: true}, []string{"2016-03-01T00:00:00Z"}},
+	}
+	for _, sg := range []storage.Graph{g, &plainGraph{g}} {
+		for _, entry := range testTable {
+			anchors := make(chan *time.Time)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- storage.PredicateAnchors(ctx, sg, peter, "bought", entry.lo, anchors)
+			}()
+			var got []string
+			for ta := range anchors {
+				got = append(got, ta.UTC().Format(time.RFC3339))
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("storage.PredicateAnchors failed with error %v", err)
+			}
+			if !reflect.DeepEqual(got, entry.want) {
+				t.Errorf("storage.PredicateAnchors(%v, \"bought\", %v) returned %v; want %v", peter, entry.lo, got, entry.want)
+			}
+		}
+	}
+}
+
+func TestDistinctObjects(t *testing.T) {
+	ctx := context.Background()
+	var ts []*triple.Triple
+	for _, s := range []string{
+		"/u<peter>\t\"mood\"@[2016-01-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-02-01T00:00:00Z]\t/m<sad>",
+		"/u<peter>\t\"mood\"@[2016-03-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-04-01T00:00:00Z]\t/m<happy>",
+		"/u<peter>\t\"mood\"@[2016-05-01T00:00:00Z]\t/m<sad>",
+		"/u<peter>\t\"mood\"@[2016-06-01T00:00:00Z]\t/m<angry>",
+		"/u<mary>\t\"mood\"@[2016-01-01T00:00:00Z]\t/m<calm>",
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+	}
+	if _, ok := g.(storage.DistinctObjecter); !ok {
+		t.Fatalf("memory graphs should implement storage.DistinctObjecter")
+	}
+	// Immutable predicates match every anchor of the temporal predicates
+	// sharing their ID.
+	s := ts[0].Subject()
+	p, err := predicate.NewImmutable("mood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	upper := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	mid := time.Date(2016, 2, 15, 0, 0, 0, 0, time.UTC)
+	testTable := []struct {
+		lo   *storage.LookupOptions
+		want []string
+	}{
+		{&storage.LookupOptions{LowerAnchor: &lower, UpperAnchor: &upper}, []string{"/m<angry>", "/m<happy>", "/m<sad>"}},
+		{&storage.LookupOptions{LowerAnchor: &mid, UpperAnchor: &upper}, []string{"/m<angry>", "/m<happy>", "/m<sad>"}},
+		{&storage.LookupOptions{LowerAnchor: &lower, UpperAnchor: &mid}, []string{"/m<happy>", "/m<sad>"}},
+		{&storage.LookupOptions{LowerAnchor: &lower, UpperAnchor: &upper, MaxElements: 2}, nil},
+	}
+	for _, sg := range []storage.Graph{g, &plainGraph{g}} {
+		for _, entry := range testTable {
+			os := make(chan *triple.Object)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- storage.DistinctObjects(ctx, sg, s, p, entry.lo, os)
+			}()
+			var got []string
+			for o := range os {
+				got = append(got, o.String())
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("storage.DistinctObjects failed with error %v", err)
+			}
+			sort.Strings(got)
+			if entry.want == nil {
+				// Only the number of distinct objects is defined under a limit.
+				if len(got) != entry.lo.MaxElements || got[0] == got[1] {
+					t.Errorf("storage.DistinctObjects(%v, %v, %v) returned %v; want %d distinct objects", s, p, entry.lo, got, entry.lo.MaxElements)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(got, entry.want) {
+				t.Errorf("storage.DistinctObjects(%v, %v, %v) returned %v; want %v", s, p, entry.lo, got, entry.want)
+			}
+		}
+	}
+}
+
+func TestAddTriplesCount(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	for i, wrap := range []func(storage.Graph) storage.Graph{
+		func(g storage.Graph) storage.Graph { return g },
+		func(g storage.Graph) storage.Graph { return &plainGraph{g} },
+	} {
+		g, err := s.NewGraph(ctx, fmt.Sprintf("test%d", i))
+		if err != nil {
+			t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+		}
+		if _, ok := g.(storage.CountingAdder); !ok {
+			t.Fatalf("memory graphs should implement storage.CountingAdder")
+		}
+		if err := g.AddTriples(ctx, ts[:3]); err != nil {
+			t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+		}
+		wg := wrap(g)
+		// Repeated triples in the input are only counted once.
+		n, err := storage.AddTriples(ctx, wg, append(ts, ts[5]))
+		if err != nil {
+			t.Fatalf("storage.AddTriples failed with error %v", err)
+		}
+		if got, want := n, 3; got != want {
+			t.Errorf("storage.AddTriples added %d triples; want %d", got, want)
+		}
+		n, err = storage.AddTriples(ctx, wg, ts)
+		if err != nil {
+			t.Fatalf("storage.AddTriples failed with error %v", err)
+		}
+		if got, want := n, 0; got != want {
+			t.Errorf("storage.AddTriples added %d already present triples; want %d", got, want)
+		}
+	}
+}
+
+func TestAddTriplesIfAbsent(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	for i, wrap := range []func(storage.Graph) storage.Graph{
+		func(g storage.Graph) storage.Graph { return g },
+		func(g storage.Graph) storage.Graph { return &plainGraph{g} },
+	} {
+		g, err := s.NewGraph(ctx, fmt.Sprintf("test%d", i))
+		if err != nil {
+			t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+		}
+		if _, ok := g.(storage.AbsentAdder); !ok {
+			t.Fatalf("memory graphs should implement storage.AbsentAdder")
+		}
+		if err := g.AddTriples(ctx, ts[:3]); err != nil {
+			t.Fatalf("g.AddTriples(_) failed failed to add test triples with error %v", err)
+		}
+		wg := wrap(g)
+		// Repeated triples in the input are only added once.
+		n, err := storage.AddTriplesIfAbsent(ctx, wg, append(ts, ts[5]))
+		if err != nil {
+			t.Fatalf("storage.AddTriplesIfAbsent failed with error %v", err)
+		}
+		if got, want := n, 3; got != want {
+			t.Errorf("storage.AddTriplesIfAbsent added %d triples; want %d", got, want)
+		}
+		n, err = storage.AddTriplesIfAbsent(ctx, wg, ts)
+		if err != nil {
+			t.Fatalf("storage.AddTriplesIfAbsent failed with error %v", err)
+		}
+		if got, want := n, 0; got != want {
+			t.Errorf("storage.AddTriplesIfAbsent added %d already present triples; want %d", got, want)
+		}
+		for _, trpl := range ts {
+			if ok, err := g.Exist(ctx, trpl); err != nil || !ok {
+				t.Errorf("g.Exist(%s) returned %v, %v; want true, nil", trpl, ok, err)
+			}
+		}
+	}
+}
+
+func TestReplaceObject(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<john>\t\"height_cm\"@[]\t\"170\"^^type:int64",
+		"/u<john>\t\"height_cm\"@[]\t\"172\"^^type:int64",
+		"/u<john>\t\"height_cm\"@[2016-04-10T04:21:00Z]\t\"171\"^^type:int64",
+		"/u<john>\t\"weight_kg\"@[]\t\"70\"^^type:int64",
+		"/u<mary>\t\"height_cm\"@[]\t\"160\"^^type:int64",
+		"/u<john>\t\"height_cm\"@[]\t\"180\"^^type:int64",
+	})
+	s := NewStore()
+	for i, wrap := range []func(storage.Graph) storage.Graph{
+		func(g storage.Graph) storage.Graph { return g },
+		func(g storage.Graph) storage.Graph { return &plainGraph{g} },
+	} {
+		g, err := s.NewGraph(ctx, fmt.Sprintf("test%d", i))
+		if err != nil {
+			t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+		}
+		if _, ok := g.(storage.ObjectReplacer); !ok {
+			t.Fatalf("memory graphs should implement storage.ObjectReplacer")
+		}
+		if err := g.AddTriples(ctx, ts[:5]); err != nil {
+			t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+		}
+		wg := wrap(g)
+		// All the triples with the same subject and predicate ID are replaced,
+		// regardless of their time anchors.
+		nt := ts[5]
+		removed, err := storage.ReplaceObject(ctx, wg, nt.Subject(), nt.Predicate(), nt.Object())
+		if err != nil {
+			t.Fatalf("storage.ReplaceObject failed with error %v", err)
+		}
+		var got []string
+		for _, rt := range removed {
+			got = append(got, rt.String())
+		}
+		sort.Strings(got)
+		var want []string
+		for _, rt := range ts[:3] {
+			want = append(want, rt.String())
+		}
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("storage.ReplaceObject removed %v; want %v", got, want)
+		}
+		for i, trpl := range ts {
+			ok, err := g.Exist(ctx, trpl)
+			if err != nil {
+				t.Fatalf("g.Exist(%s) failed with error %v", trpl, err)
+			}
+			if want := i >= 3; ok != want {
+				t.Errorf("g.Exist(%s) returned %v after replacing the object; want %v", trpl, ok, want)
+			}
+		}
+		// Replacing with the object already present keeps it.
+		removed, err = storage.ReplaceObject(ctx, wg, nt.Subject(), nt.Predicate(), nt.Object())
+		if err != nil {
+			t.Fatalf("storage.ReplaceObject failed with error %v", err)
+		}
+		if len(removed) != 0 {
+			t.Errorf("storage.ReplaceObject removed %v when replacing with the present object; want none", removed)
+		}
+		if ok, err := g.Exist(ctx, nt); err != nil || !ok {
+			t.Errorf("g.Exist(%s) returned %v, %v; want true, nil", nt, ok, err)
+		}
+	}
+}
+
+func TestReplaceObjectCapacity(t *testing.T) {
+	ctx := context.Background()
+	ts := createTriples(t, []string{
+		"/u<john>\t\"height_cm\"@[]\t\"170\"^^type:int64",
+		"/u<john>\t\"height_cm\"@[]\t\"180\"^^type:int64",
+		"/u<mary>\t\"height_cm\"@[]\t\"160\"^^type:int64",
+	})
+	s := NewStoreWithLimits(1)
+	g, err := s.NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	other, err := s.NewGraph(ctx, "?other")
+	if err != nil {
+		t.Fatalf("memoryStore.NewGraph failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g.AddTriples(_) failed with error %v", err)
+	}
+	// Replacing reuses the capacity held by the removed triples, so the store
+	// stays full.
+	if _, err := storage.ReplaceObject(ctx, g, ts[1].Subject(), ts[1].Predicate(), ts[1].Object()); err != nil {
+		t.Fatalf("storage.ReplaceObject failed with error %v", err)
+	}
+	if err := other.AddTriples(ctx, ts[2:]); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Errorf("other.AddTriples(_) returned error %v after the replacement; want capacity exceeded", err)
+	}
+	// A failed replacement leaves the graph untouched.
+	v, err := g.Version(ctx)
+	if err != nil {
+		t.Fatalf("g.Version(_) failed with error %v", err)
+	}
+	if _, err := storage.ReplaceObject(ctx, g, ts[2].Subject(), ts[2].Predicate(), ts[2].Object()); !errors.Is(err, storage.ErrCapacityExceeded) {
+		t.Errorf("storage.ReplaceObject returned error %v; want capacity exceeded", err)
+	}
+	if ok, err := g.Exist(ctx, ts[1]); err != nil || !ok {
+		t.Errorf("g.Exist(%s) returned %v, %v after a failed replacement; want true, nil", ts[1], ok, err)
+	}
+	if nv, err := g.Version(ctx); err != nil || nv != v {
+		t.Errorf("g.Version(_) returned %d, %v after a failed replacement; want %d, nil", nv, err, v)
+	}
+}
+
+func TestVersionChangesOnWrites(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("s.NewGraph(_, \"test\") failed with error %v", err)
+	}
+	version := func() uint64 {
+		v, err := g.Version(ctx)
+		if err != nil {
+			t.Fatalf("g.Version(_) failed with error %v", err)
+		}
+		return v
+	}
+	v0 := version()
+	if got := version(); got != v0 {
+		t.Errorf("g.Version(_) changed without writes; got %d, want %d", got, v0)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	v1 := version()
+	if v1 == v0 {
+		t.Errorf("g.Version(_) did not change after g.AddTriples(_); got %d", v1)
+	}
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		t.Fatalf("g.RemoveTriples(_) failed to remove test triples with error %v", err)
+	}
+	v2 := version()
+	if v2 == v1 {
+		t.Errorf("g.Version(_) did not change after g.RemoveTriples(_); got %d", v2)
+	}
+	// Recreating the graph should not reuse versions.
+	if err := s.DeleteGraph(ctx, "test"); err != nil {
+		t.Fatalf("s.DeleteGraph(_, \"test\") failed with error %v", err)
+	}
+	if g, err = s.NewGraph(ctx, "test"); err != nil {
+		t.Fatalf("s.NewGraph(_, \"test\") failed with error %v", err)
+	}
+	if v := version(); v == v0 || v == v1 || v == v2 {
+		t.Errorf("g.Version(_) reused version %d for a recreated graph", v)
+	}
+}
+
 func TestAddRemoveTriples(t *testing.T) {
 	ts, ctx := getTestTriples(t), context.Background()
 	g, errGraph := NewStore().NewGraph(ctx, "test")
@@ -281,6 +960,149 @@ func TestAddRemoveTriples(t *testing.T) {
 	}
 }
 
+func TestRemoveTriplesCount(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, err := NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("NewStore().NewGraph(_, %q) failed with error %v", "test", err)
+	}
+	if err := g.AddTriples(ctx, ts[:2]); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	n, err := storage.RemoveTriples(ctx, g, ts)
+	if err != nil {
+		t.Fatalf("storage.RemoveTriples(_) failed with error %v", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("storage.RemoveTriples(_) removed %d triples; want %d", got, want)
+	}
+	if n, err = storage.RemoveTriples(ctx, g, ts); err != nil || n != 0 {
+		t.Errorf("storage.RemoveTriples(_) on an empty graph returned (%d, %v); want (0, nil)", n, err)
+	}
+}
+
+func TestRemoveAllTriples(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	s := NewStore()
+	g, err := s.NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("s.NewGraph(_, \"test\") failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	v0, err := g.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RemoveAllTriples(ctx); err != nil {
+		t.Fatalf("g.RemoveAllTriples(_) failed with error %v", err)
+	}
+	if v1, err := g.Version(ctx); err != nil || v1 == v0 {
+		t.Errorf("g.Version(_) did not change after g.RemoveAllTriples(_); got %d, %v", v1, err)
+	}
+	trpls := make(chan *triple.Triple, len(ts))
+	if err := g.Triples(ctx, storage.DefaultLookup, trpls); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(trpls); got != 0 {
+		t.Errorf("g.Triples(_) returned %d triples after g.RemoveAllTriples(_); want 0", got)
+	}
+	for _, trpl := range ts {
+		if b, err := g.Exist(ctx, trpl); err != nil || b {
+			t.Errorf("g.Exist(_, %v) = %v, %v; want false, nil", trpl, b, err)
+		}
+	}
+	if sg, err := s.Graph(ctx, "test"); err != nil || sg.ID(ctx) != "test" {
+		t.Errorf("s.Graph(_, \"test\") should still be available after g.RemoveAllTriples(_); got %v, %v", sg, err)
+	}
+	// The emptied graph can still be reused.
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	if b, err := g.Exist(ctx, ts[0]); err != nil || !b {
+		t.Errorf("g.Exist(_, %v) = %v, %v; want true, nil", ts[0], b, err)
+	}
+}
+
+func TestPreserveInsertionOrder(t *testing.T) {
+	ctx := context.Background()
+	var ts []*triple.Triple
+	for _, s := range []string{
+		`/u<zoe> "knows"@[] /u<bob>`,
+		`/u<amy> "knows"@[] /u<zoe>`,
+		`/u<zoe> "knows"@[] /u<amy>`,
+		`/u<bob> "knows"@[] /u<amy>`,
+	} {
+		trpl, err := triple.Parse(s, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse(%q) failed with error %v", s, err)
+		}
+		ts = append(ts, trpl)
+	}
+	collect := func(g storage.Graph) []string {
+		trpls := make(chan *triple.Triple, len(ts))
+		if err := g.Triples(ctx, storage.DefaultLookup, trpls); err != nil {
+			t.Fatalf("g.Triples(_) failed with error %v", err)
+		}
+		var got []string
+		for trpl := range trpls {
+			got = append(got, trpl.String())
+		}
+		return got
+	}
+
+	g, err := NewStoreWithOptions(Options{PreserveInsertionOrder: true}).NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("NewStoreWithOptions(_).NewGraph(_, \"test\") failed with error %v", err)
+	}
+	// Adding a triple twice does not change its position.
+	if err := g.AddTriples(ctx, append(ts, ts[0])); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	var want []string
+	for _, trpl := range ts {
+		want = append(want, trpl.String())
+	}
+	if got := collect(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("g.Triples(_) = %v; want %v", got, want)
+	}
+	objs := make(chan *triple.Object, len(ts))
+	if err := g.Objects(ctx, ts[0].Subject(), ts[0].Predicate(), storage.DefaultLookup, objs); err != nil {
+		t.Fatalf("g.Objects(_) failed with error %v", err)
+	}
+	var gotObjs []string
+	for o := range objs {
+		gotObjs = append(gotObjs, o.String())
+	}
+	if wantObjs := []string{"/u<bob>", "/u<amy>"}; !reflect.DeepEqual(gotObjs, wantObjs) {
+		t.Errorf("g.Objects(_) = %v; want %v", gotObjs, wantObjs)
+	}
+	// A triple added again after being removed moves to the end.
+	if err := g.RemoveTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g.RemoveTriples(_) failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts[:1]); err != nil {
+		t.Fatalf("g.AddTriples(_) failed with error %v", err)
+	}
+	if got, want := collect(g), append(want[1:], want[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("g.Triples(_) = %v; want %v", got, want)
+	}
+
+	// By default triples are sorted by their text representation.
+	g, err = NewStore().NewGraph(ctx, "test")
+	if err != nil {
+		t.Fatalf("NewStore().NewGraph(_, \"test\") failed with error %v", err)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Fatalf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	got := collect(g)
+	if !sort.StringsAreSorted(got) || len(got) != len(ts) {
+		t.Errorf("g.Triples(_) = %v; want %d sorted triples", got, len(ts))
+	}
+}
+
 func TestObjects(t *testing.T) {
 	ts, ctx := getTestTriples(t), context.Background()
 	g, errGraph := NewStore().NewGraph(ctx, "test")
@@ -2454,6 +3276,34 @@ func TestExists(t *testing.T) {
 	}
 }
 
+func TestTriplesForSubjectPredicateObject(t *testing.T) {
+	ts, ctx := getTestTriples(t), context.Background()
+	g, errGraph := NewStore().NewGraph(ctx, "test")
+	if errGraph != nil {
+		t.Errorf("g.NewStore() failed on creating a new graph with error %v", errGraph)
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		t.Errorf("g.AddTriples(_) failed to add test triples with error %v", err)
+	}
+	for _, trpl := range ts {
+		got, err := g.TriplesForSubjectPredicateObject(ctx, trpl.Subject(), trpl.Predicate(), trpl.Object())
+		if err != nil {
+			t.Errorf("g.TriplesForSubjectPredicateObject should have not failed for triple %s with error %s", trpl, err)
+		}
+		if got != trpl {
+			t.Errorf("g.TriplesForSubjectPredicateObject returned %v for triple %s; want the stored triple", got, trpl)
+		}
+	}
+	mt := createTriples(t, []string{"/u<kim>\t\"knows\"@[]\t/u<john>"})[0]
+	got, err := g.TriplesForSubjectPredicateObject(ctx, mt.Subject(), mt.Predicate(), mt.Object())
+	if err != nil {
+		t.Errorf("g.TriplesForSubjectPredicateObject should have not failed for triple %s with error %s", mt, err)
+	}
+	if got != nil {
+		t.Errorf("g.TriplesForSubjectPredicateObject returned %v for missing triple %s; want nil", got, mt)
+	}
+}
+
 func TestTriples(t *testing.T) {
 	ts, ctx := getTestTriples(t), context.Background()
 	g, errGraph := NewStore().NewGraph(ctx, "test")
@@ -2631,3 +3481,86 @@ func TestTriplesFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	ctx := context.Background()
+	g, err := NewStore().NewGraph(ctx, "?test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	john, err := node.Parse("/u<john>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	knows, err := predicate.NewImmutable("knows")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				ts := createTriples(t, []string{fmt.Sprintf("/u<john>\t\"knows\"@[]\t/u<person_%d_%d>", w, i)})
+				if err := g.AddTriples(ctx, ts); err != nil {
+					t.Errorf("g.AddTriples(_) failed with error %v", err)
+				}
+				if i%2 == 0 {
+					if err := g.RemoveTriples(ctx, ts); err != nil {
+						t.Errorf("g.RemoveTriples(_) failed with error %v", err)
+					}
+				}
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				ts, os := make(chan *triple.Triple), make(chan *triple.Object)
+				errs := make(chan error, 2)
+				go func() {
+					errs <- g.Triples(ctx, storage.DefaultLookup, ts)
+				}()
+				go func() {
+					errs <- g.Objects(ctx, john, knows, storage.DefaultLookup, os)
+				}()
+				// Ranging over the channels only ends once they get closed.
+				for range ts {
+				}
+				for range os {
+				}
+				for j := 0; j < 2; j++ {
+					if err := <-errs; err != nil {
+						t.Errorf("concurrent lookup failed with error %v", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Readers can modify the graph while consuming the results of a lookup.
+	ts := make(chan *triple.Triple)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.TriplesForSubject(ctx, john, storage.DefaultLookup, ts)
+	}()
+	cnt := 0
+	for trpl := range ts {
+		cnt++
+		if err := g.RemoveTriples(ctx, []*triple.Triple{trpl}); err != nil {
+			t.Errorf("g.RemoveTriples(_) failed with error %v", err)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("g.TriplesForSubject(_) failed with error %v", err)
+	}
+	if got, want := cnt, 4*50; got != want {
+		t.Errorf("g.TriplesForSubject(_) returned %d triples; want %d", got, want)
+	}
+}
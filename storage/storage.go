@@ -18,7 +18,9 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -30,9 +32,76 @@ import (
 	"github.com/pborman/uuid"
 )
 
+// Errors returned by drivers for common failures. Drivers may wrap them to add
+// context, so callers should check them using errors.Is.
+var (
+	// ErrGraphExists is returned when creating a graph that already exists.
+	ErrGraphExists = errors.New("graph already exists")
+
+	// ErrGraphNotFound is returned when retrieving or deleting a graph that
+	// does not exist.
+	ErrGraphNotFound = errors.New("graph does not exist")
+
+	// ErrGraphEmpty is returned when a graph is referred with an empty ID.
+	ErrGraphEmpty = errors.New("graph ID cannot be empty")
+
+	// ErrCapacityExceeded is returned when adding triples would make a store
+	// hold more triples than it is allowed to.
+	ErrCapacityExceeded = errors.New("store capacity exceeded")
+
+	// ErrMetadataNotFound is returned when retrieving or deleting a metadata
+	// key that does not exist.
+	ErrMetadataNotFound = errors.New("metadata does not exist")
+)
+
 // bufPool keeps a pool of bytes.Buffer for usage in String().
 var bufPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
 
+// TimeRange represents a range of time anchors. Unset bounds leave the range
+// open on that side.
+type TimeRange struct {
+	Lower *time.Time
+	Upper *time.Time
+}
+
+// Contains returns true if the time anchor falls inside the range, bounds
+// included.
+func (r TimeRange) Contains(t time.Time) bool {
+	if r.Lower != nil && t.Before(*r.Lower) {
+		return false
+	}
+	if r.Upper != nil && t.After(*r.Upper) {
+		return false
+	}
+	return true
+}
+
+// InTimeRanges returns true if no time ranges are provided or the time anchor
+// falls in any of them.
+func InTimeRanges(rngs []TimeRange, t time.Time) bool {
+	if len(rngs) == 0 {
+		return true
+	}
+	for _, r := range rngs {
+		if r.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a readable version of the TimeRange.
+func (r TimeRange) String() string {
+	var l, u string
+	if r.Lower != nil {
+		l = r.Lower.Format(time.RFC3339Nano)
+	}
+	if r.Upper != nil {
+		u = r.Upper.Format(time.RFC3339Nano)
+	}
+	return l + "," + u
+}
+
 // LookupOptions allows to specify the behavior of the lookup operations.
 type LookupOptions struct {
 	// MaxElements list the maximum number of elements to return. If not
@@ -45,6 +114,11 @@ type LookupOptions struct {
 	// UpperAnchor, if provided, represents the upper time anchor to be considered.
 	UpperAnchor *time.Time
 
+	// AnchorRanges, if provided, only considers the time anchors that fall in
+	// any of the ranges, on top of the lower and upper anchors. Overlapping
+	// ranges do not return a triple more than once.
+	AnchorRanges []TimeRange
+
 	// LatestAnchor only. If set, it will ignore the time boundaries provided and
 	// just use the last available anchor.
 	LatestAnchor bool
@@ -76,6 +150,16 @@ func (l *LookupOptions) String() string {
 	} else {
 		b.WriteString("nil")
 	}
+	if len(l.AnchorRanges) > 0 {
+		b.WriteString(", anchor_ranges=[")
+		for i, r := range l.AnchorRanges {
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			b.WriteString(r.String())
+		}
+		b.WriteString("]")
+	}
 	b.WriteString(fmt.Sprintf(", LatestAnchor=%v", l.LatestAnchor))
 	b.WriteString(fmt.Sprintf(", FilterOptions=%s>", l.FilterOptions))
 	return b.String()
@@ -113,6 +197,74 @@ type Store interface {
 	GraphNames(ctx context.Context, names chan<- string) error
 }
 
+// Clearer is an optional interface implemented by stores able to drop all
+// their graphs at once.
+type Clearer interface {
+	// Clear atomically deletes all the graphs available in the store.
+	Clear(ctx context.Context) error
+}
+
+// MetadataStore is an optional interface implemented by stores able to keep
+// values next to their graphs, such as the definitions of materialized views.
+// The values are opaque to the store, and are kept as provided.
+type MetadataStore interface {
+	// SetMetadata stores the value under the provided key, replacing the
+	// value previously stored under it, if any.
+	SetMetadata(ctx context.Context, key string, v interface{}) error
+
+	// Metadata returns the value stored under the provided key. Getting a non
+	// existing key should return an error wrapping ErrMetadataNotFound.
+	Metadata(ctx context.Context, key string) (interface{}, error)
+
+	// DeleteMetadata deletes the value stored under the provided key.
+	// Deleting a non existing key should return an error wrapping
+	// ErrMetadataNotFound.
+	DeleteMetadata(ctx context.Context, key string) error
+}
+
+// SortedNamer is an optional interface implemented by stores able to list
+// their graph names in lexical order.
+type SortedNamer interface {
+	// GraphNamesSorted returns the current available graph names in the store
+	// in lexical order.
+	//
+	// This is a blocking function. It will close the channel when all the
+	// names have been pushed.
+	GraphNamesSorted(ctx context.Context, names chan<- string) error
+}
+
+// GraphNamesSorted pushes to the provided channel the graph names available
+// in the provided store in lexical order, and closes the channel when done.
+// Stores implementing SortedNamer are queried directly; for any other store
+// all the names returned by GraphNames are collected and sorted before being
+// pushed.
+func GraphNamesSorted(ctx context.Context, s Store, names chan<- string) error {
+	if sn, ok := s.(SortedNamer); ok {
+		return sn.GraphNamesSorted(ctx, names)
+	}
+	if names == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(names)
+	all := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.GraphNames(ctx, all)
+	}()
+	var ns []string
+	for n := range all {
+		ns = append(ns, n)
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	sort.Strings(ns)
+	for _, n := range ns {
+		names <- n
+	}
+	return nil
+}
+
 // Graph interface describes the low level API that storage drivers need
 // to implement to provide a compliant graph storage that can be used with
 // BadWolf.
@@ -120,6 +272,13 @@ type Store interface {
 // If you are implementing a driver or just using a low lever driver directly
 // it is important for you to keep in mind that you will need to drain the
 // provided channel. Otherwise you run the risk of leaking go routines.
+//
+// Graphs must be safe for concurrent use. Lookups run concurrently with writes
+// publish a consistent view of the graph, either before or after each write,
+// and always close the provided channel once done. Readers may also modify the
+// graph while consuming the results of a lookup; the memory driver publishes a
+// snapshot taken when the lookup starts, so such changes are not reflected in
+// its results.
 type Graph interface {
 	// ID returns the id for this graph.
 	ID(ctx context.Context) string
@@ -132,6 +291,11 @@ type Graph interface {
 	// are not present on the store should not fail.
 	RemoveTriples(ctx context.Context, ts []*triple.Triple) error
 
+	// RemoveAllTriples removes all the triples from the storage, leaving the
+	// graph and its metadata in place. Drivers should implement it without
+	// enumerating and removing the triples one by one when possible.
+	RemoveAllTriples(ctx context.Context) error
+
 	// Objects pushes to the provided channel the objects for the given object and
 	// predicate. The function does not return immediately; it closes the channel before returning.
 	//
@@ -264,8 +428,412 @@ type Graph interface {
 	// Exist checks if the provided triple exists on the store.
 	Exist(ctx context.Context, t *triple.Triple) (bool, error)
 
+	// TriplesForSubjectPredicateObject returns the triple stored in the graph
+	// for the provided subject, predicate, and object, or nil if no such triple
+	// exists. Unlike Exist, the returned triple is the instance kept by the
+	// store, so callers can recover the stored predicate without a second
+	// lookup.
+	TriplesForSubjectPredicateObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) (*triple.Triple, error)
+
 	// Triples pushes to the provided channel all available triples in the graph.
 	// The function does not return immediately but spawns a goroutine to satisfy
 	// elements in the channel.
 	Triples(ctx context.Context, lo *LookupOptions, trpls chan<- *triple.Triple) error
+
+	// Version returns a counter that changes every time the contents of the
+	// graph are modified. Two calls returning the same version guarantee that
+	// the graph was not written in between. Versions should not be reused by a
+	// graph created with the same ID after the original one was deleted.
+	Version(ctx context.Context) (uint64, error)
+}
+
+// CountingRemover is an optional interface implemented by graphs able to
+// report how many triples a removal actually removed.
+type CountingRemover interface {
+	// RemoveTriplesCount removes the triples from the storage like
+	// RemoveTriples does, and returns how many of them were present.
+	RemoveTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error)
+}
+
+// RemoveTriples removes the triples from the provided graph and returns how
+// many of them were present. Graphs implementing CountingRemover report it
+// directly; for any other graph each triple is checked with Exist before
+// being removed, so concurrent writes to the graph may skew the count.
+func RemoveTriples(ctx context.Context, g Graph, ts []*triple.Triple) (int, error) {
+	if r, ok := g.(CountingRemover); ok {
+		return r.RemoveTriplesCount(ctx, ts)
+	}
+	cnt := 0
+	for _, t := range ts {
+		b, err := g.Exist(ctx, t)
+		if err != nil {
+			return 0, err
+		}
+		if b {
+			cnt++
+		}
+	}
+	if err := g.RemoveTriples(ctx, ts); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+// AbsentAdder is an optional interface implemented by graphs able to add only
+// the triples not already present, reporting how many were added.
+type AbsentAdder interface {
+	// AddTriplesIfAbsent adds the triples that are not already present in the
+	// storage, and returns how many of them were added. Repeated triples in the
+	// provided slice are only added once.
+	AddTriplesIfAbsent(ctx context.Context, ts []*triple.Triple) (int, error)
+}
+
+// AddTriplesIfAbsent adds to the provided graph the triples that are not
+// already present, and returns how many of them were added. Graphs
+// implementing AbsentAdder report it directly; for any other graph each triple
+// is checked with Exist before adding the absent ones, so concurrent writes to
+// the graph may skew the count.
+func AddTriplesIfAbsent(ctx context.Context, g Graph, ts []*triple.Triple) (int, error) {
+	if a, ok := g.(AbsentAdder); ok {
+		return a.AddTriplesIfAbsent(ctx, ts)
+	}
+	var absent []*triple.Triple
+	seen := make(map[string]bool)
+	for _, t := range ts {
+		id := t.UUID().String()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		b, err := g.Exist(ctx, t)
+		if err != nil {
+			return 0, err
+		}
+		if !b {
+			absent = append(absent, t)
+		}
+	}
+	if len(absent) == 0 {
+		return 0, nil
+	}
+	if err := g.AddTriples(ctx, absent); err != nil {
+		return 0, err
+	}
+	return len(absent), nil
+}
+
+// CountingAdder is an optional interface implemented by graphs able to report
+// how many triples an addition actually added.
+type CountingAdder interface {
+	// AddTriplesCount adds the triples to the storage like AddTriples does,
+	// and returns how many of them were not already present. Repeated triples
+	// in the provided slice are only counted once.
+	AddTriplesCount(ctx context.Context, ts []*triple.Triple) (int, error)
+}
+
+// AddTriples adds the triples to the provided graph and returns how many of
+// them were not already present. Graphs implementing CountingAdder report it
+// directly; for any other graph each triple is checked with Exist before
+// adding all of them, so concurrent writes to the graph may skew the count.
+func AddTriples(ctx context.Context, g Graph, ts []*triple.Triple) (int, error) {
+	if a, ok := g.(CountingAdder); ok {
+		return a.AddTriplesCount(ctx, ts)
+	}
+	cnt := 0
+	seen := make(map[string]bool)
+	for _, t := range ts {
+		id := t.UUID().String()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		b, err := g.Exist(ctx, t)
+		if err != nil {
+			return 0, err
+		}
+		if !b {
+			cnt++
+		}
+	}
+	if err := g.AddTriples(ctx, ts); err != nil {
+		return 0, err
+	}
+	return cnt, nil
+}
+
+// ObjectReplacer is an optional interface implemented by graphs able to
+// atomically replace the objects of single valued facts.
+type ObjectReplacer interface {
+	// ReplaceObject removes all the triples with the provided subject and a
+	// predicate with the same ID as the provided one, regardless of their
+	// time anchors, and adds the triple built out of the provided subject,
+	// predicate, and object. If that triple was already present it is kept.
+	// It returns the triples removed.
+	ReplaceObject(ctx context.Context, s *node.Node, p *predicate.Predicate, o *triple.Object) ([]*triple.Triple, error)
+}
+
+// ReplaceObject replaces in the provided graph all the triples with the
+// provided subject and a predicate with the same ID as the provided one by the
+// triple built out of the provided subject, predicate, and object, and returns
+// the triples removed. Graphs implementing ObjectReplacer replace them
+// atomically; for any other graph the triples are looked up with
+// TriplesForSubject before being removed, so concurrent writes to the graph
+// may interleave with the replacement.
+func ReplaceObject(ctx context.Context, g Graph, s *node.Node, p *predicate.Predicate, o *triple.Object) ([]*triple.Triple, error) {
+	if r, ok := g.(ObjectReplacer); ok {
+		return r.ReplaceObject(ctx, s, p, o)
+	}
+	t, err := triple.New(s, p, o)
+	if err != nil {
+		return nil, err
+	}
+	trpls := make(chan *triple.Triple)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- g.TriplesForSubject(ctx, s, DefaultLookup, trpls)
+	}()
+	var removed []*triple.Triple
+	tID := t.UUID().String()
+	for et := range trpls {
+		if et.Predicate().ID() == p.ID() && et.UUID().String() != tID {
+			removed = append(removed, et)
+		}
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	if len(removed) > 0 {
+		if err := g.RemoveTriples(ctx, removed); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.AddTriples(ctx, []*triple.Triple{t}); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// SubjectsBatcher is an optional interface implemented by graphs able to
+// retrieve the triples of several subjects in a single lookup.
+type SubjectsBatcher interface {
+	// TriplesForSubjects pushes to the provided channel all the triples
+	// available for any of the provided subjects. The lookup options apply to
+	// each subject as they would in TriplesForSubject. Repeated subjects are
+	// only looked up once.
+	//
+	// This is a blocking function. It will close the channel when all the
+	// triples have been pushed.
+	TriplesForSubjects(ctx context.Context, subjects []*node.Node, lo *LookupOptions, trpls chan<- *triple.Triple) error
+}
+
+// TriplesForSubjects pushes to the provided channel all the triples of the
+// provided graph available for any of the provided subjects, and closes the
+// channel when done. Graphs implementing SubjectsBatcher are queried in a
+// single lookup; any other graph is queried once per distinct subject using
+// TriplesForSubject.
+func TriplesForSubjects(ctx context.Context, g Graph, subjects []*node.Node, lo *LookupOptions, trpls chan<- *triple.Triple) error {
+	if b, ok := g.(SubjectsBatcher); ok {
+		return b.TriplesForSubjects(ctx, subjects, lo, trpls)
+	}
+	if trpls == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(trpls)
+	seen := make(map[string]bool, len(subjects))
+	for _, s := range subjects {
+		k := s.UUID().String()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		ts := make(chan *triple.Triple, cap(trpls))
+		errc := make(chan error, 1)
+		go func(s *node.Node) {
+			errc <- g.TriplesForSubject(ctx, s, lo, ts)
+		}(s)
+		for t := range ts {
+			trpls <- t
+		}
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DistinctObjecter is an optional interface implemented by graphs able to
+// deduplicate the objects of a subject and predicate while retrieving them.
+type DistinctObjecter interface {
+	// DistinctObjects pushes to the provided channel the objects for the
+	// provided subject and predicate like Objects does, but each object value
+	// is only pushed once, no matter how many time anchors of the predicate
+	// it appears under. MaxElements limits the number of distinct objects
+	// returned.
+	//
+	// This is a blocking function. It will close the channel when all the
+	// objects have been pushed.
+	DistinctObjects(ctx context.Context, s *node.Node, p *predicate.Predicate, lo *LookupOptions, objs chan<- *triple.Object) error
+}
+
+// DistinctObjects pushes to the provided channel the distinct objects for the
+// provided subject and predicate in the provided graph, and closes the channel
+// when done. Graphs implementing DistinctObjecter are queried directly; any
+// other graph is queried using Objects, deduplicating the objects returned.
+func DistinctObjects(ctx context.Context, g Graph, s *node.Node, p *predicate.Predicate, lo *LookupOptions, objs chan<- *triple.Object) error {
+	if d, ok := g.(DistinctObjecter); ok {
+		return d.DistinctObjects(ctx, s, p, lo, objs)
+	}
+	if objs == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(objs)
+	if lo == nil {
+		lo = DefaultLookup
+	}
+	// The limit applies to the distinct objects, not to the triples.
+	olo := *lo
+	olo.MaxElements, olo.Offset = 0, 0
+	os := make(chan *triple.Object, cap(objs))
+	errc := make(chan error, 1)
+	go func() {
+		errc <- g.Objects(ctx, s, p, &olo, os)
+	}()
+	seen := make(map[string]bool)
+	n := 0
+	for o := range os {
+		k := o.UUID().String()
+		if seen[k] || (lo.MaxElements > 0 && n >= lo.MaxElements) {
+			continue
+		}
+		seen[k] = true
+		n++
+		objs <- o
+	}
+	return <-errc
+}
+
+// PredicateAnchorer is an optional interface implemented by graphs able to list
+// the time anchors of the temporal predicates of a subject without retrieving
+// the triples.
+type PredicateAnchorer interface {
+	// PredicateAnchors pushes to the provided channel the distinct time
+	// anchors of the temporal predicates with the provided ID that have the
+	// provided node as subject, in ascending order. The lookup options
+	// constrain the anchors as they would in TriplesForSubject, and
+	// MaxElements limits the number of anchors returned.
+	//
+	// This is a blocking function. It will close the channel when all the
+	// anchors have been pushed.
+	PredicateAnchors(ctx context.Context, s *node.Node, pid string, lo *LookupOptions, anchors chan<- *time.Time) error
+}
+
+// PredicateAnchors pushes to the provided channel the distinct time anchors of
+// the temporal predicates with the provided ID that have the provided node as
+// subject in the provided graph, in ascending order, and closes the channel
+// when done. A nil lookup options uses DefaultLookup. Graphs implementing
+// PredicateAnchorer are queried directly; any other graph is queried using
+// PredicatesForSubject.
+func PredicateAnchors(ctx context.Context, g Graph, s *node.Node, pid string, lo *LookupOptions, anchors chan<- *time.Time) error {
+	if a, ok := g.(PredicateAnchorer); ok {
+		return a.PredicateAnchors(ctx, s, pid, lo, anchors)
+	}
+	if anchors == nil {
+		return fmt.Errorf("cannot provide an empty channel")
+	}
+	defer close(anchors)
+	if lo == nil {
+		lo = DefaultLookup
+	}
+	// The limit applies to the anchors, not to the predicates.
+	plo := *lo
+	plo.MaxElements, plo.Offset = 0, 0
+	prds := make(chan *predicate.Predicate, cap(anchors))
+	errc := make(chan error, 1)
+	go func() {
+		errc <- g.PredicatesForSubject(ctx, s, &plo, prds)
+	}()
+	var tas []*time.Time
+	seen := make(map[int64]bool)
+	for p := range prds {
+		if string(p.ID()) != pid || p.Type() != predicate.Temporal {
+			continue
+		}
+		ta, err := p.TimeAnchor()
+		if err != nil {
+			continue
+		}
+		if seen[ta.UnixNano()] {
+			continue
+		}
+		seen[ta.UnixNano()] = true
+		tas = append(tas, ta)
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	sort.Slice(tas, func(i, j int) bool {
+		return tas[i].Before(*tas[j])
+	})
+	for i, ta := range tas {
+		if lo.MaxElements > 0 && i >= lo.MaxElements {
+			break
+		}
+		anchors <- ta
+	}
+	return nil
+}
+
+// WalkFunc is called by Walk once per subject with all the triples of the
+// graph that have it as subject. Returning an error stops the walk.
+type WalkFunc func(s *node.Node, ts []*triple.Triple) error
+
+// Walker is an optional interface implemented by graphs able to iterate their
+// triples clustered by subject without regrouping them.
+type Walker interface {
+	// Walk calls fn once for each subject in the graph with all the triples
+	// that have it as subject. It stops at the first error returned by fn or
+	// when the context is done, and returns that error.
+	Walk(ctx context.Context, fn WalkFunc) error
+}
+
+// Walk calls fn once for each subject in the provided graph with all the
+// triples that have it as subject, in subject order. Graphs implementing
+// Walker are walked directly; any other graph is walked by grouping the
+// triples returned by Triples. It returns the first error returned by fn or
+// the context error if the context is done before the walk finishes.
+func Walk(ctx context.Context, g Graph, fn WalkFunc) error {
+	if w, ok := g.(Walker); ok {
+		return w.Walk(ctx, fn)
+	}
+	trpls := make(chan *triple.Triple)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- g.Triples(ctx, DefaultLookup, trpls)
+	}()
+	var (
+		sbjs []string
+		ns   = make(map[string]*node.Node)
+		ts   = make(map[string][]*triple.Triple)
+	)
+	for t := range trpls {
+		k := t.Subject().String()
+		if _, ok := ns[k]; !ok {
+			sbjs = append(sbjs, k)
+			ns[k] = t.Subject()
+		}
+		ts[k] = append(ts[k], t)
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	sort.Strings(sbjs)
+	for _, k := range sbjs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(ns[k], ts[k]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
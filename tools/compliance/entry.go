@@ -22,7 +22,6 @@ package compliance
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/triple/literal"
@@ -98,19 +97,19 @@ func (s *Story) Unmarshal(ss string) error {
 // inferCell builds a Cell out of the provided string.
 func inferCell(s string) *table.Cell {
 	if n, err := node.Parse(s); err == nil {
-		return &table.Cell{N: n}
+		return table.NewNodeCell(n)
 	}
 	if p, err := predicate.Parse(s); err == nil {
-		return &table.Cell{P: p}
+		return table.NewPredicateCell(p)
 	}
 	if l, err := literal.DefaultBuilder().Parse(s); err == nil {
-		return &table.Cell{L: l}
+		return table.NewLiteralCell(l)
 	}
-	t, err := time.Parse(time.RFC3339Nano, s)
+	t, err := predicate.ParseTimeAnchor(s, nil)
 	if err == nil {
-		return &table.Cell{T: &t}
+		return table.NewTimeCell(t)
 	}
-	return &table.Cell{S: table.CellString(s)}
+	return table.NewStringCell(s)
 }
 
 // OutputTable returns the expected result table for the must result table
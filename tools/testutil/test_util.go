@@ -48,7 +48,7 @@ func MustBuildNodeFromStrings(t *testing.T, nodeType, nodeID string) *node.Node
 // MustBuildTime builds a Time out of timeLiteral or makes the given test to fail.
 func MustBuildTime(t *testing.T, timeLiteral string) *time.Time {
 	t.Helper()
-	time, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(timeLiteral))
+	time, err := predicate.ParseTimeAnchor(strings.TrimSpace(timeLiteral), nil)
 	if err != nil {
 		t.Fatalf("could not parse time literal %q, got error: %v", timeLiteral, err)
 	}
@@ -100,17 +100,32 @@ func InitializeDriver(driverName string, drivers map[string]StoreGenerator) (sto
 	return f()
 }
 
+// Options contains the optional configuration of the command line tool.
+type Options struct {
+	// StreamInsertData makes the run, bql and server commands insert the data
+	// of INSERT statements in batches while parsing them, instead of holding
+	// the whole statement in memory. The batches inserted before a syntax error
+	// stay inserted.
+	StreamInsertData bool
+}
+
 // InitializeCommands initializes the available commands with the given storage
 // instance.
 func InitializeCommands(driver storage.Store, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner, done chan bool) []*command.Command {
+	return InitializeCommandsWithOptions(driver, chanSize, bulkTripleOpSize, builderSize, rl, done, Options{})
+}
+
+// InitializeCommandsWithOptions initializes the available commands with the
+// given storage instance and options.
+func InitializeCommandsWithOptions(driver storage.Store, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner, done chan bool, opts Options) []*command.Command {
 	return []*command.Command{
 		assert.New(driver, literal.DefaultBuilder(), chanSize, bulkTripleOpSize),
 		benchmark.New(driver, chanSize, bulkTripleOpSize),
 		export.New(driver, bulkTripleOpSize),
 		load.New(driver, bulkTripleOpSize, builderSize),
-		run.New(driver, chanSize, bulkTripleOpSize),
-		repl.New(driver, chanSize, bulkTripleOpSize, builderSize, rl, done),
-		server.New(driver, chanSize, bulkTripleOpSize),
+		run.NewWithStreaming(driver, chanSize, bulkTripleOpSize, opts.StreamInsertData),
+		repl.NewWithSession(driver, chanSize, bulkTripleOpSize, builderSize, rl, done, &Session{}, opts.StreamInsertData),
+		server.NewWithStreaming(driver, chanSize, bulkTripleOpSize, opts.StreamInsertData),
 		version.New(),
 	}
 }
@@ -142,10 +157,16 @@ func Eval(ctx context.Context, args []string, cmds []*command.Command) int {
 
 // Run executes the main of the command line tool.
 func Run(driverName string, args []string, drivers map[string]StoreGenerator, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner) int {
+	return RunWithOptions(driverName, args, drivers, chanSize, bulkTripleOpSize, builderSize, rl, Options{})
+}
+
+// RunWithOptions executes the main of the command line tool with the provided
+// options.
+func RunWithOptions(driverName string, args []string, drivers map[string]StoreGenerator, chanSize, bulkTripleOpSize, builderSize int, rl repl.ReadLiner, opts Options) int {
 	driver, err := InitializeDriver(driverName, drivers)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 2
 	}
-	return Eval(context.Background(), args, InitializeCommands(driver, chanSize, bulkTripleOpSize, builderSize, rl, make(chan bool)))
+	return Eval(context.Background(), args, InitializeCommandsWithOptions(driver, chanSize, bulkTripleOpSize, builderSize, rl, make(chan bool), opts))
 }
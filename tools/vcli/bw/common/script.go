@@ -0,0 +1,153 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/badwolf/bql/grammar"
+	"github.com/google/badwolf/bql/lexer"
+	"github.com/google/badwolf/bql/planner"
+	"github.com/google/badwolf/bql/semantic"
+	"github.com/google/badwolf/bql/table"
+	"github.com/google/badwolf/storage"
+)
+
+// ScriptOptions contains the options used to run a script.
+type ScriptOptions struct {
+	ChanSize int       // Size of the channels used to talk to the store.
+	BulkSize int       // Number of triples sent to the store on each bulk operation.
+	Tracer   io.Writer // Writer the statements are traced to, if any.
+}
+
+// StatementResult holds the outcome of running one statement of a script.
+type StatementResult struct {
+	Statement string       // The statement run.
+	Table     *table.Table // The table returned by the statement, if any.
+	Err       error        // The error returned by the statement, if any.
+}
+
+// RunScript runs the ;-separated statements of the script in order against
+// the provided store, stopping on the first one that fails. It returns the
+// results of all the statements run, the failing one included, and the error
+// of the failing statement. Statements are split with the BQL lexer, so
+// semicolons inside literals and comments do not end a statement. Options may
+// be nil to use the default ones.
+//
+// The statements run so far are not undone when one of them fails.
+func RunScript(ctx context.Context, st storage.Store, script string, opts *ScriptOptions) ([]*StatementResult, error) {
+	if opts == nil {
+		opts = &ScriptOptions{}
+	}
+	var res []*StatementResult
+	for i, stm := range splitStatements(script) {
+		tbl, err := runStatement(ctx, st, stm, opts)
+		res = append(res, &StatementResult{Statement: stm, Table: tbl, Err: err})
+		if err != nil {
+			return res, fmt.Errorf("statement %d %q failed; %v", i+1, stm, err)
+		}
+	}
+	return res, nil
+}
+
+// runStatement parses, plans and executes the provided statement.
+func runStatement(ctx context.Context, st storage.Store, stm string, opts *ScriptOptions) (*table.Table, error) {
+	p, err := grammar.NewParser(grammar.SemanticBQL())
+	if err != nil {
+		return nil, fmt.Errorf("NewParser failed; %v", err)
+	}
+	s := &semantic.Statement{}
+	if err := p.Parse(grammar.NewLLk(stm, 1), s); err != nil {
+		return nil, fmt.Errorf("NewLLk parser failed; %v", err)
+	}
+	pln, err := planner.New(ctx, st, s, opts.ChanSize, opts.BulkSize, opts.Tracer)
+	if err != nil {
+		return nil, fmt.Errorf("planner.New failed with error: %v", err)
+	}
+	tbl, err := pln.Execute(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("planner.Execute: failed to execute; %v", err)
+	}
+	return tbl, nil
+}
+
+// splitStatements splits the script into its statements, each one ending with
+// its semicolon. Empty statements are dropped. If the script cannot be lexed,
+// the text left from the first statement that failed is returned as its last
+// statement, so parsing it reports the error.
+func splitStatements(script string) []string {
+	var (
+		stms  []string
+		start int
+		cur   = &cursor{input: script, line: 1, col: 1}
+	)
+	add := func(stm string) {
+		if stm = strings.TrimSpace(stm); stm != "" && stm != ";" {
+			stms = append(stms, stm)
+		}
+	}
+	for tkn := range lexer.New(script, 0) {
+		if tkn.Type == lexer.ItemError {
+			break
+		}
+		if tkn.Type == lexer.ItemSemicolon {
+			end := cur.offset(tkn.Line, tkn.Col) + 1
+			add(script[start:end])
+			start = end
+		}
+	}
+	if strings.TrimSpace(script[start:]) != "" && hasTokens(script[start:]) {
+		add(script[start:])
+	}
+	return stms
+}
+
+// hasTokens returns true if the text holds anything besides blanks and
+// comments.
+func hasTokens(text string) bool {
+	found := false
+	for tkn := range lexer.New(text, 0) {
+		if tkn.Type != lexer.ItemEOF {
+			found = true
+		}
+	}
+	return found
+}
+
+// cursor converts the lines and columns of the tokens of its input into byte
+// offsets. Positions need to be requested in increasing order.
+type cursor struct {
+	input     string
+	off       int
+	line, col int
+}
+
+// offset returns the byte offset of the rune at the provided line and column,
+// both starting at 1.
+func (c *cursor) offset(line, col int) int {
+	for c.off < len(c.input) && (c.line != line || c.col != col) {
+		r, w := utf8.DecodeRuneInString(c.input[c.off:])
+		c.off += w
+		c.col++
+		if r == '\n' {
+			c.line, c.col = c.line+1, 1
+		}
+	}
+	return c.off
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/badwolf/storage"
+	"github.com/google/badwolf/storage/memory"
+	"github.com/google/badwolf/triple"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testTable := []struct {
+		script string
+		want   []string
+	}{
+		{
+			script: "",
+			want:   nil,
+		},
+		{
+			script: "CREATE GRAPH ?a;\n;  DROP GRAPH ?a;",
+			want:   []string{"CREATE GRAPH ?a;", "DROP GRAPH ?a;"},
+		},
+		{
+			script: `INSERT DATA INTO ?a {/u<joe> "says"@[] "a;b"^^type:text}; # A comment; with a semicolon.
+/* Another one; */ SELECT ?o FROM ?a WHERE {/u<joe> "says"@[] ?o};
+# Trailing comment;`,
+			want: []string{
+				`INSERT DATA INTO ?a {/u<joe> "says"@[] "a;b"^^type:text};`,
+				`# A comment; with a semicolon.
+/* Another one; */ SELECT ?o FROM ?a WHERE {/u<joe> "says"@[] ?o};`,
+			},
+		},
+		{
+			script: "CREATE GRAPH ?a;\nSELECT ?s FROM ?a",
+			want:   []string{"CREATE GRAPH ?a;", "SELECT ?s FROM ?a"},
+		},
+	}
+	for _, entry := range testTable {
+		if got := splitStatements(entry.script); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("splitStatements(%q) returned %q; want %q", entry.script, got, entry.want)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	const script = `CREATE GRAPH ?a;
+INSERT DATA INTO ?a {
+  /u<joe> "knows"@[] /u<mary> .
+  /u<joe> "says"@[] "hi; bye"^^type:text
+};
+# Statements may be commented out; they are not run.
+SELECT ?o FROM ?a WHERE {/u<joe> "knows"@[] ?o};
+SELECT ?o FROM ?a WHERE {/u<joe> "says"@[] ?o};`
+
+	ctx, s := context.Background(), memory.NewStore()
+	res, err := RunScript(ctx, s, script, nil)
+	if err != nil {
+		t.Fatalf("RunScript failed with error: %v", err)
+	}
+	if got, want := len(res), 4; got != want {
+		t.Fatalf("RunScript returned %d results; want %d", got, want)
+	}
+	for i, want := range map[int]string{2: "/u<mary>", 3: `"hi; bye"^^type:text`} {
+		tbl := res[i].Table
+		if tbl == nil || tbl.NumRows() != 1 {
+			t.Fatalf("RunScript returned %v for statement %q; want a single row", tbl, res[i].Statement)
+		}
+		if got := tbl.Rows()[0]["?o"].String(); got != want {
+			t.Errorf("RunScript returned %s for statement %q; want %s", got, res[i].Statement, want)
+		}
+	}
+}
+
+func TestRunScriptStopsOnError(t *testing.T) {
+	const script = `CREATE GRAPH ?a;
+CREATE GRAPH ?a;
+INSERT DATA INTO ?a {/u<joe> "knows"@[] /u<mary>};`
+
+	ctx, s := context.Background(), memory.NewStore()
+	res, err := RunScript(ctx, s, script, nil)
+	if err == nil {
+		t.Fatal("RunScript should have failed creating an existing graph")
+	}
+	if got, want := len(res), 2; got != want {
+		t.Fatalf("RunScript returned %d results; want %d", got, want)
+	}
+	if res[0].Err != nil || res[1].Err == nil {
+		t.Errorf("RunScript returned errors %v and %v; want only the second statement to fail", res[0].Err, res[1].Err)
+	}
+	g, err := s.Graph(ctx, "?a")
+	if err != nil {
+		t.Fatalf("store.Graph(_, %q) failed with error: %v", "?a", err)
+	}
+	ts := make(chan *triple.Triple)
+	go func() {
+		if err := g.Triples(ctx, storage.DefaultLookup, ts); err != nil {
+			t.Errorf("graph.Triples failed with error: %v", err)
+		}
+	}()
+	n := 0
+	for range ts {
+		n++
+	}
+	if n != 0 {
+		t.Errorf("RunScript ran the statements after the failing one; found %d triples, want 0", n)
+	}
+}
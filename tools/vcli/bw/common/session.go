@@ -0,0 +1,102 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/badwolf/bql/lexer"
+)
+
+// Session holds the state of an interactive session of the console. It keeps
+// the default graph set via USE, which is injected into the queries that do
+// not list the graphs to query via FROM.
+type Session struct {
+	graph string
+}
+
+// Graph returns the current default graph, or an empty string if none is set.
+func (s *Session) Graph() string {
+	return s.graph
+}
+
+// Handle runs the session commands. It supports
+//
+//	USE ?graph;
+//	UNSET GRAPH;
+//
+// It returns the message to report and true if the line was a session command,
+// or false if the line needs to be run as a regular statement.
+func (s *Session) Handle(line string) (string, bool) {
+	args := strings.Fields(strings.TrimSuffix(strings.TrimSpace(line), ";"))
+	if len(args) == 0 {
+		return "", false
+	}
+	switch {
+	case strings.EqualFold(args[0], "use"):
+		if len(args) != 2 || !isGraphBinding(args[1]) {
+			return "[ERROR] Invalid syntax.\n\tuse <graph_binding>", true
+		}
+		s.graph = args[1]
+		return fmt.Sprintf("[OK] Queries without FROM will use graph %s.", s.graph), true
+	case strings.EqualFold(args[0], "unset"):
+		if len(args) != 2 || !strings.EqualFold(args[1], "graph") {
+			return "[ERROR] Invalid syntax.\n\tunset graph", true
+		}
+		s.graph = ""
+		return "[OK] Default graph unset.", true
+	}
+	return "", false
+}
+
+// Rewrite returns the provided statement with the default graph injected if
+// the statement is a query without a FROM clause. Any other statement, or all
+// of them if no default graph is set, are returned unchanged. Statements that
+// cannot be lexed are also returned unchanged, so the parser can report the
+// error.
+func (s *Session) Rewrite(bql string) string {
+	if s.graph == "" {
+		return bql
+	}
+	query, from, pos := false, false, -1
+	for tkn := range lexer.New(bql, 0) {
+		switch tkn.Type {
+		case lexer.ItemError:
+			return bql
+		case lexer.ItemQuery:
+			query = true
+		case lexer.ItemFrom:
+			from = true
+		case lexer.ItemDeclare, lexer.ItemWhere:
+			if query && pos < 0 {
+				pos = (&cursor{input: bql, line: 1, col: 1}).offset(tkn.Line, tkn.Col)
+			}
+		}
+	}
+	if !query || from || pos < 0 {
+		return bql
+	}
+	return bql[:pos] + "FROM " + s.graph + " " + bql[pos:]
+}
+
+// isGraphBinding returns true if the provided text lexes as a single binding.
+func isGraphBinding(text string) bool {
+	var tkns []lexer.Token
+	for tkn := range lexer.New(text, 0) {
+		tkns = append(tkns, tkn)
+	}
+	return len(tkns) == 2 && tkns[0].Type == lexer.ItemBinding && tkns[1].Type == lexer.ItemEOF
+}
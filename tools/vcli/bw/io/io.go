@@ -24,7 +24,9 @@ import (
 
 // GetStatementsFromFile returns the statements found in the provided file.
 func GetStatementsFromFile(path string) ([]string, error) {
-	stms, err := ReadLines(path)
+	// Statement lines are kept apart so end of line comments do not swallow
+	// the rest of the statement.
+	stms, err := readLines(path, "\n")
 	if err != nil {
 		return nil, err
 	}
@@ -33,6 +35,12 @@ func GetStatementsFromFile(path string) ([]string, error) {
 
 // ReadLines from a file into a string array.
 func ReadLines(path string) ([]string, error) {
+	return readLines(path, " ")
+}
+
+// readLines from a file into a string array, joining the lines of each
+// statement using the provided separator.
+func readLines(path, sep string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -47,7 +55,7 @@ func ReadLines(path string) ([]string, error) {
 		if len(l) == 0 || strings.Index(l, "#") == 0 {
 			continue
 		}
-		line += " " + l
+		line += sep + l
 		if l[len(l)-1:] == ";" {
 			lines = append(lines, strings.TrimSpace(line))
 			line = ""
@@ -23,6 +23,7 @@ import (
 	"flag"
 	"os"
 
+	"github.com/google/badwolf/bql/table"
 	"github.com/google/badwolf/storage"
 	"github.com/google/badwolf/storage/memory"
 	"github.com/google/badwolf/tools/vcli/bw/common"
@@ -38,6 +39,8 @@ var (
 	bqlChannelSize        = flag.Int("bql_channel_size", 0, "Internal channel size to use on BQL queries.")
 	bulkTripleOpSize      = flag.Int("bulk_triple_op_size", 1000, "Number of triples to use in bulk load operations.")
 	bulkTripleBuilderSize = flag.Int("bulk_triple_builder_size_in_bytes", 1000, "Maximum size of literals when parsing a triple.")
+	streamInsertData      = flag.Bool("stream_insert_data", false, "Insert the data of INSERT statements in batches while parsing them; batches inserted before a syntax error stay inserted.")
+	sortSpillThreshold    = flag.Int("sort_spill_threshold_rows", 0, "Number of rows above which sorting spills to temporary files; 0 sorts in memory.")
 
 	// Add your driver flags below.
 )
@@ -54,6 +57,8 @@ func registerDrivers() {
 
 func main() {
 	flag.Parse()
+	table.SetSortSpillThreshold(*sortSpillThreshold)
 	registerDrivers()
-	os.Exit(common.Run(*driver, flag.Args(), registeredDrivers, *bqlChannelSize, *bulkTripleOpSize, *bulkTripleBuilderSize, repl.SimpleReadLine))
+	opts := common.Options{StreamInsertData: *streamInsertData}
+	os.Exit(common.RunWithOptions(*driver, flag.Args(), registeredDrivers, *bqlChannelSize, *bulkTripleOpSize, *bulkTripleBuilderSize, repl.SimpleReadLine, opts))
 }
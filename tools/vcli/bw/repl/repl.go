@@ -47,9 +47,16 @@ const prompt = "bql> "
 
 // New create the version command.
 func New(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner, done chan bool) *command.Command {
+	return NewWithSession(driver, chanSize, bulkSize, builderSize, rl, done, nil, false)
+}
+
+// NewWithSession creates the REPL command keeping its state in the provided
+// session. If stream is true, the data of INSERT statements is streamed to the
+// store while parsing them; see REPLWithSession.
+func NewWithSession(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner, done chan bool, sess Session, stream bool) *command.Command {
 	return &command.Command{
 		Run: func(ctx context.Context, args []string) int {
-			REPL(driver, os.Stdin, rl, chanSize, bulkSize, builderSize, done)
+			REPLWithSession(driver, os.Stdin, rl, chanSize, bulkSize, builderSize, done, sess, stream)
 			return 0
 		},
 		UsageLine: "bql",
@@ -58,6 +65,24 @@ func New(driver storage.Store, chanSize, bulkSize, builderSize int, rl ReadLiner
 	}
 }
 
+// Session runs the commands that change the state of the REPL session, and
+// rewrites the statements to run according to that state.
+type Session interface {
+	// Handle runs the line if it is a session command, returning the message
+	// to report and true. It returns false for any other line.
+	Handle(line string) (string, bool)
+	// Rewrite returns the statement to run in place of the provided one.
+	Rewrite(bql string) string
+}
+
+// noSession is the session used when none is provided. It handles no commands
+// and leaves the statements unchanged.
+type noSession struct{}
+
+func (noSession) Handle(string) (string, bool) { return "", false }
+
+func (noSession) Rewrite(bql string) string { return bql }
+
 // ReadLiner returns a channel with the imput to be used for the REPL.
 type ReadLiner func(done chan bool) <-chan string
 
@@ -129,9 +154,21 @@ func stopProfiling(cpuProfile, memProfile *os.File) {
 
 // REPL starts a read-evaluation-print-loop to run BQL commands.
 func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, builderSize int, done chan bool) int {
+	return REPLWithSession(od, input, rl, chanSize, bulkSize, builderSize, done, nil, false)
+}
+
+// REPLWithSession starts a read-evaluation-print-loop to run BQL commands,
+// keeping its state in the provided session. If stream is true, the data of
+// INSERT statements is inserted in batches of bulkSize triples while they are
+// parsed, so the batches inserted before a syntax error stay inserted.
+func REPLWithSession(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, builderSize int, done chan bool, sess Session, stream bool) int {
+	if sess == nil {
+		sess = noSession{}
+	}
 	var traceWriter io.Writer
 	ctx, isTracingToFile, isProfiling, sessionStart := context.Background(), false, false, time.Now()
 	var cpuProfile, memProfile *os.File
+	var outputOrder []string
 
 	driverPlain := func() storage.Store {
 		return od
@@ -255,6 +292,21 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 			done <- false
 			continue
 		}
+		if msg, ok := sess.Handle(l); ok {
+			fmt.Println(msg)
+			done <- false
+			continue
+		}
+		if strings.HasPrefix(l, "output order") {
+			outputOrder = parseOutputOrder(l)
+			if len(outputOrder) == 0 {
+				fmt.Println("[OK] Output columns follow the projection order.")
+			} else {
+				fmt.Printf("[OK] Output columns pinned to %s.\n", strings.Join(outputOrder, ", "))
+			}
+			done <- false
+			continue
+		}
 		if strings.HasPrefix(l, "stop tracing") {
 			stopTracing()
 			fmt.Println("Tracing is off.")
@@ -335,7 +387,7 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 			continue
 		}
 		if strings.HasPrefix(l, "desc") {
-			pln, err := planBQL(ctx, l[4:], driver(), chanSize, bulkSize, nil)
+			pln, err := planBQL(ctx, sess.Rewrite(l[4:]), driver(), chanSize, bulkSize, true, false, nil)
 			if err != nil {
 				fmt.Printf("[ERROR] %s\n\n", err)
 			} else {
@@ -349,7 +401,7 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 		}
 		if strings.HasPrefix(l, "run") {
 			now := time.Now()
-			path, cmds, err := runBQLFromFile(ctx, driver(), chanSize, bulkSize, strings.TrimSpace(l[:len(l)-1]), traceWriter)
+			path, cmds, err := runBQLFromFile(ctx, driver(), chanSize, bulkSize, stream, strings.TrimSpace(l[:len(l)-1]), sess, traceWriter)
 			if err != nil {
 				fmt.Printf("[ERROR] %s\n\n", err)
 			} else {
@@ -361,7 +413,7 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 		}
 
 		now := time.Now()
-		table, err := runBQL(ctx, l, driver(), chanSize, bulkSize, traceWriter)
+		table, err := runBQL(ctx, sess.Rewrite(l), driver(), chanSize, bulkSize, stream, traceWriter)
 		bqlDiff := time.Now().Sub(now)
 		if err != nil {
 			fmt.Printf("[ERROR] %s\n", err)
@@ -373,7 +425,13 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 					bqlDiff, time.Now().Sub(now)-bqlDiff)
 			} else {
 				if len(table.Bindings()) > 0 {
-					fmt.Println(table.String())
+					if err := reorderOutput(table, outputOrder); err != nil {
+						fmt.Printf("[ERROR] %s\n", err)
+					}
+					if err := table.WriteText(os.Stdout, "\t"); err != nil {
+						fmt.Printf("[ERROR] %s\n", err)
+					}
+					fmt.Println()
 				}
 				fmt.Printf("[OK] %d rows retrieved. BQL time: %v. Display time: %v\n",
 					table.NumRows(), bqlDiff, time.Now().Sub(now)-bqlDiff)
@@ -384,6 +442,32 @@ func REPL(od storage.Store, input *os.File, rl ReadLiner, chanSize, bulkSize, bu
 	return 0
 }
 
+// parseOutputOrder returns the bindings listed in an output order command. An
+// empty list restores the projection order.
+func parseOutputOrder(l string) []string {
+	args := strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(l, "output order")), ";")
+	var bs []string
+	for _, b := range strings.Split(args, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			bs = append(bs, b)
+		}
+	}
+	return bs
+}
+
+// reorderOutput pins the columns of the table to the provided order. Pinned
+// bindings not present in the table are ignored, so the same order can be
+// kept across queries projecting different bindings.
+func reorderOutput(tbl *table.Table, order []string) error {
+	var bs []string
+	for _, b := range order {
+		if tbl.HasBinding(b) {
+			bs = append(bs, b)
+		}
+	}
+	return tbl.ReorderBindings(bs)
+}
+
 // printHelp prints help for the console commands.
 func printHelp() {
 	fmt.Println()
@@ -394,6 +478,9 @@ func printHelp() {
 	fmt.Println("desc <BQL>                                            - prints the execution plan for a BQL statement.")
 	fmt.Println("load <file_path> <graph_names_separated_by_commas>    - load triples into the specified graphs.")
 	fmt.Println("run <file_with_bql_statements>                        - runs all the BQL statements in the file.")
+	fmt.Println("output order [bindings_separated_by_commas]           - pins the order of the output columns, or restores the projection order if empty.")
+	fmt.Println("use <graph_binding>                                   - sets the graph queried by the following queries without FROM.")
+	fmt.Println("unset graph                                           - clears the graph set via use.")
 	fmt.Println("start tracing [-v verbosity_level] [trace_file]       - starts tracing queries, verbosity levels supported are 1, 2 and 3 (with 3 meaning maximum verbosity).")
 	fmt.Println("stop tracing                                          - stops tracing queries.")
 	fmt.Println("start profiling [-cpurate samples_per_second]         - starts pprof profiling for queries (customizable CPU sampling rate).")
@@ -403,7 +490,7 @@ func printHelp() {
 }
 
 // runBQLFromFile loads all the statements in the file and runs them.
-func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize, bulkSize int, line string, w io.Writer) (string, int, error) {
+func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize, bulkSize int, stream bool, line string, sess Session, w io.Writer) (string, int, error) {
 	ss := strings.Split(strings.TrimSpace(line), " ")
 	if len(ss) != 2 {
 		return "", 0, fmt.Errorf("wrong syntax: run <file_with_bql_statements>")
@@ -426,7 +513,7 @@ func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize, bulkSiz
 	}
 	for idx, stm := range lines {
 		fmt.Printf("Processing statement (%d/%d)\n", idx+1, len(lines))
-		_, err := runBQL(ctx, stm, driver, chanSize, bulkSize, w)
+		_, err := runBQL(ctx, sess.Rewrite(stm), driver, chanSize, bulkSize, stream, w)
 		if err != nil {
 			msg := fmt.Errorf("%q; %v", stm, err)
 			tracer.V(1).Trace(w, func() *tracer.Arguments {
@@ -442,13 +529,13 @@ func runBQLFromFile(ctx context.Context, driver storage.Store, chanSize, bulkSiz
 }
 
 // runBQL attempts to execute the provided query against the given store.
-func runBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, w io.Writer) (*table.Table, error) {
+func runBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, stream bool, w io.Writer) (*table.Table, error) {
 	tracer.V(1).Trace(w, func() *tracer.Arguments {
 		return &tracer.Arguments{
 			Msgs: []string{fmt.Sprintf("Executing query: %s", bql)},
 		}
 	})
-	pln, err := planBQL(ctx, bql, s, chanSize, bulkSize, w)
+	pln, err := planBQL(ctx, bql, s, chanSize, bulkSize, false, stream, w)
 	if err != nil {
 		return nil, err
 	}
@@ -475,7 +562,9 @@ func runBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize
 }
 
 // planBQL attempts to create the execution plan for the provided query against the given store.
-func planBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, w io.Writer) (planner.Executor, error) {
+// If stream is true, the data of INSERT statements to be executed is streamed to the store in
+// batches of bulkSize triples while parsing; explained statements are only planned.
+func planBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, explain, stream bool, w io.Writer) (planner.Executor, error) {
 	bql = strings.TrimSpace(bql)
 	if bql == ";" {
 		tracer.V(1).Trace(w, func() *tracer.Arguments {
@@ -496,8 +585,18 @@ func planBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSiz
 		return nil, msg
 	}
 	stm := &semantic.Statement{}
+	if explain {
+		stm.SetExplain()
+	} else if stream {
+		if err := planner.StreamInsertData(ctx, s, stm, bulkSize, w); err != nil {
+			return nil, err
+		}
+	}
 	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
 		msg := fmt.Errorf("NewLLk parser failed; %v", err)
+		if n := stm.StreamedData(); n > 0 {
+			msg = fmt.Errorf("NewLLk parser failed after inserting %d streamed triples; %v", n, err)
+		}
 		tracer.V(1).Trace(w, func() *tracer.Arguments {
 			return &tracer.Arguments{
 				Msgs: []string{msg.Error()},
@@ -505,6 +604,11 @@ func planBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSiz
 		})
 		return nil, msg
 	}
+	if stm.TraceLevel() > 0 && w == nil {
+		// Statements wrapped in a TRACE clause are traced to the console
+		// when tracing is not already on.
+		w = os.Stdout
+	}
 	pln, err := planner.New(ctx, s, stm, chanSize, bulkSize, w)
 	if err != nil {
 		msg := fmt.Errorf("planner.New failed with error: %v", err)
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/google/badwolf/bql/grammar"
@@ -33,6 +34,13 @@ import (
 
 // New creates the help command.
 func New(store storage.Store, chanSize, bulkSize int) *command.Command {
+	return NewWithStreaming(store, chanSize, bulkSize, false)
+}
+
+// NewWithStreaming creates the run command. If stream is true, the data of
+// INSERT statements is inserted in batches of bulkSize triples while they are
+// parsed, so the batches inserted before a syntax error stay inserted.
+func NewWithStreaming(store storage.Store, chanSize, bulkSize int, stream bool) *command.Command {
 	cmd := &command.Command{
 		UsageLine: "run file_path",
 		Short:     "runs BQL statements.",
@@ -42,13 +50,13 @@ sequentially.
 `,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
-		return runCommand(ctx, cmd, args, store, chanSize, bulkSize)
+		return runCommand(ctx, cmd, args, store, chanSize, bulkSize, stream)
 	}
 	return cmd
 }
 
 // runCommand runs all the BQL statements available in the file.
-func runCommand(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int) int {
+func runCommand(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int, stream bool) int {
 	if len(args) < 2 {
 		log.Printf("[ERROR] Missing required file path. ")
 		cmd.Usage()
@@ -63,14 +71,18 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 	fmt.Printf("Processing file %s\n\n", args[len(args)-1])
 	for idx, stm := range lines {
 		fmt.Printf("Processing statement (%d/%d):\n%s\n\n", idx+1, len(lines), stm)
-		tbl, err := BQL(ctx, stm, store, chanSize, bulkSize)
+		tbl, err := runBQL(ctx, stm, store, chanSize, bulkSize, stream)
 		if err != nil {
 			fmt.Printf("[FAIL] %v\n\n", err)
 			continue
 		}
 		fmt.Println("Result:")
 		if tbl.NumRows() > 0 {
-			fmt.Println(tbl)
+			if err := tbl.WriteText(os.Stdout, "\t"); err != nil {
+				fmt.Printf("[FAIL] %v\n\n", err)
+				continue
+			}
+			fmt.Println()
 		}
 		fmt.Printf("OK\n\n")
 	}
@@ -79,12 +91,26 @@ func runCommand(ctx context.Context, cmd *command.Command, args []string, store
 
 // BQL attempts to execute the provided query against the given store.
 func BQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int) (*table.Table, error) {
+	return runBQL(ctx, bql, s, chanSize, bulkSize, false)
+}
+
+// runBQL attempts to execute the provided query against the given store,
+// streaming the data of INSERT statements while parsing if requested.
+func runBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, stream bool) (*table.Table, error) {
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to initilize a valid BQL parser")
 	}
 	stm := &semantic.Statement{}
+	if stream {
+		if err := planner.StreamInsertData(ctx, s, stm, bulkSize, nil); err != nil {
+			return nil, fmt.Errorf("[ERROR] Failed to stream BQL statement data with error %v", err)
+		}
+	}
 	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
+		if n := stm.StreamedData(); n > 0 {
+			return nil, fmt.Errorf("[ERROR] Failed to parse BQL statement after inserting %d streamed triples with error %v", n, err)
+		}
 		return nil, fmt.Errorf("[ERROR] Failed to parse BQL statement with error %v", err)
 	}
 	pln, err := planner.New(ctx, s, stm, chanSize, bulkSize, nil)
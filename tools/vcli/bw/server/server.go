@@ -37,14 +37,23 @@ import (
 
 // New creates the help command.
 func New(store storage.Store, chanSize, bulkSize int) *command.Command {
+	return NewWithStreaming(store, chanSize, bulkSize, false)
+}
+
+// NewWithStreaming creates the server command. If stream is true, the data of
+// INSERT statements is inserted in batches of bulkSize triples while they are
+// parsed, so the batches inserted before a syntax error stay inserted.
+func NewWithStreaming(store storage.Store, chanSize, bulkSize int, stream bool) *command.Command {
 	cmd := &command.Command{
 		UsageLine: "server port",
 		Short:     "runs a BQL endoint.",
 		Long: `Runs a BQL endpoint with the provided driver. It allows running
-all BQL queries and returns a JSON table with the results.`,
+all BQL queries and returns a JSON table with the results. The running
+queries are listed as JSON by the /queries endpoint, and can be cancelled
+using their ID with a KILL QUERY "<id>" statement.`,
 	}
 	cmd.Run = func(ctx context.Context, args []string) int {
-		return runServer(ctx, cmd, args, store, chanSize, bulkSize)
+		return runServer(ctx, cmd, args, store, chanSize, bulkSize, stream)
 	}
 	return cmd
 }
@@ -54,10 +63,12 @@ type serverConfig struct {
 	store    storage.Store
 	chanSize int
 	bulkSize int
+	stream   bool
+	registry *planner.Registry
 }
 
 // runServer runs the simple BQL endpoint.
-func runServer(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int) int {
+func runServer(ctx context.Context, cmd *command.Command, args []string, store storage.Store, chanSize, bulkSize int, stream bool) int {
 	// Check parameters.
 	if len(args) < 2 {
 		log.Printf("[%v] Missing required port number. ", time.Now())
@@ -79,8 +90,11 @@ func runServer(ctx context.Context, cmd *command.Command, args []string, store s
 		store:    store,
 		chanSize: chanSize,
 		bulkSize: bulkSize,
+		stream:   stream,
+		registry: planner.NewRegistry(),
 	}
 	http.HandleFunc("/bql", s.bqlHandler)
+	http.HandleFunc("/queries", s.queriesHandler)
 	http.HandleFunc("/", defaultHandler)
 	if err := http.ListenAndServe(":"+p, nil); err != nil {
 		log.Printf("[%v] Failed to start server on port %s; %v", time.Now(), p, err)
@@ -122,7 +136,7 @@ func (s *serverConfig) bqlHandler(w http.ResponseWriter, r *http.Request) {
 		if nq, err := url.QueryUnescape(q); err == nil {
 			q = strings.Replace(strings.Replace(nq, "\n", " ", -1), "\r", " ", -1)
 		}
-		t, err := BQL(ctx, q, s.store, s.chanSize, s.bulkSize)
+		t, err := runBQL(ctx, q, s.store, s.chanSize, s.bulkSize, &planner.Options{Registry: s.registry}, s.stream)
 		r := &result{
 			Q: q,
 			T: t,
@@ -160,6 +174,19 @@ func (s *serverConfig) bqlHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// queriesHandler lists the queries being run as a JSON array.
+func (s *serverConfig) queriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`[`))
+	for i, q := range s.registry.Running() {
+		if i > 0 {
+			w.Write([]byte(`, `))
+		}
+		fmt.Fprintf(w, `{ "id": %q, "type": %q, "started": %q }`, q.ID, q.Type, q.Started.Format(time.RFC3339Nano))
+	}
+	w.Write([]byte(`]`))
+}
+
 // result contains a query and its outcome.
 type result struct {
 	Q   string       `json:"q,omitempty"`
@@ -184,15 +211,35 @@ func getQueries(raw []string) []string {
 
 // BQL attempts to execute the provided query against the given store.
 func BQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int) (*table.Table, error) {
+	return BQLWithOptions(ctx, bql, s, chanSize, bulkSize, nil)
+}
+
+// BQLWithOptions works like BQL, but plans the query with the provided
+// planner options.
+func BQLWithOptions(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, opts *planner.Options) (*table.Table, error) {
+	return runBQL(ctx, bql, s, chanSize, bulkSize, opts, false)
+}
+
+// runBQL works like BQLWithOptions, streaming the data of INSERT statements
+// while parsing if requested.
+func runBQL(ctx context.Context, bql string, s storage.Store, chanSize, bulkSize int, opts *planner.Options, stream bool) (*table.Table, error) {
 	p, err := grammar.NewParser(grammar.SemanticBQL())
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Failed to initilize a valid BQL parser")
 	}
 	stm := &semantic.Statement{}
+	if stream {
+		if err := planner.StreamInsertData(ctx, s, stm, bulkSize, nil); err != nil {
+			return nil, fmt.Errorf("[ERROR] Failed to stream BQL statement data with error %v", err)
+		}
+	}
 	if err := p.Parse(grammar.NewLLk(bql, 1), stm); err != nil {
+		if n := stm.StreamedData(); n > 0 {
+			return nil, fmt.Errorf("[ERROR] Failed to parse BQL statement after inserting %d streamed triples with error %v", n, err)
+		}
 		return nil, fmt.Errorf("[ERROR] Failed to parse BQL statement with error %v", err)
 	}
-	pln, err := planner.New(ctx, s, stm, chanSize, bulkSize, nil)
+	pln, err := planner.NewWithOptions(ctx, s, stm, chanSize, bulkSize, nil, opts)
 	if err != nil {
 		return nil, fmt.Errorf("[ERROR] Should have not failed to create a plan using memory.DefaultStorage for statement %v with error %v", stm, err)
 	}
@@ -0,0 +1,180 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TypeDefinition describes a custom literal type that can be registered in a
+// builder via NewBuilderWithTypes. Custom literals are text encoded as
+// "<value>"^^type:<name>.
+type TypeDefinition struct {
+	// Name is the name used to refer to the type in text encoded literals. It
+	// must be formed only by lower case letters and digits, and cannot be the
+	// name of a built-in type.
+	Name string
+
+	// Parse converts the text encoded value into the value held by the
+	// literal.
+	Parse func(s string) (interface{}, error)
+
+	// Format returns the text encoding of a value returned by Parse. It must
+	// round trip through Parse.
+	Format func(v interface{}) string
+
+	// Compare, if not nil, returns a negative number, zero, or a positive number
+	// if the first value is less than, equal to, or greater than the second
+	// one. Values of types without Compare cannot be ordered.
+	Compare func(a, b interface{}) int
+}
+
+// customBuilder implements a literal builder that also handles registered
+// custom types.
+type customBuilder struct {
+	types map[string]*TypeDefinition
+}
+
+// NewBuilderWithTypes creates a builder that, in addition to the built-in
+// types, parses the provided custom types. Literals of unknown types still
+// fail to parse.
+func NewBuilderWithTypes(defs ...*TypeDefinition) (Builder, error) {
+	b := &customBuilder{types: make(map[string]*TypeDefinition, len(defs))}
+	for _, d := range defs {
+		if d == nil || d.Parse == nil || d.Format == nil {
+			return nil, fmt.Errorf("literal.NewBuilderWithTypes: custom types require a Parse and a Format function")
+		}
+		if !validTypeName(d.Name) {
+			return nil, fmt.Errorf("literal.NewBuilderWithTypes: invalid custom type name %q", d.Name)
+		}
+		if _, err := ParseType(d.Name); err == nil {
+			return nil, fmt.Errorf("literal.NewBuilderWithTypes: custom type %q cannot redefine a built-in type", d.Name)
+		}
+		if _, ok := b.types[d.Name]; ok {
+			return nil, fmt.Errorf("literal.NewBuilderWithTypes: custom type %q registered twice", d.Name)
+		}
+		b.types[d.Name] = d
+	}
+	return b, nil
+}
+
+// validTypeName returns true if the name is only formed by lower case letters
+// and digits, and starts with a letter.
+func validTypeName(n string) bool {
+	if n == "" || n[0] < 'a' || n[0] > 'z' {
+		return false
+	}
+	for _, r := range n {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Build creates a new literal of a built-in type. Custom literals can only be
+// created by parsing them.
+func (b *customBuilder) Build(t Type, v interface{}) (*Literal, error) {
+	if t == Custom {
+		return nil, fmt.Errorf("literal.Build: custom literals can only be created via Parse")
+	}
+	return defaultBuilder.Build(t, v)
+}
+
+// Parse creates a literal out of its text encoding, handling the registered
+// custom types.
+func (b *customBuilder) Parse(s string) (*Literal, error) {
+	raw := strings.TrimSpace(s)
+	if strings.HasPrefix(raw, "\"") && strings.HasSuffix(raw, listSuffix) {
+		return parseList(b, raw)
+	}
+	if idx := strings.LastIndex(raw, "\"^^type:"); idx > 0 && raw[0] == '"' {
+		if d, ok := b.types[raw[idx+len("\"^^type:"):]]; ok {
+			v, err := d.Parse(raw[1:idx])
+			if err != nil {
+				return nil, fmt.Errorf("literal.Parse: could not convert value %q to %s: %v", raw[1:idx], d.Name, err)
+			}
+			return &Literal{
+				t:   Custom,
+				v:   v,
+				def: d,
+			}, nil
+		}
+	}
+	return defaultBuilder.Parse(s)
+}
+
+// TypeName returns the name of the type of the literal. For custom literals it
+// is the name the type was registered with.
+func (l *Literal) TypeName() string {
+	if l.t == Custom {
+		return l.def.Name
+	}
+	return l.t.String()
+}
+
+// Compare compares the literal with the provided one. It returns a negative
+// number, zero, or a positive number if the literal is less than, equal to, or
+// greater than the provided one. Int64 and float64 literals are compared by
+// value, with NaN greater than any other float64, and the rest of built-in
+// literals by their comparable strings. Custom
+// literals can only be compared with literals of the same custom type if it
+// provides a Compare function.
+func (l *Literal) Compare(o *Literal) (int, error) {
+	if l.TypeName() != o.TypeName() {
+		return 0, fmt.Errorf("literal.Compare: cannot compare literals of types %s and %s", l.TypeName(), o.TypeName())
+	}
+	switch l.t {
+	case Int64:
+		a, b := l.v.(int64), o.v.(int64)
+		if a < b {
+			return -1, nil
+		}
+		if a > b {
+			return 1, nil
+		}
+		return 0, nil
+	case Float64:
+		a, b := l.v.(float64), o.v.(float64)
+		// NaN values are greater than any other, so they sort last.
+		if an, bn := math.IsNaN(a), math.IsNaN(b); an || bn {
+			switch {
+			case an && bn:
+				return 0, nil
+			case an:
+				return 1, nil
+			default:
+				return -1, nil
+			}
+		}
+		if a < b {
+			return -1, nil
+		}
+		if a > b {
+			return 1, nil
+		}
+		return 0, nil
+	case Custom:
+		// Custom types provide their own comparison.
+	default:
+		return strings.Compare(l.ToComparableString(), o.ToComparableString()), nil
+	}
+	if l.def.Compare == nil {
+		return 0, fmt.Errorf("literal.Compare: custom type %s does not support comparisons", l.def.Name)
+	}
+	return l.def.Compare(l.v, o.v), nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package literal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// geoPoint is a simple custom type encoded as "<latitude>,<longitude>".
+type geoPoint struct {
+	lat, lng float64
+}
+
+var geoPointType = &TypeDefinition{
+	Name: "geopoint",
+	Parse: func(s string) (interface{}, error) {
+		ps := strings.Split(s, ",")
+		if len(ps) != 2 {
+			return nil, fmt.Errorf("geopoints require a latitude and a longitude; found %q", s)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(ps[0]), 64)
+		if err != nil {
+			return nil, err
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(ps[1]), 64)
+		if err != nil {
+			return nil, err
+		}
+		return geoPoint{lat: lat, lng: lng}, nil
+	},
+	Format: func(v interface{}) string {
+		p := v.(geoPoint)
+		return fmt.Sprintf("%g,%g", p.lat, p.lng)
+	},
+	Compare: func(a, b interface{}) int {
+		pa, pb := a.(geoPoint), b.(geoPoint)
+		switch {
+		case pa.lat < pb.lat:
+			return -1
+		case pa.lat > pb.lat:
+			return 1
+		default:
+			return 0
+		}
+	},
+}
+
+func TestNewBuilderWithTypes(t *testing.T) {
+	b, err := NewBuilderWithTypes(geoPointType)
+	if err != nil {
+		t.Fatalf("NewBuilderWithTypes failed with error %v", err)
+	}
+	l, err := b.Parse(`"41.39, 2.17"^^type:geopoint`)
+	if err != nil {
+		t.Fatalf("Parse failed to parse a custom literal with error %v", err)
+	}
+	if got, want := l.Type(), Custom; got != want {
+		t.Errorf("Type returned %s; want %s", got, want)
+	}
+	if got, want := l.TypeName(), "geopoint"; got != want {
+		t.Errorf("TypeName returned %q; want %q", got, want)
+	}
+	if got, want := l.Interface(), (geoPoint{lat: 41.39, lng: 2.17}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Interface returned %v; want %v", got, want)
+	}
+	if got, want := l.String(), `"41.39,2.17"^^type:geopoint`; got != want {
+		t.Errorf("String returned %q; want %q", got, want)
+	}
+	rl, err := b.Parse(l.String())
+	if err != nil {
+		t.Fatalf("Parse failed to round trip %s with error %v", l, err)
+	}
+	if !reflect.DeepEqual(rl.UUID(), l.UUID()) {
+		t.Errorf("round tripped literal %s should have the same UUID as %s", rl, l)
+	}
+	txt, err := b.Build(Text, "41.39,2.17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(txt.UUID(), l.UUID()) {
+		t.Errorf("literals %s and %s should have different UUIDs", txt, l)
+	}
+
+	// Custom literals can be compared with literals of the same type.
+	south, err := b.Parse(`"-33.87,151.21"^^type:geopoint`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := south.Compare(l); err != nil || c >= 0 {
+		t.Errorf("Compare(%s, %s) returned %d, %v; want a negative number", south, l, c, err)
+	}
+	if _, err := l.Compare(txt); err == nil {
+		t.Errorf("Compare(%s, %s) should have failed for different types", l, txt)
+	}
+
+	// Custom literals can be list elements.
+	ls, err := b.Parse(`"["41.39,2.17"^^type:geopoint, "1"^^type:int64]"^^type:list`)
+	if err != nil {
+		t.Fatalf("Parse failed to parse a list with custom elements with error %v", err)
+	}
+	if es, _ := ls.List(); len(es) != 2 || es[0].TypeName() != "geopoint" {
+		t.Errorf("Parse returned the wrong list elements %s", ls)
+	}
+
+	// Built-in types are still supported while unknown ones and invalid values
+	// fail.
+	if _, err := b.Parse(`"1"^^type:int64`); err != nil {
+		t.Errorf("Parse failed to parse a built-in literal with error %v", err)
+	}
+	for _, s := range []string{
+		`"41.39,2.17"^^type:geohash`,
+		`"41.39"^^type:geopoint`,
+	} {
+		if got, err := b.Parse(s); err == nil {
+			t.Errorf("Parse(%q) should have failed; got %s", s, got)
+		}
+	}
+	if got, err := DefaultBuilder().Parse(`"41.39,2.17"^^type:geopoint`); err == nil {
+		t.Errorf("the default builder should have failed to parse a custom type; got %s", got)
+	}
+	if got, err := b.Build(Custom, geoPoint{}); err == nil {
+		t.Errorf("Build should have failed to build a custom literal; got %s", got)
+	}
+}
+
+func TestNewBuilderWithTypesErrors(t *testing.T) {
+	for _, defs := range [][]*TypeDefinition{
+		{nil},
+		{{Name: "geopoint"}},
+		{{Name: "Geo Point", Parse: geoPointType.Parse, Format: geoPointType.Format}},
+		{{Name: "int64", Parse: geoPointType.Parse, Format: geoPointType.Format}},
+		{geoPointType, geoPointType},
+	} {
+		if _, err := NewBuilderWithTypes(defs...); err == nil {
+			t.Errorf("NewBuilderWithTypes(%v) should have failed", defs)
+		}
+	}
+}
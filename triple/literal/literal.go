@@ -30,6 +30,10 @@ import (
 // bufPool keeps a pool of bytes.Buffer for the UUID() method.
 var bufPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
 
+// langNamespace is the namespace of the UUIDs of language tagged text
+// literals, keeping them apart from the UUIDs of untagged ones.
+var langNamespace = uuid.NewSHA1(uuid.NIL, []byte("lang"))
+
 // Type represents the type contained in a literal.
 type Type uint8
 
@@ -44,6 +48,12 @@ const (
 	Text
 	// Blob indicates that the type contained in the literal is a []byte.
 	Blob
+	// List indicates that the type contained in the literal is a []*Literal.
+	// Lists cannot contain other lists.
+	List
+	// Custom indicates that the literal contains a value of a custom type
+	// registered via NewBuilderWithTypes.
+	Custom
 )
 
 // Strings returns the pretty printing version of the type
@@ -59,15 +69,31 @@ func (t Type) String() string {
 		return "text"
 	case Blob:
 		return "blob"
+	case List:
+		return "list"
+	case Custom:
+		return "custom"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ParseType returns the type for the provided pretty printed type name.
+func ParseType(s string) (Type, error) {
+	for _, t := range []Type{Bool, Int64, Float64, Text, Blob, List} {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("literal.ParseType: unknown literal type %q", s)
+}
+
 // Literal represents the type and value boxed in the literal.
 type Literal struct {
-	t Type
-	v interface{}
+	t    Type
+	v    interface{}
+	lang string
+	def  *TypeDefinition
 }
 
 // Type returns the type of a literal.
@@ -77,9 +103,67 @@ func (l *Literal) Type() Type {
 
 // String returns a string representation of the literal.
 func (l *Literal) String() string {
+	if l.t == List {
+		b := bytes.NewBufferString("\"[")
+		for i, e := range l.v.([]*Literal) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(e.String())
+		}
+		b.WriteString("]\"^^type:list")
+		return b.String()
+	}
+	if l.t == Custom {
+		return fmt.Sprintf("\"%s\"^^type:%s", l.def.Format(l.v), l.def.Name)
+	}
+	if l.lang != "" {
+		return fmt.Sprintf("\"%v\"^^type:%v@%s", l.Interface(), l.Type(), l.lang)
+	}
 	return fmt.Sprintf("\"%v\"^^type:%v", l.Interface(), l.Type())
 }
 
+// Lang returns the language tag of a text literal, or an empty string if the
+// literal carries no language tag.
+func (l *Literal) Lang() string {
+	return l.lang
+}
+
+// WithLang returns a copy of the text literal tagged with the provided
+// language tag. Tags are case insensitive and are stored in lower case. An
+// empty tag returns an untagged copy of the literal.
+func (l *Literal) WithLang(tag string) (*Literal, error) {
+	if l.t != Text {
+		return nil, fmt.Errorf("literal.WithLang: only text literals can carry a language tag; found type %s", l.t)
+	}
+	if tag != "" && !validLang(tag) {
+		return nil, fmt.Errorf("literal.WithLang: invalid language tag %q", tag)
+	}
+	return &Literal{
+		t:    l.t,
+		v:    l.v,
+		lang: strings.ToLower(tag),
+	}, nil
+}
+
+// validLang returns true if the provided tag is a well formed language tag:
+// a primary subtag of 1 to 8 letters optionally followed by '-' separated
+// subtags of 1 to 8 letters or digits; for instance, es or en-US.
+func validLang(tag string) bool {
+	for i, st := range strings.Split(tag, "-") {
+		if len(st) == 0 || len(st) > 8 {
+			return false
+		}
+		for _, r := range st {
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !isLetter && (i == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // ToComparableString returns a string that can be directly compared.
 func (l *Literal) ToComparableString() string {
 	s := ""
@@ -134,6 +218,77 @@ func (l *Literal) Blob() ([]byte, error) {
 	return l.v.([]byte), nil
 }
 
+// List returns the value of a literal as a []*Literal.
+func (l *Literal) List() ([]*Literal, error) {
+	if l.t != List {
+		return nil, fmt.Errorf("literal.List: literal is of type %v; cannot be converted to a []*Literal", l.t)
+	}
+	return l.v.([]*Literal), nil
+}
+
+// Cast returns a new literal of the provided type holding the value of the
+// literal. Casting to the same type returns the literal itself. Text can be
+// cast to bool, int64, and float64 as long as it can be parsed into the
+// requested type. Bool, int64, and float64 can be cast to text. Int64 can be
+// cast to float64, and float64 to int64 if it has no fractional part. Any
+// other combination fails.
+func (l *Literal) Cast(t Type) (*Literal, error) {
+	if l.t == t {
+		return l, nil
+	}
+	b := DefaultBuilder()
+	switch l.t {
+	case Text:
+		v := strings.TrimSpace(l.v.(string))
+		switch t {
+		case Bool:
+			pv, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("literal.Cast: could not convert text %q to bool", v)
+			}
+			return b.Build(Bool, pv)
+		case Int64:
+			pv, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("literal.Cast: could not convert text %q to int64", v)
+			}
+			return b.Build(Int64, pv)
+		case Float64:
+			pv, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("literal.Cast: could not convert text %q to float64", v)
+			}
+			if !isFinite(pv) {
+				return nil, fmt.Errorf("literal.Cast: could not convert text %q to a finite float64", v)
+			}
+			return b.Build(Float64, pv)
+		}
+	case Bool:
+		if t == Text {
+			return b.Build(Text, strconv.FormatBool(l.v.(bool)))
+		}
+	case Int64:
+		switch t {
+		case Text:
+			return b.Build(Text, strconv.FormatInt(l.v.(int64), 10))
+		case Float64:
+			return b.Build(Float64, float64(l.v.(int64)))
+		}
+	case Float64:
+		v := l.v.(float64)
+		switch t {
+		case Text:
+			return b.Build(Text, strconv.FormatFloat(v, 'g', -1, 64))
+		case Int64:
+			if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil, fmt.Errorf("literal.Cast: could not convert float64 %v to int64 without losing precision", v)
+			}
+			return b.Build(Int64, int64(v))
+		}
+	}
+	return nil, fmt.Errorf("literal.Cast: cannot cast literal of type %s to type %s", l.t, t)
+}
+
 // Interface returns the value as a simple interface{}.
 func (l *Literal) Interface() interface{} {
 	return l.v
@@ -153,9 +308,37 @@ func init() {
 	defaultBuilder = &unboundBuilder{}
 }
 
+// NonFinitePolicy sets how builders parse NaN and infinite float64 values.
+type NonFinitePolicy int
+
+const (
+	// RejectNonFinite makes parsing NaN and infinite float64 values fail. It is
+	// the policy of the default builder, so a single such literal cannot
+	// silently poison comparisons and aggregations.
+	RejectNonFinite NonFinitePolicy = iota
+	// AllowNonFinite parses NaN and infinite float64 values. NaN compares
+	// greater than any other float64, including +Inf, and equal to another NaN,
+	// so ascending sorts place it last. Sums including them follow IEEE 754, so
+	// a NaN, or both infinities, make the sum NaN.
+	AllowNonFinite
+)
+
+// NewBuilderWithNonFinitePolicy returns an unbound builder that parses NaN and
+// infinite float64 values according to the provided policy.
+func NewBuilderWithNonFinitePolicy(p NonFinitePolicy) Builder {
+	return &unboundBuilder{allowNonFinite: p == AllowNonFinite}
+}
+
+// isFinite returns true if the value is neither NaN nor infinite.
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 // The default builder is unbound. This allows to create a literal arbitrarily
-// long.
-type unboundBuilder struct{}
+// long. Unless allowed, it rejects parsing NaN and infinite float64 values.
+type unboundBuilder struct {
+	allowNonFinite bool
+}
 
 // Build creates a new unbound literal from a type and a value.
 func (b *unboundBuilder) Build(t Type, v interface{}) (*Literal, error) {
@@ -180,6 +363,18 @@ func (b *unboundBuilder) Build(t Type, v interface{}) (*Literal, error) {
 		if t != Blob {
 			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
 		}
+	case []*Literal:
+		if t != List {
+			return nil, fmt.Errorf("literal.Build: type %v does not match type of value %v", t, v)
+		}
+		for _, e := range v.([]*Literal) {
+			if e == nil {
+				return nil, fmt.Errorf("literal.Build: lists cannot contain nil literals")
+			}
+			if e.t == List {
+				return nil, fmt.Errorf("literal.Build: lists cannot contain other lists")
+			}
+		}
 	default:
 		return nil, fmt.Errorf("literal.Build: type %T is not supported when building literals", v)
 	}
@@ -189,7 +384,9 @@ func (b *unboundBuilder) Build(t Type, v interface{}) (*Literal, error) {
 	}, nil
 }
 
-// Parse creates a string out of a prettified representation.
+// Parse creates a string out of a prettified representation. Numeric literals
+// are normalized: surrounding spaces are ignored and values are stored by value,
+// so "07"^^type:int64 and "+7"^^type:int64 are both rendered as "7"^^type:int64.
 func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 	raw := strings.TrimSpace(s)
 	if len(raw) == 0 {
@@ -198,12 +395,28 @@ func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 	if raw[0] != '"' {
 		return nil, fmt.Errorf("literal.Parse: text encoded literals must start with \", missing in %s", raw)
 	}
+	if strings.HasSuffix(raw, listSuffix) {
+		return parseList(b, raw)
+	}
 	idx := strings.Index(raw, "\"^^type:")
 	if idx < 0 {
 		return nil, fmt.Errorf("literal.Parse: text encoded literals must have a type; missing in %s", raw)
 	}
 	v := raw[1:idx]
 	t := raw[idx+len("\"^^type:"):]
+	if lIdx := strings.Index(t, "@"); lIdx >= 0 {
+		if t[:lIdx] != "text" {
+			return nil, fmt.Errorf("literal.Parse: only text literals can carry a language tag; found %s", raw)
+		}
+		if lIdx == len(t)-1 {
+			return nil, fmt.Errorf("literal.Parse: missing language tag after @ in %s", raw)
+		}
+		l, err := b.Build(Text, v)
+		if err != nil {
+			return nil, err
+		}
+		return l.WithLang(t[lIdx+1:])
+	}
 	switch t {
 	case "bool":
 		pv, err := strconv.ParseBool(v)
@@ -212,16 +425,19 @@ func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 		}
 		return b.Build(Bool, pv)
 	case "int64":
-		pv, err := strconv.ParseInt(v, 10, 64)
+		pv, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf("literal.Parse: could not convert value %q to int64", v)
 		}
 		return b.Build(Int64, int64(pv))
 	case "float64":
-		pv, err := strconv.ParseFloat(v, 64)
+		pv, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
 		if err != nil {
 			return nil, fmt.Errorf("literal.Parse: could not convert value %q to float64", v)
 		}
+		if !b.allowNonFinite && !isFinite(pv) {
+			return nil, fmt.Errorf("literal.Parse: NaN and infinite float64 values are not allowed; found %q", v)
+		}
 		return b.Build(Float64, float64(pv))
 	case "text":
 		return b.Build(Text, v)
@@ -240,8 +456,54 @@ func (b *unboundBuilder) Parse(s string) (*Literal, error) {
 		}
 		return b.Build(Blob, bs)
 	default:
-		return nil, nil
+		return nil, fmt.Errorf("literal.Parse: unknown literal type %q in %s", t, raw)
+	}
+}
+
+// listSuffix is the suffix of all text encoded list literals.
+const listSuffix = "]\"^^type:list"
+
+// parseList parses a text encoded list literal. Lists are encoded as the
+// comma separated text encoding of its elements wrapped in [ and ]; for
+// instance, "["foo"^^type:text, "1"^^type:int64]"^^type:list.
+func parseList(b Builder, raw string) (*Literal, error) {
+	if !strings.HasPrefix(raw, "\"[") {
+		return nil, fmt.Errorf("literal.Parse: list literals must start with \"[, missing in %s", raw)
+	}
+	v := strings.TrimSpace(raw[2 : len(raw)-len(listSuffix)])
+	ls := []*Literal{}
+	for v != "" {
+		if v[0] != '"' {
+			return nil, fmt.Errorf("literal.Parse: list elements must start with \", missing in %s", v)
+		}
+		idx := strings.Index(v, "\"^^type:")
+		if idx < 0 {
+			return nil, fmt.Errorf("literal.Parse: list elements must have a type; missing in %s", v)
+		}
+		end := idx + len("\"^^type:")
+		for end < len(v) && v[end] != ',' && v[end] != ' ' {
+			end++
+		}
+		l, err := b.Parse(v[:end])
+		if err != nil {
+			return nil, err
+		}
+		if l == nil {
+			return nil, fmt.Errorf("literal.Parse: unknown type for list element %s", v[:end])
+		}
+		ls = append(ls, l)
+		v = strings.TrimSpace(v[end:])
+		if v != "" {
+			if v[0] != ',' {
+				return nil, fmt.Errorf("literal.Parse: list elements must be separated by ',' in %s", raw)
+			}
+			v = strings.TrimSpace(v[1:])
+			if v == "" {
+				return nil, fmt.Errorf("literal.Parse: missing list element after ',' in %s", raw)
+			}
+		}
 	}
+	return b.Build(List, ls)
 }
 
 // DefaultBuilder returns a builder with no constraints or checks.
@@ -286,6 +548,13 @@ func (b *boundedBuilder) Parse(s string) (*Literal, error) {
 		if blob, err := l.Blob(); err != nil || len(blob) > b.max {
 			return nil, fmt.Errorf("literal.Parse: cannot create literal due to size of %v (%d>%d)", t, len(blob), b.max)
 		}
+	case List:
+		ls, _ := l.List()
+		for _, e := range ls {
+			if _, err := b.Parse(e.String()); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return l, nil
 }
@@ -304,6 +573,13 @@ func (l *Literal) UUID() uuid.UUID {
 	buffer.Reset()
 	defer bufPool.Put(buffer)
 
+	if l.t == Custom {
+		// Custom values are identified by their type name and text encoding.
+		buffer.WriteString(l.def.Name)
+		buffer.WriteString(":")
+		buffer.WriteString(l.def.Format(l.v))
+		return uuid.NewSHA1(uuid.NIL, buffer.Bytes())
+	}
 	switch v := l.v.(type) {
 	case bool:
 		if v {
@@ -321,9 +597,22 @@ func (l *Literal) UUID() uuid.UUID {
 		binary.LittleEndian.PutUint64(b, bs)
 		buffer.Write(b)
 	case string:
+		if l.lang != "" {
+			// The tag is prefixed by its length, so the text and the tag can
+			// not be confused with a different split of the same bytes.
+			b := make([]byte, binary.MaxVarintLen64)
+			buffer.Write(b[:binary.PutUvarint(b, uint64(len(l.lang)))])
+			buffer.WriteString(l.lang)
+			buffer.WriteString(v)
+			return uuid.NewSHA1(langNamespace, buffer.Bytes())
+		}
 		buffer.WriteString(v)
 	case []byte:
 		buffer.Write(v)
+	case []*Literal:
+		for _, e := range v {
+			buffer.Write(e.UUID())
+		}
 	}
 
 	return uuid.NewSHA1(uuid.NIL, buffer.Bytes())
@@ -15,6 +15,7 @@
 package literal
 
 import (
+	"math"
 	"reflect"
 	"testing"
 )
@@ -26,18 +27,18 @@ func TestDefaultBuilder(t *testing.T) {
 		want *Literal
 	}{
 		// Successful cases.
-		{Bool, true, &Literal{Bool, interface{}(true)}},
-		{Bool, false, &Literal{Bool, interface{}(false)}},
-		{Int64, int64(-1), &Literal{Int64, interface{}(int64(-1))}},
-		{Int64, int64(0), &Literal{Int64, interface{}(int64(0))}},
-		{Int64, int64(1), &Literal{Int64, interface{}(int64(1))}},
-		{Float64, float64(-1), &Literal{Float64, interface{}(float64(-1))}},
-		{Float64, float64(0), &Literal{Float64, interface{}(float64(0))}},
-		{Float64, float64(1), &Literal{Float64, interface{}(float64(1))}},
-		{Text, "", &Literal{Text, interface{}("")}},
-		{Text, "some random string", &Literal{Text, interface{}("some random string")}},
-		{Blob, []byte{}, &Literal{Blob, []byte{}}},
-		{Blob, []byte("some random bytes"), &Literal{Blob, interface{}([]byte("some random bytes"))}},
+		{Bool, true, &Literal{t: Bool, v: interface{}(true)}},
+		{Bool, false, &Literal{t: Bool, v: interface{}(false)}},
+		{Int64, int64(-1), &Literal{t: Int64, v: interface{}(int64(-1))}},
+		{Int64, int64(0), &Literal{t: Int64, v: interface{}(int64(0))}},
+		{Int64, int64(1), &Literal{t: Int64, v: interface{}(int64(1))}},
+		{Float64, float64(-1), &Literal{t: Float64, v: interface{}(float64(-1))}},
+		{Float64, float64(0), &Literal{t: Float64, v: interface{}(float64(0))}},
+		{Float64, float64(1), &Literal{t: Float64, v: interface{}(float64(1))}},
+		{Text, "", &Literal{t: Text, v: interface{}("")}},
+		{Text, "some random string", &Literal{t: Text, v: interface{}("some random string")}},
+		{Blob, []byte{}, &Literal{t: Blob, v: []byte{}}},
+		{Blob, []byte("some random bytes"), &Literal{t: Blob, v: interface{}([]byte("some random bytes"))}},
 		// Invalid cases.
 		{Bool, 1, nil},
 		{Int64, 2, nil},
@@ -63,8 +64,8 @@ func TestBoundedBuilder(t *testing.T) {
 		want *Literal
 	}{
 		// Successful cases.
-		{Text, "0123456789", &Literal{Text, interface{}("0123456789")}},
-		{Blob, []byte("0123456789"), &Literal{Blob, interface{}([]byte("0123456789"))}},
+		{Text, "0123456789", &Literal{t: Text, v: interface{}("0123456789")}},
+		{Blob, []byte("0123456789"), &Literal{t: Blob, v: interface{}([]byte("0123456789"))}},
 		// Invalid cases.
 		{Text, "01234567890", nil},
 		{Blob, []byte("01234567890"), nil},
@@ -143,6 +144,71 @@ func TestToComparableString(t *testing.T) {
 	}
 }
 
+func TestList(t *testing.T) {
+	b := DefaultBuilder()
+	txt, err := b.Build(Text, "a, b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := b.Build(Int64, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		v []*Literal
+		s string
+	}{
+		{[]*Literal{}, `"[]"^^type:list`},
+		{[]*Literal{txt}, `"["a, b"^^type:text]"^^type:list`},
+		{[]*Literal{txt, i}, `"["a, b"^^type:text, "1"^^type:int64]"^^type:list`},
+	}
+	for _, entry := range table {
+		l, err := b.Build(List, entry.v)
+		if err != nil {
+			t.Fatalf("Build(List, %v) failed with error %v", entry.v, err)
+		}
+		if got, want := l.String(), entry.s; got != want {
+			t.Errorf("List literal pretty printed incorrectly; got %s, want %s", got, want)
+		}
+		got, err := b.Parse(entry.s)
+		if err != nil {
+			t.Fatalf("Failed to parse pretty printed literal %s with error %v", entry.s, err)
+		}
+		if !reflect.DeepEqual(got, l) {
+			t.Errorf("Failed to parse correctly %s; got %v, want %v", entry.s, got, l)
+		}
+		ls, err := got.List()
+		if err != nil {
+			t.Errorf("List() failed for %s with error %v", got, err)
+		}
+		if len(ls) != len(entry.v) {
+			t.Errorf("List() returned %d elements; want %d", len(ls), len(entry.v))
+		}
+	}
+	nested, err := b.Build(List, []*Literal{txt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Build(List, []*Literal{nested}); err == nil {
+		t.Errorf("Build(List, _) should have rejected nested lists")
+	}
+	if _, err := txt.List(); err == nil {
+		t.Errorf("List() should have failed for text literal %s", txt)
+	}
+	for _, s := range []string{
+		`"["a"^^type:text "b"^^type:text]"^^type:list`,
+		`"["a"^^type:text,]"^^type:list`,
+		`"[a]"^^type:list`,
+	} {
+		if l, err := b.Parse(s); err == nil {
+			t.Errorf("Parse(%s) should have failed; got %v", s, l)
+		}
+	}
+	if _, err := NewBoundedBuilder(2).Parse(`"["a, b"^^type:text]"^^type:list`); err == nil {
+		t.Errorf("bounded builder should have rejected a list containing a long text")
+	}
+}
+
 func TestParse(t *testing.T) {
 	table := []struct {
 		t Type
@@ -158,6 +224,16 @@ func TestParse(t *testing.T) {
 		{Float64, float64(-1), `"-1"^^type:float64`},
 		{Float64, float64(0), `"0"^^type:float64`},
 		{Float64, float64(1), `"1"^^type:float64`},
+		{Float64, float64(1.5e10), `"1.5e10"^^type:float64`},
+		{Float64, float64(1.5e10), `"1.5E+10"^^type:float64`},
+		{Float64, float64(-2.5e-3), `"-2.5e-3"^^type:float64`},
+		{Float64, float64(3e-300), `"3e-300"^^type:float64`},
+		// Numeric literals are normalized.
+		{Int64, int64(7), `"07"^^type:int64`},
+		{Int64, int64(7), `"+7"^^type:int64`},
+		{Int64, int64(7), `" 7 "^^type:int64`},
+		{Int64, int64(0), `"-0"^^type:int64`},
+		{Float64, float64(174), `" 174.0"^^type:float64`},
 		{Text, "", `""^^type:text`},
 		{Text, "some random string", `"some random string"^^type:text`},
 		{Blob, []byte{}, `"[]"^^type:blob`},
@@ -177,3 +253,240 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCanonicalInt64(t *testing.T) {
+	for _, s := range []string{`"07"^^type:int64`, `"007"^^type:int64`, `"+7"^^type:int64`, `" 7"^^type:int64`} {
+		l, err := DefaultBuilder().Parse(s)
+		if err != nil {
+			t.Errorf("DefaultBuilder().Parse(%q) failed with error %v", s, err)
+			continue
+		}
+		if got, want := l.String(), `"7"^^type:int64`; got != want {
+			t.Errorf("DefaultBuilder().Parse(%q).String() = %q; want %q", s, got, want)
+		}
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	parse := func(s string) *Literal {
+		l, err := DefaultBuilder().Parse(s)
+		if err != nil {
+			t.Fatalf("DefaultBuilder().Parse(%q) failed with error %v", s, err)
+		}
+		return l
+	}
+	table := []struct {
+		a, b string
+		want int
+	}{
+		{`"1e-10"^^type:float64`, `"2e-10"^^type:float64`, -1},
+		{`"1.5e10"^^type:float64`, `"15000000000"^^type:float64`, 0},
+		{`"1e40"^^type:float64`, `"9e39"^^type:float64`, 1},
+		{`"-2"^^type:float64`, `"-1e-3"^^type:float64`, -1},
+		{`"-2"^^type:int64`, `"-1"^^type:int64`, -1},
+		{`"10"^^type:int64`, `"9"^^type:int64`, 1},
+	}
+	for _, entry := range table {
+		got, err := parse(entry.a).Compare(parse(entry.b))
+		if err != nil {
+			t.Errorf("%s.Compare(%s) failed with error %v", entry.a, entry.b, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("%s.Compare(%s) = %d; want %d", entry.a, entry.b, got, entry.want)
+		}
+	}
+}
+
+func TestNonFinitePolicy(t *testing.T) {
+	nonFinite := []string{
+		`"NaN"^^type:float64`,
+		`"+Inf"^^type:float64`,
+		`"-inf"^^type:float64`,
+		`"Infinity"^^type:float64`,
+	}
+	for _, b := range []Builder{DefaultBuilder(), NewBoundedBuilder(10), NewBuilderWithNonFinitePolicy(RejectNonFinite)} {
+		for _, s := range nonFinite {
+			if l, err := b.Parse(s); err == nil {
+				t.Errorf("Parse(%q) should have rejected a non finite value; got %s", s, l)
+			}
+		}
+	}
+	nan, err := DefaultBuilder().Build(Text, "NaN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, err := nan.Cast(Float64); err == nil {
+		t.Errorf("%s.Cast(float64) should have rejected a non finite value; got %s", nan, l)
+	}
+
+	b := NewBuilderWithNonFinitePolicy(AllowNonFinite)
+	parse := func(s string) *Literal {
+		l, err := b.Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed with error %v", s, err)
+		}
+		return l
+	}
+	for _, s := range nonFinite {
+		parse(s)
+	}
+	if v, _ := parse(`"NaN"^^type:float64`).Float64(); !math.IsNaN(v) {
+		t.Errorf("Parse(\"NaN\") returned %v; want NaN", v)
+	}
+	table := []struct {
+		a, b string
+		want int
+	}{
+		{`"NaN"^^type:float64`, `"+Inf"^^type:float64`, 1},
+		{`"-Inf"^^type:float64`, `"NaN"^^type:float64`, -1},
+		{`"NaN"^^type:float64`, `"NaN"^^type:float64`, 0},
+		{`"+Inf"^^type:float64`, `"1e300"^^type:float64`, 1},
+		{`"-Inf"^^type:float64`, `"-1e300"^^type:float64`, -1},
+	}
+	for _, entry := range table {
+		got, err := parse(entry.a).Compare(parse(entry.b))
+		if err != nil {
+			t.Errorf("%s.Compare(%s) failed with error %v", entry.a, entry.b, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("%s.Compare(%s) = %d; want %d", entry.a, entry.b, got, entry.want)
+		}
+	}
+}
+
+func TestCast(t *testing.T) {
+	b := DefaultBuilder()
+	lit := func(t Type, v interface{}) *Literal {
+		l, err := b.Build(t, v)
+		if err != nil {
+			panic(err)
+		}
+		return l
+	}
+	table := []struct {
+		in   *Literal
+		t    Type
+		want *Literal
+	}{
+		// Successful cases.
+		{lit(Text, "42"), Int64, lit(Int64, int64(42))},
+		{lit(Text, " -3 "), Int64, lit(Int64, int64(-3))},
+		{lit(Text, "1.5"), Float64, lit(Float64, float64(1.5))},
+		{lit(Text, "true"), Bool, lit(Bool, true)},
+		{lit(Int64, int64(7)), Text, lit(Text, "7")},
+		{lit(Float64, float64(2.25)), Text, lit(Text, "2.25")},
+		{lit(Bool, false), Text, lit(Text, "false")},
+		{lit(Int64, int64(7)), Float64, lit(Float64, float64(7))},
+		{lit(Float64, float64(8)), Int64, lit(Int64, int64(8))},
+		{lit(Text, "same"), Text, lit(Text, "same")},
+		// Invalid cases.
+		{lit(Text, "alice"), Int64, nil},
+		{lit(Text, "1.5"), Int64, nil},
+		{lit(Text, "maybe"), Bool, nil},
+		{lit(Float64, float64(8.5)), Int64, nil},
+		{lit(Bool, true), Int64, nil},
+		{lit(Blob, []byte("a")), Text, nil},
+	}
+	for _, tc := range table {
+		got, err := tc.in.Cast(tc.t)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%s.Cast(%s) should have failed; got %s", tc.in, tc.t, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s.Cast(%s) failed with error %v", tc.in, tc.t, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s.Cast(%s) returned %s; want %s", tc.in, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, want := range []Type{Bool, Int64, Float64, Text, Blob, List} {
+		got, err := ParseType(want.String())
+		if err != nil {
+			t.Errorf("ParseType(%q) failed with error %v", want.String(), err)
+		}
+		if got != want {
+			t.Errorf("ParseType(%q) returned %s; want %s", want.String(), got, want)
+		}
+	}
+	if got, err := ParseType("int32"); err == nil {
+		t.Errorf("ParseType(%q) should have failed; got %s", "int32", got)
+	}
+}
+
+func TestLang(t *testing.T) {
+	l, err := DefaultBuilder().Parse(`"hola"^^type:text@ES`)
+	if err != nil {
+		t.Fatalf("Parse failed to parse a language tagged literal with error %v", err)
+	}
+	if got, want := l.Lang(), "es"; got != want {
+		t.Errorf("Lang returned %q; want %q", got, want)
+	}
+	if got, want := l.String(), `"hola"^^type:text@es`; got != want {
+		t.Errorf("String returned %q; want %q", got, want)
+	}
+	rl, err := DefaultBuilder().Parse(l.String())
+	if err != nil {
+		t.Fatalf("Parse failed to round trip %s with error %v", l, err)
+	}
+	if !reflect.DeepEqual(rl, l) {
+		t.Errorf("Parse failed to round trip %s; got %s", l, rl)
+	}
+
+	untagged, err := l.WithLang("")
+	if err != nil {
+		t.Fatalf("WithLang failed to remove the language tag with error %v", err)
+	}
+	plain, err := DefaultBuilder().Build(Text, "hola")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(untagged, plain) {
+		t.Errorf("WithLang(\"\") returned %s; want %s", untagged, plain)
+	}
+	if reflect.DeepEqual(l.UUID(), plain.UUID()) {
+		t.Errorf("tagged literal %s and untagged literal %s should have different UUIDs", l, plain)
+	}
+	en, err := plain.WithLang("en-US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(l.UUID(), en.UUID()) {
+		t.Errorf("literals %s and %s should have different UUIDs", l, en)
+	}
+	// The tag should not be confused with the text of untagged literals.
+	suffixed, err := DefaultBuilder().Build(Text, "hola@es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(l.UUID(), suffixed.UUID()) {
+		t.Errorf("tagged literal %s and untagged literal %s should have different UUIDs", l, suffixed)
+	}
+
+	for _, s := range []string{
+		`"1"^^type:int64@es`,
+		`"hola"^^type:text@`,
+		`"hola"^^type:text@e$`,
+		`"hola"^^type:text@1a`,
+		`"hola"^^type:text@toolongtag`,
+	} {
+		if got, err := DefaultBuilder().Parse(s); err == nil {
+			t.Errorf("Parse(%q) should have failed; got %s", s, got)
+		}
+	}
+	i, err := DefaultBuilder().Build(Int64, int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := i.WithLang("es"); err == nil {
+		t.Errorf("WithLang should have failed for non text literal %s; got %s", i, got)
+	}
+}
@@ -31,8 +31,13 @@ import (
 const (
 	slash      = byte('/')
 	underscore = byte('_')
+	backslash  = byte('\\')
 )
 
+// idEscaper escapes the characters that cannot appear verbatim in the ID
+// section of a pretty printed node.
+var idEscaper = strings.NewReplacer(`\`, `\\`, `<`, `\<`, `>`, `\>`)
+
 // bufPool holds a pool of bytes.Buffer for the UUID() method
 var bufPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
 
@@ -78,7 +83,9 @@ func (n *Node) ID() *ID {
 	return n.id
 }
 
-// String returns a pretty printing representation of Node.
+// String returns a pretty printing representation of Node. Backslashes and
+// the < and > delimiters in the ID are escaped with a backslash, so the
+// result can always be read back by Parse.
 func (n *Node) String() string {
 	nodeType := ""
 	if n.t != nil {
@@ -87,43 +94,108 @@ func (n *Node) String() string {
 	nodeID := ""
 	if n.id != nil {
 		nodeID = n.id.String()
+		if strings.ContainsAny(nodeID, `\<>`) {
+			nodeID = idEscaper.Replace(nodeID)
+		}
 	}
 	return fmt.Sprintf("%s<%s>", nodeType, nodeID)
 }
 
+// ParseError describes why a pretty printed node could not be parsed. Offset
+// is the position in Input of the first offending byte.
+type ParseError struct {
+	Input  string
+	Offset int
+	Reason string
+}
+
+// Error returns a readable version of the parse error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("node.Parse: %s at offset %d in %q", e.Reason, e.Offset, e.Input)
+}
+
 // Parse returns a node given a pretty printed representation of a Node or a BlankNode.
+// Inside the ID, \\, \< and \> stand for a backslash and the delimiters; any
+// other backslash is taken literally. If the provided text is malformed, the
+// returned error is a *ParseError.
 func Parse(s string) (*Node, error) {
 	raw := strings.TrimSpace(s)
+	off := strings.Index(s, raw)
+	perr := func(pos int, reason string) error {
+		return &ParseError{Input: s, Offset: off + pos, Reason: reason}
+	}
+	if raw == "" {
+		return nil, perr(0, "empty node")
+	}
 	switch raw[0] {
 	case slash:
-		idx := strings.Index(raw, "<")
+		idx := strings.IndexByte(raw, '<')
 		if idx < 0 {
-			return nil, fmt.Errorf("node.Parse: invalid format, could not find ID in %v", raw)
+			return nil, perr(len(raw), "missing opening <")
 		}
-		t, err := NewType(raw[:idx])
-		if err != nil {
-			return nil, fmt.Errorf("node.Parse: invalid type %q, %v", raw[:idx], err)
+		if i := strings.IndexAny(raw[:idx], " \t\n\r"); i >= 0 {
+			return nil, perr(i, "spaces are not allowed in types")
+		}
+		if raw[idx-1] == slash {
+			return nil, perr(idx-1, "type cannot end with /")
 		}
 		if raw[len(raw)-1] != '>' {
-			return nil, fmt.Errorf("node.Parse: pretty printing should finish with '>' in %q", raw)
+			return nil, perr(len(raw), "missing closing >")
 		}
-		id, err := NewID(raw[idx+1 : len(raw)-1])
-		if err != nil {
-			return nil, fmt.Errorf("node.Parse: invalid ID in %q, %v", raw, err)
+		rid, pos, reason := unescapeID(raw[idx+1 : len(raw)-1])
+		if reason != "" {
+			return nil, perr(idx+1+pos, reason)
 		}
-		return NewNode(t, id), nil
+		t, id := Type(raw[:idx]), ID(rid)
+		return NewNode(&t, &id), nil
 	case underscore:
+		if len(raw) < 3 {
+			return nil, perr(len(raw), "missing blank node ID")
+		}
 		id, err := NewID(raw[2:])
 		if err != nil {
-			return nil, fmt.Errorf("node.Parse: invalid ID in %q, %v", raw, err)
+			return nil, perr(2, "invalid blank node ID")
 		}
 		t, _ := NewType("/_")
 		return NewNode(t, id), nil
 	default:
-		return nil, fmt.Errorf("node.Parse: node representation should start with '/' or '_' in %v", raw)
+		return nil, perr(0, "node representation should start with '/' or '_'")
 	}
 }
 
+// unescapeID returns the ID represented by the escaped ID section of a pretty
+// printed node. If the section is malformed it returns the offending position
+// and the reason.
+func unescapeID(s string) (string, int, string) {
+	if s == "" {
+		return "", 0, "empty ID"
+	}
+	if !strings.ContainsAny(s, `\<>`) {
+		return s, 0, ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case backslash:
+			if i+1 == len(s) {
+				// The backslash escapes the final >.
+				return "", i + 2, "missing closing >"
+			}
+			if n := s[i+1]; n == backslash || n == '<' || n == '>' {
+				b.WriteByte(n)
+				i++
+				continue
+			}
+			b.WriteByte(c)
+		case '<', '>':
+			return "", i, fmt.Sprintf("unexpected %q in ID", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), 0, ""
+}
+
 // Covariant checks if the types of two nodes is covariant.
 func (n *Node) Covariant(on *Node) bool {
 	return n.t.Covariant(on.t)
@@ -144,11 +216,9 @@ func NewType(t string) (*Type, error) {
 	return &nt, nil
 }
 
-// NewID create a new ID from a plain string.
+// NewID create a new ID from a plain string. The ID may contain any
+// character; String escapes the ones that need it.
 func NewID(id string) (*ID, error) {
-	if strings.ContainsAny(id, "<>") {
-		return nil, fmt.Errorf("node.NewID(%q) does not allow '<' or '>'", id)
-	}
 	if id == "" {
 		return nil, fmt.Errorf("node.NewID(%q) cannot create empty ID", id)
 	}
@@ -186,6 +256,43 @@ var (
 	tBlank  Type
 )
 
+// The source set to override the UUIDs of blank nodes, if any.
+var (
+	uuidSourceMu sync.RWMutex
+	uuidSource   func() uuid.UUID
+)
+
+// SetUUIDSource sets the function used to generate the IDs of new blank nodes
+// and returns the previous one. Passing nil restores the default source, which
+// generates random UUIDs. Overriding the source is only meant to make tests
+// deterministic, since the uniqueness of blank nodes relies on the provided
+// source from then on.
+func SetUUIDSource(src func() uuid.UUID) func() uuid.UUID {
+	uuidSourceMu.Lock()
+	defer uuidSourceMu.Unlock()
+	old := uuidSource
+	uuidSource = src
+	return old
+}
+
+// SequentialUUIDSource returns a source that generates the UUIDs
+// 00000000-0000-0000-0000-000000000001, 00000000-0000-0000-0000-000000000002,
+// and so on. It is meant to be used with SetUUIDSource in tests.
+func SequentialUUIDSource() func() uuid.UUID {
+	var (
+		mu  sync.Mutex
+		cnt uint64
+	)
+	return func() uuid.UUID {
+		mu.Lock()
+		defer mu.Unlock()
+		cnt++
+		u := make(uuid.UUID, 16)
+		binary.BigEndian.PutUint64(u[8:], cnt)
+		return u
+	}
+}
+
 func init() {
 	var buffer bytes.Buffer
 	b := make([]byte, 16)
@@ -225,8 +332,16 @@ func init() {
 // NewBlankNode creates a new blank node. The blank node ID is guaranteed to
 // be unique in BadWolf.
 func NewBlankNode() *Node {
-	uuid := <-nextVal
-	id := ID(uuid.String())
+	uuidSourceMu.RLock()
+	src := uuidSource
+	uuidSourceMu.RUnlock()
+	var u uuid.UUID
+	if src != nil {
+		u = src()
+	} else {
+		u = <-nextVal
+	}
+	id := ID(u.String())
 	return &Node{
 		t:  &tBlank,
 		id: &id,
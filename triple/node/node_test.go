@@ -22,11 +22,11 @@ import (
 )
 
 func TestNewID(t *testing.T) {
-	if wID, err := NewID("<"); err == nil {
-		t.Errorf("node.NewID(\"<\") should have never validated ID %v", wID)
+	if wID, err := NewID(""); err == nil {
+		t.Errorf("node.NewID(\"\") should have never validated ID %v", wID)
 	}
-	if wID, err := NewID("<"); err == nil {
-		t.Errorf("node.NewID(\"<\") should have never validated ID %v", wID)
+	if _, err := NewID(`a<b>\c`); err != nil {
+		t.Errorf("node.NewID should accept IDs with delimiters and backslashes; failed with error %v", err)
 	}
 	id, err := NewID("some_id")
 	if err != nil {
@@ -106,6 +106,7 @@ func TestNewNodeFromString(t *testing.T) {
 func TestNodeString(t *testing.T) {
 	// NewNodeFromString's error output has already been tested in its own dedicated unit tests
 	nA, _ := NewNodeFromStrings("/some/type", "id_1")
+	nB, _ := NewNodeFromStrings("/some/type", `a<b> \c`)
 
 	table := []struct {
 		n    *Node
@@ -114,6 +115,7 @@ func TestNodeString(t *testing.T) {
 		// Do not crash on unitialize node
 		{n: &Node{}, want: "<>"},
 		{n: nA, want: "/some/type<id_1>"},
+		{n: nB, want: `/some/type<a\<b\> \\c>`},
 	}
 
 	for i, entry := range table {
@@ -143,6 +145,24 @@ func TestParse(t *testing.T) {
 			id: "v1",
 			v:  true,
 		},
+		{
+			s:  `/foo<Fire \<Escape\>>`,
+			t:  "/foo",
+			id: "Fire <Escape>",
+			v:  true,
+		},
+		{
+			s:  `/foo<C:\\dir\\>`,
+			t:  "/foo",
+			id: `C:\dir\`,
+			v:  true,
+		},
+		{
+			s:  `/foo<a\b>`,
+			t:  "/foo",
+			id: `a\b`,
+			v:  true,
+		},
 		// Invalid text nodes.
 		{
 			s:  "/foo<123",
@@ -177,6 +197,58 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseError(t *testing.T) {
+	table := []struct {
+		s      string
+		offset int
+		reason string
+	}{
+		{s: "", offset: 0, reason: "empty node"},
+		{s: "foo<123>", offset: 0, reason: "node representation should start with '/' or '_'"},
+		{s: "/foo", offset: 4, reason: "missing opening <"},
+		{s: "/foo<123", offset: 8, reason: "missing closing >"},
+		{s: "  /foo<123", offset: 10, reason: "missing closing >"},
+		{s: "/fo o<123>", offset: 3, reason: "spaces are not allowed in types"},
+		{s: "/foo/<123>", offset: 4, reason: "type cannot end with /"},
+		{s: "/foo<1<3>", offset: 6, reason: "unexpected '<' in ID"},
+		{s: "/foo<1>3>", offset: 6, reason: "unexpected '>' in ID"},
+		{s: `/foo<1\>`, offset: 8, reason: "missing closing >"},
+		{s: "/foo<>", offset: 5, reason: "empty ID"},
+		{s: "_:", offset: 2, reason: "missing blank node ID"},
+	}
+	for _, entry := range table {
+		_, err := Parse(entry.s)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("node.Parse(%q) should have returned a *ParseError; got %v", entry.s, err)
+			continue
+		}
+		if got, want := perr.Offset, entry.offset; got != want {
+			t.Errorf("node.Parse(%q) returned the wrong offset; got %d, want %d", entry.s, got, want)
+		}
+		if got, want := perr.Reason, entry.reason; got != want {
+			t.Errorf("node.Parse(%q) returned the wrong reason; got %q, want %q", entry.s, got, want)
+		}
+	}
+}
+
+func TestStringParseRoundTrip(t *testing.T) {
+	for _, id := range []string{"123", "Fire Escape", "<", ">", `\`, `a\<b`, `<\>`, `x\`, `\\<<>>`} {
+		n, err := NewNodeFromStrings("/foo", id)
+		if err != nil {
+			t.Fatalf("node.NewNodeFromStrings(%q, %q) failed with error %v", "/foo", id, err)
+		}
+		pn, err := Parse(n.String())
+		if err != nil {
+			t.Errorf("node.Parse(%q) failed with error %v", n.String(), err)
+			continue
+		}
+		if got, want := pn.ID().String(), id; got != want {
+			t.Errorf("node.Parse(%q) returned ID %q; want %q", n.String(), got, want)
+		}
+	}
+}
+
 func TestBlankNode(t *testing.T) {
 	for i := uint64(0); i < 10; i++ {
 		b := NewBlankNode()
@@ -187,6 +259,24 @@ func TestBlankNode(t *testing.T) {
 	}
 }
 
+func TestSetUUIDSource(t *testing.T) {
+	old := SetUUIDSource(SequentialUUIDSource())
+	want := []string{
+		"/_<00000000-0000-0000-0000-000000000001>",
+		"/_<00000000-0000-0000-0000-000000000002>",
+		"/_<00000000-0000-0000-0000-000000000003>",
+	}
+	for _, w := range want {
+		if got := NewBlankNode().String(); got != w {
+			t.Errorf("NewBlankNode() = %s; want %s", got, w)
+		}
+	}
+	SetUUIDSource(old)
+	if b := NewBlankNode(); b.String() == want[0] {
+		t.Errorf("NewBlankNode() = %s after restoring the default source; want a random ID", b)
+	}
+}
+
 func TestUUID(t *testing.T) {
 	n, err := Parse("/foo<123>")
 	if err != nil {
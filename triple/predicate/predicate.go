@@ -71,39 +71,88 @@ func (p *Predicate) String() string {
 	return fmt.Sprintf("%q@[%s]", p.id, p.anchor.Format(time.RFC3339Nano))
 }
 
+// ParseError describes why a pretty printed predicate could not be parsed.
+// Offset is the position in Input of the first offending byte.
+type ParseError struct {
+	Input  string
+	Offset int
+	Reason string
+}
+
+// Error returns a readable version of the parse error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("predicate.Parse: %s at offset %d in %q", e.Reason, e.Offset, e.Input)
+}
+
+// bareTimeLayout is the layout of time anchors lacking a time zone offset.
+const bareTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// ParseTimeAnchor parses a time anchor formatted as RFC3339Nano. It is the one
+// place where BadWolf parses time anchors, so all of them accept the same
+// relaxed forms: the hour may have a single digit, as in
+// 2016-04-10T4:21:00Z, and the fractional seconds may be omitted or have
+// fewer than nine digits. If a location is provided, time anchors lacking a
+// time zone offset are also accepted and interpreted in that location.
+func ParseTimeAnchor(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil || loc == nil {
+		return t, err
+	}
+	if bt, berr := time.ParseInLocation(bareTimeLayout, s, loc); berr == nil {
+		return bt, nil
+	}
+	return t, err
+}
+
 // Parse converts a pretty printed predicate into a predicate.
+// If the provided text is malformed, the returned error is a *ParseError.
 func Parse(s string) (*Predicate, error) {
+	return ParseInLocation(s, nil)
+}
+
+// ParseInLocation works like Parse, but time anchors lacking a time zone
+// offset are interpreted in the provided location. If the location is nil,
+// such anchors are rejected like Parse does.
+func ParseInLocation(s string, loc *time.Location) (*Predicate, error) {
 	raw := strings.TrimSpace(s)
+	off := strings.Index(s, raw)
+	perr := func(pos int, reason string) error {
+		return &ParseError{Input: s, Offset: off + pos, Reason: reason}
+	}
 	if raw == "" {
-		return nil, fmt.Errorf("predicate.Parse cannot create predicate from empty string %q", s)
+		return nil, perr(0, "empty predicate")
 	}
 	if raw[0] != '"' {
-		return nil, fmt.Errorf("predicate.Parse failed to parse since string does not start with \" in %s", s)
+		return nil, perr(0, "missing opening \"")
 	}
 	idx := strings.Index(raw, "\"@[")
 	if idx < 0 {
-		return nil, fmt.Errorf("predicate.Parse could not find anchor definition in %s", raw)
+		return nil, perr(len(raw), "missing anchor definition @[...]")
+	}
+	if raw[len(raw)-1] != ']' {
+		return nil, perr(len(raw), "missing closing ]")
 	}
 	id, ta := raw[0:idx+1], raw[idx+3:len(raw)-1]
 	id, err := strconv.Unquote(id)
 	if err != nil {
-		return nil, fmt.Errorf("predicate.Parse can't unquote id in %s: %v", raw, err)
+		return nil, perr(0, "invalid quoted ID")
 	}
-	// TODO: if id has \" inside, it should be unquoted.
 	if ta == "" {
 		return &Predicate{
 			id: ID(id),
 		}, nil
 	}
+	tao := idx + 3
 	if ta[0] == '"' {
 		ta = ta[1:]
+		tao++
 	}
-	if ta[len(ta)-1] == '"' {
+	if ta != "" && ta[len(ta)-1] == '"' {
 		ta = ta[:len(ta)-1]
 	}
-	pta, err := time.Parse(time.RFC3339Nano, ta)
+	pta, err := ParseTimeAnchor(ta, loc)
 	if err != nil {
-		return nil, fmt.Errorf("predicate.Parse failed to parse time anchor %s in %s with error %v", ta, raw, err)
+		return nil, perr(tao, fmt.Sprintf("invalid time anchor %q", ta))
 	}
 	return &Predicate{
 		id:     ID(id),
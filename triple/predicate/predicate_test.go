@@ -89,6 +89,34 @@ func TestPrettyPrint(t *testing.T) {
 	}
 }
 
+func TestParseError(t *testing.T) {
+	table := []struct {
+		s      string
+		offset int
+		reason string
+	}{
+		{s: "", offset: 0, reason: "empty predicate"},
+		{s: "id\"@[]", offset: 0, reason: "missing opening \""},
+		{s: "\"foo\"", offset: 5, reason: "missing anchor definition @[...]"},
+		{s: "\"foo\"@[", offset: 7, reason: "missing closing ]"},
+		{s: " \"foo\"@[2015]", offset: 8, reason: "invalid time anchor \"2015\""},
+	}
+	for _, entry := range table {
+		_, err := Parse(entry.s)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("predicate.Parse(%q) should have returned a *ParseError; got %v", entry.s, err)
+			continue
+		}
+		if got, want := perr.Offset, entry.offset; got != want {
+			t.Errorf("predicate.Parse(%q) returned the wrong offset; got %d, want %d", entry.s, got, want)
+		}
+		if got, want := perr.Reason, entry.reason; got != want {
+			t.Errorf("predicate.Parse(%q) returned the wrong reason; got %q, want %q", entry.s, got, want)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	if got, err := Parse(""); err == nil {
 		t.Errorf("predicate.Parse should reject parsing \"\", but instead returned %v", got)
@@ -130,6 +158,93 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseInLocation(t *testing.T) {
+	const bare = `"bar"@[2016-01-01T00:00:00]`
+	if got, err := Parse(bare); err == nil {
+		t.Errorf("predicate.Parse should reject time anchors without offset, but instead returned %v", got)
+	}
+	if got, err := ParseInLocation(bare, nil); err == nil {
+		t.Errorf("predicate.ParseInLocation should reject time anchors without offset and location, but instead returned %v", got)
+	}
+	loc := time.FixedZone("PST", -8*60*60)
+	table := []struct {
+		s    string
+		want time.Time
+	}{
+		{s: bare, want: time.Date(2016, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{s: `"bar"@[2016-01-01T00:00:00.5]`, want: time.Date(2016, 1, 1, 8, 0, 0, 500000000, time.UTC)},
+		// Anchors with offset ignore the location.
+		{s: `"bar"@[2016-01-01T00:00:00Z]`, want: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, entry := range table {
+		p, err := ParseInLocation(entry.s, loc)
+		if err != nil {
+			t.Fatalf("predicate.ParseInLocation(%q) failed with error %v", entry.s, err)
+		}
+		ta, err := p.TimeAnchor()
+		if err != nil {
+			t.Fatalf("predicate.TimeAnchor failed to retrieve time anchor from %v with error %v", p, err)
+		}
+		if !ta.Equal(entry.want) {
+			t.Errorf("predicate.ParseInLocation(%q) returned anchor %s; want %s", entry.s, ta, entry.want)
+		}
+	}
+	if got, err := ParseInLocation(`"bar"@[2016-01-01]`, loc); err == nil {
+		t.Errorf("predicate.ParseInLocation should reject incomplete time anchors, but instead returned %v", got)
+	}
+}
+
+func TestParseTimeAnchor(t *testing.T) {
+	loc := time.FixedZone("PST", -8*60*60)
+	table := []struct {
+		s    string
+		loc  *time.Location
+		want time.Time
+	}{
+		{s: "2016-04-10T04:21:00.000000000Z", want: time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)},
+		// Single digit hour.
+		{s: "2016-04-10T4:21:00.000000000Z", want: time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)},
+		// Missing and shorter fractional seconds.
+		{s: "2016-04-10T04:21:00Z", want: time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)},
+		{s: "2016-04-10T4:21:00Z", want: time.Date(2016, 4, 10, 4, 21, 0, 0, time.UTC)},
+		{s: "2016-04-10T04:21:00.5Z", want: time.Date(2016, 4, 10, 4, 21, 0, 500000000, time.UTC)},
+		{s: "2016-04-10T04:21:00-07:00", want: time.Date(2016, 4, 10, 11, 21, 0, 0, time.UTC)},
+		// Anchors without offset are interpreted in the provided location.
+		{s: "2016-04-10T4:21:00", loc: loc, want: time.Date(2016, 4, 10, 12, 21, 0, 0, time.UTC)},
+	}
+	for _, entry := range table {
+		got, err := ParseTimeAnchor(entry.s, entry.loc)
+		if err != nil {
+			t.Errorf("predicate.ParseTimeAnchor(%q, %v) failed with error %v", entry.s, entry.loc, err)
+			continue
+		}
+		if !got.Equal(entry.want) {
+			t.Errorf("predicate.ParseTimeAnchor(%q, %v) = %s; want %s", entry.s, entry.loc, got, entry.want)
+		}
+		// Predicates accept the same forms.
+		p, err := ParseInLocation(fmt.Sprintf("\"bar\"@[%s]", entry.s), entry.loc)
+		if err != nil {
+			t.Errorf("predicate.ParseInLocation failed for anchor %q with error %v", entry.s, err)
+			continue
+		}
+		if ta, _ := p.TimeAnchor(); !ta.Equal(entry.want) {
+			t.Errorf("predicate.ParseInLocation returned anchor %s for %q; want %s", ta, entry.s, entry.want)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"2016-04-10",
+		"2016-4-10T04:21:00Z",
+		"2016-04-10 04:21:00Z",
+		"2016-04-10T04:21Z",
+		"2016-04-10T04:21:00",
+	} {
+		if got, err := ParseTimeAnchor(s, nil); err == nil {
+			t.Errorf("predicate.ParseTimeAnchor(%q, nil) should have failed, but returned %s", s, got)
+		}
+	}
+}
+
 func TestQuotedID(t *testing.T) {
 	const id = "ba\"r"
 	const pretty = "\"ba\\\"r\"@[]"
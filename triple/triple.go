@@ -182,34 +182,124 @@ func init() {
 	oSplit = regexp.MustCompile("(]\\s+/)|(]\\s+\")")
 }
 
+// ParseError describes why a pretty printed triple could not be parsed.
+// Offset is the position in Input of the first offending byte. When the
+// failure comes from parsing one of the triple components, Err holds the
+// original error.
+type ParseError struct {
+	Input  string
+	Offset int
+	Reason string
+	Err    error
+}
+
+// Error returns a readable version of the parse error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("triple.Parse: %s at offset %d in %q", e.Reason, e.Offset, e.Input)
+}
+
+// Unwrap returns the component error that caused the parse error, if any.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // Parse process the provided text and tries to create a triple. It assumes
-// that the provided text contains only one triple.
+// that the provided text contains only one triple. If the provided text is
+// malformed, the returned error is a *ParseError.
 func Parse(line string, b literal.Builder) (*Triple, error) {
 	raw := strings.TrimSpace(line)
-	idxp := pSplit.FindIndex([]byte(raw))
-	idxo := oSplit.FindIndex([]byte(raw))
-	if len(idxp) == 0 || len(idxo) == 0 {
-		return nil, fmt.Errorf("triple.Parse could not split s p o  out of %s", raw)
+	off := strings.Index(line, raw)
+	idxp := subjectSplit(raw)
+	if len(idxp) == 0 {
+		return nil, &ParseError{Input: line, Offset: off, Reason: "could not find the end of the subject"}
+	}
+	ps := idxp[1] - 1
+	idxo := oSplit.FindStringIndex(raw[ps:])
+	if len(idxo) == 0 {
+		return nil, &ParseError{Input: line, Offset: off + ps, Reason: "could not find the end of the predicate"}
 	}
-	ss, sp, so := raw[0:idxp[0]+1], raw[idxp[1]-1:idxo[0]+1], raw[idxo[1]-1:]
+	idxo[0], idxo[1] = idxo[0]+ps, idxo[1]+ps
+	po := idxo[1] - 1
+	ss, sp, so := raw[0:idxp[0]+1], raw[ps:idxo[0]+1], raw[po:]
 	s, err := node.Parse(ss)
 	if err != nil {
-		return nil, fmt.Errorf("triple.Parse failed to parse subject %s with error %v", ss, err)
+		return nil, componentError(line, off, "subject", err)
 	}
 	p, err := predicate.Parse(sp)
 	if err != nil {
-		return nil, fmt.Errorf("triple.Parse failed to parse predicate %s with error %v", sp, err)
+		return nil, componentError(line, off+ps, "predicate", err)
 	}
 	o, err := ParseObject(so, b)
 	if err != nil {
-		return nil, fmt.Errorf("triple.Parse failed to parse object %s with error %v", so, err)
+		if so[0] == '/' || so[0] == '_' {
+			// Report the node error, since the object can only be a node.
+			_, err = node.Parse(so)
+		}
+		return nil, componentError(line, off+po, "object", err)
 	}
 	return New(s, p, o)
 }
 
+// subjectSplit returns the location of the separator between the subject and
+// the predicate of the provided text. Escaped > characters in the subject ID
+// do not end the subject.
+func subjectSplit(raw string) []int {
+	for from := 0; from < len(raw); {
+		idx := pSplit.FindStringIndex(raw[from:])
+		if idx == nil {
+			return nil
+		}
+		idx[0], idx[1] = idx[0]+from, idx[1]+from
+		bs := 0
+		for i := idx[0] - 1; i >= 0 && raw[i] == '\\'; i-- {
+			bs++
+		}
+		if bs%2 == 0 {
+			return idx
+		}
+		from = idx[0] + 1
+	}
+	return nil
+}
+
+// componentError builds the parse error for a failure parsing the named
+// component of a triple starting at the provided offset of the line.
+func componentError(line string, start int, component string, err error) error {
+	perr := &ParseError{
+		Input:  line,
+		Offset: start,
+		Reason: "invalid " + component,
+		Err:    err,
+	}
+	switch e := err.(type) {
+	case *node.ParseError:
+		perr.Offset += e.Offset
+		perr.Reason += ": " + e.Reason
+	case *predicate.ParseError:
+		perr.Offset += e.Offset
+		perr.Reason += ": " + e.Reason
+	}
+	return perr
+}
+
 // Reify given the current triple it returns the original triple and the newly
-// reified ones. It also returns the newly created blank node.
+// reified ones. It also returns the newly created blank node. The reified
+// triples link the blank node to the subject, predicate, and object of the
+// original triple via the "_subject", "_predicate", and "_object" predicates,
+// which carry the time anchor of the original predicate if it is temporal.
+// This is the reification used by CONSTRUCT queries.
 func (t *Triple) Reify() ([]*Triple, *node.Node, error) {
+	b := node.NewBlankNode()
+	rts, err := t.ReifyWith(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rts, b, nil
+}
+
+// ReifyWith works like Reify, but links the reified triples to the provided
+// node instead of a newly created blank node.
+func (t *Triple) ReifyWith(b *node.Node) ([]*Triple, error) {
 	// Function that creates the proper reification predicates.
 	rp := func(id string, p *predicate.Predicate) (*predicate.Predicate, error) {
 		if p.Type() == predicate.Temporal {
@@ -218,41 +308,40 @@ func (t *Triple) Reify() ([]*Triple, *node.Node, error) {
 		}
 		return predicate.NewImmutable(id)
 	}
-	b := node.NewBlankNode()
 	s, err := rp("_subject", t.p)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	ts, _ := New(b, s, NewNodeObject(t.s))
 	p, err := rp("_predicate", t.p)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	tp, _ := New(b, p, NewPredicateObject(t.p))
 	var to *Triple
 	if t.o.l != nil {
 		o, err := rp("_object", t.p)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		to, _ = New(b, o, NewLiteralObject(t.o.l))
 	}
 	if t.o.n != nil {
 		o, err := rp("_object", t.p)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		to, _ = New(b, o, NewNodeObject(t.o.n))
 	}
 	if t.o.p != nil {
 		o, err := rp("_object", t.p)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		to, _ = New(b, o, NewPredicateObject(t.o.p))
 	}
 
-	return []*Triple{t, ts, tp, to}, b, nil
+	return []*Triple{t, ts, tp, to}, nil
 }
 
 // UUID returns a global unique identifier for the given triple. It is
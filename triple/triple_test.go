@@ -16,6 +16,7 @@ package triple
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/google/badwolf/triple/literal"
@@ -74,12 +75,71 @@ func TestParse(t *testing.T) {
 	ss := []string{
 		"/some/type<some id>\t\"foo\"@[]\t/some/type<some id>",
 		"/some/type<some id>\t\"foo\"@[]\t\"bar\"@[]",
+		"/some/type<a\\> \"b\\\\>\t\"foo\"@[]\t/some/type<\\<c\\>>",
 	}
 	for _, s := range ss {
 		if _, err := Parse(s, literal.DefaultBuilder()); err != nil {
 			t.Errorf("triple.Parse failed to parse valid triple %s with error %v", s, err)
 		}
 	}
+	tr, err := Parse(ss[2], literal.DefaultBuilder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tr.Subject().ID().String(), `a> "b\`; got != want {
+		t.Errorf("triple.Parse returned subject ID %q; want %q", got, want)
+	}
+	if got, want := tr.String(), ss[2]; got != want {
+		t.Errorf("triple.Parse did not round trip; got %q, want %q", got, want)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	table := []struct {
+		s      string
+		offset int
+		reason string
+	}{
+		{
+			s:      "/some/type<some id>",
+			offset: 0,
+			reason: "could not find the end of the subject",
+		},
+		{
+			s:      "/some/type<some id>\t\"foo\"@[]",
+			offset: 20,
+			reason: "could not find the end of the predicate",
+		},
+		{
+			s:      "/some type<some id>\t\"foo\"@[]\t/some/type<some id>",
+			offset: 5,
+			reason: "invalid subject: spaces are not allowed in types",
+		},
+		{
+			s:      "/some/type<some id>\t\"foo\"@[2015]\t/some/type<some id>",
+			offset: 27,
+			reason: "invalid predicate: invalid time anchor \"2015\"",
+		},
+		{
+			s:      "/some/type<some id>\t\"foo\"@[]\t/some/type<some id",
+			offset: 47,
+			reason: "invalid object: missing closing >",
+		},
+	}
+	for _, entry := range table {
+		_, err := Parse(entry.s, literal.DefaultBuilder())
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("triple.Parse(%q) should have returned a *ParseError; got %v", entry.s, err)
+			continue
+		}
+		if got, want := perr.Offset, entry.offset; got != want {
+			t.Errorf("triple.Parse(%q) returned the wrong offset; got %d, want %d", entry.s, got, want)
+		}
+		if got, want := perr.Reason, entry.reason; got != want {
+			t.Errorf("triple.Parse(%q) returned the wrong reason; got %q, want %q", entry.s, got, want)
+		}
+	}
 }
 
 func TestReifyImmutable(t *testing.T) {
@@ -122,6 +182,89 @@ func TestReifyTemporal(t *testing.T) {
 	}
 }
 
+func TestReifyWith(t *testing.T) {
+	tr, err := Parse("/some/type<some id>\t\"foo\"@[]\t\"bar\"@[]", literal.DefaultBuilder())
+	if err != nil {
+		t.Fatalf("triple.Parse failed to parse valid triple with error %v", err)
+	}
+	bn, err := node.Parse("/_<fixed>")
+	if err != nil {
+		t.Fatalf("node.Parse failed to parse valid node with error %v", err)
+	}
+	rts, err := tr.ReifyWith(bn)
+	if err != nil {
+		t.Fatalf("triple.ReifyWith failed to reify %v with error %v", tr, err)
+	}
+	if len(rts) != 4 || rts[0] != tr {
+		t.Fatalf("triple.ReifyWith failed to create 4 valid triples; returned %v instead", rts)
+	}
+	for _, trpl := range rts[1:] {
+		if got, want := trpl.Subject().String(), bn.String(); got != want {
+			t.Errorf("triple.ReifyWith returned triple %s with subject %s; want %s", trpl, got, want)
+		}
+	}
+}
+
+func TestReifyTriples(t *testing.T) {
+	table := []struct {
+		t    string
+		want []string
+	}{
+		{
+			t: "/person<A>\t\"met\"@[2016-04-10T04:25:00Z]\t/person<B>",
+			want: []string{
+				"/person<A>\t\"met\"@[2016-04-10T04:25:00Z]\t/person<B>",
+				"/_<b>\t\"_subject\"@[2016-04-10T04:25:00Z]\t/person<A>",
+				"/_<b>\t\"_predicate\"@[2016-04-10T04:25:00Z]\t\"met\"@[2016-04-10T04:25:00Z]",
+				"/_<b>\t\"_object\"@[2016-04-10T04:25:00Z]\t/person<B>",
+			},
+		},
+		{
+			t: "/u<joe>\t\"height_cm\"@[]\t\"174\"^^type:int64",
+			want: []string{
+				"/u<joe>\t\"height_cm\"@[]\t\"174\"^^type:int64",
+				"/_<b>\t\"_subject\"@[]\t/u<joe>",
+				"/_<b>\t\"_predicate\"@[]\t\"height_cm\"@[]",
+				"/_<b>\t\"_object\"@[]\t\"174\"^^type:int64",
+			},
+		},
+		{
+			t: "/l<paris>\t\"predicate\"@[]\t\"turned\"@[2016-04-01T00:00:00Z]",
+			want: []string{
+				"/l<paris>\t\"predicate\"@[]\t\"turned\"@[2016-04-01T00:00:00Z]",
+				"/_<b>\t\"_subject\"@[]\t/l<paris>",
+				"/_<b>\t\"_predicate\"@[]\t\"predicate\"@[]",
+				"/_<b>\t\"_object\"@[]\t\"turned\"@[2016-04-01T00:00:00Z]",
+			},
+		},
+	}
+	for _, entry := range table {
+		tr, err := Parse(entry.t, literal.DefaultBuilder())
+		if err != nil {
+			t.Fatalf("triple.Parse failed to parse valid triple %q with error %v", entry.t, err)
+		}
+		rts, bn, err := tr.Reify()
+		if err != nil {
+			t.Fatalf("triple.Reify failed to reify %v with error %v", tr, err)
+		}
+		if len(rts) != len(entry.want) {
+			t.Fatalf("triple.Reify(%v) returned %d triples; want %d", tr, len(rts), len(entry.want))
+		}
+		for i, trpl := range rts {
+			got := trpl.String()
+			if i > 0 {
+				if trpl.Subject().String() != bn.String() {
+					t.Errorf("triple.Reify(%v) returned %v with subject other than blank node %v", tr, trpl, bn)
+				}
+				got = strings.Replace(got, bn.String(), "/_<b>", 1)
+			}
+			if got != entry.want[i] {
+				t.Errorf("triple.Reify(%v) returned %q at position %d; want %q", tr, got, i, entry.want[i])
+			}
+		}
+	}
+}
+
 func TestUUID(t *testing.T) {
 	testTable := []struct {
 		t1 string
@@ -163,6 +306,10 @@ func TestUUID(t *testing.T) {
 			"/some/type<some id>\t\"foo\"@[]\t\"text\"^^type:text",
 			"/some/type<some id>\t\"foo\"@[]\t\"text\"^^type:text",
 		},
+		{
+			"/some/type<some id>\t\"foo\"@[]\t\"texto\"^^type:text@ES",
+			"/some/type<some id>\t\"foo\"@[]\t\"texto\"^^type:text@es",
+		},
 		{
 			"/some/type<some id>\t\"foo\"@[]\t\"[0 0 0]\"^^type:blob",
 			"/some/type<some id>\t\"foo\"@[]\t\"[0 0 0]\"^^type:blob",